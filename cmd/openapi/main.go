@@ -0,0 +1,23 @@
+// Command openapi writes the API's OpenAPI description to standard output.
+// docs/openapi.json is its output, checked in so changes to the API show up
+// in review and client SDKs can be generated without running the server:
+//
+//	go run ./cmd/openapi > docs/openapi.json
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"analytics-dashboard-api/internal/handlers"
+)
+
+func main() {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(handlers.OpenAPISpec()); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write OpenAPI spec: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,21 @@
+//go:build cgo
+
+package main
+
+import (
+	"analytics-dashboard-api/internal/config"
+	"analytics-dashboard-api/internal/metrics"
+	"analytics-dashboard-api/internal/services"
+	"analytics-dashboard-api/pkg/logger"
+)
+
+// DuckDB links against the C library, so it is only available in cgo builds
+func init() {
+	registerBackend("duckdb", func(cfg *config.Config, m *metrics.Metrics, log logger.Logger) (Backend, error) {
+		service, err := services.NewDuckDBService(cfg.DuckDB, cfg.Dimensions, cfg.Outliers, cfg.TestOrders, m, log)
+		if err != nil {
+			return nil, err
+		}
+		return service, nil
+	})
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"analytics-dashboard-api/internal/config"
+	"analytics-dashboard-api/internal/metrics"
+	"analytics-dashboard-api/internal/services"
+	"analytics-dashboard-api/pkg/logger"
+)
+
+func init() {
+	registerBackend("clickhouse", func(cfg *config.Config, _ *metrics.Metrics, log logger.Logger) (Backend, error) {
+		service, err := services.NewClickHouseService(cfg.ClickHouse, log)
+		if err != nil {
+			return nil, err
+		}
+		if cfg.TestOrders != (config.TestOrderConfig{}) {
+			log.Warn("Test-order rules are ignored: ClickHouse tables are queried as loaded elsewhere")
+		}
+		return service, nil
+	})
+	registerBackend("memory", func(cfg *config.Config, _ *metrics.Metrics, log logger.Logger) (Backend, error) {
+		return services.NewMemoryService(cfg.Dimensions, cfg.Outliers, cfg.TestOrders, log), nil
+	})
+}
@@ -0,0 +1,416 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"analytics-dashboard-api/internal/config"
+	"analytics-dashboard-api/internal/handlers"
+	"analytics-dashboard-api/internal/metrics"
+	"analytics-dashboard-api/internal/middleware"
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/internal/services"
+	"analytics-dashboard-api/pkg/logger"
+)
+
+// Backend is the analytics repository selected with DATA_BACKEND
+type Backend = services.Repository
+
+// BackendFactory builds a backend from configuration
+type BackendFactory func(*config.Config, *metrics.Metrics, logger.Logger) (Backend, error)
+
+// backends holds the implementations selectable with DATA_BACKEND
+var backends = map[string]BackendFactory{}
+
+func registerBackend(name string, factory BackendFactory) {
+	backends[name] = factory
+}
+
+// backendName resolves the configured backend, defaulting to DuckDB when it
+// was compiled in and to the pure-Go memory backend otherwise
+func backendName(cfg *config.Config) string {
+	if cfg.Data.Backend != "" {
+		return cfg.Data.Backend
+	}
+	if _, ok := backends["duckdb"]; ok {
+		return "duckdb"
+	}
+	return "memory"
+}
+
+// newBackend builds the named backend. A driver that panics while starting,
+// as a cgo library can, is reported as an error like any other failure.
+func newBackend(name string, cfg *config.Config, m *metrics.Metrics, log logger.Logger) (backend Backend, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			backend, err = nil, fmt.Errorf("%s driver panicked: %v", name, r)
+		}
+	}()
+
+	factory, ok := backends[name]
+	if !ok && name == "duckdb" {
+		return nil, fmt.Errorf("duckdb backend is not compiled in: this binary was built without cgo")
+	}
+	if !ok {
+		names := make([]string, 0, len(backends))
+		for name := range backends {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown data backend %q (available: %s)", name, strings.Join(names, ", "))
+	}
+	return factory(cfg, m, log)
+}
+
+// container wires the services and handlers of one deployment. Handlers only
+// see the interfaces they declare, so the backend chosen here is the single
+// point where implementations are swapped.
+type container struct {
+	backend     Backend
+	jobs        *services.JobQueue
+	loader      *services.DataLoader
+	watcher     *services.SourceWatcher
+	scheduler   *services.RefreshScheduler
+	preferences *services.PreferenceStore
+	alerts      *services.AlertEngine
+	snapshots   *services.SnapshotReader
+	registry    *services.DatasetRegistry
+	retention   *services.BackupRetention
+	cache       *middleware.ResponseCache
+	capture     *middleware.DebugCapture
+	instruments *metrics.Metrics
+	apiKeys     middleware.APIKeys
+	timeouts    func(method, template string) (time.Duration, bool)
+
+	analytics    *handlers.AnalyticsHandler
+	products     *handlers.ProductHandler
+	targets      *handlers.TargetHandler
+	datasets     *handlers.DatasetHandler
+	annotations  *handlers.AnnotationHandler
+	flags        *handlers.FlagHandler
+	preference   *handlers.PreferenceHandler
+	alert        *handlers.AlertHandler
+	metrics      *handlers.MetricHandler
+	contribution *handlers.ContributionHandler
+	abc          *handlers.ABCHandler
+	heatmap      *handlers.HeatmapHandler
+	timeseries   *handlers.TimeSeriesHandler
+	inventory    *handlers.InventoryHandler
+	nlQuery      *handlers.NLQueryHandler
+	uploads      *handlers.UploadHandler
+	exports      *handlers.ExportHandler
+	reports      *handlers.ReportHandler
+	meta         *handlers.MetaHandler
+	job          *handlers.JobHandler
+	schedule     *handlers.RefreshScheduleHandler
+	admin        *handlers.AdminHandler
+	debugCapture *handlers.DebugCaptureHandler
+	openapi      *handlers.OpenAPIHandler
+	health       *handlers.HealthHandler
+}
+
+// backendUnavailableError reports that neither the configured backend nor
+// its fallback could be started; the server then runs degraded
+type backendUnavailableError struct {
+	Backend string
+	Err     error
+}
+
+func (e *backendUnavailableError) Error() string {
+	return fmt.Sprintf("failed to initialize %s backend: %v", e.Backend, e.Err)
+}
+
+func (e *backendUnavailableError) Unwrap() error { return e.Err }
+
+// newContainer starts the configured backend, or DATA_BACKEND_FALLBACK when
+// that fails. The backend started then stands in as the configured one.
+func newContainer(cfg *config.Config, log logger.Logger) (*container, error) {
+	m := metrics.New()
+	name := backendName(cfg)
+	backend, err := newBackend(name, cfg, m, log)
+	var fallbackCause error
+	if err != nil {
+		fallback := cfg.Data.BackendFallback
+		if fallback == "" {
+			return nil, &backendUnavailableError{Backend: name, Err: err}
+		}
+		log.Error("Data backend unavailable, falling back", "backend", name, "fallback", fallback, "error", err)
+		fallbackCause = fmt.Errorf("%s backend unavailable: %w", name, err)
+		if backend, err = newBackend(fallback, cfg, m, log); err != nil {
+			return nil, &backendUnavailableError{Backend: fallback, Err: fmt.Errorf("%w (after %v)", err, fallbackCause)}
+		}
+		name = fallback
+	}
+	// Recorded so snapshots, diagnostics and /admin/config all see the
+	// backend in use
+	cfg.Data.Backend = name
+	log.Info("Using data backend", "backend", name)
+
+	c, err := wire(cfg, backend, m, log)
+	if err != nil {
+		backend.Close()
+		return nil, err
+	}
+	if fallbackCause != nil {
+		c.health.ReportFallback(name, fallbackCause)
+	}
+	return c, nil
+}
+
+func wire(cfg *config.Config, backend Backend, m *metrics.Metrics, log logger.Logger) (*container, error) {
+	annotationStore, err := services.NewAnnotationStore(cfg.State.Dir, log)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize annotation store: %w", err)
+	}
+
+	preferenceStore, err := services.NewPreferenceStore(cfg.State.Dir, log)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize preference store: %w", err)
+	}
+
+	alertEngine, err := services.NewAlertEngine(cfg.State.Dir, backend, cfg.Alerts.WebhookTimeout, log)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize alert engine: %w", err)
+	}
+
+	metricRegistry, err := services.NewMetricRegistry(cfg.State.Dir, cfg.Metrics.Derived, log)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize metric registry: %w", err)
+	}
+
+	flagStore, err := services.NewFlagStore(cfg.State.Dir, backend, log)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize flag store: %w", err)
+	}
+
+	uploadStore, err := services.NewUploadStore(cfg.Uploads, log)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize upload store: %w", err)
+	}
+
+	auditLog, err := services.NewAuditLog(cfg.State.Dir, log)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize audit log: %w", err)
+	}
+	uploadScan := services.NewUploadScan(cfg.Uploads, auditLog, log)
+
+	quotas, err := services.NewQuotas(cfg.Quotas)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize quotas: %w", err)
+	}
+
+	jobs := services.NewJobQueue(log)
+	loader := services.NewDataLoader(backend, cfg.CSV.FilePath, cfg.Data, jobs, quotas, m, log)
+	loader.OnRefresh(alertEngine.HandleRefresh)
+
+	registry, err := newDatasets(cfg, backend, loader, jobs, quotas, m, log)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize datasets: %w", err)
+	}
+
+	// ?as_of= queries restore backups into backends of the same kind, kept
+	// in memory so they never write to DUCKDB_PATH
+	snapshotCfg := *cfg
+	snapshotCfg.DuckDB.Path = ""
+	snapshots := services.NewSnapshotReader(cfg.Backup, func() (services.Repository, error) {
+		return newBackend(backendName(cfg), &snapshotCfg, m, log)
+	}, log)
+	retention := services.NewBackupRetention(cfg.Backup, jobs, log)
+	diagnostics := services.NewDiagnostics(cfg.Diagnostics, backendName(cfg), diagnosticPaths(cfg), backend, loader, log)
+
+	// Scheduled refreshes take the backup a manual refresh would; like
+	// there, a failed backup is logged and does not fail the refresh
+	scheduler, err := services.NewRefreshScheduler(cfg.Refresh, loader, func(ctx context.Context) error {
+		if cfg.Backup.OnRefresh {
+			if _, err := backend.Backup(ctx, cfg.Backup.Dir); err != nil {
+				log.Error("Automatic backup after refresh failed", "error", err)
+			}
+		}
+		return nil
+	}, log)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize refresh scheduler: %w", err)
+	}
+
+	exportManager, err := services.NewExportManager(cfg.Exports, backend, loader, services.NewSMTPMailer(cfg.SMTP), log)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize export manager: %w", err)
+	}
+
+	cacheTTLs, err := handlers.CacheTTLs(cfg.Cache)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize response cache: %w", err)
+	}
+	cache := middleware.NewResponseCache(cacheTTLs, cfg.Cache.MaxEntries, loader.Version)
+	m.Registry().CounterFunc("response_cache_hits_total", "Responses served from the response cache.",
+		func() float64 { return float64(cache.Stats().Hits) })
+	m.Registry().CounterFunc("response_cache_misses_total", "Cacheable responses computed because they were not cached.",
+		func() float64 { return float64(cache.Stats().Misses) })
+	m.Registry().GaugeFunc("response_cache_entries", "Responses held by the response cache.",
+		func() float64 { return float64(cache.Stats().Entries) })
+
+	capture := middleware.NewDebugCapture(cfg.Capture.Size, cfg.Capture.MaxBodyBytes, cfg.Capture.Enabled, cfg.Capture.SampleRate)
+
+	timeouts, err := handlers.ResponseTimeouts(cfg.Server)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize response timeouts: %w", err)
+	}
+
+	apiKeys, err := middleware.LoadAPIKeys(cfg.Auth.APIKeys, cfg.Auth.APIKeysFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load API keys: %w", err)
+	}
+	if len(apiKeys) == 0 {
+		log.Warn("API key authentication disabled: no API keys configured")
+	}
+
+	analytics := handlers.NewAnalyticsHandler(backend, backend, loader, metricRegistry, snapshotSource{snapshots}, datasetSource{registry}, log, cfg.Backup)
+	m.Registry().CounterFunc("analytics_summary_cache_hits_total", "Dashboard summaries served from the summary cache.",
+		func() float64 { return float64(analytics.SummaryCacheStats().Hits) })
+	m.Registry().CounterFunc("analytics_summary_cache_misses_total", "Dashboard summaries of the loaded data computed because they were not cached.",
+		func() float64 { return float64(analytics.SummaryCacheStats().Misses) })
+
+	return &container{
+		backend:     backend,
+		jobs:        jobs,
+		loader:      loader,
+		watcher:     services.NewSourceWatcher(loader, cfg.CSV.FilePath, cfg.Data, log),
+		scheduler:   scheduler,
+		preferences: preferenceStore,
+		alerts:      alertEngine,
+		snapshots:   snapshots,
+		registry:    registry,
+		retention:   retention,
+		cache:       cache,
+		capture:     capture,
+		instruments: m,
+		apiKeys:     apiKeys,
+		timeouts:    timeouts,
+
+		analytics:    analytics,
+		products:     handlers.NewProductHandler(backend, loader, log),
+		targets:      handlers.NewTargetHandler(backend, loader, uploadStore, uploadScan, quotas, log),
+		datasets:     handlers.NewDatasetHandler(loader, backend, registry, uploadStore, uploadScan, cfg.Uploads, log),
+		annotations:  handlers.NewAnnotationHandler(annotationStore, log),
+		flags:        handlers.NewFlagHandler(flagStore, loader, backend, log),
+		preference:   handlers.NewPreferenceHandler(preferenceStore, log),
+		alert:        handlers.NewAlertHandler(alertEngine, log),
+		metrics:      handlers.NewMetricHandler(metricRegistry, backend, loader, services.MetricGroupings, log),
+		contribution: handlers.NewContributionHandler(services.NewContributionService(backend, log), loader, backend, log),
+		abc:          handlers.NewABCHandler(services.NewABCService(cfg.ABC, backend, log), loader, backend, log),
+		heatmap:      handlers.NewHeatmapHandler(services.NewHeatmapService(cfg.Heatmap, backend, log), loader, backend, log),
+		timeseries:   handlers.NewTimeSeriesHandler(services.NewTimeSeriesService(backend, log), loader, backend, log),
+		inventory:    handlers.NewInventoryHandler(services.NewInventoryService(cfg.Inventory, backend, log), loader, backend, log),
+		nlQuery:      handlers.NewNLQueryHandler(services.NewNLQueryService(cfg.NLQuery, backend, metricRegistry, log), loader, log),
+		uploads:      handlers.NewUploadHandler(uploadStore, quotas, cfg.Uploads, log),
+		exports:      handlers.NewExportHandler(exportManager, log),
+		reports:      handlers.NewReportHandler(services.NewReportService(cfg.Reports, backend, metricRegistry, log), loader, log),
+		meta:         handlers.NewMetaHandler(backend, loader, log),
+		job:          handlers.NewJobHandler(jobs, log),
+		schedule:     handlers.NewRefreshScheduleHandler(scheduler, log),
+		admin:        handlers.NewAdminHandler(jobs, loader, auditLog, retention, cache, backend, diagnostics, cfg, log),
+		debugCapture: handlers.NewDebugCaptureHandler(capture, log),
+		openapi:      handlers.NewOpenAPIHandler(),
+		health:       handlers.NewHealthHandler(loader, backend, log),
+	}, nil
+}
+
+// Close releases the backend, the named datasets' backends and any
+// snapshots opened for ?as_of= queries
+func (c *container) Close() error {
+	c.snapshots.Close()
+	c.registry.Close()
+	return c.backend.Close()
+}
+
+// diagnosticPaths lists the directories the self-diagnostics check: the
+// data files are only read, everything else is written. A data file in
+// object storage is downloaded to the remote cache, which is written.
+func diagnosticPaths(cfg *config.Config) []services.DiagnosticPath {
+	paths := []services.DiagnosticPath{{Name: "data", Dir: filepath.Dir(cfg.CSV.FilePath)}}
+	if services.IsRemoteSource(cfg.CSV.FilePath) {
+		paths = []services.DiagnosticPath{{Name: "remote_cache", Dir: cfg.Data.Remote.CacheDir, Writable: true}}
+	}
+	if cfg.DuckDB.Path != "" && backendName(cfg) == "duckdb" {
+		paths = append(paths, services.DiagnosticPath{Name: "duckdb", Dir: filepath.Dir(cfg.DuckDB.Path), Writable: true})
+	}
+	return append(paths,
+		services.DiagnosticPath{Name: "backups", Dir: cfg.Backup.Dir, Writable: true},
+		services.DiagnosticPath{Name: "state", Dir: cfg.State.Dir, Writable: true},
+		services.DiagnosticPath{Name: "uploads", Dir: cfg.Uploads.Dir, Writable: true},
+		services.DiagnosticPath{Name: "exports", Dir: cfg.Exports.Dir, Writable: true},
+	)
+}
+
+// snapshotSource hands out snapshots as the analytics service the handlers
+// declare
+type snapshotSource struct {
+	reader *services.SnapshotReader
+}
+
+func (s snapshotSource) AsOf(ctx context.Context, t time.Time) (handlers.AnalyticsService, *models.BackupInfo, func(), error) {
+	repo, info, release, err := s.reader.AsOf(ctx, t)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return repo, info, release, nil
+}
+
+// newDatasets registers the default dataset and opens a backend and loader
+// for each dataset listed in DATASETS. The named datasets share the job
+// queue and quotas but not the load metrics, which describe the default one.
+func newDatasets(cfg *config.Config, backend Backend, loader *services.DataLoader, jobs *services.JobQueue, quotas *services.Quotas, m *metrics.Metrics, log logger.Logger) (*services.DatasetRegistry, error) {
+	specs, err := services.ParseDatasets(cfg.Data.Datasets)
+	if err != nil {
+		return nil, err
+	}
+
+	registry := services.NewDatasetRegistry(backend, loader, cfg.CSV.FilePath)
+	for _, spec := range specs {
+		repo, err := newBackend(backendName(cfg), datasetConfig(cfg, spec.ID), m, log)
+		if err != nil {
+			registry.Close()
+			return nil, fmt.Errorf("dataset %s: %w", spec.ID, err)
+		}
+		if err := registry.Add(spec, repo, services.NewDataLoader(repo, spec.Path, cfg.Data, jobs, quotas, nil, log)); err != nil {
+			repo.Close()
+			registry.Close()
+			return nil, err
+		}
+		log.Info("Dataset registered", "dataset", spec.ID, "source", spec.Path)
+	}
+	return registry, nil
+}
+
+// datasetConfig is the backend configuration of the named dataset id: a
+// DuckDB file of its own next to DUCKDB_PATH, or a ClickHouse transactions
+// table named with the ID appended. Dimension tables and files are shared.
+func datasetConfig(cfg *config.Config, id string) *config.Config {
+	datasetCfg := *cfg
+	if path := cfg.DuckDB.Path; path != "" {
+		ext := filepath.Ext(path)
+		datasetCfg.DuckDB.Path = strings.TrimSuffix(path, ext) + "." + id + ext
+	}
+	if datasetCfg.ClickHouse.TransactionsTable != "" {
+		datasetCfg.ClickHouse.TransactionsTable += "_" + id
+	}
+	return &datasetCfg
+}
+
+// datasetSource hands out named datasets as the analytics service the
+// handlers declare
+type datasetSource struct {
+	registry *services.DatasetRegistry
+}
+
+func (s datasetSource) Dataset(ctx context.Context, id string) (handlers.AnalyticsService, error) {
+	repo, err := s.registry.Dataset(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return repo, nil
+}
@@ -2,16 +2,20 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	"analytics-dashboard-api/internal/config"
+	"analytics-dashboard-api/internal/format"
 	"analytics-dashboard-api/internal/handlers"
 	"analytics-dashboard-api/internal/middleware"
+	"analytics-dashboard-api/internal/models"
 	"analytics-dashboard-api/internal/services"
 	"analytics-dashboard-api/pkg/logger"
 
@@ -29,32 +33,83 @@ func main() {
 	// Initialize logger
 	log := logger.NewLogger(cfg.Logger.Level)
 	log.Info("Starting analytics dashboard server", "version", "1.0.0")
-	// Initialize DuckDB service
-	duckdbService, err := services.NewDuckDBService(log)
+
+	// Apply response formatting before any handler renders money
+	if err := models.SetMoneyFormat(models.MoneyFormat(cfg.Formatting.MoneyFormat)); err != nil {
+		log.Error("Invalid money format", "error", err)
+		os.Exit(1)
+	}
+	format.SetDefaultCurrency(cfg.Formatting.DefaultCurrency)
+
+	// Dates are parsed with these layouts from the first load on
+	if err := models.SetDateLayouts(cfg.Data.DateLayouts); err != nil {
+		log.Error("Invalid date layouts", "error", err)
+		os.Exit(1)
+	}
+	// Transactions columns are read as mapped from the first load on
+	columnMapping, err := services.LoadColumnMapping(cfg.Data.ColumnMap, cfg.Data.ColumnMapFile)
 	if err != nil {
-		log.Error("Failed to initialize DuckDB", "error", err)
+		log.Error("Invalid column mapping", "error", err)
 		os.Exit(1)
 	}
-	defer duckdbService.Close()
+	if columnMapping != nil {
+		log.Info("Reading transactions with a column mapping", "mapping", columnMapping.String())
+	}
+	services.SetColumnMapping(columnMapping)
 
-	// Initialize handlers
-	analyticsHandler := handlers.NewAnalyticsHandler(
-		duckdbService,
-		log,
-		cfg.CSV.FilePath,
-	)
-	healthHandler := handlers.NewHealthHandler(log)
+	tuneGC(cfg.Runtime, log)
+
+	// Wire backend, services and handlers
+	c, err := newContainer(cfg, log)
+	var unavailable *backendUnavailableError
+	switch {
+	case errors.As(err, &unavailable):
+		// Stay up to report the failure on the probes rather than exit
+		// into a restart loop that says nothing
+		log.Error("Data backend unavailable, serving health checks only", "backend", unavailable.Backend, "error", unavailable.Err)
+		serveDegraded(cfg.Server, handlers.NewDegradedHandler(unavailable.Backend, unavailable.Err), log)
+		return
+	case err != nil:
+		log.Error("Failed to initialize services", "error", err)
+		os.Exit(1)
+	}
+	defer c.Close()
 
 	// Setup router
-	router := setupRouter(analyticsHandler, healthHandler, log)
+	router := setupRouter(c, cfg.Server.QueryValidation, cfg.Server.GzipMinBytes, cfg.RateLimit, log)
+
+	// Evaluate alert rules on a schedule until shutdown
+	alertCtx, stopAlerts := context.WithCancel(context.Background())
+	defer stopAlerts()
+	c.alerts.Start(alertCtx, cfg.Alerts.EvaluationInterval)
+
+	// Remove backups outside the retention policy on a schedule
+	pruneCtx, stopPruning := context.WithCancel(context.Background())
+	defer stopPruning()
+	c.retention.Start(pruneCtx, cfg.Backup.PruneInterval)
+
+	// Reload the data when the data file is replaced
+	watchCtx, stopWatching := context.WithCancel(context.Background())
+	defer stopWatching()
+	c.watcher.Start(watchCtx)
 
+	// Refresh the data on the REFRESH_CRON schedule, or one set via the API
+	scheduleCtx, stopSchedule := context.WithCancel(context.Background())
+	defer stopSchedule()
+	c.scheduler.Start(scheduleCtx)
+
+	serve(cfg.Server, router, log)
+}
+
+// serve runs the HTTP server until SIGINT or SIGTERM, then shuts it down
+func serve(serverCfg config.ServerConfig, handler http.Handler, log logger.Logger) {
 	// Create server
 	server := &http.Server{
-		Addr:         fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port),
-		Handler:      router,
-		ReadTimeout:  cfg.Server.ReadTimeout,
-		WriteTimeout: cfg.Server.WriteTimeout,
-		IdleTimeout:  cfg.Server.IdleTimeout,
+		Addr:         fmt.Sprintf("%s:%d", serverCfg.Host, serverCfg.Port),
+		Handler:      handler,
+		ReadTimeout:  serverCfg.ReadTimeout,
+		WriteTimeout: serverCfg.WriteTimeout,
+		IdleTimeout:  serverCfg.IdleTimeout,
 	}
 
 	// Start server in goroutine
@@ -95,33 +150,211 @@ func main() {
 	log.Info("Server shutdown completed")
 }
 
-func setupRouter(
-	analyticsHandler *handlers.AnalyticsHandler,
-	healthHandler *handlers.HealthHandler,
-	log logger.Logger,
-) *mux.Router {
+// serveDegraded runs the server with only the probes, which report why the
+// data backend could not start, and 503 for every other route
+func serveDegraded(serverCfg config.ServerConfig, degraded *handlers.DegradedHandler, log logger.Logger) {
+	router := mux.NewRouter()
+	router.Use(middleware.Recovery(log))
+	router.Use(middleware.Logging(log))
+	router.HandleFunc("/health", degraded.Health).Methods("GET")
+	router.HandleFunc("/health/live", degraded.Live).Methods("GET")
+	router.HandleFunc("/health/ready", degraded.Ready).Methods("GET")
+	router.HandleFunc("/health/startup", degraded.Ready).Methods("GET")
+	router.HandleFunc("/ready", degraded.Ready).Methods("GET")
+	router.PathPrefix("/").HandlerFunc(degraded.Unavailable)
+
+	serve(serverCfg, router, log)
+}
+
+func setupRouter(c *container, queryValidation string, gzipMinBytes int, rateLimit config.RateLimitConfig, log logger.Logger) *mux.Router {
 	router := mux.NewRouter()
 
 	// Apply middleware
 	router.Use(middleware.Recovery(log))
 	router.Use(middleware.Logging(log))
+	router.Use(middleware.Metrics(c.instruments))
+	router.Use(middleware.ResponseTimeout(c.timeouts, log))
+	// Outside the response cache, so cache hits are compressed too
+	router.Use(middleware.Compression(gzipMinBytes))
+	// Inside compression so captured bodies are readable
+	router.Use(c.capture.Middleware)
 	router.Use(middleware.CORS)
+	// After CORS so preflight requests, which carry no key, are answered
+	router.Use(middleware.APIKeyAuth(c.apiKeys, "/api/v1/"))
+	// After auth so keyed clients are limited per key rather than per address
+	router.Use(middleware.RateLimit(rateLimit.RPS, rateLimit.Burst, "/api/v1/"))
+	router.Use(middleware.Identity)
+	// Validate client-supplied parameters before preferences fill in defaults
+	router.Use(middleware.QueryValidation(handlers.QueryParamSpecs, queryValidation, log))
+	router.Use(middleware.PreferenceDefaults(c.preferences, "/api/v1/analytics"))
+	// Cache after preferences so the defaults they add are part of the key
+	router.Use(c.cache.Middleware)
+	// Inside the cache, which never stores timed responses
+	router.Use(middleware.DebugTiming(c.apiKeys))
 
 	// API routes
 	api := router.PathPrefix("/api/v1").Subrouter()
 
 	// Analytics endpoints
-	api.HandleFunc("/analytics", analyticsHandler.GetAnalytics).Methods("GET")
-	api.HandleFunc("/analytics/stats", analyticsHandler.GetAnalyticsStats).Methods("GET")
-	api.HandleFunc("/analytics/country-revenue", analyticsHandler.GetCountryRevenue).Methods("GET")
-	api.HandleFunc("/analytics/top-products", analyticsHandler.GetTopProducts).Methods("GET")
-	api.HandleFunc("/analytics/monthly-sales", analyticsHandler.GetMonthlySales).Methods("GET")
-	api.HandleFunc("/analytics/top-regions", analyticsHandler.GetTopRegions).Methods("GET")
-	api.HandleFunc("/analytics/refresh", analyticsHandler.RefreshCache).Methods("POST")
-
-	// Health endpoints
-	router.HandleFunc("/health", healthHandler.Health).Methods("GET")
-	router.HandleFunc("/ready", healthHandler.Ready).Methods("GET")
+	api.HandleFunc("/analytics", c.analytics.GetAnalytics).Methods("GET")
+	api.HandleFunc("/analytics/stats", c.analytics.GetAnalyticsStats).Methods("GET")
+	api.HandleFunc("/analytics/country-revenue", c.analytics.GetCountryRevenue).Methods("GET")
+	api.HandleFunc("/analytics/top-products", c.analytics.GetTopProducts).Methods("GET")
+	api.HandleFunc("/analytics/monthly-sales", c.analytics.GetMonthlySales).Methods("GET")
+	api.HandleFunc("/analytics/top-regions", c.analytics.GetTopRegions).Methods("GET")
+	api.HandleFunc("/analytics/segments", c.analytics.GetSegments).Methods("GET")
+	api.HandleFunc("/analytics/top-customers", c.analytics.GetTopCustomers).Methods("GET")
+	api.HandleFunc("/analytics/countries/{country}", c.analytics.GetCountryDetail).Methods("GET")
+	api.HandleFunc("/analytics/aggregate", c.metrics.GetAggregate).Methods("GET")
+	api.HandleFunc("/analytics/contribution", c.contribution.GetContribution).Methods("GET")
+	api.HandleFunc("/analytics/abc", c.abc.GetABC).Methods("GET")
+	api.HandleFunc("/analytics/heatmap", c.heatmap.GetHeatmap).Methods("GET")
+	api.HandleFunc("/analytics/timeseries", c.timeseries.GetTimeSeries).Methods("GET")
+	api.HandleFunc("/analytics/calendar", c.timeseries.GetCalendar).Methods("GET")
+	api.HandleFunc("/analytics/refresh", c.analytics.RefreshCache).Methods("POST")
+	api.HandleFunc("/analytics/refresh", c.analytics.GetRefreshStatus).Methods("GET")
+
+	// Product catalog endpoints
+	api.HandleFunc("/products", c.products.ListProducts).Methods("GET")
+	api.HandleFunc("/products/{id}", c.products.GetProduct).Methods("GET")
+	api.HandleFunc("/products/{id}/price-history", c.products.GetPriceHistory).Methods("GET")
+
+	// Inventory endpoints
+	api.HandleFunc("/inventory/sell-through", c.inventory.GetSellThrough).Methods("GET")
+
+	// Filter metadata endpoints
+	api.HandleFunc("/meta/values", c.meta.GetDimensionValues).Methods("GET")
+	api.HandleFunc("/data/profile", c.meta.GetDataProfile).Methods("GET")
+	api.HandleFunc("/data/outliers", c.meta.ListOutliers).Methods("GET")
 
+	// Dataset endpoints: ad-hoc uploads replace the default dataset, and the
+	// named datasets from DATASETS are queried under their ID
+	api.HandleFunc("/datasets", c.datasets.ListDatasets).Methods("GET")
+	api.HandleFunc("/datasets", c.datasets.UploadDataset).Methods("POST")
+	dataset := api.PathPrefix("/datasets/{dataset}").Subrouter()
+	dataset.HandleFunc("/analytics", c.analytics.GetAnalytics).Methods("GET")
+	dataset.HandleFunc("/analytics/country-revenue", c.analytics.GetCountryRevenue).Methods("GET")
+	dataset.HandleFunc("/analytics/top-products", c.analytics.GetTopProducts).Methods("GET")
+	dataset.HandleFunc("/analytics/monthly-sales", c.analytics.GetMonthlySales).Methods("GET")
+	dataset.HandleFunc("/analytics/top-regions", c.analytics.GetTopRegions).Methods("GET")
+	dataset.HandleFunc("/analytics/segments", c.analytics.GetSegments).Methods("GET")
+	dataset.HandleFunc("/analytics/top-customers", c.analytics.GetTopCustomers).Methods("GET")
+	dataset.HandleFunc("/analytics/countries/{country}", c.analytics.GetCountryDetail).Methods("GET")
+
+	// Plan vs actual endpoints
+	api.HandleFunc("/targets", c.targets.UploadTargets).Methods("POST")
+	api.HandleFunc("/analytics/plan-vs-actual", c.targets.GetVariance).Methods("GET")
+
+	// Resumable upload endpoints
+	api.HandleFunc("/uploads", c.uploads.CreateUpload).Methods("POST")
+	api.HandleFunc("/uploads/{id}", c.uploads.GetUpload).Methods("GET", "HEAD")
+	api.HandleFunc("/uploads/{id}", c.uploads.AppendUpload).Methods("PATCH")
+	api.HandleFunc("/uploads/{id}", c.uploads.DeleteUpload).Methods("DELETE")
+
+	// Export job endpoints
+	api.HandleFunc("/exports", c.exports.ListExports).Methods("GET")
+	api.HandleFunc("/exports", c.exports.CreateExport).Methods("POST")
+	api.HandleFunc("/exports/{id}", c.exports.GetExport).Methods("GET")
+	api.HandleFunc("/exports/{id}", c.exports.DeleteExport).Methods("DELETE")
+	api.HandleFunc("/exports/{id}/cancel", c.exports.CancelExport).Methods("POST")
+	api.HandleFunc("/exports/{id}/download", c.exports.DownloadExport).Methods("GET")
+
+	// Report template endpoints
+	api.HandleFunc("/reports", c.reports.ListReports).Methods("GET")
+	api.HandleFunc("/reports/{name}", c.reports.GetReport).Methods("GET")
+
+	// Annotation endpoints
+	api.HandleFunc("/annotations", c.annotations.ListAnnotations).Methods("GET")
+	api.HandleFunc("/annotations", c.annotations.CreateAnnotation).Methods("POST")
+	api.HandleFunc("/annotations/{id}", c.annotations.DeleteAnnotation).Methods("DELETE")
+
+	// Preference endpoints (keyed by caller identity)
+	api.HandleFunc("/preferences", c.preference.GetPreferences).Methods("GET")
+	api.HandleFunc("/preferences", c.preference.UpdatePreferences).Methods("PUT")
+	api.HandleFunc("/preferences", c.preference.DeletePreferences).Methods("DELETE")
+
+	// Derived metric endpoints
+	api.HandleFunc("/metrics", c.metrics.ListMetrics).Methods("GET")
+	api.HandleFunc("/metrics", c.metrics.CreateMetric).Methods("POST")
+	api.HandleFunc("/metrics/{name}", c.metrics.DeleteMetric).Methods("DELETE")
+
+	// Natural-language query endpoint (experimental, off unless NL_QUERY_ENABLED)
+	api.HandleFunc("/query/nl", c.nlQuery.Query).Methods("POST")
+
+	// Alert endpoints
+	api.HandleFunc("/alerts/rules", c.alert.ListRules).Methods("GET")
+	api.HandleFunc("/alerts/rules", c.alert.CreateRule).Methods("POST")
+	api.HandleFunc("/alerts/rules/{id}", c.alert.DeleteRule).Methods("DELETE")
+	api.HandleFunc("/alerts/history", c.alert.ListHistory).Methods("GET")
+
+	// Data job endpoints (refreshes started without waiting)
+	api.HandleFunc("/jobs/{id}", c.job.GetJob).Methods("GET")
+
+	// Automatic refresh schedule
+	api.HandleFunc("/refresh/schedule", c.schedule.GetSchedule).Methods("GET")
+	api.HandleFunc("/refresh/schedule", c.schedule.UpdateSchedule).Methods("PUT")
+
+	// Admin endpoints
+	api.HandleFunc("/admin/backup", c.analytics.BackupData).Methods("POST")
+	api.HandleFunc("/admin/restore", c.analytics.RestoreData).Methods("POST")
+	api.HandleFunc("/admin/rollback", c.analytics.RollbackData).Methods("POST")
+	api.HandleFunc("/admin/stats", c.admin.GetStats).Methods("GET")
+	api.HandleFunc("/admin/audit", c.admin.ListAudit).Methods("GET")
+	api.HandleFunc("/admin/backups", c.admin.ListBackups).Methods("GET")
+	api.HandleFunc("/admin/partitions", c.admin.ListPartitions).Methods("GET")
+	api.HandleFunc("/admin/diagnostics", c.admin.GetDiagnostics).Methods("GET")
+	api.HandleFunc("/admin/config", c.admin.GetConfig).Methods("GET")
+	api.HandleFunc("/admin/debug-capture", c.debugCapture.GetCaptures).Methods("GET")
+	api.HandleFunc("/admin/debug-capture", c.debugCapture.UpdateCapture).Methods("PUT")
+	api.HandleFunc("/admin/debug-capture", c.debugCapture.ClearCaptures).Methods("DELETE")
+	api.HandleFunc("/admin/flags", c.flags.ListFlags).Methods("GET")
+	api.HandleFunc("/admin/flags", c.flags.CreateFlags).Methods("POST")
+	api.HandleFunc("/admin/flags/{id}", c.flags.DeleteFlag).Methods("DELETE")
+
+	// API description for client generators
+	api.HandleFunc("/openapi.json", c.openapi.GetSpec).Methods("GET")
+
+	// Prometheus scrape endpoint, outside /api/v1 like the probes
+	router.Handle("/metrics", c.instruments.Handler()).Methods("GET")
+
+	// Health endpoints; /ready is kept as an alias of the readiness probe
+	router.HandleFunc("/health", c.health.Health).Methods("GET")
+	router.HandleFunc("/health/live", c.health.Live).Methods("GET")
+	router.HandleFunc("/health/ready", c.health.Ready).Methods("GET")
+	router.HandleFunc("/health/startup", c.health.Startup).Methods("GET")
+	router.HandleFunc("/ready", c.health.Ready).Methods("GET")
+
+	warnUndocumentedRoutes(router, log)
 	return router
 }
+
+// warnUndocumentedRoutes logs the routes under /api/v1 that are missing from
+// handlers.RouteDocs, and so from the OpenAPI spec and generated clients
+func warnUndocumentedRoutes(router *mux.Router, log logger.Logger) {
+	for _, route := range undocumentedRoutes(router) {
+		log.Warn("Route missing from the API description", "route", route)
+	}
+}
+
+// undocumentedRoutes returns the routes under /api/v1, as "METHOD template",
+// that have no entry in handlers.RouteDocs
+func undocumentedRoutes(router *mux.Router) []string {
+	var missing []string
+	router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		template, err := route.GetPathTemplate()
+		if err != nil || !strings.HasPrefix(template, "/api/v1/") {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil
+		}
+		for _, method := range methods {
+			if _, ok := handlers.RouteDocs[method+" "+template]; !ok && method != http.MethodHead {
+				missing = append(missing, method+" "+template)
+			}
+		}
+		return nil
+	})
+	return missing
+}
@@ -0,0 +1,17 @@
+package main
+
+import (
+	"testing"
+
+	"analytics-dashboard-api/internal/config"
+	"analytics-dashboard-api/pkg/logger"
+)
+
+// Every route under /api/v1 must be described to API clients. The check
+// walks the router the server builds, so new routes cannot slip past it.
+func TestSetupRouter_RoutesDocumented(t *testing.T) {
+	router := setupRouter(&container{}, "off", 0, config.RateLimitConfig{}, logger.NewLogger("error"))
+	for _, route := range undocumentedRoutes(router) {
+		t.Errorf("%s has no handlers.RouteDocs entry", route)
+	}
+}
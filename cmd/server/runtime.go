@@ -0,0 +1,46 @@
+package main
+
+import (
+	"math"
+	"runtime/debug"
+	"strconv"
+
+	"analytics-dashboard-api/internal/config"
+	"analytics-dashboard-api/pkg/logger"
+)
+
+// ballast is never read: its only job is to count as live heap, which
+// raises the heap size the collector waits for between cycles. Untouched,
+// its pages are never backed by physical memory.
+var ballast []byte
+
+// tuneGC applies the garbage collector settings. It runs before the first
+// load, whose allocations the settings are meant to shape.
+func tuneGC(cfg config.RuntimeConfig, log logger.Logger) {
+	percent := cfg.GCPercent
+	switch percent {
+	case "":
+		percent = "default"
+	case "off":
+		debug.SetGCPercent(-1)
+	default:
+		value, _ := strconv.Atoi(percent) // checked by Validate
+		debug.SetGCPercent(value)
+	}
+	if cfg.MemoryLimit > 0 {
+		debug.SetMemoryLimit(cfg.MemoryLimit)
+	}
+	if cfg.BallastBytes > 0 {
+		ballast = make([]byte, cfg.BallastBytes)
+	}
+
+	// A negative limit reads the current one without changing it
+	limit := debug.SetMemoryLimit(-1)
+	if percent == "off" && limit == math.MaxInt64 {
+		log.Warn("Garbage collection is off without a memory limit: the heap will grow until the process is killed")
+	}
+	log.Info("Garbage collector configured",
+		"gc_percent", percent,
+		"memory_limit", limit,
+		"ballast_bytes", len(ballast))
+}
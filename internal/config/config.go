@@ -5,26 +5,366 @@ import (
 	"os"
 	"strconv"
 	"time"
+
+	"analytics-dashboard-api/internal/cron"
 )
 
 type Config struct {
-	Server ServerConfig
-	CSV    CSVConfig
-	Logger LoggerConfig
+	Server      ServerConfig
+	Auth        AuthConfig
+	RateLimit   RateLimitConfig
+	CSV         CSVConfig
+	Data        DataConfig
+	Dimensions  DimensionsConfig
+	Outliers    OutlierConfig
+	TestOrders  TestOrderConfig
+	DuckDB      DuckDBConfig     `json:"duckdb"`
+	ClickHouse  ClickHouseConfig `json:"clickhouse"`
+	Backup      BackupConfig
+	State       StateConfig
+	Uploads     UploadConfig
+	Exports     ExportConfig
+	SMTP        SMTPConfig
+	Reports     ReportConfig
+	Alerts      AlertsConfig
+	Refresh     RefreshConfig
+	Metrics     MetricsConfig
+	NLQuery     NLQueryConfig
+	ABC         ABCConfig
+	Inventory   InventoryConfig
+	Heatmap     HeatmapConfig
+	Cache       CacheConfig
+	Quotas      QuotaConfig
+	Formatting  FormattingConfig
+	Runtime     RuntimeConfig
+	Diagnostics DiagnosticsConfig
+	Capture     DebugCaptureConfig
+	Logger      LoggerConfig
+
+	// sources records where each variable LoadConfig read came from, for
+	// Effective
+	sources map[string]string
 }
 
 type ServerConfig struct {
-	Host            string
-	Port            int
-	ReadTimeout     time.Duration
-	WriteTimeout    time.Duration
-	IdleTimeout     time.Duration
+	Host         string
+	Port         int
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	IdleTimeout  time.Duration
+	// QueryValidation is off, warn or strict (see middleware.QueryValidation)
+	QueryValidation string
+	// GzipMinBytes is the smallest JSON response gzipped for clients that
+	// accept it; negative turns compression off
+	GzipMinBytes int
+	// Response timeouts of /api/v1 routes, replacing WriteTimeout for them
+	// (see handlers.ResponseTimeouts); zero lifts the limit
+	DataTimeout      time.Duration // reads
+	AdminTimeout     time.Duration // admin routes, writes, downloads and reports
+	TimeoutOverrides string        // "/analytics/refresh=2h;/meta/values=5s"
+}
+
+// AuthConfig lists the API keys accepted on /api/v1 routes; with none
+// configured the API is open
+type AuthConfig struct {
+	APIKeys     string `config:"secret"` // "dashboard=3f9c...;mobile=a71e..."
+	APIKeysFile string // one id=key pair per line, # starting a comment
 }
 
+// RateLimitConfig sets the token bucket each client of /api/v1 draws from:
+// RPS requests per second on average with bursts of up to Burst. Zero RPS
+// turns limiting off.
+type RateLimitConfig struct {
+	RPS   float64
+	Burst int
+}
+
+// CSVConfig locates the transactions source. DATA_FILE_PATH takes
+// precedence over CSV_FILE_PATH; the file may also be Parquet (see
+// DataConfig.Format).
 type CSVConfig struct {
 	FilePath string
 }
 
+type DataConfig struct {
+	// Backend names the registered analytics backend implementation. Empty
+	// selects duckdb when the binary was built with cgo, memory otherwise.
+	Backend string
+	// BackendFallback names the backend to use when Backend fails to
+	// initialize, e.g. memory when the DuckDB driver cannot start. Empty
+	// keeps the server up in a degraded mode that only reports the failure.
+	BackendFallback string
+	// LoadWait is how long a request waits for an in-progress initial load
+	// before it is answered with 503 and Retry-After
+	LoadWait time.Duration
+	// LoadTimeout bounds a single data load; refresh requests may override
+	// it with the X-Refresh-Timeout header
+	LoadTimeout time.Duration
+	// Format of the transactions files: csv, parquet, jsonl, or auto to tell
+	// them apart by extension
+	Format string
+	// WatchInterval is how often the data file is checked for changes to
+	// reload; zero disables watching. A change is only loaded once the file
+	// has stayed the same for WatchDebounce, so a file still being written is
+	// not read half-way.
+	WatchInterval time.Duration
+	WatchDebounce time.Duration
+	// DateLayouts lists the Go time layouts of source dates, separated by
+	// ";" and tried in order; empty tries the built-in layouts
+	DateLayouts string
+	// ColumnMap maps transactions columns to the source columns they are
+	// read from, as "column=source" pairs separated by ";", applied over
+	// the YAML file at ColumnMapFile (see services.LoadColumnMapping)
+	ColumnMap     string
+	ColumnMapFile string
+	// Datasets lists named sources served next to DATA_FILE_PATH as
+	// "id=path" pairs separated by ";" (see services.ParseDatasets)
+	Datasets string
+	// Remote holds the credentials for s3:// and gs:// data paths
+	Remote RemoteSourceConfig
+}
+
+// RemoteSourceConfig sets how data paths in object storage are fetched.
+// Objects are downloaded to CacheDir before each load; empty credentials
+// fetch them anonymously, as from a public bucket.
+type RemoteSourceConfig struct {
+	CacheDir string
+	Timeout  time.Duration // bounds each download, within the load timeout
+
+	S3Region          string
+	S3Endpoint        string // path-style endpoint for S3-compatible stores; empty uses AWS
+	S3AccessKeyID     string
+	S3SecretAccessKey string `config:"secret"`
+	S3SessionToken    string `config:"secret"`
+
+	GCSEndpoint    string
+	GCSAccessKeyID string // HMAC key of the Cloud Storage interoperability API
+	GCSSecret      string `config:"secret"`
+}
+
+// DimensionsConfig lists optional dimension tables loaded alongside transactions.
+// Missing files are skipped.
+type DimensionsConfig struct {
+	ProductsFilePath  string
+	CustomersFilePath string
+	StrictReferences  bool // fail loads with transactions referencing unknown keys
+}
+
+// OutlierConfig flags transactions with implausible prices or quantities on
+// load. A value is an outlier above Factor times the Percentile of its column,
+// or above the absolute maximum; zero disables either limit.
+type OutlierConfig struct {
+	Mode        string  // off, tag (keep and list the rows) or exclude (drop them)
+	Percentile  float64 // e.g. 0.99
+	Factor      float64
+	MaxPrice    float64
+	MaxQuantity int
+}
+
+// TestOrderConfig recognises QA orders in the source files so loads can
+// leave them out. Both settings are comma-separated lists; a transaction is a
+// test order when either matches.
+type TestOrderConfig struct {
+	UserPatterns    string // user_id patterns, * matching any run of characters and ? one, e.g. "qa-*,test?"
+	ProductPrefixes string // product_id prefixes, e.g. "TEST-"
+}
+
+// DuckDBConfig holds DuckDB resource settings applied at startup.
+// Empty/zero values keep DuckDB's own defaults.
+type DuckDBConfig struct {
+	// Path is the database file; empty keeps the database in memory, so
+	// every start loads the source files again
+	Path          string
+	MemoryLimit   string // e.g. "4GB"; aggregations spill to disk beyond this
+	Threads       int
+	TempDirectory string // spill location for larger-than-memory operations
+	// Optimize inserts transactions in date order and refreshes the table
+	// statistics after each load, so date-range queries skip row groups
+	Optimize bool
+	// QueryTimeout bounds each analytics query, within the response
+	// timeout of its route; zero leaves only the response timeout
+	QueryTimeout time.Duration
+}
+
+// ClickHouseConfig points the clickhouse backend at existing tables, queried
+// over the ClickHouse HTTP interface
+type ClickHouseConfig struct {
+	URL               string
+	Database          string
+	User              string
+	Password          string `config:"secret"`
+	TransactionsTable string
+	ProductsTable     string
+	CustomersTable    string
+	Timeout           time.Duration
+}
+
+// BackupConfig controls where Parquet snapshots are written
+type BackupConfig struct {
+	Dir       string
+	OnRefresh bool // take a snapshot after every successful refresh
+	// OpenSnapshots is how many backups ?as_of= queries keep restored
+	OpenSnapshots int
+	// KeepLast and KeepMonthly bound the backups retained; zero for both keeps all
+	KeepLast      int
+	KeepMonthly   int           // the newest backup of each of this many months
+	PruneInterval time.Duration // zero disables scheduled pruning
+}
+
+// StateConfig locates small JSON stores (annotations, etc.) persisted across restarts
+type StateConfig struct {
+	Dir string
+}
+
+// UploadConfig controls resumable upload sessions
+type UploadConfig struct {
+	Dir          string
+	MaxBytes     int64
+	SessionTTL   time.Duration // sessions idle for longer are discarded
+	ChunkTimeout time.Duration // how long a single chunk may take to arrive
+
+	// Every uploaded file is scanned before ingestion
+	AllowedExtensions string        // ".csv,.txt"; empty allows any file name
+	AllowedTypes      string        // sniffed MIME types; empty allows any content
+	ClamAVAddress     string        `json:"clamav_address"` // clamd socket, "unix:/path" or "tcp:host:port"; empty skips the virus scan
+	ClamAVTimeout     time.Duration `json:"clamav_timeout"`
+}
+
+// ExportConfig controls background export jobs
+type ExportConfig struct {
+	Dir          string
+	MaxAttempts  int           // attempts per export, retrying transient failures
+	RetryBackoff time.Duration // wait before the first retry, doubled for each later one
+	TTL          time.Duration // finished exports and their files are discarded after this
+
+	// Emailed exports larger than MaxAttachmentBytes are sent as a signed
+	// download link under PublicURL instead
+	PublicURL          string
+	MaxAttachmentBytes int64
+}
+
+// SMTPConfig is the mail server exports are emailed through; an empty host
+// disables email delivery
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string `config:"secret"`
+	From     string
+	Timeout  time.Duration // for a whole delivery, attachment included
+}
+
+// ReportConfig locates YAML report templates, re-read on every request so
+// they can change without a restart
+type ReportConfig struct {
+	TemplateDir string
+	// PDFCommand converts HTML on stdin to PDF on stdout, e.g.
+	// "wkhtmltopdf --quiet - -"; empty disables PDF rendering
+	PDFCommand string
+	PDFTimeout time.Duration
+}
+
+// AlertsConfig controls scheduled alert rule evaluation and webhook delivery
+type AlertsConfig struct {
+	EvaluationInterval time.Duration // zero disables scheduled evaluation
+	WebhookTimeout     time.Duration
+}
+
+// RefreshConfig schedules automatic data refreshes. The schedule can be
+// changed at runtime through the API until the next restart.
+type RefreshConfig struct {
+	Cron     string // five-field cron expression; empty disables scheduled refreshes
+	Timezone string // IANA name the schedule is read in
+}
+
+// MetricsConfig lists derived metrics available without API registration
+type MetricsConfig struct {
+	Derived string // "aov=revenue/transactions;margin=revenue*0.27"
+}
+
+// NLQueryConfig guards the experimental natural-language query endpoint
+type NLQueryConfig struct {
+	Enabled bool
+}
+
+// ABCConfig sets the default cumulative revenue shares closing the A and B
+// tiers of the ABC product classification
+type ABCConfig struct {
+	ACutoff float64 // e.g. 0.8: products making up the first 80% of revenue are A
+	BCutoff float64 // e.g. 0.95: the next products up to 95% are B, the rest C
+}
+
+// InventoryConfig sets the default window of the inventory metrics
+type InventoryConfig struct {
+	SellThroughWindowDays int // days of sales, up to the latest transaction, counted for sell-through
+}
+
+// HeatmapConfig sets the default grid of the regional heatmap
+type HeatmapConfig struct {
+	TopCountries int // countries shown, by revenue; the rest are rolled into "Other"
+	TopRegions   int // regions shown per country, by revenue; the rest are rolled into "Other"
+}
+
+// CacheConfig sizes the response cache and sets how long each class of
+// endpoint may be served from it
+type CacheConfig struct {
+	MaxEntries    int           // zero disables the cache
+	KPITTL        time.Duration `json:"kpi_ttl"` // headline figures: summary, stats, rankings
+	HistoricalTTL time.Duration // monthly series and plan-vs-actual
+	ReferenceTTL  time.Duration // product catalog and filter values
+	// Overrides sets TTLs for single routes ahead of their class, e.g.
+	// "/analytics/top-products=5m;/meta/values=0"
+	Overrides string
+}
+
+// QuotaLimit caps the rows and bytes a single load may bring in; zero
+// leaves a measure unlimited
+type QuotaLimit struct {
+	MaxRows  int64
+	MaxBytes int64
+}
+
+// QuotaConfig limits how much each dataset, and each tenant (the caller's
+// X-User-ID), may load into memory
+type QuotaConfig struct {
+	Transactions QuotaLimit
+	Targets      QuotaLimit
+	Tenant       QuotaLimit // applies to every tenant without an override
+	// Tenants overrides the tenant limit for single tenants, e.g.
+	// "acme-uk=rows:500000,bytes:200000000;acme-de=rows:0"
+	Tenants string
+}
+
+// FormattingConfig controls how values are rendered in responses
+type FormattingConfig struct {
+	MoneyFormat     string // number, string or cents
+	DefaultCurrency string // ISO 4217 code used for ?locale= display strings
+}
+
+// RuntimeConfig tunes the Go garbage collector at startup. Empty/zero values
+// keep the runtime's own settings, including GOGC and GOMEMLIMIT from the
+// environment. DuckDB's memory is not on the Go heap; see
+// DuckDBConfig.MemoryLimit for it.
+type RuntimeConfig struct {
+	GCPercent    string // "off" or a percentage, as for GOGC
+	MemoryLimit  int64  // soft Go heap limit in bytes, as for GOMEMLIMIT
+	BallastBytes int64  // heap allocated up front to space out collections
+}
+
+// DiagnosticsConfig sets the thresholds of the self-diagnostics checks
+type DiagnosticsConfig struct {
+	MinFreeBytes int64 // free disk space below this fails a data path's check
+}
+
+// DebugCaptureConfig sets up the capture of failed requests for debugging.
+// Enabled and SampleRate are only the initial setting; both can be changed
+// at runtime through the admin API.
+type DebugCaptureConfig struct {
+	Enabled      bool
+	SampleRate   float64 // share of requests timed and captured if they fail
+	Size         int     // failed exchanges kept, the oldest dropped first
+	MaxBodyBytes int64   // request and response bodies are cut at this size
+}
 
 type LoggerConfig struct {
 	Level string
@@ -32,6 +372,11 @@ type LoggerConfig struct {
 
 // LoadConfig loads configuration from environment variables with defaults
 func LoadConfig() (*Config, error) {
+	loadMu.Lock()
+	defer loadMu.Unlock()
+	sources = map[string]string{}
+	defer func() { sources = nil }()
+
 	config := &Config{
 		Server: ServerConfig{
 			Host:            getEnv("SERVER_HOST", "localhost"),
@@ -39,13 +384,201 @@ func LoadConfig() (*Config, error) {
 			ReadTimeout:     getEnvAsDuration("SERVER_READ_TIMEOUT", "15s"),
 			WriteTimeout:    getEnvAsDuration("SERVER_WRITE_TIMEOUT", "15s"),
 			IdleTimeout:     getEnvAsDuration("SERVER_IDLE_TIMEOUT", "60s"),
+			QueryValidation: getEnv("QUERY_VALIDATION", "warn"),
+			GzipMinBytes:    getEnvAsInt("GZIP_MIN_BYTES", 1024),
+
+			DataTimeout:      getEnvAsDuration("RESPONSE_TIMEOUT_DATA", "30s"),
+			AdminTimeout:     getEnvAsDuration("RESPONSE_TIMEOUT_ADMIN", "30m"),
+			TimeoutOverrides: getEnv("RESPONSE_TIMEOUT_OVERRIDES", ""),
+		},
+		Auth: AuthConfig{
+			APIKeys:     getEnv("API_KEYS", ""),
+			APIKeysFile: getEnv("API_KEYS_FILE", ""),
+		},
+		RateLimit: RateLimitConfig{
+			RPS:   getEnvAsFloat("RATE_LIMIT_RPS", 0),
+			Burst: getEnvAsInt("RATE_LIMIT_BURST", 20),
 		},
 		CSV: CSVConfig{
-			FilePath: getEnv("CSV_FILE_PATH", "./data/raw/transactions.csv"),
+			FilePath: getEnv("DATA_FILE_PATH", getEnv("CSV_FILE_PATH", "./data/raw/transactions.csv")),
+		},
+		Data: DataConfig{
+			Backend:         getEnv("DATA_BACKEND", ""),
+			BackendFallback: getEnv("DATA_BACKEND_FALLBACK", ""),
+			LoadWait:        getEnvAsDuration("DATA_LOAD_WAIT", "2s"),
+			LoadTimeout:     getEnvAsDuration("DATA_LOAD_TIMEOUT", "30m"),
+			Format:          getEnv("DATA_FORMAT", "auto"),
+
+			WatchInterval: getEnvAsDuration("DATA_WATCH_INTERVAL", "0"),
+			WatchDebounce: getEnvAsDuration("DATA_WATCH_DEBOUNCE", "2s"),
+			DateLayouts:   getEnv("DATA_DATE_LAYOUTS", ""),
+			ColumnMap:     getEnv("DATA_COLUMN_MAP", ""),
+			ColumnMapFile: getEnv("DATA_COLUMN_MAP_FILE", ""),
+
+			Datasets: getEnv("DATASETS", ""),
+			Remote: RemoteSourceConfig{
+				CacheDir: getEnv("DATA_REMOTE_CACHE_DIR", "./data/remote"),
+				Timeout:  getEnvAsDuration("DATA_REMOTE_TIMEOUT", "10m"),
+
+				S3Region:          getEnv("AWS_REGION", getEnv("AWS_DEFAULT_REGION", "us-east-1")),
+				S3Endpoint:        getEnv("S3_ENDPOINT", ""),
+				S3AccessKeyID:     getEnv("AWS_ACCESS_KEY_ID", ""),
+				S3SecretAccessKey: getEnv("AWS_SECRET_ACCESS_KEY", ""),
+				S3SessionToken:    getEnv("AWS_SESSION_TOKEN", ""),
+
+				GCSEndpoint:    getEnv("GCS_ENDPOINT", "https://storage.googleapis.com"),
+				GCSAccessKeyID: getEnv("GCS_HMAC_ACCESS_KEY_ID", ""),
+				GCSSecret:      getEnv("GCS_HMAC_SECRET", ""),
+			},
+		},
+		Dimensions: DimensionsConfig{
+			ProductsFilePath:  getEnv("PRODUCTS_FILE_PATH", "./data/raw/products.csv"),
+			CustomersFilePath: getEnv("CUSTOMERS_FILE_PATH", "./data/raw/customers.csv"),
+			StrictReferences:  getEnvAsBool("STRICT_REFERENCES", false),
+		},
+		Outliers: OutlierConfig{
+			Mode:        getEnv("OUTLIER_MODE", "tag"),
+			Percentile:  getEnvAsFloat("OUTLIER_PERCENTILE", 0.99),
+			Factor:      getEnvAsFloat("OUTLIER_FACTOR", 10),
+			MaxPrice:    getEnvAsFloat("OUTLIER_MAX_PRICE", 0),
+			MaxQuantity: getEnvAsInt("OUTLIER_MAX_QUANTITY", 0),
+		},
+		TestOrders: TestOrderConfig{
+			UserPatterns:    getEnv("TEST_ORDER_USER_PATTERNS", ""),
+			ProductPrefixes: getEnv("TEST_ORDER_PRODUCT_PREFIXES", ""),
+		},
+		DuckDB: DuckDBConfig{
+			Path:          getEnv("DUCKDB_PATH", ""),
+			MemoryLimit:   getEnv("DUCKDB_MEMORY_LIMIT", ""),
+			Threads:       getEnvAsInt("DUCKDB_THREADS", 0),
+			TempDirectory: getEnv("DUCKDB_TEMP_DIRECTORY", ""),
+			Optimize:      getEnvAsBool("DUCKDB_OPTIMIZE", true),
+			QueryTimeout:  getEnvAsDuration("QUERY_TIMEOUT", "0"),
+		},
+		ClickHouse: ClickHouseConfig{
+			URL:               getEnv("CLICKHOUSE_URL", "http://localhost:8123"),
+			Database:          getEnv("CLICKHOUSE_DATABASE", "default"),
+			User:              getEnv("CLICKHOUSE_USER", "default"),
+			Password:          getEnv("CLICKHOUSE_PASSWORD", ""),
+			TransactionsTable: getEnv("CLICKHOUSE_TRANSACTIONS_TABLE", "transactions"),
+			ProductsTable:     getEnv("CLICKHOUSE_PRODUCTS_TABLE", "products"),
+			CustomersTable:    getEnv("CLICKHOUSE_CUSTOMERS_TABLE", "customers"),
+			Timeout:           getEnvAsDuration("CLICKHOUSE_TIMEOUT", "30s"),
+		},
+		Backup: BackupConfig{
+			Dir:       getEnv("BACKUP_DIR", "./data/backups"),
+			OnRefresh: getEnvAsBool("BACKUP_ON_REFRESH", false),
+
+			OpenSnapshots: getEnvAsInt("BACKUP_OPEN_SNAPSHOTS", 2),
+			KeepLast:      getEnvAsInt("BACKUP_KEEP_LAST", 0),
+			KeepMonthly:   getEnvAsInt("BACKUP_KEEP_MONTHLY", 0),
+			PruneInterval: getEnvAsDuration("BACKUP_PRUNE_INTERVAL", "1h"),
+		},
+		State: StateConfig{
+			Dir: getEnv("STATE_DIR", "./data/state"),
+		},
+		Uploads: UploadConfig{
+			Dir:          getEnv("UPLOAD_DIR", "./data/uploads"),
+			MaxBytes:     getEnvAsInt64("UPLOAD_MAX_BYTES", 16<<30),
+			SessionTTL:   getEnvAsDuration("UPLOAD_SESSION_TTL", "24h"),
+			ChunkTimeout: getEnvAsDuration("UPLOAD_CHUNK_TIMEOUT", "10m"),
+
+			AllowedExtensions: getEnv("UPLOAD_ALLOWED_EXTENSIONS", ".csv"),
+			AllowedTypes:      getEnv("UPLOAD_ALLOWED_TYPES", "text/plain,text/csv"),
+			ClamAVAddress:     getEnv("UPLOAD_CLAMAV_ADDRESS", ""),
+			ClamAVTimeout:     getEnvAsDuration("UPLOAD_CLAMAV_TIMEOUT", "5m"),
+		},
+		Exports: ExportConfig{
+			Dir:          getEnv("EXPORT_DIR", "./data/exports"),
+			MaxAttempts:  getEnvAsInt("EXPORT_MAX_ATTEMPTS", 3),
+			RetryBackoff: getEnvAsDuration("EXPORT_RETRY_BACKOFF", "5s"),
+			TTL:          getEnvAsDuration("EXPORT_TTL", "24h"),
+
+			PublicURL:          getEnv("EXPORT_PUBLIC_URL", ""),
+			MaxAttachmentBytes: getEnvAsInt64("EXPORT_MAX_ATTACHMENT_BYTES", 10<<20),
+		},
+		SMTP: SMTPConfig{
+			Host:     getEnv("SMTP_HOST", ""),
+			Port:     getEnvAsInt("SMTP_PORT", 587),
+			Username: getEnv("SMTP_USERNAME", ""),
+			Password: getEnv("SMTP_PASSWORD", ""),
+			From:     getEnv("SMTP_FROM", ""),
+			Timeout:  getEnvAsDuration("SMTP_TIMEOUT", "5m"),
+		},
+		Reports: ReportConfig{
+			TemplateDir: getEnv("REPORT_TEMPLATE_DIR", "./reports"),
+			PDFCommand:  getEnv("REPORT_PDF_COMMAND", ""),
+			PDFTimeout:  getEnvAsDuration("REPORT_PDF_TIMEOUT", "1m"),
+		},
+		Alerts: AlertsConfig{
+			EvaluationInterval: getEnvAsDuration("ALERT_EVALUATION_INTERVAL", "5m"),
+			WebhookTimeout:     getEnvAsDuration("ALERT_WEBHOOK_TIMEOUT", "10s"),
+		},
+		Refresh: RefreshConfig{
+			Cron:     getEnv("REFRESH_CRON", ""),
+			Timezone: getEnv("REFRESH_TIMEZONE", "UTC"),
+		},
+		Metrics: MetricsConfig{
+			Derived: getEnv("DERIVED_METRICS", ""),
+		},
+		NLQuery: NLQueryConfig{
+			Enabled: getEnvAsBool("NL_QUERY_ENABLED", false),
+		},
+		ABC: ABCConfig{
+			ACutoff: getEnvAsFloat("ABC_A_CUTOFF", 0.8),
+			BCutoff: getEnvAsFloat("ABC_B_CUTOFF", 0.95),
+		},
+		Inventory: InventoryConfig{
+			SellThroughWindowDays: getEnvAsInt("SELL_THROUGH_WINDOW_DAYS", 90),
+		},
+		Heatmap: HeatmapConfig{
+			TopCountries: getEnvAsInt("HEATMAP_TOP_COUNTRIES", 20),
+			TopRegions:   getEnvAsInt("HEATMAP_TOP_REGIONS", 10),
+		},
+		Cache: CacheConfig{
+			MaxEntries:    getEnvAsInt("CACHE_MAX_ENTRIES", 1000),
+			KPITTL:        getEnvAsDuration("CACHE_TTL_KPI", "1m"),
+			HistoricalTTL: getEnvAsDuration("CACHE_TTL_HISTORICAL", "1h"),
+			ReferenceTTL:  getEnvAsDuration("CACHE_TTL_REFERENCE", "10m"),
+			Overrides:     getEnv("CACHE_TTL_OVERRIDES", ""),
+		},
+		Quotas: QuotaConfig{
+			Transactions: QuotaLimit{
+				MaxRows:  getEnvAsInt64("QUOTA_TRANSACTIONS_MAX_ROWS", 0),
+				MaxBytes: getEnvAsInt64("QUOTA_TRANSACTIONS_MAX_BYTES", 0),
+			},
+			Targets: QuotaLimit{
+				MaxRows:  getEnvAsInt64("QUOTA_TARGETS_MAX_ROWS", 0),
+				MaxBytes: getEnvAsInt64("QUOTA_TARGETS_MAX_BYTES", 0),
+			},
+			Tenant: QuotaLimit{
+				MaxRows:  getEnvAsInt64("QUOTA_TENANT_MAX_ROWS", 0),
+				MaxBytes: getEnvAsInt64("QUOTA_TENANT_MAX_BYTES", 0),
+			},
+			Tenants: getEnv("QUOTA_TENANTS", ""),
+		},
+		Formatting: FormattingConfig{
+			MoneyFormat:     getEnv("MONEY_FORMAT", "number"),
+			DefaultCurrency: getEnv("DEFAULT_CURRENCY", "USD"),
+		},
+		Runtime: RuntimeConfig{
+			GCPercent:    getEnv("GC_PERCENT", ""),
+			MemoryLimit:  getEnvAsInt64("GC_MEMORY_LIMIT_BYTES", 0),
+			BallastBytes: getEnvAsInt64("GC_BALLAST_BYTES", 0),
+		},
+		Diagnostics: DiagnosticsConfig{
+			MinFreeBytes: getEnvAsInt64("DIAGNOSTICS_MIN_FREE_BYTES", 1<<30),
+		},
+		Capture: DebugCaptureConfig{
+			Enabled:      getEnvAsBool("DEBUG_CAPTURE_ENABLED", false),
+			SampleRate:   getEnvAsFloat("DEBUG_CAPTURE_SAMPLE_RATE", 1),
+			Size:         getEnvAsInt("DEBUG_CAPTURE_SIZE", 50),
+			MaxBodyBytes: getEnvAsInt64("DEBUG_CAPTURE_MAX_BODY_BYTES", 64<<10),
 		},
 		Logger: LoggerConfig{
 			Level: getEnv("LOG_LEVEL", "info"),
 		},
+		sources: sources,
 	}
 
 	if err := config.Validate(); err != nil {
@@ -61,10 +594,237 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("invalid server port: %d", c.Server.Port)
 	}
 
+	if c.Server.DataTimeout < 0 || c.Server.AdminTimeout < 0 {
+		return fmt.Errorf("invalid response timeouts: data %s, admin %s", c.Server.DataTimeout, c.Server.AdminTimeout)
+	}
+
+	switch c.Server.QueryValidation {
+	case "off", "warn", "strict":
+	default:
+		return fmt.Errorf("invalid query validation mode: %s", c.Server.QueryValidation)
+	}
+
+	if c.RateLimit.RPS < 0 {
+		return fmt.Errorf("invalid rate limit: %g requests per second", c.RateLimit.RPS)
+	}
+	if c.RateLimit.RPS > 0 && c.RateLimit.Burst < 1 {
+		return fmt.Errorf("invalid rate limit burst: %d", c.RateLimit.Burst)
+	}
+
 	if c.CSV.FilePath == "" {
-		return fmt.Errorf("CSV file path is required")
+		return fmt.Errorf("data file path is required")
+	}
+
+	if c.Data.LoadWait < 0 {
+		return fmt.Errorf("invalid data load wait: %s", c.Data.LoadWait)
+	}
+	if c.Data.LoadTimeout <= 0 {
+		return fmt.Errorf("invalid data load timeout: %s", c.Data.LoadTimeout)
+	}
+	switch c.Data.Format {
+	case "auto", "csv", "parquet", "jsonl":
+	default:
+		return fmt.Errorf("invalid data format: %s", c.Data.Format)
+	}
+	if c.Data.WatchInterval < 0 {
+		return fmt.Errorf("invalid data watch interval: %s", c.Data.WatchInterval)
+	}
+	if c.Data.WatchDebounce < 0 {
+		return fmt.Errorf("invalid data watch debounce: %s", c.Data.WatchDebounce)
+	}
+	if c.Data.Remote.Timeout <= 0 {
+		return fmt.Errorf("invalid remote data timeout: %s", c.Data.Remote.Timeout)
+	}
+	if (c.Data.Remote.S3AccessKeyID == "") != (c.Data.Remote.S3SecretAccessKey == "") {
+		return fmt.Errorf("S3 credentials need both an access key ID and a secret access key")
+	}
+	if (c.Data.Remote.GCSAccessKeyID == "") != (c.Data.Remote.GCSSecret == "") {
+		return fmt.Errorf("GCS credentials need both an HMAC access key ID and a secret")
+	}
+
+	if c.Data.BackendFallback != "" && c.Data.BackendFallback == c.Data.Backend {
+		return fmt.Errorf("DATA_BACKEND_FALLBACK must name a different backend than DATA_BACKEND")
+	}
+
+	if c.Data.Backend == "clickhouse" || c.Data.BackendFallback == "clickhouse" {
+		if c.ClickHouse.URL == "" {
+			return fmt.Errorf("ClickHouse URL is required")
+		}
+		if c.ClickHouse.Timeout <= 0 {
+			return fmt.Errorf("invalid ClickHouse timeout: %s", c.ClickHouse.Timeout)
+		}
+	}
+
+	switch c.Outliers.Mode {
+	case "off", "tag", "exclude":
+	default:
+		return fmt.Errorf("invalid outlier mode: %s", c.Outliers.Mode)
+	}
+	if c.Outliers.Percentile < 0 || c.Outliers.Percentile >= 1 {
+		return fmt.Errorf("invalid outlier percentile: %g", c.Outliers.Percentile)
+	}
+	if c.Outliers.Percentile > 0 && c.Outliers.Factor <= 0 {
+		return fmt.Errorf("invalid outlier factor: %g", c.Outliers.Factor)
+	}
+	if c.Outliers.MaxPrice < 0 || c.Outliers.MaxQuantity < 0 {
+		return fmt.Errorf("outlier maximums must not be negative")
+	}
+
+	if c.DuckDB.Threads < 0 {
+		return fmt.Errorf("invalid DuckDB threads: %d", c.DuckDB.Threads)
+	}
+	if c.DuckDB.QueryTimeout < 0 {
+		return fmt.Errorf("invalid query timeout: %s", c.DuckDB.QueryTimeout)
+	}
+
+	if c.Backup.Dir == "" {
+		return fmt.Errorf("backup directory is required")
+	}
+	if c.Backup.OpenSnapshots < 1 {
+		return fmt.Errorf("invalid open snapshot count: %d", c.Backup.OpenSnapshots)
+	}
+	if c.Backup.KeepLast < 0 {
+		return fmt.Errorf("invalid backup keep-last count: %d", c.Backup.KeepLast)
+	}
+	if c.Backup.KeepMonthly < 0 {
+		return fmt.Errorf("invalid backup keep-monthly count: %d", c.Backup.KeepMonthly)
+	}
+	if c.Backup.PruneInterval < 0 {
+		return fmt.Errorf("invalid backup prune interval: %s", c.Backup.PruneInterval)
+	}
+
+	if c.State.Dir == "" {
+		return fmt.Errorf("state directory is required")
+	}
+
+	if c.Uploads.Dir == "" {
+		return fmt.Errorf("upload directory is required")
+	}
+	if c.Uploads.MaxBytes <= 0 {
+		return fmt.Errorf("invalid upload max bytes: %d", c.Uploads.MaxBytes)
+	}
+	if c.Uploads.SessionTTL <= 0 {
+		return fmt.Errorf("invalid upload session TTL: %s", c.Uploads.SessionTTL)
+	}
+	if c.Uploads.ChunkTimeout <= 0 {
+		return fmt.Errorf("invalid upload chunk timeout: %s", c.Uploads.ChunkTimeout)
+	}
+	if c.Uploads.ClamAVAddress != "" && c.Uploads.ClamAVTimeout <= 0 {
+		return fmt.Errorf("invalid ClamAV timeout: %s", c.Uploads.ClamAVTimeout)
+	}
+
+	if c.Exports.Dir == "" {
+		return fmt.Errorf("export directory is required")
+	}
+	if c.Exports.MaxAttempts < 1 {
+		return fmt.Errorf("invalid export max attempts: %d", c.Exports.MaxAttempts)
+	}
+	if c.Exports.RetryBackoff < 0 {
+		return fmt.Errorf("invalid export retry backoff: %s", c.Exports.RetryBackoff)
+	}
+	if c.Exports.TTL <= 0 {
+		return fmt.Errorf("invalid export TTL: %s", c.Exports.TTL)
+	}
+	if c.Exports.MaxAttachmentBytes < 0 {
+		return fmt.Errorf("invalid export max attachment bytes: %d", c.Exports.MaxAttachmentBytes)
+	}
+
+	if c.SMTP.Host != "" {
+		if c.SMTP.Port <= 0 || c.SMTP.Port > 65535 {
+			return fmt.Errorf("invalid SMTP port: %d", c.SMTP.Port)
+		}
+		if c.SMTP.From == "" {
+			return fmt.Errorf("SMTP sender address is required when SMTP_HOST is set")
+		}
+		if c.SMTP.Timeout <= 0 {
+			return fmt.Errorf("invalid SMTP timeout: %s", c.SMTP.Timeout)
+		}
+	}
+
+	if c.Reports.TemplateDir == "" {
+		return fmt.Errorf("report template directory is required")
+	}
+	if c.Reports.PDFTimeout <= 0 {
+		return fmt.Errorf("invalid report PDF timeout: %s", c.Reports.PDFTimeout)
 	}
 
+	if c.ABC.ACutoff <= 0 || c.ABC.ACutoff >= c.ABC.BCutoff || c.ABC.BCutoff > 1 {
+		return fmt.Errorf("invalid ABC cut-offs %g and %g: want 0 < A < B <= 1", c.ABC.ACutoff, c.ABC.BCutoff)
+	}
+
+	if c.Inventory.SellThroughWindowDays <= 0 {
+		return fmt.Errorf("invalid sell-through window: %d days", c.Inventory.SellThroughWindowDays)
+	}
+
+	if c.Heatmap.TopCountries <= 0 || c.Heatmap.TopRegions <= 0 {
+		return fmt.Errorf("invalid heatmap grid: %d countries by %d regions", c.Heatmap.TopCountries, c.Heatmap.TopRegions)
+	}
+
+	if c.Cache.MaxEntries < 0 {
+		return fmt.Errorf("invalid cache max entries: %d", c.Cache.MaxEntries)
+	}
+	if c.Cache.KPITTL < 0 || c.Cache.HistoricalTTL < 0 || c.Cache.ReferenceTTL < 0 {
+		return fmt.Errorf("invalid cache TTLs: kpi %s, historical %s, reference %s",
+			c.Cache.KPITTL, c.Cache.HistoricalTTL, c.Cache.ReferenceTTL)
+	}
+
+	for name, limit := range map[string]QuotaLimit{
+		"transactions": c.Quotas.Transactions,
+		"targets":      c.Quotas.Targets,
+		"tenant":       c.Quotas.Tenant,
+	} {
+		if limit.MaxRows < 0 || limit.MaxBytes < 0 {
+			return fmt.Errorf("invalid %s quota: %d rows, %d bytes", name, limit.MaxRows, limit.MaxBytes)
+		}
+	}
+
+	switch c.Formatting.MoneyFormat {
+	case "number", "string", "cents":
+	default:
+		return fmt.Errorf("invalid money format: %s", c.Formatting.MoneyFormat)
+	}
+
+	if len(c.Formatting.DefaultCurrency) != 3 {
+		return fmt.Errorf("invalid default currency: %s", c.Formatting.DefaultCurrency)
+	}
+
+	if c.Alerts.EvaluationInterval < 0 {
+		return fmt.Errorf("invalid alert evaluation interval: %s", c.Alerts.EvaluationInterval)
+	}
+
+	if c.Alerts.WebhookTimeout <= 0 {
+		return fmt.Errorf("invalid alert webhook timeout: %s", c.Alerts.WebhookTimeout)
+	}
+
+	if c.Refresh.Cron != "" {
+		if _, err := cron.Parse(c.Refresh.Cron); err != nil {
+			return fmt.Errorf("invalid refresh schedule: %w", err)
+		}
+	}
+	if _, err := time.LoadLocation(c.Refresh.Timezone); err != nil {
+		return fmt.Errorf("invalid refresh timezone: %s", c.Refresh.Timezone)
+	}
+
+	if c.Runtime.GCPercent != "" && c.Runtime.GCPercent != "off" {
+		if percent, err := strconv.Atoi(c.Runtime.GCPercent); err != nil || percent < 0 {
+			return fmt.Errorf("invalid GC percent: %s", c.Runtime.GCPercent)
+		}
+	}
+
+	if c.Runtime.MemoryLimit < 0 || c.Runtime.BallastBytes < 0 {
+		return fmt.Errorf("invalid GC memory settings: limit %d, ballast %d", c.Runtime.MemoryLimit, c.Runtime.BallastBytes)
+	}
+
+	if c.Diagnostics.MinFreeBytes < 0 {
+		return fmt.Errorf("invalid diagnostics minimum free bytes: %d", c.Diagnostics.MinFreeBytes)
+	}
+
+	if c.Capture.SampleRate < 0 || c.Capture.SampleRate > 1 {
+		return fmt.Errorf("invalid debug capture sample rate: %v (want 0 to 1)", c.Capture.SampleRate)
+	}
+	if c.Capture.Size <= 0 || c.Capture.MaxBodyBytes < 0 {
+		return fmt.Errorf("invalid debug capture size: %d exchanges of up to %d bytes", c.Capture.Size, c.Capture.MaxBodyBytes)
+	}
 
 	return nil
 }
@@ -72,25 +832,74 @@ func (c *Config) Validate() error {
 // Helper functions for environment variable parsing
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
+		recordSource(key, SourceEnv)
 		return value
 	}
+	recordSource(key, SourceDefault)
 	return defaultValue
 }
 
 func getEnvAsInt(key string, defaultValue int) int {
 	if value := os.Getenv(key); value != "" {
 		if intValue, err := strconv.Atoi(value); err == nil {
+			recordSource(key, SourceEnv)
+			return intValue
+		}
+		recordSource(key, SourceInvalid)
+		return defaultValue
+	}
+	recordSource(key, SourceDefault)
+	return defaultValue
+}
+
+func getEnvAsInt64(key string, defaultValue int64) int64 {
+	if value := os.Getenv(key); value != "" {
+		if intValue, err := strconv.ParseInt(value, 10, 64); err == nil {
+			recordSource(key, SourceEnv)
 			return intValue
 		}
+		recordSource(key, SourceInvalid)
+		return defaultValue
+	}
+	recordSource(key, SourceDefault)
+	return defaultValue
+}
+
+func getEnvAsFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if floatValue, err := strconv.ParseFloat(value, 64); err == nil {
+			recordSource(key, SourceEnv)
+			return floatValue
+		}
+		recordSource(key, SourceInvalid)
+		return defaultValue
+	}
+	recordSource(key, SourceDefault)
+	return defaultValue
+}
+
+func getEnvAsBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolValue, err := strconv.ParseBool(value); err == nil {
+			recordSource(key, SourceEnv)
+			return boolValue
+		}
+		recordSource(key, SourceInvalid)
+		return defaultValue
 	}
+	recordSource(key, SourceDefault)
 	return defaultValue
 }
 
 func getEnvAsDuration(key string, defaultValue string) time.Duration {
 	if value := os.Getenv(key); value != "" {
 		if duration, err := time.ParseDuration(value); err == nil {
+			recordSource(key, SourceEnv)
 			return duration
 		}
+		recordSource(key, SourceInvalid)
+	} else {
+		recordSource(key, SourceDefault)
 	}
 
 	duration, _ := time.ParseDuration(defaultValue)
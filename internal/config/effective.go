@@ -0,0 +1,117 @@
+package config
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// redacted replaces the value of a secret that is set
+const redacted = "[REDACTED]"
+
+// Where a variable read by LoadConfig came from
+const (
+	SourceEnv     = "env"     // set and applied
+	SourceDefault = "default" // unset, the default applies
+	SourceInvalid = "invalid" // set but unparseable, the default applies
+)
+
+// sources records, for the LoadConfig in progress, where each variable it
+// reads came from; loadMu keeps loads from recording into each other
+var (
+	loadMu  sync.Mutex
+	sources map[string]string
+)
+
+func recordSource(key, source string) {
+	if sources != nil {
+		sources[key] = source
+	}
+}
+
+// EffectiveConfig is the configuration a running instance applies, as
+// reported to operators
+type EffectiveConfig struct {
+	// Settings mirrors Config with snake_case keys, durations as strings
+	// and secrets redacted
+	Settings map[string]interface{} `json:"settings"`
+	// Sources maps each environment variable read to env, default or
+	// invalid
+	Sources map[string]string `json:"sources"`
+}
+
+// Effective returns the configuration with secrets redacted. Fields tagged
+// config:"secret" show whether they are set but never their value, and
+// passwords in URLs are masked.
+func (c *Config) Effective() EffectiveConfig {
+	return EffectiveConfig{
+		Settings: settings(reflect.ValueOf(*c)),
+		Sources:  c.sources,
+	}
+}
+
+func settings(v reflect.Value) map[string]interface{} {
+	out := make(map[string]interface{}, v.NumField())
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := field.Tag.Get("json")
+		if name == "" {
+			name = snakeCase(field.Name)
+		}
+		out[name] = setting(v.Field(i), field.Tag.Get("config") == "secret")
+	}
+	return out
+}
+
+func setting(v reflect.Value, secret bool) interface{} {
+	if secret {
+		if v.IsZero() {
+			return ""
+		}
+		return redacted
+	}
+	switch value := v.Interface().(type) {
+	case time.Duration:
+		return value.String()
+	case string:
+		return redactURL(value)
+	}
+	if v.Kind() == reflect.Struct {
+		return settings(v)
+	}
+	return v.Interface()
+}
+
+// redactURL masks the password of a URL carrying credentials as xxxxx, and
+// returns any other string unchanged
+func redactURL(s string) string {
+	u, err := url.Parse(s)
+	if err != nil || u.User == nil {
+		return s
+	}
+	return u.Redacted()
+}
+
+// snakeCase turns a Go field name into snake_case, keeping initialisms
+// together: APIKeys is api_keys, S3SecretAccessKey s3_secret_access_key
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
@@ -0,0 +1,294 @@
+// Package expr parses and evaluates arithmetic expressions over named
+// variables. Only numbers, identifiers, parentheses and + - * / are
+// supported, so user-supplied expressions cannot do anything but arithmetic.
+package expr
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	maxLength = 256
+	maxDepth  = 32
+)
+
+var (
+	ErrSyntax          = errors.New("syntax error")
+	ErrDivisionByZero  = errors.New("division by zero")
+	ErrUnknownVariable = errors.New("unknown variable")
+)
+
+// Expr is a parsed expression
+type Expr struct {
+	source    string
+	root      node
+	variables []string
+}
+
+// Parse parses an arithmetic expression such as "revenue / transactions"
+func Parse(source string) (*Expr, error) {
+	if len(source) > maxLength {
+		return nil, fmt.Errorf("%w: expression longer than %d characters", ErrSyntax, maxLength)
+	}
+
+	tokens, err := tokenize(source)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens, seen: make(map[string]bool)}
+	root, err := p.parseExpr(0)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("%w: unexpected %q", ErrSyntax, p.tokens[p.pos].text)
+	}
+
+	return &Expr{source: strings.TrimSpace(source), root: root, variables: p.variables}, nil
+}
+
+// Eval evaluates the expression with the given variable values
+func (e *Expr) Eval(vars map[string]float64) (float64, error) {
+	return e.root.eval(vars)
+}
+
+// Variables returns the identifiers referenced by the expression, in order of first use
+func (e *Expr) Variables() []string {
+	return append([]string{}, e.variables...)
+}
+
+func (e *Expr) String() string {
+	return e.source
+}
+
+type node interface {
+	eval(vars map[string]float64) (float64, error)
+}
+
+type number float64
+
+func (n number) eval(map[string]float64) (float64, error) {
+	return float64(n), nil
+}
+
+type variable string
+
+func (v variable) eval(vars map[string]float64) (float64, error) {
+	value, ok := vars[string(v)]
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", ErrUnknownVariable, string(v))
+	}
+	return value, nil
+}
+
+type negate struct {
+	operand node
+}
+
+func (n negate) eval(vars map[string]float64) (float64, error) {
+	value, err := n.operand.eval(vars)
+	return -value, err
+}
+
+type binary struct {
+	op          byte
+	left, right node
+}
+
+func (b binary) eval(vars map[string]float64) (float64, error) {
+	left, err := b.left.eval(vars)
+	if err != nil {
+		return 0, err
+	}
+	right, err := b.right.eval(vars)
+	if err != nil {
+		return 0, err
+	}
+
+	switch b.op {
+	case '+':
+		return left + right, nil
+	case '-':
+		return left - right, nil
+	case '*':
+		return left * right, nil
+	default:
+		if right == 0 {
+			return 0, ErrDivisionByZero
+		}
+		return left / right, nil
+	}
+}
+
+type tokenKind int
+
+const (
+	tokenNumber tokenKind = iota
+	tokenIdent
+	tokenOperator
+	tokenLParen
+	tokenRParen
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+func tokenize(source string) ([]token, error) {
+	var tokens []token
+	runes := []rune(source)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case unicode.IsDigit(r) || r == '.':
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{tokenNumber, string(runes[start:i])})
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, token{tokenIdent, string(runes[start:i])})
+		case strings.ContainsRune("+-*/", r):
+			tokens = append(tokens, token{tokenOperator, string(r)})
+			i++
+		case r == '(':
+			tokens = append(tokens, token{tokenLParen, "("})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{tokenRParen, ")"})
+			i++
+		default:
+			return nil, fmt.Errorf("%w: unexpected character %q", ErrSyntax, r)
+		}
+	}
+
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("%w: empty expression", ErrSyntax)
+	}
+	return tokens, nil
+}
+
+// parser is a recursive descent parser over the grammar
+//
+//	expr    = term { ("+" | "-") term }
+//	term    = unary { ("*" | "/") unary }
+//	unary   = "-" unary | primary
+//	primary = number | ident | "(" expr ")"
+type parser struct {
+	tokens    []token
+	pos       int
+	variables []string
+	seen      map[string]bool
+}
+
+func (p *parser) peek() (token, bool) {
+	if p.pos >= len(p.tokens) {
+		return token{}, false
+	}
+	return p.tokens[p.pos], true
+}
+
+func (p *parser) parseExpr(depth int) (node, error) {
+	if depth > maxDepth {
+		return nil, fmt.Errorf("%w: expression nested too deeply", ErrSyntax)
+	}
+
+	left, err := p.parseTerm(depth)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok, ok := p.peek()
+		if !ok || tok.kind != tokenOperator || (tok.text != "+" && tok.text != "-") {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.parseTerm(depth)
+		if err != nil {
+			return nil, err
+		}
+		left = binary{op: tok.text[0], left: left, right: right}
+	}
+}
+
+func (p *parser) parseTerm(depth int) (node, error) {
+	left, err := p.parseUnary(depth)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok, ok := p.peek()
+		if !ok || tok.kind != tokenOperator || (tok.text != "*" && tok.text != "/") {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.parseUnary(depth)
+		if err != nil {
+			return nil, err
+		}
+		left = binary{op: tok.text[0], left: left, right: right}
+	}
+}
+
+func (p *parser) parseUnary(depth int) (node, error) {
+	if depth > maxDepth {
+		return nil, fmt.Errorf("%w: expression nested too deeply", ErrSyntax)
+	}
+	if tok, ok := p.peek(); ok && tok.kind == tokenOperator && tok.text == "-" {
+		p.pos++
+		operand, err := p.parseUnary(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return negate{operand: operand}, nil
+	}
+	return p.parsePrimary(depth)
+}
+
+func (p *parser) parsePrimary(depth int) (node, error) {
+	tok, ok := p.peek()
+	if !ok {
+		return nil, fmt.Errorf("%w: unexpected end of expression", ErrSyntax)
+	}
+	p.pos++
+
+	switch tok.kind {
+	case tokenNumber:
+		value, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid number %q", ErrSyntax, tok.text)
+		}
+		return number(value), nil
+	case tokenIdent:
+		if !p.seen[tok.text] {
+			p.seen[tok.text] = true
+			p.variables = append(p.variables, tok.text)
+		}
+		return variable(tok.text), nil
+	case tokenLParen:
+		inner, err := p.parseExpr(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		if next, ok := p.peek(); !ok || next.kind != tokenRParen {
+			return nil, fmt.Errorf("%w: missing closing parenthesis", ErrSyntax)
+		}
+		p.pos++
+		return inner, nil
+	default:
+		return nil, fmt.Errorf("%w: unexpected %q", ErrSyntax, tok.text)
+	}
+}
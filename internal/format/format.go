@@ -0,0 +1,224 @@
+// Package format renders numbers and money as locale-specific display
+// strings for clients without a formatting library
+package format
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"analytics-dashboard-api/internal/models"
+)
+
+var ErrUnsupportedLocale = errors.New("unsupported locale")
+
+// Locale describes number and currency conventions for a language tag
+type Locale struct {
+	Tag            string
+	Decimal        string
+	Group          string
+	IndianGrouping bool // 12,34,567 instead of 1,234,567
+	CurrencyAfter  bool // 1.234,50 € instead of $1,234.50
+	CurrencySpace  bool // separate symbol and amount with a non-breaking space
+}
+
+var locales = []Locale{
+	{Tag: "en-US", Decimal: ".", Group: ","},
+	{Tag: "en-GB", Decimal: ".", Group: ","},
+	{Tag: "en-IN", Decimal: ".", Group: ",", IndianGrouping: true},
+	{Tag: "de-DE", Decimal: ",", Group: ".", CurrencyAfter: true, CurrencySpace: true},
+	{Tag: "fr-FR", Decimal: ",", Group: "\u202f", CurrencyAfter: true, CurrencySpace: true},
+	{Tag: "es-ES", Decimal: ",", Group: ".", CurrencyAfter: true, CurrencySpace: true},
+	{Tag: "it-IT", Decimal: ",", Group: ".", CurrencyAfter: true, CurrencySpace: true},
+	{Tag: "nl-NL", Decimal: ",", Group: ".", CurrencySpace: true},
+	{Tag: "pt-BR", Decimal: ",", Group: ".", CurrencySpace: true},
+	{Tag: "sv-SE", Decimal: ",", Group: "\u00a0", CurrencyAfter: true, CurrencySpace: true},
+	{Tag: "ja-JP", Decimal: ".", Group: ","},
+}
+
+var currencySymbols = map[string]string{
+	"USD": "$",
+	"EUR": "€",
+	"GBP": "£",
+	"JPY": "¥",
+	"INR": "₹",
+	"CAD": "CA$",
+	"AUD": "A$",
+	"BRL": "R$",
+	"SEK": "kr",
+}
+
+// zeroDecimalCurrencies are displayed rounded to whole units
+var zeroDecimalCurrencies = map[string]bool{"JPY": true}
+
+// defaultCurrency is set once at startup from configuration
+var defaultCurrency = "USD"
+
+// SetDefaultCurrency sets the currency used when a request doesn't name one
+func SetDefaultCurrency(code string) {
+	defaultCurrency = strings.ToUpper(code)
+}
+
+// DefaultCurrency returns the configured default currency code
+func DefaultCurrency() string {
+	return defaultCurrency
+}
+
+// Lookup finds a supported locale by tag. Matching is case-insensitive,
+// accepts "_" separators and falls back from a bare language ("de") to the
+// first locale for that language.
+func Lookup(tag string) (Locale, bool) {
+	tag = strings.ReplaceAll(strings.TrimSpace(tag), "_", "-")
+	if tag == "" {
+		return Locale{}, false
+	}
+
+	for _, locale := range locales {
+		if strings.EqualFold(locale.Tag, tag) {
+			return locale, true
+		}
+	}
+
+	language, _, _ := strings.Cut(tag, "-")
+	for _, locale := range locales {
+		if strings.EqualFold(strings.SplitN(locale.Tag, "-", 2)[0], language) {
+			return locale, true
+		}
+	}
+	return Locale{}, false
+}
+
+// Negotiate picks the best supported locale from an Accept-Language header
+func Negotiate(acceptLanguage string) (Locale, bool) {
+	type candidate struct {
+		tag     string
+		quality float64
+	}
+
+	var candidates []candidate
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		quality := 1.0
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(q, 64); err == nil {
+				quality = parsed
+			}
+		}
+		if tag != "" && tag != "*" && quality > 0 {
+			candidates = append(candidates, candidate{tag, quality})
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].quality > candidates[j].quality
+	})
+
+	for _, c := range candidates {
+		if locale, ok := Lookup(c.tag); ok {
+			return locale, true
+		}
+	}
+	return Locale{}, false
+}
+
+// Formatter renders values for one locale and currency
+type Formatter struct {
+	locale   Locale
+	currency string
+}
+
+func NewFormatter(locale Locale, currency string) *Formatter {
+	if currency == "" {
+		currency = defaultCurrency
+	}
+	return &Formatter{locale: locale, currency: strings.ToUpper(currency)}
+}
+
+func (f *Formatter) Locale() string {
+	return f.locale.Tag
+}
+
+func (f *Formatter) Currency() string {
+	return f.currency
+}
+
+// Money formats an amount with the currency symbol, e.g. "$1,234.50" or "1.234,50 €"
+func (f *Formatter) Money(m models.Money) string {
+	cents := int64(m)
+	negative := cents < 0
+	if negative {
+		cents = -cents
+	}
+
+	var amount string
+	if zeroDecimalCurrencies[f.currency] {
+		amount = f.group(strconv.FormatInt((cents+50)/100, 10))
+	} else {
+		amount = f.group(strconv.FormatInt(cents/100, 10)) + f.locale.Decimal + pad2(cents%100)
+	}
+
+	symbol, ok := currencySymbols[f.currency]
+	if !ok {
+		symbol = f.currency
+	}
+	space := ""
+	if f.locale.CurrencySpace || !ok {
+		space = "\u00a0"
+	}
+
+	var result string
+	if f.locale.CurrencyAfter {
+		result = amount + space + symbol
+	} else {
+		result = symbol + space + amount
+	}
+	if negative {
+		result = "-" + result
+	}
+	return result
+}
+
+// Number formats a value with the given number of decimal places
+func (f *Formatter) Number(value float64, decimals int) string {
+	text := strconv.FormatFloat(math.Abs(value), 'f', decimals, 64)
+	whole, fraction, _ := strings.Cut(text, ".")
+
+	result := f.group(whole)
+	if fraction != "" {
+		result += f.locale.Decimal + fraction
+	}
+	if value < 0 && strings.Trim(text, "0.") != "" {
+		result = "-" + result
+	}
+	return result
+}
+
+// group inserts group separators into a string of digits
+func (f *Formatter) group(digits string) string {
+	if len(digits) <= 3 {
+		return digits
+	}
+
+	head, tail := digits[:len(digits)-3], digits[len(digits)-3:]
+	size := 3
+	if f.locale.IndianGrouping {
+		size = 2
+	}
+
+	var parts []string
+	for len(head) > size {
+		parts = append([]string{head[len(head)-size:]}, parts...)
+		head = head[:len(head)-size]
+	}
+	parts = append([]string{head}, parts...)
+	return strings.Join(append(parts, tail), f.locale.Group)
+}
+
+func pad2(n int64) string {
+	if n < 10 {
+		return "0" + strconv.FormatInt(n, 10)
+	}
+	return strconv.FormatInt(n, 10)
+}
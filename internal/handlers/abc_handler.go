@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"analytics-dashboard-api/internal/httpquery"
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/internal/utils"
+	"analytics-dashboard-api/pkg/logger"
+)
+
+// ABCService classifies products by cumulative revenue share
+type ABCService interface {
+	Classify(context.Context, models.QueryOptions, models.ABCCutoffs) (*models.ABCClassification, error)
+}
+
+// ABCHandler serves the ABC product classification
+type ABCHandler struct {
+	abc         ABCService
+	initializer Initializer
+	coverage    CoverageProvider
+	logger      logger.Logger
+}
+
+func NewABCHandler(abc ABCService, initializer Initializer, coverage CoverageProvider, logger logger.Logger) *ABCHandler {
+	return &ABCHandler{
+		abc:         abc,
+		initializer: initializer,
+		coverage:    coverage,
+		logger:      logger,
+	}
+}
+
+// GetABC returns the class totals and a page of products ranked by revenue
+// with their class. ?a_cutoff=&b_cutoff= override the configured cut-offs,
+// ?class=A|B|C lists one class, and ?from=&to= (YYYY-MM-DD, inclusive) and
+// the analytics filters select the transactions.
+func (h *ABCHandler) GetABC(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	opts := getQueryOptions(r)
+	if err := getDateRange(r, &opts); err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var cutoffs models.ABCCutoffs
+	for _, param := range []struct {
+		name string
+		dest *float64
+	}{{"a_cutoff", &cutoffs.A}, {"b_cutoff", &cutoffs.B}} {
+		value := query.Get(param.name)
+		if value == "" {
+			continue
+		}
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid "+param.name+" parameter")
+			return
+		}
+		*param.dest = parsed
+	}
+
+	class := strings.ToUpper(query.Get("class"))
+	switch class {
+	case "", models.ABCClassA, models.ABCClassB, models.ABCClassC:
+	default:
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid class parameter: must be A, B or C")
+		return
+	}
+
+	page, err := httpquery.ParsePage(query, httpquery.DefaultPageOptions)
+	if err != nil {
+		h.logger.Debug("Ignoring invalid pagination parameter", "error", err)
+	}
+
+	if err := h.initializer.EnsureInitialized(r.Context()); err != nil {
+		writeServiceError(w, h.logger, err, "Failed to initialize database")
+		return
+	}
+
+	classification, err := h.abc.Classify(r.Context(), opts, cutoffs)
+	if err != nil {
+		writeServiceError(w, h.logger, err, "Failed to classify products")
+		return
+	}
+
+	products := classification.Products
+	if class != "" {
+		products = make([]models.ABCProduct, 0, len(products))
+		for _, p := range classification.Products {
+			if p.Class == class {
+				products = append(products, p)
+			}
+		}
+	}
+	total := len(products)
+	start := min(page.Offset, total)
+	data := products[start:min(start+page.Limit, total)]
+
+	response := map[string]interface{}{
+		"cutoffs":       classification.Cutoffs,
+		"total_revenue": classification.TotalRevenue,
+		"classes":       classification.Classes,
+		"data":          data,
+		"count":         len(data),
+		"total":         total,
+		"limit":         page.Limit,
+		"offset":        page.Offset,
+		"has_more":      page.HasMore(total),
+		"links":         pageLinks(w, r, page, total),
+	}
+	addSampleInfo(response, opts)
+	addCoverage(response, h.coverage)
+
+	utils.WriteJSONResponse(w, http.StatusOK, response)
+}
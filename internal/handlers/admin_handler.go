@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"analytics-dashboard-api/internal/config"
+	"analytics-dashboard-api/internal/httpquery"
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/internal/utils"
+	"analytics-dashboard-api/pkg/logger"
+)
+
+// JobStatsProvider reports the state of the data job queue
+type JobStatsProvider interface {
+	Stats() models.JobQueueStats
+}
+
+// CacheStatsProvider reports response cache usage
+type CacheStatsProvider interface {
+	Stats() models.ResponseCacheStats
+}
+
+// AuditReader lists recorded audit events, newest first
+type AuditReader interface {
+	List(int) ([]models.AuditEvent, error)
+}
+
+// BackupLister lists the stored backups under the retention policy
+type BackupLister interface {
+	Policy() models.BackupRetentionPolicy
+	List() ([]models.StoredBackup, error)
+}
+
+// PartitionLister describes the month partitions of the loaded transactions
+type PartitionLister interface {
+	GetPartitions(context.Context) ([]models.Partition, error)
+}
+
+// DiagnosticsRunner runs the self-diagnostics checks
+type DiagnosticsRunner interface {
+	Run(context.Context) models.DiagnosticsReport
+}
+
+// ConfigInspector reports the configuration in effect, secrets redacted
+type ConfigInspector interface {
+	Effective() config.EffectiveConfig
+}
+
+// AdminHandler serves operational views of the data pipeline
+type AdminHandler struct {
+	jobs        JobStatsProvider
+	loader      LoaderStatsProvider
+	audit       AuditReader
+	backups     BackupLister
+	cache       CacheStatsProvider
+	partitions  PartitionLister
+	diagnostics DiagnosticsRunner
+	config      ConfigInspector
+	logger      logger.Logger
+}
+
+func NewAdminHandler(jobs JobStatsProvider, loader LoaderStatsProvider, audit AuditReader, backups BackupLister, cache CacheStatsProvider, partitions PartitionLister, diagnostics DiagnosticsRunner, cfg ConfigInspector, logger logger.Logger) *AdminHandler {
+	return &AdminHandler{
+		jobs:        jobs,
+		loader:      loader,
+		audit:       audit,
+		backups:     backups,
+		cache:       cache,
+		partitions:  partitions,
+		diagnostics: diagnostics,
+		config:      cfg,
+		logger:      logger,
+	}
+}
+
+// GetStats returns the job queue (depth, running and pending jobs), the
+// data loader counters and the response cache counters
+func (h *AdminHandler) GetStats(w http.ResponseWriter, r *http.Request) {
+	utils.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"jobs":   h.jobs.Stats(),
+		"loader": h.loader.Stats(),
+		"cache":  h.cache.Stats(),
+	})
+}
+
+// auditPageOptions bounds ?limit= on the audit log endpoint
+var auditPageOptions = httpquery.PageOptions{DefaultLimit: 100, MinLimit: 1, MaxLimit: 1000}
+
+// ListAudit returns audit log events, newest first (?limit=, default 100)
+func (h *AdminHandler) ListAudit(w http.ResponseWriter, r *http.Request) {
+	page, err := httpquery.ParsePage(r.URL.Query(), auditPageOptions)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid limit parameter (must be 1-1000)")
+		return
+	}
+
+	data, err := h.audit.List(page.Limit)
+	if err != nil {
+		writeServiceError(w, h.logger, err, "Failed to read audit log")
+		return
+	}
+
+	utils.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"data":  data,
+		"count": len(data),
+	})
+}
+
+// ListBackups returns the stored backups, newest first, with the retention
+// rules keeping each one. Backups marked prune go on the next scheduled prune.
+func (h *AdminHandler) ListBackups(w http.ResponseWriter, r *http.Request) {
+	data, err := h.backups.List()
+	if err != nil {
+		writeServiceError(w, h.logger, err, "Failed to list backups")
+		return
+	}
+
+	var total int64
+	for _, backup := range data {
+		total += backup.SizeBytes
+	}
+
+	utils.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"data":             data,
+		"count":            len(data),
+		"total_size_bytes": total,
+		"retention":        h.backups.Policy(),
+	})
+}
+
+// ListPartitions returns the loaded transactions month by month, with the
+// rows and dates of each month and, on DuckDB, the row groups it spans
+func (h *AdminHandler) ListPartitions(w http.ResponseWriter, r *http.Request) {
+	data, err := h.partitions.GetPartitions(r.Context())
+	if err != nil {
+		writeServiceError(w, h.logger, err, "Failed to list partitions")
+		return
+	}
+
+	var rows int
+	for _, partition := range data {
+		rows += partition.Rows
+	}
+
+	utils.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"data":       data,
+		"count":      len(data),
+		"total_rows": rows,
+	})
+}
+
+// GetDiagnostics runs the self-diagnostics checks and returns each outcome
+// with its details. Failed checks still answer 200: the report, not the
+// status code, tells what is wrong.
+func (h *AdminHandler) GetDiagnostics(w http.ResponseWriter, r *http.Request) {
+	utils.WriteJSONResponse(w, http.StatusOK, h.diagnostics.Run(r.Context()))
+}
+
+// GetConfig returns the configuration the instance runs with: every
+// setting, secrets redacted, and whether each environment variable was
+// applied, left to its default or ignored as invalid
+func (h *AdminHandler) GetConfig(w http.ResponseWriter, r *http.Request) {
+	utils.WriteJSONResponse(w, http.StatusOK, h.config.Effective())
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"analytics-dashboard-api/internal/httpquery"
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/internal/utils"
+	"analytics-dashboard-api/pkg/logger"
+
+	"github.com/gorilla/mux"
+)
+
+type AlertService interface {
+	Rules() []models.AlertRule
+	CreateRule(models.AlertRule) (*models.AlertRule, error)
+	DeleteRule(string) error
+	History(int) []models.Alert
+}
+
+type AlertHandler struct {
+	alertService AlertService
+	logger       logger.Logger
+}
+
+func NewAlertHandler(alertService AlertService, logger logger.Logger) *AlertHandler {
+	return &AlertHandler{
+		alertService: alertService,
+		logger:       logger,
+	}
+}
+
+// ListRules returns all alert rules
+func (h *AlertHandler) ListRules(w http.ResponseWriter, r *http.Request) {
+	data := h.alertService.Rules()
+
+	utils.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"data":  data,
+		"count": len(data),
+	})
+}
+
+// CreateRule stores a new threshold rule from the JSON body. Rules are
+// enabled unless the body explicitly sets "enabled": false.
+func (h *AlertHandler) CreateRule(w http.ResponseWriter, r *http.Request) {
+	rule := models.AlertRule{Enabled: true}
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		utils.WriteErrorResponseWithCode(w, http.StatusBadRequest, utils.ErrCodeInvalidBody, "Invalid request body")
+		return
+	}
+
+	rule.Name = utils.SanitizeString(rule.Name)
+
+	created, err := h.alertService.CreateRule(rule)
+	if err != nil {
+		writeServiceError(w, h.logger, err, "Failed to create alert rule")
+		return
+	}
+
+	utils.WriteJSONResponse(w, http.StatusCreated, created)
+}
+
+// DeleteRule removes an alert rule by ID
+func (h *AlertHandler) DeleteRule(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	if err := h.alertService.DeleteRule(id); err != nil {
+		writeServiceError(w, h.logger, err, "Failed to delete alert rule", "id", id)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// historyPageOptions bounds ?limit= on the alert history endpoint
+var historyPageOptions = httpquery.PageOptions{DefaultLimit: 100, MinLimit: 1, MaxLimit: 500}
+
+// ListHistory returns fired alerts, newest first (?limit=, default 100)
+func (h *AlertHandler) ListHistory(w http.ResponseWriter, r *http.Request) {
+	page, err := httpquery.ParsePage(r.URL.Query(), historyPageOptions)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid limit parameter (must be 1-500)")
+		return
+	}
+
+	data := h.alertService.History(page.Limit)
+
+	utils.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"data":  data,
+		"count": len(data),
+	})
+}
@@ -2,370 +2,1304 @@ package handlers
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"net/http"
+	"regexp"
+	"slices"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"analytics-dashboard-api/internal/config"
+	"analytics-dashboard-api/internal/format"
+	"analytics-dashboard-api/internal/httpquery"
 	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/internal/timing"
 	"analytics-dashboard-api/internal/utils"
 	"analytics-dashboard-api/pkg/logger"
+
+	"github.com/gorilla/mux"
 )
 
-type DuckDBService interface {
-	LoadFromCSV(string) error
-	GetCountryRevenue(context.Context, int, int) ([]models.CountryRevenue, error)
-	GetTopProducts(context.Context) ([]models.ProductFrequency, error)
-	GetMonthlySales(context.Context) ([]models.MonthlySales, error)
-	GetTopRegions(context.Context) ([]models.RegionRevenue, error)
+// AnalyticsService runs the dashboard queries against the loaded data
+type AnalyticsService interface {
+	GetCountryRevenue(context.Context, models.QueryOptions, int, int) ([]models.CountryRevenue, *models.Totals, error)
+	GetTopProducts(context.Context, models.QueryOptions, string, int, int) ([]models.ProductFrequency, error)
+	GetTopProductsCount(context.Context, models.QueryOptions) (int, error)
+	GetMonthlySales(context.Context, models.QueryOptions) ([]models.MonthlySales, error)
+	GetTopRegions(context.Context, models.QueryOptions, string, int, int) ([]models.RegionRevenue, error)
+	GetTopRegionsCount(context.Context, models.QueryOptions) (int, error)
 	GetTotalRecords(context.Context) (int, error)
-	GetCountryRevenueCount(context.Context) (int, error)
-	Close() error
+	GetCountryRevenueCount(context.Context, models.QueryOptions) (int, error)
+	GetDistinctCounts(context.Context) (models.DistinctCounts, error)
+	GetSegmentBreakdown(context.Context, models.QueryOptions) ([]models.SegmentRevenue, error)
+	GetTopCustomers(context.Context, models.QueryOptions, int) ([]models.CustomerSpend, error)
+	GetRepeatPurchase(context.Context) (models.RepeatPurchase, error)
+	GetBaseMetrics(context.Context, models.QueryOptions, string) ([]models.MetricRow, error)
+	CoverageProvider
+}
+
+// BackupService snapshots and restores the loaded data
+type BackupService interface {
+	Backup(context.Context, string) (*models.BackupInfo, error)
+	Restore(context.Context, string, string) (*models.BackupInfo, error)
+}
+
+// SnapshotSource serves queries over the backup in effect at a past time.
+// The returned release func must be called once the caller is done with it.
+type SnapshotSource interface {
+	AsOf(context.Context, time.Time) (AnalyticsService, *models.BackupInfo, func(), error)
+}
+
+// DatasetSource serves queries over the named datasets configured next to
+// the default one, loading a dataset on first use
+type DatasetSource interface {
+	Dataset(context.Context, string) (AnalyticsService, error)
+}
+
+// DataRefresher controls loading of the dataset shared by all handlers
+type DataRefresher interface {
+	Initializer
+	StartReload(time.Duration, func(context.Context) error) models.JobInfo
+	WaitJob(context.Context, uint64) (models.JobInfo, error)
+	RefreshStatus() models.RefreshStatus
+	MarkLoaded()
+	Version() uint64
+	Inspect(context.Context, time.Duration) (*models.SourceInspection, error)
+	Rollback(context.Context) (*models.DataSnapshot, error)
+	DataJobs
+}
+
+// DataJobs runs work that changes the loaded data on the job queue, so it
+// never overlaps a load
+type DataJobs interface {
+	RunJob(context.Context, string, func(context.Context) error) error
+}
+
+// CoverageProvider reports the date range and load time of the loaded data
+type CoverageProvider interface {
+	DataCoverage() models.DataCoverage
+}
+
+// DerivedMetrics evaluates registered metric expressions over base metric values
+type DerivedMetrics interface {
+	List() []models.DerivedMetric
+	Evaluate([]string, map[string]float64) map[string]*float64
 }
 
+var currencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
 type AnalyticsHandler struct {
-	duckdbService DuckDBService
-	logger        logger.Logger
-	csvPath       string
-	initialized   bool
+	analyticsService AnalyticsService
+	backupService    BackupService
+	loader           DataRefresher
+	derivedMetrics   DerivedMetrics
+	snapshots        SnapshotSource
+	datasets         DatasetSource
+	logger           logger.Logger
+	backupConfig     config.BackupConfig
+
+	// statsMu serializes stats computation so each data version is counted once
+	statsMu sync.Mutex
+	stats   *models.AnalyticsStats
+
+	summaries summaryCache
 }
 
 func NewAnalyticsHandler(
-	duckdbService DuckDBService,
+	analyticsService AnalyticsService,
+	backupService BackupService,
+	loader DataRefresher,
+	derivedMetrics DerivedMetrics,
+	snapshots SnapshotSource,
+	datasets DatasetSource,
 	logger logger.Logger,
-	csvPath string,
+	backupConfig config.BackupConfig,
 ) *AnalyticsHandler {
 	return &AnalyticsHandler{
-		duckdbService: duckdbService,
-		logger:        logger,
-		csvPath:       csvPath,
-		initialized:   false,
+		analyticsService: analyticsService,
+		backupService:    backupService,
+		loader:           loader,
+		derivedMetrics:   derivedMetrics,
+		snapshots:        snapshots,
+		datasets:         datasets,
+		logger:           logger,
+		backupConfig:     backupConfig,
 	}
 }
 
-// ensureInitialized loads CSV data into DuckDB if not already done
-func (h *AnalyticsHandler) ensureInitialized(ctx context.Context) error {
-	if h.initialized {
-		return nil
+// SummaryCacheStats returns the number of cached dashboard summaries and
+// the lookups of them so far
+func (h *AnalyticsHandler) SummaryCacheStats() models.ResponseCacheStats {
+	return h.summaries.stats()
+}
+
+// analyticsSource is the data an analytics request queries: the loaded data,
+// a named dataset chosen with ?dataset= or the /datasets/{dataset} routes,
+// or with ?as_of= the backup in effect at that time
+type analyticsSource struct {
+	AnalyticsService
+	dataset  string             // empty for the default dataset
+	snapshot *models.BackupInfo // nil for the loaded data
+	release  func()
+}
+
+// source resolves the data a request queries. When it is unavailable the
+// error response is written and ok is false.
+func (h *AnalyticsHandler) source(w http.ResponseWriter, r *http.Request) (*analyticsSource, bool) {
+	value := r.URL.Query().Get("as_of")
+	dataset := requestDataset(r)
+	if dataset != "" {
+		if value != "" {
+			utils.WriteErrorResponse(w, http.StatusBadRequest, "as_of cannot be combined with a named dataset")
+			return nil, false
+		}
+		if h.datasets == nil {
+			writeServiceError(w, h.logger, fmt.Errorf("%w: %s", models.ErrDatasetNotFound, dataset), "Failed to open dataset")
+			return nil, false
+		}
+		service, err := h.datasets.Dataset(r.Context(), dataset)
+		if err != nil {
+			writeServiceError(w, h.logger, err, "Failed to open dataset", "dataset", dataset)
+			return nil, false
+		}
+		return &analyticsSource{AnalyticsService: service, dataset: dataset, release: func() {}}, true
+	}
+
+	if value == "" {
+		if err := h.loader.EnsureInitialized(r.Context()); err != nil {
+			writeServiceError(w, h.logger, err, "Failed to initialize database")
+			return nil, false
+		}
+		return &analyticsSource{AnalyticsService: h.analyticsService, release: func() {}}, true
+	}
+	asOf, err := parseAsOf(value)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+		return nil, false
+	}
+	if h.snapshots == nil {
+		writeServiceError(w, h.logger, models.ErrNotSupported, "Failed to open snapshot")
+		return nil, false
 	}
 
-	h.logger.Info("Initializing DuckDB with CSV data", "file", h.csvPath)
-	
-	if err := h.duckdbService.LoadFromCSV(h.csvPath); err != nil {
-		return fmt.Errorf("failed to load CSV into DuckDB: %w", err)
+	service, snapshot, release, err := h.snapshots.AsOf(r.Context(), asOf)
+	if err != nil {
+		writeServiceError(w, h.logger, err, "Failed to open snapshot", "as_of", value)
+		return nil, false
 	}
+	return &analyticsSource{AnalyticsService: service, snapshot: snapshot, release: release}, true
+}
 
-	h.initialized = true
-	h.logger.Info("DuckDB initialization completed")
-	return nil
+// requestDataset returns the named dataset a request is for: the
+// {dataset} path variable, or else ?dataset=. It is empty for the default
+// dataset.
+func requestDataset(r *http.Request) string {
+	dataset := mux.Vars(r)["dataset"]
+	if dataset == "" {
+		dataset = r.URL.Query().Get("dataset")
+	}
+	if dataset == models.DefaultDataset {
+		return ""
+	}
+	return dataset
 }
 
-// GetAnalytics returns all dashboard analytics data
+// parseAsOf reads ?as_of= as an RFC 3339 time or a YYYY-MM-DD date, which
+// covers the whole day in UTC
+func parseAsOf(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	day, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid as_of %q: must be YYYY-MM-DD or an RFC 3339 time", value)
+	}
+	return day.AddDate(0, 0, 1).Add(-time.Nanosecond), nil
+}
+
+// analyticsSections lists the sections of the dashboard summary that
+// ?include= can select, in response order
+var analyticsSections = []string{"summary", "country_revenue", "top_products", "monthly_sales", "top_regions"}
+
+// liteMonths is how many of the latest months of sales ?lite=true returns
+const liteMonths = 12
+
+// parseInclude returns the sections selected by ?include=, all of them when
+// the parameter is absent. ?lite=true selects the summary and the monthly
+// sales it is computed from; the second result reports whether it was set.
+func parseInclude(r *http.Request) (map[string]bool, bool, error) {
+	include := make(map[string]bool, len(analyticsSections))
+	param := r.URL.Query().Get("include")
+	lite, err := getBoolQueryParam(r, "lite")
+	if err != nil {
+		return nil, false, err
+	}
+	if lite {
+		if param != "" {
+			return nil, false, errors.New("lite cannot be combined with include")
+		}
+		include["summary"], include["monthly_sales"] = true, true
+		return include, true, nil
+	}
+	if param == "" {
+		for _, section := range analyticsSections {
+			include[section] = true
+		}
+		return include, false, nil
+	}
+
+	for _, name := range strings.Split(param, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !slices.Contains(analyticsSections, name) {
+			return nil, false, fmt.Errorf("unknown section %q in include (supported: %s)", name, strings.Join(analyticsSections, ", "))
+		}
+		include[name] = true
+	}
+	if len(include) == 0 {
+		return nil, false, errors.New("include must name at least one section")
+	}
+	return include, false, nil
+}
+
+// analyticsQuery is one sub-query of the dashboard summary and the sections
+// that need its result
+type analyticsQuery struct {
+	name     string
+	sections []string
+	run      func(context.Context) error
+}
+
+// GetAnalytics returns the dashboard analytics data. ?include= restricts the
+// response to some sections, and only their queries run. ?lite=true is the
+// mobile app's summary: the KPIs and the last 12 months of sales, from two
+// queries. Results for the loaded data are kept per filters and sections
+// until the data version changes, and summary.cache_hit tells when a
+// response came from them.
 func (h *AnalyticsHandler) GetAnalytics(w http.ResponseWriter, r *http.Request) {
 	startTime := time.Now()
 	ctx := r.Context()
 
 	h.logger.Info("Analytics request received", "method", r.Method, "path", r.URL.Path)
 
-	// Ensure DuckDB is initialized
-	if err := h.ensureInitialized(ctx); err != nil {
-		h.logger.Error("Failed to initialize DuckDB", "error", err)
-		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to initialize database")
+	opts := getQueryOptions(r)
+	formatter, err := getFormatter(r)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
 		return
 	}
-
-	// Get all analytics data concurrently
-	var countryRevenue []models.CountryRevenue
-	var topProducts []models.ProductFrequency
-	var monthlySales []models.MonthlySales
-	var topRegions []models.RegionRevenue
-	var totalRecords int
-	var countryRevenueCount int
-
-	type result struct {
-		name string
-		err  error
+	include, lite, err := parseInclude(r)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
 	}
 
-	results := make(chan result, 6)
-
-	// Get country revenue (first 1000 records)
-	go func() {
-		data, err := h.duckdbService.GetCountryRevenue(ctx, 1000, 0)
-		countryRevenue = data
-		results <- result{"country_revenue", err}
-	}()
-
-	// Get top products
-	go func() {
-		data, err := h.duckdbService.GetTopProducts(ctx)
-		topProducts = data
-		results <- result{"top_products", err}
-	}()
+	source, ok := h.source(w, r)
+	if !ok {
+		return
+	}
+	defer source.release()
 
-	// Get monthly sales
-	go func() {
-		data, err := h.duckdbService.GetMonthlySales(ctx)
-		monthlySales = data
-		results <- result{"monthly_sales", err}
-	}()
+	// Summaries of the loaded data are cached until it changes. The version
+	// is read before querying, so a reload racing the queries leaves their
+	// result with the older version, where no later request finds it.
+	version := h.loader.Version()
+	key := summaryKey(opts, include)
+	var analytics *models.AnalyticsResponse
+	cacheHit := false
+	cacheable := source.snapshot == nil && source.dataset == ""
+	if cacheable {
+		analytics, cacheHit = h.summaries.get(version, key)
+	}
+	var sectionErrors []models.SectionError
+	if !cacheHit {
+		analytics = &models.AnalyticsResponse{}
+		var countryRevenueCount int
 
-	// Get top regions
-	go func() {
-		data, err := h.duckdbService.GetTopRegions(ctx)
-		topRegions = data
-		results <- result{"top_regions", err}
-	}()
+		queries := []analyticsQuery{
+			{"country_revenue", []string{"country_revenue"}, func(ctx context.Context) (err error) {
+				// First 1000 records; the paginated endpoint serves the rest
+				analytics.CountryRevenue, _, err = source.GetCountryRevenue(ctx, opts, 1000, 0)
+				return err
+			}},
+			{"country_revenue_count", []string{"country_revenue"}, func(ctx context.Context) (err error) {
+				countryRevenueCount, err = source.GetCountryRevenueCount(ctx, opts)
+				return err
+			}},
+			{"top_products", []string{"top_products"}, func(ctx context.Context) (err error) {
+				analytics.TopProducts, err = source.GetTopProducts(ctx, opts, models.RankByPurchaseCount, topProductsPage.DefaultLimit, 0)
+				return err
+			}},
+			// The summary's total revenue is the sum of the monthly sales
+			{"monthly_sales", []string{"monthly_sales", "summary"}, func(ctx context.Context) (err error) {
+				analytics.MonthlySales, err = source.GetMonthlySales(ctx, opts)
+				return err
+			}},
+			{"top_regions", []string{"top_regions"}, func(ctx context.Context) (err error) {
+				analytics.TopRegions, err = source.GetTopRegions(ctx, opts, models.RankByRevenue, topRegionsPage.DefaultLimit, 0)
+				return err
+			}},
+			{"total_records", []string{"summary"}, func(ctx context.Context) (err error) {
+				analytics.TotalRecords, err = source.GetTotalRecords(ctx)
+				return err
+			}},
+		}
+		queries = slices.DeleteFunc(queries, func(q analyticsQuery) bool {
+			return !slices.ContainsFunc(q.sections, func(section string) bool { return include[section] })
+		})
 
-	// Get total records
-	go func() {
-		count, err := h.duckdbService.GetTotalRecords(ctx)
-		totalRecords = count
-		results <- result{"total_records", err}
-	}()
+		// Run the selected queries concurrently, each timed as a section
+		type result struct {
+			name string
+			err  error
+		}
+		results := make(chan result, len(queries))
+		timings := timing.FromContext(ctx)
+		for _, query := range queries {
+			go func() {
+				start := time.Now()
+				err := query.run(ctx)
+				timings.Since(timing.PhaseSection, query.name, start)
+				results <- result{query.name, err}
+			}()
+		}
 
-	// Get country revenue count
-	go func() {
-		count, err := h.duckdbService.GetCountryRevenueCount(ctx)
-		countryRevenueCount = count
-		results <- result{"country_revenue_count", err}
-	}()
+		// Wait for all goroutines to complete
+		var errs []error
+		for range queries {
+			res := <-results
+			if res.err == nil {
+				continue
+			}
+			errs = append(errs, fmt.Errorf("%s: %w", res.name, res.err))
+			status, message := logServiceError(h.logger, res.err, "Failed to get analytics section", "section", res.name)
+			sectionErrors = append(sectionErrors, models.SectionError{Section: res.name, Status: status, ErrorCode: string(ErrorCode(res.err)), Message: message})
+		}
 
-	// Wait for all goroutines to complete
-	var errors []string
-	for i := 0; i < 6; i++ {
-		res := <-results
-		if res.err != nil {
-			errors = append(errors, fmt.Sprintf("%s: %v", res.name, res.err))
+		// Degraded mode: serve the sections that succeeded unless every one
+		// failed, the request was abandoned or the client asked for all or nothing
+		if len(errs) > 0 && (len(errs) == len(queries) || ctx.Err() != nil || r.URL.Query().Get("partial") == "false") {
+			writeServiceError(w, h.logger, errors.Join(errs...), "Failed to get analytics data")
+			return
 		}
-	}
+		sort.Slice(sectionErrors, func(i, j int) bool { return sectionErrors[i].Section < sectionErrors[j].Section })
 
-	if len(errors) > 0 {
-		h.logger.Error("Failed to get analytics data", "errors", errors)
-		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to get analytics data")
-		return
+		h.logger.Debug("Analytics queries finished", "queries", len(queries), "country_revenue_count", countryRevenueCount)
+		// Partial results are not cached, so the failed sections are retried
+		if cacheable && len(sectionErrors) == 0 {
+			h.summaries.put(version, key, analytics)
+		}
 	}
 
 	processingTime := time.Since(startTime)
-	analytics := &models.AnalyticsResponse{
-		CountryRevenue:   countryRevenue,
-		TopProducts:      topProducts,
-		MonthlySales:     monthlySales,
-		TopRegions:       topRegions,
-		ProcessingTimeMs: processingTime.Milliseconds(),
-		TotalRecords:     totalRecords,
-		CacheHit:         false, // DuckDB queries are always fresh
-	}
+	analytics.ProcessingTimeMs = processingTime.Milliseconds()
+	analytics.CacheHit = cacheHit
+	analytics.Errors = sectionErrors
 
 	h.logger.Info("Analytics generated successfully",
-		"records", totalRecords,
-		"country_revenue_count", countryRevenueCount,
+		"records", analytics.TotalRecords,
+		"sections", len(include),
+		"failed_sections", len(sectionErrors),
+		"cache_hit", cacheHit,
 		"processing_time", processingTime)
 
-	// Return summary version
-	summary := h.createAnalyticsSummary(analytics)
-	utils.WriteJSONResponse(w, http.StatusOK, summary)
+	months := 0
+	if lite {
+		months = liteMonths
+	}
+	response := h.createAnalyticsSummary(analytics, formatter, include, months)
+	addSampleInfo(response, opts)
+	addFormatInfo(response, formatter)
+	addCoverage(response, source)
+	addDatasetInfo(response, source)
+	addSnapshotInfo(response, source.snapshot)
+
+	if len(sectionErrors) > 0 {
+		// Keep partial results out of the response cache
+		w.Header().Set("Cache-Control", "no-store")
+	}
+	utils.WriteJSONResponse(w, http.StatusOK, response)
 }
 
-// GetCountryRevenue returns country-level revenue data
+// GetCountryRevenue returns country-level revenue data. ?include_totals=true
+// adds the revenue, transactions and units over every row, not just the page.
 func (h *AnalyticsHandler) GetCountryRevenue(w http.ResponseWriter, r *http.Request) {
 	// Parse query parameters
-	limit := h.getIntQueryParam(r, "limit", 100) // Default 100, max 1000
-	offset := h.getIntQueryParam(r, "offset", 0)
+	page, err := httpquery.ParsePage(r.URL.Query(), httpquery.DefaultPageOptions)
+	if err != nil {
+		h.logger.Debug("Ignoring invalid pagination parameter", "error", err)
+	}
+	opts := getQueryOptions(r)
 
-	if limit > 1000 {
-		limit = 1000 // Cap at 1000 records
+	formatter, err := getFormatter(r)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	includeTotals, err := getBoolQueryParam(r, "include_totals")
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
 	}
 
-	// Ensure DuckDB is initialized
-	if err := h.ensureInitialized(r.Context()); err != nil {
-		h.logger.Error("Failed to initialize DuckDB", "error", err)
-		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to initialize database")
+	source, ok := h.source(w, r)
+	if !ok {
 		return
 	}
+	defer source.release()
 
-	// Get data from DuckDB
-	data, err := h.duckdbService.GetCountryRevenue(r.Context(), limit, offset)
+	data, totals, err := source.GetCountryRevenue(r.Context(), opts, page.Limit, page.Offset)
 	if err != nil {
-		h.logger.Error("Failed to get country revenue", "error", err)
-		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to get country revenue data")
+		writeServiceError(w, h.logger, err, "Failed to get country revenue data")
 		return
 	}
+	if formatter != nil {
+		for i := range data {
+			data[i].ApplyDisplay(formatter)
+		}
+		totals.ApplyDisplay(formatter)
+	}
 
 	// Get total count for pagination
-	total, err := h.duckdbService.GetCountryRevenueCount(r.Context())
+	total, err := source.GetCountryRevenueCount(r.Context(), opts)
 	if err != nil {
-		h.logger.Error("Failed to get country revenue count", "error", err)
-		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to get total count")
+		writeServiceError(w, h.logger, err, "Failed to get total count")
 		return
 	}
 
-	utils.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
+	response := map[string]interface{}{
 		"data":     data,
 		"count":    len(data),
 		"total":    total,
-		"limit":    limit,
-		"offset":   offset,
-		"has_more": offset+limit < total,
-	})
+		"limit":    page.Limit,
+		"offset":   page.Offset,
+		"has_more": page.HasMore(total),
+		"links":    pageLinks(w, r, page, total),
+	}
+	if includeTotals {
+		response["totals"] = totals
+	}
+	addSampleInfo(response, opts)
+	addFormatInfo(response, formatter)
+	addCoverage(response, source)
+	addDatasetInfo(response, source)
+	addSnapshotInfo(response, source.snapshot)
+
+	utils.WriteJSONResponse(w, http.StatusOK, response)
 }
 
-// GetAnalyticsStats returns summary statistics about the analytics data
+// GetAnalyticsStats returns summary statistics about the analytics data. The
+// counts are computed once per data version and served from cache until the
+// data is reloaded.
 func (h *AnalyticsHandler) GetAnalyticsStats(w http.ResponseWriter, r *http.Request) {
 	// Ensure DuckDB is initialized
-	if err := h.ensureInitialized(r.Context()); err != nil {
-		h.logger.Error("Failed to initialize DuckDB", "error", err)
-		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to initialize database")
+	if err := h.loader.EnsureInitialized(r.Context()); err != nil {
+		writeServiceError(w, h.logger, err, "Failed to initialize database")
 		return
 	}
 
-	// Get counts from DuckDB
-	totalRecords, err := h.duckdbService.GetTotalRecords(r.Context())
+	cached, cacheHit, err := h.analyticsStats(r.Context())
 	if err != nil {
-		h.logger.Error("Failed to get total records", "error", err)
-		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to get total records")
-		return
-	}
-
-	countryRevenueCount, err := h.duckdbService.GetCountryRevenueCount(r.Context())
-	if err != nil {
-		h.logger.Error("Failed to get country revenue count", "error", err)
-		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to get country revenue count")
+		writeServiceError(w, h.logger, err, "Failed to get analytics stats")
 		return
 	}
 
 	stats := map[string]interface{}{
-		"total_records":         totalRecords,
-		"processing_time_ms":    0, // DuckDB queries are fast
-		"cache_hit":             false, // Always fresh data
-		"country_revenue_count": countryRevenueCount,
-		"top_products_count":    20, // Fixed limit
-		"monthly_sales_count":   "varies", // Depends on data
-		"top_regions_count":     30, // Fixed limit
+		"data_version":          cached.DataVersion,
+		"computed_at":           cached.ComputedAt,
+		"total_records":         cached.TotalRecords,
+		"processing_time_ms":    cached.ProcessingTime.Milliseconds(),
+		"cache_hit":             cacheHit,
+		"country_revenue_count": cached.CountryRevenueCount,
+		"top_products_count":    cached.TopProductsCount,
+		"monthly_sales_count":   cached.MonthlySalesCount,
+		"top_regions_count":     cached.TopRegionsCount,
+		"unique_customers":      cached.Distinct.UniqueCustomers, // Approximate on DuckDB
+		"unique_products":       cached.Distinct.UniqueProducts,  // Approximate on DuckDB
+		"repeat_customers":      cached.RepeatPurchase.RepeatCustomers,
+		"repeat_purchase_rate":  cached.RepeatPurchase.Rate,
 		"endpoints": map[string]string{
 			"country_revenue": "/api/v1/analytics/country-revenue?limit=100&offset=0",
 			"top_products":    "/api/v1/analytics/top-products",
 			"monthly_sales":   "/api/v1/analytics/monthly-sales",
 			"top_regions":     "/api/v1/analytics/top-regions",
+			"top_customers":   "/api/v1/analytics/top-customers",
 		},
 	}
 
+	// Derived KPIs are evaluated per request: metrics can be registered
+	// without the data changing
+	if derived := h.derivedMetrics.List(); len(derived) > 0 && cached.Totals != nil {
+		names := make([]string, len(derived))
+		for i, metric := range derived {
+			names[i] = metric.Name
+		}
+		stats["derived_metrics"] = h.derivedMetrics.Evaluate(names, cached.Totals)
+	}
+
+	addCoverage(stats, h.analyticsService)
+
 	utils.WriteJSONResponse(w, http.StatusOK, stats)
 }
 
-// GetTopProducts returns top 20 frequently purchased products
+// analyticsStats returns the stats of the current data version, computing
+// them if the data changed since they were cached. The bool reports a cache hit.
+func (h *AnalyticsHandler) analyticsStats(ctx context.Context) (*models.AnalyticsStats, bool, error) {
+	h.statsMu.Lock()
+	defer h.statsMu.Unlock()
+
+	// Read the version before querying: a reload racing the computation
+	// leaves the stats tagged with the older version, so they are redone
+	version := h.loader.Version()
+	if h.stats != nil && h.stats.DataVersion == version {
+		return h.stats, true, nil
+	}
+
+	start := time.Now()
+	stats := &models.AnalyticsStats{DataVersion: version}
+	var err error
+
+	if stats.TotalRecords, err = h.analyticsService.GetTotalRecords(ctx); err != nil {
+		return nil, false, err
+	}
+	if stats.CountryRevenueCount, err = h.analyticsService.GetCountryRevenueCount(ctx, models.QueryOptions{}); err != nil {
+		return nil, false, err
+	}
+	if stats.Distinct, err = h.analyticsService.GetDistinctCounts(ctx); err != nil {
+		return nil, false, err
+	}
+	if stats.RepeatPurchase, err = h.analyticsService.GetRepeatPurchase(ctx); err != nil {
+		return nil, false, err
+	}
+
+	all := models.QueryOptions{}
+	topProducts, err := h.analyticsService.GetTopProducts(ctx, all, models.RankByPurchaseCount, topProductsPage.DefaultLimit, 0)
+	if err != nil {
+		return nil, false, err
+	}
+	monthlySales, err := h.analyticsService.GetMonthlySales(ctx, all)
+	if err != nil {
+		return nil, false, err
+	}
+	topRegions, err := h.analyticsService.GetTopRegions(ctx, all, models.RankByRevenue, topRegionsPage.DefaultLimit, 0)
+	if err != nil {
+		return nil, false, err
+	}
+	stats.TopProductsCount = len(topProducts)
+	stats.MonthlySalesCount = len(monthlySales)
+	stats.TopRegionsCount = len(topRegions)
+
+	totals, err := h.analyticsService.GetBaseMetrics(ctx, all, "")
+	if err != nil {
+		return nil, false, err
+	}
+	if len(totals) > 0 {
+		stats.Totals = totals[0].Values
+	}
+
+	stats.ComputedAt = time.Now().UTC()
+	stats.ProcessingTime = time.Since(start)
+	h.stats = stats
+
+	h.logger.Debug("Analytics stats computed", "data_version", version, "duration", stats.ProcessingTime)
+	return stats, false, nil
+}
+
+// The top lists keep their historical sizes as the default page
+var (
+	topProductsPage = httpquery.PageOptions{DefaultLimit: 20, MinLimit: 0, MaxLimit: 1000}
+	topRegionsPage  = httpquery.PageOptions{DefaultLimit: 30, MinLimit: 0, MaxLimit: 1000}
+)
+
+// productRanks and regionRanks are the ?sort_by= values of the top lists,
+// the default first
+var (
+	productRanks = []string{models.RankByPurchaseCount, models.RankByRevenue, models.RankByStock}
+	regionRanks  = []string{models.RankByRevenue, models.RankByPurchaseCount}
+)
+
+// getRankParam reads ?sort_by=, one of allowed, defaulting to the first
+func getRankParam(r *http.Request, allowed []string) (string, error) {
+	value := r.URL.Query().Get("sort_by")
+	if value == "" {
+		return allowed[0], nil
+	}
+	if !slices.Contains(allowed, value) {
+		return "", fmt.Errorf("invalid sort_by parameter: must be one of %s", strings.Join(allowed, ", "))
+	}
+	return value, nil
+}
+
+// GetTopProducts returns a page of products ranked by ?sort_by=
+// (purchase_count by default, revenue or stock), 20 by default.
+// ?include_other=true appends an "Other" row with the units and revenue of
+// the products not on the page, and ?include_totals=true adds the revenue,
+// transactions and units over every product.
 func (h *AnalyticsHandler) GetTopProducts(w http.ResponseWriter, r *http.Request) {
-	// Ensure DuckDB is initialized
-	if err := h.ensureInitialized(r.Context()); err != nil {
-		h.logger.Error("Failed to initialize DuckDB", "error", err)
-		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to initialize database")
+	page, err := httpquery.ParsePage(r.URL.Query(), topProductsPage)
+	if err != nil {
+		h.logger.Debug("Ignoring invalid pagination parameter", "error", err)
+	}
+	sortBy, err := getRankParam(r, productRanks)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	formatter, err := getFormatter(r)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	includeOther, err := getBoolQueryParam(r, "include_other")
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	includeTotals, err := getBoolQueryParam(r, "include_totals")
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	// Get data from DuckDB
-	data, err := h.duckdbService.GetTopProducts(r.Context())
+	source, ok := h.source(w, r)
+	if !ok {
+		return
+	}
+	defer source.release()
+
+	opts := getQueryOptions(r)
+	data, err := source.GetTopProducts(r.Context(), opts, sortBy, page.Limit, page.Offset)
 	if err != nil {
-		h.logger.Error("Failed to get top products", "error", err)
-		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to get top products data")
+		writeServiceError(w, h.logger, err, "Failed to get top products data")
 		return
 	}
+	total, err := source.GetTopProductsCount(r.Context(), opts)
+	if err != nil {
+		writeServiceError(w, h.logger, err, "Failed to get total count")
+		return
+	}
+	var totals *models.Totals
+	if includeOther || includeTotals {
+		if totals, err = filterTotals(r.Context(), source, opts); err != nil {
+			writeServiceError(w, h.logger, err, "Failed to get top products data")
+			return
+		}
+	}
+	if includeOther {
+		revenue, quantity := totals.Revenue, totals.Quantity
+		for _, p := range data {
+			revenue -= p.TotalRevenue
+			quantity -= p.PurchaseCount
+		}
+		if revenue != 0 || quantity != 0 {
+			data = append(data, models.ProductFrequency{ProductName: models.OtherBucket, PurchaseCount: quantity, TotalRevenue: revenue, Other: true})
+		}
+	}
+	if formatter != nil {
+		for i := range data {
+			data[i].ApplyDisplay(formatter)
+		}
+		if totals != nil {
+			totals.ApplyDisplay(formatter)
+		}
+	}
 
-	utils.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
-		"data":  data,
-		"count": len(data),
-	})
+	response := map[string]interface{}{
+		"data":     data,
+		"count":    len(data),
+		"total":    total,
+		"limit":    page.Limit,
+		"offset":   page.Offset,
+		"has_more": page.HasMore(total),
+		"links":    pageLinks(w, r, page, total),
+		"sort_by":  sortBy,
+	}
+	if includeTotals {
+		response["totals"] = totals
+	}
+	addSampleInfo(response, opts)
+	addFormatInfo(response, formatter)
+	addCoverage(response, source)
+	addDatasetInfo(response, source)
+	addSnapshotInfo(response, source.snapshot)
+
+	utils.WriteJSONResponse(w, http.StatusOK, response)
 }
 
 // GetMonthlySales returns monthly sales volume data
 func (h *AnalyticsHandler) GetMonthlySales(w http.ResponseWriter, r *http.Request) {
-	// Ensure DuckDB is initialized
-	if err := h.ensureInitialized(r.Context()); err != nil {
-		h.logger.Error("Failed to initialize DuckDB", "error", err)
-		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to initialize database")
+	formatter, err := getFormatter(r)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	source, ok := h.source(w, r)
+	if !ok {
 		return
 	}
+	defer source.release()
 
-	// Get data from DuckDB
-	data, err := h.duckdbService.GetMonthlySales(r.Context())
+	opts := getQueryOptions(r)
+	data, err := source.GetMonthlySales(r.Context(), opts)
 	if err != nil {
-		h.logger.Error("Failed to get monthly sales", "error", err)
-		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to get monthly sales data")
+		writeServiceError(w, h.logger, err, "Failed to get monthly sales data")
 		return
 	}
+	if formatter != nil {
+		for i := range data {
+			data[i].ApplyDisplay(formatter)
+		}
+	}
 
-	utils.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
+	response := map[string]interface{}{
 		"data":  data,
 		"count": len(data),
-	})
+	}
+	addSampleInfo(response, opts)
+	addFormatInfo(response, formatter)
+	addCoverage(response, source)
+	addDatasetInfo(response, source)
+	addSnapshotInfo(response, source.snapshot)
+
+	utils.WriteJSONResponse(w, http.StatusOK, response)
 }
 
-// GetTopRegions returns top 30 regions by revenue
+// GetTopRegions returns a page of regions ranked by ?sort_by= (revenue by
+// default, or purchase_count for units sold), 30 by default.
+// ?include_other=true appends an "Other" row with the revenue and units of
+// the regions not on the page, and ?include_totals=true adds the revenue,
+// transactions and units over every region.
 func (h *AnalyticsHandler) GetTopRegions(w http.ResponseWriter, r *http.Request) {
-	// Ensure DuckDB is initialized
-	if err := h.ensureInitialized(r.Context()); err != nil {
-		h.logger.Error("Failed to initialize DuckDB", "error", err)
-		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to initialize database")
+	page, err := httpquery.ParsePage(r.URL.Query(), topRegionsPage)
+	if err != nil {
+		h.logger.Debug("Ignoring invalid pagination parameter", "error", err)
+	}
+	sortBy, err := getRankParam(r, regionRanks)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
 		return
 	}
+	formatter, err := getFormatter(r)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	includeOther, err := getBoolQueryParam(r, "include_other")
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	includeTotals, err := getBoolQueryParam(r, "include_totals")
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	source, ok := h.source(w, r)
+	if !ok {
+		return
+	}
+	defer source.release()
 
-	// Get data from DuckDB
-	data, err := h.duckdbService.GetTopRegions(r.Context())
+	opts := getQueryOptions(r)
+	data, err := source.GetTopRegions(r.Context(), opts, sortBy, page.Limit, page.Offset)
+	if err != nil {
+		writeServiceError(w, h.logger, err, "Failed to get top regions data")
+		return
+	}
+	total, err := source.GetTopRegionsCount(r.Context(), opts)
 	if err != nil {
-		h.logger.Error("Failed to get top regions", "error", err)
-		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to get top regions data")
+		writeServiceError(w, h.logger, err, "Failed to get total count")
 		return
 	}
+	var totals *models.Totals
+	if includeOther || includeTotals {
+		if totals, err = filterTotals(r.Context(), source, opts); err != nil {
+			writeServiceError(w, h.logger, err, "Failed to get top regions data")
+			return
+		}
+	}
+	if includeOther {
+		revenue, quantity := totals.Revenue, totals.Quantity
+		for _, rr := range data {
+			revenue -= rr.TotalRevenue
+			quantity -= rr.ItemsSold
+		}
+		if revenue != 0 || quantity != 0 {
+			data = append(data, models.RegionRevenue{Region: models.OtherBucket, TotalRevenue: revenue, ItemsSold: quantity, Other: true})
+		}
+	}
+	if formatter != nil {
+		for i := range data {
+			data[i].ApplyDisplay(formatter)
+		}
+		if totals != nil {
+			totals.ApplyDisplay(formatter)
+		}
+	}
 
-	utils.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
+	response := map[string]interface{}{
+		"data":     data,
+		"count":    len(data),
+		"total":    total,
+		"limit":    page.Limit,
+		"offset":   page.Offset,
+		"has_more": page.HasMore(total),
+		"links":    pageLinks(w, r, page, total),
+		"sort_by":  sortBy,
+	}
+	if includeTotals {
+		response["totals"] = totals
+	}
+	addSampleInfo(response, opts)
+	addFormatInfo(response, formatter)
+	addCoverage(response, source)
+	addDatasetInfo(response, source)
+	addSnapshotInfo(response, source.snapshot)
+
+	utils.WriteJSONResponse(w, http.StatusOK, response)
+}
+
+// GetSegments returns revenue and retention broken down by customer segment
+func (h *AnalyticsHandler) GetSegments(w http.ResponseWriter, r *http.Request) {
+	formatter, err := getFormatter(r)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	source, ok := h.source(w, r)
+	if !ok {
+		return
+	}
+	defer source.release()
+
+	opts := getQueryOptions(r)
+	data, err := source.GetSegmentBreakdown(r.Context(), opts)
+	if err != nil {
+		writeServiceError(w, h.logger, err, "Failed to get segment data")
+		return
+	}
+	if formatter != nil {
+		for i := range data {
+			data[i].ApplyDisplay(formatter)
+		}
+	}
+
+	response := map[string]interface{}{
 		"data":  data,
 		"count": len(data),
-	})
+	}
+	addSampleInfo(response, opts)
+	addFormatInfo(response, formatter)
+	addCoverage(response, source)
+	addDatasetInfo(response, source)
+	addSnapshotInfo(response, source.snapshot)
+
+	utils.WriteJSONResponse(w, http.StatusOK, response)
+}
+
+// Top customers returned without ?limit=, and the most ?limit= may ask for
+const (
+	defaultTopCustomers = 20
+	maxTopCustomers     = 1000
+)
+
+// GetTopCustomers ranks customers by total spend (?limit=20), optionally
+// within ?from=&to=. Sampling is not offered: one customer's spend cannot
+// be extrapolated from a sample.
+func (h *AnalyticsHandler) GetTopCustomers(w http.ResponseWriter, r *http.Request) {
+	formatter, err := getFormatter(r)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	limit := defaultTopCustomers
+	if value := r.URL.Query().Get("limit"); value != "" {
+		if limit, err = strconv.Atoi(value); err != nil || limit < 1 || limit > maxTopCustomers {
+			utils.WriteErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("Invalid limit parameter: must be between 1 and %d", maxTopCustomers))
+			return
+		}
+	}
+
+	opts := getQueryOptions(r)
+	opts.SampleRate = 0
+	if err := getDateRange(r, &opts); err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	source, ok := h.source(w, r)
+	if !ok {
+		return
+	}
+	defer source.release()
+
+	data, err := source.GetTopCustomers(r.Context(), opts, limit)
+	if err != nil {
+		writeServiceError(w, h.logger, err, "Failed to get top customers")
+		return
+	}
+	if formatter != nil {
+		for i := range data {
+			data[i].ApplyDisplay(formatter)
+		}
+	}
+
+	response := map[string]interface{}{
+		"data":  data,
+		"count": len(data),
+		"limit": limit,
+	}
+	addFormatInfo(response, formatter)
+	addCoverage(response, source)
+	addDatasetInfo(response, source)
+	addSnapshotInfo(response, source.snapshot)
+
+	utils.WriteJSONResponse(w, http.StatusOK, response)
 }
 
-// RefreshCache forces a cache refresh by reloading the CSV into DuckDB
+const (
+	defaultCountryDetailLimit = 10
+	maxCountryDetailLimit     = 100
+)
+
+// GetCountryDetail drills into the country in the path: its revenue by
+// month and its top ?limit= products and regions by revenue (10 by
+// default), in one response. A country without transactions is a 404.
+func (h *AnalyticsHandler) GetCountryDetail(w http.ResponseWriter, r *http.Request) {
+	formatter, err := getFormatter(r)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	limit := defaultCountryDetailLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		if limit, err = strconv.Atoi(value); err != nil || limit < 1 || limit > maxCountryDetailLimit {
+			utils.WriteErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("Invalid limit parameter: must be between 1 and %d", maxCountryDetailLimit))
+			return
+		}
+	}
+
+	country := utils.SanitizeString(mux.Vars(r)["country"])
+	if country == "" {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Country is required")
+		return
+	}
+	opts := getQueryOptions(r)
+	opts.SampleRate = 0
+	opts.Country = country
+	if err := getDateRange(r, &opts); err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	source, ok := h.source(w, r)
+	if !ok {
+		return
+	}
+	defer source.release()
+
+	ctx := r.Context()
+	// Checked without the other filters so a known country with no sales
+	// in the range or segment answers with empty lists rather than a 404
+	regions, err := source.GetTopRegionsCount(ctx, models.QueryOptions{Country: country})
+	if err != nil {
+		writeServiceError(w, h.logger, err, "Failed to get country detail")
+		return
+	}
+	if regions == 0 {
+		writeServiceError(w, h.logger, models.ErrCountryNotFound, "Failed to get country detail")
+		return
+	}
+
+	detail := models.CountryDetail{Country: country}
+	if detail.RevenueOverTime, err = source.GetMonthlySales(ctx, opts); err != nil {
+		writeServiceError(w, h.logger, err, "Failed to get country revenue over time")
+		return
+	}
+	if detail.TopProducts, err = source.GetTopProducts(ctx, opts, models.RankByRevenue, limit, 0); err != nil {
+		writeServiceError(w, h.logger, err, "Failed to get country top products")
+		return
+	}
+	if detail.TopRegions, err = source.GetTopRegions(ctx, opts, models.RankByRevenue, limit, 0); err != nil {
+		writeServiceError(w, h.logger, err, "Failed to get country top regions")
+		return
+	}
+	if formatter != nil {
+		for i := range detail.RevenueOverTime {
+			detail.RevenueOverTime[i].ApplyDisplay(formatter)
+		}
+		for i := range detail.TopProducts {
+			detail.TopProducts[i].ApplyDisplay(formatter)
+		}
+		for i := range detail.TopRegions {
+			detail.TopRegions[i].ApplyDisplay(formatter)
+		}
+	}
+
+	response := map[string]interface{}{
+		"data":  detail,
+		"limit": limit,
+	}
+	addFormatInfo(response, formatter)
+	addCoverage(response, source)
+	addDatasetInfo(response, source)
+	addSnapshotInfo(response, source.snapshot)
+
+	utils.WriteJSONResponse(w, http.StatusOK, response)
+}
+
+// RefreshCache reloads the configured source. The load is queued as a job
+// and answered with 202 and the job, to be followed at /api/v1/jobs/{id};
+// ?wait=true holds the response until the load finished instead.
+// ?dry_run=true only inspects the source.
 func (h *AnalyticsHandler) RefreshCache(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		utils.WriteErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
 
+	dryRun, err := getBoolQueryParam(r, "dry_run")
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid dry_run parameter")
+		return
+	}
+	wait, err := getBoolQueryParam(r, "wait")
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid wait parameter")
+		return
+	}
+
+	timeout, err := parseRefreshTimeout(r)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	startTime := time.Now()
-	ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
-	defer cancel()
+	ctx := r.Context()
 
-	h.logger.Info("DuckDB refresh requested")
+	// A dry run validates the source and leaves the loaded data alone
+	if dryRun {
+		inspection, err := h.loader.Inspect(ctx, timeout)
+		if err != nil {
+			writeServiceError(w, h.logger, err, "Failed to inspect source")
+			return
+		}
+		h.logger.Info("Refresh dry run completed",
+			"source", inspection.Source,
+			"valid", inspection.Valid,
+			"records", inspection.Records,
+			"duration", time.Since(startTime))
+		utils.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
+			"dry_run":     true,
+			"source":      inspection,
+			"duration_ms": time.Since(startTime).Milliseconds(),
+		})
+		return
+	}
 
-	// Reset initialization flag to force reload
-	h.initialized = false
+	h.logger.Info("DuckDB refresh requested", "timeout", timeout, "wait", wait)
 
-	// Reload CSV into DuckDB
-	if err := h.duckdbService.LoadFromCSV(h.csvPath); err != nil {
-		h.logger.Error("Failed to refresh DuckDB", "error", err)
-		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to refresh database")
+	// Every refresh is a queued job, and one requested while another waits
+	// to start gets that job instead: concurrent requests load the data
+	// once and follow the same job, whether they wait for it or not.
+	var backup *models.BackupInfo
+	job := h.loader.StartReload(timeout, func(ctx context.Context) error {
+		backup = h.backupAfterRefresh(ctx)
+		return nil
+	})
+	if !wait {
+		w.Header().Set("Location", fmt.Sprintf("/api/v1/jobs/%d", job.ID))
+		utils.WriteJSONResponse(w, http.StatusAccepted, job)
 		return
 	}
 
-	h.initialized = true
+	// A client that gives up waiting, or a response that times out, does
+	// not cancel the job
+	job, err = h.loader.WaitJob(ctx, job.ID)
+	if err != nil {
+		if !stillWaiting(ctx, w, h.logger, "refresh", time.Since(startTime)) {
+			return
+		}
+		writeServiceError(w, h.logger, err, "Failed to refresh database")
+		return
+	}
 
 	// Get record count for stats
-	totalRecords, err := h.duckdbService.GetTotalRecords(ctx)
+	totalRecords, err := h.analyticsService.GetTotalRecords(ctx)
 	if err != nil {
-		h.logger.Error("Failed to get total records", "error", err)
-		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to get record count")
+		writeServiceError(w, h.logger, err, "Failed to get record count")
 		return
 	}
 
 	h.logger.Info("DuckDB refreshed successfully", "duration", time.Since(startTime))
 
-	utils.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
+	response := map[string]interface{}{
 		"message":       "Database refreshed successfully",
+		"job_id":        job.ID,
 		"total_records": totalRecords,
 		"duration_ms":   time.Since(startTime).Milliseconds(),
+	}
+	// Requests that joined another's job are not told about its backup
+	if backup != nil {
+		response["backup"] = backup
+	}
+
+	utils.WriteJSONResponse(w, http.StatusOK, response)
+}
+
+// GetRefreshStatus reports the refresh loading now, the one queued behind
+// it and the outcome of the last one, to follow refreshes without their
+// job IDs
+func (h *AnalyticsHandler) GetRefreshStatus(w http.ResponseWriter, r *http.Request) {
+	utils.WriteJSONResponse(w, http.StatusOK, h.loader.RefreshStatus())
+}
+
+// backupAfterRefresh keeps a warm standby snapshot of every successful load
+// when configured. A failed backup is logged and leaves the refresh intact.
+func (h *AnalyticsHandler) backupAfterRefresh(ctx context.Context) *models.BackupInfo {
+	if !h.backupConfig.OnRefresh {
+		return nil
+	}
+	backup, err := h.backupService.Backup(ctx, h.backupConfig.Dir)
+	if err != nil {
+		h.logger.Error("Automatic backup after refresh failed", "error", err)
+		return nil
+	}
+	return backup
+}
+
+// stillWaiting reports whether the caller of a waited-on load is still
+// there to be told its outcome. A response that timed out is answered with
+// 504; a client that disconnected gets nothing. Either way the load itself
+// continues in the background.
+func stillWaiting(ctx context.Context, w http.ResponseWriter, log logger.Logger, what string, waited time.Duration) bool {
+	switch ctx.Err() {
+	case nil:
+		return true
+	case context.DeadlineExceeded:
+		log.Info("Response timed out, "+what+" continues in the background", "waited", waited)
+		utils.WriteErrorResponse(w, http.StatusGatewayTimeout,
+			"Timed out waiting for the "+what+", which continues in the background")
+	default:
+		log.Info("Client disconnected, "+what+" continues in the background", "waited", waited)
+	}
+	return false
+}
+
+// parseRefreshTimeout reads the X-Refresh-Timeout header as a Go duration or
+// a number of seconds. Zero means the configured load timeout.
+func parseRefreshTimeout(r *http.Request) (time.Duration, error) {
+	value := r.Header.Get("X-Refresh-Timeout")
+	if value == "" {
+		return 0, nil
+	}
+
+	timeout, err := time.ParseDuration(value)
+	if err != nil {
+		seconds, convErr := strconv.Atoi(value)
+		if convErr != nil {
+			return 0, fmt.Errorf("invalid X-Refresh-Timeout %q: want a duration like 45m or a number of seconds", value)
+		}
+		timeout = time.Duration(seconds) * time.Second
+	}
+	if timeout <= 0 {
+		return 0, errors.New("X-Refresh-Timeout must be positive")
+	}
+	return timeout, nil
+}
+
+// BackupData exports the loaded data as a Parquet snapshot in the backup
+// directory. It runs on the job queue so a refresh cannot swap tables
+// between the exports of one backup.
+func (h *AnalyticsHandler) BackupData(w http.ResponseWriter, r *http.Request) {
+	// Ensure DuckDB is initialized
+	if err := h.loader.EnsureInitialized(r.Context()); err != nil {
+		writeServiceError(w, h.logger, err, "Failed to initialize database")
+		return
+	}
+
+	var backup *models.BackupInfo
+	err := h.loader.RunJob(r.Context(), "backup", func(ctx context.Context) error {
+		var err error
+		backup, err = h.backupService.Backup(ctx, h.backupConfig.Dir)
+		return err
+	})
+	if err != nil {
+		writeServiceError(w, h.logger, err, "Failed to create backup")
+		return
+	}
+
+	utils.WriteJSONResponse(w, http.StatusCreated, map[string]interface{}{
+		"message": "Backup created successfully",
+		"backup":  backup,
 	})
 }
 
+// RestoreData replaces the loaded data with a snapshot from the backup directory.
+// The optional JSON body {"name": "..."} selects a backup; the latest is used otherwise.
+func (h *AnalyticsHandler) RestoreData(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Name string `json:"name"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			utils.WriteErrorResponseWithCode(w, http.StatusBadRequest, utils.ErrCodeInvalidBody, "Invalid request body")
+			return
+		}
+	}
+
+	var backup *models.BackupInfo
+	err := h.loader.RunJob(r.Context(), "restore", func(ctx context.Context) error {
+		var err error
+		if backup, err = h.backupService.Restore(ctx, h.backupConfig.Dir, request.Name); err != nil {
+			return err
+		}
+		// Restored data replaces the CSV load
+		h.loader.MarkLoaded()
+		return nil
+	})
+	if err != nil {
+		writeServiceError(w, h.logger, err, "Failed to restore backup")
+		return
+	}
+
+	utils.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"message": "Backup restored successfully",
+		"backup":  backup,
+	})
+}
+
+// RollbackData restores the data replaced by the latest refresh, for when a
+// refresh loaded a bad file
+func (h *AnalyticsHandler) RollbackData(w http.ResponseWriter, r *http.Request) {
+	snapshot, err := h.loader.Rollback(r.Context())
+	if err != nil {
+		writeServiceError(w, h.logger, err, "Failed to roll back data")
+		return
+	}
+
+	utils.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"message":      "Rolled back to previous data version",
+		"data_version": h.loader.Version(),
+		"restored":     snapshot,
+	})
+}
 
-func (h *AnalyticsHandler) createAnalyticsSummary(analytics *models.AnalyticsResponse) map[string]interface{} {
+func (h *AnalyticsHandler) createAnalyticsSummary(analytics *models.AnalyticsResponse, formatter *format.Formatter, include map[string]bool, months int) map[string]interface{} {
 	// Limit each section to prevent huge responses
 	countryRevenue := analytics.CountryRevenue
 	if len(countryRevenue) > 50 {
@@ -382,37 +1316,263 @@ func (h *AnalyticsHandler) createAnalyticsSummary(analytics *models.AnalyticsRes
 		topRegions = topRegions[:30]
 	}
 
-	// Calculate total revenue from monthly sales
-	var totalRevenue float64
-	for _, sale := range analytics.MonthlySales {
-		totalRevenue += sale.SalesVolume
+	// The latest months only; the summary still totals every month
+	monthlySales := analytics.MonthlySales
+	if months > 0 && len(monthlySales) > months {
+		monthlySales = monthlySales[len(monthlySales)-months:]
 	}
 
-	return map[string]interface{}{
-		"summary": map[string]interface{}{
-			"total_records":         analytics.TotalRecords,
-			"processing_time_ms":    analytics.ProcessingTimeMs,
-			"cache_hit":             analytics.CacheHit,
-			"country_revenue_count": len(analytics.CountryRevenue),
-			"top_products_count":    len(analytics.TopProducts),
-			"monthly_sales_count":   len(analytics.MonthlySales),
-			"top_regions_count":     len(analytics.TopRegions),
-			"total_revenue":         totalRevenue,
-		},
+	if formatter != nil {
+		for i := range countryRevenue {
+			countryRevenue[i].ApplyDisplay(formatter)
+		}
+		for i := range monthlySales {
+			monthlySales[i].ApplyDisplay(formatter)
+		}
+		for i := range topProducts {
+			topProducts[i].ApplyDisplay(formatter)
+		}
+		for i := range topRegions {
+			topRegions[i].ApplyDisplay(formatter)
+		}
+	}
+
+	response := map[string]interface{}{
+		"message": "Use specific endpoints with pagination for complete data: /api/v1/analytics/country-revenue?limit=100&offset=0",
+	}
+	sections := map[string]interface{}{
 		"country_revenue": countryRevenue,
 		"top_products":    topProducts,
-		"monthly_sales":   analytics.MonthlySales,
+		"monthly_sales":   monthlySales,
 		"top_regions":     topRegions,
-		"message":         "Use specific endpoints with pagination for complete data: /api/v1/analytics/country-revenue?limit=100&offset=0",
 	}
+	for name, data := range sections {
+		if include[name] {
+			response[name] = data
+		}
+	}
+
+	if include["summary"] {
+		// Calculate total revenue from monthly sales
+		var totalRevenue models.Money
+		for _, sale := range analytics.MonthlySales {
+			totalRevenue += sale.SalesVolume
+		}
+
+		summary := map[string]interface{}{
+			"total_records":      analytics.TotalRecords,
+			"processing_time_ms": analytics.ProcessingTimeMs,
+			"cache_hit":          analytics.CacheHit,
+			"total_revenue":      totalRevenue,
+		}
+		counts := map[string]int{
+			"country_revenue": len(analytics.CountryRevenue),
+			"top_products":    len(analytics.TopProducts),
+			"monthly_sales":   len(analytics.MonthlySales),
+			"top_regions":     len(analytics.TopRegions),
+		}
+		for name, count := range counts {
+			if include[name] {
+				summary[name+"_count"] = count
+			}
+		}
+		if formatter != nil {
+			summary["display"] = map[string]string{"total_revenue": formatter.Money(totalRevenue)}
+		}
+		response["summary"] = summary
+	}
+
+	if len(analytics.Errors) > 0 {
+		response["partial"] = true
+		response["errors"] = analytics.Errors
+	}
+	return response
 }
 
-// Helper function to get integer query parameter with default value
-func (h *AnalyticsHandler) getIntQueryParam(r *http.Request, key string, defaultValue int) int {
+// filterTotals returns the revenue, transactions and units sold of all
+// transactions under opts: the ?include_totals=true of a top-N endpoint, and
+// what it subtracts its rows from to build the "Other" row of
+// ?include_other=true. The totals come from the same base metrics as
+// /analytics/stats and /aggregate, so the rows add up to them.
+func filterTotals(ctx context.Context, source AnalyticsService, opts models.QueryOptions) (*models.Totals, error) {
+	totals, err := source.GetBaseMetrics(ctx, opts, "")
+	if err != nil {
+		return nil, err
+	}
+	if len(totals) == 0 {
+		return &models.Totals{}, nil
+	}
+	values := totals[0].Values
+	return &models.Totals{
+		Revenue:      models.MoneyFromFloat(values["revenue"]),
+		Transactions: int(math.Round(values["transactions"])),
+		Quantity:     int(math.Round(values["quantity"])),
+	}, nil
+}
+
+// getBoolQueryParam reads an optional true/false query parameter
+func getBoolQueryParam(r *http.Request, key string) (bool, error) {
+	value := r.URL.Query().Get(key)
+	if value == "" {
+		return false, nil
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s parameter: must be true or false", key)
+	}
+	return parsed, nil
+}
+
+// Helper function to get float query parameter with default value
+func getFloatQueryParam(r *http.Request, key string, defaultValue float64) float64 {
 	if value := r.URL.Query().Get(key); value != "" {
-		if intValue, err := strconv.Atoi(value); err == nil && intValue >= 0 {
-			return intValue
+		if floatValue, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatValue
 		}
 	}
 	return defaultValue
 }
+
+// getQueryOptions builds the query modifiers shared by the analytics endpoints
+func getQueryOptions(r *http.Request) models.QueryOptions {
+	opts := models.QueryOptions{}
+
+	// Sampling trades accuracy for speed on large datasets; ignore rates outside (0, 1)
+	if sample := getFloatQueryParam(r, "sample", 0); sample > 0 && sample < 1 {
+		opts.SampleRate = sample
+	}
+
+	// Restrict to customer segments, countries, regions or categories, or
+	// leave some out. A lone segment or country keeps its single-value
+	// field, which reports override their template filters with.
+	query := r.URL.Query()
+	for _, dimension := range models.FilterDimensions {
+		// Lists past httpquery.MaxFilterValues are cut short; query
+		// validation rejects them or flags them in a Warning header
+		parsed, _ := httpquery.ParseValueFilter(query, dimension)
+		filter := models.DimensionFilter{
+			Dimension: dimension,
+			Include:   sanitizeValues(parsed.Include),
+			Exclude:   sanitizeValues(parsed.Exclude),
+		}
+		if len(filter.Include) == 1 {
+			switch dimension {
+			case models.FilterSegment:
+				opts.Segment, filter.Include = filter.Include[0], nil
+			case models.FilterCountry:
+				opts.Country, filter.Include = filter.Include[0], nil
+			}
+		}
+		if len(filter.Include) > 0 || len(filter.Exclude) > 0 {
+			opts.Filters = append(opts.Filters, filter)
+		}
+	}
+
+	return opts
+}
+
+// sanitizeValues sanitizes filter values, dropping those left empty
+func sanitizeValues(values []string) []string {
+	var sanitized []string
+	for _, value := range values {
+		if value = utils.SanitizeString(value); value != "" {
+			sanitized = append(sanitized, value)
+		}
+	}
+	return sanitized
+}
+
+// getDateRange restricts opts to ?from=&to= (YYYY-MM-DD, both inclusive)
+func getDateRange(r *http.Request, opts *models.QueryOptions) error {
+	dates, err := httpquery.ParseDateRange(r.URL.Query())
+	if err != nil {
+		return err
+	}
+	if dates.From != "" {
+		opts.From, _ = time.Parse(httpquery.DateLayout, dates.From)
+	}
+	if dates.To != "" {
+		to, _ := time.Parse(httpquery.DateLayout, dates.To)
+		opts.To = to.AddDate(0, 0, 1)
+	}
+	return nil
+}
+
+// getFormatter returns the display formatter requested with ?locale= (and
+// optionally ?currency=), or nil when the client only wants raw numbers.
+// ?locale=auto negotiates the locale from Accept-Language.
+func getFormatter(r *http.Request) (*format.Formatter, error) {
+	tag := utils.SanitizeString(r.URL.Query().Get("locale"))
+	if tag == "" {
+		return nil, nil
+	}
+
+	var locale format.Locale
+	var ok bool
+	if tag == "auto" {
+		if locale, ok = format.Negotiate(r.Header.Get("Accept-Language")); !ok {
+			locale, ok = format.Lookup("en-US")
+		}
+	} else {
+		locale, ok = format.Lookup(tag)
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", format.ErrUnsupportedLocale, tag)
+	}
+
+	currency := strings.ToUpper(utils.SanitizeString(r.URL.Query().Get("currency")))
+	if currency != "" && !currencyPattern.MatchString(currency) {
+		return nil, fmt.Errorf("invalid currency %q: must be an ISO 4217 code", currency)
+	}
+
+	return format.NewFormatter(locale, currency), nil
+}
+
+// addFormatInfo echoes the locale and currency used for display strings
+func addFormatInfo(response map[string]interface{}, formatter *format.Formatter) {
+	if formatter == nil {
+		return
+	}
+	response["locale"] = formatter.Locale()
+	response["currency"] = formatter.Currency()
+}
+
+// addCoverage adds the period and load time of the underlying data to a
+// response envelope
+func addCoverage(response map[string]interface{}, provider CoverageProvider) {
+	response["coverage"] = provider.DataCoverage()
+}
+
+// addDatasetInfo names the dataset a response was computed from, when it
+// is not the default one
+func addDatasetInfo(response map[string]interface{}, source *analyticsSource) {
+	if source.dataset == "" {
+		return
+	}
+	response["dataset"] = source.dataset
+}
+
+// addSnapshotInfo names the backup an ?as_of= response was computed from
+func addSnapshotInfo(response map[string]interface{}, snapshot *models.BackupInfo) {
+	if snapshot == nil {
+		return
+	}
+	response["snapshot"] = snapshot
+}
+
+// addSampleInfo echoes the sampling rate so clients know the figures are estimates
+func addSampleInfo(response map[string]interface{}, opts models.QueryOptions) {
+	if !opts.Sampled() {
+		return
+	}
+	response["sample_rate"] = opts.SampleRate
+	response["approximate"] = true
+}
+
+// pageLinks sets the RFC 5988 Link header for a page of a list and returns
+// the same links for the response body
+func pageLinks(w http.ResponseWriter, r *http.Request, page httpquery.Page, total int) httpquery.Links {
+	links := page.Links(r.URL, total)
+	w.Header().Set("Link", links.Header())
+	return links
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"analytics-dashboard-api/internal/httpquery"
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/internal/utils"
+	"analytics-dashboard-api/pkg/logger"
+
+	"github.com/gorilla/mux"
+)
+
+type AnnotationService interface {
+	Create(models.Annotation) (*models.Annotation, error)
+	List(string, string) []models.Annotation
+	Delete(string) error
+}
+
+type AnnotationHandler struct {
+	annotationService AnnotationService
+	logger            logger.Logger
+}
+
+func NewAnnotationHandler(annotationService AnnotationService, logger logger.Logger) *AnnotationHandler {
+	return &AnnotationHandler{
+		annotationService: annotationService,
+		logger:            logger,
+	}
+}
+
+// ListAnnotations returns annotations, optionally limited to ?from=&to= (YYYY-MM-DD)
+func (h *AnnotationHandler) ListAnnotations(w http.ResponseWriter, r *http.Request) {
+	dates, err := httpquery.ParseDateRange(r.URL.Query())
+	if err != nil {
+		h.logger.Debug("Ignoring invalid date range parameter", "error", err)
+	}
+
+	data := h.annotationService.List(dates.From, dates.To)
+
+	utils.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"data":  data,
+		"count": len(data),
+	})
+}
+
+// CreateAnnotation stores a new annotation from the JSON body
+func (h *AnnotationHandler) CreateAnnotation(w http.ResponseWriter, r *http.Request) {
+	var annotation models.Annotation
+	if err := json.NewDecoder(r.Body).Decode(&annotation); err != nil {
+		utils.WriteErrorResponseWithCode(w, http.StatusBadRequest, utils.ErrCodeInvalidBody, "Invalid request body")
+		return
+	}
+
+	annotation.Title = utils.SanitizeString(annotation.Title)
+	if err := utils.ValidateStringNotEmpty(annotation.Title, "title"); err != nil {
+		utils.WriteErrorResponseWithCode(w, http.StatusBadRequest, utils.ErrCodeValidation, err.Error())
+		return
+	}
+
+	created, err := h.annotationService.Create(annotation)
+	if err != nil {
+		writeServiceError(w, h.logger, err, "Failed to create annotation")
+		return
+	}
+
+	utils.WriteJSONResponse(w, http.StatusCreated, created)
+}
+
+// DeleteAnnotation removes an annotation by ID
+func (h *AnnotationHandler) DeleteAnnotation(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	if err := h.annotationService.Delete(id); err != nil {
+		writeServiceError(w, h.logger, err, "Failed to delete annotation", "id", id)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,636 @@
+package handlers
+
+import (
+	"time"
+
+	"analytics-dashboard-api/internal/config"
+	"analytics-dashboard-api/internal/httpquery"
+	"analytics-dashboard-api/internal/models"
+)
+
+// RouteDoc describes a route to API clients: the OpenAPI spec, and the
+// SDKs generated from it, are built from these. Bodies are given as values
+// of their Go type, so the spec carries the same JSON field names the
+// handlers encode.
+type RouteDoc struct {
+	// ID is the operationId generated clients name their method after;
+	// renaming it breaks them
+	ID      string
+	Summary string
+	// Request is the JSON request body, nil for routes without one
+	Request interface{}
+	// Upload lists the content types of a file the route takes as its body
+	Upload []string
+	// Status is the status of a success, 200 when zero
+	Status int
+	// Response is the JSON body of a success, nil when there is none
+	Response interface{}
+	// Files lists the content types of non-JSON bodies the route answers
+	// with instead
+	Files []string
+	// Accepted is the body of a 202, for routes that may answer with a job
+	Accepted interface{}
+}
+
+// Response envelopes. Handlers build these bodies as maps; the types name
+// their fields for the spec.
+
+// list carries a list under data
+type list[T any] struct {
+	Data  []T `json:"data"`
+	Count int `json:"count"`
+}
+
+// page is a list that pages through a larger result
+type page[T any] struct {
+	list[T]
+	Total   int             `json:"total"`
+	Limit   int             `json:"limit"`
+	Offset  int             `json:"offset"`
+	HasMore bool            `json:"has_more"`
+	Links   httpquery.Links `json:"links"`
+}
+
+// sourceInfo describes the data a response was computed from, as added by
+// addSampleInfo, addFormatInfo, addCoverage, addDatasetInfo and
+// addSnapshotInfo
+type sourceInfo struct {
+	SampleRate  float64              `json:"sample_rate,omitempty"`
+	Approximate bool                 `json:"approximate,omitempty"`
+	Locale      string               `json:"locale,omitempty"`
+	Currency    string               `json:"currency,omitempty"`
+	Coverage    *models.DataCoverage `json:"coverage,omitempty"`
+	Dataset     string               `json:"dataset,omitempty"`
+	Snapshot    *models.BackupInfo   `json:"snapshot,omitempty"`
+}
+
+type sourceList[T any] struct {
+	list[T]
+	sourceInfo
+}
+
+type sourcePage[T any] struct {
+	page[T]
+	sourceInfo
+}
+
+type rankedPage[T any] struct {
+	page[T]
+	sourceInfo
+	SortBy string         `json:"sort_by"`
+	Totals *models.Totals `json:"totals,omitempty"`
+}
+
+type analyticsSummaryResponse struct {
+	Message        string                    `json:"message"`
+	CountryRevenue []models.CountryRevenue   `json:"country_revenue,omitempty"`
+	TopProducts    []models.ProductFrequency `json:"top_products,omitempty"`
+	MonthlySales   []models.MonthlySales     `json:"monthly_sales,omitempty"`
+	TopRegions     []models.RegionRevenue    `json:"top_regions,omitempty"`
+	Summary        *analyticsSummary         `json:"summary,omitempty"`
+	Partial        bool                      `json:"partial,omitempty"`
+	Errors         []models.SectionError     `json:"errors,omitempty"`
+	sourceInfo
+}
+
+type analyticsSummary struct {
+	TotalRecords        int               `json:"total_records"`
+	ProcessingTimeMs    int64             `json:"processing_time_ms"`
+	CacheHit            bool              `json:"cache_hit"`
+	TotalRevenue        models.Money      `json:"total_revenue"`
+	CountryRevenueCount int               `json:"country_revenue_count,omitempty"`
+	TopProductsCount    int               `json:"top_products_count,omitempty"`
+	MonthlySalesCount   int               `json:"monthly_sales_count,omitempty"`
+	TopRegionsCount     int               `json:"top_regions_count,omitempty"`
+	Display             map[string]string `json:"display,omitempty"`
+}
+
+type analyticsStatsResponse struct {
+	DataVersion         uint64              `json:"data_version"`
+	ComputedAt          time.Time           `json:"computed_at"`
+	TotalRecords        int                 `json:"total_records"`
+	ProcessingTimeMs    int64               `json:"processing_time_ms"`
+	CacheHit            bool                `json:"cache_hit"`
+	CountryRevenueCount int                 `json:"country_revenue_count"`
+	TopProductsCount    int                 `json:"top_products_count"`
+	MonthlySalesCount   int                 `json:"monthly_sales_count"`
+	TopRegionsCount     int                 `json:"top_regions_count"`
+	UniqueCustomers     int                 `json:"unique_customers"`
+	UniqueProducts      int                 `json:"unique_products"`
+	RepeatCustomers     int                 `json:"repeat_customers"`
+	RepeatPurchaseRate  float64             `json:"repeat_purchase_rate"`
+	Endpoints           map[string]string   `json:"endpoints"`
+	DerivedMetrics      map[string]*float64 `json:"derived_metrics,omitempty"`
+	Coverage            models.DataCoverage `json:"coverage"`
+}
+
+type countryRevenueResponse struct {
+	sourcePage[models.CountryRevenue]
+	Totals *models.Totals `json:"totals,omitempty"`
+}
+
+type topCustomersResponse struct {
+	sourceList[models.CustomerSpend]
+	Limit int `json:"limit"`
+}
+
+type countryDetailResponse struct {
+	Data  models.CountryDetail `json:"data"`
+	Limit int                  `json:"limit"`
+	sourceInfo
+}
+
+type aggregateResponse struct {
+	sourceList[models.MetricResult]
+	GroupBy string   `json:"group_by"`
+	Metrics []string `json:"metrics"`
+}
+
+type contributionResponse struct {
+	sourceList[models.ContributionBreakdown]
+	Metric         string                    `json:"metric"`
+	Period         models.ContributionPeriod `json:"period"`
+	PreviousPeriod models.ContributionPeriod `json:"previous_period"`
+	Total          models.ContributionTotal  `json:"total"`
+}
+
+type abcResponse struct {
+	sourcePage[models.ABCProduct]
+	Cutoffs      models.ABCCutoffs        `json:"cutoffs"`
+	TotalRevenue float64                  `json:"total_revenue"`
+	Classes      []models.ABCClassSummary `json:"classes"`
+}
+
+type heatmapResponse struct {
+	sourceList[models.HeatmapCell]
+	Grid         models.HeatmapGrid `json:"grid"`
+	TotalRevenue models.Money       `json:"total_revenue"`
+	MaxRevenue   models.Money       `json:"max_revenue"`
+	Countries    int                `json:"countries"`
+}
+
+type timeSeriesResponse struct {
+	Granularity string               `json:"granularity"`
+	Metrics     []string             `json:"metrics"`
+	Dates       []string             `json:"dates"`
+	Series      map[string][]float64 `json:"series"`
+	Count       int                  `json:"count"`
+	Smooth      string               `json:"smooth,omitempty"`
+	Smoothed    map[string][]float64 `json:"smoothed,omitempty"`
+	sourceInfo
+}
+
+type calendarResponse struct {
+	sourceList[models.CalendarDay]
+	Year     int     `json:"year"`
+	Metric   string  `json:"metric"`
+	Total    float64 `json:"total"`
+	MaxValue float64 `json:"max_value"`
+}
+
+// refreshResponse answers a refresh waited for, or with dry_run and
+// source, a dry run
+type refreshResponse struct {
+	Message      string                   `json:"message,omitempty"`
+	JobID        uint64                   `json:"job_id,omitempty"`
+	TotalRecords int                      `json:"total_records,omitempty"`
+	Backup       *models.BackupInfo       `json:"backup,omitempty"`
+	DryRun       bool                     `json:"dry_run,omitempty"`
+	Source       *models.SourceInspection `json:"source,omitempty"`
+	DurationMs   int64                    `json:"duration_ms"`
+}
+
+type productResponse struct {
+	Product models.Product      `json:"product"`
+	Sales   models.ProductSales `json:"sales"`
+	sourceInfo
+}
+
+type priceHistoryResponse struct {
+	sourceList[models.PricePoint]
+	ProductID string `json:"product_id"`
+}
+
+type sellThroughResponse struct {
+	sourcePage[models.SellThrough]
+	By     string                   `json:"by"`
+	Window models.SellThroughWindow `json:"window"`
+	Sort   string                   `json:"sort"`
+}
+
+type dimensionValuesResponse struct {
+	page[models.DimensionValue]
+	Dimension string `json:"dimension"`
+}
+
+type dataProfileResponse struct {
+	DataVersion uint64                 `json:"data_version"`
+	ComputedAt  time.Time              `json:"computed_at"`
+	CacheHit    bool                   `json:"cache_hit"`
+	Table       string                 `json:"table"`
+	Rows        int64                  `json:"rows"`
+	Columns     []models.ColumnProfile `json:"columns"`
+}
+
+type outliersResponse struct {
+	page[models.Outlier]
+	Report *models.OutlierReport `json:"report"`
+}
+
+type datasetUploadResponse struct {
+	Message     string                `json:"message"`
+	File        string                `json:"file"`
+	SizeBytes   int64                 `json:"size_bytes"`
+	Columns     []models.SourceColumn `json:"columns"`
+	DataVersion uint64                `json:"data_version"`
+	Coverage    models.DataCoverage   `json:"coverage"`
+}
+
+type targetsUploadResponse struct {
+	Message string `json:"message"`
+	Records int    `json:"records"`
+}
+
+type varianceResponse struct {
+	list[models.TargetVariance]
+	Totals varianceTotals `json:"totals"`
+	sourceInfo
+}
+
+type varianceTotals struct {
+	Target   models.Money      `json:"target"`
+	Actual   models.Money      `json:"actual"`
+	Variance models.Money      `json:"variance"`
+	Display  map[string]string `json:"display,omitempty"`
+}
+
+type metricsResponse struct {
+	list[models.DerivedMetric]
+	Available []string `json:"available"`
+}
+
+type nlQueryResponse struct {
+	list[models.MetricResult]
+	Question       string                  `json:"question"`
+	Interpretation models.NLInterpretation `json:"interpretation"`
+	Experimental   bool                    `json:"experimental"`
+}
+
+type backupResponse struct {
+	Message string             `json:"message"`
+	Backup  *models.BackupInfo `json:"backup"`
+}
+
+// restoreRequest selects a backup; the latest is restored without one
+type restoreRequest struct {
+	Name string `json:"name,omitempty"`
+}
+
+type rollbackResponse struct {
+	Message     string               `json:"message"`
+	DataVersion uint64               `json:"data_version"`
+	Restored    *models.DataSnapshot `json:"restored"`
+}
+
+type adminStatsResponse struct {
+	Jobs   models.JobQueueStats      `json:"jobs"`
+	Loader models.LoaderStats        `json:"loader"`
+	Cache  models.ResponseCacheStats `json:"cache"`
+}
+
+type backupsResponse struct {
+	list[models.StoredBackup]
+	TotalSizeBytes int64                        `json:"total_size_bytes"`
+	Retention      models.BackupRetentionPolicy `json:"retention"`
+}
+
+type partitionsResponse struct {
+	list[models.Partition]
+	TotalRows int `json:"total_rows"`
+}
+
+type debugCaptureResponse struct {
+	list[models.CapturedExchange]
+	State models.DebugCaptureState `json:"state"`
+}
+
+type flagsResponse struct {
+	list[models.TransactionFlag]
+	Excluded int `json:"excluded"`
+}
+
+// csvUpload are the ways a CSV file can be sent: as the body, or as the
+// "file" field of a form
+var csvUpload = []string{"text/csv", "multipart/form-data"}
+
+// RouteDocs describes every route under /api/v1, keyed like QueryParamSpecs
+// by "METHOD /path/template". Keep in sync with setupRouter: the server
+// logs the routes it serves that are missing here. Adding a field to a
+// documented type changes the spec; renaming or removing one breaks
+// generated clients.
+var RouteDocs = map[string]RouteDoc{
+	"GET /api/v1/analytics": {
+		ID: "getAnalytics", Summary: "Dashboard summary",
+		Response: analyticsSummaryResponse{},
+	},
+	"GET /api/v1/analytics/stats": {
+		ID: "getAnalyticsStats", Summary: "Record counts and distinct values of the loaded data",
+		Response: analyticsStatsResponse{},
+	},
+	"GET /api/v1/analytics/country-revenue": {
+		ID: "getCountryRevenue", Summary: "Revenue by country and product",
+		Response: countryRevenueResponse{},
+	},
+	"GET /api/v1/analytics/top-products": {
+		ID: "getTopProducts", Summary: "Products ranked by purchases or revenue",
+		Response: rankedPage[models.ProductFrequency]{},
+	},
+	"GET /api/v1/analytics/monthly-sales": {
+		ID: "getMonthlySales", Summary: "Sales volume per month",
+		Response: sourceList[models.MonthlySales]{},
+	},
+	"GET /api/v1/analytics/top-regions": {
+		ID: "getTopRegions", Summary: "Regions ranked by revenue or items sold",
+		Response: rankedPage[models.RegionRevenue]{},
+	},
+	"GET /api/v1/analytics/segments": {
+		ID: "getSegments", Summary: "Revenue by customer segment",
+		Response: sourceList[models.SegmentRevenue]{},
+	},
+	"GET /api/v1/analytics/top-customers": {
+		ID: "getTopCustomers", Summary: "Customers ranked by spend",
+		Response: topCustomersResponse{},
+	},
+	"GET /api/v1/analytics/countries/{country}": {
+		ID: "getCountryDetail", Summary: "Revenue over time, top products and top regions of one country",
+		Response: countryDetailResponse{},
+	},
+	"GET /api/v1/analytics/aggregate": {
+		ID: "getAggregate", Summary: "Metrics grouped by a dimension",
+		Response: aggregateResponse{},
+	},
+	"GET /api/v1/analytics/contribution": {
+		ID: "getContribution", Summary: "What drove a metric's change between two periods",
+		Response: contributionResponse{},
+	},
+	"GET /api/v1/analytics/abc": {
+		ID: "getABC", Summary: "ABC classification of products by revenue",
+		Response: abcResponse{},
+	},
+	"GET /api/v1/analytics/heatmap": {
+		ID: "getHeatmap", Summary: "Revenue by country and category",
+		Response: heatmapResponse{},
+	},
+	"GET /api/v1/analytics/timeseries": {
+		ID: "getTimeSeries", Summary: "Metrics per day, week or month",
+		Response: timeSeriesResponse{},
+	},
+	"GET /api/v1/analytics/calendar": {
+		ID: "getCalendar", Summary: "A metric per day of a year",
+		Response: calendarResponse{},
+	},
+	"GET /api/v1/analytics/plan-vs-actual": {
+		ID: "getPlanVsActual", Summary: "Revenue against targets",
+		Response: varianceResponse{},
+	},
+	"POST /api/v1/analytics/refresh": {
+		ID: "refreshData", Summary: "Reload the data files",
+		Response: refreshResponse{}, Accepted: models.JobInfo{},
+	},
+	"GET /api/v1/analytics/refresh": {
+		ID: "getRefreshStatus", Summary: "State of the latest refresh",
+		Response: models.RefreshStatus{},
+	},
+
+	"GET /api/v1/products": {
+		ID: "listProducts", Summary: "Products, optionally searched by name",
+		Response: page[models.Product]{},
+	},
+	"GET /api/v1/products/{id}": {
+		ID: "getProduct", Summary: "A product and its sales",
+		Response: productResponse{},
+	},
+	"GET /api/v1/products/{id}/price-history": {
+		ID: "getPriceHistory", Summary: "A product's price per month",
+		Response: priceHistoryResponse{},
+	},
+	"GET /api/v1/inventory/sell-through": {
+		ID: "getSellThrough", Summary: "Sell-through rate by product or category",
+		Response: sellThroughResponse{},
+	},
+
+	"GET /api/v1/meta/values": {
+		ID: "getDimensionValues", Summary: "Distinct values of a dimension",
+		Response: dimensionValuesResponse{},
+	},
+	"GET /api/v1/data/profile": {
+		ID: "getDataProfile", Summary: "Per-column profile of the transactions table",
+		Response: dataProfileResponse{},
+	},
+	"GET /api/v1/data/outliers": {
+		ID: "listOutliers", Summary: "Transactions flagged as outliers at load",
+		Response: outliersResponse{},
+	},
+
+	"GET /api/v1/datasets": {
+		ID: "listDatasets", Summary: "Named datasets",
+		Response: list[models.DatasetInfo]{},
+	},
+	"POST /api/v1/datasets": {
+		ID: "uploadDataset", Summary: "Replace the transactions with an uploaded CSV file",
+		Upload: csvUpload, Response: datasetUploadResponse{},
+	},
+	"POST /api/v1/targets": {
+		ID: "uploadTargets", Summary: "Load revenue targets from a CSV file",
+		Upload: csvUpload, Response: targetsUploadResponse{},
+	},
+
+	"POST /api/v1/uploads": {
+		ID: "createUpload", Summary: "Start a resumable upload of Upload-Length bytes",
+		Status: 201, Response: models.Upload{},
+	},
+	"GET /api/v1/uploads/{id}": {
+		ID: "getUpload", Summary: "Bytes received so far by a resumable upload",
+		Response: models.Upload{},
+	},
+	"PATCH /api/v1/uploads/{id}": {
+		ID: "appendUpload", Summary: "Send a chunk of a resumable upload from Upload-Offset",
+		Upload: []string{"application/offset+octet-stream"}, Response: models.Upload{},
+	},
+	"DELETE /api/v1/uploads/{id}": {
+		ID: "deleteUpload", Summary: "Discard a resumable upload", Status: 204,
+	},
+
+	"GET /api/v1/exports": {
+		ID: "listExports", Summary: "Export jobs",
+		Response: list[models.ExportJob]{},
+	},
+	"POST /api/v1/exports": {
+		ID: "createExport", Summary: "Start an export of the transactions",
+		Request: models.ExportRequest{}, Status: 202, Response: models.ExportJob{},
+	},
+	"GET /api/v1/exports/{id}": {
+		ID: "getExport", Summary: "An export job",
+		Response: models.ExportJob{},
+	},
+	"DELETE /api/v1/exports/{id}": {
+		ID: "deleteExport", Summary: "Delete an export and its file", Status: 204,
+	},
+	"POST /api/v1/exports/{id}/cancel": {
+		ID: "cancelExport", Summary: "Cancel a running export",
+		Response: models.ExportJob{},
+	},
+	"GET /api/v1/exports/{id}/download": {
+		ID: "downloadExport", Summary: "The file of a finished export",
+		Files: []string{"text/csv", "application/vnd.apache.parquet"},
+	},
+
+	"GET /api/v1/reports": {
+		ID: "listReports", Summary: "Report templates",
+		Response: list[models.ReportTemplateInfo]{},
+	},
+	"GET /api/v1/reports/{name}": {
+		ID: "getReport", Summary: "Render a report as HTML, PDF or JSON",
+		Response: models.Report{}, Files: []string{"text/html", "application/pdf"},
+	},
+
+	"GET /api/v1/annotations": {
+		ID: "listAnnotations", Summary: "Annotations, optionally within a date range",
+		Response: list[models.Annotation]{},
+	},
+	"POST /api/v1/annotations": {
+		ID: "createAnnotation", Summary: "Annotate a date",
+		Request: models.Annotation{}, Status: 201, Response: models.Annotation{},
+	},
+	"DELETE /api/v1/annotations/{id}": {
+		ID: "deleteAnnotation", Summary: "Delete an annotation", Status: 204,
+	},
+
+	"GET /api/v1/preferences": {
+		ID: "getPreferences", Summary: "The caller's saved dashboard defaults",
+		Response: models.Preferences{},
+	},
+	"PUT /api/v1/preferences": {
+		ID: "updatePreferences", Summary: "Save the caller's dashboard defaults",
+		Request: models.Preferences{}, Response: models.Preferences{},
+	},
+	"DELETE /api/v1/preferences": {
+		ID: "deletePreferences", Summary: "Forget the caller's dashboard defaults", Status: 204,
+	},
+
+	"GET /api/v1/metrics": {
+		ID: "listMetrics", Summary: "Derived metrics",
+		Response: metricsResponse{},
+	},
+	"POST /api/v1/metrics": {
+		ID: "createMetric", Summary: "Define a derived metric",
+		Request: models.DerivedMetric{}, Status: 201, Response: models.DerivedMetric{},
+	},
+	"DELETE /api/v1/metrics/{name}": {
+		ID: "deleteMetric", Summary: "Delete a derived metric", Status: 204,
+	},
+	"POST /api/v1/query/nl": {
+		ID: "queryNaturalLanguage", Summary: "Answer a question in plain English",
+		Request: models.NLQueryRequest{}, Response: nlQueryResponse{},
+	},
+
+	"GET /api/v1/alerts/rules": {
+		ID: "listAlertRules", Summary: "Alert rules",
+		Response: list[models.AlertRule]{},
+	},
+	"POST /api/v1/alerts/rules": {
+		ID: "createAlertRule", Summary: "Add an alert rule",
+		Request: models.AlertRule{}, Status: 201, Response: models.AlertRule{},
+	},
+	"DELETE /api/v1/alerts/rules/{id}": {
+		ID: "deleteAlertRule", Summary: "Delete an alert rule", Status: 204,
+	},
+	"GET /api/v1/alerts/history": {
+		ID: "listAlerts", Summary: "Alerts fired, newest first",
+		Response: list[models.Alert]{},
+	},
+
+	"GET /api/v1/jobs/{id}": {
+		ID: "getJob", Summary: "A queued, running or finished job",
+		Response: models.JobInfo{},
+	},
+	"GET /api/v1/refresh/schedule": {
+		ID: "getRefreshSchedule", Summary: "The scheduled refresh",
+		Response: models.RefreshSchedule{},
+	},
+	"PUT /api/v1/refresh/schedule": {
+		ID: "updateRefreshSchedule", Summary: "Change the scheduled refresh",
+		Request: models.RefreshScheduleRequest{}, Response: models.RefreshSchedule{},
+	},
+
+	"POST /api/v1/admin/backup": {
+		ID: "createBackup", Summary: "Back up the loaded data",
+		Status: 201, Response: backupResponse{},
+	},
+	"POST /api/v1/admin/restore": {
+		ID: "restoreBackup", Summary: "Replace the loaded data with a backup",
+		Request: restoreRequest{}, Response: backupResponse{},
+	},
+	"POST /api/v1/admin/rollback": {
+		ID: "rollbackData", Summary: "Return to the data loaded before the latest load",
+		Response: rollbackResponse{},
+	},
+	"GET /api/v1/admin/stats": {
+		ID: "getAdminStats", Summary: "Job queue, loader and cache counters",
+		Response: adminStatsResponse{},
+	},
+	"GET /api/v1/admin/audit": {
+		ID: "listAuditEvents", Summary: "Audit log, newest first",
+		Response: list[models.AuditEvent]{},
+	},
+	"GET /api/v1/admin/backups": {
+		ID: "listBackups", Summary: "Stored backups and their retention",
+		Response: backupsResponse{},
+	},
+	"GET /api/v1/admin/partitions": {
+		ID: "listPartitions", Summary: "Partitions of the transactions table",
+		Response: partitionsResponse{},
+	},
+	"GET /api/v1/admin/diagnostics": {
+		ID: "getDiagnostics", Summary: "Run the support diagnostics",
+		Response: models.DiagnosticsReport{},
+	},
+	"GET /api/v1/admin/config": {
+		ID: "getConfig", Summary: "The configuration in effect, secrets redacted",
+		Response: config.EffectiveConfig{},
+	},
+	"GET /api/v1/admin/debug-capture": {
+		ID: "listDebugCaptures", Summary: "Failed requests kept by the debug capture",
+		Response: debugCaptureResponse{},
+	},
+	"PUT /api/v1/admin/debug-capture": {
+		ID: "updateDebugCapture", Summary: "Switch the debug capture on or off",
+		Request: models.DebugCaptureRequest{}, Response: models.DebugCaptureState{},
+	},
+	"DELETE /api/v1/admin/debug-capture": {
+		ID: "clearDebugCaptures", Summary: "Discard the captured requests", Status: 204,
+	},
+	"GET /api/v1/admin/flags": {
+		ID: "listFlags", Summary: "Transactions flagged out of the analytics",
+		Response: flagsResponse{},
+	},
+	"POST /api/v1/admin/flags": {
+		ID: "createFlags", Summary: "Flag transactions and reload the data",
+		Request: models.FlagRequest{}, Status: 201, Response: flagsResponse{},
+	},
+	"DELETE /api/v1/admin/flags/{id}": {
+		ID: "deleteFlag", Summary: "Unflag a transaction and reload the data", Status: 204,
+	},
+	"GET /api/v1/openapi.json": {
+		ID: "getOpenAPISpec", Summary: "This API's OpenAPI description",
+		Response: map[string]interface{}{},
+	},
+}
+
+func init() {
+	for _, path := range datasetRoutes {
+		doc := RouteDocs["GET /api/v1"+path]
+		doc.ID += "ForDataset"
+		RouteDocs["GET /api/v1/datasets/{dataset}"+path] = doc
+	}
+}
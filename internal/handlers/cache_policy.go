@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"analytics-dashboard-api/internal/config"
+)
+
+// Cache classes group endpoints by how fresh their responses must be
+const (
+	CacheClassKPI        = "kpi"
+	CacheClassHistorical = "historical"
+	CacheClassReference  = "reference"
+)
+
+// CacheClasses assigns the cacheable GET routes, as registered under
+// /api/v1, to a cache class. Routes not listed here are never cached.
+var CacheClasses = map[string]string{
+	"/analytics":                     CacheClassKPI,
+	"/analytics/stats":               CacheClassKPI,
+	"/analytics/aggregate":           CacheClassKPI,
+	"/analytics/country-revenue":     CacheClassKPI,
+	"/analytics/top-products":        CacheClassKPI,
+	"/analytics/top-regions":         CacheClassKPI,
+	"/analytics/segments":            CacheClassKPI,
+	"/analytics/top-customers":       CacheClassKPI,
+	"/analytics/countries/{country}": CacheClassKPI,
+	"/analytics/contribution":        CacheClassKPI,
+	"/analytics/abc":                 CacheClassKPI,
+	"/analytics/heatmap":             CacheClassKPI,
+	"/inventory/sell-through":        CacheClassKPI,
+
+	"/analytics/monthly-sales":     CacheClassHistorical,
+	"/analytics/plan-vs-actual":    CacheClassHistorical,
+	"/analytics/timeseries":        CacheClassHistorical,
+	"/analytics/calendar":          CacheClassHistorical,
+	"/products/{id}/price-history": CacheClassHistorical,
+
+	"/products":      CacheClassReference,
+	"/products/{id}": CacheClassReference,
+	"/meta/values":   CacheClassReference,
+	"/data/profile":  CacheClassReference,
+	"/openapi.json":  CacheClassReference,
+}
+
+// CacheTTLs resolves the TTL of every cacheable route, keyed by
+// "GET /api/v1/path" for middleware.ResponseCache. A route listed in
+// cfg.Overrides takes that TTL instead of its class TTL; zero disables
+// caching for it.
+func CacheTTLs(cfg config.CacheConfig) (map[string]time.Duration, error) {
+	classTTLs := map[string]time.Duration{
+		CacheClassKPI:        cfg.KPITTL,
+		CacheClassHistorical: cfg.HistoricalTTL,
+		CacheClassReference:  cfg.ReferenceTTL,
+	}
+
+	ttls := make(map[string]time.Duration, len(CacheClasses))
+	for path, class := range CacheClasses {
+		ttls["GET /api/v1"+path] = classTTLs[class]
+	}
+
+	for _, pair := range strings.Split(cfg.Overrides, ";") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		path, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid cache TTL override %q: want path=duration", pair)
+		}
+		path = strings.TrimSpace(path)
+		if _, known := CacheClasses[path]; !known {
+			return nil, fmt.Errorf("invalid cache TTL override %q: %s is not a cacheable route", pair, path)
+		}
+		ttl, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil || ttl < 0 {
+			return nil, fmt.Errorf("invalid cache TTL override %q: bad duration", pair)
+		}
+		ttls["GET /api/v1"+path] = ttl
+	}
+	return ttls, nil
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"analytics-dashboard-api/internal/httpquery"
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/internal/utils"
+	"analytics-dashboard-api/pkg/logger"
+)
+
+// ContributionService decomposes a period-over-period change by group
+type ContributionService interface {
+	Analyze(context.Context, models.ContributionRequest) (*models.ContributionAnalysis, error)
+}
+
+// ContributionHandler serves the contribution analysis endpoint
+type ContributionHandler struct {
+	contributions ContributionService
+	initializer   Initializer
+	coverage      CoverageProvider
+	logger        logger.Logger
+}
+
+func NewContributionHandler(contributions ContributionService, initializer Initializer, coverage CoverageProvider, logger logger.Logger) *ContributionHandler {
+	return &ContributionHandler{
+		contributions: contributions,
+		initializer:   initializer,
+		coverage:      coverage,
+		logger:        logger,
+	}
+}
+
+// GetContribution explains how a metric changed from the period before
+// ?from=&to= (YYYY-MM-DD, inclusive) by the countries, products and
+// categories that drove it (?metric=revenue&by=country,product&limit=10)
+func (h *ContributionHandler) GetContribution(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	dates, err := httpquery.ParseDateRange(query)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	request := models.ContributionRequest{
+		Metric:  utils.SanitizeString(query.Get("metric")),
+		Filters: getQueryOptions(r),
+	}
+	request.From, _ = time.Parse(httpquery.DateLayout, dates.From)
+	request.To, _ = time.Parse(httpquery.DateLayout, dates.To)
+	for _, dimension := range strings.Split(query.Get("by"), ",") {
+		if dimension = strings.TrimSpace(dimension); dimension != "" {
+			request.Dimensions = append(request.Dimensions, dimension)
+		}
+	}
+	if value := query.Get("limit"); value != "" {
+		if request.Limit, err = strconv.Atoi(value); err != nil || request.Limit < 1 {
+			utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid limit parameter")
+			return
+		}
+	}
+
+	if err := h.initializer.EnsureInitialized(r.Context()); err != nil {
+		writeServiceError(w, h.logger, err, "Failed to initialize database")
+		return
+	}
+
+	analysis, err := h.contributions.Analyze(r.Context(), request)
+	if err != nil {
+		writeServiceError(w, h.logger, err, "Failed to analyze contributions")
+		return
+	}
+
+	response := map[string]interface{}{
+		"metric":          analysis.Metric,
+		"period":          analysis.Period,
+		"previous_period": analysis.PreviousPeriod,
+		"total":           analysis.Total,
+		"data":            analysis.Breakdowns,
+		"count":           len(analysis.Breakdowns),
+	}
+	addSampleInfo(response, request.Filters)
+	addCoverage(response, h.coverage)
+
+	utils.WriteJSONResponse(w, http.StatusOK, response)
+}
@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"analytics-dashboard-api/internal/config"
+	"analytics-dashboard-api/internal/middleware"
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/internal/utils"
+	"analytics-dashboard-api/pkg/logger"
+)
+
+// DatasetLoader loads uploaded datasets in place of the configured source
+type DatasetLoader interface {
+	LoadUpload(context.Context, models.DatasetUpload) error
+	Version() uint64
+}
+
+// DatasetInspector validates a transactions file without loading it
+type DatasetInspector interface {
+	InspectSource(context.Context, string, string) (*models.SourceInspection, error)
+	CoverageProvider
+}
+
+// DatasetLister describes the datasets an instance serves
+type DatasetLister interface {
+	List() []models.DatasetInfo
+}
+
+// DatasetHandler lists the datasets served and accepts ad-hoc transaction
+// datasets over the API
+type DatasetHandler struct {
+	loader    DatasetLoader
+	inspector DatasetInspector
+	datasets  DatasetLister
+	uploads   UploadSource
+	scanner   FileScanner
+	config    config.UploadConfig
+	logger    logger.Logger
+}
+
+func NewDatasetHandler(
+	loader DatasetLoader,
+	inspector DatasetInspector,
+	datasets DatasetLister,
+	uploads UploadSource,
+	scanner FileScanner,
+	config config.UploadConfig,
+	logger logger.Logger,
+) *DatasetHandler {
+	return &DatasetHandler{
+		loader:    loader,
+		inspector: inspector,
+		datasets:  datasets,
+		uploads:   uploads,
+		scanner:   scanner,
+		config:    config,
+		logger:    logger,
+	}
+}
+
+// ListDatasets returns the default dataset and the named ones configured
+// with DATASETS, with their load state
+func (h *DatasetHandler) ListDatasets(w http.ResponseWriter, r *http.Request) {
+	datasets := h.datasets.List()
+	utils.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"data":  datasets,
+		"count": len(datasets),
+	})
+}
+
+// UploadDataset replaces the loaded transactions with an uploaded CSV, sent
+// as a multipart "file" field, as a text/csv request body or as a completed
+// resumable upload named by ?upload_id=. The file is scanned and validated
+// first, and is queryable as soon as the response arrives. The next refresh
+// loads the configured source again.
+func (h *DatasetHandler) UploadDataset(w http.ResponseWriter, r *http.Request) {
+	uploadID := r.URL.Query().Get("upload_id")
+
+	var file *models.UploadedFile
+	var err error
+	if uploadID != "" {
+		if file, err = h.uploads.File(uploadID); err != nil {
+			writeServiceError(w, h.logger, err, "Failed to use upload", "upload", uploadID)
+			return
+		}
+	} else {
+		r.Body = http.MaxBytesReader(w, r.Body, h.config.MaxBytes)
+		if file, err = saveUploadedCSV(r, "dataset-*.csv"); err != nil {
+			h.logger.Warn("Rejected dataset upload", "error", err)
+			utils.WriteErrorResponseWithCode(w, http.StatusBadRequest, utils.ErrCodeInvalidBody, "Invalid dataset upload: "+err.Error())
+			return
+		}
+	}
+	path := file.Path
+
+	// discard drops the file unless it is a resumable upload that may be
+	// fixed and retried; refused ones are discarded too
+	discard := func(err error) {
+		switch {
+		case uploadID == "":
+			os.Remove(path)
+		case err == nil || errors.Is(err, models.ErrUploadRejected) ||
+			errors.Is(err, models.ErrQuotaExceeded) || errors.Is(err, models.ErrInvalidDataset):
+			if err := h.uploads.Delete(uploadID); err != nil {
+				h.logger.Warn("Failed to remove upload", "upload", uploadID, "error", err)
+			}
+		}
+	}
+
+	file.UploadedBy, _ = middleware.IdentityFromContext(r.Context())
+	if err := h.scanner.Scan(r.Context(), *file); err != nil {
+		discard(err)
+		writeServiceError(w, h.logger, err, "Failed to scan dataset upload")
+		return
+	}
+
+	inspection, err := h.inspector.InspectSource(r.Context(), models.DataFormatCSV, path)
+	if err == nil && !inspection.Valid {
+		err = fmt.Errorf("%w: %s", models.ErrInvalidDataset, strings.Join(inspection.Problems, "; "))
+	}
+	if err != nil {
+		discard(err)
+		writeServiceError(w, h.logger, err, "Failed to inspect dataset upload")
+		return
+	}
+
+	// The load job owns the file from here: it may still be queued when
+	// the client leaves
+	err = h.loader.LoadUpload(r.Context(), models.DatasetUpload{
+		Paths:   []string{path},
+		Format:  models.DataFormatCSV,
+		Tenant:  file.UploadedBy,
+		Release: discard,
+	})
+	if err != nil {
+		writeServiceError(w, h.logger, err, "Failed to load dataset", "tenant", file.UploadedBy)
+		return
+	}
+
+	h.logger.Info("Dataset uploaded", "file", file.Name, "bytes", file.Size, "tenant", file.UploadedBy)
+	response := map[string]interface{}{
+		"message":      "Dataset loaded successfully",
+		"file":         file.Name,
+		"size_bytes":   file.Size,
+		"columns":      inspection.Columns,
+		"data_version": h.loader.Version(),
+	}
+	addCoverage(response, h.inspector)
+	utils.WriteJSONResponse(w, http.StatusOK, response)
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/internal/utils"
+	"analytics-dashboard-api/pkg/logger"
+)
+
+// DebugCaptureService keeps failed requests for inspection and is switched
+// on and off at runtime
+type DebugCaptureService interface {
+	State() models.DebugCaptureState
+	Configure(models.DebugCaptureRequest) (models.DebugCaptureState, error)
+	List() []models.CapturedExchange
+	Clear()
+}
+
+// DebugCaptureHandler lets operators turn the debug capture on and read the
+// failed requests it kept
+type DebugCaptureHandler struct {
+	capture DebugCaptureService
+	logger  logger.Logger
+}
+
+func NewDebugCaptureHandler(capture DebugCaptureService, logger logger.Logger) *DebugCaptureHandler {
+	return &DebugCaptureHandler{
+		capture: capture,
+		logger:  logger,
+	}
+}
+
+// GetCaptures returns the capture's setting and the failed exchanges it
+// kept, newest first
+func (h *DebugCaptureHandler) GetCaptures(w http.ResponseWriter, r *http.Request) {
+	captures := h.capture.List()
+	utils.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"state": h.capture.State(),
+		"data":  captures,
+		"count": len(captures),
+	})
+}
+
+// UpdateCapture switches the capture on or off and sets its sample rate
+// from the JSON body; omitted fields keep their value
+func (h *DebugCaptureHandler) UpdateCapture(w http.ResponseWriter, r *http.Request) {
+	var request models.DebugCaptureRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		utils.WriteErrorResponseWithCode(w, http.StatusBadRequest, utils.ErrCodeInvalidBody, "Invalid request body")
+		return
+	}
+
+	state, err := h.capture.Configure(request)
+	if err != nil {
+		writeServiceError(w, h.logger, err, "Failed to configure debug capture")
+		return
+	}
+	h.logger.Info("Debug capture configured", "enabled", state.Enabled, "sample_rate", state.SampleRate)
+
+	utils.WriteJSONResponse(w, http.StatusOK, state)
+}
+
+// ClearCaptures discards the kept exchanges
+func (h *DebugCaptureHandler) ClearCaptures(w http.ResponseWriter, r *http.Request) {
+	h.capture.Clear()
+	w.WriteHeader(http.StatusNoContent)
+}
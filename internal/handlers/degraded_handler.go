@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"analytics-dashboard-api/internal/utils"
+)
+
+// DegradedHandler serves the probes while the data backend could not be
+// started, so the server stays up to report why instead of exiting. Every
+// other route answers 503.
+type DegradedHandler struct {
+	backend   string
+	cause     error
+	startTime time.Time
+}
+
+func NewDegradedHandler(backend string, cause error) *DegradedHandler {
+	return &DegradedHandler{backend: backend, cause: cause, startTime: time.Now()}
+}
+
+// diagnostic names the backend that failed and why, with the settings that
+// get the server running again
+func (h *DegradedHandler) diagnostic() map[string]interface{} {
+	return map[string]interface{}{
+		"name":  h.backend,
+		"error": h.cause.Error(),
+		"hint":  "fix the backend and restart, or set DATA_BACKEND_FALLBACK=memory to serve from the in-memory backend instead",
+	}
+}
+
+// Health answers 503 with status "degraded" and the backend diagnostic
+func (h *DegradedHandler) Health(w http.ResponseWriter, r *http.Request) {
+	utils.WriteJSONResponse(w, http.StatusServiceUnavailable, map[string]interface{}{
+		"status":    "degraded",
+		"timestamp": time.Now().UTC(),
+		"uptime":    time.Since(h.startTime).String(),
+		"version":   "1.0.0",
+		"backend":   h.diagnostic(),
+	})
+}
+
+// Live answers 200 like the liveness probe of a healthy server: restarting
+// the pod would fail the same way
+func (h *DegradedHandler) Live(w http.ResponseWriter, r *http.Request) {
+	utils.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"status":    "alive",
+		"timestamp": time.Now().UTC(),
+		"uptime":    time.Since(h.startTime).String(),
+	})
+}
+
+// Ready answers 503 for the readiness and startup probes, with the backend
+// diagnostic under checks
+func (h *DegradedHandler) Ready(w http.ResponseWriter, r *http.Request) {
+	utils.WriteJSONResponse(w, http.StatusServiceUnavailable, map[string]interface{}{
+		"status":    "not_ready",
+		"timestamp": time.Now().UTC(),
+		"checks":    map[string]string{"database": h.cause.Error()},
+	})
+}
+
+// Unavailable answers every other route
+func (h *DegradedHandler) Unavailable(w http.ResponseWriter, r *http.Request) {
+	utils.WriteErrorResponse(w, http.StatusServiceUnavailable, "Data backend unavailable, see /health")
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/internal/utils"
+	"analytics-dashboard-api/pkg/logger"
+)
+
+// errorStatuses maps the service error taxonomy to HTTP status codes and
+// error codes, in match order: an initial load that failed on a missing
+// source is reported as data not loaded, not as the missing file
+var errorStatuses = []struct {
+	kind   error
+	status int
+	code   utils.ErrorCode
+}{
+	{models.ErrDataLoading, http.StatusServiceUnavailable, utils.ErrCodeDataLoading},
+	{models.ErrDataNotLoaded, http.StatusServiceUnavailable, utils.ErrCodeDatasetNotLoaded},
+	{models.ErrQueryTimeout, http.StatusGatewayTimeout, utils.ErrCodeQueryTimeout},
+	{models.ErrNotSupported, http.StatusNotImplemented, utils.ErrCodeNotSupported},
+	{models.ErrInvalidParam, http.StatusBadRequest, utils.ErrCodeInvalidParam},
+	{models.ErrValidation, http.StatusBadRequest, utils.ErrCodeValidation},
+	{models.ErrNotFound, http.StatusNotFound, utils.ErrCodeNotFound},
+	{models.ErrSourceMissing, http.StatusNotFound, utils.ErrCodeSourceMissing},
+	{models.ErrConflict, http.StatusConflict, utils.ErrCodeConflict},
+	{models.ErrQuotaExceeded, http.StatusRequestEntityTooLarge, utils.ErrCodeQuotaExceeded},
+}
+
+// ErrorStatus returns the HTTP status for a service error and the taxonomy
+// kind it matched. Unclassified errors are internal server errors. A request
+// deadline that expired outside a query, such as while waiting for a job,
+// counts as a timeout like one that cut a query short.
+func ErrorStatus(err error) (int, error) {
+	status, _, kind := classifyError(err)
+	return status, kind
+}
+
+// ErrorCode returns the error code clients see for a service error
+func ErrorCode(err error) utils.ErrorCode {
+	_, code, _ := classifyError(err)
+	return code
+}
+
+// classifyError matches err against errorStatuses
+func classifyError(err error) (int, utils.ErrorCode, error) {
+	if errors.Is(err, context.DeadlineExceeded) && !errors.Is(err, models.ErrQueryTimeout) {
+		err = fmt.Errorf("%w: %w", models.ErrQueryTimeout, err)
+	}
+	for _, entry := range errorStatuses {
+		if errors.Is(err, entry.kind) {
+			return entry.status, entry.code, entry.kind
+		}
+	}
+	return http.StatusInternalServerError, utils.ErrCodeInternal, nil
+}
+
+// writeServiceError reports a failed service call with the status of its
+// taxonomy kind. Client errors carry the error text so the caller can fix the
+// request; server-side failures only name the kind, never internal details.
+// An in-progress load gets a loading response with Retry-After.
+func writeServiceError(w http.ResponseWriter, log logger.Logger, err error, message string, fields ...interface{}) {
+	var loading *models.LoadingError
+	if errors.As(err, &loading) {
+		log.Debug("Request arrived while data is loading", "retry_after", loading.RetryAfter)
+		utils.WriteLoadingResponse(w, loading.RetryAfter)
+		return
+	}
+
+	status, text := logServiceError(log, err, message, fields...)
+	utils.WriteErrorResponseWithCode(w, status, ErrorCode(err), text)
+}
+
+// logServiceError logs a failed service call at the level its status
+// deserves and returns the status with the text safe to show the caller
+func logServiceError(log logger.Logger, err error, message string, fields ...interface{}) (int, string) {
+	status, kind := ErrorStatus(err)
+	fields = append([]interface{}{"error", err, "status", status}, fields...)
+
+	switch {
+	case status < http.StatusInternalServerError:
+		log.Debug(message, fields...)
+		return status, err.Error()
+	case kind != nil:
+		log.Warn(message, fields...)
+		return status, message + ": " + kind.Error()
+	default:
+		log.Error(message, fields...)
+		return status, message
+	}
+}
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/internal/utils"
+	"analytics-dashboard-api/pkg/logger"
+
+	"github.com/gorilla/mux"
+)
+
+// ExportService runs background exports of the loaded transactions
+type ExportService interface {
+	Start(models.ExportRequest) (*models.ExportJob, error)
+	Get(string) (*models.ExportJob, error)
+	List() []models.ExportJob
+	Cancel(string) (*models.ExportJob, error)
+	Delete(string) error
+	File(string) (string, *models.ExportJob, error)
+	VerifyLink(string, string, string) error
+}
+
+// ExportHandler serves the export job API: start an export, poll its
+// progress, cancel it and download the file once it has succeeded
+type ExportHandler struct {
+	exports ExportService
+	logger  logger.Logger
+}
+
+func NewExportHandler(exports ExportService, logger logger.Logger) *ExportHandler {
+	return &ExportHandler{
+		exports: exports,
+		logger:  logger,
+	}
+}
+
+// CreateExport queues an export described by the JSON body
+// {"format": "csv"|"parquet", "country": "...", "segment": "..."}
+func (h *ExportHandler) CreateExport(w http.ResponseWriter, r *http.Request) {
+	var request models.ExportRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			utils.WriteErrorResponseWithCode(w, http.StatusBadRequest, utils.ErrCodeInvalidBody, "Invalid request body")
+			return
+		}
+	}
+
+	job, err := h.exports.Start(request)
+	if err != nil {
+		writeServiceError(w, h.logger, err, "Failed to start export")
+		return
+	}
+
+	w.Header().Set("Location", "/api/v1/exports/"+job.ID)
+	utils.WriteJSONResponse(w, http.StatusAccepted, job)
+}
+
+// ListExports returns every export job, newest first
+func (h *ExportHandler) ListExports(w http.ResponseWriter, r *http.Request) {
+	data := h.exports.List()
+	utils.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"data":  data,
+		"count": len(data),
+	})
+}
+
+// GetExport returns an export job with its progress
+func (h *ExportHandler) GetExport(w http.ResponseWriter, r *http.Request) {
+	job, err := h.exports.Get(mux.Vars(r)["id"])
+	if err != nil {
+		writeServiceError(w, h.logger, err, "Failed to get export")
+		return
+	}
+
+	utils.WriteJSONResponse(w, http.StatusOK, job)
+}
+
+// CancelExport stops a queued or running export
+func (h *ExportHandler) CancelExport(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	job, err := h.exports.Cancel(id)
+	if err != nil {
+		writeServiceError(w, h.logger, err, "Failed to cancel export", "export", id)
+		return
+	}
+
+	utils.WriteJSONResponse(w, http.StatusOK, job)
+}
+
+// DeleteExport discards an export and its file, cancelling it if needed
+func (h *ExportHandler) DeleteExport(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	if err := h.exports.Delete(id); err != nil {
+		writeServiceError(w, h.logger, err, "Failed to delete export", "export", id)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DownloadExport sends the file of a succeeded export. Links sent by email
+// carry ?expires= and ?signature=, which must be valid when present.
+func (h *ExportHandler) DownloadExport(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if q := r.URL.Query(); q.Has("signature") {
+		if err := h.exports.VerifyLink(id, q.Get("expires"), q.Get("signature")); err != nil {
+			utils.WriteErrorResponse(w, http.StatusForbidden, err.Error())
+			return
+		}
+	}
+
+	path, job, err := h.exports.File(id)
+	if err != nil {
+		writeServiceError(w, h.logger, err, "Failed to download export")
+		return
+	}
+
+	contentType := "text/csv"
+	if job.Format == models.ExportFormatParquet {
+		contentType = "application/vnd.apache.parquet"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", job.Filename()))
+	http.ServeFile(w, r, path)
+}
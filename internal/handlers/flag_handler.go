@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"analytics-dashboard-api/internal/middleware"
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/internal/utils"
+	"analytics-dashboard-api/pkg/logger"
+
+	"github.com/gorilla/mux"
+)
+
+// FlagService keeps the transactions excluded from every load
+type FlagService interface {
+	List() []models.TransactionFlag
+	Flag(models.FlagRequest, string) ([]models.TransactionFlag, error)
+	Unflag(string) error
+}
+
+// DataReloader reloads the source files, applying the current flags
+type DataReloader interface {
+	Reload(context.Context, time.Duration) error
+}
+
+// FlagHandler serves the admin endpoints that flag transactions (fraud,
+// test orders) so they drop out of every aggregate
+type FlagHandler struct {
+	flags    FlagService
+	loader   DataReloader
+	coverage CoverageProvider
+	logger   logger.Logger
+}
+
+func NewFlagHandler(flags FlagService, loader DataReloader, coverage CoverageProvider, logger logger.Logger) *FlagHandler {
+	return &FlagHandler{
+		flags:    flags,
+		loader:   loader,
+		coverage: coverage,
+		logger:   logger,
+	}
+}
+
+// ListFlags returns the flagged transactions, most recently flagged first
+func (h *FlagHandler) ListFlags(w http.ResponseWriter, r *http.Request) {
+	flags := h.flags.List()
+	utils.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"data":     flags,
+		"count":    len(flags),
+		"excluded": h.coverage.DataCoverage().Excluded,
+	})
+}
+
+// CreateFlags flags the transactions in the JSON body
+// ({"transaction_ids": [...], "reason": "fraud"}) and reloads the data
+// without them
+func (h *FlagHandler) CreateFlags(w http.ResponseWriter, r *http.Request) {
+	var request models.FlagRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		utils.WriteErrorResponseWithCode(w, http.StatusBadRequest, utils.ErrCodeInvalidBody, "Invalid request body")
+		return
+	}
+	request.Reason = utils.SanitizeString(request.Reason)
+
+	flaggedBy, _ := middleware.IdentityFromContext(r.Context())
+	flagged, err := h.flags.Flag(request, flaggedBy)
+	if err != nil {
+		writeServiceError(w, h.logger, err, "Failed to flag transactions")
+		return
+	}
+	h.logger.Info("Transactions flagged", "count", len(flagged), "reason", request.Reason, "by", flaggedBy)
+
+	if !h.reload(w, r) {
+		return
+	}
+	utils.WriteJSONResponse(w, http.StatusCreated, map[string]interface{}{
+		"data":     flagged,
+		"count":    len(flagged),
+		"excluded": h.coverage.DataCoverage().Excluded,
+	})
+}
+
+// DeleteFlag unflags a transaction and reloads the data with it
+func (h *FlagHandler) DeleteFlag(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if err := h.flags.Unflag(id); err != nil {
+		writeServiceError(w, h.logger, err, "Failed to unflag transaction", "id", id)
+		return
+	}
+	h.logger.Info("Transaction unflagged", "id", id)
+
+	if !h.reload(w, r) {
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// reload applies changed flags by reloading the source files. The flags are
+// already saved, so a failed reload is reported but the next load still
+// applies them. It reports whether the caller should write its response.
+func (h *FlagHandler) reload(w http.ResponseWriter, r *http.Request) bool {
+	start := time.Now()
+	if err := h.loader.Reload(r.Context(), 0); err != nil {
+		if !stillWaiting(r.Context(), w, h.logger, "reload", time.Since(start)) {
+			return false
+		}
+		writeServiceError(w, h.logger, err, "Flags saved, but reloading the data failed")
+		return false
+	}
+	return true
+}
@@ -1,26 +1,64 @@
 package handlers
 
 import (
+	"context"
 	"net/http"
 	"runtime"
 	"time"
 
+	"analytics-dashboard-api/internal/models"
 	"analytics-dashboard-api/internal/utils"
 	"analytics-dashboard-api/pkg/logger"
 )
 
+// LoaderStatsProvider reports the data loader's activity
+type LoaderStatsProvider interface {
+	Stats() models.LoaderStats
+}
+
+// ProbeLoader is the data loader as seen by the probes, which start the
+// initial load rather than wait for the first request to
+type ProbeLoader interface {
+	LoaderStatsProvider
+	StartInitialLoad()
+}
+
+// Pinger checks that the analytics backend answers
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// pingTimeout bounds the backend check of a readiness probe, well under the
+// default Kubernetes probe timeout
+const pingTimeout = 2 * time.Second
+
 type HealthHandler struct {
+	loader    ProbeLoader
+	db        Pinger
 	logger    logger.Logger
 	startTime time.Time
+
+	// fallback names the backend serving in place of the configured one,
+	// and fallbackCause why the configured one could not start
+	fallback      string
+	fallbackCause error
 }
 
-func NewHealthHandler(logger logger.Logger) *HealthHandler {
+func NewHealthHandler(loader ProbeLoader, db Pinger, logger logger.Logger) *HealthHandler {
 	return &HealthHandler{
+		loader:    loader,
+		db:        db,
 		logger:    logger,
 		startTime: time.Now(),
 	}
 }
 
+// ReportFallback records that backend serves because the configured one
+// failed to start with cause. /health then reports status "degraded".
+func (h *HealthHandler) ReportFallback(backend string, cause error) {
+	h.fallback, h.fallbackCause = backend, cause
+}
+
 func (h *HealthHandler) Health(w http.ResponseWriter, r *http.Request) {
 	var memStats runtime.MemStats
 	runtime.ReadMemStats(&memStats)
@@ -37,16 +75,103 @@ func (h *HealthHandler) Health(w http.ResponseWriter, r *http.Request) {
 			"num_gc":         memStats.NumGC,
 		},
 		"goroutines": runtime.NumGoroutine(),
+		"data":       h.loader.Stats(),
+	}
+	if h.fallback != "" {
+		health["status"] = "degraded"
+		health["backend_fallback"] = map[string]string{
+			"backend": h.fallback,
+			"reason":  h.fallbackCause.Error(),
+		}
 	}
 
 	utils.WriteJSONResponse(w, http.StatusOK, health)
 }
 
+// Live is the liveness probe: it answers 200 as long as the process serves
+// requests, whatever the state of the data or the backend, so a slow load or
+// a database outage never gets the pod restarted
+func (h *HealthHandler) Live(w http.ResponseWriter, r *http.Request) {
+	utils.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"status":    "alive",
+		"timestamp": time.Now().UTC(),
+		"uptime":    time.Since(h.startTime).String(),
+	})
+}
+
+// Ready is the readiness probe: 200 once data is loaded and the backend
+// answers, 503 with the failing checks otherwise. It starts the initial load
+// if nothing has started it yet.
 func (h *HealthHandler) Ready(w http.ResponseWriter, r *http.Request) {
-	ready := map[string]interface{}{
-		"status":    "ready",
+	h.loader.StartInitialLoad()
+	stats := h.loader.Stats()
+
+	checks := map[string]string{"data": "ok", "database": "ok"}
+	ready := true
+	if !stats.Loaded {
+		checks["data"] = loadState(stats)
+		ready = false
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), pingTimeout)
+	defer cancel()
+	if err := h.db.Ping(ctx); err != nil {
+		h.logger.Warn("Readiness check failed", "error", err)
+		checks["database"] = err.Error()
+		ready = false
+	}
+
+	status, code := "ready", http.StatusOK
+	if !ready {
+		status, code = "not_ready", http.StatusServiceUnavailable
+	}
+	utils.WriteJSONResponse(w, code, map[string]interface{}{
+		"status":    status,
+		"timestamp": time.Now().UTC(),
+		"checks":    checks,
+	})
+}
+
+// Startup is the startup probe: 503 while the initial load runs or after it
+// failed, 200 once data has been loaded. The body reports the load's
+// progress so a slow rollout can be told from a stuck one. Like Ready, it
+// starts the initial load.
+func (h *HealthHandler) Startup(w http.ResponseWriter, r *http.Request) {
+	h.loader.StartInitialLoad()
+	stats := h.loader.Stats()
+
+	body := map[string]interface{}{
+		"status":    loadState(stats),
 		"timestamp": time.Now().UTC(),
+		"uptime":    time.Since(h.startTime).String(),
+	}
+	if stats.LoadStartedAt != nil {
+		body["load_started_at"] = stats.LoadStartedAt
+		body["elapsed_ms"] = time.Since(*stats.LoadStartedAt).Milliseconds()
+		body["estimated_remaining_ms"] = stats.EstimatedRemainingMs
+	}
+	if stats.LastError != "" && !stats.Loaded {
+		body["error"] = stats.LastError
 	}
 
-	utils.WriteJSONResponse(w, http.StatusOK, ready)
+	code := http.StatusOK
+	if !stats.Loaded {
+		code = http.StatusServiceUnavailable
+	}
+	utils.WriteJSONResponse(w, code, body)
+}
+
+// loadState names where the initial load stands: loaded, loading, failed or
+// pending
+func loadState(stats models.LoaderStats) string {
+	switch {
+	case stats.Loaded:
+		return "loaded"
+	case stats.Loading:
+		return "loading"
+	case stats.LastError != "":
+		return "failed"
+	default:
+		return "pending"
+	}
 }
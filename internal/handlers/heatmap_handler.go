@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/internal/utils"
+	"analytics-dashboard-api/pkg/logger"
+)
+
+// HeatmapService aggregates revenue into country and region cells
+type HeatmapService interface {
+	Heatmap(context.Context, models.QueryOptions, models.HeatmapGrid) (*models.Heatmap, error)
+}
+
+// HeatmapHandler serves the regional heatmap of the map widget
+type HeatmapHandler struct {
+	heatmap     HeatmapService
+	initializer Initializer
+	coverage    CoverageProvider
+	logger      logger.Logger
+}
+
+func NewHeatmapHandler(heatmap HeatmapService, initializer Initializer, coverage CoverageProvider, logger logger.Logger) *HeatmapHandler {
+	return &HeatmapHandler{
+		heatmap:     heatmap,
+		initializer: initializer,
+		coverage:    coverage,
+		logger:      logger,
+	}
+}
+
+// GetHeatmap returns revenue per country and region with an intensity
+// between 0 and 1. ?countries= and ?regions= (per country) size the grid,
+// ?other=false drops what falls outside it instead of rolling it into
+// "Other" cells, and ?from=&to= (YYYY-MM-DD, inclusive) and the analytics
+// filters select the transactions.
+func (h *HeatmapHandler) GetHeatmap(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	formatter, err := getFormatter(r)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	opts := getQueryOptions(r)
+	if err := getDateRange(r, &opts); err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	grid := models.HeatmapGrid{Other: true}
+	for _, param := range []struct {
+		name string
+		dest *int
+	}{{"countries", &grid.Countries}, {"regions", &grid.Regions}} {
+		value := query.Get(param.name)
+		if value == "" {
+			continue
+		}
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid "+param.name+" parameter")
+			return
+		}
+		*param.dest = parsed
+	}
+	if value := query.Get("other"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid other parameter")
+			return
+		}
+		grid.Other = parsed
+	}
+
+	if err := h.initializer.EnsureInitialized(r.Context()); err != nil {
+		writeServiceError(w, h.logger, err, "Failed to initialize database")
+		return
+	}
+
+	heatmap, err := h.heatmap.Heatmap(r.Context(), opts, grid)
+	if err != nil {
+		writeServiceError(w, h.logger, err, "Failed to build heatmap")
+		return
+	}
+	if formatter != nil {
+		for i := range heatmap.Cells {
+			heatmap.Cells[i].ApplyDisplay(formatter)
+		}
+	}
+
+	response := map[string]interface{}{
+		"grid":          heatmap.Grid,
+		"total_revenue": heatmap.TotalRevenue,
+		"max_revenue":   heatmap.MaxRevenue,
+		"countries":     heatmap.Countries,
+		"data":          heatmap.Cells,
+		"count":         len(heatmap.Cells),
+	}
+	addSampleInfo(response, opts)
+	addFormatInfo(response, formatter)
+	addCoverage(response, h.coverage)
+
+	utils.WriteJSONResponse(w, http.StatusOK, response)
+}
@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"cmp"
+	"context"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"analytics-dashboard-api/internal/httpquery"
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/internal/utils"
+	"analytics-dashboard-api/pkg/logger"
+)
+
+// InventoryService computes inventory metrics from sales and stock
+type InventoryService interface {
+	SellThrough(context.Context, models.QueryOptions, string, int, time.Time) (*models.SellThroughReport, error)
+}
+
+// sellThroughSorts are the ?sort= fields of the sell-through endpoint
+var sellThroughSorts = []string{"sell_through", "units_sold", "stock", "group"}
+
+// InventoryHandler serves the inventory endpoints
+type InventoryHandler struct {
+	inventory   InventoryService
+	initializer Initializer
+	coverage    CoverageProvider
+	logger      logger.Logger
+}
+
+func NewInventoryHandler(inventory InventoryService, initializer Initializer, coverage CoverageProvider, logger logger.Logger) *InventoryHandler {
+	return &InventoryHandler{
+		inventory:   inventory,
+		initializer: initializer,
+		coverage:    coverage,
+		logger:      logger,
+	}
+}
+
+// GetSellThrough returns a page of sell-through rates per product or
+// category (?by=product|category) over the ?window_days= ending on ?to=
+// (YYYY-MM-DD, the latest transaction by default). Rows are ordered by
+// ?sort=, slowest sellers first by default.
+func (h *InventoryHandler) GetSellThrough(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	opts := getQueryOptions(r)
+
+	var end time.Time
+	if value := query.Get("to"); value != "" {
+		parsed, err := time.Parse(httpquery.DateLayout, value)
+		if err != nil {
+			utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid to parameter: must be a date in YYYY-MM-DD format")
+			return
+		}
+		end = parsed
+	}
+	var windowDays int
+	if value := query.Get("window_days"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid window_days parameter")
+			return
+		}
+		windowDays = parsed
+	}
+
+	order, err := httpquery.ParseSort(query, sellThroughSorts, httpquery.Sort{Field: "sell_through"})
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	page, err := httpquery.ParsePage(query, httpquery.DefaultPageOptions)
+	if err != nil {
+		h.logger.Debug("Ignoring invalid pagination parameter", "error", err)
+	}
+
+	if err := h.initializer.EnsureInitialized(r.Context()); err != nil {
+		writeServiceError(w, h.logger, err, "Failed to initialize database")
+		return
+	}
+
+	report, err := h.inventory.SellThrough(r.Context(), opts, utils.SanitizeString(query.Get("by")), windowDays, end)
+	if err != nil {
+		writeServiceError(w, h.logger, err, "Failed to compute sell-through")
+		return
+	}
+
+	rows := report.Rows
+	sortSellThrough(rows, order)
+	total := len(rows)
+	start := min(page.Offset, total)
+	data := rows[start:min(start+page.Limit, total)]
+
+	response := map[string]interface{}{
+		"by":       report.By,
+		"window":   report.Window,
+		"sort":     order.String(),
+		"data":     data,
+		"count":    len(data),
+		"total":    total,
+		"limit":    page.Limit,
+		"offset":   page.Offset,
+		"has_more": page.HasMore(total),
+		"links":    pageLinks(w, r, page, total),
+	}
+	addSampleInfo(response, opts)
+	addCoverage(response, h.coverage)
+
+	utils.WriteJSONResponse(w, http.StatusOK, response)
+}
+
+// sortSellThrough orders rows by the sort field, then by group. Rows
+// without a rate (nothing sold or in stock) go last either way.
+func sortSellThrough(rows []models.SellThrough, order httpquery.Sort) {
+	slices.SortStableFunc(rows, func(a, b models.SellThrough) int {
+		var c int
+		switch order.Field {
+		case "sell_through":
+			if (a.SellThroughPct == nil) != (b.SellThroughPct == nil) {
+				if a.SellThroughPct == nil {
+					return 1
+				}
+				return -1
+			}
+			if a.SellThroughPct != nil {
+				c = cmp.Compare(*a.SellThroughPct, *b.SellThroughPct)
+			}
+		case "units_sold":
+			c = cmp.Compare(a.UnitsSold, b.UnitsSold)
+		case "stock":
+			c = cmp.Compare(a.Stock, b.Stock)
+		}
+		if order.Desc {
+			c = -c
+		}
+		if c == 0 {
+			c = cmp.Compare(a.Group, b.Group)
+			if order.Field == "group" && order.Desc {
+				c = -c
+			}
+		}
+		return c
+	})
+}
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/internal/utils"
+	"analytics-dashboard-api/pkg/logger"
+
+	"github.com/gorilla/mux"
+)
+
+// JobLookup finds queued, running and recently finished data jobs
+type JobLookup interface {
+	Job(uint64) (models.JobInfo, error)
+}
+
+// JobHandler lets callers follow data jobs they started without waiting,
+// such as a refresh
+type JobHandler struct {
+	jobs   JobLookup
+	logger logger.Logger
+}
+
+func NewJobHandler(jobs JobLookup, logger logger.Logger) *JobHandler {
+	return &JobHandler{
+		jobs:   jobs,
+		logger: logger,
+	}
+}
+
+// GetJob returns a data job with its status, and its error once it failed
+func (h *JobHandler) GetJob(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusNotFound, models.ErrJobNotFound.Error())
+		return
+	}
+
+	job, err := h.jobs.Job(id)
+	if err != nil {
+		writeServiceError(w, h.logger, err, "Failed to get job")
+		return
+	}
+
+	utils.WriteJSONResponse(w, http.StatusOK, job)
+}
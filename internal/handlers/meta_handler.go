@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"analytics-dashboard-api/internal/httpquery"
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/internal/utils"
+	"analytics-dashboard-api/pkg/logger"
+)
+
+type MetaService interface {
+	ListDimensionValues(context.Context, string, string, int, int) ([]models.DimensionValue, error)
+	CountDimensionValues(context.Context, string, string) (int, error)
+	GetDataProfile(context.Context, int) (*models.DataProfile, error)
+	ListOutliers(context.Context, int, int) ([]models.Outlier, error)
+	CoverageProvider
+}
+
+// VersionedData loads the data on first use and reports which version is
+// loaded, so results computed from it can be cached per version
+type VersionedData interface {
+	Initializer
+	Version() uint64
+}
+
+const (
+	defaultProfileTop = 5
+	maxProfileTop     = 50
+)
+
+type MetaHandler struct {
+	metaService MetaService
+	data        VersionedData
+	logger      logger.Logger
+
+	// profileMu serializes profiling so each data version is summarized once
+	profileMu  sync.Mutex
+	profile    *models.DataProfile
+	profileTop int
+}
+
+func NewMetaHandler(
+	metaService MetaService,
+	data VersionedData,
+	logger logger.Logger,
+) *MetaHandler {
+	return &MetaHandler{
+		metaService: metaService,
+		data:        data,
+		logger:      logger,
+	}
+}
+
+// GetDimensionValues returns distinct values of a dimension for filter
+// dropdowns (?dimension=country|category|region&search=&limit=100&offset=0)
+func (h *MetaHandler) GetDimensionValues(w http.ResponseWriter, r *http.Request) {
+	dimension := utils.SanitizeString(r.URL.Query().Get("dimension"))
+	search := utils.SanitizeString(r.URL.Query().Get("search"))
+	page, err := httpquery.ParsePage(r.URL.Query(), httpquery.DefaultPageOptions)
+	if err != nil {
+		h.logger.Debug("Ignoring invalid pagination parameter", "error", err)
+	}
+
+	if err := utils.ValidateStringNotEmpty(dimension, "dimension"); err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.data.EnsureInitialized(r.Context()); err != nil {
+		writeServiceError(w, h.logger, err, "Failed to initialize database")
+		return
+	}
+
+	data, err := h.metaService.ListDimensionValues(r.Context(), dimension, search, page.Limit, page.Offset)
+	if err != nil {
+		writeServiceError(w, h.logger, err, "Failed to get dimension values", "dimension", dimension)
+		return
+	}
+
+	total, err := h.metaService.CountDimensionValues(r.Context(), dimension, search)
+	if err != nil {
+		writeServiceError(w, h.logger, err, "Failed to get total count", "dimension", dimension)
+		return
+	}
+
+	utils.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"dimension": dimension,
+		"data":      data,
+		"count":     len(data),
+		"total":     total,
+		"limit":     page.Limit,
+		"offset":    page.Offset,
+		"has_more":  page.HasMore(total),
+		"links":     pageLinks(w, r, page, total),
+	})
+}
+
+// GetDataProfile returns per-column statistics of the loaded transactions:
+// null rate, approximate distinct count, min, max and the ?top= most frequent
+// values (default 5, 0 to skip them)
+func (h *MetaHandler) GetDataProfile(w http.ResponseWriter, r *http.Request) {
+	top := defaultProfileTop
+	if value := r.URL.Query().Get("top"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 || parsed > maxProfileTop {
+			utils.WriteErrorResponse(w, http.StatusBadRequest, "top must be an integer between 0 and "+strconv.Itoa(maxProfileTop))
+			return
+		}
+		top = parsed
+	}
+
+	if err := h.data.EnsureInitialized(r.Context()); err != nil {
+		writeServiceError(w, h.logger, err, "Failed to initialize database")
+		return
+	}
+
+	profile, cacheHit, err := h.dataProfile(r.Context(), top)
+	if err != nil {
+		writeServiceError(w, h.logger, err, "Failed to profile data")
+		return
+	}
+
+	utils.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"data_version": profile.DataVersion,
+		"computed_at":  profile.ComputedAt,
+		"cache_hit":    cacheHit,
+		"table":        profile.Table,
+		"rows":         profile.Rows,
+		"columns":      profile.Columns,
+	})
+}
+
+// ListOutliers returns the transactions the latest load flagged as outliers,
+// largest total first (?limit=100&offset=0), with the load's outlier report.
+// In exclude mode these rows are not in any aggregate.
+func (h *MetaHandler) ListOutliers(w http.ResponseWriter, r *http.Request) {
+	page, err := httpquery.ParsePage(r.URL.Query(), httpquery.DefaultPageOptions)
+	if err != nil {
+		h.logger.Debug("Ignoring invalid pagination parameter", "error", err)
+	}
+
+	if err := h.data.EnsureInitialized(r.Context()); err != nil {
+		writeServiceError(w, h.logger, err, "Failed to initialize database")
+		return
+	}
+
+	// Read the report first: a reload between the two calls leaves the page
+	// newer than the total, which only shortens has_more
+	report := h.metaService.DataCoverage().Outliers
+	data, err := h.metaService.ListOutliers(r.Context(), page.Limit, page.Offset)
+	if err != nil {
+		writeServiceError(w, h.logger, err, "Failed to list outliers")
+		return
+	}
+
+	total := 0
+	if report != nil {
+		total = report.Rows
+	}
+	utils.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"report":   report,
+		"data":     data,
+		"count":    len(data),
+		"total":    total,
+		"limit":    page.Limit,
+		"offset":   page.Offset,
+		"has_more": page.HasMore(total),
+		"links":    pageLinks(w, r, page, total),
+	})
+}
+
+// dataProfile returns the profile of the current data version, computing it
+// if the data or top changed since it was cached. The bool reports a cache hit.
+func (h *MetaHandler) dataProfile(ctx context.Context, top int) (*models.DataProfile, bool, error) {
+	h.profileMu.Lock()
+	defer h.profileMu.Unlock()
+
+	// Read the version before querying, as analyticsStats does
+	version := h.data.Version()
+	if h.profile != nil && h.profile.DataVersion == version && h.profileTop == top {
+		return h.profile, true, nil
+	}
+
+	start := time.Now()
+	profile, err := h.metaService.GetDataProfile(ctx, top)
+	if err != nil {
+		return nil, false, err
+	}
+	profile.DataVersion = version
+	profile.ComputedAt = time.Now().UTC()
+
+	h.profile, h.profileTop = profile, top
+	h.logger.Debug("Data profile computed", "data_version", version, "columns", len(profile.Columns), "duration", time.Since(start))
+	return profile, false, nil
+}
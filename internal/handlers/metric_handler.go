@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/internal/utils"
+	"analytics-dashboard-api/pkg/logger"
+
+	"github.com/gorilla/mux"
+)
+
+type MetricService interface {
+	List() []models.DerivedMetric
+	Define(models.DerivedMetric) (*models.DerivedMetric, error)
+	Delete(string) error
+	Names() []string
+	Validate([]string) error
+	Evaluate([]string, map[string]float64) map[string]*float64
+}
+
+type AggregateService interface {
+	GetBaseMetrics(context.Context, models.QueryOptions, string) ([]models.MetricRow, error)
+	CoverageProvider
+}
+
+type MetricHandler struct {
+	metricService    MetricService
+	aggregateService AggregateService
+	initializer      Initializer
+	groupings        map[string]string
+	logger           logger.Logger
+}
+
+func NewMetricHandler(
+	metricService MetricService,
+	aggregateService AggregateService,
+	initializer Initializer,
+	groupings map[string]string,
+	logger logger.Logger,
+) *MetricHandler {
+	return &MetricHandler{
+		metricService:    metricService,
+		aggregateService: aggregateService,
+		initializer:      initializer,
+		groupings:        groupings,
+		logger:           logger,
+	}
+}
+
+// ListMetrics returns the derived metric definitions and every usable metric name
+func (h *MetricHandler) ListMetrics(w http.ResponseWriter, r *http.Request) {
+	data := h.metricService.List()
+
+	utils.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"data":      data,
+		"count":     len(data),
+		"available": h.metricService.Names(),
+	})
+}
+
+// CreateMetric registers a derived metric from the JSON body
+// ({"name": "aov", "expression": "revenue / transactions"})
+func (h *MetricHandler) CreateMetric(w http.ResponseWriter, r *http.Request) {
+	var metric models.DerivedMetric
+	if err := json.NewDecoder(r.Body).Decode(&metric); err != nil {
+		utils.WriteErrorResponseWithCode(w, http.StatusBadRequest, utils.ErrCodeInvalidBody, "Invalid request body")
+		return
+	}
+
+	if err := utils.ValidateStringNotEmpty(metric.Expression, "expression"); err != nil {
+		utils.WriteErrorResponseWithCode(w, http.StatusBadRequest, utils.ErrCodeValidation, err.Error())
+		return
+	}
+
+	created, err := h.metricService.Define(metric)
+	if err != nil {
+		writeServiceError(w, h.logger, err, "Failed to define metric")
+		return
+	}
+
+	utils.WriteJSONResponse(w, http.StatusCreated, created)
+}
+
+// DeleteMetric removes an API-defined metric
+func (h *MetricHandler) DeleteMetric(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+
+	if err := h.metricService.Delete(name); err != nil {
+		writeServiceError(w, h.logger, err, "Failed to delete metric", "name", name)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetAggregate returns base and derived metrics, optionally grouped
+// (?group_by=month|country|region|category|product&metrics=revenue,aov)
+func (h *MetricHandler) GetAggregate(w http.ResponseWriter, r *http.Request) {
+	groupBy := utils.SanitizeString(r.URL.Query().Get("group_by"))
+	if _, ok := h.groupings[groupBy]; groupBy != "" && !ok {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid group_by parameter")
+		return
+	}
+
+	names := h.metricService.Names()
+	if param := r.URL.Query().Get("metrics"); param != "" {
+		names = nil
+		for _, name := range strings.Split(param, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, name)
+			}
+		}
+		if err := h.metricService.Validate(names); err != nil {
+			utils.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	if err := h.initializer.EnsureInitialized(r.Context()); err != nil {
+		writeServiceError(w, h.logger, err, "Failed to initialize database")
+		return
+	}
+
+	opts := getQueryOptions(r)
+	rows, err := h.aggregateService.GetBaseMetrics(r.Context(), opts, groupBy)
+	if err != nil {
+		writeServiceError(w, h.logger, err, "Failed to get aggregate metrics")
+		return
+	}
+
+	data := make([]models.MetricResult, len(rows))
+	for i, row := range rows {
+		data[i] = models.MetricResult{
+			Group:   row.Group,
+			Metrics: h.metricService.Evaluate(names, row.Values),
+		}
+	}
+
+	response := map[string]interface{}{
+		"data":     data,
+		"count":    len(data),
+		"group_by": groupBy,
+		"metrics":  names,
+	}
+	addSampleInfo(response, opts)
+	addCoverage(response, h.aggregateService)
+
+	utils.WriteJSONResponse(w, http.StatusOK, response)
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/internal/utils"
+	"analytics-dashboard-api/pkg/logger"
+)
+
+// NLQueryService answers natural-language questions about the loaded data
+type NLQueryService interface {
+	Answer(context.Context, string) (*models.NLAnswer, error)
+}
+
+// NLQueryHandler serves the experimental natural-language query endpoint
+type NLQueryHandler struct {
+	queries     NLQueryService
+	initializer Initializer
+	logger      logger.Logger
+}
+
+func NewNLQueryHandler(queries NLQueryService, initializer Initializer, logger logger.Logger) *NLQueryHandler {
+	return &NLQueryHandler{
+		queries:     queries,
+		initializer: initializer,
+		logger:      logger,
+	}
+}
+
+// Query answers the question in the JSON body ({"question": "revenue by
+// country last quarter"}) together with how it was interpreted
+func (h *NLQueryHandler) Query(w http.ResponseWriter, r *http.Request) {
+	var request models.NLQueryRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		utils.WriteErrorResponseWithCode(w, http.StatusBadRequest, utils.ErrCodeInvalidBody, "Invalid request body")
+		return
+	}
+
+	if err := h.initializer.EnsureInitialized(r.Context()); err != nil {
+		writeServiceError(w, h.logger, err, "Failed to initialize database")
+		return
+	}
+
+	answer, err := h.queries.Answer(r.Context(), request.Question)
+	if err != nil {
+		writeServiceError(w, h.logger, err, "Failed to answer question")
+		return
+	}
+
+	utils.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"question":       answer.Question,
+		"interpretation": answer.Interpretation,
+		"data":           answer.Data,
+		"count":          len(answer.Data),
+		"experimental":   true,
+	})
+}
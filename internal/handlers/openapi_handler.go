@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"analytics-dashboard-api/internal/middleware"
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/internal/openapi"
+	"analytics-dashboard-api/internal/utils"
+)
+
+// APIVersion is the version of the API the spec describes
+const APIVersion = "1.0.0"
+
+// pathParam matches the {name} variables of a route template
+var pathParam = regexp.MustCompile(`\{(\w+)\}`)
+
+// OpenAPISpec describes the routes in RouteDocs, with the query parameters
+// listed in QueryParamSpecs
+func OpenAPISpec() openapi.Document {
+	b := openapi.NewBuilder(openapi.Info{
+		Title:       "Analytics Dashboard API",
+		Version:     APIVersion,
+		Description: "Sales analytics over the loaded transactions. Routes need an X-API-Key when the server has API keys configured.",
+	})
+	b.Override(models.Money(0), openapi.Schema{
+		Type:        "number",
+		Description: "An amount in currency units; a string, or whole cents, when the server sets MONEY_FORMAT",
+	})
+	b.APIKey(middleware.APIKeyHeader)
+	errorResponse := openapi.Response{
+		Description: "Error",
+		Content:     jsonContent(b.Schema(utils.ErrorResponse{})),
+	}
+
+	routes := make([]string, 0, len(RouteDocs))
+	for route := range RouteDocs {
+		routes = append(routes, route)
+	}
+	sort.Strings(routes)
+	for _, route := range routes {
+		doc := RouteDocs[route]
+		method, path, _ := strings.Cut(route, " ")
+
+		op := &openapi.Operation{
+			OperationID: doc.ID,
+			Summary:     doc.Summary,
+			Tags:        []string{routeTag(path)},
+			Parameters:  routeParameters(path, QueryParamSpecs[route]),
+			Responses:   map[string]openapi.Response{"default": errorResponse},
+		}
+		switch {
+		case doc.Request != nil:
+			op.RequestBody = &openapi.RequestBody{Required: true, Content: jsonContent(b.Schema(doc.Request))}
+		case len(doc.Upload) > 0:
+			op.RequestBody = &openapi.RequestBody{Required: true, Content: fileContent(doc.Upload)}
+		}
+
+		success := openapi.Response{Description: http.StatusText(max(doc.Status, http.StatusOK))}
+		if doc.Response != nil {
+			success.Content = jsonContent(b.Schema(doc.Response))
+		}
+		if len(doc.Files) > 0 {
+			if success.Content == nil {
+				success.Content = map[string]openapi.MediaType{}
+			}
+			for contentType, media := range fileContent(doc.Files) {
+				success.Content[contentType] = media
+			}
+		}
+		op.Responses[strconv.Itoa(max(doc.Status, http.StatusOK))] = success
+		if doc.Accepted != nil {
+			op.Responses[strconv.Itoa(http.StatusAccepted)] = openapi.Response{
+				Description: http.StatusText(http.StatusAccepted),
+				Content:     jsonContent(b.Schema(doc.Accepted)),
+			}
+		}
+		b.Add(method, path, op)
+	}
+	return b.Document()
+}
+
+func jsonContent(schema *openapi.Schema) map[string]openapi.MediaType {
+	return map[string]openapi.MediaType{"application/json": {Schema: schema}}
+}
+
+// fileContent describes files of the content types; a form carries the
+// file in its "file" field
+func fileContent(contentTypes []string) map[string]openapi.MediaType {
+	content := make(map[string]openapi.MediaType, len(contentTypes))
+	for _, contentType := range contentTypes {
+		file := &openapi.Schema{Type: "string", Format: "binary"}
+		if contentType == "multipart/form-data" {
+			file = &openapi.Schema{Type: "object", Properties: map[string]*openapi.Schema{"file": file}, Required: []string{"file"}}
+		}
+		content[contentType] = openapi.MediaType{Schema: file}
+	}
+	return content
+}
+
+// routeTag groups a route by the first segment of its path under /api/v1
+func routeTag(path string) string {
+	tag, _, _ := strings.Cut(strings.TrimPrefix(path, "/api/v1/"), "/")
+	return strings.TrimSuffix(tag, ".json")
+}
+
+func routeParameters(path string, specs []middleware.ParamSpec) []openapi.Parameter {
+	var params []openapi.Parameter
+	for _, match := range pathParam.FindAllStringSubmatch(path, -1) {
+		params = append(params, openapi.Parameter{Name: match[1], In: "path", Required: true, Schema: &openapi.Schema{Type: "string"}})
+	}
+	for _, spec := range specs {
+		params = append(params, openapi.Parameter{Name: spec.Name, In: "query", Schema: paramSchema(spec)})
+	}
+	return params
+}
+
+func paramSchema(spec middleware.ParamSpec) *openapi.Schema {
+	var schema openapi.Schema
+	switch spec.Type {
+	case middleware.ParamInt:
+		schema.Type = "integer"
+	case middleware.ParamFloat:
+		schema.Type = "number"
+	case middleware.ParamDate:
+		schema = openapi.Schema{Type: "string", Format: "date"}
+	case middleware.ParamEnum:
+		schema = openapi.Schema{Type: "string", Enum: spec.Values}
+	default:
+		schema.Type = "string"
+	}
+	if (spec.Type == middleware.ParamInt || spec.Type == middleware.ParamFloat) && spec.Max > spec.Min {
+		schema.Minimum, schema.Maximum = &spec.Min, &spec.Max
+	}
+	return &schema
+}
+
+// OpenAPIHandler serves the OpenAPI description of the API, for client
+// generators and API explorers
+type OpenAPIHandler struct {
+	spec openapi.Document
+}
+
+func NewOpenAPIHandler() *OpenAPIHandler {
+	return &OpenAPIHandler{spec: OpenAPISpec()}
+}
+
+// GetSpec returns the OpenAPI document
+func (h *OpenAPIHandler) GetSpec(w http.ResponseWriter, r *http.Request) {
+	utils.WriteJSONResponse(w, http.StatusOK, h.spec)
+}
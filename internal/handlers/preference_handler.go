@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"analytics-dashboard-api/internal/middleware"
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/internal/utils"
+	"analytics-dashboard-api/pkg/logger"
+)
+
+type PreferenceService interface {
+	Get(string) (*models.Preferences, bool)
+	Save(string, models.Preferences) (*models.Preferences, error)
+	Delete(string) error
+}
+
+type PreferenceHandler struct {
+	preferenceService PreferenceService
+	logger            logger.Logger
+}
+
+func NewPreferenceHandler(preferenceService PreferenceService, logger logger.Logger) *PreferenceHandler {
+	return &PreferenceHandler{
+		preferenceService: preferenceService,
+		logger:            logger,
+	}
+}
+
+// GetPreferences returns the caller's stored preferences
+func (h *PreferenceHandler) GetPreferences(w http.ResponseWriter, r *http.Request) {
+	identity, ok := middleware.IdentityFromContext(r.Context())
+	if !ok {
+		utils.WriteErrorResponse(w, http.StatusUnauthorized, "Caller identity is required")
+		return
+	}
+
+	prefs, ok := h.preferenceService.Get(identity)
+	if !ok {
+		prefs = &models.Preferences{Identity: identity}
+	}
+
+	utils.WriteJSONResponse(w, http.StatusOK, prefs)
+}
+
+// UpdatePreferences replaces the caller's preferences with the JSON body
+func (h *PreferenceHandler) UpdatePreferences(w http.ResponseWriter, r *http.Request) {
+	identity, ok := middleware.IdentityFromContext(r.Context())
+	if !ok {
+		utils.WriteErrorResponse(w, http.StatusUnauthorized, "Caller identity is required")
+		return
+	}
+
+	var prefs models.Preferences
+	if err := json.NewDecoder(r.Body).Decode(&prefs); err != nil {
+		utils.WriteErrorResponseWithCode(w, http.StatusBadRequest, utils.ErrCodeInvalidBody, "Invalid request body")
+		return
+	}
+
+	saved, err := h.preferenceService.Save(identity, prefs)
+	if err != nil {
+		writeServiceError(w, h.logger, err, "Failed to save preferences", "identity", identity)
+		return
+	}
+
+	utils.WriteJSONResponse(w, http.StatusOK, saved)
+}
+
+// DeletePreferences clears the caller's preferences
+func (h *PreferenceHandler) DeletePreferences(w http.ResponseWriter, r *http.Request) {
+	identity, ok := middleware.IdentityFromContext(r.Context())
+	if !ok {
+		utils.WriteErrorResponse(w, http.StatusUnauthorized, "Caller identity is required")
+		return
+	}
+
+	if err := h.preferenceService.Delete(identity); err != nil {
+		writeServiceError(w, h.logger, err, "Failed to delete preferences", "identity", identity)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
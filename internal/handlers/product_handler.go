@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"analytics-dashboard-api/internal/httpquery"
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/internal/utils"
+	"analytics-dashboard-api/pkg/logger"
+
+	"github.com/gorilla/mux"
+)
+
+type ProductService interface {
+	ListProducts(context.Context, string, int, int) ([]models.Product, error)
+	CountProducts(context.Context, string) (int, error)
+	GetProduct(context.Context, string) (*models.Product, *models.ProductSales, error)
+	GetPriceHistory(context.Context, string, models.QueryOptions) ([]models.PricePoint, error)
+}
+
+// Initializer loads data on first use so handlers serving dimension tables
+// see the same data as the analytics endpoints
+type Initializer interface {
+	EnsureInitialized(context.Context) error
+}
+
+type ProductHandler struct {
+	productService ProductService
+	initializer    Initializer
+	logger         logger.Logger
+}
+
+func NewProductHandler(
+	productService ProductService,
+	initializer Initializer,
+	logger logger.Logger,
+) *ProductHandler {
+	return &ProductHandler{
+		productService: productService,
+		initializer:    initializer,
+		logger:         logger,
+	}
+}
+
+// ListProducts returns the product catalog with pagination and optional search
+func (h *ProductHandler) ListProducts(w http.ResponseWriter, r *http.Request) {
+	page, err := httpquery.ParsePage(r.URL.Query(), httpquery.DefaultPageOptions)
+	if err != nil {
+		h.logger.Debug("Ignoring invalid pagination parameter", "error", err)
+	}
+	search := utils.SanitizeString(r.URL.Query().Get("search"))
+
+	if err := h.initializer.EnsureInitialized(r.Context()); err != nil {
+		writeServiceError(w, h.logger, err, "Failed to initialize database")
+		return
+	}
+
+	data, err := h.productService.ListProducts(r.Context(), search, page.Limit, page.Offset)
+	if err != nil {
+		writeServiceError(w, h.logger, err, "Failed to get products")
+		return
+	}
+
+	total, err := h.productService.CountProducts(r.Context(), search)
+	if err != nil {
+		writeServiceError(w, h.logger, err, "Failed to get total count")
+		return
+	}
+
+	utils.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"data":     data,
+		"count":    len(data),
+		"total":    total,
+		"limit":    page.Limit,
+		"offset":   page.Offset,
+		"has_more": page.HasMore(total),
+		"links":    pageLinks(w, r, page, total),
+	})
+}
+
+// GetProduct returns a single product with its sales summary
+func (h *ProductHandler) GetProduct(w http.ResponseWriter, r *http.Request) {
+	productID := mux.Vars(r)["id"]
+	formatter, err := getFormatter(r)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.initializer.EnsureInitialized(r.Context()); err != nil {
+		writeServiceError(w, h.logger, err, "Failed to initialize database")
+		return
+	}
+
+	product, sales, err := h.productService.GetProduct(r.Context(), productID)
+	if err != nil {
+		writeServiceError(w, h.logger, err, "Failed to get product", "product_id", productID)
+		return
+	}
+
+	response := map[string]interface{}{
+		"product": product,
+		"sales":   sales,
+	}
+	if formatter != nil {
+		sales.ApplyDisplay(formatter)
+		addFormatInfo(response, formatter)
+	}
+
+	utils.WriteJSONResponse(w, http.StatusOK, response)
+}
+
+// GetPriceHistory returns a product's average selling price per month, so
+// price drift shows without exporting transactions. It takes the analytics
+// filters and ?from=&to= (YYYY-MM-DD, inclusive).
+func (h *ProductHandler) GetPriceHistory(w http.ResponseWriter, r *http.Request) {
+	productID := mux.Vars(r)["id"]
+	formatter, err := getFormatter(r)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	opts := getQueryOptions(r)
+	if err := getDateRange(r, &opts); err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.initializer.EnsureInitialized(r.Context()); err != nil {
+		writeServiceError(w, h.logger, err, "Failed to initialize database")
+		return
+	}
+
+	data, err := h.productService.GetPriceHistory(r.Context(), productID, opts)
+	if err != nil {
+		writeServiceError(w, h.logger, err, "Failed to get price history", "product_id", productID)
+		return
+	}
+	if formatter != nil {
+		for i := range data {
+			data[i].ApplyDisplay(formatter)
+		}
+	}
+
+	response := map[string]interface{}{
+		"product_id": productID,
+		"data":       data,
+		"count":      len(data),
+	}
+	addSampleInfo(response, opts)
+	addFormatInfo(response, formatter)
+
+	utils.WriteJSONResponse(w, http.StatusOK, response)
+}
@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"math"
+	"slices"
+
+	"analytics-dashboard-api/internal/httpquery"
+	"analytics-dashboard-api/internal/middleware"
+)
+
+// Query parameters shared by several endpoints
+var (
+	paramSample   = middleware.ParamSpec{Name: "sample", Type: middleware.ParamFloat, Min: 0, Max: 1}
+	paramSegment  = middleware.ParamSpec{Name: "segment", Type: middleware.ParamString}
+	paramCountry  = middleware.ParamSpec{Name: "country", Type: middleware.ParamString}
+	paramRegion   = middleware.ParamSpec{Name: "region", Type: middleware.ParamString}
+	paramCategory = middleware.ParamSpec{Name: "category", Type: middleware.ParamString}
+	paramLocale   = middleware.ParamSpec{Name: "locale", Type: middleware.ParamString}
+	paramCurrency = middleware.ParamSpec{Name: "currency", Type: middleware.ParamString}
+	paramSearch   = middleware.ParamSpec{Name: "search", Type: middleware.ParamString}
+	paramLimit    = middleware.ParamSpec{Name: "limit", Type: middleware.ParamInt, Min: 0, Max: float64(httpquery.DefaultPageOptions.MaxLimit)}
+	paramOffset   = middleware.ParamSpec{Name: "offset", Type: middleware.ParamInt, Min: 0, Max: math.MaxInt32}
+	paramAsOf     = middleware.ParamSpec{Name: "as_of", Type: middleware.ParamString} // date or RFC 3339 time
+	paramDataset  = middleware.ParamSpec{Name: "dataset", Type: middleware.ParamString}
+
+	paramDebugTiming   = middleware.ParamSpec{Name: middleware.DebugTimingParam, Type: middleware.ParamEnum, Values: []string{"true", "false"}}
+	paramIncludeOther  = middleware.ParamSpec{Name: "include_other", Type: middleware.ParamEnum, Values: []string{"true", "false"}}
+	paramIncludeTotals = middleware.ParamSpec{Name: "include_totals", Type: middleware.ParamEnum, Values: []string{"true", "false"}}
+)
+
+// optionParams are read by getQueryOptions, formatParams by getFormatter.
+// filterParams are the dimension filters of optionParams: comma-separated
+// lists, each with an exclude_ counterpart.
+var (
+	filterParams = withExclusions(paramSegment, paramCountry, paramRegion, paramCategory)
+	optionParams = params([]middleware.ParamSpec{paramSample}, filterParams)
+	formatParams = []middleware.ParamSpec{paramLocale, paramCurrency}
+)
+
+// withExclusions returns the filter parameters as lists, followed by their
+// exclude_ counterparts
+func withExclusions(filters ...middleware.ParamSpec) []middleware.ParamSpec {
+	all := slices.Clone(filters)
+	for i := range all {
+		all[i].Type = middleware.ParamList
+	}
+	for _, filter := range all[:len(filters)] {
+		filter.Name = httpquery.ExcludePrefix + filter.Name
+		all = append(all, filter)
+	}
+	return all
+}
+
+func params(groups ...[]middleware.ParamSpec) []middleware.ParamSpec {
+	var all []middleware.ParamSpec
+	for _, group := range groups {
+		all = append(all, group...)
+	}
+	return all
+}
+
+// QueryParamSpecs lists the query parameters each GET route accepts, keyed by
+// "METHOD /path/template" for middleware.QueryValidation. Keep in sync with
+// the handlers when adding parameters.
+var QueryParamSpecs = map[string][]middleware.ParamSpec{
+	"GET /api/v1/analytics": params(optionParams, formatParams, []middleware.ParamSpec{
+		paramAsOf, paramDataset,
+		{Name: "partial", Type: middleware.ParamEnum, Values: []string{"true", "false"}},
+		{Name: "include", Type: middleware.ParamString},
+		{Name: "lite", Type: middleware.ParamEnum, Values: []string{"true", "false"}},
+	}),
+	"GET /api/v1/analytics/stats": {},
+	"GET /api/v1/analytics/country-revenue": params(optionParams, formatParams, []middleware.ParamSpec{
+		paramLimit, paramOffset, paramAsOf, paramDataset, paramIncludeTotals,
+	}),
+	"GET /api/v1/analytics/top-products": params(optionParams, formatParams, []middleware.ParamSpec{
+		paramLimit, paramOffset, paramAsOf, paramDataset, paramIncludeOther, paramIncludeTotals,
+		{Name: "sort_by", Type: middleware.ParamEnum, Values: productRanks},
+	}),
+	"GET /api/v1/analytics/monthly-sales": params(optionParams, formatParams, []middleware.ParamSpec{paramAsOf, paramDataset}),
+	"GET /api/v1/analytics/top-regions": params(optionParams, formatParams, []middleware.ParamSpec{
+		paramLimit, paramOffset, paramAsOf, paramDataset, paramIncludeOther, paramIncludeTotals,
+		{Name: "sort_by", Type: middleware.ParamEnum, Values: regionRanks},
+	}),
+	"GET /api/v1/analytics/segments": params(optionParams, formatParams, []middleware.ParamSpec{paramAsOf, paramDataset}),
+	"GET /api/v1/analytics/top-customers": params(formatParams, filterParams, []middleware.ParamSpec{
+		paramAsOf, paramDataset,
+		{Name: "from", Type: middleware.ParamDate},
+		{Name: "to", Type: middleware.ParamDate},
+		{Name: "limit", Type: middleware.ParamInt, Min: 1, Max: 1000},
+	}),
+	"GET /api/v1/analytics/countries/{country}": params(formatParams, []middleware.ParamSpec{
+		paramSegment, paramAsOf, paramDataset,
+		{Name: "from", Type: middleware.ParamDate},
+		{Name: "to", Type: middleware.ParamDate},
+		{Name: "limit", Type: middleware.ParamInt, Min: 1, Max: 100},
+	}),
+	"GET /api/v1/analytics/aggregate": params(optionParams, []middleware.ParamSpec{
+		{Name: "group_by", Type: middleware.ParamString},
+		{Name: "metrics", Type: middleware.ParamString},
+	}),
+	"GET /api/v1/analytics/contribution": params(optionParams, []middleware.ParamSpec{
+		{Name: "metric", Type: middleware.ParamString},
+		{Name: "from", Type: middleware.ParamDate},
+		{Name: "to", Type: middleware.ParamDate},
+		{Name: "by", Type: middleware.ParamString},
+		{Name: "limit", Type: middleware.ParamInt, Min: 1, Max: 100},
+	}),
+	"GET /api/v1/analytics/abc": params(optionParams, []middleware.ParamSpec{
+		{Name: "from", Type: middleware.ParamDate},
+		{Name: "to", Type: middleware.ParamDate},
+		{Name: "a_cutoff", Type: middleware.ParamFloat, Min: 0, Max: 1},
+		{Name: "b_cutoff", Type: middleware.ParamFloat, Min: 0, Max: 1},
+		{Name: "class", Type: middleware.ParamEnum, Values: []string{"A", "B", "C", "a", "b", "c"}},
+		paramLimit, paramOffset,
+	}),
+	"GET /api/v1/analytics/heatmap": params(optionParams, formatParams, []middleware.ParamSpec{
+		{Name: "from", Type: middleware.ParamDate},
+		{Name: "to", Type: middleware.ParamDate},
+		{Name: "countries", Type: middleware.ParamInt, Min: 1, Max: 250},
+		{Name: "regions", Type: middleware.ParamInt, Min: 1, Max: 100},
+		{Name: "other", Type: middleware.ParamEnum, Values: []string{"true", "false"}},
+	}),
+	"GET /api/v1/analytics/timeseries": params(optionParams, []middleware.ParamSpec{
+		{Name: "metrics", Type: middleware.ParamString},
+		{Name: "granularity", Type: middleware.ParamEnum, Values: []string{"day", "week", "month"}},
+		{Name: "smooth", Type: middleware.ParamEnum, Values: []string{"ma7", "ma30", "ewma"}},
+		{Name: "from", Type: middleware.ParamDate},
+		{Name: "to", Type: middleware.ParamDate},
+	}),
+	"GET /api/v1/analytics/calendar": params(optionParams, []middleware.ParamSpec{
+		{Name: "year", Type: middleware.ParamInt, Min: 1, Max: 9999},
+		{Name: "metric", Type: middleware.ParamEnum, Values: []string{"revenue", "orders", "units"}},
+	}),
+	"GET /api/v1/analytics/plan-vs-actual": params(formatParams, []middleware.ParamSpec{paramCountry}),
+	"GET /api/v1/analytics/refresh":        {},
+	"GET /api/v1/products":                 {paramLimit, paramOffset, paramSearch},
+	"GET /api/v1/products/{id}":            formatParams,
+	"GET /api/v1/products/{id}/price-history": params(optionParams, formatParams, []middleware.ParamSpec{
+		{Name: "from", Type: middleware.ParamDate},
+		{Name: "to", Type: middleware.ParamDate},
+	}),
+	"GET /api/v1/inventory/sell-through": params(optionParams, []middleware.ParamSpec{
+		{Name: "by", Type: middleware.ParamEnum, Values: []string{"product", "category"}},
+		{Name: "window_days", Type: middleware.ParamInt, Min: 1, Max: 3660},
+		{Name: "to", Type: middleware.ParamDate},
+		{Name: "sort", Type: middleware.ParamString},
+		paramLimit, paramOffset,
+	}),
+	"GET /api/v1/meta/values": {
+		{Name: "dimension", Type: middleware.ParamString},
+		paramSearch, paramLimit, paramOffset,
+	},
+	"GET /api/v1/data/profile": {
+		{Name: "top", Type: middleware.ParamInt, Min: 0, Max: maxProfileTop},
+	},
+	"GET /api/v1/data/outliers": {paramLimit, paramOffset},
+	"GET /api/v1/annotations": {
+		{Name: "from", Type: middleware.ParamDate},
+		{Name: "to", Type: middleware.ParamDate},
+	},
+	"GET /api/v1/admin/stats": {},
+	"GET /api/v1/admin/audit": {{
+		Name: "limit", Type: middleware.ParamInt,
+		Min: float64(auditPageOptions.MinLimit), Max: float64(auditPageOptions.MaxLimit),
+	}},
+	"GET /api/v1/admin/backups":       {},
+	"GET /api/v1/admin/partitions":    {},
+	"GET /api/v1/admin/diagnostics":   {},
+	"GET /api/v1/admin/config":        {},
+	"GET /api/v1/admin/debug-capture": {},
+	"GET /api/v1/admin/flags":         {},
+	"GET /api/v1/openapi.json":        {},
+	"GET /api/v1/datasets":            {},
+	"GET /api/v1/exports":             {},
+	"GET /api/v1/exports/{id}":        {},
+	"GET /api/v1/exports/{id}/download": {
+		{Name: "expires", Type: middleware.ParamInt, Min: 0, Max: math.MaxInt64},
+		{Name: "signature", Type: middleware.ParamString},
+	},
+	"GET /api/v1/jobs/{id}":        {},
+	"GET /api/v1/refresh/schedule": {},
+	"GET /api/v1/uploads/{id}":     {},
+	"GET /api/v1/reports":          {},
+	"GET /api/v1/reports/{name}": params(optionParams, []middleware.ParamSpec{
+		{Name: "format", Type: middleware.ParamEnum, Values: []string{"html", "pdf", "json"}},
+	}),
+	"GET /api/v1/alerts/history": {{
+		Name: "limit", Type: middleware.ParamInt,
+		Min: float64(historyPageOptions.MinLimit), Max: float64(historyPageOptions.MaxLimit),
+	}},
+}
+
+// datasetRoutes are the analytics routes, as registered under /api/v1, that
+// are also served for a named dataset under /api/v1/datasets/{dataset}
+var datasetRoutes = []string{
+	"/analytics",
+	"/analytics/country-revenue",
+	"/analytics/top-products",
+	"/analytics/monthly-sales",
+	"/analytics/top-regions",
+	"/analytics/segments",
+	"/analytics/top-customers",
+	"/analytics/countries/{country}",
+}
+
+func init() {
+	for _, path := range datasetRoutes {
+		QueryParamSpecs["GET /api/v1/datasets/{dataset}"+path] = QueryParamSpecs["GET /api/v1"+path]
+	}
+	// Every route can report its timing breakdown
+	for route, specs := range QueryParamSpecs {
+		QueryParamSpecs[route] = append(specs[:len(specs):len(specs)], paramDebugTiming)
+	}
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/internal/utils"
+	"analytics-dashboard-api/pkg/logger"
+)
+
+// RefreshScheduleService reads and replaces the automatic refresh schedule
+type RefreshScheduleService interface {
+	Schedule() models.RefreshSchedule
+	SetSchedule(models.RefreshScheduleRequest) (models.RefreshSchedule, error)
+}
+
+// RefreshScheduleHandler lets operators inspect and change when the data is
+// refreshed automatically
+type RefreshScheduleHandler struct {
+	scheduler RefreshScheduleService
+	logger    logger.Logger
+}
+
+func NewRefreshScheduleHandler(scheduler RefreshScheduleService, logger logger.Logger) *RefreshScheduleHandler {
+	return &RefreshScheduleHandler{
+		scheduler: scheduler,
+		logger:    logger,
+	}
+}
+
+// GetSchedule returns the schedule with its next run and the outcome of the
+// last one
+func (h *RefreshScheduleHandler) GetSchedule(w http.ResponseWriter, r *http.Request) {
+	utils.WriteJSONResponse(w, http.StatusOK, h.scheduler.Schedule())
+}
+
+// UpdateSchedule replaces the schedule with the JSON body until the next
+// restart; an empty cron expression disables it
+func (h *RefreshScheduleHandler) UpdateSchedule(w http.ResponseWriter, r *http.Request) {
+	var request models.RefreshScheduleRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		utils.WriteErrorResponseWithCode(w, http.StatusBadRequest, utils.ErrCodeInvalidBody, "Invalid request body")
+		return
+	}
+
+	schedule, err := h.scheduler.SetSchedule(request)
+	if err != nil {
+		writeServiceError(w, h.logger, err, "Failed to update refresh schedule")
+		return
+	}
+
+	utils.WriteJSONResponse(w, http.StatusOK, schedule)
+}
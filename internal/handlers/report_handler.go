@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/internal/utils"
+	"analytics-dashboard-api/pkg/logger"
+
+	"github.com/gorilla/mux"
+)
+
+// ReportService renders report templates defined in YAML
+type ReportService interface {
+	List() ([]models.ReportTemplateInfo, error)
+	Render(context.Context, string, models.QueryOptions) (*models.Report, error)
+	HTML(*models.Report) ([]byte, error)
+	PDF(context.Context, *models.Report) ([]byte, error)
+}
+
+// ReportHandler lists report templates and renders them as HTML, PDF or JSON
+type ReportHandler struct {
+	reports     ReportService
+	initializer Initializer
+	logger      logger.Logger
+}
+
+func NewReportHandler(reports ReportService, initializer Initializer, logger logger.Logger) *ReportHandler {
+	return &ReportHandler{
+		reports:     reports,
+		initializer: initializer,
+		logger:      logger,
+	}
+}
+
+// ListReports returns every report template, with the error of any that
+// fails to load
+func (h *ReportHandler) ListReports(w http.ResponseWriter, r *http.Request) {
+	data, err := h.reports.List()
+	if err != nil {
+		writeServiceError(w, h.logger, err, "Failed to list reports")
+		return
+	}
+
+	utils.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"data":  data,
+		"count": len(data),
+	})
+}
+
+// GetReport renders a report template (?format=html|pdf|json, html by
+// default). ?country= and ?segment= override the template's filters.
+func (h *ReportHandler) GetReport(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	format := r.URL.Query().Get("format")
+	switch format {
+	case "":
+		format = "html"
+	case "html", "pdf", "json":
+	default:
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid format parameter")
+		return
+	}
+
+	if err := h.initializer.EnsureInitialized(r.Context()); err != nil {
+		writeServiceError(w, h.logger, err, "Failed to initialize database")
+		return
+	}
+
+	report, err := h.reports.Render(r.Context(), name, getQueryOptions(r))
+	if err != nil {
+		writeServiceError(w, h.logger, err, "Failed to render report", "report", name)
+		return
+	}
+
+	if format == "json" {
+		utils.WriteJSONResponse(w, http.StatusOK, report)
+		return
+	}
+
+	var body []byte
+	contentType := "text/html; charset=utf-8"
+	if format == "pdf" {
+		body, err = h.reports.PDF(r.Context(), report)
+		contentType = "application/pdf"
+	} else {
+		body, err = h.reports.HTML(report)
+	}
+	if err != nil {
+		writeServiceError(w, h.logger, err, "Failed to render report", "report", name)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	if format == "pdf" {
+		w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", report.Name+".pdf"))
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
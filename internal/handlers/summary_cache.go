@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"analytics-dashboard-api/internal/models"
+)
+
+// maxSummaryEntries bounds the summaries cached for one data version; the
+// cache starts over when it is full
+const maxSummaryEntries = 256
+
+// summaryCache holds dashboard summaries of the loaded data by their
+// filters and sections. Every entry belongs to the same data version, and
+// the cache empties itself when asked about another one.
+type summaryCache struct {
+	mu      sync.Mutex
+	version uint64
+	entries map[string]*models.AnalyticsResponse
+	hits    uint64
+	misses  uint64
+}
+
+// summaryKey identifies the queries a summary request runs
+func summaryKey(opts models.QueryOptions, include map[string]bool) string {
+	sections := make([]string, 0, len(include))
+	for name, ok := range include {
+		if ok {
+			sections = append(sections, name)
+		}
+	}
+	slices.Sort(sections)
+	return fmt.Sprintf("%g|%s|%s|%q|%s|%s|%s", opts.SampleRate, opts.Segment, opts.Country, opts.Filters,
+		opts.From.Format(time.RFC3339Nano), opts.To.Format(time.RFC3339Nano), strings.Join(sections, ","))
+}
+
+// get returns a copy of the summary cached for key at version, counting
+// the lookup as a hit or a miss. The copy's sections may be formatted
+// without touching the cached ones.
+func (c *summaryCache) get(version uint64, key string) (*models.AnalyticsResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.version != version {
+		c.version, c.entries = version, nil
+	}
+	cached, ok := c.entries[key]
+	if !ok {
+		c.misses++
+		return nil, false
+	}
+	c.hits++
+	return cloneSummary(cached), true
+}
+
+// put caches a copy of analytics for key, unless the data has moved past
+// version since the summary was computed
+func (c *summaryCache) put(version uint64, key string, analytics *models.AnalyticsResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.version != version {
+		return
+	}
+	if c.entries == nil || len(c.entries) >= maxSummaryEntries {
+		c.entries = make(map[string]*models.AnalyticsResponse)
+	}
+	c.entries[key] = cloneSummary(analytics)
+}
+
+// cloneSummary copies analytics and its sections, which formatting changes
+// in place
+func cloneSummary(analytics *models.AnalyticsResponse) *models.AnalyticsResponse {
+	clone := *analytics
+	clone.CountryRevenue = slices.Clone(analytics.CountryRevenue)
+	clone.TopProducts = slices.Clone(analytics.TopProducts)
+	clone.MonthlySales = slices.Clone(analytics.MonthlySales)
+	clone.TopRegions = slices.Clone(analytics.TopRegions)
+	return &clone
+}
+
+func (c *summaryCache) stats() models.ResponseCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return models.ResponseCacheStats{Entries: len(c.entries), Hits: c.hits, Misses: c.misses}
+}
@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"analytics-dashboard-api/internal/middleware"
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/internal/utils"
+	"analytics-dashboard-api/pkg/logger"
+)
+
+// maxTargetsUploadBytes bounds the size of an uploaded targets file
+const maxTargetsUploadBytes = 32 << 20
+
+type TargetService interface {
+	LoadTargets(context.Context, string) (*models.TableLoadResult, error)
+	GetTargetVariance(context.Context, string) ([]models.TargetVariance, error)
+	CoverageProvider
+}
+
+// UploadSource hands out the files of completed resumable uploads
+type UploadSource interface {
+	File(string) (*models.UploadedFile, error)
+	Delete(string) error
+}
+
+// FileScanner vets uploaded files before they are ingested
+type FileScanner interface {
+	Scan(context.Context, models.UploadedFile) error
+}
+
+// TargetQuota refuses target files larger than the dataset or the uploading
+// tenant may load
+type TargetQuota interface {
+	CheckFiles(ctx context.Context, dataset, tenant string, paths ...string) error
+}
+
+// TargetLoader initializes the data and queues target uploads with the loads
+type TargetLoader interface {
+	Initializer
+	DataJobs
+}
+
+type TargetHandler struct {
+	targetService TargetService
+	initializer   TargetLoader
+	uploads       UploadSource
+	scanner       FileScanner
+	quotas        TargetQuota
+	logger        logger.Logger
+}
+
+func NewTargetHandler(
+	targetService TargetService,
+	initializer TargetLoader,
+	uploads UploadSource,
+	scanner FileScanner,
+	quotas TargetQuota,
+	logger logger.Logger,
+) *TargetHandler {
+	return &TargetHandler{
+		targetService: targetService,
+		initializer:   initializer,
+		uploads:       uploads,
+		scanner:       scanner,
+		quotas:        quotas,
+		logger:        logger,
+	}
+}
+
+// UploadTargets replaces the revenue targets with an uploaded CSV
+// (month,country,revenue_target), sent either as a multipart "file" field,
+// as a text/csv request body or as a completed resumable upload named by
+// ?upload_id=
+func (h *TargetHandler) UploadTargets(w http.ResponseWriter, r *http.Request) {
+	uploadID := r.URL.Query().Get("upload_id")
+
+	var file *models.UploadedFile
+	var err error
+	if uploadID != "" {
+		if file, err = h.uploads.File(uploadID); err != nil {
+			writeServiceError(w, h.logger, err, "Failed to use upload", "upload", uploadID)
+			return
+		}
+	} else {
+		r.Body = http.MaxBytesReader(w, r.Body, maxTargetsUploadBytes)
+		if file, err = saveUploadedCSV(r, "targets-*.csv"); err != nil {
+			h.logger.Warn("Rejected targets upload", "error", err)
+			utils.WriteErrorResponseWithCode(w, http.StatusBadRequest, utils.ErrCodeInvalidBody, "Invalid targets upload: "+err.Error())
+			return
+		}
+	}
+	path := file.Path
+
+	// Files the scan refuses are discarded
+	file.UploadedBy, _ = middleware.IdentityFromContext(r.Context())
+	if err := h.scanner.Scan(r.Context(), *file); err != nil {
+		if uploadID == "" {
+			os.Remove(path)
+		} else if errors.Is(err, models.ErrUploadRejected) {
+			h.uploads.Delete(uploadID)
+		}
+		writeServiceError(w, h.logger, err, "Failed to scan targets upload")
+		return
+	}
+
+	// Checked before queuing, so an oversized file never replaces the targets
+	if err := h.quotas.CheckFiles(r.Context(), models.DatasetTargets, file.UploadedBy, path); err != nil {
+		if uploadID == "" {
+			os.Remove(path)
+		} else if errors.Is(err, models.ErrQuotaExceeded) {
+			h.uploads.Delete(uploadID)
+		}
+		writeServiceError(w, h.logger, err, "Failed to check targets quota", "tenant", file.UploadedBy)
+		return
+	}
+
+	// The job owns the file: it may still be queued when the client leaves.
+	// A resumable upload is only discarded once it loaded.
+	var result *models.TableLoadResult
+	err = h.initializer.RunJob(r.Context(), "targets_upload", func(ctx context.Context) error {
+		var err error
+		result, err = h.targetService.LoadTargets(ctx, path)
+		switch {
+		case uploadID == "":
+			os.Remove(path)
+		case err == nil:
+			if err := h.uploads.Delete(uploadID); err != nil {
+				h.logger.Warn("Failed to remove loaded upload", "upload", uploadID, "error", err)
+			}
+		}
+		return err
+	})
+	if err != nil {
+		writeServiceError(w, h.logger, err, "Failed to load targets")
+		return
+	}
+
+	utils.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"message": "Targets uploaded successfully",
+		"records": result.Records,
+	})
+}
+
+// GetVariance returns actual vs target revenue per month and country
+func (h *TargetHandler) GetVariance(w http.ResponseWriter, r *http.Request) {
+	country := utils.SanitizeString(r.URL.Query().Get("country"))
+	formatter, err := getFormatter(r)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.initializer.EnsureInitialized(r.Context()); err != nil {
+		writeServiceError(w, h.logger, err, "Failed to initialize database")
+		return
+	}
+
+	data, err := h.targetService.GetTargetVariance(r.Context(), country)
+	if err != nil {
+		writeServiceError(w, h.logger, err, "Failed to get target variance")
+		return
+	}
+
+	var totalTarget, totalActual models.Money
+	for _, tv := range data {
+		totalTarget += tv.Target
+		totalActual += tv.Actual
+	}
+
+	totals := map[string]interface{}{
+		"target":   totalTarget,
+		"actual":   totalActual,
+		"variance": totalActual - totalTarget,
+	}
+	if formatter != nil {
+		for i := range data {
+			data[i].ApplyDisplay(formatter)
+		}
+		totals["display"] = map[string]string{
+			"target":   formatter.Money(totalTarget),
+			"actual":   formatter.Money(totalActual),
+			"variance": formatter.Money(totalActual - totalTarget),
+		}
+	}
+
+	response := map[string]interface{}{
+		"data":   data,
+		"count":  len(data),
+		"totals": totals,
+	}
+	addFormatInfo(response, formatter)
+	addCoverage(response, h.targetService)
+
+	utils.WriteJSONResponse(w, http.StatusOK, response)
+}
+
+// saveUploadedCSV writes the uploaded CSV to a temporary file. Callers are
+// responsible for removing the file.
+func saveUploadedCSV(r *http.Request, pattern string) (*models.UploadedFile, error) {
+	var src io.Reader = r.Body
+	var name string
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			return nil, fmt.Errorf("missing file field: %w", err)
+		}
+		defer file.Close()
+		src = file
+		name = header.Filename
+	}
+
+	tmp, err := os.CreateTemp("", pattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer tmp.Close()
+
+	written, err := io.Copy(tmp, src)
+	if err != nil {
+		os.Remove(tmp.Name())
+		return nil, fmt.Errorf("failed to read upload: %w", err)
+	}
+	if written == 0 {
+		os.Remove(tmp.Name())
+		return nil, fmt.Errorf("empty upload")
+	}
+
+	return &models.UploadedFile{Path: tmp.Name(), Name: name, Size: written}, nil
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"analytics-dashboard-api/internal/config"
+)
+
+// slowReadRoutes are GET routes, as registered under /api/v1, that stream
+// files or render documents and get the admin timeout instead of the data
+// timeout
+var slowReadRoutes = map[string]bool{
+	"/exports/{id}/download": true,
+	"/reports/{name}":        true,
+}
+
+// ResponseTimeouts resolves how long a route under /api/v1, given by method
+// and path template, may take to respond. Reads get cfg.DataTimeout; admin
+// routes, routes that change data and slowReadRoutes get cfg.AdminTimeout.
+// A path listed in cfg.TimeoutOverrides takes that timeout for every
+// method; zero lifts the limit. ok is false for routes outside /api/v1.
+func ResponseTimeouts(cfg config.ServerConfig) (func(method, template string) (time.Duration, bool), error) {
+	overrides := make(map[string]time.Duration)
+	for _, pair := range strings.Split(cfg.TimeoutOverrides, ";") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		path, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid response timeout override %q: want path=duration", pair)
+		}
+		path = strings.TrimSpace(path)
+		if !strings.HasPrefix(path, "/") {
+			return nil, fmt.Errorf("invalid response timeout override %q: path must start with /", pair)
+		}
+		timeout, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil || timeout < 0 {
+			return nil, fmt.Errorf("invalid response timeout override %q: bad duration", pair)
+		}
+		overrides[path] = timeout
+	}
+
+	return func(method, template string) (time.Duration, bool) {
+		path, ok := strings.CutPrefix(template, "/api/v1")
+		if !ok {
+			return 0, false
+		}
+		if timeout, ok := overrides[path]; ok {
+			return timeout, true
+		}
+		read := method == http.MethodGet || method == http.MethodHead
+		if !read || strings.HasPrefix(path, "/admin/") || slowReadRoutes[path] {
+			return cfg.AdminTimeout, true
+		}
+		return cfg.DataTimeout, true
+	}, nil
+}
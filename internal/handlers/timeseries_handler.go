@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/internal/utils"
+	"analytics-dashboard-api/pkg/logger"
+)
+
+// TimeSeriesService aligns several metrics on one list of periods
+type TimeSeriesService interface {
+	Series(context.Context, models.QueryOptions, string, string) (*models.TimeSeries, error)
+	Calendar(context.Context, models.QueryOptions, int, string) (*models.Calendar, error)
+}
+
+// timeSeriesMetrics are the ?metrics= of the time series endpoint, in
+// their default order
+var timeSeriesMetrics = []string{models.SeriesRevenue, models.SeriesOrders, models.SeriesUnits}
+
+// TimeSeriesHandler serves multi-metric time series for charts
+type TimeSeriesHandler struct {
+	series      TimeSeriesService
+	initializer Initializer
+	coverage    CoverageProvider
+	logger      logger.Logger
+}
+
+func NewTimeSeriesHandler(series TimeSeriesService, initializer Initializer, coverage CoverageProvider, logger logger.Logger) *TimeSeriesHandler {
+	return &TimeSeriesHandler{
+		series:      series,
+		initializer: initializer,
+		coverage:    coverage,
+		logger:      logger,
+	}
+}
+
+// GetTimeSeries returns ?metrics= (revenue, orders, units; all by default)
+// per ?granularity= (day, week or month; day by default) as series aligned
+// on one list of dates. ?from=&to= (YYYY-MM-DD, inclusive) and the
+// analytics filters select the transactions. ?smooth=ma7|ma30|ewma adds the
+// smoothed metrics under "smoothed", next to the raw ones.
+func (h *TimeSeriesHandler) GetTimeSeries(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	opts := getQueryOptions(r)
+	if err := getDateRange(r, &opts); err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	granularity := query.Get("granularity")
+	if granularity == "" {
+		granularity = models.GranularityDay
+	}
+	metrics := timeSeriesMetrics
+	if value := query.Get("metrics"); value != "" {
+		metrics = nil
+		for _, name := range strings.Split(value, ",") {
+			name = strings.TrimSpace(name)
+			if !slices.Contains(timeSeriesMetrics, name) {
+				utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid metrics parameter: must list revenue, orders or units")
+				return
+			}
+			if !slices.Contains(metrics, name) {
+				metrics = append(metrics, name)
+			}
+		}
+	}
+
+	if err := h.initializer.EnsureInitialized(r.Context()); err != nil {
+		writeServiceError(w, h.logger, err, "Failed to initialize database")
+		return
+	}
+
+	series, err := h.series.Series(r.Context(), opts, granularity, query.Get("smooth"))
+	if err != nil {
+		writeServiceError(w, h.logger, err, "Failed to get time series")
+		return
+	}
+
+	values := make(map[string]interface{}, len(metrics))
+	for _, name := range metrics {
+		switch name {
+		case models.SeriesRevenue:
+			values[name] = series.Revenue
+		case models.SeriesOrders:
+			values[name] = series.Orders
+		case models.SeriesUnits:
+			values[name] = series.Units
+		}
+	}
+
+	response := map[string]interface{}{
+		"granularity": series.Granularity,
+		"metrics":     metrics,
+		"dates":       series.Dates,
+		"series":      values,
+		"count":       len(series.Dates),
+	}
+	if series.Smooth != "" {
+		smoothed := make(map[string]interface{}, len(metrics))
+		for _, name := range metrics {
+			smoothed[name] = series.Smoothed[name]
+		}
+		response["smooth"] = series.Smooth
+		response["smoothed"] = smoothed
+	}
+	addSampleInfo(response, opts)
+	addCoverage(response, h.coverage)
+
+	utils.WriteJSONResponse(w, http.StatusOK, response)
+}
+
+// GetCalendar returns ?metric= (revenue, orders or units; revenue by
+// default) for every day of ?year=, by default the year of the latest data,
+// shaped for calendar heatmaps. The analytics filters select the
+// transactions.
+func (h *TimeSeriesHandler) GetCalendar(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	opts := getQueryOptions(r)
+
+	year := 0
+	if value := query.Get("year"); value != "" {
+		var err error
+		if year, err = strconv.Atoi(value); err != nil || year < 1 || year > 9999 {
+			utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid year parameter: must be between 1 and 9999")
+			return
+		}
+	}
+	metric := query.Get("metric")
+	if metric == "" {
+		metric = models.SeriesRevenue
+	}
+
+	if err := h.initializer.EnsureInitialized(r.Context()); err != nil {
+		writeServiceError(w, h.logger, err, "Failed to initialize database")
+		return
+	}
+
+	calendar, err := h.series.Calendar(r.Context(), opts, year, metric)
+	if err != nil {
+		writeServiceError(w, h.logger, err, "Failed to get calendar")
+		return
+	}
+
+	response := map[string]interface{}{
+		"year":      calendar.Year,
+		"metric":    calendar.Metric,
+		"total":     calendar.Total,
+		"max_value": calendar.MaxValue,
+		"data":      calendar.Days,
+		"count":     len(calendar.Days),
+	}
+	addSampleInfo(response, opts)
+	addCoverage(response, h.coverage)
+
+	utils.WriteJSONResponse(w, http.StatusOK, response)
+}
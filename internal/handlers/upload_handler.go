@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"analytics-dashboard-api/internal/config"
+	"analytics-dashboard-api/internal/middleware"
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/internal/utils"
+	"analytics-dashboard-api/pkg/logger"
+
+	"github.com/gorilla/mux"
+)
+
+// UploadService manages resumable upload sessions
+type UploadService interface {
+	Create(int64, string) (*models.Upload, error)
+	Get(string) (*models.Upload, error)
+	Append(string, int64, io.Reader) (*models.Upload, error)
+	Delete(string) error
+}
+
+// UploadQuota refuses uploads larger than the tenant may load
+type UploadQuota interface {
+	CheckSize(tenant string, size int64) error
+}
+
+// UploadHandler serves a tus-style resumable upload API: create a session
+// with Upload-Length, send chunks with PATCH and Upload-Offset, and after a
+// dropped connection ask for the offset and continue from there
+type UploadHandler struct {
+	uploads UploadService
+	quotas  UploadQuota
+	config  config.UploadConfig
+	logger  logger.Logger
+}
+
+func NewUploadHandler(uploads UploadService, quotas UploadQuota, config config.UploadConfig, logger logger.Logger) *UploadHandler {
+	return &UploadHandler{
+		uploads: uploads,
+		quotas:  quotas,
+		config:  config,
+		logger:  logger,
+	}
+}
+
+// CreateUpload starts a session for a file of Upload-Length bytes. The file
+// name may be passed as "filename" in tus Upload-Metadata.
+func (h *UploadHandler) CreateUpload(w http.ResponseWriter, r *http.Request) {
+	size, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Upload-Length header must give the file size in bytes")
+		return
+	}
+	metadata, err := parseUploadMetadata(r.Header.Get("Upload-Metadata"))
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Only the base name is kept; it is used for the extension check
+	filename := metadata["filename"]
+	if filename != "" {
+		filename = filepath.Base(filename)
+	}
+
+	tenant, _ := middleware.IdentityFromContext(r.Context())
+	if err := h.quotas.CheckSize(tenant, size); err != nil {
+		writeServiceError(w, h.logger, err, "Failed to create upload", "tenant", tenant)
+		return
+	}
+
+	upload, err := h.uploads.Create(size, filename)
+	if err != nil {
+		writeServiceError(w, h.logger, err, "Failed to create upload")
+		return
+	}
+
+	w.Header().Set("Location", "/api/v1/uploads/"+upload.ID)
+	writeUploadResponse(w, http.StatusCreated, upload)
+}
+
+// GetUpload reports how many bytes of a session have arrived. It also
+// answers HEAD, as tus clients expect.
+func (h *UploadHandler) GetUpload(w http.ResponseWriter, r *http.Request) {
+	upload, err := h.uploads.Get(mux.Vars(r)["id"])
+	if err != nil {
+		writeServiceError(w, h.logger, err, "Failed to get upload")
+		return
+	}
+
+	writeUploadResponse(w, http.StatusOK, upload)
+}
+
+// AppendUpload writes the request body at Upload-Offset
+func (h *UploadHandler) AppendUpload(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Upload-Offset header must give the chunk's byte offset")
+		return
+	}
+
+	// A chunk on a slow link may take longer than SERVER_READ_TIMEOUT
+	if err := http.NewResponseController(w).SetReadDeadline(time.Now().Add(h.config.ChunkTimeout)); err != nil {
+		h.logger.Debug("Could not extend read deadline for upload chunk", "error", err)
+	}
+
+	upload, err := h.uploads.Append(id, offset, r.Body)
+	if err != nil {
+		// Tell the client where to resume
+		if errors.Is(err, models.ErrUploadOffset) {
+			if current, getErr := h.uploads.Get(id); getErr == nil {
+				w.Header().Set("Upload-Offset", strconv.FormatInt(current.Offset, 10))
+			}
+		}
+		writeServiceError(w, h.logger, err, "Failed to append upload chunk", "upload", id)
+		return
+	}
+
+	writeUploadResponse(w, http.StatusOK, upload)
+}
+
+// DeleteUpload discards a session and the data received so far
+func (h *UploadHandler) DeleteUpload(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	if err := h.uploads.Delete(id); err != nil {
+		writeServiceError(w, h.logger, err, "Failed to delete upload", "upload", id)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// parseUploadMetadata decodes a tus Upload-Metadata header: comma-separated
+// pairs of a key and a base64 value, the value optional
+func parseUploadMetadata(header string) (map[string]string, error) {
+	metadata := map[string]string{}
+	for _, pair := range strings.Split(header, ",") {
+		key, encoded, _ := strings.Cut(strings.TrimSpace(pair), " ")
+		if key == "" {
+			continue
+		}
+		value, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Upload-Metadata value for %q: must be base64", key)
+		}
+		metadata[key] = string(value)
+	}
+	return metadata, nil
+}
+
+// writeUploadResponse mirrors the session state in tus headers so clients
+// can resume without parsing the body
+func writeUploadResponse(w http.ResponseWriter, status int, upload *models.Upload) {
+	w.Header().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	w.Header().Set("Upload-Length", strconv.FormatInt(upload.Size, 10))
+	w.Header().Set("Cache-Control", "no-store")
+	utils.WriteJSONResponse(w, status, upload)
+}
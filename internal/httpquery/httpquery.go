@@ -0,0 +1,268 @@
+// Package httpquery parses the pagination, sorting and date-range query
+// parameters shared by list endpoints, so every endpoint applies the same
+// defaults, caps and validation.
+//
+// Parsers always return a usable value: malformed parameters fall back to
+// their defaults and are reported as an *Error. Endpoints that have always
+// been lenient log and ignore the error (strictness is enforced up front by
+// middleware.QueryValidation); others reject the request with it.
+package httpquery
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DateLayout is the accepted format of date parameters
+const DateLayout = "2006-01-02"
+
+// Error describes a malformed query parameter
+type Error struct {
+	Param  string
+	Value  string
+	Reason string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("invalid %s parameter %q: %s", e.Param, e.Value, e.Reason)
+}
+
+// Page is a parsed ?limit=&offset= pair
+type Page struct {
+	Limit  int
+	Offset int
+}
+
+// HasMore reports whether rows remain after this page
+func (p Page) HasMore(total int) bool {
+	return p.Offset+p.Limit < total
+}
+
+// Links holds the URLs of the first, previous, next and last pages of a
+// list; Prev and Next are empty on the first and last page
+type Links struct {
+	First string `json:"first"`
+	Prev  string `json:"prev,omitempty"`
+	Next  string `json:"next,omitempty"`
+	Last  string `json:"last"`
+}
+
+// Links builds the page URLs from the request URL u, keeping its path and
+// other query parameters and rewriting limit and offset. A zero limit
+// pages nowhere, so every link points at the first page.
+func (p Page) Links(u *url.URL, total int) Links {
+	link := func(offset int) string {
+		query := u.Query()
+		query.Set("limit", strconv.Itoa(p.Limit))
+		query.Set("offset", strconv.Itoa(offset))
+		return u.Path + "?" + query.Encode()
+	}
+
+	links := Links{First: link(0), Last: link(0)}
+	if p.Limit <= 0 {
+		return links
+	}
+	if total > 0 {
+		links.Last = link((total - 1) / p.Limit * p.Limit)
+	}
+	if p.Offset > 0 {
+		links.Prev = link(max(p.Offset-p.Limit, 0))
+	}
+	if p.HasMore(total) {
+		links.Next = link(p.Offset + p.Limit)
+	}
+	return links
+}
+
+// Header formats the links as an RFC 5988 Link header value
+func (l Links) Header() string {
+	var parts []string
+	for _, link := range []struct{ rel, url string }{
+		{"first", l.First}, {"prev", l.Prev}, {"next", l.Next}, {"last", l.Last},
+	} {
+		if link.url != "" {
+			parts = append(parts, fmt.Sprintf("<%s>; rel=%q", link.url, link.rel))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// PageOptions configures ParsePage
+type PageOptions struct {
+	DefaultLimit int
+	MinLimit     int
+	MaxLimit     int
+}
+
+// DefaultPageOptions is used by the list endpoints: 100 rows, capped at 1000
+var DefaultPageOptions = PageOptions{DefaultLimit: 100, MinLimit: 0, MaxLimit: 1000}
+
+// ParsePage reads ?limit= and ?offset=. Limits above MaxLimit are capped,
+// other invalid values fall back to the defaults.
+func ParsePage(query url.Values, opts PageOptions) (Page, error) {
+	page := Page{Limit: opts.DefaultLimit}
+	var firstErr error
+
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		switch {
+		case err != nil:
+			firstErr = &Error{Param: "limit", Value: value, Reason: "must be an integer"}
+		case limit < opts.MinLimit:
+			firstErr = &Error{Param: "limit", Value: value, Reason: fmt.Sprintf("must be at least %d", opts.MinLimit)}
+		case opts.MaxLimit > 0 && limit > opts.MaxLimit:
+			firstErr = &Error{Param: "limit", Value: value, Reason: fmt.Sprintf("must be at most %d", opts.MaxLimit)}
+			page.Limit = opts.MaxLimit
+		default:
+			page.Limit = limit
+		}
+	}
+
+	if value := query.Get("offset"); value != "" {
+		offset, err := strconv.Atoi(value)
+		if err != nil || offset < 0 {
+			if firstErr == nil {
+				firstErr = &Error{Param: "offset", Value: value, Reason: "must be a non-negative integer"}
+			}
+		} else {
+			page.Offset = offset
+		}
+	}
+
+	return page, firstErr
+}
+
+// Sort is a parsed ?sort= parameter: "field" for ascending, "-field" for
+// descending
+type Sort struct {
+	Field string
+	Desc  bool
+}
+
+// String renders the sort in ?sort= syntax
+func (s Sort) String() string {
+	if s.Desc {
+		return "-" + s.Field
+	}
+	return s.Field
+}
+
+// ParseSort reads ?sort=, accepting only the allowed fields. A missing or
+// unknown field yields fallback.
+func ParseSort(query url.Values, allowed []string, fallback Sort) (Sort, error) {
+	value := strings.TrimSpace(query.Get("sort"))
+	if value == "" {
+		return fallback, nil
+	}
+
+	sort := Sort{Field: value}
+	if strings.HasPrefix(value, "-") {
+		sort = Sort{Field: value[1:], Desc: true}
+	}
+
+	for _, field := range allowed {
+		if sort.Field == field {
+			return sort, nil
+		}
+	}
+	return fallback, &Error{Param: "sort", Value: value, Reason: "must be one of: " + strings.Join(allowed, ", ")}
+}
+
+// DateRange is a parsed ?from=&to= pair of YYYY-MM-DD dates (inclusive).
+// Empty bounds are open.
+type DateRange struct {
+	From string
+	To   string
+}
+
+// ParseDateRange reads ?from= and ?to=. Malformed bounds are dropped; an
+// inverted range is kept (it matches nothing) but reported.
+func ParseDateRange(query url.Values) (DateRange, error) {
+	var dates DateRange
+	var firstErr error
+
+	for _, bound := range []struct {
+		param string
+		dest  *string
+	}{{"from", &dates.From}, {"to", &dates.To}} {
+		value := strings.TrimSpace(query.Get(bound.param))
+		if value == "" {
+			continue
+		}
+		if _, err := time.Parse(DateLayout, value); err != nil {
+			if firstErr == nil {
+				firstErr = &Error{Param: bound.param, Value: value, Reason: "must be a date in YYYY-MM-DD format"}
+			}
+			continue
+		}
+		*bound.dest = value
+	}
+
+	if dates.From != "" && dates.To != "" && dates.From > dates.To {
+		return dates, &Error{Param: "from", Value: dates.From, Reason: "must not be after to"}
+	}
+	return dates, firstErr
+}
+
+// ExcludePrefix names the parameter dropping a filter's values, as in
+// ?exclude_country=
+const ExcludePrefix = "exclude_"
+
+// MaxFilterValues caps the values of one filter parameter
+const MaxFilterValues = 100
+
+// ValueFilter is a parsed ?name=a,b&exclude_name=c pair: rows must have one
+// of Include, if any are given, and none of Exclude
+type ValueFilter struct {
+	Include []string
+	Exclude []string
+}
+
+// Empty reports whether the filter keeps every row
+func (f ValueFilter) Empty() bool {
+	return len(f.Include) == 0 && len(f.Exclude) == 0
+}
+
+// ParseValueFilter reads ?name= and ?exclude_name=. Each takes a
+// comma-separated list and may be repeated. Values are trimmed and blanks
+// and repeats (ignoring case) dropped; values past MaxFilterValues are
+// dropped and reported.
+func ParseValueFilter(query url.Values, name string) (ValueFilter, error) {
+	include, err := ParseValues(query, name)
+	exclude, excludeErr := ParseValues(query, ExcludePrefix+name)
+	if err == nil {
+		err = excludeErr
+	}
+	return ValueFilter{Include: include, Exclude: exclude}, err
+}
+
+// ParseValues reads one list parameter of a ValueFilter, such as ?country=
+// or ?exclude_country=
+func ParseValues(query url.Values, param string) ([]string, error) {
+	var values []string
+	for _, list := range query[param] {
+		for _, value := range strings.Split(list, ",") {
+			value = strings.TrimSpace(value)
+			if value == "" || containsFold(values, value) {
+				continue
+			}
+			if len(values) == MaxFilterValues {
+				return values, &Error{Param: param, Value: strings.Join(query[param], ","), Reason: fmt.Sprintf("must list at most %d values", MaxFilterValues)}
+			}
+			values = append(values, value)
+		}
+	}
+	return values, nil
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
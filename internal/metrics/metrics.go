@@ -0,0 +1,153 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"analytics-dashboard-api/internal/models"
+)
+
+// Latency buckets in seconds: requests and queries from a millisecond up to
+// the data response timeout, loads up to an hour
+var (
+	latencyBuckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+	loadBuckets    = []float64{1, 5, 15, 30, 60, 120, 300, 600, 1800, 3600}
+)
+
+// Throughput buckets for the ingestion phases: rows per second from a
+// thousand to a hundred million, bytes per second from 1 MiB to 4 GiB
+var (
+	rowRateBuckets  = []float64{1e3, 1e4, 5e4, 1e5, 5e5, 1e6, 5e6, 1e7, 5e7, 1e8}
+	byteRateBuckets = []float64{1 << 20, 4 << 20, 16 << 20, 64 << 20, 128 << 20, 256 << 20, 512 << 20, 1 << 30, 2 << 30, 4 << 30}
+)
+
+// Metrics holds the instruments of one server. Handlers, middleware and
+// services record into it; a nil *Metrics records nothing.
+type Metrics struct {
+	registry *Registry
+
+	requests        *CounterVec
+	requestDuration *HistogramVec
+	queryDuration   *HistogramVec
+	loads           *CounterVec
+	loadDuration    *HistogramVec
+	loadedRows      *GaugeVec
+	loadedBytes     *GaugeVec
+
+	ingestRows     *CounterVec
+	ingestErrors   *CounterVec
+	ingestDuration *HistogramVec
+	ingestRowRate  *HistogramVec
+	ingestByteRate *HistogramVec
+}
+
+func New() *Metrics {
+	r := NewRegistry()
+	return &Metrics{
+		registry: r,
+		requests: r.Counter("http_requests_total",
+			"HTTP requests by method, route template and status code.", "method", "route", "status"),
+		requestDuration: r.Histogram("http_request_duration_seconds",
+			"HTTP request latency by method and route template.", latencyBuckets, "method", "route"),
+		queryDuration: r.Histogram("analytics_query_duration_seconds",
+			"Analytics backend query latency by backend and query type.", latencyBuckets, "backend", "query"),
+		loads: r.Counter("analytics_loads_total",
+			"Data loads by kind (initial_load, refresh, scheduled_refresh, file_change, dataset_upload) and result.", "kind", "result"),
+		loadDuration: r.Histogram("analytics_load_duration_seconds",
+			"Duration of successful data loads.", loadBuckets),
+		loadedRows: r.Gauge("analytics_loaded_rows",
+			"Transactions held by the latest successful load."),
+		loadedBytes: r.Gauge("analytics_loaded_source_bytes",
+			"Size of the source files read by the latest successful load."),
+		ingestRows: r.Counter("analytics_ingest_rows_total",
+			"Transaction rows parsed by data loads."),
+		ingestErrors: r.Counter("analytics_ingest_parse_errors_total",
+			"Transaction rows that failed to parse, failing their load."),
+		ingestDuration: r.Histogram("analytics_ingest_phase_duration_seconds",
+			"Time each load spent per ingestion phase (read, parse, insert, optimize).", loadBuckets, "phase"),
+		ingestRowRate: r.Histogram("analytics_ingest_rows_per_second",
+			"Rows per second through each ingestion phase, per load.", rowRateBuckets, "phase"),
+		ingestByteRate: r.Histogram("analytics_ingest_bytes_per_second",
+			"Source bytes per second through each ingestion phase, per load.", byteRateBuckets, "phase"),
+	}
+}
+
+// Registry returns the registry, to add metrics read from other components
+// such as the response cache counters
+func (m *Metrics) Registry() *Registry {
+	if m == nil {
+		return nil
+	}
+	return m.registry
+}
+
+// Handler serves the metrics to Prometheus scrapes
+func (m *Metrics) Handler() http.Handler {
+	if m == nil {
+		return http.NotFoundHandler()
+	}
+	return m.registry.Handler()
+}
+
+// ObserveRequest records a served HTTP request. route is the matched route
+// template, so paths with IDs do not each get their own series.
+func (m *Metrics) ObserveRequest(method, route string, status int, duration time.Duration) {
+	if m == nil {
+		return
+	}
+	m.requests.Inc(method, route, strconv.Itoa(status))
+	m.requestDuration.Observe(duration.Seconds(), method, route)
+}
+
+// ObserveQuery records how long a query of the given type took on backend
+func (m *Metrics) ObserveQuery(backend, query string, duration time.Duration) {
+	if m == nil {
+		return
+	}
+	m.queryDuration.Observe(duration.Seconds(), backend, query)
+}
+
+// ObserveLoad records a finished data load. rows and bytes describe the
+// loaded data and are only recorded for a successful load.
+func (m *Metrics) ObserveLoad(kind string, err error, duration time.Duration, rows, bytes int64) {
+	if m == nil {
+		return
+	}
+	if err != nil {
+		m.loads.Inc(kind, "failure")
+		return
+	}
+	m.loads.Inc(kind, "success")
+	m.loadDuration.Observe(duration.Seconds())
+	m.loadedRows.Set(float64(rows))
+	m.loadedBytes.Set(float64(bytes))
+}
+
+// ObserveIngest records the phases of a load of the transactions files. The
+// phase with the lowest throughput is where a slow load spends its time.
+func (m *Metrics) ObserveIngest(stats models.IngestStats) {
+	if m == nil {
+		return
+	}
+	m.ingestRows.Add(float64(stats.Rows))
+	m.ingestErrors.Add(float64(stats.ParseErrors))
+
+	phases := []struct {
+		name     string
+		duration time.Duration
+	}{{"read", stats.Read}, {"parse", stats.Parse}, {"insert", stats.Insert}, {"optimize", stats.Optimize}}
+	for _, phase := range phases {
+		if phase.duration <= 0 {
+			continue
+		}
+		seconds := phase.duration.Seconds()
+		m.ingestDuration.Observe(seconds, phase.name)
+		if stats.Rows > 0 {
+			m.ingestRowRate.Observe(float64(stats.Rows)/seconds, phase.name)
+		}
+		if stats.Bytes > 0 {
+			m.ingestByteRate.Observe(float64(stats.Bytes)/seconds, phase.name)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package middleware
+
+import (
+	"bufio"
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"analytics-dashboard-api/internal/utils"
+)
+
+type apiKeyIDKey struct{}
+
+// APIKeyHeader carries the API key required on protected routes
+const APIKeyHeader = "X-API-Key"
+
+// APIKeys maps each accepted API key to the identifier logged for it
+type APIKeys map[string]string
+
+// LoadAPIKeys combines the keys listed in spec, as "id=key" pairs separated
+// by semicolons, with those in file, one "id=key" pair per line with blank
+// lines and # comments ignored. An empty file path is skipped.
+func LoadAPIKeys(spec, file string) (APIKeys, error) {
+	keys := APIKeys{}
+	for _, pair := range strings.Split(spec, ";") {
+		if err := keys.add(pair); err != nil {
+			return nil, err
+		}
+	}
+	if file == "" {
+		return keys, nil
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open API keys file: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if strings.HasPrefix(strings.TrimSpace(text), "#") {
+			continue
+		}
+		if err := keys.add(text); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", file, line, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read API keys file: %w", err)
+	}
+	return keys, nil
+}
+
+// add records one "id=key" pair; blank pairs are ignored
+func (k APIKeys) add(pair string) error {
+	pair = strings.TrimSpace(pair)
+	if pair == "" {
+		return nil
+	}
+	id, key, ok := strings.Cut(pair, "=")
+	id, key = strings.TrimSpace(id), strings.TrimSpace(key)
+	if !ok || id == "" || key == "" {
+		return fmt.Errorf("invalid API key entry for %q: want id=key", id)
+	}
+	if _, dup := k[key]; dup {
+		return fmt.Errorf("invalid API key entry for %q: key already assigned", id)
+	}
+	k[key] = id
+	return nil
+}
+
+// lookup returns the identifier of key, comparing against every accepted key
+// in constant time
+func (k APIKeys) lookup(key string) (string, bool) {
+	var match string
+	found := 0
+	for candidate, id := range k {
+		if subtle.ConstantTimeCompare([]byte(candidate), []byte(key)) == 1 {
+			match = id
+			found = 1
+		}
+	}
+	return match, found == 1
+}
+
+// APIKeyAuth middleware requires a known X-API-Key on requests under
+// pathPrefix and answers others with 401. Paths outside the prefix, such as
+// the health probes, stay open, and so does everything when no keys are
+// configured. The key's identifier is stored in the request context and
+// added to the request log.
+func APIKeyAuth(keys APIKeys, pathPrefix string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if len(keys) == 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !strings.HasPrefix(r.URL.Path, pathPrefix) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			key := r.Header.Get(APIKeyHeader)
+			if key == "" {
+				utils.WriteErrorResponse(w, http.StatusUnauthorized, "Missing API key")
+				return
+			}
+			id, ok := keys.lookup(key)
+			if !ok {
+				utils.WriteErrorResponse(w, http.StatusUnauthorized, "Invalid API key")
+				return
+			}
+
+			setLogField(r.Context(), "api_key", id)
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), apiKeyIDKey{}, id)))
+		})
+	}
+}
+
+// APIKeyIDFromContext returns the identifier of the API key the request was
+// authenticated with, if any
+func APIKeyIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(apiKeyIDKey{}).(string)
+	return id, ok && id != ""
+}
@@ -0,0 +1,167 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Compression gzips JSON responses of at least minBytes for clients that
+// accept it. The body is held back until minBytes have been written, so
+// small responses go out as they are; a negative minBytes turns compression
+// off. Responses that already carry a Content-Encoding are left alone.
+func Compression(minBytes int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if minBytes < 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+			if r.Method == http.MethodHead || !acceptsGzip(r.Header.Get("Accept-Encoding")) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// Not deferred: after a panic, Recovery answers on w, which
+			// has not been written to unless the response was under way
+			gw := &gzipWriter{ResponseWriter: w, minBytes: minBytes, statusCode: http.StatusOK}
+			next.ServeHTTP(gw, r)
+			gw.close()
+		})
+	}
+}
+
+// acceptsGzip reports whether an Accept-Encoding header allows gzip, by
+// name or through *, with a non-zero quality
+func acceptsGzip(header string) bool {
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding != "gzip" && coding != "*" {
+			continue
+		}
+		quality := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if q, err := strconv.ParseFloat(value, 64); err == nil {
+				quality = q
+			}
+		}
+		if quality > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// gzipWriter buffers the start of a response until it knows whether to
+// compress it: once minBytes are written, or when the handler flushes or
+// returns
+type gzipWriter struct {
+	http.ResponseWriter
+	minBytes   int
+	statusCode int
+	headerSent bool // WriteHeader was called by the handler
+	buffer     []byte
+	decided    bool
+	gz         *gzip.Writer // nil when the response goes out uncompressed
+}
+
+func (gw *gzipWriter) WriteHeader(code int) {
+	if gw.decided || gw.headerSent {
+		return // superfluous, as without the middleware
+	}
+	gw.statusCode = code
+	gw.headerSent = true
+	// Bodiless responses cannot be compressed, so there is nothing to wait for
+	if code < http.StatusOK || code == http.StatusNoContent || code == http.StatusNotModified {
+		gw.decide(false)
+	}
+}
+
+func (gw *gzipWriter) Write(b []byte) (int, error) {
+	if !gw.decided {
+		gw.buffer = append(gw.buffer, b...)
+		if len(gw.buffer) < gw.minBytes {
+			return len(b), nil
+		}
+		if err := gw.decide(gw.compressible()); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+	if gw.gz != nil {
+		return gw.gz.Write(b)
+	}
+	return gw.ResponseWriter.Write(b)
+}
+
+// Flush sends what was written so far; a response still below minBytes
+// goes out uncompressed, since more of it may take a while
+func (gw *gzipWriter) Flush() {
+	if !gw.decided {
+		gw.decide(false)
+	}
+	if gw.gz != nil {
+		gw.gz.Flush()
+	}
+	http.NewResponseController(gw.ResponseWriter).Flush()
+}
+
+// Unwrap lets http.ResponseController reach the underlying connection
+func (gw *gzipWriter) Unwrap() http.ResponseWriter {
+	return gw.ResponseWriter
+}
+
+// compressible reports whether the response is JSON that nothing encoded yet
+func (gw *gzipWriter) compressible() bool {
+	header := gw.Header()
+	if header.Get("Content-Encoding") != "" {
+		return false
+	}
+	contentType := header.Get("Content-Type")
+	if contentType == "" {
+		contentType = http.DetectContentType(gw.buffer)
+	}
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// decide sends the header and the buffered body, compressed or not
+func (gw *gzipWriter) decide(compress bool) error {
+	gw.decided = true
+	if compress {
+		header := gw.Header()
+		header.Set("Content-Encoding", "gzip")
+		header.Del("Content-Length")
+		gw.gz = gzip.NewWriter(gw.ResponseWriter)
+	}
+	gw.ResponseWriter.WriteHeader(gw.statusCode)
+
+	buffered := gw.buffer
+	gw.buffer = nil
+	if len(buffered) == 0 {
+		return nil
+	}
+	if gw.gz != nil {
+		_, err := gw.gz.Write(buffered)
+		return err
+	}
+	_, err := gw.ResponseWriter.Write(buffered)
+	return err
+}
+
+// close finishes the response once the handler returned
+func (gw *gzipWriter) close() {
+	if !gw.decided {
+		if !gw.headerSent && len(gw.buffer) == 0 {
+			// Nothing was written; net/http sends its default 200
+			return
+		}
+		gw.decide(false)
+	}
+	if gw.gz != nil {
+		gw.gz.Close()
+	}
+}
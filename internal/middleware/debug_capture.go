@@ -0,0 +1,236 @@
+package middleware
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"sync"
+	"time"
+
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/internal/timing"
+)
+
+// capturedHeaderRedactions are the headers whose values a capture never
+// keeps, since they carry credentials
+var capturedHeaderRedactions = []string{"Authorization", APIKeyHeader, "Cookie", "Set-Cookie", "X-Clickhouse-Key"}
+
+// DebugCapture keeps the most recent failed requests (status 500 and above)
+// with their responses and a timing breakdown, for operators debugging
+// errors they cannot reproduce. It is switched on and off at runtime and
+// samples SampleRate of requests, so it can stay on under load; requests not
+// sampled cost nothing. Captures live in a ring buffer of fixed capacity.
+type DebugCapture struct {
+	maxBody int64
+
+	mu         sync.Mutex
+	enabled    bool
+	sampleRate float64
+	ring       []models.CapturedExchange
+	next       int // ring slot the next capture goes to
+	count      int // captures in the ring
+	lastID     uint64
+	dropped    uint64
+}
+
+// NewDebugCapture keeps up to capacity failed exchanges, each body cut at
+// maxBody bytes
+func NewDebugCapture(capacity int, maxBody int64, enabled bool, sampleRate float64) *DebugCapture {
+	return &DebugCapture{
+		maxBody:    maxBody,
+		enabled:    enabled,
+		sampleRate: sampleRate,
+		ring:       make([]models.CapturedExchange, max(capacity, 1)),
+	}
+}
+
+// State returns the setting and how full the buffer is
+func (c *DebugCapture) State() models.DebugCaptureState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.state()
+}
+
+func (c *DebugCapture) state() models.DebugCaptureState {
+	return models.DebugCaptureState{
+		Enabled:    c.enabled,
+		SampleRate: c.sampleRate,
+		Capacity:   len(c.ring),
+		Captured:   c.count,
+		Dropped:    c.dropped,
+	}
+}
+
+// Configure switches the capture on or off and sets its sample rate
+func (c *DebugCapture) Configure(req models.DebugCaptureRequest) (models.DebugCaptureState, error) {
+	if req.SampleRate != nil && (*req.SampleRate < 0 || *req.SampleRate > 1) {
+		return models.DebugCaptureState{}, models.ErrInvalidSampleRate
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if req.Enabled != nil {
+		c.enabled = *req.Enabled
+	}
+	if req.SampleRate != nil {
+		c.sampleRate = *req.SampleRate
+	}
+	return c.state(), nil
+}
+
+// List returns the captured exchanges, newest first
+func (c *DebugCapture) List() []models.CapturedExchange {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	list := make([]models.CapturedExchange, 0, c.count)
+	for i := 1; i <= c.count; i++ {
+		list = append(list, c.ring[(c.next-i+len(c.ring))%len(c.ring)])
+	}
+	return list
+}
+
+// Clear empties the buffer
+func (c *DebugCapture) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	clear(c.ring)
+	c.next, c.count, c.dropped = 0, 0, 0
+}
+
+func (c *DebugCapture) sampled() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.enabled && c.sampleRate > 0 && (c.sampleRate >= 1 || rand.Float64() < c.sampleRate)
+}
+
+func (c *DebugCapture) store(exchange models.CapturedExchange) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lastID++
+	exchange.ID = c.lastID
+	if c.count == len(c.ring) {
+		c.dropped++
+	} else {
+		c.count++
+	}
+	c.ring[c.next] = exchange
+	c.next = (c.next + 1) % len(c.ring)
+}
+
+// Middleware times sampled requests and keeps those that fail. It has to
+// sit inside Compression so bodies are captured as sent by the handler; a
+// handler panic is captured as a 500 and then passed on to Recovery.
+func (c *DebugCapture) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !c.sampled() {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		start := time.Now()
+		timings := timing.New()
+		r = r.WithContext(timing.WithTimings(r.Context(), timings))
+
+		// Read the start of the body for the capture and hand the handler
+		// all of it
+		var requestBody []byte
+		if r.Body != nil && r.Body != http.NoBody {
+			requestBody, _ = io.ReadAll(io.LimitReader(r.Body, c.maxBody+1))
+			r.Body = readCloser{io.MultiReader(bytes.NewReader(requestBody), r.Body), r.Body}
+		}
+		requestHeaders := redactHeaders(r.Header)
+
+		writer := &captureWriter{ResponseWriter: w, statusCode: http.StatusOK, maxBody: c.maxBody, timings: timings}
+		defer func() {
+			recovered := recover()
+			if recovered != nil {
+				writer.statusCode = http.StatusInternalServerError
+			}
+			if writer.statusCode >= http.StatusInternalServerError {
+				breakdown := timings.Breakdown()
+				exchange := models.CapturedExchange{
+					CapturedAt:        start.UTC(),
+					Method:            r.Method,
+					URL:               r.URL.RequestURI(),
+					RequestHeaders:    requestHeaders,
+					RequestBody:       string(truncate(requestBody, c.maxBody)),
+					RequestTruncated:  int64(len(requestBody)) > c.maxBody,
+					Status:            writer.statusCode,
+					ResponseHeaders:   redactHeaders(w.Header()),
+					ResponseBody:      writer.body.String(),
+					ResponseTruncated: writer.truncated,
+					DurationMs:        breakdown.TotalMs,
+					Timings:           breakdown.Phases,
+					Spans:             breakdown.Spans,
+				}
+				if recovered != nil {
+					exchange.Panic = fmt.Sprint(recovered)
+				}
+				c.store(exchange)
+			}
+			if recovered != nil {
+				panic(recovered)
+			}
+		}()
+
+		next.ServeHTTP(writer, r)
+	})
+}
+
+func redactHeaders(header http.Header) map[string][]string {
+	redacted := header.Clone()
+	for _, name := range capturedHeaderRedactions {
+		if redacted.Get(name) != "" {
+			redacted.Set(name, "[REDACTED]")
+		}
+	}
+	return redacted
+}
+
+func truncate(body []byte, limit int64) []byte {
+	if int64(len(body)) > limit {
+		return body[:limit]
+	}
+	return body
+}
+
+// readCloser reads from a reader built over a request body and closes the
+// body itself
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// captureWriter records the status and the start of the body, and carries
+// the request's timings so the response can be timed as it is encoded
+type captureWriter struct {
+	http.ResponseWriter
+	statusCode int
+	maxBody    int64
+	body       bytes.Buffer
+	truncated  bool
+	timings    *timing.Timings
+}
+
+func (cw *captureWriter) WriteHeader(code int) {
+	cw.statusCode = code
+	cw.ResponseWriter.WriteHeader(code)
+}
+
+func (cw *captureWriter) Write(b []byte) (int, error) {
+	room := cw.maxBody - int64(cw.body.Len())
+	if int64(len(b)) > room {
+		cw.truncated = true
+	}
+	cw.body.Write(truncate(b, max(room, 0)))
+	return cw.ResponseWriter.Write(b)
+}
+
+func (cw *captureWriter) Timings() *timing.Timings {
+	return cw.timings
+}
+
+func (cw *captureWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
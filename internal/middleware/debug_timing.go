@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"net/http"
+
+	"analytics-dashboard-api/internal/timing"
+)
+
+// DebugTimingParam asks for the timing breakdown of a request in its response
+const DebugTimingParam = "debug_timing"
+
+// DebugTiming middleware answers ?debug_timing=true with where the time of
+// the request went: JSON responses get a debug_timing object and every
+// response a Server-Timing header. Like the admin endpoints it needs an API
+// key when keys are configured; without one the parameter is ignored. It
+// sits inside the response cache, whose hits would carry no timings, and
+// timed responses are marked no-store so they are never cached.
+func DebugTiming(keys APIKeys) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get(DebugTimingParam) != "true" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if _, ok := APIKeyIDFromContext(r.Context()); len(keys) > 0 && !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// Record into the debug capture's timings when it samples the
+			// request, so both see the same spans
+			timings := timing.FromContext(r.Context())
+			if timings == nil {
+				timings = timing.New()
+				r = r.WithContext(timing.WithTimings(r.Context(), timings))
+			}
+			timings.Report()
+			next.ServeHTTP(&timingWriter{ResponseWriter: w, timings: timings}, r)
+		})
+	}
+}
+
+// timingWriter carries the timings of a request asking for its breakdown
+type timingWriter struct {
+	http.ResponseWriter
+	timings *timing.Timings
+}
+
+func (tw *timingWriter) Timings() *timing.Timings {
+	return tw.timings
+}
+
+func (tw *timingWriter) Unwrap() http.ResponseWriter {
+	return tw.ResponseWriter
+}
@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+type identityKey struct{}
+
+// IdentityHeader carries the caller identity used to key per-user state
+const IdentityHeader = "X-User-ID"
+
+// Identity middleware stores the caller identity in the request context
+func Identity(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if id := strings.TrimSpace(r.Header.Get(IdentityHeader)); id != "" {
+			r = r.WithContext(WithIdentity(r.Context(), id))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// WithIdentity returns a context carrying the caller identity
+func WithIdentity(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, identityKey{}, id)
+}
+
+// IdentityFromContext returns the caller identity, if any
+func IdentityFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(identityKey{}).(string)
+	return id, ok && id != ""
+}
@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"context"
 	"net/http"
 	"time"
 
@@ -24,6 +25,27 @@ func (rw *responseWriter) Write(b []byte) (int, error) {
 	return size, err
 }
 
+// Unwrap lets http.ResponseController reach the underlying connection
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+type logFieldsKey struct{}
+
+// logFields collects key-value pairs that inner middleware adds to the
+// request log, which is written once the response is done
+type logFields struct {
+	fields []interface{}
+}
+
+// setLogField adds a key-value pair to the log line of the request, if it
+// is being logged
+func setLogField(ctx context.Context, key string, value interface{}) {
+	if lf, ok := ctx.Value(logFieldsKey{}).(*logFields); ok {
+		lf.fields = append(lf.fields, key, value)
+	}
+}
+
 // Logging middleware for request/response logging
 func Logging(logger logger.Logger) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
@@ -35,9 +57,10 @@ func Logging(logger logger.Logger) func(http.Handler) http.Handler {
 				statusCode:     200,
 			}
 
-			next.ServeHTTP(wrapped, r)
+			extra := &logFields{}
+			next.ServeHTTP(wrapped, r.WithContext(context.WithValue(r.Context(), logFieldsKey{}, extra)))
 
-			logger.Info("HTTP Request",
+			fields := []interface{}{
 				"method", r.Method,
 				"path", r.URL.Path,
 				"status", wrapped.statusCode,
@@ -45,7 +68,8 @@ func Logging(logger logger.Logger) func(http.Handler) http.Handler {
 				"size", wrapped.size,
 				"remote_addr", r.RemoteAddr,
 				"user_agent", r.UserAgent(),
-			)
+			}
+			logger.Info("HTTP Request", append(fields, extra.fields...)...)
 		})
 	}
 }
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"analytics-dashboard-api/internal/models"
+)
+
+type PreferenceProvider interface {
+	Get(string) (*models.Preferences, bool)
+}
+
+// PreferenceDefaults middleware fills in omitted analytics query parameters
+// from the caller's stored preferences. Explicit parameters always win.
+func PreferenceDefaults(provider PreferenceProvider, pathPrefix string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet || !strings.HasPrefix(r.URL.Path, pathPrefix) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			identity, ok := IdentityFromContext(r.Context())
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			prefs, ok := provider.Get(identity)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			defaults := make(map[string]string, len(prefs.Filters)+1)
+			for key, value := range prefs.Filters {
+				defaults[key] = value
+			}
+			// Display strings use the preferred currency symbol
+			defaults["currency"] = prefs.Currency
+
+			query := r.URL.Query()
+			applied := false
+			for key, value := range defaults {
+				if value != "" && !query.Has(key) {
+					query.Set(key, value)
+					applied = true
+				}
+			}
+
+			if applied {
+				r = r.Clone(r.Context())
+				r.URL.RawQuery = query.Encode()
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"analytics-dashboard-api/internal/httpquery"
+	"analytics-dashboard-api/internal/utils"
+	"analytics-dashboard-api/pkg/logger"
+
+	"github.com/gorilla/mux"
+)
+
+// Query validation modes
+const (
+	// ValidationOff skips query validation entirely (legacy behaviour)
+	ValidationOff = "off"
+	// ValidationWarn logs invalid parameters and flags them in a Warning
+	// header, but still serves the request with defaults
+	ValidationWarn = "warn"
+	// ValidationStrict rejects requests with invalid parameters with a 400
+	ValidationStrict = "strict"
+)
+
+// ParamType is the expected type of a query parameter value
+type ParamType int
+
+const (
+	ParamString ParamType = iota
+	ParamInt
+	ParamFloat
+	ParamDate
+	ParamEnum
+	// ParamList is a comma-separated list that may be repeated, of at most
+	// httpquery.MaxFilterValues distinct values
+	ParamList
+)
+
+// ParamSpec describes a query parameter accepted by a route
+type ParamSpec struct {
+	Name string
+	Type ParamType
+	// Min and Max bound numeric values (inclusive) when Max > Min
+	Min float64
+	Max float64
+	// Values lists the accepted values of a ParamEnum
+	Values []string
+}
+
+// check returns why value is not acceptable, or "" when it is
+func (p ParamSpec) check(value string) string {
+	switch p.Type {
+	case ParamInt:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return "must be an integer"
+		}
+		return p.checkRange(float64(n))
+	case ParamFloat:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return "must be a number"
+		}
+		return p.checkRange(f)
+	case ParamDate:
+		if _, err := time.Parse(httpquery.DateLayout, value); err != nil {
+			return "must be a date in YYYY-MM-DD format"
+		}
+	case ParamEnum:
+		for _, allowed := range p.Values {
+			if value == allowed {
+				return ""
+			}
+		}
+		return "must be one of: " + strings.Join(p.Values, ", ")
+	}
+	return ""
+}
+
+func (p ParamSpec) checkRange(v float64) string {
+	if p.Max > p.Min && (v < p.Min || v > p.Max) {
+		return fmt.Sprintf("must be between %s and %s",
+			strconv.FormatFloat(p.Min, 'f', -1, 64), strconv.FormatFloat(p.Max, 'f', -1, 64))
+	}
+	return ""
+}
+
+// ValidateQuery checks query against the accepted parameters and returns every
+// unknown, repeated or malformed parameter, sorted by name
+func ValidateQuery(query url.Values, specs []ParamSpec) []utils.InvalidParam {
+	known := make(map[string]ParamSpec, len(specs))
+	for _, spec := range specs {
+		known[spec.Name] = spec
+	}
+
+	var invalid []utils.InvalidParam
+	for name, values := range query {
+		spec, ok := known[name]
+		if !ok {
+			invalid = append(invalid, utils.InvalidParam{Name: name, Value: values[0], Reason: "unknown parameter"})
+			continue
+		}
+		if spec.Type == ParamList {
+			if _, err := httpquery.ParseValues(query, name); err != nil {
+				var listErr *httpquery.Error
+				errors.As(err, &listErr)
+				invalid = append(invalid, utils.InvalidParam{Name: name, Value: listErr.Value, Reason: listErr.Reason})
+			}
+			continue
+		}
+		if len(values) > 1 {
+			invalid = append(invalid, utils.InvalidParam{Name: name, Value: values[0], Reason: "must not be repeated"})
+			continue
+		}
+		if values[0] == "" {
+			continue
+		}
+		if reason := spec.check(values[0]); reason != "" {
+			invalid = append(invalid, utils.InvalidParam{Name: name, Value: values[0], Reason: reason})
+		}
+	}
+
+	sort.Slice(invalid, func(i, j int) bool { return invalid[i].Name < invalid[j].Name })
+	return invalid
+}
+
+// QueryValidation middleware validates query parameters against the specs
+// registered for the matched route, keyed by "METHOD /path/template". Routes
+// without a spec are not checked.
+func QueryValidation(specs map[string][]ParamSpec, mode string, logger logger.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if mode == ValidationOff || r.URL.RawQuery == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			route := mux.CurrentRoute(r)
+			if route == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			template, err := route.GetPathTemplate()
+			if err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			routeSpecs, ok := specs[r.Method+" "+template]
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			invalid := ValidateQuery(r.URL.Query(), routeSpecs)
+			if len(invalid) > 0 {
+				if mode == ValidationStrict {
+					utils.WriteValidationErrorResponse(w, invalid)
+					return
+				}
+
+				names := make([]string, len(invalid))
+				for i, param := range invalid {
+					names[i] = param.Name
+				}
+				logger.Warn("Invalid query parameters ignored",
+					"path", r.URL.Path,
+					"params", strings.Join(names, ","),
+				)
+				w.Header().Set("Warning", fmt.Sprintf(`199 - "invalid query parameters ignored: %s"`, strings.Join(names, ", ")))
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package middleware
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"analytics-dashboard-api/internal/utils"
+)
+
+// bucket is the token bucket of one client: tokens refill at the limiter's
+// rate up to its burst, and each request takes one
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// rateLimiter keeps a bucket per client. Buckets that have refilled are
+// dropped now and then, since a full bucket is the same as none.
+type rateLimiter struct {
+	rate  float64 // tokens per second
+	burst float64
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// take spends a token of client's bucket at now. When the bucket is empty it
+// returns false and how long until a token is available.
+func (l *rateLimiter) take(client string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastSweep) >= l.refillTime() {
+		l.sweep(now)
+	}
+
+	b, ok := l.buckets[client]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[client] = b
+	}
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(l.burst, b.tokens+elapsed*l.rate)
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	}
+	b.tokens--
+	return true, 0
+}
+
+// refillTime is how long an empty bucket takes to fill up
+func (l *rateLimiter) refillTime() time.Duration {
+	return time.Duration(l.burst / l.rate * float64(time.Second))
+}
+
+// sweep drops the buckets that have refilled by now
+func (l *rateLimiter) sweep(now time.Time) {
+	full := l.refillTime()
+	for client, b := range l.buckets {
+		if now.Sub(b.last) >= full {
+			delete(l.buckets, client)
+		}
+	}
+	l.lastSweep = now
+}
+
+// RateLimit middleware limits each client to rps requests per second on
+// average, with bursts of up to burst requests, on paths under pathPrefix.
+// Clients are told apart by their API key when they authenticated with one
+// and by their IP address otherwise, so it must run after APIKeyAuth.
+// Requests over the limit are answered with 429 and a Retry-After header.
+// Paths outside the prefix, such as the health probes, are not limited, and
+// a non-positive rps turns limiting off; burst is at least one.
+func RateLimit(rps float64, burst int, pathPrefix string) func(http.Handler) http.Handler {
+	// One limiter for every handler wrapped: mux wraps the matched route's
+	// handler anew on each request
+	limiter := &rateLimiter{
+		rate:    rps,
+		burst:   float64(max(burst, 1)),
+		buckets: make(map[string]*bucket),
+	}
+	return func(next http.Handler) http.Handler {
+		if rps <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !strings.HasPrefix(r.URL.Path, pathPrefix) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			allowed, wait := limiter.take(rateLimitClient(r), time.Now())
+			if !allowed {
+				seconds := max(int(math.Ceil(wait.Seconds())), 1)
+				w.Header().Set("Retry-After", strconv.Itoa(seconds))
+				setLogField(r.Context(), "rate_limited", true)
+				utils.WriteErrorResponse(w, http.StatusTooManyRequests, "Rate limit exceeded, retry later")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// rateLimitClient names the bucket a request draws from: its API key's
+// identifier, or the address it came from. Forwarding headers are not
+// trusted, since any client can set them.
+func rateLimitClient(r *http.Request) string {
+	if id, ok := APIKeyIDFromContext(r.Context()); ok {
+		return "key:" + id
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
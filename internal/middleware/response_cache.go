@@ -0,0 +1,220 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"analytics-dashboard-api/internal/models"
+
+	"github.com/gorilla/mux"
+)
+
+// ResponseCache keeps successful GET responses in memory for a TTL chosen
+// per route. Entries are keyed by the data version as well as the request,
+// so a load, restore or rollback is never answered from an older dataset.
+// Any other successful request except HEAD and OPTIONS (POST, PUT, PATCH,
+// DELETE) clears the cache, since targets, metrics and similar stores feed the
+// cached responses without changing the data version.
+type ResponseCache struct {
+	ttls       map[string]time.Duration // "METHOD /path/template" to TTL
+	maxEntries int
+	version    func() uint64
+
+	mu         sync.Mutex
+	entries    map[string]*cachedResponse
+	generation uint64 // bumped by Purge so responses begun earlier are not stored
+	hits       uint64
+	misses     uint64
+}
+
+type cachedResponse struct {
+	contentType string
+	link        string // pagination Link header
+	body        []byte
+	storedAt    time.Time
+	expiresAt   time.Time
+}
+
+// NewResponseCache caches the routes with a positive TTL in ttls, keeping
+// at most maxEntries responses; zero maxEntries disables caching
+func NewResponseCache(ttls map[string]time.Duration, maxEntries int, version func() uint64) *ResponseCache {
+	return &ResponseCache{
+		ttls:       ttls,
+		maxEntries: maxEntries,
+		version:    version,
+		entries:    make(map[string]*cachedResponse),
+	}
+}
+
+// Stats returns the number of cached responses and lookups so far
+func (c *ResponseCache) Stats() models.ResponseCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return models.ResponseCacheStats{Entries: len(c.entries), Hits: c.hits, Misses: c.misses}
+}
+
+// Purge discards every cached response
+func (c *ResponseCache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]*cachedResponse)
+	c.generation++
+}
+
+// Middleware serves cached responses with X-Cache: HIT and an Age header,
+// and stores fresh 200 responses unless the handler marked them no-store
+func (c *ResponseCache) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if c.maxEntries <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, r)
+			return
+		default:
+			c.purgeAfter(next, w, r)
+			return
+		}
+
+		ttl := c.ttl(r)
+		if ttl <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		version := c.version()
+		key := c.key(r, version)
+		now := time.Now()
+		c.mu.Lock()
+		entry, ok := c.entries[key]
+		if ok && now.Before(entry.expiresAt) {
+			c.hits++
+			c.mu.Unlock()
+			w.Header().Set("Content-Type", entry.contentType)
+			if entry.link != "" {
+				w.Header().Set("Link", entry.link)
+			}
+			w.Header().Set("X-Cache", "HIT")
+			w.Header().Set("Age", strconv.Itoa(int(now.Sub(entry.storedAt).Seconds())))
+			w.WriteHeader(http.StatusOK)
+			w.Write(entry.body)
+			return
+		}
+		c.misses++
+		generation := c.generation
+		c.mu.Unlock()
+
+		w.Header().Set("X-Cache", "MISS")
+		recorder := &recordingWriter{ResponseWriter: w, statusCode: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+
+		if recorder.statusCode != http.StatusOK || strings.Contains(w.Header().Get("Cache-Control"), "no-store") {
+			return
+		}
+		if c.version() != version {
+			// Data was (re)loaded during the request, e.g. the lazy first
+			// load, so the response may not match the version in the key
+			return
+		}
+		c.store(key, generation, &cachedResponse{
+			contentType: w.Header().Get("Content-Type"),
+			link:        w.Header().Get("Link"),
+			body:        recorder.body.Bytes(),
+			storedAt:    now,
+			expiresAt:   now.Add(ttl),
+		})
+	})
+}
+
+// purgeAfter serves a state-changing request and clears the cache if it succeeded
+func (c *ResponseCache) purgeAfter(next http.Handler, w http.ResponseWriter, r *http.Request) {
+	wrapped := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+	next.ServeHTTP(wrapped, r)
+	if wrapped.statusCode < http.StatusBadRequest {
+		c.Purge()
+	}
+}
+
+func (c *ResponseCache) ttl(r *http.Request) time.Duration {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return 0
+	}
+	template, err := route.GetPathTemplate()
+	if err != nil {
+		return 0
+	}
+	return c.ttls[r.Method+" "+template]
+}
+
+// key identifies a response by data version, path and query. Parameters
+// are sorted so their order does not matter. ?locale=auto depends on
+// Accept-Language, so that header is part of the key too.
+func (c *ResponseCache) key(r *http.Request, version uint64) string {
+	query := r.URL.Query()
+	key := strconv.FormatUint(version, 10) + " " + r.URL.Path + "?" + query.Encode()
+	if query.Get("locale") == "auto" {
+		key += " " + r.Header.Get("Accept-Language")
+	}
+	return key
+}
+
+// store adds an entry unless the cache was purged since generation,
+// evicting expired entries first and then the entry closest to expiry when
+// the cache is full
+func (c *ResponseCache) store(key string, generation uint64, entry *cachedResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+
+	if _, exists := c.entries[key]; !exists && len(c.entries) >= c.maxEntries {
+		var oldest string
+		for k, e := range c.entries {
+			if !entry.storedAt.Before(e.expiresAt) {
+				delete(c.entries, k)
+				continue
+			}
+			if oldest == "" || e.expiresAt.Before(c.entries[oldest].expiresAt) {
+				oldest = k
+			}
+		}
+		if len(c.entries) >= c.maxEntries && oldest != "" {
+			delete(c.entries, oldest)
+		}
+	}
+	c.entries[key] = entry
+}
+
+// recordingWriter copies the response body while writing it through
+type recordingWriter struct {
+	http.ResponseWriter
+	statusCode int
+	body       bytes.Buffer
+}
+
+func (rw *recordingWriter) WriteHeader(code int) {
+	rw.statusCode = code
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+func (rw *recordingWriter) Write(b []byte) (int, error) {
+	if rw.statusCode == http.StatusOK {
+		rw.body.Write(b)
+	}
+	return rw.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying connection
+func (rw *recordingWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"analytics-dashboard-api/pkg/logger"
+
+	"github.com/gorilla/mux"
+)
+
+// timeoutWriteGrace is added to the write deadline so a handler whose
+// context expired still has time to answer 504
+const timeoutWriteGrace = 5 * time.Second
+
+// ResponseTimeout middleware bounds each matched route by the timeout
+// resolve gives for its method and path template. The request context
+// expires after the timeout and the write deadline shortly after, replacing
+// the server's write timeout; a zero timeout lifts both. Routes resolve
+// does not know keep the server's timeouts.
+func ResponseTimeout(resolve func(method, template string) (time.Duration, bool), logger logger.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route := mux.CurrentRoute(r)
+			if route == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			template, err := route.GetPathTemplate()
+			if err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			timeout, ok := resolve(r.Method, template)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			deadline := time.Time{}
+			if timeout > 0 {
+				ctx, cancel := context.WithTimeout(r.Context(), timeout)
+				defer cancel()
+				r = r.WithContext(ctx)
+				deadline = time.Now().Add(timeout + timeoutWriteGrace)
+			}
+			if err := http.NewResponseController(w).SetWriteDeadline(deadline); err != nil {
+				logger.Debug("Could not set write deadline", "path", r.URL.Path, "error", err)
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package models
+
+var (
+	ErrInvalidABCCutoffs = newKindError(ErrInvalidParam, "invalid ABC cut-offs")
+)
+
+// ABC classes, from the products making up most of the revenue to the long tail
+const (
+	ABCClassA = "A"
+	ABCClassB = "B"
+	ABCClassC = "C"
+)
+
+// ABCCutoffs are the cumulative revenue shares (0-1] closing classes A and B
+type ABCCutoffs struct {
+	A float64 `json:"a"`
+	B float64 `json:"b"`
+}
+
+// ABCProduct is a product's revenue, its place in the cumulative revenue
+// share and the class that place puts it in
+type ABCProduct struct {
+	Product       string  `json:"product"`
+	Revenue       float64 `json:"revenue"`
+	SharePct      float64 `json:"share_pct"`
+	CumulativePct float64 `json:"cumulative_pct"` // share of the product and every product above it
+	Class         string  `json:"class"`
+}
+
+// ABCClassSummary totals one class
+type ABCClassSummary struct {
+	Class           string  `json:"class"`
+	Products        int     `json:"products"`
+	Revenue         float64 `json:"revenue"`
+	RevenueSharePct float64 `json:"revenue_share_pct"`
+	ProductSharePct float64 `json:"product_share_pct"`
+}
+
+// ABCClassification ranks products by revenue, largest first, and splits
+// them into classes by cumulative revenue share
+type ABCClassification struct {
+	Cutoffs      ABCCutoffs        `json:"cutoffs"`
+	TotalRevenue float64           `json:"total_revenue"`
+	Classes      []ABCClassSummary `json:"classes"`
+	Products     []ABCProduct      `json:"products"`
+}
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+)
+
+var (
+	ErrAlertRuleNotFound = newKindError(ErrNotFound, "alert rule not found")
+	ErrInvalidAlertRule  = newKindError(ErrValidation, "invalid alert rule")
+)
+
+// AlertRule fires a notification when a metric crosses a threshold
+type AlertRule struct {
+	ID         string    `json:"id"`
+	Name       string    `json:"name"`
+	Metric     string    `json:"metric"`   // e.g. daily_revenue, refresh_failures
+	Operator   string    `json:"operator"` // <, <=, >, >=, ==, !=
+	Threshold  float64   `json:"threshold"`
+	Channel    string    `json:"channel"` // webhook or slack
+	WebhookURL string    `json:"webhook_url"`
+	Enabled    bool      `json:"enabled"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Alert records a rule firing
+type Alert struct {
+	RuleID        string    `json:"rule_id"`
+	RuleName      string    `json:"rule_name"`
+	Metric        string    `json:"metric"`
+	Operator      string    `json:"operator"`
+	Threshold     float64   `json:"threshold"`
+	Value         float64   `json:"value"`
+	Trigger       string    `json:"trigger"` // refresh, schedule or manual
+	FiredAt       time.Time `json:"fired_at"`
+	Delivered     bool      `json:"delivered"`
+	DeliveryError string    `json:"delivery_error,omitempty"`
+}
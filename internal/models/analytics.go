@@ -1,42 +1,128 @@
 package models
 
 import (
-	"errors"
 	"time"
 )
 
 var (
-	ErrInvalidCSVRow = errors.New("invalid CSV row format")
+	ErrInvalidCSVRow   = newKindError(ErrValidation, "invalid CSV row format")
+	ErrCountryNotFound = newKindError(ErrNotFound, "country not found")
 )
 
 // CountryRevenue represents revenue data by country and product
 type CountryRevenue struct {
-	Country          string  `json:"country"`
-	ProductName      string  `json:"product_name"`
-	TotalRevenue     float64 `json:"total_revenue"`
-	TransactionCount int     `json:"transaction_count"`
+	Country          string            `json:"country"`
+	ProductName      string            `json:"product_name"`
+	TotalRevenue     Money             `json:"total_revenue"`
+	TransactionCount int               `json:"transaction_count"`
+	Display          map[string]string `json:"display,omitempty"`
 }
 
+// Totals are the revenue, transactions and units sold over every row of a
+// table, whichever page of it a response holds
+type Totals struct {
+	Revenue      Money             `json:"revenue"`
+	Transactions int               `json:"transactions"`
+	Quantity     int               `json:"quantity"`
+	Display      map[string]string `json:"display,omitempty"`
+}
+
+// Measures the top products and top regions can be ranked by, largest
+// first. Regions have no stock, so they take only the first two.
+const (
+	RankByPurchaseCount = "purchase_count"
+	RankByRevenue       = "revenue"
+	RankByStock         = "stock"
+)
+
 // ProductFrequency represents frequently purchased products
 type ProductFrequency struct {
 	ProductID     string `json:"product_id"`
 	ProductName   string `json:"product_name"`
 	PurchaseCount int    `json:"purchase_count"`
+	TotalRevenue  Money  `json:"total_revenue"`
 	StockQuantity int    `json:"current_stock"`
+	Brand         string `json:"brand,omitempty"`    // from the products dimension
+	Supplier      string `json:"supplier,omitempty"` // from the products dimension
+	Other         bool   `json:"other,omitempty"`    // rolls up the products past the top N
+
+	Display map[string]string `json:"display,omitempty"`
 }
 
 // MonthlySales represents sales volume by month
 type MonthlySales struct {
-	Month       string  `json:"month"`
-	SalesVolume float64 `json:"sales_volume"`
-	ItemCount   int     `json:"item_count"`
+	Month           string            `json:"month"`
+	SalesVolume     Money             `json:"sales_volume"`
+	ItemCount       int               `json:"item_count"`
+	UniqueCustomers int               `json:"unique_customers"` // approximate (HyperLogLog)
+	UniqueProducts  int               `json:"unique_products"`  // approximate (HyperLogLog)
+	Display         map[string]string `json:"display,omitempty"`
 }
 
 // RegionRevenue represents revenue data by region
 type RegionRevenue struct {
-	Region       string  `json:"region"`
-	TotalRevenue float64 `json:"total_revenue"`
-	ItemsSold    int     `json:"items_sold"`
+	Region       string            `json:"region"`
+	TotalRevenue Money             `json:"total_revenue"`
+	ItemsSold    int               `json:"items_sold"`
+	Other        bool              `json:"other,omitempty"` // rolls up the regions past the top N
+	Display      map[string]string `json:"display,omitempty"`
+}
+
+// CountryDetail is one country's revenue by month with its best selling
+// products and regions by revenue
+type CountryDetail struct {
+	Country         string             `json:"country"`
+	RevenueOverTime []MonthlySales     `json:"revenue_over_time"`
+	TopProducts     []ProductFrequency `json:"top_products"`
+	TopRegions      []RegionRevenue    `json:"top_regions"`
+}
+
+// SegmentRevenue represents revenue and retention for a customer segment
+type SegmentRevenue struct {
+	Segment          string            `json:"segment"`
+	TotalRevenue     Money             `json:"total_revenue"`
+	TransactionCount int               `json:"transaction_count"`
+	CustomerCount    int               `json:"customer_count"`
+	AvgOrderValue    Money             `json:"avg_order_value"`
+	RetentionRate    float64           `json:"retention_rate"` // share of customers active in 2+ months
+	Display          map[string]string `json:"display,omitempty"`
+}
+
+// CustomerSpend is what one customer spent, for the top customers ranking
+type CustomerSpend struct {
+	UserID        string            `json:"user_id"`
+	TotalSpend    Money             `json:"total_spend"`
+	OrderCount    int               `json:"order_count"`
+	AvgOrderValue Money             `json:"avg_order_value"`
+	Display       map[string]string `json:"display,omitempty"`
+}
+
+// RepeatPurchase counts the customers who bought more than once
+type RepeatPurchase struct {
+	Customers       int     `json:"customers"`
+	RepeatCustomers int     `json:"repeat_customers"`
+	Rate            float64 `json:"repeat_purchase_rate"` // share of customers with 2+ orders
+}
+
+// DistinctCounts holds approximate distinct counts across the whole dataset
+type DistinctCounts struct {
+	UniqueCustomers int `json:"unique_customers"`
+	UniqueProducts  int `json:"unique_products"`
+}
+
+// AnalyticsStats holds dataset-wide counts, computed once per data version
+type AnalyticsStats struct {
+	DataVersion         uint64
+	ComputedAt          time.Time
+	ProcessingTime      time.Duration
+	TotalRecords        int
+	CountryRevenueCount int
+	TopProductsCount    int
+	MonthlySalesCount   int
+	TopRegionsCount     int
+	RepeatPurchase      RepeatPurchase
+	Distinct            DistinctCounts
+	Totals              map[string]float64 // base metrics over the whole dataset
 }
 
 // AnalyticsResponse wraps all dashboard data
@@ -48,6 +134,16 @@ type AnalyticsResponse struct {
 	ProcessingTimeMs int64              `json:"processing_time_ms"`
 	TotalRecords     int                `json:"total_records"`
 	CacheHit         bool               `json:"cache_hit"`
+	Errors           []SectionError     `json:"errors,omitempty"`
+}
+
+// SectionError reports a dashboard section that could not be computed while
+// the rest of the response was
+type SectionError struct {
+	Section   string `json:"section"`
+	Status    int    `json:"status"`
+	ErrorCode string `json:"error_code"`
+	Message   string `json:"message"`
 }
 
 // ProcessingStats holds statistics about data processing
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+)
+
+var (
+	ErrAnnotationNotFound = newKindError(ErrNotFound, "annotation not found")
+	ErrInvalidAnnotation  = newKindError(ErrValidation, "invalid annotation")
+)
+
+// Annotation marks a dated business event (price change, campaign, outage)
+// that charts overlay as context
+type Annotation struct {
+	ID          string    `json:"id"`
+	Date        string    `json:"date"`               // YYYY-MM-DD
+	EndDate     string    `json:"end_date,omitempty"` // YYYY-MM-DD, for events spanning a period
+	Title       string    `json:"title"`
+	Description string    `json:"description,omitempty"`
+	Category    string    `json:"category,omitempty"` // e.g. price_change, campaign
+	CreatedAt   time.Time `json:"created_at"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+)
+
+// Audit outcomes
+const (
+	AuditAccepted = "accepted"
+	AuditRejected = "rejected"
+	AuditError    = "error"
+)
+
+// AuditEvent records a security-relevant decision, such as the verdict of an
+// upload scan
+type AuditEvent struct {
+	Time    time.Time              `json:"time"`
+	Action  string                 `json:"action"`          // e.g. upload_scan
+	Subject string                 `json:"subject"`         // what the action applied to
+	Actor   string                 `json:"actor,omitempty"` // X-User-ID of the caller
+	Outcome string                 `json:"outcome"`         // accepted, rejected or error
+	Reason  string                 `json:"reason,omitempty"`
+	Details map[string]interface{} `json:"details,omitempty"`
+}
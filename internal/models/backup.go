@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+)
+
+var (
+	ErrBackupNotFound = newKindError(ErrNotFound, "backup not found")
+)
+
+// BackupInfo describes a Parquet snapshot of the loaded tables
+type BackupInfo struct {
+	Name      string    `json:"name"`
+	Path      string    `json:"path"`
+	Records   int       `json:"records"`
+	SizeBytes int64     `json:"size_bytes"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// StoredBackup is a backup in the backup directory and why the retention
+// policy keeps it. A backup kept by no rule is removed by the next prune.
+type StoredBackup struct {
+	Name      string    `json:"name"`
+	SizeBytes int64     `json:"size_bytes"`
+	CreatedAt time.Time `json:"created_at"`
+	KeptBy    []string  `json:"kept_by"`
+	Prune     bool      `json:"prune"`
+}
+
+// BackupRetentionPolicy is the retention configuration; zero for both counts
+// keeps every backup
+type BackupRetentionPolicy struct {
+	KeepLast    int    `json:"keep_last"`
+	KeepMonthly int    `json:"keep_monthly"`
+	Interval    string `json:"prune_interval"`
+}
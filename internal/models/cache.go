@@ -0,0 +1,8 @@
+package models
+
+// ResponseCacheStats counts response cache entries and lookups
+type ResponseCacheStats struct {
+	Entries int    `json:"entries"`
+	Hits    uint64 `json:"hits"`
+	Misses  uint64 `json:"misses"`
+}
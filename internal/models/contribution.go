@@ -0,0 +1,60 @@
+package models
+
+import "time"
+
+var (
+	ErrInvalidContribution = newKindError(ErrInvalidParam, "invalid contribution request")
+)
+
+// ContributionRequest asks which groups drove the change of an additive
+// metric between a period and the equally long period just before it
+type ContributionRequest struct {
+	Metric     string
+	From       time.Time // first day of the period
+	To         time.Time // last day of the period, inclusive
+	Dimensions []string  // groupings to break the change down by
+	Limit      int       // groups listed per dimension; the rest are summed as other
+	Filters    QueryOptions
+}
+
+// ContributionPeriod is an inclusive range of YYYY-MM-DD dates
+type ContributionPeriod struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Contribution is one group's share of the change. SharePct is the part of
+// the total change it accounts for, nil when the total did not change.
+type Contribution struct {
+	Group    string   `json:"group"`
+	Previous float64  `json:"previous"`
+	Current  float64  `json:"current"`
+	Change   float64  `json:"change"`
+	SharePct *float64 `json:"share_pct"`
+}
+
+// ContributionTotal is the change of the metric over all groups
+type ContributionTotal struct {
+	Previous  float64  `json:"previous"`
+	Current   float64  `json:"current"`
+	Change    float64  `json:"change"`
+	ChangePct *float64 `json:"change_pct"` // nil when the previous period is zero
+}
+
+// ContributionBreakdown lists the groups of one dimension by the size of
+// their change, largest first
+type ContributionBreakdown struct {
+	Dimension     string         `json:"dimension"`
+	Contributions []Contribution `json:"contributions"`
+	Other         *Contribution  `json:"other,omitempty"` // the groups past the limit, summed
+	Groups        int            `json:"groups"`          // groups with a value in either period
+}
+
+// ContributionAnalysis decomposes a period-over-period change
+type ContributionAnalysis struct {
+	Metric         string                  `json:"metric"`
+	Period         ContributionPeriod      `json:"period"`
+	PreviousPeriod ContributionPeriod      `json:"previous_period"`
+	Total          ContributionTotal       `json:"total"`
+	Breakdowns     []ContributionBreakdown `json:"breakdowns"`
+}
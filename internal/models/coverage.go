@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// DataCoverage describes the period covered by the loaded transactions and
+// when they were loaded, so consumers can detect stale or partial loads
+type DataCoverage struct {
+	From     string    `json:"from"` // earliest transaction_date, YYYY-MM-DD
+	To       string    `json:"to"`   // latest transaction_date, YYYY-MM-DD
+	Records  int       `json:"records"`
+	LoadedAt time.Time `json:"loaded_at"`
+	// Outliers is nil when outlier detection is off
+	Outliers *OutlierReport `json:"outliers,omitempty"`
+	// Excluded counts the rows left out because their transaction is flagged
+	Excluded int `json:"excluded,omitempty"`
+	// TestOrders counts the rows left out by the test-order rules
+	TestOrders int `json:"test_orders,omitempty"`
+}
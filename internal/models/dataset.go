@@ -0,0 +1,22 @@
+package models
+
+// DefaultDataset is the ID of the dataset read from DATA_FILE_PATH
+const DefaultDataset = "default"
+
+var ErrDatasetNotFound = newKindError(ErrNotFound, "dataset not found")
+
+// DatasetSpec names a transactions source served next to the default one
+type DatasetSpec struct {
+	ID   string
+	Path string
+}
+
+// DatasetInfo describes one of the datasets an instance serves. Coverage is
+// only set once the dataset was loaded.
+type DatasetInfo struct {
+	ID          string        `json:"id"`
+	Source      string        `json:"source"`
+	Loaded      bool          `json:"loaded"`
+	DataVersion uint64        `json:"data_version"`
+	Coverage    *DataCoverage `json:"coverage,omitempty"`
+}
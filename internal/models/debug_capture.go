@@ -0,0 +1,65 @@
+package models
+
+import "time"
+
+var ErrInvalidSampleRate = newKindError(ErrValidation, "sample_rate must be between 0 and 1")
+
+// TimingSpan is one timed step of a request, such as a single query
+type TimingSpan struct {
+	Phase      string  `json:"phase"`
+	Name       string  `json:"name"`
+	DurationMs float64 `json:"duration_ms"`
+}
+
+// TimingBreakdown is where the time of a request went. Phases sums the spans
+// by phase: queue, db and serialize, with the remainder of the total under
+// other, and section when the response is built from sections. Concurrent
+// spans each count in full.
+type TimingBreakdown struct {
+	TotalMs float64            `json:"total_ms"`
+	Phases  map[string]float64 `json:"phases"`
+	Spans   []TimingSpan       `json:"spans"`
+}
+
+// CapturedExchange is a failed request and the response it got, as kept by
+// the debug capture. Bodies are cut at the configured size and credential
+// headers are redacted.
+type CapturedExchange struct {
+	ID         uint64    `json:"id"`
+	CapturedAt time.Time `json:"captured_at"`
+	Method     string    `json:"method"`
+	URL        string    `json:"url"`
+
+	RequestHeaders   map[string][]string `json:"request_headers"`
+	RequestBody      string              `json:"request_body,omitempty"`
+	RequestTruncated bool                `json:"request_truncated,omitempty"`
+
+	Status            int                 `json:"status"`
+	ResponseHeaders   map[string][]string `json:"response_headers"`
+	ResponseBody      string              `json:"response_body,omitempty"`
+	ResponseTruncated bool                `json:"response_truncated,omitempty"`
+	// Panic holds the value a handler panicked with, if it did
+	Panic string `json:"panic,omitempty"`
+
+	DurationMs float64 `json:"duration_ms"`
+	// Timings sums the spans by phase: queue, db and serialize, with the
+	// remainder of the duration under other
+	Timings map[string]float64 `json:"timings"`
+	Spans   []TimingSpan       `json:"spans"`
+}
+
+// DebugCaptureState is the debug capture's setting and buffer usage
+type DebugCaptureState struct {
+	Enabled    bool    `json:"enabled"`
+	SampleRate float64 `json:"sample_rate"`
+	Capacity   int     `json:"capacity"`
+	Captured   int     `json:"captured"` // exchanges in the buffer
+	Dropped    uint64  `json:"dropped"`  // exchanges overwritten by newer ones
+}
+
+// DebugCaptureRequest changes the debug capture; omitted fields keep their
+// current value
+type DebugCaptureRequest struct {
+	Enabled    *bool    `json:"enabled,omitempty"`
+	SampleRate *float64 `json:"sample_rate,omitempty"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// Diagnostic check outcomes; a report takes the worst outcome of its checks
+const (
+	DiagnosticPass = "pass"
+	DiagnosticWarn = "warn"
+	DiagnosticFail = "fail"
+	DiagnosticSkip = "skip" // the check does not apply, e.g. to this backend
+)
+
+// DiagnosticCheck is the outcome of one self-diagnostics check, with what
+// it found in Detail
+type DiagnosticCheck struct {
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	Detail     string `json:"detail"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+// DiagnosticsReport describes the environment the server runs in, to be
+// attached to support tickets
+type DiagnosticsReport struct {
+	Status     string            `json:"status"`
+	CheckedAt  time.Time         `json:"checked_at"`
+	DurationMs int64             `json:"duration_ms"`
+	Backend    string            `json:"backend"`
+	GoVersion  string            `json:"go_version"`
+	Platform   string            `json:"platform"`
+	Uptime     string            `json:"uptime"`
+	Checks     []DiagnosticCheck `json:"checks"`
+}
@@ -0,0 +1,65 @@
+package models
+
+// MoneyFormatter renders money as a locale-specific display string
+type MoneyFormatter interface {
+	Money(Money) string
+}
+
+// ApplyDisplay fills Display with formatted copies of the money fields,
+// keyed by their JSON names. Raw values are left untouched.
+func (c *CountryRevenue) ApplyDisplay(f MoneyFormatter) {
+	c.Display = map[string]string{"total_revenue": f.Money(c.TotalRevenue)}
+}
+
+func (t *Totals) ApplyDisplay(f MoneyFormatter) {
+	t.Display = map[string]string{"revenue": f.Money(t.Revenue)}
+}
+
+func (m *MonthlySales) ApplyDisplay(f MoneyFormatter) {
+	m.Display = map[string]string{"sales_volume": f.Money(m.SalesVolume)}
+}
+
+func (p *ProductFrequency) ApplyDisplay(f MoneyFormatter) {
+	p.Display = map[string]string{"total_revenue": f.Money(p.TotalRevenue)}
+}
+
+func (r *RegionRevenue) ApplyDisplay(f MoneyFormatter) {
+	r.Display = map[string]string{"total_revenue": f.Money(r.TotalRevenue)}
+}
+
+func (s *SegmentRevenue) ApplyDisplay(f MoneyFormatter) {
+	s.Display = map[string]string{
+		"total_revenue":   f.Money(s.TotalRevenue),
+		"avg_order_value": f.Money(s.AvgOrderValue),
+	}
+}
+
+func (c *CustomerSpend) ApplyDisplay(f MoneyFormatter) {
+	c.Display = map[string]string{
+		"total_spend":     f.Money(c.TotalSpend),
+		"avg_order_value": f.Money(c.AvgOrderValue),
+	}
+}
+
+func (t *TargetVariance) ApplyDisplay(f MoneyFormatter) {
+	t.Display = map[string]string{
+		"target":   f.Money(t.Target),
+		"actual":   f.Money(t.Actual),
+		"variance": f.Money(t.Variance),
+	}
+}
+
+func (p *ProductSales) ApplyDisplay(f MoneyFormatter) {
+	p.Display = map[string]string{"total_revenue": f.Money(p.TotalRevenue)}
+}
+
+func (p *PricePoint) ApplyDisplay(f MoneyFormatter) {
+	p.Display = map[string]string{
+		"avg_price": f.Money(p.AvgPrice),
+		"revenue":   f.Money(p.Revenue),
+	}
+}
+
+func (c *HeatmapCell) ApplyDisplay(f MoneyFormatter) {
+	c.Display = map[string]string{"revenue": f.Money(c.Revenue)}
+}
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Error taxonomy shared by every service. Specific sentinels (ErrProductNotFound,
+// ErrInvalidTargets, ...) match one of these kinds with errors.Is, so handlers
+// can pick an HTTP status from the kind alone.
+var (
+	// ErrValidation marks input the caller has to correct
+	ErrValidation = errors.New("validation failed")
+	// ErrInvalidParam marks a query parameter the caller has to correct. It
+	// is a kind of ErrValidation.
+	ErrInvalidParam = newKindError(ErrValidation, "invalid query parameter")
+	// ErrNotFound marks a missing resource addressed by the caller
+	ErrNotFound = errors.New("not found")
+	// ErrConflict marks a request that clashes with the current state
+	ErrConflict = errors.New("conflict")
+	// ErrSourceMissing marks a data source file that does not exist
+	ErrSourceMissing = errors.New("source file not found")
+	// ErrDataLoading marks queries made while the initial load is still running
+	ErrDataLoading = errors.New("data is loading")
+	// ErrDataNotLoaded marks queries made while no dataset could be loaded
+	ErrDataNotLoaded = errors.New("data not loaded")
+	// ErrQueryTimeout marks a query that ran past its deadline
+	ErrQueryTimeout = errors.New("query timed out")
+	// ErrQuotaExceeded marks a load larger than its dataset or tenant may hold
+	ErrQuotaExceeded = errors.New("quota exceeded")
+)
+
+// kindError is a sentinel with its own message that also matches its kind
+type kindError struct {
+	message string
+	kind    error
+}
+
+func (e *kindError) Error() string {
+	return e.message
+}
+
+func (e *kindError) Is(target error) bool {
+	return target == e.kind
+}
+
+// Unwrap lets a kind that is itself a kindError match its own kind too
+func (e *kindError) Unwrap() error {
+	return e.kind
+}
+
+// newKindError returns a sentinel error of the given taxonomy kind
+func newKindError(kind error, message string) error {
+	return &kindError{message: message, kind: kind}
+}
+
+// LoadingError reports that the initial load is in progress and when the
+// caller should retry. It matches ErrDataLoading.
+type LoadingError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoadingError) Error() string {
+	return fmt.Sprintf("data is loading, retry in %s", e.RetryAfter)
+}
+
+func (e *LoadingError) Is(target error) bool {
+	return target == ErrDataLoading
+}
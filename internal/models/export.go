@@ -0,0 +1,100 @@
+package models
+
+import "time"
+
+var (
+	ErrExportNotFound = newKindError(ErrNotFound, "export not found")
+	ErrInvalidExport  = newKindError(ErrValidation, "invalid export")
+	ErrExportNotReady = newKindError(ErrConflict, "export has not succeeded")
+	ErrExportFinished = newKindError(ErrConflict, "export has already finished")
+	ErrExportLink     = newKindError(ErrValidation, "invalid or expired download link")
+)
+
+// Export file formats
+const (
+	ExportFormatCSV     = "csv"
+	ExportFormatParquet = "parquet"
+)
+
+// Export job states. Queued covers waiting on the job queue and waiting
+// to retry after a transient failure.
+const (
+	ExportQueued    = "queued"
+	ExportRunning   = "running"
+	ExportSucceeded = "succeeded"
+	ExportFailed    = "failed"
+	ExportCancelled = "cancelled"
+)
+
+// Ways an export is emailed
+const (
+	EmailAttachment = "attachment"
+	EmailLink       = "link"
+)
+
+// Email delivery states
+const (
+	EmailPending = "pending"
+	EmailSent    = "sent"
+	EmailFailed  = "failed"
+)
+
+// ExportRequest selects the transactions to export and the file format, and
+// optionally who to email the file to
+type ExportRequest struct {
+	Format  string       `json:"format"`
+	Country string       `json:"country,omitempty"`
+	Segment string       `json:"segment,omitempty"`
+	Email   *ExportEmail `json:"email,omitempty"`
+}
+
+// ExportEmail asks for an export to be emailed once it succeeds. Delivery
+// is an attachment by default, falling back to a link for large files.
+type ExportEmail struct {
+	To       []string `json:"to"`
+	Subject  string   `json:"subject,omitempty"`
+	Delivery string   `json:"delivery,omitempty"`
+}
+
+// EmailDelivery reports on emailing an export. Via is how the file was
+// sent: as an attachment or as a signed download link.
+type EmailDelivery struct {
+	To       []string   `json:"to"`
+	Status   string     `json:"status"`
+	Via      string     `json:"via,omitempty"`
+	Attempts int        `json:"attempts"`
+	Error    string     `json:"error,omitempty"`
+	SentAt   *time.Time `json:"sent_at,omitempty"`
+}
+
+// ExportJob is a background export of the loaded transactions. RowsWritten
+// counts towards TotalRows while the file is written.
+type ExportJob struct {
+	ID          string     `json:"id"`
+	Format      string     `json:"format"`
+	Country     string     `json:"country,omitempty"`
+	Segment     string     `json:"segment,omitempty"`
+	Status      string     `json:"status"`
+	RowsWritten int64      `json:"rows_written"`
+	TotalRows   int64      `json:"total_rows"`
+	Progress    float64    `json:"progress"` // fraction of TotalRows written, 0-1
+	Attempts    int        `json:"attempts"`
+	Error       string     `json:"error,omitempty"`
+	SizeBytes   int64      `json:"size_bytes,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+
+	Email *EmailDelivery `json:"email,omitempty"`
+}
+
+// Finished reports whether the job has stopped for good
+func (j *ExportJob) Finished() bool {
+	return j.Status == ExportSucceeded || j.Status == ExportFailed || j.Status == ExportCancelled
+}
+
+// Filename is the name the export file is downloaded as
+func (j *ExportJob) Filename() string {
+	return "transactions-" + j.ID + "." + j.Format
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+var (
+	ErrFlagNotFound = newKindError(ErrNotFound, "transaction is not flagged")
+	ErrInvalidFlag  = newKindError(ErrValidation, "invalid transaction flag")
+)
+
+// TransactionFlag excludes a transaction, such as a fraudulent or test
+// order, from every load without editing the source files
+type TransactionFlag struct {
+	TransactionID string    `json:"transaction_id"`
+	Reason        string    `json:"reason,omitempty"` // e.g. fraud, test_order
+	FlaggedBy     string    `json:"flagged_by,omitempty"`
+	FlaggedAt     time.Time `json:"flagged_at"`
+}
+
+// FlagRequest flags one or more transactions for the same reason
+type FlagRequest struct {
+	TransactionIDs []string `json:"transaction_ids"`
+	Reason         string   `json:"reason"`
+}
@@ -0,0 +1,49 @@
+package models
+
+var (
+	ErrInvalidHeatmapGrid = newKindError(ErrInvalidParam, "invalid heatmap grid")
+)
+
+// OtherBucket labels the rows rolling up everything past a top-N cut
+const OtherBucket = "Other"
+
+// RegionSales is the revenue of one region of a country
+type RegionSales struct {
+	Country      string
+	Region       string
+	Revenue      Money
+	Transactions int
+	ItemsSold    int
+}
+
+// HeatmapGrid is how many countries, and regions within each, the heatmap
+// shows. With Other set the rest are rolled into "Other" cells, otherwise
+// they are left out.
+type HeatmapGrid struct {
+	Countries int  `json:"countries"`
+	Regions   int  `json:"regions"`
+	Other     bool `json:"other"`
+}
+
+// HeatmapCell is a country and region with its revenue and the revenue
+// relative to the largest named cell
+type HeatmapCell struct {
+	Country      string            `json:"country"`
+	Region       string            `json:"region"`
+	Revenue      Money             `json:"revenue"`
+	Transactions int               `json:"transactions"`
+	ItemsSold    int               `json:"items_sold"`
+	Intensity    float64           `json:"intensity"`       // 0 to 1
+	Other        bool              `json:"other,omitempty"` // rolls up the countries or regions past the grid
+	Display      map[string]string `json:"display,omitempty"`
+}
+
+// Heatmap is the cells of a regional heatmap, largest first within each
+// country and countries largest first
+type Heatmap struct {
+	Grid         HeatmapGrid   `json:"grid"`
+	TotalRevenue Money         `json:"total_revenue"`
+	MaxRevenue   Money         `json:"max_revenue"` // of the largest cell other than "Other"
+	Countries    int           `json:"countries"`   // countries with sales, before the cut
+	Cells        []HeatmapCell `json:"cells"`
+}
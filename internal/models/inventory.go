@@ -0,0 +1,42 @@
+package models
+
+var (
+	ErrInvalidSellThrough = newKindError(ErrInvalidParam, "invalid sell-through request")
+)
+
+// StockLevel is a product's sales over a window and its stock at the end of
+// it, the stock_quantity of its latest transaction
+type StockLevel struct {
+	ProductID   string
+	ProductName string
+	Category    string
+	UnitsSold   int
+	Stock       int
+}
+
+// SellThroughWindow is the inclusive range of days whose sales are counted
+type SellThroughWindow struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Days int    `json:"days"`
+}
+
+// SellThrough is the share of available units that sold: units sold
+// divided by units sold plus the stock left. For a category, units and
+// stock are summed over its products.
+type SellThrough struct {
+	Group          string   `json:"group"`                  // product ID or category
+	ProductName    string   `json:"product_name,omitempty"` // product rows only
+	Category       string   `json:"category,omitempty"`     // product rows only
+	Products       int      `json:"products,omitempty"`     // category rows only
+	UnitsSold      int      `json:"units_sold"`
+	Stock          int      `json:"stock"`
+	SellThroughPct *float64 `json:"sell_through_pct"` // nil without units sold or in stock
+}
+
+// SellThroughReport lists sell-through per product or category
+type SellThroughReport struct {
+	By     string            `json:"by"`
+	Window SellThroughWindow `json:"window"`
+	Rows   []SellThrough     `json:"rows"`
+}
@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+var ErrJobNotFound = newKindError(ErrNotFound, "job not found")
+
+// Data job states
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+)
+
+// JobInfo describes a data job; finished jobs are kept for a while so
+// callers that submitted one can look up its outcome
+type JobInfo struct {
+	ID         uint64     `json:"id"`
+	Kind       string     `json:"kind"`
+	Priority   string     `json:"priority"`
+	Status     string     `json:"status"`
+	EnqueuedAt time.Time  `json:"enqueued_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	Error      string     `json:"error,omitempty"`
+}
+
+// JobQueueStats reports the state of the data job queue
+type JobQueueStats struct {
+	Depth     int       `json:"depth"`
+	Running   *JobInfo  `json:"running"`
+	Pending   []JobInfo `json:"pending"`
+	Completed int64     `json:"completed"`
+	Failed    int64     `json:"failed"`
+}
+
+// RefreshStatus reports the refresh in progress, the one queued behind it
+// and the outcome of the last one to finish
+type RefreshStatus struct {
+	Running     *JobInfo `json:"running"`
+	Queued      *JobInfo `json:"queued"`
+	Last        *JobInfo `json:"last"`
+	DataVersion uint64   `json:"data_version"`
+}
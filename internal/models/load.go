@@ -0,0 +1,121 @@
+package models
+
+import "time"
+
+var (
+	ErrNoPreviousVersion = newKindError(ErrConflict, "no previous data version to roll back to")
+	ErrLoadTimeout       = newKindError(ErrQueryTimeout, "data load timed out")
+	ErrInvalidDataset    = newKindError(ErrValidation, "invalid dataset")
+)
+
+// TableLoadResult reports how many rows were loaded into a table
+type TableLoadResult struct {
+	Name    string `json:"name"`
+	Source  string `json:"source"`
+	Records int    `json:"records"`
+}
+
+// ReferenceCheck reports fact rows whose key is missing from a dimension table
+type ReferenceCheck struct {
+	Table     string `json:"table"`
+	Column    string `json:"column"`
+	RefTable  string `json:"ref_table"`
+	RefColumn string `json:"ref_column"`
+	Orphans   int    `json:"orphans"`
+}
+
+// LoadResult summarizes a multi-table load
+type LoadResult struct {
+	Tables          []TableLoadResult `json:"tables"`
+	ReferenceChecks []ReferenceCheck  `json:"reference_checks,omitempty"`
+	Outliers        *OutlierReport    `json:"outliers,omitempty"`
+	Excluded        int               `json:"excluded,omitempty"`    // flagged transactions left out
+	TestOrders      int               `json:"test_orders,omitempty"` // test orders left out
+}
+
+// IngestStats times the phases of loading the transactions files, so a slow
+// load can be pinned on the disk, the parsing or the inserting. A failed load
+// reports the rows parsed before it failed.
+type IngestStats struct {
+	Rows        int64
+	Bytes       int64
+	ParseErrors int64
+	Read        time.Duration // reading the files from disk
+	Parse       time.Duration // decoding rows and converting values
+	Insert      time.Duration // storing the rows in the backend
+	Optimize    time.Duration // refreshing statistics for the query planner
+}
+
+// LoaderStats reports the data loader's activity since startup
+type LoaderStats struct {
+	Loaded         bool   `json:"loaded"`
+	Loading        bool   `json:"loading"`
+	Loads          int64  `json:"loads"`
+	Failures       int64  `json:"failures"`
+	CoalescedWaits int64  `json:"coalesced_waits"`
+	LastDurationMs int64  `json:"last_duration_ms"`
+	DataVersion    uint64 `json:"data_version"`
+	// LoadStartedAt and EstimatedRemainingMs describe the initial load
+	// while it runs
+	LoadStartedAt        *time.Time `json:"load_started_at,omitempty"`
+	EstimatedRemainingMs int64      `json:"estimated_remaining_ms,omitempty"`
+	// LastError is why the latest initial load failed, until one succeeds
+	LastError string `json:"last_error,omitempty"`
+	// Persisted describes data kept on disk from an earlier run, as found
+	// at startup
+	Persisted *PersistedData `json:"persisted,omitempty"`
+}
+
+// PersistedData is data a file-backed backend kept from an earlier run.
+// It is stale once the files it was loaded from changed.
+type PersistedData struct {
+	LoadedAt time.Time `json:"loaded_at"`
+	Stale    bool      `json:"stale"`
+	Reason   string    `json:"reason,omitempty"` // why it is stale
+}
+
+// Source file formats. DataFormatAuto reads .parquet files as Parquet,
+// .jsonl and .ndjson files as JSON Lines and anything else as CSV.
+const (
+	DataFormatAuto    = "auto"
+	DataFormatCSV     = "csv"
+	DataFormatParquet = "parquet"
+	DataFormatJSONL   = "jsonl"
+)
+
+// DatasetUpload is an ad-hoc dataset to load in place of the configured
+// source. Release is called from the load job once the load finished or was
+// refused, so the files can be discarded even if the caller stopped waiting.
+type DatasetUpload struct {
+	Paths   []string
+	Format  string // a DataFormat constant
+	Tenant  string // whose quota the files count against
+	Release func(error)
+}
+
+// SourceColumn is a column found in a source file and the type it was
+// detected or will be parsed as
+type SourceColumn struct {
+	Name string `json:"name"`
+	Type string `json:"type,omitempty"`
+}
+
+// SourceInspection describes what loading a source file would do, without
+// touching the loaded data
+type SourceInspection struct {
+	Source          string         `json:"source"`
+	SizeBytes       int64          `json:"size_bytes"`
+	Records         int            `json:"records"`
+	Columns         []SourceColumn `json:"columns"`
+	MissingColumns  []string       `json:"missing_columns,omitempty"`
+	Valid           bool           `json:"valid"`
+	Problems        []string       `json:"problems,omitempty"`
+	EstimatedLoadMs int64          `json:"estimated_load_ms"`
+}
+
+// DataSnapshot describes a version of the loaded data: the tables it holds
+// and their coverage
+type DataSnapshot struct {
+	Tables   []string     `json:"tables"`
+	Coverage DataCoverage `json:"coverage"`
+}
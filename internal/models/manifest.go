@@ -0,0 +1,21 @@
+package models
+
+var (
+	ErrInvalidManifest  = newKindError(ErrValidation, "invalid manifest")
+	ErrManifestMismatch = newKindError(ErrValidation, "file does not match the manifest")
+)
+
+// Manifest lists the files of a multi-part dataset with the checksum and row
+// count each must have
+type Manifest struct {
+	Files []ManifestFile `json:"files"`
+}
+
+// ManifestFile describes one part of a dataset. Paths are relative to the
+// manifest.
+type ManifestFile struct {
+	Path   string `json:"path"`
+	SHA256 string `json:"sha256"`
+	Rows   int64  `json:"rows"`            // data rows, excluding the header
+	Bytes  int64  `json:"bytes,omitempty"` // optional, checked before any file is hashed
+}
@@ -0,0 +1,10 @@
+package models
+
+var ErrUnknownDimension = newKindError(ErrInvalidParam, "unknown dimension")
+
+// DimensionValue is a distinct value of a filterable dimension with the
+// number of transactions carrying it
+type DimensionValue struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+)
+
+var (
+	ErrMetricNotFound = newKindError(ErrNotFound, "metric not found")
+	ErrInvalidMetric  = newKindError(ErrValidation, "invalid metric")
+	ErrMetricReadOnly = newKindError(ErrConflict, "metric is defined in configuration")
+)
+
+// DerivedMetric is a named arithmetic expression over base metrics,
+// e.g. aov = revenue / transactions
+type DerivedMetric struct {
+	Name        string     `json:"name"`
+	Expression  string     `json:"expression"`
+	Description string     `json:"description,omitempty"`
+	Source      string     `json:"source"` // api or config
+	CreatedAt   *time.Time `json:"created_at,omitempty"`
+}
+
+// MetricRow holds base metric values for one group of an aggregate query
+type MetricRow struct {
+	Group  string
+	Values map[string]float64
+}
+
+// MetricResult is an aggregate row with requested base and derived metrics.
+// Metrics that cannot be computed for the row (division by zero) are null.
+type MetricResult struct {
+	Group   string              `json:"group,omitempty"`
+	Metrics map[string]*float64 `json:"metrics"`
+}
@@ -0,0 +1,121 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Money is an amount in integer cents. Revenue is summed as DECIMAL inside
+// DuckDB and converted to cents at the boundary, so totals never pick up
+// float64 rounding drift.
+type Money int64
+
+// MoneyFormat controls how Money values are rendered in JSON responses
+type MoneyFormat string
+
+const (
+	MoneyFormatNumber MoneyFormat = "number" // 1234.50
+	MoneyFormatString MoneyFormat = "string" // "1234.50", for clients that parse numbers as float
+	MoneyFormatCents  MoneyFormat = "cents"  // 123450
+)
+
+// moneyFormat is set once at startup from configuration
+var moneyFormat = MoneyFormatNumber
+
+// SetMoneyFormat selects the JSON rendering for all Money values
+func SetMoneyFormat(format MoneyFormat) error {
+	switch format {
+	case MoneyFormatNumber, MoneyFormatString, MoneyFormatCents:
+		moneyFormat = format
+		return nil
+	}
+	return fmt.Errorf("unsupported money format %q", format)
+}
+
+// MoneyFromFloat rounds a float amount to the nearest cent
+func MoneyFromFloat(amount float64) Money {
+	return Money(math.Round(amount * 100))
+}
+
+// Float64 returns the amount in currency units
+func (m Money) Float64() float64 {
+	return float64(m) / 100
+}
+
+// String formats the amount with exactly two decimal places
+func (m Money) String() string {
+	cents := int64(m)
+	sign := ""
+	if cents < 0 {
+		sign = "-"
+		cents = -cents
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, cents/100, cents%100)
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	switch moneyFormat {
+	case MoneyFormatString:
+		return []byte(strconv.Quote(m.String())), nil
+	case MoneyFormatCents:
+		return []byte(strconv.FormatInt(int64(m), 10)), nil
+	default:
+		return []byte(m.String()), nil
+	}
+}
+
+// UnmarshalJSON accepts decimal numbers or strings in currency units
+func (m *Money) UnmarshalJSON(data []byte) error {
+	text := strings.Trim(string(data), `"`)
+	if text == "null" || text == "" {
+		*m = 0
+		return nil
+	}
+
+	parsed, err := ParseMoney(text)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// ParseMoney parses a decimal amount such as "1234.5" without going through
+// float64. More than two decimal places is an error rather than silently rounded.
+func ParseMoney(text string) (Money, error) {
+	text = strings.TrimSpace(text)
+	negative := strings.HasPrefix(text, "-")
+	digits := strings.TrimPrefix(text, "-")
+
+	whole, fraction, _ := strings.Cut(digits, ".")
+	if whole == "" || len(fraction) > 2 || !isDigits(whole) || !isDigits(fraction) {
+		return 0, fmt.Errorf("invalid money amount %q", text)
+	}
+	fraction += strings.Repeat("0", 2-len(fraction))
+
+	units, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid money amount %q", text)
+	}
+	cents, err := strconv.ParseInt(fraction, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid money amount %q", text)
+	}
+
+	total := units*100 + cents
+	if negative {
+		total = -total
+	}
+	return Money(total), nil
+}
+
+func isDigits(text string) bool {
+	for _, r := range text {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
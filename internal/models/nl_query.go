@@ -0,0 +1,47 @@
+package models
+
+var (
+	ErrNLQueryDisabled  = newKindError(ErrNotFound, "natural-language queries are disabled (set NL_QUERY_ENABLED)")
+	ErrNLQueryUnmatched = newKindError(ErrValidation, "question not understood")
+)
+
+// Natural-language query templates. Every question maps to one of these
+// fixed aggregate queries; nothing from the question reaches SQL except as
+// whitelisted metric and grouping names or bound filter values.
+const (
+	NLTemplateTotal     = "total"     // metrics over the whole (filtered) data
+	NLTemplateBreakdown = "breakdown" // metrics per group, largest first
+	NLTemplateTop       = "top"       // the largest groups by a metric
+	NLTemplateTrend     = "trend"     // metrics per month, in date order
+)
+
+// NLQueryRequest is a question such as "revenue by country last quarter"
+type NLQueryRequest struct {
+	Question string `json:"question"`
+}
+
+// NLInterpretation is the query a question was mapped to, returned so the
+// caller can check the question was understood as intended
+type NLInterpretation struct {
+	Template string    `json:"template"`
+	Metrics  []string  `json:"metrics"`
+	GroupBy  string    `json:"group_by,omitempty"`
+	Country  string    `json:"country,omitempty"`
+	Period   *NLPeriod `json:"period,omitempty"`
+	Sort     string    `json:"sort,omitempty"` // metric groups are ordered by, descending
+	Limit    int       `json:"limit,omitempty"`
+}
+
+// NLPeriod is a date range resolved from a question, From and To inclusive
+type NLPeriod struct {
+	Label string `json:"label"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+}
+
+// NLAnswer is an interpreted question with its result rows
+type NLAnswer struct {
+	Question       string           `json:"question"`
+	Interpretation NLInterpretation `json:"interpretation"`
+	Data           []MetricResult   `json:"data"`
+}
@@ -0,0 +1,24 @@
+package models
+
+// Outlier modes, see config.OutlierConfig
+const (
+	OutlierModeTag     = "tag"
+	OutlierModeExclude = "exclude"
+)
+
+// OutlierReport summarizes the transactions a load flagged as outliers.
+// A nil limit means the column had no limit.
+type OutlierReport struct {
+	Mode             string   `json:"mode"`
+	PriceLimit       *float64 `json:"price_limit"`
+	QuantityLimit    *float64 `json:"quantity_limit"`
+	PriceOutliers    int      `json:"price_outliers"`
+	QuantityOutliers int      `json:"quantity_outliers"`
+	Rows             int      `json:"rows"` // Rows flagged, each counted once
+}
+
+// Outlier is a flagged transaction with the columns that exceeded their limit
+type Outlier struct {
+	Transaction
+	Reasons []string `json:"reasons"`
+}
@@ -0,0 +1,13 @@
+package models
+
+// Partition describes one month of the loaded transactions. Queries with a
+// date range only read the partitions it overlaps.
+type Partition struct {
+	Month string `json:"month"` // YYYY-MM
+	Rows  int    `json:"rows"`
+	From  string `json:"from"` // earliest transaction_date in the month, YYYY-MM-DD
+	To    string `json:"to"`   // latest transaction_date in the month, YYYY-MM-DD
+	// RowGroups counts the DuckDB storage row groups holding the month, the
+	// unit DuckDB skips by when a date filter rules it out
+	RowGroups int `json:"row_groups,omitempty"`
+}
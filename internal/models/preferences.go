@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+)
+
+var (
+	ErrInvalidPreferences = newKindError(ErrValidation, "invalid preferences")
+)
+
+// Preferences holds per-identity defaults applied to analytics queries when
+// the corresponding parameters are omitted
+type Preferences struct {
+	Identity  string            `json:"identity"`
+	Filters   map[string]string `json:"filters,omitempty"`  // query parameter -> default value
+	Timezone  string            `json:"timezone,omitempty"` // IANA name, e.g. Europe/Berlin
+	Currency  string            `json:"currency,omitempty"` // ISO 4217 code, e.g. EUR
+	UpdatedAt time.Time         `json:"updated_at"`
+}
@@ -0,0 +1,34 @@
+package models
+
+var (
+	ErrProductNotFound = newKindError(ErrNotFound, "product not found")
+)
+
+// Product represents a row of the products dimension table
+type Product struct {
+	ProductID   string `json:"product_id"`
+	ProductName string `json:"product_name"`
+	Category    string `json:"category"`
+	Brand       string `json:"brand"`
+	Supplier    string `json:"supplier"`
+}
+
+// ProductSales summarizes the transactions recorded for a single product
+type ProductSales struct {
+	TotalRevenue     Money             `json:"total_revenue"`
+	UnitsSold        int               `json:"units_sold"`
+	TransactionCount int               `json:"transaction_count"`
+	Display          map[string]string `json:"display,omitempty"`
+}
+
+// PricePoint is a product's average selling price for one month: revenue
+// divided by units sold, so discounts and bulk prices count by volume
+type PricePoint struct {
+	Month        string            `json:"month"`
+	AvgPrice     Money             `json:"avg_price"`
+	Revenue      Money             `json:"revenue"`
+	UnitsSold    int               `json:"units_sold"`
+	Transactions int               `json:"transactions"`
+	ChangePct    *float64          `json:"change_pct"` // against the previous month listed, nil for the first
+	Display      map[string]string `json:"display,omitempty"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// DataProfile summarizes every column of the loaded transactions, for
+// sanity-checking a newly loaded file
+type DataProfile struct {
+	DataVersion uint64          `json:"data_version"`
+	Table       string          `json:"table"`
+	Rows        int64           `json:"rows"`
+	Columns     []ColumnProfile `json:"columns"`
+	ComputedAt  time.Time       `json:"computed_at"`
+}
+
+// ColumnProfile holds the statistics of one column. Min and max are rendered
+// as text whatever the column type and are nil for an all-null column.
+type ColumnProfile struct {
+	Name          string           `json:"name"`
+	Type          string           `json:"type"`
+	NullRate      float64          `json:"null_rate"`      // Fraction of rows that are null, 0 to 1
+	DistinctCount int64            `json:"distinct_count"` // Approximate
+	Min           *string          `json:"min"`
+	Max           *string          `json:"max"`
+	TopValues     []DimensionValue `json:"top_values"` // Most frequent non-null values; empty for near-unique columns
+}
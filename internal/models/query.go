@@ -0,0 +1,72 @@
+package models
+
+import "time"
+
+// QueryOptions carries per-request modifiers applied to analytics queries
+type QueryOptions struct {
+	// SampleRate is the fraction of rows to scan (0 < rate < 1); zero means full scan
+	SampleRate float64
+	// Segment restricts results to customers in the given customers-dimension segment
+	Segment string
+	// Country restricts results to transactions from a single country
+	Country string
+	// From and To restrict results to transactions dated From up to but
+	// excluding To; zero values leave that end open
+	From time.Time
+	To   time.Time
+	// Filters keep or drop rows by several values of a dimension; Segment
+	// and Country are the single-value forms
+	Filters []DimensionFilter
+}
+
+// Dimensions rows can be filtered by
+const (
+	FilterCountry  = "country"
+	FilterRegion   = "region"
+	FilterCategory = "category"
+	FilterSegment  = "segment"
+)
+
+// FilterDimensions lists the dimensions rows can be filtered by
+var FilterDimensions = []string{FilterCountry, FilterRegion, FilterCategory, FilterSegment}
+
+// DimensionFilter keeps the rows whose dimension has one of Include, if
+// any are given, and drops those with one of Exclude. Values match
+// regardless of case.
+type DimensionFilter struct {
+	Dimension string // a Filter constant
+	Include   []string
+	Exclude   []string
+}
+
+// Sampled reports whether queries should run against a sample of the data
+func (o QueryOptions) Sampled() bool {
+	return o.SampleRate > 0 && o.SampleRate < 1
+}
+
+// ScaleFactor returns the multiplier that extrapolates sampled sums and counts
+// to the full dataset
+func (o QueryOptions) ScaleFactor() float64 {
+	if !o.Sampled() {
+		return 1
+	}
+	return 1 / o.SampleRate
+}
+
+// Filtered reports whether any row filter is set
+func (o QueryOptions) Filtered() bool {
+	return o.Segment != "" || o.Country != "" || len(o.Filters) > 0 || !o.From.IsZero() || !o.To.IsZero()
+}
+
+// DimensionFilters returns Filters with Segment and Country added as
+// single-value filters
+func (o QueryOptions) DimensionFilters() []DimensionFilter {
+	filters := make([]DimensionFilter, 0, len(o.Filters)+2)
+	if o.Segment != "" {
+		filters = append(filters, DimensionFilter{Dimension: FilterSegment, Include: []string{o.Segment}})
+	}
+	if o.Country != "" {
+		filters = append(filters, DimensionFilter{Dimension: FilterCountry, Include: []string{o.Country}})
+	}
+	return append(filters, o.Filters...)
+}
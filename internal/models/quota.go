@@ -0,0 +1,31 @@
+package models
+
+import "fmt"
+
+// Datasets subject to load quotas
+const (
+	DatasetTransactions = "transactions"
+	DatasetTargets      = "targets"
+)
+
+// QuotaError reports a load refused for exceeding a dataset or tenant quota.
+// It matches ErrQuotaExceeded.
+type QuotaError struct {
+	Dataset string // empty for a check before the dataset is known
+	Tenant  string // set when the tenant's quota was exceeded
+	Measure string // "rows" or "bytes"
+	Value   int64
+	Limit   int64
+}
+
+func (e *QuotaError) Error() string {
+	owner := e.Dataset + " dataset"
+	if e.Tenant != "" {
+		owner = "tenant " + e.Tenant
+	}
+	return fmt.Sprintf("quota exceeded for %s: %d %s, the limit is %d", owner, e.Value, e.Measure, e.Limit)
+}
+
+func (e *QuotaError) Is(target error) bool {
+	return target == ErrQuotaExceeded
+}
@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// ErrInvalidSchedule is returned for a refresh schedule that does not parse
+var ErrInvalidSchedule = newKindError(ErrValidation, "invalid refresh schedule")
+
+// Where the active refresh schedule came from
+const (
+	ScheduleSourceConfig = "config"
+	ScheduleSourceAPI    = "api"
+)
+
+// RefreshSchedule describes the automatic refreshes and how they went
+type RefreshSchedule struct {
+	Enabled        bool       `json:"enabled"`
+	Cron           string     `json:"cron"`
+	Timezone       string     `json:"timezone"`
+	Source         string     `json:"source"`
+	NextRun        *time.Time `json:"next_run,omitempty"`
+	LastRun        *time.Time `json:"last_run,omitempty"`
+	LastDurationMs int64      `json:"last_duration_ms,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	Runs           int        `json:"runs"`
+	Failures       int        `json:"failures"`
+}
+
+// RefreshScheduleRequest replaces the refresh schedule. An empty cron
+// expression disables scheduled refreshes; an empty timezone keeps the
+// current one.
+type RefreshScheduleRequest struct {
+	Cron     string `json:"cron"`
+	Timezone string `json:"timezone,omitempty"`
+}
@@ -0,0 +1,73 @@
+package models
+
+import "time"
+
+var (
+	ErrReportNotFound = newKindError(ErrNotFound, "report template not found")
+	ErrInvalidReport  = newKindError(ErrValidation, "invalid report template")
+	ErrReportNoPDF    = newKindError(ErrValidation, "PDF reports are not configured (set REPORT_PDF_COMMAND)")
+)
+
+// Report chart types. A table lists every metric; bar and line charts plot
+// the section's first metric per group.
+const (
+	ChartTable = "table"
+	ChartBar   = "bar"
+	ChartLine  = "line"
+)
+
+// ReportTemplate defines a report in YAML: a title and a list of sections,
+// each a metrics query over the loaded data
+type ReportTemplate struct {
+	Name        string          `yaml:"-" json:"name"`
+	Title       string          `yaml:"title" json:"title"`
+	Description string          `yaml:"description" json:"description,omitempty"`
+	Filters     ReportFilters   `yaml:"filters" json:"filters"`
+	Sections    []ReportSection `yaml:"sections" json:"sections"`
+}
+
+// ReportFilters restrict every section of a report; request parameters
+// override them
+type ReportFilters struct {
+	Country string `yaml:"country" json:"country,omitempty"`
+	Segment string `yaml:"segment" json:"segment,omitempty"`
+}
+
+// ReportSection is one query of a report: base or derived metrics, grouped
+// by one of the aggregate groupings, sorted by a metric and cut to a limit.
+// A section with only text renders as a paragraph.
+type ReportSection struct {
+	Title   string   `yaml:"title" json:"title"`
+	Text    string   `yaml:"text" json:"text,omitempty"`
+	Metrics []string `yaml:"metrics" json:"metrics,omitempty"`
+	GroupBy string   `yaml:"group_by" json:"group_by,omitempty"`
+	Sort    string   `yaml:"sort" json:"sort,omitempty"` // metric to sort groups by, descending
+	Limit   int      `yaml:"limit" json:"limit,omitempty"`
+	Chart   string   `yaml:"chart" json:"chart,omitempty"`
+}
+
+// ReportTemplateInfo lists a template file; templates that fail to parse
+// are listed with the error so authors can find their mistake
+type ReportTemplateInfo struct {
+	Name        string `json:"name"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Sections    int    `json:"sections"`
+	Error       string `json:"error,omitempty"`
+}
+
+// Report is a template evaluated against the loaded data
+type Report struct {
+	Name        string         `json:"name"`
+	Title       string         `json:"title"`
+	Description string         `json:"description,omitempty"`
+	Filters     ReportFilters  `json:"filters"`
+	Sections    []ReportResult `json:"sections"`
+	GeneratedAt time.Time      `json:"generated_at"`
+}
+
+// ReportResult is the outcome of one report section
+type ReportResult struct {
+	ReportSection
+	Rows []MetricResult `json:"rows,omitempty"`
+}
@@ -0,0 +1,7 @@
+package models
+
+import "errors"
+
+// ErrNotSupported is returned by analytics backends for operations they
+// cannot serve, e.g. backups on a remote warehouse
+var ErrNotSupported = errors.New("not supported by the configured data backend")
@@ -0,0 +1,16 @@
+package models
+
+var (
+	ErrInvalidTargets = newKindError(ErrValidation, "invalid targets file")
+)
+
+// TargetVariance compares actual revenue with the planned target for a month and country
+type TargetVariance struct {
+	Month       string            `json:"month"`
+	Country     string            `json:"country"`
+	Target      Money             `json:"target"`
+	Actual      Money             `json:"actual"`
+	Variance    Money             `json:"variance"`     // actual - target
+	VariancePct *float64          `json:"variance_pct"` // nil when the target is zero
+	Display     map[string]string `json:"display,omitempty"`
+}
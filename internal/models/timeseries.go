@@ -0,0 +1,69 @@
+package models
+
+var (
+	ErrInvalidTimeSeries = newKindError(ErrInvalidParam, "invalid time series request")
+	ErrInvalidCalendar   = newKindError(ErrInvalidParam, "invalid calendar request")
+)
+
+// Time series granularities. Weeks start on Monday.
+const (
+	GranularityDay   = "day"
+	GranularityWeek  = "week"
+	GranularityMonth = "month"
+)
+
+// Time series metrics
+const (
+	SeriesRevenue = "revenue"
+	SeriesOrders  = "orders" // transactions
+	SeriesUnits   = "units"  // quantity sold
+)
+
+// Time series smoothing. The moving averages take the mean of a period and
+// the periods before it, up to 7 or 30 in all; the exponentially weighted
+// average weighs each period by 2/(7+1) against the average before it.
+const (
+	SmoothMA7  = "ma7"
+	SmoothMA30 = "ma30"
+	SmoothEWMA = "ewma"
+)
+
+// TimeBucket is the sales of one day, week or month, keyed by its first day
+type TimeBucket struct {
+	Start   string // YYYY-MM-DD
+	Revenue Money
+	Orders  int
+	Units   int
+}
+
+// TimeSeries holds series aligned on Dates: the i-th value of every series
+// belongs to the i-th date. Periods without sales are zero, not missing.
+type TimeSeries struct {
+	Granularity string
+	Dates       []string // first day of each period, YYYY-MM-DD
+	Revenue     []Money
+	Orders      []int
+	Units       []int
+	// Smooth is the smoothing applied to Smoothed, which then holds every
+	// metric's smoothed values aligned on Dates; empty without smoothing
+	Smooth   string
+	Smoothed map[string][]float64
+}
+
+// CalendarDay is one cell of a calendar heatmap. Value is in currency units
+// for revenue.
+type CalendarDay struct {
+	Date      string  `json:"date"` // YYYY-MM-DD
+	Value     float64 `json:"value"`
+	Intensity float64 `json:"intensity"` // value over the year's largest, 0 to 1
+}
+
+// Calendar holds one metric for every day of a year, days without sales
+// included
+type Calendar struct {
+	Year     int           `json:"year"`
+	Metric   string        `json:"metric"`
+	Total    float64       `json:"total"`
+	MaxValue float64       `json:"max_value"`
+	Days     []CalendarDay `json:"days"`
+}
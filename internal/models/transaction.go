@@ -26,6 +26,107 @@ type Transaction struct {
 	AddedDate       time.Time `json:"added_date" csv:"added_date"`
 }
 
+// isoDateLayout is parsed without the time package, see parseISODate
+const isoDateLayout = "2006-01-02"
+
+// defaultDateLayouts are tried when no layouts are configured; added dates
+// accept the first two only
+var defaultDateLayouts = []string{isoDateLayout, "01/02/2006", "2006-01-02 15:04:05"}
+
+// Layouts a transaction date and an added date may come in, tried in order.
+// SetDateLayouts replaces both at startup.
+var (
+	transactionDateLayouts = defaultDateLayouts
+	addedDateLayouts       = defaultDateLayouts[:2]
+)
+
+// SetDateLayouts sets the Go time layouts source dates are parsed with, as
+// a ";"-separated list tried in order, for transaction and added dates
+// alike. An empty spec restores the defaults. Listing only the layout a
+// deployment's files use spares every row the attempts that fail.
+func SetDateLayouts(spec string) error {
+	if strings.TrimSpace(spec) == "" {
+		transactionDateLayouts, addedDateLayouts = defaultDateLayouts, defaultDateLayouts[:2]
+		return nil
+	}
+
+	var layouts []string
+	for _, layout := range strings.Split(spec, ";") {
+		layout = strings.TrimSpace(layout)
+		if layout == "" {
+			continue
+		}
+		// A layout must read back the reference date it formats, which
+		// fails for layouts missing the year, month or day
+		reference := time.Date(2006, time.January, 2, 15, 4, 5, 0, time.UTC)
+		date, err := time.Parse(layout, reference.Format(layout))
+		if err != nil || date.YearDay() != reference.YearDay() || date.Year() != reference.Year() {
+			return fmt.Errorf("invalid date layout %q: it must include a year, month and day", layout)
+		}
+		layouts = append(layouts, layout)
+	}
+	if len(layouts) == 0 {
+		return fmt.Errorf("no date layouts in %q", spec)
+	}
+	transactionDateLayouts, addedDateLayouts = layouts, layouts
+	return nil
+}
+
+// parseDate parses value with the first of layouts that fits it
+func parseDate(value string, layouts []string) (time.Time, bool) {
+	for _, layout := range layouts {
+		if layout == isoDateLayout {
+			if date, ok := parseISODate(value); ok {
+				return date, true
+			}
+			continue
+		}
+		if date, err := time.Parse(layout, value); err == nil {
+			return date, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// parseISODate parses a YYYY-MM-DD date like time.Parse with isoDateLayout
+// would, reading the digits directly. A value that does not fit costs no
+// error allocation, so the next layout is tried cheaply.
+func parseISODate(value string) (time.Time, bool) {
+	if len(value) != len(isoDateLayout) || value[4] != '-' || value[7] != '-' {
+		return time.Time{}, false
+	}
+	year, ok1 := digits(value[0:4])
+	month, ok2 := digits(value[5:7])
+	day, ok3 := digits(value[8:10])
+	if !ok1 || !ok2 || !ok3 || month < 1 || month > 12 || day < 1 {
+		return time.Time{}, false
+	}
+	if day > daysInMonth[month] || (month == 2 && day == 29 && !isLeap(year)) {
+		return time.Time{}, false
+	}
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC), true
+}
+
+// daysInMonth is indexed by month, February counted in a leap year
+var daysInMonth = [13]int{0, 31, 29, 31, 30, 31, 30, 31, 31, 30, 31, 30, 31}
+
+func isLeap(year int) bool {
+	return year%4 == 0 && (year%100 != 0 || year%400 == 0)
+}
+
+// digits reads an unsigned decimal number made only of ASCII digits
+func digits(s string) (int, bool) {
+	n := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < '0' || c > '9' {
+			return 0, false
+		}
+		n = n*10 + int(c-'0')
+	}
+	return n, true
+}
+
 // ParseCSVRow converts a CSV row to Transaction
 func (t *Transaction) ParseCSVRow(row []string) error {
 	if len(row) < 12 {
@@ -40,18 +141,11 @@ func (t *Transaction) ParseCSVRow(row []string) error {
 
 	// Parse transaction date
 	if dateStr := strings.TrimSpace(row[1]); dateStr != "" {
-		if date, err := time.Parse("2006-01-02", dateStr); err == nil {
-			t.TransactionDate = date
-		} else {
-			// Try alternative date formats
-			if date, err := time.Parse("01/02/2006", dateStr); err == nil {
-				t.TransactionDate = date
-			} else if date, err := time.Parse("2006-01-02 15:04:05", dateStr); err == nil {
-				t.TransactionDate = date
-			} else {
-				return fmt.Errorf("invalid transaction_date: %s", dateStr)
-			}
+		date, ok := parseDate(dateStr, transactionDateLayouts)
+		if !ok {
+			return fmt.Errorf("invalid transaction_date: %s", dateStr)
 		}
+		t.TransactionDate = date
 	}
 
 	t.UserID = strings.TrimSpace(row[2])
@@ -97,12 +191,8 @@ func (t *Transaction) ParseCSVRow(row []string) error {
 	// Parse added date if exists
 	if len(row) > 12 {
 		if dateStr := strings.TrimSpace(row[12]); dateStr != "" {
-			if date, err := time.Parse("2006-01-02", dateStr); err == nil {
-				t.AddedDate = date
-			} else if date, err := time.Parse("01/02/2006", dateStr); err == nil {
-				t.AddedDate = date
-			}
 			// If parsing fails, just leave AddedDate as zero value
+			t.AddedDate, _ = parseDate(dateStr, addedDateLayouts)
 		}
 	}
 
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrUploadNotFound   = newKindError(ErrNotFound, "upload not found")
+	ErrInvalidUpload    = newKindError(ErrValidation, "invalid upload")
+	ErrUploadOffset     = newKindError(ErrConflict, "chunk offset does not match the upload")
+	ErrUploadBusy       = newKindError(ErrConflict, "upload is receiving another chunk")
+	ErrUploadIncomplete = newKindError(ErrConflict, "upload is incomplete")
+	ErrUploadRejected   = newKindError(ErrValidation, "upload rejected")
+)
+
+// Upload is a resumable upload session. Chunks are appended at Offset until
+// it reaches Size; a client that loses its connection asks for the offset and
+// continues from there.
+type Upload struct {
+	ID        string    `json:"id"`
+	Filename  string    `json:"filename,omitempty"`
+	Size      int64     `json:"size"`
+	Offset    int64     `json:"offset"`
+	Complete  bool      `json:"complete"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// UploadedFile is a received file awaiting ingestion
+type UploadedFile struct {
+	Path       string
+	Name       string // client file name, empty when unknown
+	Size       int64
+	UploadedBy string
+}
+
+// ScanRejection reports why an upload scanner refused a file. It matches
+// ErrUploadRejected.
+type ScanRejection struct {
+	Scanner string
+	Reason  string
+}
+
+func (e *ScanRejection) Error() string {
+	return fmt.Sprintf("upload rejected by %s scan: %s", e.Scanner, e.Reason)
+}
+
+func (e *ScanRejection) Is(target error) bool {
+	return target == ErrUploadRejected || errors.Is(ErrUploadRejected, target)
+}
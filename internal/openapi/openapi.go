@@ -0,0 +1,276 @@
+// Package openapi describes the API as an OpenAPI 3.0 document built from the
+// Go types the handlers encode, so generated clients use the same JSON field
+// names as the server.
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Version is the OpenAPI version of the documents built here
+const Version = "3.0.3"
+
+// Document is an OpenAPI document, reduced to the parts this API uses
+type Document struct {
+	OpenAPI    string                `json:"openapi"`
+	Info       Info                  `json:"info"`
+	Paths      map[string]PathItem   `json:"paths"`
+	Components Components            `json:"components"`
+	Security   []map[string][]string `json:"security,omitempty"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem maps the lower-case HTTP methods of a path to their operations
+type PathItem map[string]*Operation
+
+type Operation struct {
+	OperationID string              `json:"operationId"`
+	Summary     string              `json:"summary,omitempty"`
+	Tags        []string            `json:"tags,omitempty"`
+	Parameters  []Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]Response `json:"responses"`
+}
+
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"` // path, query or header
+	Required bool    `json:"required,omitempty"`
+	Schema   *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required,omitempty"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type SecurityScheme struct {
+	Type string `json:"type"`
+	In   string `json:"in,omitempty"`
+	Name string `json:"name,omitempty"`
+}
+
+// Schema is a JSON schema as OpenAPI 3.0 uses it. The zero Schema accepts
+// any value.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+// Builder collects operations and the schemas of the types they use.
+// Exported named structs become components referenced by name; unexported
+// and anonymous structs, such as response envelopes, are inlined.
+type Builder struct {
+	doc       Document
+	overrides map[reflect.Type]Schema
+	names     map[string]reflect.Type
+}
+
+func NewBuilder(info Info) *Builder {
+	return &Builder{
+		doc: Document{
+			OpenAPI:    Version,
+			Info:       info,
+			Paths:      map[string]PathItem{},
+			Components: Components{Schemas: map[string]*Schema{}},
+		},
+		overrides: map[reflect.Type]Schema{},
+		names:     map[string]reflect.Type{},
+	}
+}
+
+// Override describes the type of v by schema, for types that encode
+// themselves rather than as their Go structure
+func (b *Builder) Override(v interface{}, schema Schema) {
+	b.overrides[reflect.TypeOf(v)] = schema
+}
+
+// APIKey requires the header on every operation
+func (b *Builder) APIKey(header string) {
+	b.doc.Components.SecuritySchemes = map[string]SecurityScheme{
+		"apiKey": {Type: "apiKey", In: "header", Name: header},
+	}
+	b.doc.Security = []map[string][]string{{"apiKey": {}}}
+}
+
+// Add adds the operation served for method on path. Adding an operation
+// twice is a mistake in the route table and panics.
+func (b *Builder) Add(method, path string, op *Operation) {
+	item, ok := b.doc.Paths[path]
+	if !ok {
+		item = PathItem{}
+		b.doc.Paths[path] = item
+	}
+	method = strings.ToLower(method)
+	if _, dup := item[method]; dup {
+		panic(fmt.Sprintf("openapi: %s %s added twice", method, path))
+	}
+	item[method] = op
+}
+
+// Document returns the document built so far
+func (b *Builder) Document() Document {
+	return b.doc
+}
+
+// Schema returns the schema of v's type; a nil v gives a schema accepting
+// anything
+func (b *Builder) Schema(v interface{}) *Schema {
+	if v == nil {
+		return &Schema{}
+	}
+	return b.schema(reflect.TypeOf(v))
+}
+
+func (b *Builder) schema(t reflect.Type) *Schema {
+	if s, ok := b.overrides[t]; ok {
+		return &s
+	}
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t.Kind() == reflect.Pointer:
+		s := b.schema(t.Elem())
+		if s.Ref != "" {
+			return s
+		}
+		s.Nullable = true
+		return s
+	case t.Implements(marshalerType):
+		// Its JSON cannot be derived from the type; override it
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Uint:
+		return &Schema{Type: "integer"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: b.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: b.schema(t.Elem())}
+	case reflect.Struct:
+		if !isComponent(t) {
+			return b.object(t)
+		}
+		name := b.componentName(t)
+		if _, ok := b.doc.Components.Schemas[name]; !ok {
+			// Reserve the name first: the type may refer to itself
+			b.doc.Components.Schemas[name] = &Schema{}
+			*b.doc.Components.Schemas[name] = *b.object(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + name}
+	}
+	return &Schema{}
+}
+
+// isComponent tells whether t is an exported named struct, which is shared
+// as a component
+func isComponent(t reflect.Type) bool {
+	name := t.Name()
+	return name != "" && !strings.Contains(name, "[") && name[0] >= 'A' && name[0] <= 'Z'
+}
+
+// componentName names t's component by its type name, qualified by its
+// package when another type of that name is already a component
+func (b *Builder) componentName(t reflect.Type) string {
+	name := t.Name()
+	if other, ok := b.names[name]; ok && other != t {
+		name = path.Base(t.PkgPath()) + "." + name
+	}
+	b.names[name] = t
+	return name
+}
+
+// object describes a struct as encoding/json encodes it: by json tag name,
+// with embedded structs flattened and fields tagged "-" left out. Fields
+// without omitempty are always present and so required.
+func (b *Builder) object(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	b.addFields(s, t)
+	return s
+}
+
+func (b *Builder) addFields(s *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				b.addFields(s, embedded)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		s.Properties[name] = b.schema(field.Type)
+		if !strings.Contains(options, "omitempty") {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
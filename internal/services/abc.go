@@ -0,0 +1,104 @@
+package services
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+
+	"analytics-dashboard-api/internal/config"
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/pkg/logger"
+)
+
+// ABCService classifies products into A, B and C tiers by their cumulative
+// share of revenue, for inventory planning. Products are ranked by revenue;
+// a product is A while the products above it make up less than the A
+// cut-off, B while they make up less than the B cut-off, and C after that.
+// The product crossing a cut-off therefore stays in the higher class, and
+// the best seller is always A.
+type ABCService struct {
+	cutoffs models.ABCCutoffs
+	queries MetricQuerier
+	logger  logger.Logger
+}
+
+func NewABCService(cfg config.ABCConfig, queries MetricQuerier, logger logger.Logger) *ABCService {
+	return &ABCService{
+		cutoffs: models.ABCCutoffs{A: cfg.ACutoff, B: cfg.BCutoff},
+		queries: queries,
+		logger:  logger,
+	}
+}
+
+// Classify ranks the products sold under opts. Zero cut-offs take the
+// configured ones.
+func (s *ABCService) Classify(ctx context.Context, opts models.QueryOptions, cutoffs models.ABCCutoffs) (*models.ABCClassification, error) {
+	if cutoffs.A == 0 {
+		cutoffs.A = s.cutoffs.A
+	}
+	if cutoffs.B == 0 {
+		cutoffs.B = s.cutoffs.B
+	}
+	if cutoffs.A <= 0 || cutoffs.A >= cutoffs.B || cutoffs.B > 1 {
+		return nil, fmt.Errorf("%w: %g and %g, want 0 < a < b <= 1", models.ErrInvalidABCCutoffs, cutoffs.A, cutoffs.B)
+	}
+
+	rows, err := s.queries.GetBaseMetrics(ctx, opts, "product")
+	if err != nil {
+		return nil, err
+	}
+
+	products := make([]models.ABCProduct, len(rows))
+	var total float64
+	for i, row := range rows {
+		products[i] = models.ABCProduct{Product: row.Group, Revenue: roundCents(row.Values["revenue"])}
+		total += products[i].Revenue
+	}
+	slices.SortFunc(products, func(a, b models.ABCProduct) int {
+		if c := cmp.Compare(b.Revenue, a.Revenue); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Product, b.Product)
+	})
+
+	classification := &models.ABCClassification{
+		Cutoffs:      cutoffs,
+		TotalRevenue: roundCents(total),
+		Classes: []models.ABCClassSummary{
+			{Class: models.ABCClassA}, {Class: models.ABCClassB}, {Class: models.ABCClassC},
+		},
+		Products: products,
+	}
+	var cumulative float64
+	for i := range products {
+		p := &products[i]
+		above := cumulative
+		cumulative += p.Revenue
+		summary := &classification.Classes[2]
+		if total > 0 && p.Revenue > 0 {
+			p.SharePct = p.Revenue / total * 100
+			p.CumulativePct = cumulative / total * 100
+			switch {
+			case above/total < cutoffs.A:
+				summary = &classification.Classes[0]
+			case above/total < cutoffs.B:
+				summary = &classification.Classes[1]
+			}
+		}
+		p.Class = summary.Class
+		summary.Products++
+		summary.Revenue += p.Revenue
+	}
+	for i := range classification.Classes {
+		summary := &classification.Classes[i]
+		summary.Revenue = roundCents(summary.Revenue)
+		if total > 0 {
+			summary.RevenueSharePct = summary.Revenue / total * 100
+		}
+		if len(products) > 0 {
+			summary.ProductSharePct = float64(summary.Products) / float64(len(products)) * 100
+		}
+	}
+	return classification, nil
+}
@@ -0,0 +1,374 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/pkg/logger"
+)
+
+// maxAlertHistory bounds the persisted alert history
+const maxAlertHistory = 500
+
+// Alert evaluation triggers
+const (
+	TriggerRefresh  = "refresh"
+	TriggerSchedule = "schedule"
+	TriggerManual   = "manual"
+)
+
+// AlertMetrics lists the metrics rules can reference
+var AlertMetrics = []string{
+	"total_records",
+	"total_revenue",
+	"daily_revenue",
+	"daily_transactions",
+	"monthly_revenue",
+	"refresh_failures",
+}
+
+type MetricSource interface {
+	GetAlertMetrics(context.Context) (map[string]float64, error)
+}
+
+// AlertEngine evaluates threshold rules against dataset metrics after each
+// refresh and on a schedule, notifying webhooks when a rule starts firing
+type AlertEngine struct {
+	mu              sync.Mutex
+	rulesPath       string
+	historyPath     string
+	rules           []models.AlertRule
+	history         []models.Alert
+	firing          map[string]bool // rule ID -> currently breaching
+	dataLoaded      bool
+	refreshFailures int
+
+	metrics MetricSource
+	client  *http.Client
+	logger  logger.Logger
+}
+
+func NewAlertEngine(stateDir string, metrics MetricSource, webhookTimeout time.Duration, logger logger.Logger) (*AlertEngine, error) {
+	engine := &AlertEngine{
+		rulesPath:   filepath.Join(stateDir, "alert_rules.json"),
+		historyPath: filepath.Join(stateDir, "alert_history.json"),
+		firing:      make(map[string]bool),
+		metrics:     metrics,
+		client:      &http.Client{Timeout: webhookTimeout},
+		logger:      logger,
+	}
+
+	if err := loadJSONFile(engine.rulesPath, &engine.rules); err != nil {
+		return nil, err
+	}
+	if err := loadJSONFile(engine.historyPath, &engine.history); err != nil {
+		return nil, err
+	}
+
+	return engine, nil
+}
+
+// Rules returns all configured rules
+func (e *AlertEngine) Rules() []models.AlertRule {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return append([]models.AlertRule{}, e.rules...)
+}
+
+// CreateRule validates and stores a new rule
+func (e *AlertEngine) CreateRule(rule models.AlertRule) (*models.AlertRule, error) {
+	if err := validateAlertRule(&rule); err != nil {
+		return nil, err
+	}
+
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+	rule.ID = id
+	rule.CreatedAt = time.Now().UTC()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.rules = append(e.rules, rule)
+	if err := saveJSONFile(e.rulesPath, e.rules); err != nil {
+		e.rules = e.rules[:len(e.rules)-1]
+		return nil, err
+	}
+
+	return &rule, nil
+}
+
+// DeleteRule removes a rule by ID
+func (e *AlertEngine) DeleteRule(id string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for i, rule := range e.rules {
+		if rule.ID != id {
+			continue
+		}
+
+		remaining := append(append([]models.AlertRule{}, e.rules[:i]...), e.rules[i+1:]...)
+		if err := saveJSONFile(e.rulesPath, remaining); err != nil {
+			return err
+		}
+		e.rules = remaining
+		delete(e.firing, id)
+		return nil
+	}
+
+	return models.ErrAlertRuleNotFound
+}
+
+// History returns up to limit most recent alerts, newest first
+func (e *AlertEngine) History(limit int) []models.Alert {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	results := make([]models.Alert, 0, len(e.history))
+	for i := len(e.history) - 1; i >= 0 && (limit <= 0 || len(results) < limit); i-- {
+		results = append(results, e.history[i])
+	}
+	return results
+}
+
+// HandleRefresh records a refresh outcome and evaluates rules against the
+// freshly loaded data
+func (e *AlertEngine) HandleRefresh(ctx context.Context, refreshErr error) {
+	e.mu.Lock()
+	if refreshErr != nil {
+		e.refreshFailures++
+	} else {
+		e.refreshFailures = 0
+		e.dataLoaded = true
+	}
+	e.mu.Unlock()
+
+	if _, err := e.Evaluate(ctx, TriggerRefresh); err != nil {
+		e.logger.Error("Alert evaluation after refresh failed", "error", err)
+	}
+}
+
+// Start evaluates rules every interval until ctx is cancelled
+func (e *AlertEngine) Start(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := e.Evaluate(ctx, TriggerSchedule); err != nil {
+					e.logger.Error("Scheduled alert evaluation failed", "error", err)
+				}
+			}
+		}
+	}()
+}
+
+// Evaluate checks every enabled rule and notifies rules that start breaching.
+// Rules already firing are not re-notified until they recover.
+func (e *AlertEngine) Evaluate(ctx context.Context, trigger string) ([]models.Alert, error) {
+	e.mu.Lock()
+	rules := append([]models.AlertRule{}, e.rules...)
+	dataLoaded := e.dataLoaded
+	refreshFailures := e.refreshFailures
+	e.mu.Unlock()
+
+	if len(rules) == 0 {
+		return nil, nil
+	}
+
+	metrics := map[string]float64{
+		"refresh_failures": float64(refreshFailures),
+	}
+	// Dataset metrics are meaningless before the first load
+	if dataLoaded {
+		values, err := e.metrics.GetAlertMetrics(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to collect alert metrics: %w", err)
+		}
+		for name, value := range values {
+			metrics[name] = value
+		}
+	}
+
+	var fired []models.Alert
+	for _, rule := range rules {
+		if !rule.Enabled {
+			continue
+		}
+		value, ok := metrics[rule.Metric]
+		if !ok {
+			continue
+		}
+
+		breaching := compare(value, rule.Operator, rule.Threshold)
+
+		e.mu.Lock()
+		wasFiring := e.firing[rule.ID]
+		e.firing[rule.ID] = breaching
+		e.mu.Unlock()
+
+		if !breaching || wasFiring {
+			continue
+		}
+
+		alert := models.Alert{
+			RuleID:    rule.ID,
+			RuleName:  rule.Name,
+			Metric:    rule.Metric,
+			Operator:  rule.Operator,
+			Threshold: rule.Threshold,
+			Value:     value,
+			Trigger:   trigger,
+			FiredAt:   time.Now().UTC(),
+		}
+
+		if err := e.notify(ctx, rule, alert); err != nil {
+			alert.DeliveryError = err.Error()
+			e.logger.Error("Alert delivery failed", "rule", rule.Name, "error", err)
+		} else {
+			alert.Delivered = true
+		}
+
+		e.logger.Warn("Alert fired",
+			"rule", rule.Name,
+			"metric", rule.Metric,
+			"value", value,
+			"threshold", rule.Threshold)
+		fired = append(fired, alert)
+	}
+
+	if len(fired) > 0 {
+		e.recordHistory(fired)
+	}
+	return fired, nil
+}
+
+func (e *AlertEngine) recordHistory(alerts []models.Alert) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.history = append(e.history, alerts...)
+	if len(e.history) > maxAlertHistory {
+		e.history = e.history[len(e.history)-maxAlertHistory:]
+	}
+	if err := saveJSONFile(e.historyPath, e.history); err != nil {
+		e.logger.Error("Failed to persist alert history", "error", err)
+	}
+}
+
+// notify posts the alert to the rule's webhook. Slack incoming webhooks only
+// receive a text message; generic webhooks receive the full alert.
+func (e *AlertEngine) notify(ctx context.Context, rule models.AlertRule, alert models.Alert) error {
+	text := fmt.Sprintf("Alert %q: %s is %g (%s %g)",
+		rule.Name, rule.Metric, alert.Value, rule.Operator, rule.Threshold)
+
+	var payload interface{} = map[string]interface{}{
+		"text":  text,
+		"alert": alert,
+	}
+	if rule.Channel == "slack" {
+		payload = map[string]string{"text": text}
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode alert: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, rule.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func compare(value float64, operator string, threshold float64) bool {
+	switch operator {
+	case "<":
+		return value < threshold
+	case "<=":
+		return value <= threshold
+	case ">":
+		return value > threshold
+	case ">=":
+		return value >= threshold
+	case "==":
+		return value == threshold
+	case "!=":
+		return value != threshold
+	}
+	return false
+}
+
+func validateAlertRule(rule *models.AlertRule) error {
+	rule.Name = strings.TrimSpace(rule.Name)
+	if rule.Name == "" {
+		return fmt.Errorf("%w: name is required", models.ErrInvalidAlertRule)
+	}
+
+	if !containsString(AlertMetrics, rule.Metric) {
+		return fmt.Errorf("%w: unknown metric %q (supported: %s)",
+			models.ErrInvalidAlertRule, rule.Metric, strings.Join(AlertMetrics, ", "))
+	}
+
+	switch rule.Operator {
+	case "<", "<=", ">", ">=", "==", "!=":
+	default:
+		return fmt.Errorf("%w: unsupported operator %q", models.ErrInvalidAlertRule, rule.Operator)
+	}
+
+	if rule.Channel == "" {
+		rule.Channel = "webhook"
+	}
+	if rule.Channel != "webhook" && rule.Channel != "slack" {
+		return fmt.Errorf("%w: channel must be webhook or slack", models.ErrInvalidAlertRule)
+	}
+
+	parsed, err := url.Parse(rule.WebhookURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("%w: webhook_url must be an http(s) URL", models.ErrInvalidAlertRule)
+	}
+
+	return nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
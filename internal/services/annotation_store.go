@@ -0,0 +1,134 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/pkg/logger"
+)
+
+// AnnotationStore keeps dashboard annotations in memory and persists them to
+// a JSON file in the state directory
+type AnnotationStore struct {
+	mu          sync.RWMutex
+	path        string
+	annotations []models.Annotation
+	logger      logger.Logger
+}
+
+func NewAnnotationStore(stateDir string, logger logger.Logger) (*AnnotationStore, error) {
+	store := &AnnotationStore{
+		path:   filepath.Join(stateDir, "annotations.json"),
+		logger: logger,
+	}
+
+	if err := loadJSONFile(store.path, &store.annotations); err != nil {
+		return nil, err
+	}
+
+	return store, nil
+}
+
+// Create validates and stores a new annotation
+func (s *AnnotationStore) Create(annotation models.Annotation) (*models.Annotation, error) {
+	if err := validateAnnotation(annotation); err != nil {
+		return nil, err
+	}
+
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+	annotation.ID = id
+	annotation.CreatedAt = time.Now().UTC()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.annotations = append(s.annotations, annotation)
+	if err := saveJSONFile(s.path, s.annotations); err != nil {
+		s.annotations = s.annotations[:len(s.annotations)-1]
+		return nil, err
+	}
+
+	return &annotation, nil
+}
+
+// List returns annotations overlapping [from, to], ordered by date.
+// Empty bounds are open.
+func (s *AnnotationStore) List(from, to string) []models.Annotation {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	results := make([]models.Annotation, 0, len(s.annotations))
+	for _, a := range s.annotations {
+		end := a.EndDate
+		if end == "" {
+			end = a.Date
+		}
+		// YYYY-MM-DD strings compare chronologically
+		if from != "" && end < from {
+			continue
+		}
+		if to != "" && a.Date > to {
+			continue
+		}
+		results = append(results, a)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Date < results[j].Date
+	})
+	return results
+}
+
+// Delete removes an annotation by ID
+func (s *AnnotationStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, a := range s.annotations {
+		if a.ID != id {
+			continue
+		}
+
+		remaining := append(append([]models.Annotation{}, s.annotations[:i]...), s.annotations[i+1:]...)
+		if err := saveJSONFile(s.path, remaining); err != nil {
+			return err
+		}
+		s.annotations = remaining
+		return nil
+	}
+
+	return models.ErrAnnotationNotFound
+}
+
+func validateAnnotation(a models.Annotation) error {
+	if _, err := time.Parse("2006-01-02", a.Date); err != nil {
+		return fmt.Errorf("%w: date must be YYYY-MM-DD", models.ErrInvalidAnnotation)
+	}
+	if a.EndDate != "" {
+		if _, err := time.Parse("2006-01-02", a.EndDate); err != nil {
+			return fmt.Errorf("%w: end_date must be YYYY-MM-DD", models.ErrInvalidAnnotation)
+		}
+		if a.EndDate < a.Date {
+			return fmt.Errorf("%w: end_date must not be before date", models.ErrInvalidAnnotation)
+		}
+	}
+	return nil
+}
+
+// newID returns a random 16-character hex identifier
+func newID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate id: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
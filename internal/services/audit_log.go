@@ -0,0 +1,96 @@
+package services
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/pkg/logger"
+)
+
+// AuditLog appends security-relevant events to a JSON Lines file in the state
+// directory. Entries are only ever appended, never rewritten, so the file can
+// be shipped to a log collector as is.
+type AuditLog struct {
+	mu     sync.Mutex
+	path   string
+	logger logger.Logger
+}
+
+func NewAuditLog(stateDir string, logger logger.Logger) (*AuditLog, error) {
+	if err := os.MkdirAll(stateDir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create state directory: %w", err)
+	}
+
+	return &AuditLog{
+		path:   filepath.Join(stateDir, "audit.log"),
+		logger: logger,
+	}, nil
+}
+
+// Record appends an event, stamping it with the current time if unset
+func (a *AuditLog) Record(event models.AuditEvent) error {
+	if event.Time.IsZero() {
+		event.Time = time.Now().UTC()
+	}
+
+	line, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit event: %w", err)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	file, err := os.OpenFile(a.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return file.Sync()
+}
+
+// List returns up to limit most recent events, newest first
+func (a *AuditLog) List(limit int) ([]models.AuditEvent, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	file, err := os.Open(a.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return []models.AuditEvent{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer file.Close()
+
+	var events []models.AuditEvent
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var event models.AuditEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			a.logger.Warn("Skipping malformed audit log entry", "error", err)
+			continue
+		}
+		events = append(events, event)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+
+	results := make([]models.AuditEvent, 0, len(events))
+	for i := len(events) - 1; i >= 0 && (limit <= 0 || len(results) < limit); i-- {
+		results = append(results, events[i])
+	}
+	return results, nil
+}
@@ -0,0 +1,153 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"analytics-dashboard-api/internal/config"
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/pkg/logger"
+)
+
+// Reasons a backup is retained, as listed in models.StoredBackup.KeptBy
+const (
+	keepReasonLast    = "last"
+	keepReasonMonthly = "monthly"
+)
+
+// BackupRetention applies the retention policy to the backup directory: keep
+// the newest KeepLast backups plus the newest backup of each of the last
+// KeepMonthly months that have one. Everything else is removed by Prune.
+type BackupRetention struct {
+	cfg    config.BackupConfig
+	jobs   *JobQueue
+	logger logger.Logger
+}
+
+// NewBackupRetention prunes through jobs so a backup is never removed while a
+// restore is reading it
+func NewBackupRetention(cfg config.BackupConfig, jobs *JobQueue, logger logger.Logger) *BackupRetention {
+	return &BackupRetention{cfg: cfg, jobs: jobs, logger: logger}
+}
+
+// Policy returns the configured retention rules
+func (r *BackupRetention) Policy() models.BackupRetentionPolicy {
+	return models.BackupRetentionPolicy{
+		KeepLast:    r.cfg.KeepLast,
+		KeepMonthly: r.cfg.KeepMonthly,
+		Interval:    r.cfg.PruneInterval.String(),
+	}
+}
+
+// List returns the backups in the backup directory, newest first, with the
+// rules that keep each one
+func (r *BackupRetention) List() ([]models.StoredBackup, error) {
+	names, err := listBackups(r.cfg.Dir)
+	if err != nil {
+		return nil, err
+	}
+	kept := retainedBackups(names, r.cfg.KeepLast, r.cfg.KeepMonthly)
+
+	backups := make([]models.StoredBackup, 0, len(names))
+	for i := len(names) - 1; i >= 0; i-- {
+		name := names[i]
+		createdAt, _ := time.Parse(backupNameLayout, name)
+		keptBy := kept[name]
+		if keptBy == nil {
+			keptBy = []string{}
+		}
+		backups = append(backups, models.StoredBackup{
+			Name:      name,
+			SizeBytes: dirSize(filepath.Join(r.cfg.Dir, name)),
+			CreatedAt: createdAt,
+			KeptBy:    keptBy,
+			Prune:     r.enabled() && len(keptBy) == 0,
+		})
+	}
+	return backups, nil
+}
+
+// Prune removes the backups the policy does not keep and returns their names
+func (r *BackupRetention) Prune(ctx context.Context) ([]string, error) {
+	if !r.enabled() {
+		return nil, nil
+	}
+
+	var removed []string
+	err := r.jobs.Run(ctx, "backup_prune", PriorityScheduled, func(ctx context.Context) error {
+		names, err := listBackups(r.cfg.Dir)
+		if err != nil {
+			return err
+		}
+		kept := retainedBackups(names, r.cfg.KeepLast, r.cfg.KeepMonthly)
+
+		for _, name := range names {
+			if len(kept[name]) > 0 {
+				continue
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			dir := filepath.Join(r.cfg.Dir, name)
+			size := dirSize(dir)
+			if err := os.RemoveAll(dir); err != nil {
+				return fmt.Errorf("failed to remove backup %s: %w", name, err)
+			}
+			removed = append(removed, name)
+			r.logger.Info("Backup pruned", "name", name, "size_bytes", size)
+		}
+		return nil
+	})
+	return removed, err
+}
+
+// Start prunes on a schedule until ctx is cancelled. A zero interval or a
+// policy that keeps everything disables it.
+func (r *BackupRetention) Start(ctx context.Context, interval time.Duration) {
+	if interval <= 0 || !r.enabled() {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := r.Prune(ctx); err != nil && ctx.Err() == nil {
+					r.logger.Error("Scheduled backup pruning failed", "error", err)
+				}
+			}
+		}
+	}()
+}
+
+func (r *BackupRetention) enabled() bool {
+	return r.cfg.KeepLast > 0 || r.cfg.KeepMonthly > 0
+}
+
+// retainedBackups maps each backup the policy keeps to the rules keeping it.
+// names must be sorted oldest first, as listBackups returns them.
+func retainedBackups(names []string, keepLast, keepMonthly int) map[string][]string {
+	kept := map[string][]string{}
+	months := map[string]bool{}
+	for i := len(names) - 1; i >= 0; i-- {
+		name := names[i]
+		if len(names)-i <= keepLast {
+			kept[name] = append(kept[name], keepReasonLast)
+		}
+		// Names start with yyyymm, so the newest backup of a month is seen first
+		month := name[:6]
+		if !months[month] && len(months) < keepMonthly {
+			months[month] = true
+			kept[name] = append(kept[name], keepReasonMonthly)
+		}
+	}
+	return kept
+}
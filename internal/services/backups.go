@@ -0,0 +1,89 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+
+	"analytics-dashboard-api/internal/models"
+)
+
+// backupNameFormat names snapshot directories so they sort chronologically.
+// Milliseconds keep back-to-back backups apart.
+const backupNameFormat = "20060102T150405.000Z"
+
+// backupNameLayout parses backup names. time.Parse accepts the milliseconds
+// without the layout naming them, so names from before they were added
+// parse too.
+const backupNameLayout = "20060102T150405Z"
+
+var backupNamePattern = regexp.MustCompile(`^\d{8}T\d{6}(\.\d{3})?Z$`)
+
+// listBackups returns the names of the backups in backupDir, oldest first
+func listBackups(backupDir string) ([]string, error) {
+	entries, err := os.ReadDir(backupDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to list backups: %w", err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() && backupNamePattern.MatchString(entry.Name()) {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// latestBackup returns the name of the newest backup in backupDir
+func latestBackup(backupDir string) (string, error) {
+	names, err := listBackups(backupDir)
+	if err != nil {
+		return "", err
+	}
+	if len(names) == 0 {
+		return "", models.ErrBackupNotFound
+	}
+	return names[len(names)-1], nil
+}
+
+// placeBackup renames the complete backup in tmp to its name under
+// backupDir, taken from created. A name already taken moves on to the next
+// millisecond, so a backup never replaces another.
+func placeBackup(backupDir, tmp string, created time.Time) (string, string, error) {
+	for {
+		name := created.Format(backupNameFormat)
+		dir := filepath.Join(backupDir, name)
+		if _, err := os.Stat(dir); err == nil {
+			created = created.Add(time.Millisecond)
+			continue
+		}
+		if err := os.Rename(tmp, dir); err != nil {
+			return "", "", fmt.Errorf("failed to store backup %s: %w", name, err)
+		}
+		return name, dir, nil
+	}
+}
+
+// dirSize returns the total size of regular files directly inside dir
+func dirSize(dir string) int64 {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0
+	}
+
+	var size int64
+	for _, entry := range entries {
+		if info, err := entry.Info(); err == nil && info.Mode().IsRegular() {
+			size += info.Size()
+		}
+	}
+	return size
+}
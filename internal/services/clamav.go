@@ -0,0 +1,108 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"analytics-dashboard-api/internal/models"
+)
+
+// clamAVChunkSize is the size of the INSTREAM chunks sent to clamd
+const clamAVChunkSize = 64 << 10
+
+// clamAVScanner streams uploads to a clamd daemon with the INSTREAM command.
+// clamd refuses streams over its StreamMaxLength, which then fails the scan.
+type clamAVScanner struct {
+	network string
+	address string
+	timeout time.Duration
+}
+
+// newClamAVScanner accepts "unix:/path/clamd.sock", "tcp:host:port", a bare
+// socket path or a bare host:port
+func newClamAVScanner(address string, timeout time.Duration) clamAVScanner {
+	network := "tcp"
+	switch {
+	case strings.HasPrefix(address, "unix:"):
+		network, address = "unix", strings.TrimPrefix(address, "unix:")
+	case strings.HasPrefix(address, "tcp:"):
+		address = strings.TrimPrefix(address, "tcp:")
+	case strings.HasPrefix(address, "/"):
+		network = "unix"
+	}
+	return clamAVScanner{network: network, address: address, timeout: timeout}
+}
+
+func (clamAVScanner) Name() string {
+	return "clamav"
+}
+
+func (s clamAVScanner) Scan(ctx context.Context, file models.UploadedFile) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	f, err := os.Open(file.Path)
+	if err != nil {
+		return fmt.Errorf("failed to open upload: %w", err)
+	}
+	defer f.Close()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, s.network, s.address)
+	if err != nil {
+		return fmt.Errorf("failed to reach clamd: %w", err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return fmt.Errorf("failed to send to clamd: %w", err)
+	}
+	buf := make([]byte, 4+clamAVChunkSize)
+	for {
+		n, err := f.Read(buf[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(buf[:4], uint32(n))
+			if _, err := conn.Write(buf[:4+n]); err != nil {
+				return fmt.Errorf("failed to stream to clamd: %w", err)
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read upload: %w", err)
+		}
+	}
+	// A zero-length chunk ends the stream
+	if _, err := conn.Write([]byte{0, 0, 0, 0}); err != nil {
+		return fmt.Errorf("failed to stream to clamd: %w", err)
+	}
+
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && reply == "" {
+		return fmt.Errorf("failed to read clamd reply: %w", err)
+	}
+	reply = strings.TrimSpace(strings.TrimRight(reply, "\x00"))
+
+	// Replies are "stream: OK", "stream: <signature> FOUND" or "<message> ERROR"
+	result := strings.TrimPrefix(reply, "stream: ")
+	switch {
+	case result == "OK":
+		return nil
+	case strings.HasSuffix(result, " FOUND"):
+		return &models.ScanRejection{Scanner: "clamav", Reason: "malware detected: " + strings.TrimSuffix(result, " FOUND")}
+	default:
+		return fmt.Errorf("clamd: %s", reply)
+	}
+}
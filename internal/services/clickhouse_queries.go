@@ -0,0 +1,531 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"analytics-dashboard-api/internal/models"
+)
+
+// clickhouseGroupings mirrors MetricGroupings in ClickHouse SQL
+var clickhouseGroupings = map[string]string{
+	"month":    "formatDateTime(transaction_date, '%Y-%m')",
+	"country":  "country",
+	"region":   "region",
+	"category": "category",
+	"product":  "product_name",
+}
+
+// GetSegmentBreakdown returns revenue and retention per customer segment.
+// Customers missing from the customers table are grouped as "Unknown".
+func (s *ClickHouseService) GetSegmentBreakdown(ctx context.Context, opts models.QueryOptions) ([]models.SegmentRevenue, error) {
+	source, params := s.source(opts)
+	// ClickHouse fills unmatched LEFT JOIN columns with '' rather than NULL
+	rows, err := s.query(ctx, fmt.Sprintf(`
+		WITH per_customer AS (
+			SELECT
+				if(c.segment = '', 'Unknown', c.segment) AS segment,
+				t.user_id AS user_id,
+				sum(t.total_price) AS revenue,
+				count() AS orders,
+				uniqExact(formatDateTime(t.transaction_date, '%%Y-%%m')) AS active_months
+			FROM %s AS t
+			LEFT JOIN %s AS c ON c.user_id = t.user_id
+			GROUP BY segment, user_id
+		)
+		SELECT
+			segment,
+			%s AS total_revenue,
+			toInt64(round(sum(orders) * {scale:Float64})) AS transaction_count,
+			count() AS customer_count,
+			toInt64(round(sum(revenue) * 100 / sum(orders))) AS avg_order_value,
+			avg(active_months > 1) AS retention_rate
+		FROM per_customer
+		GROUP BY segment
+		ORDER BY total_revenue DESC
+	`, source, s.customers, chMoneyCents("sum(revenue)", opts)), params)
+	if err != nil {
+		return nil, queryError("failed to query segment breakdown", err)
+	}
+
+	var results []models.SegmentRevenue
+	for _, row := range rows {
+		var sr models.SegmentRevenue
+		err := scanRow(row,
+			&sr.Segment,
+			(*int64)(&sr.TotalRevenue),
+			&sr.TransactionCount,
+			&sr.CustomerCount,
+			(*int64)(&sr.AvgOrderValue),
+			&sr.RetentionRate,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan segment breakdown: %w", err)
+		}
+		results = append(results, sr)
+	}
+
+	return results, nil
+}
+
+// GetTopCustomers returns the limit customers who spent the most, with
+// their order count and average order value. Ties go to the lower user ID.
+func (s *ClickHouseService) GetTopCustomers(ctx context.Context, opts models.QueryOptions, limit int) ([]models.CustomerSpend, error) {
+	source, params := s.source(opts)
+	params["limit"] = strconv.Itoa(limit)
+	rows, err := s.query(ctx, fmt.Sprintf(`
+		SELECT
+			user_id,
+			toInt64(sum(total_price) * 100) AS total_spend,
+			count() AS order_count,
+			toInt64(round(sum(total_price) * 100 / count())) AS avg_order_value
+		FROM %s
+		GROUP BY user_id
+		ORDER BY total_spend DESC, user_id
+		LIMIT {limit:UInt32}
+	`, source), params)
+	if err != nil {
+		return nil, queryError("failed to query top customers", err)
+	}
+
+	var results []models.CustomerSpend
+	for _, row := range rows {
+		var cs models.CustomerSpend
+		err := scanRow(row,
+			&cs.UserID,
+			(*int64)(&cs.TotalSpend),
+			&cs.OrderCount,
+			(*int64)(&cs.AvgOrderValue),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan top customers: %w", err)
+		}
+		results = append(results, cs)
+	}
+
+	return results, nil
+}
+
+// GetRepeatPurchase counts the customers with more than one order across
+// the whole dataset
+func (s *ClickHouseService) GetRepeatPurchase(ctx context.Context) (models.RepeatPurchase, error) {
+	var repeat models.RepeatPurchase
+	err := s.queryRow(ctx, fmt.Sprintf(`
+		SELECT count(), countIf(orders > 1)
+		FROM (
+			SELECT user_id, count() AS orders
+			FROM %s
+			GROUP BY user_id
+		)
+	`, s.transactions), nil, &repeat.Customers, &repeat.RepeatCustomers)
+	if err != nil {
+		return repeat, queryError("failed to query repeat purchases", err)
+	}
+	if repeat.Customers > 0 {
+		repeat.Rate = float64(repeat.RepeatCustomers) / float64(repeat.Customers)
+	}
+	return repeat, nil
+}
+
+// GetBaseMetrics computes every base metric, either for the whole (filtered)
+// dataset or per group when groupBy names one of MetricGroupings
+func (s *ClickHouseService) GetBaseMetrics(ctx context.Context, opts models.QueryOptions, groupBy string) ([]models.MetricRow, error) {
+	groupExpr := "''"
+	groupClause := ""
+	if groupBy != "" {
+		column, ok := clickhouseGroupings[groupBy]
+		if !ok {
+			return nil, fmt.Errorf("unsupported grouping %q", groupBy)
+		}
+		groupExpr = column
+		groupClause = "GROUP BY grp ORDER BY grp"
+	}
+
+	source, params := s.source(opts)
+	rows, err := s.query(ctx, fmt.Sprintf(`
+		SELECT
+			toString(%s) AS grp,
+			toFloat64(sum(total_price)) * {scale:Float64} AS revenue,
+			count() * {scale:Float64} AS transactions,
+			toFloat64(sum(quantity)) * {scale:Float64} AS quantity,
+			toFloat64(uniqExact(user_id)) AS customers,
+			toFloat64(uniqExact(product_id)) AS products
+		FROM %s
+		%s
+	`, groupExpr, source, groupClause), params)
+	if err != nil {
+		return nil, queryError("failed to query base metrics", err)
+	}
+
+	var results []models.MetricRow
+	for _, row := range rows {
+		var group string
+		var revenue, transactions, quantity, customers, products float64
+		if err := scanRow(row, &group, &revenue, &transactions, &quantity, &customers, &products); err != nil {
+			return nil, fmt.Errorf("failed to scan base metrics: %w", err)
+		}
+		results = append(results, models.MetricRow{
+			Group: group,
+			Values: map[string]float64{
+				"revenue":      revenue,
+				"transactions": transactions,
+				"quantity":     quantity,
+				"customers":    customers,
+				"products":     products,
+			},
+		})
+	}
+
+	return results, nil
+}
+
+// clickhousePeriods maps time series granularities to the ClickHouse
+// function returning the first day of the period
+var clickhousePeriods = map[string]string{
+	models.GranularityDay:   "toDate",
+	models.GranularityWeek:  "toMonday",
+	models.GranularityMonth: "toStartOfMonth",
+}
+
+// GetTimeSeries returns revenue, orders and units per day, week or month
+// with sales, in date order
+func (s *ClickHouseService) GetTimeSeries(ctx context.Context, opts models.QueryOptions, granularity string) ([]models.TimeBucket, error) {
+	period, ok := clickhousePeriods[granularity]
+	if !ok {
+		return nil, fmt.Errorf("%w: unsupported granularity %q", models.ErrInvalidTimeSeries, granularity)
+	}
+
+	source, params := s.source(opts)
+	rows, err := s.query(ctx, fmt.Sprintf(`
+		SELECT
+			formatDateTime(%s(transaction_date), '%%Y-%%m-%%d') AS period,
+			%s AS revenue,
+			toInt64(round(count() * {scale:Float64})) AS orders,
+			toInt64(round(sum(quantity) * {scale:Float64})) AS units
+		FROM %s
+		GROUP BY period
+		ORDER BY period
+	`, period, chMoneyCents("sum(total_price)", opts), source), params)
+	if err != nil {
+		return nil, queryError("failed to query time series", err)
+	}
+
+	var results []models.TimeBucket
+	for _, row := range rows {
+		var bucket models.TimeBucket
+		if err := scanRow(row, &bucket.Start, (*int64)(&bucket.Revenue), &bucket.Orders, &bucket.Units); err != nil {
+			return nil, fmt.Errorf("failed to scan time series: %w", err)
+		}
+		results = append(results, bucket)
+	}
+	return results, nil
+}
+
+// GetAlertMetrics returns the dataset metrics alert rules are evaluated
+// against, for the latest day and month present in the data
+func (s *ClickHouseService) GetAlertMetrics(ctx context.Context) (map[string]float64, error) {
+	var totalRecords, dailyTransactions int64
+	var totalRevenue, dailyRevenue, monthlyRevenue float64
+
+	err := s.queryRow(ctx, fmt.Sprintf(`
+		WITH (SELECT max(transaction_date) FROM %s) AS latest_day
+		SELECT
+			count(),
+			toFloat64(sum(total_price)),
+			toFloat64(sumIf(total_price, transaction_date = latest_day)),
+			countIf(transaction_date = latest_day),
+			toFloat64(sumIf(total_price, toStartOfMonth(transaction_date) = toStartOfMonth(latest_day)))
+		FROM %s
+	`, s.transactions, s.transactions), nil, &totalRecords, &totalRevenue, &dailyRevenue, &dailyTransactions, &monthlyRevenue)
+	if err != nil {
+		return nil, queryError("failed to query alert metrics", err)
+	}
+
+	return map[string]float64{
+		"total_records":      float64(totalRecords),
+		"total_revenue":      totalRevenue,
+		"daily_revenue":      dailyRevenue,
+		"daily_transactions": float64(dailyTransactions),
+		"monthly_revenue":    monthlyRevenue,
+	}, nil
+}
+
+// productSearch matches id, name and brand case-insensitively
+const productSearch = `{search:String} = ''
+			OR product_id ILIKE concat('%', {search:String}, '%')
+			OR product_name ILIKE concat('%', {search:String}, '%')
+			OR brand ILIKE concat('%', {search:String}, '%')`
+
+// ListProducts returns a page of the product catalog, optionally filtered by a
+// case-insensitive search over id, name and brand
+func (s *ClickHouseService) ListProducts(ctx context.Context, search string, limit, offset int) ([]models.Product, error) {
+	rows, err := s.query(ctx, fmt.Sprintf(`
+		SELECT product_id, product_name, category, brand, supplier
+		FROM %s
+		WHERE %s
+		ORDER BY product_id
+		LIMIT {limit:UInt32} OFFSET {offset:UInt32}
+	`, s.products, productSearch), chParams{
+		"search": search,
+		"limit":  strconv.Itoa(limit),
+		"offset": strconv.Itoa(offset),
+	})
+	if err != nil {
+		return nil, queryError("failed to query products", err)
+	}
+
+	var results []models.Product
+	for _, row := range rows {
+		var p models.Product
+		if err := scanRow(row, &p.ProductID, &p.ProductName, &p.Category, &p.Brand, &p.Supplier); err != nil {
+			return nil, fmt.Errorf("failed to scan products: %w", err)
+		}
+		results = append(results, p)
+	}
+
+	return results, nil
+}
+
+// CountProducts returns the number of catalog entries matching search
+func (s *ClickHouseService) CountProducts(ctx context.Context, search string) (int, error) {
+	var count int
+	err := s.queryRow(ctx, fmt.Sprintf("SELECT count() FROM %s WHERE %s", s.products, productSearch),
+		chParams{"search": search}, &count)
+	return count, err
+}
+
+// GetProduct returns a catalog entry together with its sales summary
+func (s *ClickHouseService) GetProduct(ctx context.Context, productID string) (*models.Product, *models.ProductSales, error) {
+	var p models.Product
+	err := s.queryRow(ctx, fmt.Sprintf(`
+		SELECT product_id, product_name, category, brand, supplier
+		FROM %s
+		WHERE product_id = {id:String}
+		LIMIT 1
+	`, s.products), chParams{"id": productID}, &p.ProductID, &p.ProductName, &p.Category, &p.Brand, &p.Supplier)
+	if errors.Is(err, errNoRows) {
+		return nil, nil, models.ErrProductNotFound
+	}
+	if err != nil {
+		return nil, nil, queryError("failed to query product", err)
+	}
+
+	var sales models.ProductSales
+	err = s.queryRow(ctx, fmt.Sprintf(`
+		SELECT toInt64(sum(total_price) * 100), sum(quantity), count()
+		FROM %s
+		WHERE product_id = {id:String}
+	`, s.transactions), chParams{"id": productID}, (*int64)(&sales.TotalRevenue), &sales.UnitsSold, &sales.TransactionCount)
+	if err != nil {
+		return nil, nil, queryError("failed to query product sales", err)
+	}
+
+	return &p, &sales, nil
+}
+
+// GetPriceHistory returns the monthly average selling price of a product in
+// month order. A product without any transactions is not found.
+func (s *ClickHouseService) GetPriceHistory(ctx context.Context, productID string, opts models.QueryOptions) ([]models.PricePoint, error) {
+	source, params := s.source(opts)
+	params["id"] = productID
+	rows, err := s.query(ctx, fmt.Sprintf(`
+		SELECT
+			formatDateTime(transaction_date, '%%Y-%%m') AS month,
+			%s AS revenue,
+			toInt64(round(sum(quantity) * {scale:Float64})) AS units_sold,
+			toInt64(round(count() * {scale:Float64})) AS transactions
+		FROM %s
+		WHERE product_id = {id:String}
+		GROUP BY month
+		ORDER BY month
+	`, chMoneyCents("sum(total_price)", opts), source), params)
+	if err != nil {
+		return nil, queryError("failed to query price history", err)
+	}
+
+	var results []models.PricePoint
+	for _, row := range rows {
+		var p models.PricePoint
+		if err := scanRow(row, &p.Month, (*int64)(&p.Revenue), &p.UnitsSold, &p.Transactions); err != nil {
+			return nil, fmt.Errorf("failed to scan price history: %w", err)
+		}
+		results = append(results, p)
+	}
+
+	if len(results) == 0 {
+		// Tell an unknown product from one without sales in the range
+		var count int
+		err := s.queryRow(ctx, fmt.Sprintf(`
+			SELECT count() FROM %s WHERE product_id = {id:String}
+		`, s.transactions), chParams{"id": productID}, &count)
+		if err != nil {
+			return nil, queryError("failed to query product", err)
+		}
+		if count == 0 {
+			return nil, models.ErrProductNotFound
+		}
+	}
+
+	completePriceHistory(results)
+	return results, nil
+}
+
+// GetStockLevels returns every product with transactions under opts, with
+// the units sold since the given day and the stock_quantity of its latest
+// transaction
+func (s *ClickHouseService) GetStockLevels(ctx context.Context, opts models.QueryOptions, since time.Time) ([]models.StockLevel, error) {
+	source, params := s.source(opts)
+	params["since"] = since.Format("2006-01-02")
+	rows, err := s.query(ctx, fmt.Sprintf(`
+		SELECT
+			product_id,
+			argMax(product_name, transaction_date),
+			argMax(category, transaction_date),
+			toInt64(round(sumIf(quantity, transaction_date >= {since:Date}) * {scale:Float64})),
+			argMax(stock_quantity, transaction_date)
+		FROM %s
+		GROUP BY product_id
+		ORDER BY product_id
+	`, source), params)
+	if err != nil {
+		return nil, queryError("failed to query stock levels", err)
+	}
+
+	var results []models.StockLevel
+	for _, row := range rows {
+		var level models.StockLevel
+		if err := scanRow(row, &level.ProductID, &level.ProductName, &level.Category, &level.UnitsSold, &level.Stock); err != nil {
+			return nil, fmt.Errorf("failed to scan stock levels: %w", err)
+		}
+		results = append(results, level)
+	}
+	return results, nil
+}
+
+// ListDimensionValues returns a page of distinct values for a dimension with
+// transaction counts, most frequent first
+func (s *ClickHouseService) ListDimensionValues(ctx context.Context, dimension, search string, limit, offset int) ([]models.DimensionValue, error) {
+	column, ok := dimensionColumns[dimension]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", models.ErrUnknownDimension, dimension)
+	}
+
+	rows, err := s.query(ctx, fmt.Sprintf(`
+		SELECT %s AS value, count() AS count
+		FROM %s
+		WHERE {search:String} = '' OR %s ILIKE concat('%%', {search:String}, '%%')
+		GROUP BY value
+		ORDER BY count DESC, value
+		LIMIT {limit:UInt32} OFFSET {offset:UInt32}
+	`, column, s.transactions, column), chParams{
+		"search": search,
+		"limit":  strconv.Itoa(limit),
+		"offset": strconv.Itoa(offset),
+	})
+	if err != nil {
+		return nil, queryError("failed to query "+dimension+" values", err)
+	}
+
+	var results []models.DimensionValue
+	for _, row := range rows {
+		var dv models.DimensionValue
+		if err := scanRow(row, &dv.Value, &dv.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan %s values: %w", dimension, err)
+		}
+		results = append(results, dv)
+	}
+
+	return results, nil
+}
+
+// CountDimensionValues returns the number of distinct values matching search
+func (s *ClickHouseService) CountDimensionValues(ctx context.Context, dimension, search string) (int, error) {
+	column, ok := dimensionColumns[dimension]
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", models.ErrUnknownDimension, dimension)
+	}
+
+	var count int
+	err := s.queryRow(ctx, fmt.Sprintf(`
+		SELECT uniqExact(%s)
+		FROM %s
+		WHERE {search:String} = '' OR %s ILIKE concat('%%', {search:String}, '%%')
+	`, column, s.transactions, column), chParams{"search": search}, &count)
+	return count, err
+}
+
+// GetDataProfile relies on DuckDB's SUMMARIZE
+func (s *ClickHouseService) GetDataProfile(context.Context, int) (*models.DataProfile, error) {
+	return nil, models.ErrNotSupported
+}
+
+// GetPartitions describes the transactions month by month. How ClickHouse
+// prunes them depends on the table's own PARTITION BY and ORDER BY keys.
+func (s *ClickHouseService) GetPartitions(ctx context.Context) ([]models.Partition, error) {
+	rows, err := s.query(ctx, fmt.Sprintf(`
+		SELECT
+			formatDateTime(transaction_date, '%%Y-%%m') AS month,
+			count(),
+			formatDateTime(min(transaction_date), '%%Y-%%m-%%d'),
+			formatDateTime(max(transaction_date), '%%Y-%%m-%%d')
+		FROM %s
+		GROUP BY month
+		ORDER BY month
+	`, s.transactions), nil)
+	if err != nil {
+		return nil, queryError("failed to query partitions", err)
+	}
+
+	results := make([]models.Partition, 0, len(rows))
+	for _, row := range rows {
+		var p models.Partition
+		if err := scanRow(row, &p.Month, &p.Rows, &p.From, &p.To); err != nil {
+			return nil, fmt.Errorf("failed to scan partition: %w", err)
+		}
+		results = append(results, p)
+	}
+	return results, nil
+}
+
+// ListOutliers has nothing to list: ClickHouse tables are loaded elsewhere,
+// without outlier detection
+func (s *ClickHouseService) ListOutliers(context.Context, int, int) ([]models.Outlier, error) {
+	return nil, models.ErrNotSupported
+}
+
+// ExcludeTransactions is not supported: rows are not loaded through the API,
+// so there is no load to leave flagged transactions out of
+func (s *ClickHouseService) ExcludeTransactions(ids []string) error {
+	if len(ids) > 0 {
+		return models.ErrNotSupported
+	}
+	return nil
+}
+
+// Targets and backups belong to the embedded pipeline; ClickHouse deployments
+// manage their own tables and snapshots
+
+func (s *ClickHouseService) LoadTargets(context.Context, string) (*models.TableLoadResult, error) {
+	return nil, models.ErrNotSupported
+}
+
+func (s *ClickHouseService) GetTargetVariance(context.Context, string) ([]models.TargetVariance, error) {
+	return nil, models.ErrNotSupported
+}
+
+func (s *ClickHouseService) Backup(context.Context, string) (*models.BackupInfo, error) {
+	return nil, models.ErrNotSupported
+}
+
+func (s *ClickHouseService) Restore(context.Context, string, string) (*models.BackupInfo, error) {
+	return nil, models.ErrNotSupported
+}
+
+// Exports are written by the embedded pipeline; ClickHouse can export its
+// tables itself
+func (s *ClickHouseService) ExportTransactions(context.Context, models.QueryOptions, string, string, func(int64, int64)) (int64, error) {
+	return 0, models.ErrNotSupported
+}
@@ -0,0 +1,527 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"analytics-dashboard-api/internal/config"
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/internal/timing"
+	"analytics-dashboard-api/pkg/logger"
+)
+
+// identifierPattern accepts plain and database-qualified table names
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// ClickHouseService serves analytics from tables that already exist in
+// ClickHouse, using its HTTP interface. There is no CSV pipeline: "loading"
+// only verifies the tables are reachable and records their coverage.
+type ClickHouseService struct {
+	client       *http.Client
+	endpoint     string
+	database     string
+	user         string
+	password     string
+	transactions string
+	products     string
+	customers    string
+	logger       logger.Logger
+
+	coverageMu sync.RWMutex
+	coverage   models.DataCoverage
+}
+
+func NewClickHouseService(cfg config.ClickHouseConfig, logger logger.Logger) (*ClickHouseService, error) {
+	if _, err := url.Parse(cfg.URL); err != nil {
+		return nil, fmt.Errorf("invalid ClickHouse URL: %w", err)
+	}
+	for _, table := range []string{cfg.TransactionsTable, cfg.ProductsTable, cfg.CustomersTable} {
+		if !identifierPattern.MatchString(table) {
+			return nil, fmt.Errorf("invalid ClickHouse table name %q", table)
+		}
+	}
+
+	return &ClickHouseService{
+		client:       &http.Client{Timeout: cfg.Timeout},
+		endpoint:     cfg.URL,
+		database:     cfg.Database,
+		user:         cfg.User,
+		password:     cfg.Password,
+		transactions: cfg.TransactionsTable,
+		products:     cfg.ProductsTable,
+		customers:    cfg.CustomersTable,
+		logger:       logger,
+	}, nil
+}
+
+func (s *ClickHouseService) Close() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
+
+// Ping checks that ClickHouse answers queries
+func (s *ClickHouseService) Ping(ctx context.Context) error {
+	if _, err := s.query(ctx, "SELECT 1", nil); err != nil {
+		return fmt.Errorf("ClickHouse is unreachable: %w", err)
+	}
+	return nil
+}
+
+// chParams holds values for {name:Type} query placeholders
+type chParams map[string]string
+
+// chResult is a JSONCompact response. Totals holds the extra row of a
+// GROUP BY ... WITH TOTALS query.
+type chResult struct {
+	Data   [][]json.RawMessage `json:"data"`
+	Totals []json.RawMessage   `json:"totals"`
+}
+
+// query runs a SELECT and returns its rows in JSONCompact form
+func (s *ClickHouseService) query(ctx context.Context, query string, params chParams) ([][]json.RawMessage, error) {
+	result, err := s.run(ctx, query, params)
+	if err != nil {
+		return nil, err
+	}
+	return result.Data, nil
+}
+
+// run sends a query and decodes the whole JSONCompact response
+func (s *ClickHouseService) run(ctx context.Context, query string, params chParams) (*chResult, error) {
+	defer timing.FromContext(ctx).Since(timing.PhaseDB, "clickhouse", time.Now())
+
+	endpoint, err := url.Parse(s.endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	values := endpoint.Query()
+	values.Set("database", s.database)
+	values.Set("default_format", "JSONCompact")
+	values.Set("output_format_json_quote_64bit_integers", "0")
+	values.Set("output_format_json_quote_decimals", "0")
+	for name, value := range params {
+		values.Set("param_"+name, value)
+	}
+	endpoint.RawQuery = values.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.String(), strings.NewReader(query))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-ClickHouse-User", s.user)
+	if s.password != "" {
+		req.Header.Set("X-ClickHouse-Key", s.password)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("clickhouse returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var result chResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode clickhouse response: %w", err)
+	}
+	return &result, nil
+}
+
+// queryRow runs a query expected to return exactly one row
+func (s *ClickHouseService) queryRow(ctx context.Context, query string, params chParams, dest ...interface{}) error {
+	rows, err := s.query(ctx, query, params)
+	if err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		return errNoRows
+	}
+	return scanRow(rows[0], dest...)
+}
+
+var errNoRows = errors.New("no rows in result set")
+
+// scanRow decodes the columns of a JSONCompact row into dest. Money columns
+// are scanned through *int64 since ClickHouse returns integer cents.
+func scanRow(row []json.RawMessage, dest ...interface{}) error {
+	if len(row) != len(dest) {
+		return fmt.Errorf("expected %d columns, got %d", len(dest), len(row))
+	}
+	for i, column := range row {
+		if err := json.Unmarshal(column, dest[i]); err != nil {
+			return fmt.Errorf("column %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// LoadFromFile ignores the files: the data already lives in ClickHouse. It
+// checks the transactions table is reachable and refreshes coverage.
+func (s *ClickHouseService) LoadFromFile(ctx context.Context, _ string, _ ...string) error {
+	s.logger.Info("Using ClickHouse tables, data file path ignored", "table", s.transactions)
+	return s.refreshCoverage(ctx)
+}
+
+// InspectSource is not supported: ClickHouse tables are loaded outside the API
+func (s *ClickHouseService) InspectSource(context.Context, string, string) (*models.SourceInspection, error) {
+	return nil, models.ErrNotSupported
+}
+
+// Rollback is not supported: ClickHouse keeps no previous version of its tables
+func (s *ClickHouseService) Rollback(context.Context) (*models.DataSnapshot, error) {
+	return nil, models.ErrNotSupported
+}
+
+func (s *ClickHouseService) refreshCoverage(ctx context.Context) error {
+	var from, to string
+	var records int
+	err := s.queryRow(ctx, fmt.Sprintf(`
+		SELECT toString(min(transaction_date)), toString(max(transaction_date)), count()
+		FROM %s
+	`, s.transactions), nil, &from, &to, &records)
+	if err != nil {
+		return queryError("failed to query data coverage", err)
+	}
+
+	coverage := models.DataCoverage{
+		Records:  records,
+		LoadedAt: time.Now().UTC(),
+	}
+	// min/max of an empty table are the epoch, not real dates
+	if records > 0 {
+		coverage.From = from
+		coverage.To = to
+	}
+
+	s.coverageMu.Lock()
+	s.coverage = coverage
+	s.coverageMu.Unlock()
+	return nil
+}
+
+// DataCoverage returns the coverage recorded by the most recent load
+func (s *ClickHouseService) DataCoverage() models.DataCoverage {
+	s.coverageMu.RLock()
+	defer s.coverageMu.RUnlock()
+	return s.coverage
+}
+
+// source returns the FROM target for a query with the options' sampling and
+// filters applied, and the parameters it binds. ClickHouse only supports
+// SAMPLE on tables with a sampling key, so rows are sampled with rand().
+// The scale parameter is always bound so queries can extrapolate with it.
+// Dimension filters become IN and NOT IN lists of bound parameters, compared
+// as lowerUTF8 values to match like DuckDB's NOCASE.
+func (s *ClickHouseService) source(opts models.QueryOptions) (string, chParams) {
+	params := chParams{"scale": strconv.FormatFloat(opts.ScaleFactor(), 'g', -1, 64)}
+	if !opts.Sampled() && !opts.Filtered() {
+		return s.transactions, params
+	}
+
+	var conditions []string
+	if opts.Sampled() {
+		conditions = append(conditions, "rand() < {sample_threshold:UInt32}")
+		params["sample_threshold"] = strconv.FormatUint(uint64(opts.SampleRate*math.MaxUint32), 10)
+	}
+	for i, filter := range opts.DimensionFilters() {
+		var compared string
+		switch filter.Dimension {
+		case models.FilterSegment:
+			compared = "user_id"
+		case models.FilterCountry, models.FilterRegion, models.FilterCategory:
+			compared = fmt.Sprintf("lowerUTF8(%s)", filter.Dimension)
+		default:
+			continue
+		}
+		for _, list := range []struct {
+			values []string
+			op     string
+			name   string
+		}{{filter.Include, "IN", "in"}, {filter.Exclude, "NOT IN", "not_in"}} {
+			if len(list.values) == 0 {
+				continue
+			}
+			values := make([]string, len(list.values))
+			for j, value := range list.values {
+				name := fmt.Sprintf("filter_%d_%s_%d", i, list.name, j)
+				values[j] = fmt.Sprintf("lowerUTF8({%s:String})", name)
+				params[name] = value
+			}
+			set := "(" + strings.Join(values, ", ") + ")"
+			if filter.Dimension == models.FilterSegment {
+				set = fmt.Sprintf("(SELECT user_id FROM %s WHERE lowerUTF8(segment) IN %s)", s.customers, set)
+			}
+			conditions = append(conditions, fmt.Sprintf("%s %s %s", compared, list.op, set))
+		}
+	}
+	if !opts.From.IsZero() {
+		conditions = append(conditions, "transaction_date >= {from:Date}")
+		params["from"] = opts.From.Format("2006-01-02")
+	}
+	if !opts.To.IsZero() {
+		conditions = append(conditions, "transaction_date < {to:Date}")
+		params["to"] = opts.To.Format("2006-01-02")
+	}
+
+	return fmt.Sprintf("(SELECT * FROM %s WHERE %s)", s.transactions, strings.Join(conditions, " AND ")), params
+}
+
+// chMoneyCents converts a Decimal sum to integer cents. Unsampled totals stay
+// in Decimal arithmetic so they are exact.
+func chMoneyCents(amount string, opts models.QueryOptions) string {
+	if !opts.Sampled() {
+		return fmt.Sprintf("toInt64(%s * 100)", amount)
+	}
+	return fmt.Sprintf("toInt64(round(toFloat64(%s) * 100 * {scale:Float64}))", amount)
+}
+
+// GetCountryRevenue returns a page of revenue per country and product,
+// largest first, with the totals over every pair. WITH TOTALS computes
+// them in the same query, before LIMIT applies.
+func (s *ClickHouseService) GetCountryRevenue(ctx context.Context, opts models.QueryOptions, limit, offset int) ([]models.CountryRevenue, *models.Totals, error) {
+	source, params := s.source(opts)
+	params["limit"] = strconv.Itoa(limit)
+	params["offset"] = strconv.Itoa(offset)
+
+	result, err := s.run(ctx, fmt.Sprintf(`
+		SELECT
+			country,
+			product_name,
+			%s AS total_revenue,
+			toInt64(round(count() * {scale:Float64})) AS transaction_count,
+			toInt64(round(sum(quantity) * {scale:Float64})) AS quantity
+		FROM %s
+		GROUP BY country, product_name WITH TOTALS
+		ORDER BY total_revenue DESC
+		LIMIT {limit:UInt32} OFFSET {offset:UInt32}
+	`, chMoneyCents("sum(total_price)", opts), source), params)
+	if err != nil {
+		return nil, nil, queryError("failed to query country revenue", err)
+	}
+
+	var results []models.CountryRevenue
+	var quantity int
+	for _, row := range result.Data {
+		var cr models.CountryRevenue
+		if err := scanRow(row, &cr.Country, &cr.ProductName, (*int64)(&cr.TotalRevenue), &cr.TransactionCount, &quantity); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan country revenue: %w", err)
+		}
+		results = append(results, cr)
+	}
+
+	totals := &models.Totals{}
+	if result.Totals != nil {
+		var country, product string
+		if err := scanRow(result.Totals, &country, &product, (*int64)(&totals.Revenue), &totals.Transactions, &totals.Quantity); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan country revenue totals: %w", err)
+		}
+	}
+
+	return results, totals, nil
+}
+
+// GetTopProducts returns a page of products ranked by sortBy, largest first
+func (s *ClickHouseService) GetTopProducts(ctx context.Context, opts models.QueryOptions, sortBy string, limit, offset int) ([]models.ProductFrequency, error) {
+	rank, err := rankColumn(productRanks, sortBy)
+	if err != nil {
+		return nil, err
+	}
+	source, params := s.source(opts)
+	params["limit"] = strconv.Itoa(limit)
+	params["offset"] = strconv.Itoa(offset)
+
+	// Rank first, then join the products dimension for just the page
+	rows, err := s.query(ctx, fmt.Sprintf(`
+		SELECT
+			top.product_id,
+			top.product_name,
+			top.purchase_count,
+			top.total_revenue,
+			top.stock_quantity,
+			p.brand,
+			p.supplier
+		FROM (
+			SELECT
+				product_id,
+				product_name,
+				toInt64(round(sum(quantity) * {scale:Float64})) AS purchase_count,
+				%s AS total_revenue,
+				max(stock_quantity) AS stock_quantity
+			FROM %s
+			GROUP BY product_id, product_name
+			ORDER BY %s DESC, product_id, product_name
+			LIMIT {limit:UInt32} OFFSET {offset:UInt32}
+		) AS top
+		LEFT JOIN %s AS p ON p.product_id = top.product_id
+		ORDER BY top.%s DESC, top.product_id, top.product_name
+	`, chMoneyCents("sum(total_price)", opts), source, rank, s.products, rank), params)
+	if err != nil {
+		return nil, queryError("failed to query top products", err)
+	}
+
+	var results []models.ProductFrequency
+	for _, row := range rows {
+		var pf models.ProductFrequency
+		if err := scanRow(row, &pf.ProductID, &pf.ProductName, &pf.PurchaseCount, (*int64)(&pf.TotalRevenue), &pf.StockQuantity, &pf.Brand, &pf.Supplier); err != nil {
+			return nil, fmt.Errorf("failed to scan top products: %w", err)
+		}
+		results = append(results, pf)
+	}
+
+	return results, nil
+}
+
+// GetTopProductsCount returns the number of products GetTopProducts ranks
+func (s *ClickHouseService) GetTopProductsCount(ctx context.Context, opts models.QueryOptions) (int, error) {
+	source, params := s.source(opts)
+	var count int
+	err := s.queryRow(ctx, fmt.Sprintf("SELECT uniqExact(product_id, product_name) FROM %s", source), params, &count)
+	return count, err
+}
+
+func (s *ClickHouseService) GetMonthlySales(ctx context.Context, opts models.QueryOptions) ([]models.MonthlySales, error) {
+	source, params := s.source(opts)
+	rows, err := s.query(ctx, fmt.Sprintf(`
+		SELECT
+			formatDateTime(transaction_date, '%%Y-%%m') AS month,
+			%s AS sales_volume,
+			toInt64(round(sum(quantity) * {scale:Float64})) AS item_count,
+			uniq(user_id) AS unique_customers,
+			uniq(product_id) AS unique_products
+		FROM %s
+		GROUP BY month
+		ORDER BY month
+	`, chMoneyCents("sum(total_price)", opts), source), params)
+	if err != nil {
+		return nil, queryError("failed to query monthly sales", err)
+	}
+
+	var results []models.MonthlySales
+	for _, row := range rows {
+		var ms models.MonthlySales
+		if err := scanRow(row, &ms.Month, (*int64)(&ms.SalesVolume), &ms.ItemCount, &ms.UniqueCustomers, &ms.UniqueProducts); err != nil {
+			return nil, fmt.Errorf("failed to scan monthly sales: %w", err)
+		}
+		results = append(results, ms)
+	}
+
+	return results, nil
+}
+
+// GetTopRegions returns a page of regions ranked by sortBy, largest first
+func (s *ClickHouseService) GetTopRegions(ctx context.Context, opts models.QueryOptions, sortBy string, limit, offset int) ([]models.RegionRevenue, error) {
+	rank, err := rankColumn(regionRanks, sortBy)
+	if err != nil {
+		return nil, err
+	}
+	source, params := s.source(opts)
+	params["limit"] = strconv.Itoa(limit)
+	params["offset"] = strconv.Itoa(offset)
+
+	rows, err := s.query(ctx, fmt.Sprintf(`
+		SELECT
+			region,
+			%s AS total_revenue,
+			toInt64(round(sum(quantity) * {scale:Float64})) AS items_sold
+		FROM %s
+		GROUP BY region
+		ORDER BY %s DESC, region
+		LIMIT {limit:UInt32} OFFSET {offset:UInt32}
+	`, chMoneyCents("sum(total_price)", opts), source, rank), params)
+	if err != nil {
+		return nil, queryError("failed to query top regions", err)
+	}
+
+	var results []models.RegionRevenue
+	for _, row := range rows {
+		var rr models.RegionRevenue
+		if err := scanRow(row, &rr.Region, (*int64)(&rr.TotalRevenue), &rr.ItemsSold); err != nil {
+			return nil, fmt.Errorf("failed to scan top regions: %w", err)
+		}
+		results = append(results, rr)
+	}
+
+	return results, nil
+}
+
+// GetTopRegionsCount returns the number of regions GetTopRegions ranks
+func (s *ClickHouseService) GetTopRegionsCount(ctx context.Context, opts models.QueryOptions) (int, error) {
+	source, params := s.source(opts)
+	var count int
+	err := s.queryRow(ctx, fmt.Sprintf("SELECT uniqExact(region) FROM %s", source), params, &count)
+	return count, err
+}
+
+// GetRegionSales returns the revenue of every country and region pair,
+// largest first
+func (s *ClickHouseService) GetRegionSales(ctx context.Context, opts models.QueryOptions) ([]models.RegionSales, error) {
+	source, params := s.source(opts)
+	rows, err := s.query(ctx, fmt.Sprintf(`
+		SELECT
+			country,
+			region,
+			%s AS revenue,
+			toInt64(round(count() * {scale:Float64})) AS transactions,
+			toInt64(round(sum(quantity) * {scale:Float64})) AS items_sold
+		FROM %s
+		GROUP BY country, region
+		ORDER BY revenue DESC, country, region
+	`, chMoneyCents("sum(total_price)", opts), source), params)
+	if err != nil {
+		return nil, queryError("failed to query region sales", err)
+	}
+
+	var results []models.RegionSales
+	for _, row := range rows {
+		var rs models.RegionSales
+		if err := scanRow(row, &rs.Country, &rs.Region, (*int64)(&rs.Revenue), &rs.Transactions, &rs.ItemsSold); err != nil {
+			return nil, fmt.Errorf("failed to scan region sales: %w", err)
+		}
+		results = append(results, rs)
+	}
+	return results, nil
+}
+
+func (s *ClickHouseService) GetTotalRecords(ctx context.Context) (int, error) {
+	var count int
+	err := s.queryRow(ctx, fmt.Sprintf("SELECT count() FROM %s", s.transactions), nil, &count)
+	return count, err
+}
+
+// GetCountryRevenueCount returns the number of country and product rows
+// GetCountryRevenue pages through
+func (s *ClickHouseService) GetCountryRevenueCount(ctx context.Context, opts models.QueryOptions) (int, error) {
+	source, params := s.source(opts)
+	var count int
+	err := s.queryRow(ctx, fmt.Sprintf("SELECT uniqExact(country, product_name) FROM %s", source), params, &count)
+	return count, err
+}
+
+// GetDistinctCounts returns approximate unique customer and product counts
+func (s *ClickHouseService) GetDistinctCounts(ctx context.Context) (models.DistinctCounts, error) {
+	var counts models.DistinctCounts
+	err := s.queryRow(ctx, fmt.Sprintf(`
+		SELECT uniq(user_id), uniq(product_id)
+		FROM %s
+	`, s.transactions), nil, &counts.UniqueCustomers, &counts.UniqueProducts)
+	if err != nil {
+		return counts, queryError("failed to query distinct counts", err)
+	}
+	return counts, nil
+}
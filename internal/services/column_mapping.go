@@ -0,0 +1,165 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ColumnSource locates a transactions column in a source file: by header
+// name or, with ByIndex, by zero-based position
+type ColumnSource struct {
+	Name    string
+	Index   int
+	ByIndex bool
+}
+
+// ColumnMapping maps transactions columns to the source columns they are
+// read from. Unmapped columns are read from the header of the same name.
+type ColumnMapping map[string]ColumnSource
+
+// columnMapping applies to every transactions load, by either backend.
+// SetColumnMapping replaces it at startup.
+var columnMapping ColumnMapping
+
+// SetColumnMapping sets the mapping transactions files are read with; nil
+// reads every column from the header of its own name
+func SetColumnMapping(mapping ColumnMapping) {
+	columnMapping = mapping
+}
+
+// LoadColumnMapping reads a mapping from a YAML file of column: source
+// pairs, then applies spec over it, a list of column=source pairs separated
+// by ";". A source of digits only is a position, anything else a header
+// name. Either may be empty.
+func LoadColumnMapping(spec, path string) (ColumnMapping, error) {
+	mapping := ColumnMapping{}
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read column mapping: %w", err)
+		}
+		var pairs map[string]string
+		if err := yaml.Unmarshal(data, &pairs); err != nil {
+			return nil, fmt.Errorf("invalid column mapping %s: %w", path, err)
+		}
+		for column, value := range pairs {
+			if err := mapping.add(column, value); err != nil {
+				return nil, fmt.Errorf("invalid column mapping %s: %w", path, err)
+			}
+		}
+	}
+
+	for _, pair := range strings.Split(spec, ";") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		column, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid column mapping %q: want column=source", pair)
+		}
+		if err := mapping.add(column, value); err != nil {
+			return nil, fmt.Errorf("invalid column mapping %q: %w", pair, err)
+		}
+	}
+
+	if len(mapping) == 0 {
+		return nil, nil
+	}
+	return mapping, nil
+}
+
+// add maps a transactions column to value, a position or a header name
+func (m ColumnMapping) add(column, value string) error {
+	column = strings.ToLower(strings.TrimSpace(column))
+	value = strings.TrimSpace(value)
+	if _, ok := lookupColumn(transactionsTable, column); !ok {
+		return fmt.Errorf("unknown column %q, want one of %s", column, strings.Join(transactionsTable.columnNames(), ", "))
+	}
+	if value == "" {
+		return fmt.Errorf("no source for %s", column)
+	}
+	if strings.Trim(value, "0123456789") == "" {
+		index, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid position for %s: %w", column, err)
+		}
+		m[column] = ColumnSource{Index: index, ByIndex: true}
+		return nil
+	}
+	m[column] = ColumnSource{Name: value}
+	return nil
+}
+
+// String lists the mapping as the column=source pairs LoadColumnMapping
+// reads
+func (m ColumnMapping) String() string {
+	pairs := make([]string, 0, len(m))
+	for column, source := range m {
+		value := source.Name
+		if source.ByIndex {
+			value = strconv.Itoa(source.Index)
+		}
+		pairs = append(pairs, column+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ";")
+}
+
+// lookupColumn returns the spec's column of the given name
+func lookupColumn(spec TableSpec, name string) (ColumnSpec, bool) {
+	for _, col := range spec.Columns {
+		if col.Name == name {
+			return col, true
+		}
+	}
+	return ColumnSpec{}, false
+}
+
+// mapping returns the column mapping that applies to the table, nil when
+// its columns are read by their own names
+func (t TableSpec) mapping() ColumnMapping {
+	if t.Name != transactionsTable.Name {
+		return nil
+	}
+	return columnMapping
+}
+
+// sourceIndex maps each of the spec's columns to its position in header,
+// -1 for a column the file lacks. Names are compared lowercased and
+// trimmed, like readHeader normalizes them.
+func (t TableSpec) sourceIndex(header []string) []int {
+	positions := make(map[string]int, len(header))
+	for i, name := range header {
+		positions[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	mapping := t.mapping()
+	index := make([]int, len(t.Columns))
+	for i, column := range t.Columns {
+		source, mapped := mapping[column.Name]
+		switch {
+		case mapped && source.ByIndex:
+			index[i] = -1
+			if source.Index < len(header) {
+				index[i] = source.Index
+			}
+		case mapped:
+			index[i] = lookupPosition(positions, strings.ToLower(source.Name))
+		default:
+			index[i] = lookupPosition(positions, column.Name)
+		}
+	}
+	return index
+}
+
+func lookupPosition(positions map[string]int, name string) int {
+	if pos, ok := positions[name]; ok {
+		return pos
+	}
+	return -1
+}
@@ -0,0 +1,454 @@
+package services
+
+import (
+	"cmp"
+	"math"
+	"math/rand"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"analytics-dashboard-api/internal/models"
+)
+
+// dictionary encodes the distinct values of a string column as dense uint32
+// codes, assigned in first-seen order
+type dictionary struct {
+	values []string
+	codes  map[string]uint32
+}
+
+func newDictionary() *dictionary {
+	return &dictionary{codes: map[string]uint32{}}
+}
+
+// encode returns the code for value, adding it on first sight
+func (d *dictionary) encode(value string) uint32 {
+	if code, ok := d.codes[value]; ok {
+		return code
+	}
+	code := uint32(len(d.values))
+	d.values = append(d.values, value)
+	d.codes[value] = code
+	return code
+}
+
+// lookup returns the code for value without adding it
+func (d *dictionary) lookup(value string) (uint32, bool) {
+	code, ok := d.codes[value]
+	return code, ok
+}
+
+// lookupFold marks the codes of the values equal to one of values under
+// Unicode case folding, and reports whether there are any
+func (d *dictionary) lookupFold(values ...string) ([]bool, bool) {
+	match := make([]bool, len(d.values))
+	found := false
+	for code, v := range d.values {
+		for _, value := range values {
+			if strings.EqualFold(v, value) {
+				match[code], found = true, true
+				break
+			}
+		}
+	}
+	return match, found
+}
+
+func (d *dictionary) len() int {
+	return len(d.values)
+}
+
+// dimension is a dictionary-encoded string column
+type dimension struct {
+	codes []uint32
+	dict  *dictionary
+}
+
+func newDimension(capacity int) *dimension {
+	return &dimension{codes: make([]uint32, 0, capacity), dict: newDictionary()}
+}
+
+func (c *dimension) append(value string) {
+	c.codes = append(c.codes, c.dict.encode(value))
+}
+
+// noSegment marks customers without a segment in columnStore.userSegment
+const noSegment = ^uint32(0)
+
+// columnStore holds the transactions column by column. Measures are typed
+// slices and every string column is dictionary encoded, so filters compare
+// integers and groupings index dense arrays instead of hashing strings. Rows
+// are in date order, which makes each month a contiguous partition.
+type columnStore struct {
+	rows       int
+	partitions []partition
+
+	days     []int32 // transaction_date as days since the Unix epoch
+	quantity []int32
+	total    []int64 // cents
+	stock    []int32
+
+	month       *dimension // YYYY-MM
+	user        *dimension
+	country     *dimension
+	region      *dimension
+	product     *dimension
+	productName *dimension
+	category    *dimension
+
+	// userSegment maps user codes to segment codes, noSegment when the
+	// customer is missing from the customers dimension
+	userSegment []uint32
+	segment     *dictionary
+}
+
+// partition is the rows [start, end) of one month
+type partition struct {
+	month      uint32 // code in the month dimension
+	start, end int
+}
+
+const secondsPerDay = 24 * 60 * 60
+
+// epochDay returns the day of t as days since the Unix epoch
+func epochDay(t time.Time) int32 {
+	return int32(math.Floor(float64(t.Unix()) / secondsPerDay))
+}
+
+// newColumnStore encodes transactions column by column, sorted by date.
+// segments maps user IDs to their customers-dimension segment.
+func newColumnStore(transactions []models.Transaction, segments map[string]string) *columnStore {
+	n := len(transactions)
+	c := &columnStore{
+		rows:        n,
+		days:        make([]int32, n),
+		quantity:    make([]int32, n),
+		total:       make([]int64, n),
+		stock:       make([]int32, n),
+		month:       newDimension(n),
+		user:        newDimension(n),
+		country:     newDimension(n),
+		region:      newDimension(n),
+		product:     newDimension(n),
+		productName: newDimension(n),
+		category:    newDimension(n),
+		segment:     newDictionary(),
+	}
+
+	// Sort an index rather than the transactions, which are large to move.
+	// The sort is stable so rows of a day keep their file order.
+	days := make([]int32, n)
+	order := make([]int32, n)
+	for i := range transactions {
+		days[i] = epochDay(transactions[i].TransactionDate)
+		order[i] = int32(i)
+	}
+	slices.SortStableFunc(order, func(a, b int32) int { return cmp.Compare(days[a], days[b]) })
+
+	for i, j := range order {
+		t := &transactions[j]
+		c.days[i] = days[j]
+		c.quantity[i] = int32(t.Quantity)
+		c.total[i] = int64(models.MoneyFromFloat(t.TotalPrice))
+		c.stock[i] = int32(t.StockQuantity)
+		c.month.append(t.GetMonth())
+		c.user.append(t.UserID)
+		c.country.append(t.Country)
+		c.region.append(t.Region)
+		c.product.append(t.ProductID)
+		c.productName.append(t.ProductName)
+		c.category.append(t.Category)
+
+		if month := c.month.codes[i]; i == 0 || month != c.month.codes[i-1] {
+			c.partitions = append(c.partitions, partition{month: month, start: i})
+		}
+		c.partitions[len(c.partitions)-1].end = i + 1
+	}
+
+	c.userSegment = make([]uint32, c.user.dict.len())
+	for code, userID := range c.user.dict.values {
+		c.userSegment[code] = noSegment
+		if segment := segments[userID]; segment != "" {
+			c.userSegment[code] = c.segment.encode(segment)
+		}
+	}
+	return c
+}
+
+// dayTime converts a days column value back to a UTC date
+func dayTime(day int32) time.Time {
+	return time.Unix(int64(day)*secondsPerDay, 0).UTC()
+}
+
+// selection lists the rows a query aggregates over, in ascending order.
+// all avoids materializing an index for unfiltered queries.
+type selection struct {
+	all  bool
+	rows []int32
+}
+
+// each calls fn for every selected row
+func (s selection) each(c *columnStore, fn func(i int)) {
+	if s.all {
+		for i := 0; i < c.rows; i++ {
+			fn(i)
+		}
+		return
+	}
+	for _, i := range s.rows {
+		fn(int(i))
+	}
+}
+
+// len returns the number of selected rows
+func (s selection) len(c *columnStore) int {
+	if s.all {
+		return c.rows
+	}
+	return len(s.rows)
+}
+
+// filter selects the rows matching the options' filters, Bernoulli sampled
+// when a sample rate is set. Filter values are resolved to codes once, so a
+// value absent from the data selects nothing without scanning. Values match
+// regardless of case, like the NOCASE collation of the DuckDB backend.
+func (c *columnStore) filter(opts models.QueryOptions) selection {
+	if !opts.Sampled() && !opts.Filtered() {
+		return selection{all: true}
+	}
+
+	var filters []rowFilter
+	for _, filter := range opts.DimensionFilters() {
+		var base rowFilter
+		var dict *dictionary
+		switch filter.Dimension {
+		case models.FilterCountry:
+			base.codes, dict = c.country.codes, c.country.dict
+		case models.FilterRegion:
+			base.codes, dict = c.region.codes, c.region.dict
+		case models.FilterCategory:
+			base.codes, dict = c.category.codes, c.category.dict
+		case models.FilterSegment:
+			base.codes, base.via, dict = c.user.codes, c.userSegment, c.segment
+		default:
+			continue
+		}
+		if len(filter.Include) > 0 {
+			match, ok := dict.lookupFold(filter.Include...)
+			if !ok {
+				return selection{}
+			}
+			include := base
+			include.match = match
+			filters = append(filters, include)
+		}
+		// Excluding values absent from the data drops nothing
+		if match, ok := dict.lookupFold(filter.Exclude...); ok {
+			exclude := base
+			exclude.match, exclude.exclude = match, true
+			filters = append(filters, exclude)
+		}
+	}
+
+	from, to := int32(math.MinInt32), int32(math.MaxInt32)
+	if !opts.From.IsZero() {
+		from = epochDay(opts.From)
+	}
+	if !opts.To.IsZero() {
+		to = epochDay(opts.To)
+	}
+
+	var rows []int32
+	start, end := c.scanRange(from, to)
+rows:
+	for i := start; i < end; i++ {
+		if c.days[i] < from || c.days[i] >= to {
+			continue
+		}
+		for _, filter := range filters {
+			if !filter.keep(i) {
+				continue rows
+			}
+		}
+		if opts.Sampled() && rand.Float64() >= opts.SampleRate {
+			continue
+		}
+		rows = append(rows, int32(i))
+	}
+	return selection{rows: rows}
+}
+
+// rowFilter keeps the rows whose code in a dimension is marked in match, or
+// with exclude the rows whose code is not
+type rowFilter struct {
+	codes   []uint32 // per row
+	via     []uint32 // maps codes to those of the filtered dictionary, for segments
+	match   []bool
+	exclude bool
+}
+
+func (f rowFilter) keep(row int) bool {
+	code := f.codes[row]
+	if f.via != nil {
+		code = f.via[code] // noSegment is never marked
+	}
+	matched := int(code) < len(f.match) && f.match[code]
+	return matched != f.exclude
+}
+
+// scanRange returns the rows of the partitions overlapping the days
+// [from, to). Rows outside the range may remain at its ends, in partitions
+// it only partly covers.
+func (c *columnStore) scanRange(from, to int32) (int, int) {
+	first := sort.Search(len(c.partitions), func(p int) bool {
+		return c.days[c.partitions[p].end-1] >= from
+	})
+	last := sort.Search(len(c.partitions), func(p int) bool {
+		return c.days[c.partitions[p].start] >= to
+	})
+	if first >= last {
+		return 0, 0
+	}
+	return c.partitions[first].start, c.partitions[last-1].end
+}
+
+// partitionStats describes each month partition
+func (c *columnStore) partitionStats() []models.Partition {
+	stats := make([]models.Partition, 0, len(c.partitions))
+	for _, p := range c.partitions {
+		stats = append(stats, models.Partition{
+			Month: c.month.dict.values[p.month],
+			Rows:  p.end - p.start,
+			From:  dayTime(c.days[p.start]).Format("2006-01-02"),
+			To:    dayTime(c.days[p.end-1]).Format("2006-01-02"),
+		})
+	}
+	return stats
+}
+
+// measures accumulates the additive measures of one group
+type measures struct {
+	rows     int
+	quantity int
+	total    models.Money
+	stock    int // maximum stock_quantity seen
+}
+
+func (m *measures) add(c *columnStore, i int) {
+	m.rows++
+	m.quantity += int(c.quantity[i])
+	m.total += models.Money(c.total[i])
+	m.stock = max(m.stock, int(c.stock[i]))
+}
+
+// grouping assigns rows to groups by the codes of a dimension. The zero
+// grouping puts every row in a single group.
+type grouping struct {
+	dim *dimension
+}
+
+func (g grouping) size() int {
+	if g.dim == nil {
+		return 1
+	}
+	return g.dim.dict.len()
+}
+
+func (g grouping) code(i int) uint32 {
+	if g.dim == nil {
+		return 0
+	}
+	return g.dim.codes[i]
+}
+
+func (g grouping) label(code uint32) string {
+	if g.dim == nil {
+		return ""
+	}
+	return g.dim.dict.values[code]
+}
+
+// aggregate sums the selected rows per group, indexed by group code. Groups
+// without selected rows have zero rows.
+func (c *columnStore) aggregate(sel selection, g grouping) []measures {
+	groups := make([]measures, g.size())
+	sel.each(c, func(i int) {
+		groups[g.code(i)].add(c, i)
+	})
+	return groups
+}
+
+// pairKey combines the codes of two dimensions into one group key
+type pairKey struct {
+	a, b uint32
+}
+
+// pairGroup holds the measures of one combination of two dimensions
+type pairGroup struct {
+	key pairKey
+	measures
+}
+
+// densePairCells bounds the combinations aggregatePairs sums into a flat
+// array, a few MiB of measures; larger dictionaries group through a map
+const densePairCells = 1 << 16
+
+// aggregatePairs sums the selected rows per combination of two dimensions,
+// returning the combinations that have rows in no particular order. When
+// the dictionaries are small every combination gets a slot of an array
+// indexed by both codes; otherwise a map sized from the dictionaries finds
+// each combination's group. Either way rows only add to preallocated
+// groups.
+func (c *columnStore) aggregatePairs(sel selection, a, b *dimension) []pairGroup {
+	na, nb := a.dict.len(), b.dict.len()
+	selected := sel.len(c)
+
+	if na <= densePairCells && nb <= densePairCells && na*nb <= densePairCells {
+		cells := make([]measures, na*nb)
+		sel.each(c, func(i int) {
+			cells[int(a.codes[i])*nb+int(b.codes[i])].add(c, i)
+		})
+		groups := make([]pairGroup, 0, min(len(cells), selected))
+		for cell := range cells {
+			if cells[cell].rows > 0 {
+				key := pairKey{uint32(cell / nb), uint32(cell % nb)}
+				groups = append(groups, pairGroup{key: key, measures: cells[cell]})
+			}
+		}
+		return groups
+	}
+
+	// Most combinations pair a value of one dimension with a few of the
+	// other, so the larger dictionary estimates the group count
+	estimate := min(max(na, nb), selected)
+	index := make(map[pairKey]int32, estimate)
+	groups := make([]pairGroup, 0, estimate)
+	sel.each(c, func(i int) {
+		key := pairKey{a.codes[i], b.codes[i]}
+		g, ok := index[key]
+		if !ok {
+			g = int32(len(groups))
+			index[key] = g
+			groups = append(groups, pairGroup{key: key})
+		}
+		groups[g].add(c, i)
+	})
+	return groups
+}
+
+// countDistinct counts the distinct values of of among the selected rows
+// of each group, indexed by group code
+func (c *columnStore) countDistinct(sel selection, g grouping, of *dimension) []int {
+	counts := make([]int, g.size())
+	seen := map[pairKey]struct{}{}
+	sel.each(c, func(i int) {
+		key := pairKey{g.code(i), of.codes[i]}
+		if _, ok := seen[key]; !ok {
+			seen[key] = struct{}{}
+			counts[key.a]++
+		}
+	})
+	return counts
+}
@@ -0,0 +1,218 @@
+package services
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/pkg/logger"
+)
+
+// ContributionMetrics are the metrics a change can be decomposed for. Only
+// additive metrics qualify: the groups' changes must sum to the total change.
+var ContributionMetrics = []string{"revenue", "transactions", "quantity"}
+
+// ContributionDimensions are the groupings a change can be broken down by
+var ContributionDimensions = []string{"country", "product", "category"}
+
+const (
+	defaultContributionLimit = 10
+	maxContributionLimit     = 100
+	maxContributionDays      = 3660
+)
+
+// ContributionService explains a period-over-period change by the groups
+// that drove it. Each dimension costs two grouped aggregate queries, one
+// per period; merging and ranking the groups happens here.
+type ContributionService struct {
+	queries MetricQuerier
+	logger  logger.Logger
+}
+
+func NewContributionService(queries MetricQuerier, logger logger.Logger) *ContributionService {
+	return &ContributionService{
+		queries: queries,
+		logger:  logger,
+	}
+}
+
+// Analyze compares request.From to request.To with the equally long period
+// that ends the day before request.From
+func (s *ContributionService) Analyze(ctx context.Context, request models.ContributionRequest) (*models.ContributionAnalysis, error) {
+	if err := validateContributionRequest(&request); err != nil {
+		return nil, err
+	}
+
+	days := int(request.To.Sub(request.From).Hours()/24) + 1
+	current := request.Filters
+	current.From, current.To = request.From, request.To.AddDate(0, 0, 1)
+	previous := request.Filters
+	previous.From, previous.To = request.From.AddDate(0, 0, -days), request.From
+
+	analysis := &models.ContributionAnalysis{
+		Metric:         request.Metric,
+		Period:         contributionPeriod(current),
+		PreviousPeriod: contributionPeriod(previous),
+		Breakdowns:     []models.ContributionBreakdown{},
+	}
+	for i, dimension := range request.Dimensions {
+		contributions, err := s.contributions(ctx, request.Metric, dimension, previous, current)
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 {
+			// The metric is additive, so any dimension's groups sum to the total
+			analysis.Total = contributionTotal(contributions)
+		}
+		analysis.Breakdowns = append(analysis.Breakdowns, contributionBreakdown(dimension, contributions, analysis.Total.Change, request.Limit))
+	}
+	return analysis, nil
+}
+
+func validateContributionRequest(request *models.ContributionRequest) error {
+	if request.Metric == "" {
+		request.Metric = "revenue"
+	}
+	if !slices.Contains(ContributionMetrics, request.Metric) {
+		return fmt.Errorf("%w: metric %q cannot be decomposed (want %s)",
+			models.ErrInvalidContribution, request.Metric, strings.Join(ContributionMetrics, ", "))
+	}
+	if request.From.IsZero() || request.To.IsZero() {
+		return fmt.Errorf("%w: from and to are required", models.ErrInvalidContribution)
+	}
+	if request.To.Before(request.From) {
+		return fmt.Errorf("%w: from must not be after to", models.ErrInvalidContribution)
+	}
+	if request.To.Sub(request.From).Hours()/24 >= maxContributionDays {
+		return fmt.Errorf("%w: the period may span at most %d days", models.ErrInvalidContribution, maxContributionDays)
+	}
+	if len(request.Dimensions) == 0 {
+		request.Dimensions = ContributionDimensions
+	}
+	for _, dimension := range request.Dimensions {
+		if !slices.Contains(ContributionDimensions, dimension) {
+			return fmt.Errorf("%w: unknown dimension %q (want %s)",
+				models.ErrInvalidContribution, dimension, strings.Join(ContributionDimensions, ", "))
+		}
+	}
+	if request.Limit == 0 {
+		request.Limit = defaultContributionLimit
+	}
+	if request.Limit < 1 || request.Limit > maxContributionLimit {
+		return fmt.Errorf("%w: limit must be between 1 and %d", models.ErrInvalidContribution, maxContributionLimit)
+	}
+	return nil
+}
+
+// contributions returns the metric per group of dimension in both periods,
+// including groups present in only one of them
+func (s *ContributionService) contributions(ctx context.Context, metric, dimension string, previous, current models.QueryOptions) ([]models.Contribution, error) {
+	before, err := s.queries.GetBaseMetrics(ctx, previous, dimension)
+	if err != nil {
+		return nil, err
+	}
+	after, err := s.queries.GetBaseMetrics(ctx, current, dimension)
+	if err != nil {
+		return nil, err
+	}
+
+	index := make(map[string]int)
+	var contributions []models.Contribution
+	group := func(name string) *models.Contribution {
+		i, ok := index[name]
+		if !ok {
+			i = len(contributions)
+			index[name] = i
+			contributions = append(contributions, models.Contribution{Group: name})
+		}
+		return &contributions[i]
+	}
+	for _, row := range before {
+		group(row.Group).Previous = row.Values[metric]
+	}
+	for _, row := range after {
+		group(row.Group).Current = row.Values[metric]
+	}
+	for i := range contributions {
+		contributions[i].Change = roundCents(contributions[i].Current - contributions[i].Previous)
+	}
+	return contributions, nil
+}
+
+func contributionTotal(contributions []models.Contribution) models.ContributionTotal {
+	var total models.ContributionTotal
+	for _, c := range contributions {
+		total.Previous += c.Previous
+		total.Current += c.Current
+	}
+	total.Previous, total.Current = roundCents(total.Previous), roundCents(total.Current)
+	total.Change = roundCents(total.Current - total.Previous)
+	if total.Previous != 0 {
+		pct := total.Change / total.Previous * 100
+		total.ChangePct = &pct
+	}
+	return total
+}
+
+// contributionBreakdown ranks the groups by the size of their change, ties
+// by name, and sums those past limit as other
+func contributionBreakdown(dimension string, contributions []models.Contribution, totalChange float64, limit int) models.ContributionBreakdown {
+	slices.SortFunc(contributions, func(a, b models.Contribution) int {
+		if c := cmp.Compare(math.Abs(b.Change), math.Abs(a.Change)); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Group, b.Group)
+	})
+
+	breakdown := models.ContributionBreakdown{
+		Dimension:     dimension,
+		Contributions: []models.Contribution{},
+		Groups:        len(contributions),
+	}
+	for i, c := range contributions {
+		if i < limit {
+			breakdown.Contributions = append(breakdown.Contributions, withShare(c, totalChange))
+			continue
+		}
+		if breakdown.Other == nil {
+			breakdown.Other = &models.Contribution{Group: "other"}
+		}
+		breakdown.Other.Previous += c.Previous
+		breakdown.Other.Current += c.Current
+		breakdown.Other.Change += c.Change
+	}
+	if breakdown.Other != nil {
+		other := *breakdown.Other
+		other.Previous, other.Current = roundCents(other.Previous), roundCents(other.Current)
+		other.Change = roundCents(other.Current - other.Previous)
+		other = withShare(other, totalChange)
+		breakdown.Other = &other
+	}
+	return breakdown
+}
+
+func withShare(c models.Contribution, totalChange float64) models.Contribution {
+	if totalChange != 0 {
+		share := c.Change / totalChange * 100
+		c.SharePct = &share
+	}
+	return c
+}
+
+// roundCents drops the float noise that summing amounts adds. Revenue is the
+// only metric with fractions, and it is in currency units.
+func roundCents(value float64) float64 {
+	return math.Round(value*100) / 100
+}
+
+// contributionPeriod converts query options, To exclusive, to an inclusive period
+func contributionPeriod(opts models.QueryOptions) models.ContributionPeriod {
+	return models.ContributionPeriod{
+		From: opts.From.Format("2006-01-02"),
+		To:   opts.To.AddDate(0, 0, -1).Format("2006-01-02"),
+	}
+}
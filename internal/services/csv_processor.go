@@ -0,0 +1,259 @@
+package services
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/pkg/logger"
+)
+
+// CSVProcessor parses the source CSV files in Go for backends without a SQL
+// engine to read them. Columns are matched by header name, or as the column
+// mapping says, so files may order or extend their columns freely.
+type CSVProcessor struct {
+	logger logger.Logger
+}
+
+func NewCSVProcessor(logger logger.Logger) *CSVProcessor {
+	return &CSVProcessor{logger: logger}
+}
+
+// ReadTransactions parses every row of a transactions file. A malformed row
+// fails the whole read with its line number. The stats time reading the file
+// apart from parsing it; their Insert phase is left to the caller.
+//
+// Rows are parsed straight into the result as they are read, through
+// buffers reused from row to row, so each row costs a single allocation for
+// the strings its transaction keeps. The result comes from a pool of
+// batches: callers done with it hand it back with Release, so the next read
+// reuses it instead of allocating another.
+func (p *CSVProcessor) ReadTransactions(path string) (_ []models.Transaction, stats models.IngestStats, _ error) {
+	start := time.Now()
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, stats, sourceError(path, err)
+	}
+	defer file.Close()
+
+	var size int64
+	if info, err := file.Stat(); err == nil {
+		size = info.Size()
+	}
+	disk := &timedReader{r: file}
+	defer func() {
+		stats.Bytes, stats.Read = disk.bytes, disk.elapsed
+		stats.Parse = time.Since(start) - disk.elapsed
+	}()
+
+	scanner := newRecordScanner(disk)
+	defer scanner.release()
+	header, err := readHeader(scanner, path)
+	if header == nil || err != nil {
+		stats.ParseErrors = parseErrors(err)
+		return nil, stats, err
+	}
+	index := p.columnIndex(path, header, transactionsTable)
+
+	transactions := getBatch()
+	row := make([]string, len(index))
+	for {
+		record, err := scanner.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			stats.Rows, stats.ParseErrors = int64(len(transactions)), parseErrors(err)
+			p.Release(transactions)
+			return nil, stats, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+
+		if len(transactions) == sizingRows {
+			transactions = growToFit(transactions, scanner.consumed, size)
+		}
+		selectColumns(row, record, index) // ParseCSVRow trims the values
+		transactions = append(transactions, models.Transaction{})
+		if err := transactions[len(transactions)-1].ParseCSVRow(row); err != nil {
+			stats.Rows, stats.ParseErrors = int64(len(transactions)-1), 1
+			p.Release(transactions)
+			// +1 for the header and 1-based line numbers
+			return nil, stats, fmt.Errorf("%s line %d: %w", path, len(transactions)+1, err)
+		}
+	}
+	stats.Rows = int64(len(transactions))
+	return transactions, stats, nil
+}
+
+// Release hands a batch returned by ReadTransactions back for reuse. The
+// caller must not use the transactions afterwards; their values are cleared
+// so the pool keeps no row strings alive.
+func (p *CSVProcessor) Release(transactions []models.Transaction) {
+	if cap(transactions) == 0 {
+		return
+	}
+	batch := transactions[:cap(transactions)]
+	clear(batch)
+	batch = batch[:0]
+	batchPool.Put(&batch)
+}
+
+// batchPool holds released transaction batches
+var batchPool sync.Pool // of *[]models.Transaction
+
+// getBatch returns an empty batch, reusing a released one if any
+func getBatch() []models.Transaction {
+	if batch, ok := batchPool.Get().(*[]models.Transaction); ok {
+		return *batch
+	}
+	return nil
+}
+
+// ReadTable returns the rows of a CSV file with values ordered like the
+// spec's columns. Columns missing from the file read as empty strings.
+func (p *CSVProcessor) ReadTable(path string, spec TableSpec) ([][]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, sourceError(path, err)
+	}
+	defer file.Close()
+
+	reader := newCSVReader(file)
+	header, err := readHeader(reader, path)
+	if header == nil || err != nil {
+		return nil, err
+	}
+	index := p.columnIndex(path, header, spec)
+
+	var rows [][]string
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+
+		row := make([]string, len(index))
+		selectColumns(row, record, index)
+		for i := range row {
+			row[i] = strings.TrimSpace(row[i])
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// columnIndex maps each of the spec's columns to its position in header,
+// following the column mapping, -1 for a column the file lacks
+func (p *CSVProcessor) columnIndex(path string, header []string, spec TableSpec) []int {
+	index := spec.sourceIndex(header)
+	for i, pos := range index {
+		if pos < 0 {
+			p.logger.Debug("Column missing from CSV", "file", path, "column", spec.Columns[i].Name)
+		}
+	}
+	return index
+}
+
+// selectColumns fills row with the values of record at the positions of
+// index
+func selectColumns(row, record []string, index []int) {
+	for i, pos := range index {
+		row[i] = ""
+		if pos >= 0 && pos < len(record) {
+			row[i] = record[pos]
+		}
+	}
+}
+
+// sizingRows is how many rows are read before the result is sized for the
+// whole file
+const sizingRows = 1024
+
+// growToFit makes room in transactions for the rows of a file of size bytes,
+// estimated from the consumed bytes its rows so far took. Growing once
+// spares copying the result over and over while it doubles.
+func growToFit(transactions []models.Transaction, consumed, size int64) []models.Transaction {
+	if consumed <= 0 || size <= consumed {
+		return transactions
+	}
+	estimate := int(int64(len(transactions)) * (size - consumed) / consumed)
+	return slices.Grow(transactions, estimate+estimate/16)
+}
+
+// timedReader counts the bytes read through it and the time spent reading
+// them
+type timedReader struct {
+	r       io.Reader
+	bytes   int64
+	elapsed time.Duration
+}
+
+func (t *timedReader) Read(p []byte) (int, error) {
+	start := time.Now()
+	n, err := t.r.Read(p)
+	t.elapsed += time.Since(start)
+	t.bytes += int64(n)
+	return n, err
+}
+
+// parseErrors is 1 when err is a malformed CSV record, 0 otherwise
+func parseErrors(err error) int64 {
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return 1
+	}
+	return 0
+}
+
+// ReadHeader returns the normalized column names of a CSV file, nil for an
+// empty file
+func (p *CSVProcessor) ReadHeader(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, sourceError(path, err)
+	}
+	defer file.Close()
+
+	return readHeader(newCSVReader(file), path)
+}
+
+func newCSVReader(r io.Reader) *csv.Reader {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	return reader
+}
+
+// recordReader is a *csv.Reader or a *recordScanner
+type recordReader interface {
+	Read() ([]string, error)
+}
+
+// readHeader reads the header row with names lowercased and stripped of
+// whitespace and a byte order mark, nil for an empty file
+func readHeader(reader recordReader, path string) ([]string, error) {
+	record, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s header: %w", path, err)
+	}
+
+	// A copy, as the scanner reuses its record
+	header := make([]string, len(record))
+	for i, name := range record {
+		name = strings.TrimPrefix(name, "\uFEFF")
+		header[i] = strings.ToLower(strings.TrimSpace(name))
+	}
+	return header, nil
+}
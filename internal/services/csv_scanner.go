@@ -0,0 +1,173 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"io"
+	"sync"
+)
+
+// maxPooledLine caps the long-line buffer a pooled scanner keeps, so one
+// file with a huge quoted field does not hold on to its memory
+const maxPooledLine = 1 << 20
+
+// recordScanner reads CSV records like the reader of newCSVReader does:
+// quoted fields with doubled quotes, leading spaces and tabs trimmed, blank
+// lines skipped and \r\n read as \n. It works on bytes in buffers reused
+// from record to record, and makes a single string per record that every
+// field of it shares. The returned fields are only valid until the next
+// call to Read.
+type recordScanner struct {
+	r        *bufio.Reader
+	line     int    // number of the last line read
+	consumed int64  // bytes of input the lines read so far took
+	long     []byte // lines longer than the bufio buffer, assembled
+	buf      []byte // unquoted contents of the current record's fields
+	ends     []int  // end of each field in buf
+	fields   []string
+}
+
+// scannerPool holds released scanners, whose buffers outlive any one file
+var scannerPool sync.Pool // of *recordScanner
+
+// newRecordScanner returns a scanner of r, reusing a released one if any
+func newRecordScanner(r io.Reader) *recordScanner {
+	if s, ok := scannerPool.Get().(*recordScanner); ok {
+		s.r.Reset(r)
+		s.line, s.consumed = 0, 0
+		return s
+	}
+	return &recordScanner{r: bufio.NewReaderSize(r, 64<<10)}
+}
+
+// release returns the scanner to the pool. Neither it nor the last record
+// it read may be used afterwards.
+func (s *recordScanner) release() {
+	s.r.Reset(nil)
+	clear(s.fields) // the record string may be large; don't keep it alive
+	if cap(s.long) > maxPooledLine {
+		s.long = nil
+	}
+	scannerPool.Put(s)
+}
+
+// Read returns the next record, or io.EOF after the last one. Malformed
+// quoting fails with a *csv.ParseError, as from encoding/csv.
+func (s *recordScanner) Read() ([]string, error) {
+	line, err := s.readLine()
+	for err == nil && len(line) == 1 { // a blank line is only its \n
+		line, err = s.readLine()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	start := s.line
+	s.buf, s.ends = s.buf[:0], s.ends[:0]
+	for {
+		line = trimLeadingSpace(line)
+		if len(line) == 0 || line[0] != '"' {
+			// Unquoted field, up to the next comma or the end of the line
+			end := bytes.IndexByte(line, ',')
+			last := end < 0
+			if last {
+				end = len(line) - 1 // drop the \n
+			}
+			field := line[:end]
+			if bytes.IndexByte(field, '"') >= 0 {
+				return nil, s.parseError(start, csv.ErrBareQuote)
+			}
+			s.buf = append(s.buf, field...)
+			s.ends = append(s.ends, len(s.buf))
+			if last {
+				break
+			}
+			line = line[end+1:]
+			continue
+		}
+
+		// Quoted field, which may run over several lines
+		line = line[1:]
+		for {
+			quote := bytes.IndexByte(line, '"')
+			if quote < 0 {
+				s.buf = append(s.buf, line...)
+				if line, err = s.readLine(); err != nil {
+					return nil, s.parseError(start, csv.ErrQuote)
+				}
+				continue
+			}
+			s.buf = append(s.buf, line[:quote]...)
+			line = line[quote+1:]
+			if len(line) > 0 && line[0] == '"' { // "" is an escaped quote
+				s.buf = append(s.buf, '"')
+				line = line[1:]
+				continue
+			}
+			break
+		}
+		s.ends = append(s.ends, len(s.buf))
+		switch {
+		case len(line) > 0 && line[0] == ',':
+			line = line[1:]
+			continue
+		case len(line) == 1: // the \n
+		default:
+			return nil, s.parseError(start, csv.ErrQuote)
+		}
+		break
+	}
+
+	record := string(s.buf)
+	s.fields = s.fields[:0]
+	from := 0
+	for _, end := range s.ends {
+		s.fields = append(s.fields, record[from:end])
+		from = end
+	}
+	return s.fields, nil
+}
+
+// readLine returns the next line ending in \n, adding one to a last line
+// without it. The line is only valid until the next read.
+func (s *recordScanner) readLine() ([]byte, error) {
+	line, err := s.r.ReadSlice('\n')
+	if errors.Is(err, bufio.ErrBufferFull) {
+		s.long = append(s.long[:0], line...)
+		for errors.Is(err, bufio.ErrBufferFull) {
+			line, err = s.r.ReadSlice('\n')
+			s.long = append(s.long, line...)
+		}
+		line = s.long
+	}
+	if len(line) == 0 && err != nil {
+		return nil, err
+	}
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	s.line++
+	s.consumed += int64(len(line))
+
+	if line[len(line)-1] != '\n' {
+		s.long = append(append(s.long[:0], line...), '\n')
+		line = s.long
+	} else if n := len(line); n >= 2 && line[n-2] == '\r' {
+		line[n-2] = '\n'
+		line = line[:n-1]
+	}
+	return line, nil
+}
+
+func (s *recordScanner) parseError(start int, err error) error {
+	return &csv.ParseError{StartLine: start, Line: s.line, Err: err}
+}
+
+func trimLeadingSpace(b []byte) []byte {
+	for len(b) > 0 && (b[0] == ' ' || b[0] == '\t') {
+		b = b[1:]
+	}
+	return b
+}
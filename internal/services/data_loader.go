@@ -0,0 +1,628 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"analytics-dashboard-api/internal/config"
+	"analytics-dashboard-api/internal/metrics"
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/internal/timing"
+	"analytics-dashboard-api/pkg/logger"
+)
+
+// SourceLoader loads transactions files, CSV or Parquet, into an analytics
+// backend
+type SourceLoader interface {
+	LoadFromFile(context.Context, string, ...string) error
+	InspectSource(context.Context, string, string) (*models.SourceInspection, error)
+	Rollback(context.Context) (*models.DataSnapshot, error)
+}
+
+// PersistentStore is implemented by backends that keep loaded data across
+// restarts. PersistedData describes the data kept from an earlier run,
+// checked against the files it should have been loaded from; nil when
+// there is none.
+type PersistentStore interface {
+	PersistedData(context.Context, []string) (*models.PersistedData, error)
+}
+
+// coverageSource is implemented by loaders that know the size of the loaded
+// data, reported to the load metrics
+type coverageSource interface {
+	DataCoverage() models.DataCoverage
+}
+
+// ingestSource is implemented by loaders that time the phases of their
+// latest load, reported to the ingestion metrics
+type ingestSource interface {
+	LastIngest() models.IngestStats
+}
+
+// RefreshHook is notified after every data load with the load error, if any
+type RefreshHook func(context.Context, error)
+
+// defaultRetryAfter is suggested to callers while the very first load runs
+// and there is no previous load duration to estimate from
+const defaultRetryAfter = 5 * time.Second
+
+// assumedLoadRate estimates load time in bytes per second until a load has
+// been timed
+const assumedLoadRate = 20 << 20
+
+// DataLoader owns the lifecycle of the loaded dataset: lazy loading on first
+// use, forced reloads and refresh notifications. Handlers share one loader so
+// they all see the same data.
+type DataLoader struct {
+	loader      SourceLoader
+	csvPath     string
+	format      string
+	loadWait    time.Duration
+	loadTimeout time.Duration
+	remote      *remoteSource
+	quotas      *Quotas
+	metrics     *metrics.Metrics
+	logger      logger.Logger
+
+	// jobs serializes loads with every other job that changes the data; mu
+	// guards the state below and is never held while loading, so requests
+	// can observe an in-progress load
+	jobs *JobQueue
+
+	mu           sync.Mutex
+	loaded       bool
+	pending      *loadAttempt // in-flight initial load, nil when idle
+	lastDuration time.Duration
+	lastSize     int64  // source size of the last successful load
+	version      uint64 // bumped whenever the loaded data changes
+	hooks        []RefreshHook
+	stats        models.LoaderStats
+}
+
+// loadAttempt is a background initial load. err is set before done is closed.
+type loadAttempt struct {
+	started time.Time
+	done    chan struct{}
+	err     error
+}
+
+// NewDataLoader returns a loader for csvPath, read in cfg.Format, that runs
+// its loads on jobs. Requests wait up to cfg.LoadWait for an initial load
+// before being told to retry later, and each load may run for
+// cfg.LoadTimeout. A csvPath in S3 or Cloud Storage is downloaded with the
+// credentials of cfg.Remote before each load. Loads over the transactions
+// quota are refused; quotas may be nil, and so may metrics.
+func NewDataLoader(loader SourceLoader, csvPath string, cfg config.DataConfig, jobs *JobQueue, quotas *Quotas, metrics *metrics.Metrics, logger logger.Logger) *DataLoader {
+	return &DataLoader{
+		loader:      loader,
+		csvPath:     csvPath,
+		format:      cfg.Format,
+		loadWait:    cfg.LoadWait,
+		loadTimeout: cfg.LoadTimeout,
+		remote:      newRemoteSource(cfg.Remote),
+		quotas:      quotas,
+		metrics:     metrics,
+		jobs:        jobs,
+		logger:      logger,
+	}
+}
+
+// OnRefresh registers a hook run after every load, initial or forced
+func (l *DataLoader) OnRefresh(hook RefreshHook) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.hooks = append(l.hooks, hook)
+}
+
+// EnsureInitialized starts loading the CSV if no data has been loaded yet and
+// waits up to the configured load wait for it. Concurrent first requests share
+// a single load and all receive its outcome. A load still running after that
+// is reported as a *models.LoadingError so the caller can answer 503 instead
+// of holding the request for the whole load. A failed load is reported as
+// models.ErrDataNotLoaded wrapping the cause; the next call retries it.
+func (l *DataLoader) EnsureInitialized(ctx context.Context) error {
+	attempt := l.beginInitialLoad(true)
+	if attempt == nil {
+		return nil
+	}
+	defer timing.FromContext(ctx).Since(timing.PhaseQueue, "initial_load", time.Now())
+
+	timer := time.NewTimer(l.loadWait)
+	defer timer.Stop()
+
+	select {
+	case <-attempt.done:
+		if attempt.err != nil {
+			return fmt.Errorf("%w: %w", models.ErrDataNotLoaded, attempt.err)
+		}
+		return nil
+	case <-timer.C:
+		return &models.LoadingError{RetryAfter: l.retryAfter(attempt)}
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// StartInitialLoad starts loading the data in the background if nothing has
+// been loaded yet, without waiting for it, so startup probes can bring a
+// new instance up before any request arrives
+func (l *DataLoader) StartInitialLoad() {
+	l.beginInitialLoad(false)
+}
+
+// beginInitialLoad returns the in-progress initial load, starting one if
+// needed, or nil when data is loaded. A waiter joining a running load is
+// counted as coalesced.
+func (l *DataLoader) beginInitialLoad(waiter bool) *loadAttempt {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.loaded {
+		return nil
+	}
+	attempt := l.pending
+	if attempt == nil {
+		attempt = &loadAttempt{started: time.Now(), done: make(chan struct{})}
+		l.pending = attempt
+		go l.initialLoad(attempt)
+	} else if waiter {
+		l.stats.CoalescedWaits++
+	}
+	return attempt
+}
+
+// initialLoad runs a background load on behalf of every waiting request
+func (l *DataLoader) initialLoad(attempt *loadAttempt) {
+	l.logger.Info("Loading data", "file", l.csvPath)
+	err := l.load(context.Background(), "initial_load", PriorityInitial, 0, nil)
+
+	l.mu.Lock()
+	attempt.err = err
+	l.pending = nil
+	l.stats.LastError = ""
+	if err != nil {
+		l.stats.LastError = err.Error()
+	}
+	l.mu.Unlock()
+	close(attempt.done)
+
+	if err != nil {
+		l.logger.Error("Data load failed", "error", err)
+		return
+	}
+	l.logger.Info("Data load completed", "duration", time.Since(attempt.started))
+}
+
+// retryAfter estimates how long the attempt still needs from the duration of
+// the previous successful load
+func (l *DataLoader) retryAfter(attempt *loadAttempt) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.remaining(attempt)
+}
+
+// remaining is retryAfter with the lock held
+func (l *DataLoader) remaining(attempt *loadAttempt) time.Duration {
+	if l.lastDuration == 0 {
+		return defaultRetryAfter
+	}
+	return max(l.lastDuration-time.Since(attempt.started), time.Second)
+}
+
+// Stats returns a snapshot of the loader's activity counters
+func (l *DataLoader) Stats() models.LoaderStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	stats := l.stats
+	stats.Loaded = l.loaded
+	stats.Loading = l.pending != nil
+	if l.pending != nil {
+		started := l.pending.started.UTC()
+		stats.LoadStartedAt = &started
+		stats.EstimatedRemainingMs = l.remaining(l.pending).Milliseconds()
+	}
+	stats.LastDurationMs = l.lastDuration.Milliseconds()
+	stats.DataVersion = l.version
+	return stats
+}
+
+// Version identifies the loaded data. It changes after every successful load
+// or restore, so results computed from the data can be cached until then.
+func (l *DataLoader) Version() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.version
+}
+
+// Inspect validates the CSV without loading it and estimates how long a load
+// would take, scaling the last load's duration by the change in file size.
+// The inspection gets the load timeout, or timeout if positive.
+func (l *DataLoader) Inspect(ctx context.Context, timeout time.Duration) (*models.SourceInspection, error) {
+	ctx, cancel := context.WithTimeout(ctx, l.timeout(timeout))
+	defer cancel()
+
+	inspection, err := l.inspect(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect data: %w", err)
+	}
+	if err := l.quotas.Check(models.DatasetTransactions, "", int64(inspection.Records), inspection.SizeBytes); err != nil {
+		inspection.Valid = false
+		inspection.Problems = append(inspection.Problems, err.Error())
+	}
+
+	l.mu.Lock()
+	lastDuration, lastSize := l.lastDuration, l.lastSize
+	l.mu.Unlock()
+
+	estimate := time.Duration(float64(inspection.SizeBytes) / assumedLoadRate * float64(time.Second))
+	if lastDuration > 0 && lastSize > 0 {
+		estimate = time.Duration(float64(lastDuration) * float64(inspection.SizeBytes) / float64(lastSize))
+	}
+	inspection.EstimatedLoadMs = estimate.Milliseconds()
+	return inspection, nil
+}
+
+// inspect inspects the configured source. For a manifest, a file that does
+// not match it makes the source invalid, and the files' inspections are
+// merged: sizes and records add up, and problems name their file.
+func (l *DataLoader) inspect(ctx context.Context) (*models.SourceInspection, error) {
+	if IsRemoteSource(l.csvPath) {
+		local, err := l.fetchRemote(ctx)
+		if err != nil {
+			return nil, err
+		}
+		inspection, err := l.loader.InspectSource(ctx, l.format, local)
+		if inspection != nil {
+			inspection.Source = l.csvPath
+		}
+		return inspection, err
+	}
+	if !isManifest(l.csvPath) {
+		return l.loader.InspectSource(ctx, l.format, l.csvPath)
+	}
+
+	manifest, err := readManifest(l.csvPath)
+	if err != nil {
+		return nil, err
+	}
+	merged := &models.SourceInspection{Source: l.csvPath, Valid: true}
+	if err := verifyManifest(ctx, manifest, l.format); err != nil {
+		if !errors.Is(err, models.ErrManifestMismatch) && !errors.Is(err, models.ErrSourceMissing) {
+			return nil, err
+		}
+		merged.Valid = false
+		merged.Problems = append(merged.Problems, err.Error())
+	}
+
+	for _, file := range manifest.Files {
+		inspection, err := l.loader.InspectSource(ctx, l.format, file.Path)
+		if errors.Is(err, models.ErrSourceMissing) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		merged.SizeBytes += inspection.SizeBytes
+		merged.Records += inspection.Records
+		if merged.Columns == nil {
+			merged.Columns = inspection.Columns
+			merged.MissingColumns = inspection.MissingColumns
+		}
+		merged.Valid = merged.Valid && inspection.Valid
+		for _, problem := range inspection.Problems {
+			merged.Problems = append(merged.Problems, filepath.Base(file.Path)+": "+problem)
+		}
+	}
+	return merged, nil
+}
+
+// sources returns the files to load. A manifest is verified first, so a
+// load never starts on a partial or corrupted drop.
+func (l *DataLoader) sources(ctx context.Context) ([]string, error) {
+	if IsRemoteSource(l.csvPath) {
+		local, err := l.fetchRemote(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return []string{local}, nil
+	}
+	if !isManifest(l.csvPath) {
+		return []string{l.csvPath}, nil
+	}
+
+	manifest, err := readManifest(l.csvPath)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	if err := verifyManifest(ctx, manifest, l.format); err != nil {
+		return nil, fmt.Errorf("manifest verification failed: %w", err)
+	}
+	l.logger.Info("Manifest verified", "manifest", l.csvPath, "files", len(manifest.Files), "duration", time.Since(start))
+
+	paths := make([]string, len(manifest.Files))
+	for i, file := range manifest.Files {
+		paths[i] = file.Path
+	}
+	return paths, nil
+}
+
+// fetchRemote downloads the configured object and returns its local copy.
+// A manifest must be local, as its parts are resolved against its
+// directory.
+func (l *DataLoader) fetchRemote(ctx context.Context) (string, error) {
+	if isManifest(l.csvPath) {
+		return "", fmt.Errorf("%w: %s must be a local file", models.ErrInvalidManifest, l.csvPath)
+	}
+	start := time.Now()
+	local, size, cached, err := l.remote.Fetch(ctx, l.csvPath)
+	if err != nil {
+		return "", err
+	}
+	if cached {
+		l.logger.Info("Remote data file unchanged, using cached copy", "source", l.csvPath, "file", local, "bytes", size)
+		return local, nil
+	}
+	l.logger.Info("Remote data file downloaded", "source", l.csvPath, "file", local, "bytes", size, "duration", time.Since(start))
+	return local, nil
+}
+
+// Reload forces the CSV to be loaded again and waits for it. Requests keep
+// being served from the current data while it runs. The load gets the
+// configured timeout, or timeout if positive; it keeps running if ctx is
+// done first.
+func (l *DataLoader) Reload(ctx context.Context, timeout time.Duration) error {
+	return l.load(ctx, "refresh", PriorityManual, timeout, nil)
+}
+
+// StartReload queues a reload like Reload and returns at once with the job,
+// so no request goroutine is held for the load; a reload already queued is
+// returned instead of queueing another. then, if not nil, runs as part of
+// the job after a successful load and fails the job with its error.
+func (l *DataLoader) StartReload(timeout time.Duration, then func(context.Context) error) models.JobInfo {
+	load := l.loadJob("refresh", PriorityManual, l.timeout(timeout), nil)
+	return l.jobs.Submit("refresh", PriorityManual, func(ctx context.Context) error {
+		if err := load(ctx); err != nil {
+			return fmt.Errorf("failed to load data: %w", err)
+		}
+		if then != nil {
+			return then(ctx)
+		}
+		return nil
+	})
+}
+
+// WaitJob waits for a job, such as one StartReload returned, to finish or
+// for ctx to be done. It returns the job with the error it failed with.
+func (l *DataLoader) WaitJob(ctx context.Context, id uint64) (models.JobInfo, error) {
+	return l.jobs.Wait(ctx, id)
+}
+
+// RefreshStatus reports the refreshes requested through the API: the one
+// loading, the one queued behind it, and the last one to finish
+func (l *DataLoader) RefreshStatus() models.RefreshStatus {
+	running, queued, last := l.jobs.Latest("refresh")
+	return models.RefreshStatus{
+		Running:     running,
+		Queued:      queued,
+		Last:        last,
+		DataVersion: l.Version(),
+	}
+}
+
+// SourceChanged reloads the source after a watcher saw it change, queued as
+// scheduled work behind refreshes and uploads. Until data has been loaded
+// there is nothing to replace, as the first request loads the new file.
+func (l *DataLoader) SourceChanged(ctx context.Context) error {
+	l.mu.Lock()
+	loaded := l.loaded
+	l.mu.Unlock()
+	if !loaded {
+		return nil
+	}
+	return l.load(ctx, "file_change", PriorityScheduled, 0, nil)
+}
+
+// ScheduledReload reloads the source on behalf of the refresh schedule,
+// queued as scheduled work behind refreshes and uploads
+func (l *DataLoader) ScheduledReload(ctx context.Context) error {
+	return l.load(ctx, "scheduled_refresh", PriorityScheduled, 0, nil)
+}
+
+// LoadUpload replaces the loaded data with an uploaded dataset, queued like
+// a refresh. The data stays until the next load, which reads the configured
+// source again.
+func (l *DataLoader) LoadUpload(ctx context.Context, upload models.DatasetUpload) error {
+	return l.load(ctx, "dataset_upload", PriorityManual, 0, &upload)
+}
+
+// RunJob runs fn on the job queue as a manual job, so it never overlaps a
+// load. The job completes even if ctx is done first.
+func (l *DataLoader) RunJob(ctx context.Context, kind string, fn func(context.Context) error) error {
+	return l.jobs.Run(ctx, kind, PriorityManual, fn)
+}
+
+// Rollback restores the data replaced by the latest load. It counts as a
+// new data version and runs the refresh hooks like a load. The restored data
+// is marked loaded so a request after a failed load does not retry the CSV
+// over it.
+func (l *DataLoader) Rollback(ctx context.Context) (*models.DataSnapshot, error) {
+	var snapshot *models.DataSnapshot
+	err := l.jobs.Run(ctx, "rollback", PriorityManual, func(ctx context.Context) error {
+		var err error
+		if snapshot, err = l.loader.Rollback(ctx); err != nil {
+			return err
+		}
+
+		l.mu.Lock()
+		l.loaded = true
+		l.version++
+		hooks := l.hooks
+		l.mu.Unlock()
+
+		runHooks(hooks, nil)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to roll back data: %w", err)
+	}
+	return snapshot, nil
+}
+
+// MarkLoaded records that data was loaded by other means (a backup restore),
+// so the next request does not overwrite it with the CSV
+func (l *DataLoader) MarkLoaded() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.loaded = true
+	l.version++
+}
+
+// load queues a load and waits for it. The timeout starts when the job
+// does, not while it is queued. A failure clears the loaded flag so the next
+// request retries instead of trusting a half-refreshed backend.
+func (l *DataLoader) load(ctx context.Context, kind string, priority JobPriority, timeout time.Duration, upload *models.DatasetUpload) error {
+	if err := l.jobs.Run(ctx, kind, priority, l.loadJob(kind, priority, l.timeout(timeout), upload)); err != nil {
+		return fmt.Errorf("failed to load data: %w", err)
+	}
+	return nil
+}
+
+// loadJob returns the job body of a load bounded by timeout
+func (l *DataLoader) loadJob(kind string, priority JobPriority, timeout time.Duration, upload *models.DatasetUpload) func(context.Context) error {
+	return func(ctx context.Context) error {
+		if priority == PriorityInitial && l.reusePersisted(ctx) {
+			return nil
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		err := l.runLoad(ctx, kind, upload)
+		if errors.Is(err, context.DeadlineExceeded) {
+			return fmt.Errorf("%w after %s: %w", models.ErrLoadTimeout, timeout, err)
+		}
+		return err
+	}
+}
+
+// reusePersisted keeps the data a persistent backend holds from an earlier
+// run, unless the source files changed since it was loaded. Manifest parts
+// are compared by size and modification time without being verified again.
+// A remote source is always loaded, as telling whether it changed takes
+// downloading it.
+func (l *DataLoader) reusePersisted(ctx context.Context) bool {
+	store, ok := l.loader.(PersistentStore)
+	if !ok || IsRemoteSource(l.csvPath) {
+		return false
+	}
+
+	paths := []string{l.csvPath}
+	if isManifest(l.csvPath) {
+		manifest, err := readManifest(l.csvPath)
+		if err != nil {
+			return false
+		}
+		paths = paths[:0]
+		for _, file := range manifest.Files {
+			paths = append(paths, file.Path)
+		}
+	}
+	persisted, err := store.PersistedData(ctx, paths)
+	if err != nil {
+		l.logger.Warn("Failed to check persisted data, loading the source", "error", err)
+		return false
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.stats.Persisted = persisted
+	if persisted == nil {
+		return false
+	}
+	if persisted.Stale {
+		l.logger.Info("Persisted data is stale, loading the source", "loaded_at", persisted.LoadedAt, "reason", persisted.Reason)
+		return false
+	}
+	l.logger.Info("Reusing persisted data", "loaded_at", persisted.LoadedAt)
+	l.loaded = true
+	l.version++
+	return true
+}
+
+// timeout returns override if positive, the configured load timeout otherwise
+func (l *DataLoader) timeout(override time.Duration) time.Duration {
+	if override > 0 {
+		return override
+	}
+	return l.loadTimeout
+}
+
+// runLoad loads the source files, or upload if not nil, and records the
+// outcome; it runs as a queued job. A load refused by the quota never
+// reaches the backend, so the data already loaded stays in place.
+func (l *DataLoader) runLoad(ctx context.Context, kind string, upload *models.DatasetUpload) error {
+	start := time.Now()
+	var paths []string
+	var tenant string
+	format := l.format
+	var err error
+	if upload != nil {
+		paths, format, tenant = upload.Paths, upload.Format, upload.Tenant
+	} else {
+		paths, err = l.sources(ctx)
+	}
+	if err == nil {
+		err = l.quotas.CheckFiles(ctx, models.DatasetTransactions, tenant, paths...)
+	}
+	attempted := err == nil
+	if attempted {
+		err = l.loader.LoadFromFile(ctx, format, paths...)
+	}
+
+	l.mu.Lock()
+	l.loaded = err == nil || (l.loaded && errors.Is(err, models.ErrQuotaExceeded))
+	l.stats.Loads++
+	if err == nil {
+		l.lastDuration = time.Since(start)
+		l.lastSize = 0
+		for _, path := range paths {
+			if info, statErr := os.Stat(path); statErr == nil {
+				l.lastSize += info.Size()
+			}
+		}
+		l.version++
+	} else {
+		l.stats.Failures++
+	}
+	duration, size := l.lastDuration, l.lastSize
+	hooks := l.hooks
+	l.mu.Unlock()
+
+	var rows int64
+	if source, ok := l.loader.(coverageSource); ok && err == nil {
+		rows = int64(source.DataCoverage().Records)
+	}
+	l.metrics.ObserveLoad(kind, err, duration, rows, size)
+	if source, ok := l.loader.(ingestSource); ok && attempted {
+		l.metrics.ObserveIngest(source.LastIngest())
+	}
+
+	if upload != nil && upload.Release != nil {
+		upload.Release(err)
+	}
+	runHooks(hooks, err)
+	return err
+}
+
+// runHooks runs the refresh hooks in the background so slow hooks (webhooks,
+// for example) don't hold up the request that triggered the load
+func runHooks(hooks []RefreshHook, err error) {
+	for _, hook := range hooks {
+		go hook(context.Background(), err)
+	}
+}
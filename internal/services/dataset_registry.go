@@ -0,0 +1,128 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"analytics-dashboard-api/internal/models"
+)
+
+// datasetIDPattern keeps dataset IDs usable in paths, file names and table
+// names
+var datasetIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_]{0,31}$`)
+
+// ParseDatasets reads the named datasets listed in spec as "id=path" pairs
+// separated by semicolons, in order. IDs are lowercase letters, digits and
+// underscores; "default" is taken by DATA_FILE_PATH.
+func ParseDatasets(spec string) ([]models.DatasetSpec, error) {
+	var specs []models.DatasetSpec
+	seen := map[string]bool{models.DefaultDataset: true}
+	for _, pair := range strings.Split(spec, ";") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		id, path, ok := strings.Cut(pair, "=")
+		id, path = strings.TrimSpace(id), strings.TrimSpace(path)
+		if !ok || path == "" {
+			return nil, fmt.Errorf("invalid dataset entry %q: want id=path", pair)
+		}
+		if !datasetIDPattern.MatchString(id) {
+			return nil, fmt.Errorf("invalid dataset ID %q: use up to 32 lowercase letters, digits and underscores", id)
+		}
+		if seen[id] {
+			return nil, fmt.Errorf("invalid dataset entry %q: ID already used", pair)
+		}
+		seen[id] = true
+		specs = append(specs, models.DatasetSpec{ID: id, Path: path})
+	}
+	return specs, nil
+}
+
+// registeredDataset is a dataset, the backend holding it and the loader
+// that fills the backend
+type registeredDataset struct {
+	spec   models.DatasetSpec
+	repo   Repository
+	loader *DataLoader
+}
+
+// DatasetRegistry serves several transactions sources from one instance.
+// Each dataset has a backend and a loader of its own, so its data, version
+// and load state are independent of the others; the default dataset is the
+// one every other service works with.
+type DatasetRegistry struct {
+	datasets map[string]*registeredDataset
+	order    []string
+}
+
+// NewDatasetRegistry returns a registry holding the default dataset, read
+// from path into repo by loader
+func NewDatasetRegistry(repo Repository, loader *DataLoader, path string) *DatasetRegistry {
+	r := &DatasetRegistry{datasets: map[string]*registeredDataset{}}
+	r.add(models.DatasetSpec{ID: models.DefaultDataset, Path: path}, repo, loader)
+	return r
+}
+
+// Add registers a named dataset, loaded on first use. The registry closes
+// its backend on Close.
+func (r *DatasetRegistry) Add(spec models.DatasetSpec, repo Repository, loader *DataLoader) error {
+	if _, ok := r.datasets[spec.ID]; ok {
+		return fmt.Errorf("dataset %q already registered", spec.ID)
+	}
+	r.add(spec, repo, loader)
+	return nil
+}
+
+func (r *DatasetRegistry) add(spec models.DatasetSpec, repo Repository, loader *DataLoader) {
+	r.datasets[spec.ID] = &registeredDataset{spec: spec, repo: repo, loader: loader}
+	r.order = append(r.order, spec.ID)
+}
+
+// Dataset returns the backend of the dataset id, loading it first if it was
+// not loaded yet. Load failures are reported as by DataLoader.EnsureInitialized.
+func (r *DatasetRegistry) Dataset(ctx context.Context, id string) (Repository, error) {
+	dataset, ok := r.datasets[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", models.ErrDatasetNotFound, id)
+	}
+	if err := dataset.loader.EnsureInitialized(ctx); err != nil {
+		return nil, err
+	}
+	return dataset.repo, nil
+}
+
+// List describes every dataset, the default one first and the others in
+// configuration order
+func (r *DatasetRegistry) List() []models.DatasetInfo {
+	infos := make([]models.DatasetInfo, 0, len(r.order))
+	for _, id := range r.order {
+		dataset := r.datasets[id]
+		stats := dataset.loader.Stats()
+		info := models.DatasetInfo{
+			ID:          id,
+			Source:      dataset.spec.Path,
+			Loaded:      stats.Loaded,
+			DataVersion: stats.DataVersion,
+		}
+		if stats.Loaded {
+			coverage := dataset.repo.DataCoverage()
+			info.Coverage = &coverage
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+// Close closes the backends of the named datasets. The default dataset's
+// backend belongs to the caller.
+func (r *DatasetRegistry) Close() error {
+	for _, id := range r.order {
+		if id != models.DefaultDataset {
+			r.datasets[id].repo.Close()
+		}
+	}
+	return nil
+}
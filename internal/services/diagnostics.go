@@ -0,0 +1,242 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"analytics-dashboard-api/internal/config"
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/pkg/logger"
+)
+
+// errDiskSpaceUnsupported is returned where free space cannot be measured
+var errDiskSpaceUnsupported = errors.New("free disk space cannot be measured on this platform")
+
+// diagnosticQueryTimeout bounds the backend's part of the self-diagnostics
+const diagnosticQueryTimeout = 5 * time.Second
+
+// maxClockDrift is how far the wall clock may move against the monotonic
+// clock since startup, or data may seem loaded in the future, before the
+// clock check complains
+const maxClockDrift = time.Minute
+
+// DiagnosticPath is a directory the server keeps data in
+type DiagnosticPath struct {
+	Name     string // names the checks, e.g. "backups"
+	Dir      string
+	Writable bool // whether the server writes there, checked by writing a file
+}
+
+// ExtensionChecker is implemented by backends that depend on database
+// extensions. MissingExtensions lists those that can be neither loaded nor
+// installed.
+type ExtensionChecker interface {
+	MissingExtensions(context.Context) ([]string, error)
+}
+
+// Diagnostics checks the environment the server runs in: free space and
+// write access where it keeps data, the backend's extensions, a sample
+// query and the clock. Every check runs on each request, so the report
+// shows the state at the time it was asked for.
+type Diagnostics struct {
+	cfg     config.DiagnosticsConfig
+	backend string
+	paths   []DiagnosticPath
+	repo    Repository
+	loader  interface{ Stats() models.LoaderStats }
+	started time.Time
+	logger  logger.Logger
+}
+
+func NewDiagnostics(cfg config.DiagnosticsConfig, backend string, paths []DiagnosticPath, repo Repository, loader *DataLoader, logger logger.Logger) *Diagnostics {
+	return &Diagnostics{
+		cfg:     cfg,
+		backend: backend,
+		paths:   paths,
+		repo:    repo,
+		loader:  loader,
+		started: time.Now(),
+		logger:  logger,
+	}
+}
+
+// Run runs every check and reports each outcome. A failing check does not
+// stop the others; the report takes the worst outcome of its checks.
+func (d *Diagnostics) Run(ctx context.Context) models.DiagnosticsReport {
+	start := time.Now()
+	report := models.DiagnosticsReport{
+		Status:    models.DiagnosticPass,
+		CheckedAt: start.UTC(),
+		Backend:   d.backend,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+		Uptime:    time.Since(d.started).Round(time.Second).String(),
+	}
+
+	var failed []string
+	run := func(name string, check func() (string, string)) {
+		checkStart := time.Now()
+		status, detail := check()
+		report.Checks = append(report.Checks, models.DiagnosticCheck{
+			Name:       name,
+			Status:     status,
+			Detail:     detail,
+			DurationMs: time.Since(checkStart).Milliseconds(),
+		})
+		switch {
+		case status == models.DiagnosticFail:
+			report.Status = models.DiagnosticFail
+			failed = append(failed, name)
+		case status == models.DiagnosticWarn && report.Status == models.DiagnosticPass:
+			report.Status = models.DiagnosticWarn
+		}
+	}
+
+	for _, path := range d.paths {
+		run("disk_space:"+path.Name, func() (string, string) { return d.checkDiskSpace(path) })
+		if path.Writable {
+			run("write_access:"+path.Name, func() (string, string) { return checkWriteAccess(path) })
+		}
+	}
+	run("database_extensions", func() (string, string) { return d.checkExtensions(ctx) })
+	run("sample_query", func() (string, string) { return d.checkSampleQuery(ctx) })
+	run("clock", d.checkClock)
+
+	report.DurationMs = time.Since(start).Milliseconds()
+	if len(failed) > 0 {
+		d.logger.Warn("Self-diagnostics found failures", "checks", strings.Join(failed, ","))
+	}
+	return report
+}
+
+// checkDiskSpace compares the free space where path's files go with the
+// configured minimum
+func (d *Diagnostics) checkDiskSpace(path DiagnosticPath) (string, string) {
+	dir, err := existingDir(path.Dir)
+	if err != nil {
+		return models.DiagnosticFail, err.Error()
+	}
+	free, total, err := diskSpace(dir)
+	if errors.Is(err, errDiskSpaceUnsupported) {
+		return models.DiagnosticSkip, err.Error()
+	}
+	if err != nil {
+		return models.DiagnosticFail, fmt.Sprintf("cannot measure free space of %s: %v", dir, err)
+	}
+
+	detail := fmt.Sprintf("%s: %d MiB free of %d MiB", dir, free>>20, total>>20)
+	if free < uint64(d.cfg.MinFreeBytes) {
+		return models.DiagnosticFail, fmt.Sprintf("%s, below the minimum of %d MiB", detail, d.cfg.MinFreeBytes>>20)
+	}
+	return models.DiagnosticPass, detail
+}
+
+// checkWriteAccess writes and removes a file where path's files go. A
+// directory the server has not created yet is checked in the nearest
+// parent that exists, where it would be created.
+func checkWriteAccess(path DiagnosticPath) (string, string) {
+	dir, err := existingDir(path.Dir)
+	if err != nil {
+		return models.DiagnosticFail, err.Error()
+	}
+	file, err := os.CreateTemp(dir, ".diagnostics-*")
+	if err != nil {
+		return models.DiagnosticFail, fmt.Sprintf("cannot write to %s: %v", dir, err)
+	}
+	file.Close()
+	os.Remove(file.Name())
+
+	if dir != filepath.Clean(path.Dir) {
+		return models.DiagnosticPass, fmt.Sprintf("%s does not exist yet and can be created in %s", path.Dir, dir)
+	}
+	return models.DiagnosticPass, dir + " is writable"
+}
+
+// existingDir returns dir, or its nearest parent that exists when dir does
+// not exist yet
+func existingDir(dir string) (string, error) {
+	dir = filepath.Clean(dir)
+	for {
+		info, err := os.Stat(dir)
+		switch {
+		case err == nil && info.IsDir():
+			return dir, nil
+		case err == nil:
+			return "", fmt.Errorf("%s is not a directory", dir)
+		case !errors.Is(err, os.ErrNotExist):
+			return "", fmt.Errorf("cannot access %s: %w", dir, err)
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", fmt.Errorf("no parent of %s exists", dir)
+		}
+		dir = parent
+	}
+}
+
+// checkExtensions asks the backend for the extensions it needs but cannot
+// load
+func (d *Diagnostics) checkExtensions(ctx context.Context) (string, string) {
+	checker, ok := d.repo.(ExtensionChecker)
+	if !ok {
+		return models.DiagnosticSkip, fmt.Sprintf("the %s backend uses no database extensions", d.backend)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, diagnosticQueryTimeout)
+	defer cancel()
+	missing, err := checker.MissingExtensions(ctx)
+	if err != nil {
+		return models.DiagnosticFail, fmt.Sprintf("cannot list extensions: %v", err)
+	}
+	if len(missing) > 0 {
+		return models.DiagnosticFail, "missing extensions: " + strings.Join(missing, ", ")
+	}
+	return models.DiagnosticPass, "all required extensions are available"
+}
+
+// checkSampleQuery pings the backend and, once data is loaded, counts the
+// transactions. It never starts a load.
+func (d *Diagnostics) checkSampleQuery(ctx context.Context) (string, string) {
+	ctx, cancel := context.WithTimeout(ctx, diagnosticQueryTimeout)
+	defer cancel()
+
+	if err := d.repo.Ping(ctx); err != nil {
+		return models.DiagnosticFail, err.Error()
+	}
+	if !d.loader.Stats().Loaded {
+		return models.DiagnosticWarn, "the backend answers, but no data is loaded to query"
+	}
+	records, err := d.repo.GetTotalRecords(ctx)
+	if err != nil {
+		return models.DiagnosticFail, fmt.Sprintf("counting transactions failed: %v", err)
+	}
+	return models.DiagnosticPass, fmt.Sprintf("counted %d transactions", records)
+}
+
+// checkClock looks for a clock that is far off: one set before 2020, one
+// that went back past the latest load, or a wall clock that jumped since
+// startup
+func (d *Diagnostics) checkClock() (string, string) {
+	now := time.Now()
+	zone, _ := now.Zone()
+	detail := fmt.Sprintf("%s (local zone %s)", now.UTC().Format(time.RFC3339), zone)
+
+	if now.Year() < 2020 {
+		return models.DiagnosticFail, detail + ", before 2020"
+	}
+	if loadedAt := d.repo.DataCoverage().LoadedAt; loadedAt.After(now.Add(maxClockDrift)) {
+		return models.DiagnosticFail, fmt.Sprintf("%s, before the latest load at %s", detail, loadedAt.UTC().Format(time.RFC3339))
+	}
+	// Round(0) drops the monotonic reading, so this compares wall clocks
+	drift := now.Round(0).Sub(d.started.Round(0)) - now.Sub(d.started)
+	if drift.Abs() > maxClockDrift {
+		return models.DiagnosticWarn, fmt.Sprintf("%s, the wall clock moved %s against the monotonic clock since startup", detail, drift.Round(time.Second))
+	}
+	return models.DiagnosticPass, detail
+}
@@ -0,0 +1,15 @@
+//go:build linux || darwin
+
+package services
+
+import "syscall"
+
+// diskSpace returns the bytes available to the server and the size of the
+// file system holding dir
+func diskSpace(dir string) (free, total uint64, err error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(dir, &stat); err != nil {
+		return 0, 0, err
+	}
+	return stat.Bavail * uint64(stat.Bsize), stat.Blocks * uint64(stat.Bsize), nil
+}
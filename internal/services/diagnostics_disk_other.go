@@ -0,0 +1,8 @@
+//go:build !linux && !darwin
+
+package services
+
+// diskSpace cannot measure free space on this platform
+func diskSpace(string) (free, total uint64, err error) {
+	return 0, 0, errDiskSpaceUnsupported
+}
@@ -0,0 +1,44 @@
+//go:build cgo
+
+package services
+
+import (
+	"context"
+)
+
+// GetAlertMetrics returns the dataset metrics alert rules are evaluated
+// against. Daily and monthly figures cover the latest day and month present
+// in the data rather than the wall clock, so historical datasets still alert.
+func (s *DuckDBService) GetAlertMetrics(ctx context.Context) (map[string]float64, error) {
+	ctx, done := s.begin(ctx, "alert_metrics")
+	defer done()
+
+	var totalRecords, dailyTransactions int64
+	var totalRevenue, dailyRevenue, monthlyRevenue float64
+
+	err := s.db.QueryRowContext(ctx, `
+		WITH latest AS (
+			SELECT MAX(transaction_date) as day FROM transactions
+		)
+		SELECT 
+			COUNT(*),
+			CAST(COALESCE(SUM(total_price), 0) AS DOUBLE),
+			CAST(COALESCE(SUM(total_price) FILTER (WHERE transaction_date = latest.day), 0) AS DOUBLE),
+			COUNT(*) FILTER (WHERE transaction_date = latest.day),
+			CAST(COALESCE(SUM(total_price) FILTER (
+				WHERE date_trunc('month', transaction_date) = date_trunc('month', latest.day)
+			), 0) AS DOUBLE)
+		FROM transactions, latest
+	`).Scan(&totalRecords, &totalRevenue, &dailyRevenue, &dailyTransactions, &monthlyRevenue)
+	if err != nil {
+		return nil, queryError("failed to query alert metrics", err)
+	}
+
+	return map[string]float64{
+		"total_records":      float64(totalRecords),
+		"total_revenue":      totalRevenue,
+		"daily_revenue":      dailyRevenue,
+		"daily_transactions": float64(dailyTransactions),
+		"monthly_revenue":    monthlyRevenue,
+	}, nil
+}
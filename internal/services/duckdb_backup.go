@@ -0,0 +1,150 @@
+//go:build cgo
+
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"analytics-dashboard-api/internal/models"
+)
+
+// Backup exports every registered table as a Parquet file into a new
+// timestamped directory under backupDir. The files are written to a hidden
+// directory that is renamed into place once complete, so listings and
+// restores never see a partial backup.
+func (s *DuckDBService) Backup(ctx context.Context, backupDir string) (*models.BackupInfo, error) {
+	startTime := time.Now()
+	if err := os.MkdirAll(backupDir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create backup directory: %w", err)
+	}
+	tmp, err := os.MkdirTemp(backupDir, ".backup-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create backup directory: %w", err)
+	}
+	defer os.RemoveAll(tmp) // fails harmlessly once renamed
+
+	for _, spec := range tableRegistry {
+		path := filepath.Join(tmp, spec.Name+".parquet")
+		copySQL := fmt.Sprintf("COPY %s TO '%s' (FORMAT PARQUET)", spec.Name, escapeLiteral(path))
+		if _, err := s.db.ExecContext(ctx, copySQL); err != nil {
+			return nil, fmt.Errorf("failed to export %s: %w", spec.Name, err)
+		}
+	}
+
+	records, err := s.GetTotalRecords(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get row count: %w", err)
+	}
+
+	name, dir, err := placeBackup(backupDir, tmp, startTime.UTC())
+	if err != nil {
+		return nil, err
+	}
+	createdAt, _ := time.Parse(backupNameLayout, name)
+
+	info := &models.BackupInfo{
+		Name:      name,
+		Path:      dir,
+		Records:   records,
+		SizeBytes: dirSize(dir),
+		CreatedAt: createdAt,
+	}
+
+	s.logger.Info("Backup created",
+		"name", name,
+		"records", records,
+		"duration", time.Since(startTime))
+
+	return info, nil
+}
+
+// Restore replaces the loaded tables with the contents of the named backup.
+// An empty name restores the most recent backup.
+func (s *DuckDBService) Restore(ctx context.Context, backupDir, name string) (*models.BackupInfo, error) {
+	if name == "" {
+		latest, err := latestBackup(backupDir)
+		if err != nil {
+			return nil, err
+		}
+		name = latest
+	}
+
+	if !backupNamePattern.MatchString(name) {
+		return nil, fmt.Errorf("invalid backup name %q: %w", name, models.ErrBackupNotFound)
+	}
+
+	dir := filepath.Join(backupDir, name)
+	if _, err := os.Stat(filepath.Join(dir, transactionsTable.Name+".parquet")); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, models.ErrBackupNotFound)
+	}
+
+	startTime := time.Now()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin restore: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, spec := range tableRegistry {
+		path := filepath.Join(dir, spec.Name+".parquet")
+		if _, err := os.Stat(path); err != nil {
+			// Backups taken before a table was registered don't include it
+			continue
+		}
+
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s", spec.Name)); err != nil {
+			return nil, fmt.Errorf("failed to clear %s: %w", spec.Name, err)
+		}
+
+		insertSQL := fmt.Sprintf("INSERT INTO %s SELECT * FROM read_parquet('%s')", spec.Name, escapeLiteral(path))
+		if _, err := tx.ExecContext(ctx, insertSQL); err != nil {
+			return nil, fmt.Errorf("failed to import %s: %w", spec.Name, err)
+		}
+	}
+
+	// Backups do not keep outliers, so the restored data has none listed
+	if _, err := tx.ExecContext(ctx, "DELETE FROM "+outliersTable.Name); err != nil {
+		return nil, fmt.Errorf("failed to clear %s: %w", outliersTable.Name, err)
+	}
+	// Transactions flagged since the backup was taken stay excluded
+	excluded, err := s.excludeTransactions(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+	testOrders, err := s.excludeTestOrders(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit restore: %w", err)
+	}
+
+	if err := s.refreshCoverage(ctx, nil, excluded, testOrders); err != nil {
+		return nil, err
+	}
+	s.forgetSources(ctx)
+
+	records, err := s.GetTotalRecords(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get row count: %w", err)
+	}
+
+	createdAt, _ := time.Parse(backupNameLayout, name)
+	s.logger.Info("Backup restored",
+		"name", name,
+		"records", records,
+		"duration", time.Since(startTime))
+
+	return &models.BackupInfo{
+		Name:      name,
+		Path:      dir,
+		Records:   records,
+		SizeBytes: dirSize(dir),
+		CreatedAt: createdAt,
+	}, nil
+}
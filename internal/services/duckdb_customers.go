@@ -0,0 +1,138 @@
+//go:build cgo
+
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"analytics-dashboard-api/internal/models"
+)
+
+// GetSegmentBreakdown returns revenue and retention per customer segment.
+// Customers missing from the customers dimension are grouped as "Unknown".
+// Retention is the share of customers who purchased in more than one month.
+func (s *DuckDBService) GetSegmentBreakdown(ctx context.Context, opts models.QueryOptions) ([]models.SegmentRevenue, error) {
+	ctx, done := s.begin(ctx, "segment_breakdown")
+	defer done()
+
+	source, sourceArgs := sourceRelation(opts)
+	query := fmt.Sprintf(`
+		WITH per_customer AS (
+			SELECT 
+				COALESCE(c.segment, 'Unknown') as segment,
+				t.user_id,
+				SUM(t.total_price) as revenue,
+				COUNT(*) as orders,
+				COUNT(DISTINCT STRFTIME('%%Y-%%m', t.transaction_date)) as active_months
+			FROM %s t
+			LEFT JOIN customers c ON c.user_id = t.user_id
+			GROUP BY 1, 2
+		)
+		SELECT 
+			segment,
+			%s as total_revenue,
+			CAST(ROUND(SUM(orders) * ?) AS BIGINT) as transaction_count,
+			COUNT(*) as customer_count,
+			CAST(ROUND(SUM(revenue) * 100 / SUM(orders)) AS BIGINT) as avg_order_value,
+			CAST(AVG(CASE WHEN active_months > 1 THEN 1 ELSE 0 END) AS DOUBLE) as retention_rate
+		FROM per_customer
+		GROUP BY segment
+		ORDER BY total_revenue DESC
+	`, source, moneyCents("SUM(revenue)"))
+
+	scale := opts.ScaleFactor()
+	args := append(append([]interface{}{}, sourceArgs...), scale, scale)
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, queryError("failed to query segment breakdown", err)
+	}
+	defer rows.Close()
+
+	var results []models.SegmentRevenue
+	for rows.Next() {
+		var sr models.SegmentRevenue
+		err := rows.Scan(
+			&sr.Segment,
+			&sr.TotalRevenue,
+			&sr.TransactionCount,
+			&sr.CustomerCount,
+			&sr.AvgOrderValue,
+			&sr.RetentionRate,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan segment breakdown: %w", err)
+		}
+		results = append(results, sr)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, queryError("failed to query segment breakdown", err)
+	}
+	return results, nil
+}
+
+// GetTopCustomers returns the limit customers who spent the most, with
+// their order count and average order value. Ties go to the lower user ID.
+func (s *DuckDBService) GetTopCustomers(ctx context.Context, opts models.QueryOptions, limit int) ([]models.CustomerSpend, error) {
+	ctx, done := s.begin(ctx, "top_customers")
+	defer done()
+
+	source, sourceArgs := sourceRelation(opts)
+	query := fmt.Sprintf(`
+		SELECT 
+			user_id,
+			CAST(SUM(total_price) * 100 AS BIGINT) as total_spend,
+			COUNT(*) as order_count,
+			CAST(ROUND(SUM(total_price) * 100 / COUNT(*)) AS BIGINT) as avg_order_value
+		FROM %s
+		GROUP BY user_id
+		ORDER BY total_spend DESC, user_id
+		LIMIT ?
+	`, source)
+
+	args := append(append([]interface{}{}, sourceArgs...), limit)
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, queryError("failed to query top customers", err)
+	}
+	defer rows.Close()
+
+	var results []models.CustomerSpend
+	for rows.Next() {
+		var cs models.CustomerSpend
+		if err := rows.Scan(&cs.UserID, &cs.TotalSpend, &cs.OrderCount, &cs.AvgOrderValue); err != nil {
+			return nil, fmt.Errorf("failed to scan top customers: %w", err)
+		}
+		results = append(results, cs)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, queryError("failed to query top customers", err)
+	}
+	return results, nil
+}
+
+// GetRepeatPurchase counts the customers with more than one order across
+// the whole dataset
+func (s *DuckDBService) GetRepeatPurchase(ctx context.Context) (models.RepeatPurchase, error) {
+	ctx, done := s.begin(ctx, "repeat_purchase")
+	defer done()
+
+	var repeat models.RepeatPurchase
+	err := s.db.QueryRowContext(ctx, `
+		SELECT 
+			COUNT(*),
+			COUNT(*) FILTER (WHERE orders > 1)
+		FROM (
+			SELECT user_id, COUNT(*) as orders
+			FROM transactions
+			GROUP BY user_id
+		)
+	`).Scan(&repeat.Customers, &repeat.RepeatCustomers)
+	if err != nil {
+		return repeat, queryError("failed to query repeat purchases", err)
+	}
+	if repeat.Customers > 0 {
+		repeat.Rate = float64(repeat.RepeatCustomers) / float64(repeat.Customers)
+	}
+	return repeat, nil
+}
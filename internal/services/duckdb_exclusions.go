@@ -0,0 +1,78 @@
+//go:build cgo
+
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// excludeBatch bounds the IDs bound to one DELETE statement
+const excludeBatch = 500
+
+// ExcludeTransactions sets the transaction IDs that every later load and
+// restore leaves out
+func (s *DuckDBService) ExcludeTransactions(ids []string) error {
+	s.excludedMu.Lock()
+	defer s.excludedMu.Unlock()
+	s.excluded = slices.Clone(ids)
+	return nil
+}
+
+// excludeTransactions deletes the excluded transactions from the
+// transactions table and returns how many rows it removed
+func (s *DuckDBService) excludeTransactions(ctx context.Context, tx *sql.Tx) (int, error) {
+	s.excludedMu.RLock()
+	ids := s.excluded
+	s.excludedMu.RUnlock()
+
+	var removed int64
+	for batch := range slices.Chunk(ids, excludeBatch) {
+		args := make([]interface{}, len(batch))
+		for i, id := range batch {
+			args[i] = id
+		}
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(batch)), ", ")
+		res, err := tx.ExecContext(ctx, "DELETE FROM transactions WHERE transaction_id IN ("+placeholders+")", args...)
+		if err != nil {
+			return 0, fmt.Errorf("failed to exclude flagged transactions: %w", err)
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return 0, fmt.Errorf("failed to count excluded transactions: %w", err)
+		}
+		removed += n
+	}
+	return int(removed), nil
+}
+
+// excludeTestOrders deletes the transactions matching the test-order rules
+// and returns how many rows it removed
+func (s *DuckDBService) excludeTestOrders(ctx context.Context, tx *sql.Tx) (int, error) {
+	if s.testOrders.empty() {
+		return 0, nil
+	}
+
+	var conditions []string
+	var args []interface{}
+	for _, pattern := range s.testOrders.userPatterns {
+		conditions = append(conditions, "regexp_full_match(user_id, ?)")
+		args = append(args, pattern)
+	}
+	for _, prefix := range s.testOrders.productPrefixes {
+		conditions = append(conditions, "starts_with(product_id, ?)")
+		args = append(args, prefix)
+	}
+	res, err := tx.ExecContext(ctx, "DELETE FROM transactions WHERE "+strings.Join(conditions, " OR "), args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to exclude test orders: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count excluded test orders: %w", err)
+	}
+	return int(n), nil
+}
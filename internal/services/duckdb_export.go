@@ -0,0 +1,118 @@
+//go:build cgo
+
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"analytics-dashboard-api/internal/models"
+)
+
+// exportProgressRows is how often a CSV export reports progress
+const exportProgressRows = 10000
+
+// ExportTransactions writes the transactions matching opts to path as CSV or
+// Parquet, in date order, and returns the number of rows written. progress is
+// called with the rows written so far and the total: per batch of rows for
+// CSV, and only before and after the file is written for Parquet, which
+// DuckDB writes in one statement.
+func (s *DuckDBService) ExportTransactions(ctx context.Context, opts models.QueryOptions, format, path string, progress func(written, total int64)) (int64, error) {
+	defer s.observe(ctx, "export", time.Now())
+
+	source, args := sourceRelation(opts)
+
+	var total int64
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+source, args...).Scan(&total); err != nil {
+		return 0, queryError("failed to count export rows", err)
+	}
+	progress(0, total)
+
+	columns := make([]string, len(transactionsTable.Columns))
+	for i, column := range transactionsTable.Columns {
+		columns[i] = column.Name
+	}
+	query := fmt.Sprintf("SELECT %%s FROM %s ORDER BY transaction_date, transaction_id", source)
+
+	switch format {
+	case models.ExportFormatParquet:
+		copySQL := fmt.Sprintf("COPY (%s) TO '%s' (FORMAT PARQUET)",
+			fmt.Sprintf(query, strings.Join(columns, ", ")), escapeLiteral(path))
+		if _, err := s.db.ExecContext(ctx, copySQL, args...); err != nil {
+			return 0, queryError("failed to export transactions", err)
+		}
+		progress(total, total)
+		return total, nil
+
+	case models.ExportFormatCSV:
+		// Cast in SQL so dates and decimals are written as DuckDB prints them
+		casts := make([]string, len(columns))
+		for i, column := range columns {
+			casts[i] = fmt.Sprintf("CAST(%s AS VARCHAR)", column)
+		}
+		rows, err := s.db.QueryContext(ctx, fmt.Sprintf(query, strings.Join(casts, ", ")), args...)
+		if err != nil {
+			return 0, queryError("failed to query export rows", err)
+		}
+		defer rows.Close()
+		return writeExportCSV(path, columns, rows, total, progress)
+	}
+	return 0, fmt.Errorf("%w: unknown format %q", models.ErrInvalidExport, format)
+}
+
+// writeExportCSV writes a header and the VARCHAR rows to path. NULLs are
+// written as empty fields.
+func writeExportCSV(path string, columns []string, rows *sql.Rows, total int64, progress func(int64, int64)) (int64, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create export file: %w", err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	if err := writer.Write(columns); err != nil {
+		return 0, fmt.Errorf("failed to write export file: %w", err)
+	}
+
+	values := make([]sql.NullString, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	record := make([]string, len(columns))
+
+	var written int64
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return written, fmt.Errorf("failed to scan export row: %w", err)
+		}
+		for i, value := range values {
+			record[i] = value.String
+		}
+		if err := writer.Write(record); err != nil {
+			return written, fmt.Errorf("failed to write export file: %w", err)
+		}
+		written++
+		if written%exportProgressRows == 0 {
+			progress(written, total)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return written, queryError("failed to read export rows", err)
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return written, fmt.Errorf("failed to write export file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return written, fmt.Errorf("failed to write export file: %w", err)
+	}
+	progress(written, total)
+	return written, nil
+}
@@ -0,0 +1,76 @@
+//go:build cgo
+
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"analytics-dashboard-api/internal/models"
+)
+
+// ListDimensionValues returns a page of distinct values for a dimension with
+// transaction counts, most frequent first, optionally filtered by a
+// case-insensitive search
+func (s *DuckDBService) ListDimensionValues(ctx context.Context, dimension, search string, limit, offset int) ([]models.DimensionValue, error) {
+	ctx, done := s.begin(ctx, "dimension_values")
+	defer done()
+
+	column, ok := dimensionColumns[dimension]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", models.ErrUnknownDimension, dimension)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT 
+			%s as value,
+			COUNT(*) as count
+		FROM transactions
+		WHERE %s IS NOT NULL
+			AND (? = '' OR %s ILIKE '%%' || ? || '%%')
+		GROUP BY %s
+		ORDER BY count DESC, value
+		LIMIT ? OFFSET ?
+	`, column, column, column, column)
+
+	rows, err := s.db.QueryContext(ctx, query, search, search, limit, offset)
+	if err != nil {
+		return nil, queryError("failed to query "+dimension+" values", err)
+	}
+	defer rows.Close()
+
+	var results []models.DimensionValue
+	for rows.Next() {
+		var dv models.DimensionValue
+		if err := rows.Scan(&dv.Value, &dv.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan %s values: %w", dimension, err)
+		}
+		results = append(results, dv)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, queryError("failed to query "+dimension+" values", err)
+	}
+	return results, nil
+}
+
+// CountDimensionValues returns the number of distinct values matching search
+func (s *DuckDBService) CountDimensionValues(ctx context.Context, dimension, search string) (int, error) {
+	ctx, done := s.begin(ctx, "dimension_value_count")
+	defer done()
+
+	column, ok := dimensionColumns[dimension]
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", models.ErrUnknownDimension, dimension)
+	}
+
+	var count int
+	err := s.db.QueryRowContext(ctx, fmt.Sprintf(`
+		SELECT COUNT(DISTINCT %s)
+		FROM transactions
+		WHERE ? = '' OR %s ILIKE '%%' || ? || '%%'
+	`, column, column), search, search).Scan(&count)
+	if err != nil {
+		return 0, queryError("failed to count "+dimension+" values", err)
+	}
+	return count, nil
+}
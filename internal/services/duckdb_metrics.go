@@ -0,0 +1,113 @@
+//go:build cgo
+
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"analytics-dashboard-api/internal/models"
+)
+
+// GetBaseMetrics computes every base metric, either for the whole (filtered)
+// dataset or per group when groupBy names one of MetricGroupings. Distinct
+// customer and product counts are not extrapolated when sampling.
+func (s *DuckDBService) GetBaseMetrics(ctx context.Context, opts models.QueryOptions, groupBy string) ([]models.MetricRow, error) {
+	ctx, done := s.begin(ctx, "base_metrics")
+	defer done()
+
+	groupExpr := "''"
+	groupClause := ""
+	if groupBy != "" {
+		column, ok := MetricGroupings[groupBy]
+		if !ok {
+			return nil, fmt.Errorf("unsupported grouping %q", groupBy)
+		}
+		groupExpr = column
+		groupClause = "GROUP BY 1 ORDER BY 1"
+	}
+
+	source, sourceArgs := sourceRelation(opts)
+	query := fmt.Sprintf(`
+		SELECT 
+			COALESCE(CAST(%s AS VARCHAR), '') as grp,
+			CAST(COALESCE(SUM(total_price), 0) * ? AS DOUBLE) as revenue,
+			CAST(COUNT(*) * ? AS DOUBLE) as transactions,
+			CAST(COALESCE(SUM(quantity), 0) * ? AS DOUBLE) as quantity,
+			CAST(COUNT(DISTINCT user_id) AS DOUBLE) as customers,
+			CAST(COUNT(DISTINCT product_id) AS DOUBLE) as products
+		FROM %s 
+		%s
+	`, groupExpr, source, groupClause)
+
+	scale := opts.ScaleFactor()
+	args := append([]interface{}{scale, scale, scale}, sourceArgs...)
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, queryError("failed to query base metrics", err)
+	}
+	defer rows.Close()
+
+	var results []models.MetricRow
+	for rows.Next() {
+		var group string
+		var revenue, transactions, quantity, customers, products float64
+		if err := rows.Scan(&group, &revenue, &transactions, &quantity, &customers, &products); err != nil {
+			return nil, fmt.Errorf("failed to scan base metrics: %w", err)
+		}
+		results = append(results, models.MetricRow{
+			Group: group,
+			Values: map[string]float64{
+				"revenue":      revenue,
+				"transactions": transactions,
+				"quantity":     quantity,
+				"customers":    customers,
+				"products":     products,
+			},
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, queryError("failed to query base metrics", err)
+	}
+	return results, nil
+}
+
+// GetTimeSeries returns revenue, orders and units per day, week or month
+// with sales, in date order. Weeks start on Monday, as date_trunc has them.
+func (s *DuckDBService) GetTimeSeries(ctx context.Context, opts models.QueryOptions, granularity string) ([]models.TimeBucket, error) {
+	ctx, done := s.begin(ctx, "time_series")
+	defer done()
+
+	source, sourceArgs := sourceRelation(opts)
+	query := fmt.Sprintf(`
+		SELECT 
+			STRFTIME(date_trunc(?, transaction_date), '%%Y-%%m-%%d') as period,
+			%s as revenue,
+			CAST(ROUND(COUNT(*) * ?) AS BIGINT) as orders,
+			CAST(ROUND(SUM(quantity) * ?) AS BIGINT) as units
+		FROM %s 
+		GROUP BY 1
+		ORDER BY 1
+	`, moneyCents("SUM(total_price)"), source)
+
+	scale := opts.ScaleFactor()
+	args := append([]interface{}{granularity, scale, scale, scale}, sourceArgs...)
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, queryError("failed to query time series", err)
+	}
+	defer rows.Close()
+
+	var results []models.TimeBucket
+	for rows.Next() {
+		var bucket models.TimeBucket
+		if err := rows.Scan(&bucket.Start, &bucket.Revenue, &bucket.Orders, &bucket.Units); err != nil {
+			return nil, fmt.Errorf("failed to scan time series: %w", err)
+		}
+		results = append(results, bucket)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, queryError("failed to query time series", err)
+	}
+	return results, nil
+}
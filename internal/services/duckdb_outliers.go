@@ -0,0 +1,157 @@
+//go:build cgo
+
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"strconv"
+
+	"analytics-dashboard-api/internal/models"
+)
+
+// flagOutliers fills the outliers table from the transactions being loaded
+// and, in exclude mode, deletes the outliers from them. The report is nil
+// when detection is off.
+func (s *DuckDBService) flagOutliers(ctx context.Context, tx *sql.Tx) (*models.OutlierReport, error) {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM "+outliersTable.Name); err != nil {
+		return nil, fmt.Errorf("failed to clear %s: %w", outliersTable.Name, err)
+	}
+
+	cfg := s.outliers
+	if cfg.Mode != models.OutlierModeTag && cfg.Mode != models.OutlierModeExclude {
+		return nil, nil
+	}
+
+	var pricePercentile, quantityPercentile sql.NullFloat64
+	if cfg.Percentile > 0 {
+		err := tx.QueryRowContext(ctx, fmt.Sprintf(`
+			SELECT
+				CAST(quantile_cont(price, %[1]g) AS DOUBLE),
+				CAST(quantile_cont(quantity, %[1]g) AS DOUBLE)
+			FROM transactions
+		`, cfg.Percentile)).Scan(&pricePercentile, &quantityPercentile)
+		if err != nil {
+			return nil, queryError("failed to query outlier percentiles", err)
+		}
+	}
+	limits := newOutlierLimits(cfg, pricePercentile.Float64, quantityPercentile.Float64)
+	report := limits.report(cfg.Mode)
+
+	priceOutlier := outlierCondition("price", limits.price)
+	quantityOutlier := outlierCondition("quantity", limits.quantity)
+	flagged := fmt.Sprintf("%s OR %s", priceOutlier, quantityOutlier)
+
+	insertSQL := fmt.Sprintf(`
+		INSERT INTO %s
+		SELECT *, %s, %s
+		FROM transactions
+		WHERE %s
+	`, outliersTable.Name, priceOutlier, quantityOutlier, flagged)
+	if _, err := tx.ExecContext(ctx, insertSQL); err != nil {
+		return nil, fmt.Errorf("failed to flag outliers: %w", err)
+	}
+
+	err := tx.QueryRowContext(ctx, fmt.Sprintf(`
+		SELECT
+			COUNT(*),
+			COUNT(*) FILTER (WHERE price_outlier),
+			COUNT(*) FILTER (WHERE quantity_outlier)
+		FROM %s
+	`, outliersTable.Name)).Scan(&report.Rows, &report.PriceOutliers, &report.QuantityOutliers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count outliers: %w", err)
+	}
+
+	if cfg.Mode == models.OutlierModeExclude {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM transactions WHERE "+flagged); err != nil {
+			return nil, fmt.Errorf("failed to exclude outliers: %w", err)
+		}
+	}
+	return report, nil
+}
+
+// outlierCondition returns a SQL condition matching values above limit
+func outlierCondition(column string, limit float64) string {
+	if math.IsInf(limit, 1) {
+		return "false"
+	}
+	return fmt.Sprintf("%s > %s", column, strconv.FormatFloat(limit, 'g', -1, 64))
+}
+
+// ListOutliers returns a page of the transactions the latest load flagged,
+// largest total first
+func (s *DuckDBService) ListOutliers(ctx context.Context, limit, offset int) ([]models.Outlier, error) {
+	ctx, done := s.begin(ctx, "outliers")
+	defer done()
+
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT
+			transaction_id,
+			transaction_date,
+			COALESCE(user_id, ''),
+			COALESCE(country, ''),
+			COALESCE(region, ''),
+			COALESCE(product_id, ''),
+			COALESCE(product_name, ''),
+			COALESCE(category, ''),
+			CAST(COALESCE(price, 0) AS DOUBLE),
+			COALESCE(quantity, 0),
+			CAST(COALESCE(total_price, 0) AS DOUBLE),
+			COALESCE(stock_quantity, 0),
+			added_date,
+			price_outlier,
+			quantity_outlier
+		FROM %s
+		ORDER BY total_price DESC NULLS LAST, transaction_id
+		LIMIT ? OFFSET ?
+	`, outliersTable.Name), limit, offset)
+	if err != nil {
+		return nil, queryError("failed to query outliers", err)
+	}
+	defer rows.Close()
+
+	results := []models.Outlier{}
+	for rows.Next() {
+		var o models.Outlier
+		var id sql.NullString
+		var transactionDate, addedDate sql.NullTime
+		var priceOutlier, quantityOutlier bool
+		err := rows.Scan(
+			&id,
+			&transactionDate,
+			&o.UserID,
+			&o.Country,
+			&o.Region,
+			&o.ProductID,
+			&o.ProductName,
+			&o.Category,
+			&o.Price,
+			&o.Quantity,
+			&o.TotalPrice,
+			&o.StockQuantity,
+			&addedDate,
+			&priceOutlier,
+			&quantityOutlier,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan outliers: %w", err)
+		}
+		o.TransactionID = id.String
+		o.TransactionDate = transactionDate.Time
+		o.AddedDate = addedDate.Time
+		if priceOutlier {
+			o.Reasons = append(o.Reasons, "price")
+		}
+		if quantityOutlier {
+			o.Reasons = append(o.Reasons, "quantity")
+		}
+		results = append(results, o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, queryError("failed to query outliers", err)
+	}
+	return results, nil
+}
@@ -0,0 +1,156 @@
+//go:build cgo
+
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"analytics-dashboard-api/internal/models"
+)
+
+// sourceFile identifies a loaded file by size and modification time, which
+// is enough to notice a replaced export without hashing it
+type sourceFile struct {
+	Path     string    `json:"path"`
+	Size     int64     `json:"size"`
+	Modified time.Time `json:"modified"`
+}
+
+// statSources describes the files as they are now
+func statSources(paths []string) ([]sourceFile, error) {
+	files := make([]sourceFile, len(paths))
+	for i, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		files[i] = sourceFile{Path: path, Size: info.Size(), Modified: info.ModTime().UTC()}
+	}
+	return files, nil
+}
+
+// recordSources stores the loaded files and the coverage of their data in a
+// file-backed database. Failing to record only costs a full load on the next
+// start, so it is logged rather than failing the load.
+func (s *DuckDBService) recordSources(ctx context.Context, paths []string) {
+	if s.path == "" {
+		return
+	}
+	err := func() error {
+		files, err := statSources(paths)
+		if err != nil {
+			return err
+		}
+		sources, err := json.Marshal(files)
+		if err != nil {
+			return err
+		}
+		coverage, err := json.Marshal(s.DataCoverage())
+		if err != nil {
+			return err
+		}
+		return s.writeLoadState(ctx, string(sources), string(coverage))
+	}()
+	if err != nil {
+		s.logger.Warn("Failed to record loaded sources, the next start reloads them", "error", err)
+	}
+}
+
+// forgetSources clears the recorded sources after the data changed by other
+// means than a load (rollback, restore), so the next start reloads the files
+// instead of trusting data that no longer came from them
+func (s *DuckDBService) forgetSources(ctx context.Context) {
+	if s.path == "" {
+		return
+	}
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM "+loadStateTable.Name); err != nil {
+		s.logger.Warn("Failed to clear recorded sources", "error", err)
+	}
+}
+
+func (s *DuckDBService) writeLoadState(ctx context.Context, sources, coverage string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM "+loadStateTable.Name); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "INSERT INTO "+loadStateTable.Name+" VALUES (?, ?)", sources, coverage); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// readLoadState returns the recorded sources and coverage, or nil when
+// nothing was recorded
+func (s *DuckDBService) readLoadState(ctx context.Context) ([]sourceFile, *models.DataCoverage, error) {
+	var sources, coverage string
+	err := s.db.QueryRowContext(ctx, "SELECT sources, coverage FROM "+loadStateTable.Name+" LIMIT 1").Scan(&sources, &coverage)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, queryError("failed to read load state", err)
+	}
+
+	var files []sourceFile
+	var cov models.DataCoverage
+	if err := json.Unmarshal([]byte(sources), &files); err != nil {
+		return nil, nil, fmt.Errorf("invalid recorded sources: %w", err)
+	}
+	if err := json.Unmarshal([]byte(coverage), &cov); err != nil {
+		return nil, nil, fmt.Errorf("invalid recorded coverage: %w", err)
+	}
+	return files, &cov, nil
+}
+
+// PersistedData reports the data a file-backed database holds from an
+// earlier run, nil if there is none. The data is stale unless it was loaded
+// from exactly paths and none of them changed size or modification time
+// since. Fresh data gets its recorded coverage back, so it can be served
+// without a load.
+func (s *DuckDBService) PersistedData(ctx context.Context, paths []string) (*models.PersistedData, error) {
+	if s.path == "" {
+		return nil, nil
+	}
+	recorded, coverage, err := s.readLoadState(ctx)
+	if err != nil || recorded == nil {
+		return nil, err
+	}
+
+	persisted := &models.PersistedData{LoadedAt: coverage.LoadedAt}
+	current, err := statSources(paths)
+	switch {
+	case err != nil:
+		persisted.Stale, persisted.Reason = true, err.Error()
+	case len(current) != len(recorded):
+		persisted.Stale, persisted.Reason = true, "the source files changed"
+	default:
+		for i := range current {
+			if current[i].Path != recorded[i].Path {
+				persisted.Stale, persisted.Reason = true, "the source files changed"
+				break
+			}
+			if current[i].Size != recorded[i].Size || !current[i].Modified.Equal(recorded[i].Modified) {
+				persisted.Stale, persisted.Reason = true, current[i].Path+" was modified"
+				break
+			}
+		}
+	}
+
+	if !persisted.Stale {
+		s.coverageMu.Lock()
+		s.coverage = *coverage
+		s.coverageMu.Unlock()
+	}
+	return persisted, nil
+}
@@ -0,0 +1,219 @@
+//go:build cgo
+
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"analytics-dashboard-api/internal/models"
+)
+
+// ListProducts returns a page of the product catalog, optionally filtered by a
+// case-insensitive search over id, name and brand
+func (s *DuckDBService) ListProducts(ctx context.Context, search string, limit, offset int) ([]models.Product, error) {
+	ctx, done := s.begin(ctx, "products")
+	defer done()
+
+	query := `
+		SELECT 
+			product_id,
+			COALESCE(product_name, ''),
+			COALESCE(category, ''),
+			COALESCE(brand, ''),
+			COALESCE(supplier, '')
+		FROM products
+		WHERE ? = ''
+			OR product_id ILIKE '%' || ? || '%'
+			OR product_name ILIKE '%' || ? || '%'
+			OR brand ILIKE '%' || ? || '%'
+		ORDER BY product_id
+		LIMIT ? OFFSET ?
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, search, search, search, search, limit, offset)
+	if err != nil {
+		return nil, queryError("failed to query products", err)
+	}
+	defer rows.Close()
+
+	var results []models.Product
+	for rows.Next() {
+		var p models.Product
+		err := rows.Scan(
+			&p.ProductID,
+			&p.ProductName,
+			&p.Category,
+			&p.Brand,
+			&p.Supplier,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan products: %w", err)
+		}
+		results = append(results, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, queryError("failed to query products", err)
+	}
+	return results, nil
+}
+
+// CountProducts returns the number of catalog entries matching search
+func (s *DuckDBService) CountProducts(ctx context.Context, search string) (int, error) {
+	ctx, done := s.begin(ctx, "product_count")
+	defer done()
+
+	var count int
+	err := s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*)
+		FROM products
+		WHERE ? = ''
+			OR product_id ILIKE '%' || ? || '%'
+			OR product_name ILIKE '%' || ? || '%'
+			OR brand ILIKE '%' || ? || '%'
+	`, search, search, search, search).Scan(&count)
+	if err != nil {
+		return 0, queryError("failed to count products", err)
+	}
+	return count, nil
+}
+
+// GetProduct returns a catalog entry together with its sales summary
+func (s *DuckDBService) GetProduct(ctx context.Context, productID string) (*models.Product, *models.ProductSales, error) {
+	ctx, done := s.begin(ctx, "product")
+	defer done()
+
+	var p models.Product
+	err := s.db.QueryRowContext(ctx, `
+		SELECT 
+			product_id,
+			COALESCE(product_name, ''),
+			COALESCE(category, ''),
+			COALESCE(brand, ''),
+			COALESCE(supplier, '')
+		FROM products
+		WHERE product_id = ?
+		LIMIT 1
+	`, productID).Scan(&p.ProductID, &p.ProductName, &p.Category, &p.Brand, &p.Supplier)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, models.ErrProductNotFound
+	}
+	if err != nil {
+		return nil, nil, queryError("failed to query product", err)
+	}
+
+	var sales models.ProductSales
+	err = s.db.QueryRowContext(ctx, `
+		SELECT 
+			CAST(COALESCE(SUM(total_price), 0) * 100 AS BIGINT),
+			COALESCE(SUM(quantity), 0),
+			COUNT(*)
+		FROM transactions
+		WHERE product_id = ?
+	`, productID).Scan(&sales.TotalRevenue, &sales.UnitsSold, &sales.TransactionCount)
+	if err != nil {
+		return nil, nil, queryError("failed to query product sales", err)
+	}
+
+	return &p, &sales, nil
+}
+
+// GetPriceHistory returns the monthly average selling price of a product in
+// month order. A product without any transactions is not found.
+func (s *DuckDBService) GetPriceHistory(ctx context.Context, productID string, opts models.QueryOptions) ([]models.PricePoint, error) {
+	ctx, done := s.begin(ctx, "price_history")
+	defer done()
+
+	source, sourceArgs := sourceRelation(opts)
+	query := fmt.Sprintf(`
+		SELECT 
+			STRFTIME('%%Y-%%m', transaction_date) as month,
+			%s as revenue,
+			CAST(ROUND(SUM(quantity) * ?) AS BIGINT) as units_sold,
+			CAST(ROUND(COUNT(*) * ?) AS BIGINT) as transactions
+		FROM %s 
+		WHERE product_id = ?
+		GROUP BY STRFTIME('%%Y-%%m', transaction_date)
+		ORDER BY month
+	`, moneyCents("SUM(total_price)"), source)
+
+	scale := opts.ScaleFactor()
+	args := append([]interface{}{scale, scale, scale}, sourceArgs...)
+	rows, err := s.db.QueryContext(ctx, query, append(args, productID)...)
+	if err != nil {
+		return nil, queryError("failed to query price history", err)
+	}
+	defer rows.Close()
+
+	var results []models.PricePoint
+	for rows.Next() {
+		var p models.PricePoint
+		if err := rows.Scan(&p.Month, &p.Revenue, &p.UnitsSold, &p.Transactions); err != nil {
+			return nil, fmt.Errorf("failed to scan price history: %w", err)
+		}
+		results = append(results, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, queryError("failed to query price history", err)
+	}
+
+	if len(results) == 0 {
+		// Tell an unknown product from one without sales in the range
+		var exists bool
+		err := s.db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM transactions WHERE product_id = ?)", productID).Scan(&exists)
+		if err != nil {
+			return nil, queryError("failed to query product", err)
+		}
+		if !exists {
+			return nil, models.ErrProductNotFound
+		}
+	}
+
+	completePriceHistory(results)
+	return results, nil
+}
+
+// GetStockLevels returns every product with transactions under opts, with
+// the units sold since the given day and the stock_quantity of its latest
+// transaction
+func (s *DuckDBService) GetStockLevels(ctx context.Context, opts models.QueryOptions, since time.Time) ([]models.StockLevel, error) {
+	ctx, done := s.begin(ctx, "stock_levels")
+	defer done()
+
+	source, sourceArgs := sourceRelation(opts)
+	query := fmt.Sprintf(`
+		SELECT 
+			product_id,
+			COALESCE(arg_max(product_name, transaction_date), ''),
+			COALESCE(arg_max(category, transaction_date), ''),
+			CAST(ROUND(COALESCE(SUM(quantity) FILTER (WHERE transaction_date >= CAST(? AS DATE)), 0) * ?) AS BIGINT),
+			COALESCE(arg_max(stock_quantity, transaction_date), 0)
+		FROM %s 
+		WHERE product_id IS NOT NULL
+		GROUP BY product_id
+		ORDER BY product_id
+	`, source)
+
+	args := append([]interface{}{since.Format("2006-01-02"), opts.ScaleFactor()}, sourceArgs...)
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, queryError("failed to query stock levels", err)
+	}
+	defer rows.Close()
+
+	var results []models.StockLevel
+	for rows.Next() {
+		var level models.StockLevel
+		if err := rows.Scan(&level.ProductID, &level.ProductName, &level.Category, &level.UnitsSold, &level.Stock); err != nil {
+			return nil, fmt.Errorf("failed to scan stock levels: %w", err)
+		}
+		results = append(results, level)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, queryError("failed to query stock levels", err)
+	}
+	return results, nil
+}
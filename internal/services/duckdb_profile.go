@@ -0,0 +1,112 @@
+//go:build cgo
+
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"analytics-dashboard-api/internal/models"
+)
+
+// profileMaxDistinct skips top values for columns with more distinct values
+// than this: the top values of a near-unique column such as transaction_id
+// are all seen once and cost a full grouping to find
+const profileMaxDistinct = 10000
+
+// GetDataProfile summarizes each transactions column with SUMMARIZE, then
+// adds up to top most frequent values per column
+func (s *DuckDBService) GetDataProfile(ctx context.Context, top int) (*models.DataProfile, error) {
+	ctx, done := s.begin(ctx, "data_profile")
+	defer done()
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT
+			column_name,
+			column_type,
+			min,
+			max,
+			approx_unique,
+			count,
+			CAST(null_percentage AS DOUBLE)
+		FROM (SUMMARIZE transactions)
+	`)
+	if err != nil {
+		return nil, queryError("failed to summarize transactions", err)
+	}
+	defer rows.Close()
+
+	profile := &models.DataProfile{Table: transactionsTable.Name, Columns: []models.ColumnProfile{}}
+	for rows.Next() {
+		var column models.ColumnProfile
+		var min, max sql.NullString
+		var nullPercentage float64
+		if err := rows.Scan(&column.Name, &column.Type, &min, &max, &column.DistinctCount, &profile.Rows, &nullPercentage); err != nil {
+			return nil, fmt.Errorf("failed to scan column summary: %w", err)
+		}
+		if min.Valid {
+			column.Min = &min.String
+		}
+		if max.Valid {
+			column.Max = &max.String
+		}
+		column.NullRate = nullPercentage / 100
+		column.TopValues = []models.DimensionValue{}
+		profile.Columns = append(profile.Columns, column)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, queryError("failed to read column summary", err)
+	}
+	rows.Close()
+
+	for i := range profile.Columns {
+		column := &profile.Columns[i]
+		if top <= 0 || column.DistinctCount > profileMaxDistinct {
+			continue
+		}
+		if column.TopValues, err = s.topValues(ctx, column.Name, top); err != nil {
+			return nil, err
+		}
+	}
+
+	return profile, nil
+}
+
+// topValues returns the most frequent non-null values of a column
+func (s *DuckDBService) topValues(ctx context.Context, column string, limit int) ([]models.DimensionValue, error) {
+	quoted := quoteIdent(column)
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT
+			CAST(%s AS VARCHAR) as value,
+			COUNT(*) as count
+		FROM transactions
+		WHERE %s IS NOT NULL
+		GROUP BY %s
+		ORDER BY count DESC, value
+		LIMIT ?
+	`, quoted, quoted, quoted), limit)
+	if err != nil {
+		return nil, queryError("failed to query top "+column+" values", err)
+	}
+	defer rows.Close()
+
+	results := []models.DimensionValue{}
+	for rows.Next() {
+		var dv models.DimensionValue
+		if err := rows.Scan(&dv.Value, &dv.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan top %s values: %w", column, err)
+		}
+		results = append(results, dv)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, queryError("failed to query top "+column+" values", err)
+	}
+	return results, nil
+}
+
+// quoteIdent quotes a column name for interpolation into SQL
+func quoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
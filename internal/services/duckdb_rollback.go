@@ -0,0 +1,72 @@
+//go:build cgo
+
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"analytics-dashboard-api/internal/models"
+)
+
+// previousTable names the table holding the contents a load replaced
+func previousTable(name string) string {
+	return name + "_previous"
+}
+
+// snapshotTable copies a table's current contents to its previous table
+func snapshotTable(ctx context.Context, tx *sql.Tx, name string) error {
+	snapshotSQL := fmt.Sprintf("CREATE OR REPLACE TABLE %s AS SELECT * FROM %s", previousTable(name), name)
+	if _, err := tx.ExecContext(ctx, snapshotSQL); err != nil {
+		return fmt.Errorf("failed to snapshot %s: %w", name, err)
+	}
+	return nil
+}
+
+// Rollback swaps the tables replaced by the latest load back in. The swapped
+// out data becomes the previous version, so a second rollback undoes the first.
+func (s *DuckDBService) Rollback(ctx context.Context) (*models.DataSnapshot, error) {
+	s.previousMu.Lock()
+	defer s.previousMu.Unlock()
+
+	if s.previous == nil {
+		return nil, models.ErrNoPreviousVersion
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin rollback: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, name := range s.previous.Tables {
+		swap := name + "_rollback"
+		for _, stmt := range []string{
+			fmt.Sprintf("ALTER TABLE %s RENAME TO %s", name, swap),
+			fmt.Sprintf("ALTER TABLE %s RENAME TO %s", previousTable(name), name),
+			fmt.Sprintf("ALTER TABLE %s RENAME TO %s", swap, previousTable(name)),
+		} {
+			if _, err := tx.ExecContext(ctx, stmt); err != nil {
+				return nil, fmt.Errorf("failed to roll back %s: %w", name, err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit rollback: %w", err)
+	}
+
+	restored := s.previous
+	s.coverageMu.Lock()
+	s.previous = &models.DataSnapshot{Tables: restored.Tables, Coverage: s.coverage}
+	s.coverage = restored.Coverage
+	s.coverageMu.Unlock()
+
+	s.forgetSources(ctx)
+
+	s.logger.Info("Rolled back to previous data version",
+		"tables", restored.Tables,
+		"records", restored.Coverage.Records)
+	return restored, nil
+}
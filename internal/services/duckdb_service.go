@@ -1,35 +1,94 @@
+//go:build cgo
+
 package services
 
 import (
 	"context"
 	"database/sql"
 	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
+	"analytics-dashboard-api/internal/config"
+	"analytics-dashboard-api/internal/metrics"
 	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/internal/timing"
 	"analytics-dashboard-api/pkg/logger"
 
 	_ "github.com/marcboeker/go-duckdb"
 )
 
+var _ Repository = (*DuckDBService)(nil)
+
 type DuckDBService struct {
-	db     *sql.DB
-	logger logger.Logger
+	db               *sql.DB
+	path             string // database file, empty for an in-memory database
+	metrics          *metrics.Metrics
+	logger           logger.Logger
+	dimensionSources map[string]string // table name -> CSV or Parquet path
+	strictReferences bool
+	optimize         bool          // see config.DuckDBConfig.Optimize
+	queryTimeout     time.Duration // see config.DuckDBConfig.QueryTimeout
+	outliers         config.OutlierConfig
+	testOrders       testOrderRules
+
+	excludedMu sync.RWMutex
+	excluded   []string // flagged transaction IDs left out of every load
+
+	coverageMu sync.RWMutex
+	coverage   models.DataCoverage
+	ingest     models.IngestStats // phases of the latest transactions load
+
+	// previous describes the tables kept from before the latest load, nil
+	// until a load replaced existing data. Guarded by previousMu.
+	previousMu sync.Mutex
+	previous   *models.DataSnapshot
 }
 
-func NewDuckDBService(logger logger.Logger) (*DuckDBService, error) {
-	// Create in-memory DuckDB database
-	db, err := sql.Open("duckdb", ":memory:")
+// NewDuckDBService opens the database in cfg.Path, or an in-memory one when
+// it is empty. A database file keeps the loaded tables across restarts (see
+// PersistedData). Query durations are recorded into metrics, which may be
+// nil.
+func NewDuckDBService(cfg config.DuckDBConfig, dimensions config.DimensionsConfig, outliers config.OutlierConfig, testOrders config.TestOrderConfig, metrics *metrics.Metrics, logger logger.Logger) (*DuckDBService, error) {
+	dsn := ":memory:"
+	if cfg.Path != "" {
+		if err := os.MkdirAll(filepath.Dir(cfg.Path), 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create DuckDB directory: %w", err)
+		}
+		dsn = cfg.Path
+	}
+	db, err := sql.Open("duckdb", dsn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open DuckDB: %w", err)
 	}
 
 	service := &DuckDBService{
-		db:     db,
-		logger: logger,
+		db:           db,
+		path:         cfg.Path,
+		metrics:      metrics,
+		logger:       logger,
+		queryTimeout: cfg.QueryTimeout,
+		dimensionSources: map[string]string{
+			productsTable.Name:  dimensions.ProductsFilePath,
+			customersTable.Name: dimensions.CustomersFilePath,
+		},
+		strictReferences: dimensions.StrictReferences,
+		optimize:         cfg.Optimize,
+		outliers:         outliers,
+		testOrders:       newTestOrderRules(testOrders),
+	}
+
+	// Apply resource limits before any data is loaded
+	if err := service.configure(cfg); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to configure DuckDB: %w", err)
 	}
 
-	// Create transactions table
+	// Create registered tables
 	if err := service.createTables(); err != nil {
 		db.Close()
 		return nil, fmt.Errorf("failed to create tables: %w", err)
@@ -42,124 +101,430 @@ func (s *DuckDBService) Close() error {
 	return s.db.Close()
 }
 
+// Ping checks that the database answers queries
+func (s *DuckDBService) Ping(ctx context.Context) error {
+	if err := s.db.PingContext(ctx); err != nil {
+		return fmt.Errorf("DuckDB is unreachable: %w", err)
+	}
+	return nil
+}
+
+// requiredExtensions are the DuckDB extensions loads, backups and exports
+// depend on
+var requiredExtensions = []string{"parquet"}
+
+// MissingExtensions lists the required extensions DuckDB can neither load
+// nor install
+func (s *DuckDBService) MissingExtensions(ctx context.Context) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT extension_name FROM duckdb_extensions() WHERE loaded OR installed`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list DuckDB extensions: %w", err)
+	}
+	defer rows.Close()
+
+	available := map[string]bool{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to scan DuckDB extension: %w", err)
+		}
+		available[name] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list DuckDB extensions: %w", err)
+	}
+
+	var missing []string
+	for _, name := range requiredExtensions {
+		if !available[name] {
+			missing = append(missing, name)
+		}
+	}
+	return missing, nil
+}
+
+// begin starts a query of the given type, bounding ctx by the query timeout.
+// The returned function, deferred, cancels the query's context and records
+// its duration. A query cut short by the timeout is interrupted in DuckDB
+// and fails with models.ErrQueryTimeout (see queryError).
+func (s *DuckDBService) begin(ctx context.Context, query string) (context.Context, func()) {
+	start := time.Now()
+	cancel := context.CancelFunc(func() {})
+	if s.queryTimeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, s.queryTimeout)
+	}
+	return ctx, func() {
+		cancel()
+		s.observe(ctx, query, start)
+	}
+}
+
+// observe records the duration of a query of the given type started at start,
+// in the metrics and in the timings of the request ctx belongs to
+func (s *DuckDBService) observe(ctx context.Context, query string, start time.Time) {
+	s.metrics.ObserveQuery("duckdb", query, time.Since(start))
+	timing.FromContext(ctx).Since(timing.PhaseDB, query, start)
+}
+
+// configure applies memory, thread and spill settings so large aggregations
+// spill to disk instead of exhausting process memory
+func (s *DuckDBService) configure(cfg config.DuckDBConfig) error {
+	var settings []string
+	if cfg.MemoryLimit != "" {
+		settings = append(settings, fmt.Sprintf("SET memory_limit = '%s'", escapeLiteral(cfg.MemoryLimit)))
+	}
+	if cfg.Threads > 0 {
+		settings = append(settings, fmt.Sprintf("SET threads = %d", cfg.Threads))
+	}
+	if cfg.TempDirectory != "" {
+		settings = append(settings, fmt.Sprintf("SET temp_directory = '%s'", escapeLiteral(cfg.TempDirectory)))
+	}
+
+	for _, stmt := range settings {
+		if _, err := s.db.Exec(stmt); err != nil {
+			return fmt.Errorf("%s: %w", stmt, err)
+		}
+	}
+
+	s.logger.Info("DuckDB configured",
+		"memory_limit", cfg.MemoryLimit,
+		"threads", cfg.Threads,
+		"temp_directory", cfg.TempDirectory)
+	return nil
+}
+
+// escapeLiteral escapes single quotes for use inside a SQL string literal
+func escapeLiteral(value string) string {
+	return strings.ReplaceAll(value, "'", "''")
+}
+
 func (s *DuckDBService) createTables() error {
-	createTableSQL := `
-	CREATE TABLE IF NOT EXISTS transactions (
-		transaction_id VARCHAR,
-		transaction_date DATE,
-		user_id VARCHAR,
-		country VARCHAR,
-		region VARCHAR,
-		product_id VARCHAR,
-		product_name VARCHAR,
-		category VARCHAR,
-		price DECIMAL(10,2),
-		quantity INTEGER,
-		total_price DECIMAL(10,2),
-		stock_quantity INTEGER,
-		added_date DATE
-	)`
-	
-	_, err := s.db.Exec(createTableSQL)
-	return err
+	for _, spec := range append(slices.Clone(tableRegistry), outliersTable, loadStateTable) {
+		if _, err := s.db.Exec(spec.createTableSQL()); err != nil {
+			return fmt.Errorf("%s: %w", spec.Name, err)
+		}
+	}
+	return nil
 }
 
-func (s *DuckDBService) LoadFromCSV(csvPath string) error {
+// LoadFromFile replaces the transactions table with the contents of the
+// files, read as CSV or Parquet (see resolveFormat), together with any
+// configured dimension tables, in a single transaction
+func (s *DuckDBService) LoadFromFile(ctx context.Context, format string, paths ...string) error {
 	startTime := time.Now()
-	s.logger.Info("Loading CSV data into DuckDB", "file", csvPath)
+	s.logger.Info("Loading data into DuckDB", "files", paths, "format", format)
+	s.setIngest(models.IngestStats{})
 
-	// Use DuckDB's CSV reader to load data directly
-	loadSQL := fmt.Sprintf(`
-		INSERT INTO transactions 
-		SELECT 
-			transaction_id,
-			CAST(transaction_date AS DATE) as transaction_date,
-			user_id,
-			country,
-			region,
-			product_id,
-			product_name,
-			category,
-			CAST(price AS DECIMAL(10,2)) as price,
-			CAST(quantity AS INTEGER) as quantity,
-			CAST(total_price AS DECIMAL(10,2)) as total_price,
-			CAST(stock_quantity AS INTEGER) as stock_quantity,
-			CAST(added_date AS DATE) as added_date
-		FROM read_csv_auto('%s', header=true)
-	`, csvPath)
-
-	_, err := s.db.Exec(loadSQL)
-	if err != nil {
-		return fmt.Errorf("failed to load CSV: %w", err)
+	sources := map[string]tableSource{transactionsTable.Name: {paths: paths, format: format}}
+	for table, path := range s.dimensionSources {
+		sources[table] = tableSource{paths: []string{path}, format: models.DataFormatAuto}
 	}
 
-	// Get row count
-	var count int
-	err = s.db.QueryRow("SELECT COUNT(*) FROM transactions").Scan(&count)
+	result, err := s.loadTables(ctx, sources)
 	if err != nil {
-		return fmt.Errorf("failed to get row count: %w", err)
+		return fmt.Errorf("failed to load data: %w", err)
+	}
+
+	for _, table := range result.Tables {
+		s.logger.Info("Table loaded", "table", table.Name, "file", table.Source, "records", table.Records)
+	}
+	for _, check := range result.ReferenceChecks {
+		if check.Orphans > 0 {
+			s.logger.Warn("Referential check found orphaned rows",
+				"table", check.Table,
+				"column", check.Column,
+				"ref_table", check.RefTable,
+				"orphans", check.Orphans)
+		}
 	}
 
-	s.logger.Info("CSV data loaded successfully", 
-		"records", count, 
+	logTestOrders(s.logger, result.TestOrders)
+	logOutliers(s.logger, result.Outliers)
+	if s.optimize {
+		s.analyze(ctx)
+	}
+
+	if err := s.refreshCoverage(ctx, result.Outliers, result.Excluded, result.TestOrders); err != nil {
+		return err
+	}
+	s.recordSources(ctx, paths)
+
+	s.logger.Info("Data loaded successfully",
+		"tables", len(result.Tables),
 		"duration", time.Since(startTime))
 
 	return nil
 }
 
-func (s *DuckDBService) GetCountryRevenue(ctx context.Context, limit, offset int) ([]models.CountryRevenue, error) {
-	query := `
+// analyze refreshes the statistics the query planner estimates with, such
+// as distinct counts, for the tables just loaded. Stale statistics only make
+// plans worse, so a failure is logged rather than failing the load.
+func (s *DuckDBService) analyze(ctx context.Context) {
+	start := time.Now()
+	if _, err := s.db.ExecContext(ctx, "ANALYZE"); err != nil {
+		s.logger.Warn("Failed to refresh table statistics", "error", err)
+		return
+	}
+	ingest := s.LastIngest()
+	ingest.Optimize = time.Since(start)
+	s.setIngest(ingest)
+	s.logger.Info("Table statistics refreshed", "duration", ingest.Optimize)
+}
+
+// refreshCoverage records the date range of the loaded transactions, the
+// load's outlier report and the flagged rows and test orders it left out. It
+// runs once per load so every response can report coverage without a query.
+func (s *DuckDBService) refreshCoverage(ctx context.Context, outliers *models.OutlierReport, excluded, testOrders int) error {
+	var from, to sql.NullTime
+	var records int
+	err := s.db.QueryRowContext(ctx, `
+		SELECT MIN(transaction_date), MAX(transaction_date), COUNT(*)
+		FROM transactions
+	`).Scan(&from, &to, &records)
+	if err != nil {
+		return queryError("failed to query data coverage", err)
+	}
+
+	coverage := models.DataCoverage{
+		Records:    records,
+		LoadedAt:   time.Now().UTC(),
+		Outliers:   outliers,
+		Excluded:   excluded,
+		TestOrders: testOrders,
+	}
+	if from.Valid {
+		coverage.From = from.Time.Format("2006-01-02")
+	}
+	if to.Valid {
+		coverage.To = to.Time.Format("2006-01-02")
+	}
+
+	s.coverageMu.Lock()
+	s.coverage = coverage
+	s.coverageMu.Unlock()
+	return nil
+}
+
+// LastIngest returns the phase timings of the most recent load of the
+// transactions files
+func (s *DuckDBService) LastIngest() models.IngestStats {
+	s.coverageMu.RLock()
+	defer s.coverageMu.RUnlock()
+	return s.ingest
+}
+
+func (s *DuckDBService) setIngest(stats models.IngestStats) {
+	s.coverageMu.Lock()
+	s.ingest = stats
+	s.coverageMu.Unlock()
+}
+
+// DataCoverage returns the coverage recorded by the most recent load
+func (s *DuckDBService) DataCoverage() models.DataCoverage {
+	s.coverageMu.RLock()
+	defer s.coverageMu.RUnlock()
+	return s.coverage
+}
+
+// sourceRelation returns the FROM target for a query and its bind arguments.
+// Sampling and filters from the options are applied in a subquery so every
+// analytics query can aggregate over it unchanged. Dimension filters become
+// IN and NOT IN lists of bound values, compared through the NOCASE
+// collation so they ignore case, Unicode letters included.
+func sourceRelation(opts models.QueryOptions) (string, []interface{}) {
+	if !opts.Sampled() && !opts.Filtered() {
+		return "transactions", nil
+	}
+
+	relation := "SELECT * FROM transactions"
+	if opts.Sampled() {
+		relation += fmt.Sprintf(" TABLESAMPLE bernoulli(%g%%)", opts.SampleRate*100)
+	}
+
+	var conditions []string
+	var args []interface{}
+	for _, filter := range opts.DimensionFilters() {
+		for _, list := range []struct {
+			values  []string
+			exclude bool
+		}{{filter.Include, false}, {filter.Exclude, true}} {
+			if len(list.values) == 0 {
+				continue
+			}
+			condition, ok := dimensionCondition(filter.Dimension, list.exclude, len(list.values))
+			if !ok {
+				continue
+			}
+			conditions = append(conditions, condition)
+			for _, value := range list.values {
+				args = append(args, value)
+			}
+		}
+	}
+	if !opts.From.IsZero() {
+		conditions = append(conditions, "transaction_date >= CAST(? AS DATE)")
+		args = append(args, opts.From.Format("2006-01-02"))
+	}
+	if !opts.To.IsZero() {
+		conditions = append(conditions, "transaction_date < CAST(? AS DATE)")
+		args = append(args, opts.To.Format("2006-01-02"))
+	}
+
+	if len(conditions) > 0 {
+		relation += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	return "(" + relation + ")", args
+}
+
+// dimensionCondition returns the condition keeping the rows whose dimension
+// is one of n bound values or, with exclude, none of them. Rows without a
+// value, or customers without a segment, are kept by exclusions.
+func dimensionCondition(dimension string, exclude bool, n int) (string, bool) {
+	values := strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+	var column, compared, set string
+	switch dimension {
+	case models.FilterSegment:
+		column, compared = "user_id", "user_id"
+		set = fmt.Sprintf("(SELECT user_id FROM customers WHERE user_id IS NOT NULL AND segment COLLATE NOCASE IN (%s))", values)
+	case models.FilterCountry, models.FilterRegion, models.FilterCategory:
+		column, compared = dimension, dimension+" COLLATE NOCASE"
+		set = "(" + values + ")"
+	default:
+		return "", false
+	}
+
+	if !exclude {
+		return fmt.Sprintf("%s IN %s", compared, set), true
+	}
+	return fmt.Sprintf("(%s IS NULL OR %s NOT IN %s)", column, compared, set), true
+}
+
+// moneyCents converts a DECIMAL amount to integer cents for scanning into
+// models.Money. The bound sampling scale factor is applied as DECIMAL so
+// unsampled totals stay exact.
+func moneyCents(amount string) string {
+	return fmt.Sprintf("CAST(%s * 100 * CAST(? AS DECIMAL(18,6)) AS BIGINT)", amount)
+}
+
+// GetCountryRevenue returns a page of revenue per country and product,
+// largest first, with the totals over every pair. The totals come from the
+// same query: the groups are materialized once, so a sampled query pages
+// and totals the same sample.
+func (s *DuckDBService) GetCountryRevenue(ctx context.Context, opts models.QueryOptions, limit, offset int) ([]models.CountryRevenue, *models.Totals, error) {
+	ctx, done := s.begin(ctx, "country_revenue")
+	defer done()
+
+	source, sourceArgs := sourceRelation(opts)
+	query := fmt.Sprintf(`
+		WITH groups AS MATERIALIZED (
+			SELECT 
+				country,
+				product_name,
+				SUM(total_price) as revenue,
+				COUNT(*) as transactions,
+				SUM(quantity) as quantity
+			FROM %s 
+			GROUP BY country, product_name
+		)
 		SELECT 
-			country,
-			product_name,
-			CAST(SUM(total_price) AS DOUBLE) as total_revenue,
-			COUNT(*) as transaction_count
-		FROM transactions 
-		GROUP BY country, product_name
-		ORDER BY total_revenue DESC
-		LIMIT ? OFFSET ?
-	`
+			is_total,
+			COALESCE(country, ''),
+			COALESCE(product_name, ''),
+			%s as total_revenue,
+			CAST(ROUND(COALESCE(transactions, 0) * ?) AS BIGINT) as transaction_count,
+			CAST(ROUND(COALESCE(quantity, 0) * ?) AS BIGINT) as quantity
+		FROM (
+			SELECT * FROM (
+				SELECT FALSE as is_total, * FROM groups
+				ORDER BY revenue DESC
+				LIMIT ? OFFSET ?
+			)
+			UNION ALL
+			SELECT TRUE, NULL, NULL, SUM(revenue), SUM(transactions), SUM(quantity) FROM groups
+		)
+		ORDER BY is_total, revenue DESC
+	`, source, moneyCents("COALESCE(revenue, 0)"))
 
-	rows, err := s.db.QueryContext(ctx, query, limit, offset)
+	scale := opts.ScaleFactor()
+	args := append(sourceArgs, scale, scale, scale, limit, offset)
+	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query country revenue: %w", err)
+		return nil, nil, queryError("failed to query country revenue", err)
 	}
 	defer rows.Close()
 
 	var results []models.CountryRevenue
+	totals := &models.Totals{}
 	for rows.Next() {
 		var cr models.CountryRevenue
+		var isTotal bool
+		var quantity int
 		err := rows.Scan(
+			&isTotal,
 			&cr.Country,
 			&cr.ProductName,
 			&cr.TotalRevenue,
 			&cr.TransactionCount,
+			&quantity,
 		)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan country revenue: %w", err)
+			return nil, nil, fmt.Errorf("failed to scan country revenue: %w", err)
+		}
+		if isTotal {
+			*totals = models.Totals{Revenue: cr.TotalRevenue, Transactions: cr.TransactionCount, Quantity: quantity}
+			continue
 		}
 		results = append(results, cr)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, queryError("failed to query country revenue", err)
+	}
 
-	return results, nil
+	return results, totals, nil
 }
 
-func (s *DuckDBService) GetTopProducts(ctx context.Context) ([]models.ProductFrequency, error) {
-	query := `
+// GetTopProducts returns a page of products ranked by sortBy, largest first
+func (s *DuckDBService) GetTopProducts(ctx context.Context, opts models.QueryOptions, sortBy string, limit, offset int) ([]models.ProductFrequency, error) {
+	ctx, done := s.begin(ctx, "top_products")
+	defer done()
+
+	rank, err := rankColumn(productRanks, sortBy)
+	if err != nil {
+		return nil, err
+	}
+	source, sourceArgs := sourceRelation(opts)
+	// Rank first, then join the products dimension for just the page
+	query := fmt.Sprintf(`
 		SELECT 
-			product_id,
-			product_name,
-			SUM(quantity) as purchase_count,
-			MAX(stock_quantity) as stock_quantity
-		FROM transactions 
-		GROUP BY product_id, product_name
-		ORDER BY purchase_count DESC
-		LIMIT 20
-	`
-
-	rows, err := s.db.QueryContext(ctx, query)
+			top.product_id,
+			top.product_name,
+			top.purchase_count,
+			top.total_revenue,
+			top.stock_quantity,
+			COALESCE(p.brand, '') as brand,
+			COALESCE(p.supplier, '') as supplier
+		FROM (
+			SELECT 
+				product_id,
+				product_name,
+				CAST(ROUND(SUM(quantity) * ?) AS BIGINT) as purchase_count,
+				%s as total_revenue,
+				MAX(stock_quantity) as stock_quantity
+			FROM %s 
+			GROUP BY product_id, product_name
+			ORDER BY %s DESC, product_id, product_name
+			LIMIT ? OFFSET ?
+		) top
+		LEFT JOIN products p ON p.product_id = top.product_id
+		ORDER BY top.%s DESC, top.product_id, top.product_name
+	`, moneyCents("SUM(total_price)"), source, rank, rank)
+
+	scale := opts.ScaleFactor()
+	args := append([]interface{}{scale, scale}, sourceArgs...)
+	args = append(args, limit, offset)
+	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query top products: %w", err)
+		return nil, queryError("failed to query top products", err)
 	}
 	defer rows.Close()
 
@@ -170,31 +535,60 @@ func (s *DuckDBService) GetTopProducts(ctx context.Context) ([]models.ProductFre
 			&pf.ProductID,
 			&pf.ProductName,
 			&pf.PurchaseCount,
+			&pf.TotalRevenue,
 			&pf.StockQuantity,
+			&pf.Brand,
+			&pf.Supplier,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan top products: %w", err)
 		}
 		results = append(results, pf)
 	}
-
+	if err := rows.Err(); err != nil {
+		return nil, queryError("failed to query top products", err)
+	}
 	return results, nil
 }
 
-func (s *DuckDBService) GetMonthlySales(ctx context.Context) ([]models.MonthlySales, error) {
-	query := `
+// GetTopProductsCount returns the number of products GetTopProducts ranks
+func (s *DuckDBService) GetTopProductsCount(ctx context.Context, opts models.QueryOptions) (int, error) {
+	ctx, done := s.begin(ctx, "top_products_count")
+	defer done()
+
+	source, sourceArgs := sourceRelation(opts)
+	var count int
+	err := s.db.QueryRowContext(ctx, fmt.Sprintf(`
+		SELECT COUNT(*) FROM (SELECT DISTINCT product_id, product_name FROM %s)
+	`, source), sourceArgs...).Scan(&count)
+	if err != nil {
+		return 0, queryError("failed to count top products", err)
+	}
+	return count, nil
+}
+
+func (s *DuckDBService) GetMonthlySales(ctx context.Context, opts models.QueryOptions) ([]models.MonthlySales, error) {
+	ctx, done := s.begin(ctx, "monthly_sales")
+	defer done()
+
+	source, sourceArgs := sourceRelation(opts)
+	query := fmt.Sprintf(`
 		SELECT 
-			STRFTIME('%Y-%m', transaction_date) as month,
-			CAST(SUM(total_price) AS DOUBLE) as sales_volume,
-			SUM(quantity) as item_count
-		FROM transactions 
-		GROUP BY STRFTIME('%Y-%m', transaction_date)
+			STRFTIME('%%Y-%%m', transaction_date) as month,
+			%s as sales_volume,
+			CAST(ROUND(SUM(quantity) * ?) AS BIGINT) as item_count,
+			approx_count_distinct(user_id) as unique_customers,
+			approx_count_distinct(product_id) as unique_products
+		FROM %s 
+		GROUP BY STRFTIME('%%Y-%%m', transaction_date)
 		ORDER BY month
-	`
+	`, moneyCents("SUM(total_price)"), source)
 
-	rows, err := s.db.QueryContext(ctx, query)
+	scale := opts.ScaleFactor()
+	args := append([]interface{}{scale, scale}, sourceArgs...)
+	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query monthly sales: %w", err)
+		return nil, queryError("failed to query monthly sales", err)
 	}
 	defer rows.Close()
 
@@ -205,31 +599,47 @@ func (s *DuckDBService) GetMonthlySales(ctx context.Context) ([]models.MonthlySa
 			&ms.Month,
 			&ms.SalesVolume,
 			&ms.ItemCount,
+			&ms.UniqueCustomers,
+			&ms.UniqueProducts,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan monthly sales: %w", err)
 		}
 		results = append(results, ms)
 	}
-
+	if err := rows.Err(); err != nil {
+		return nil, queryError("failed to query monthly sales", err)
+	}
 	return results, nil
 }
 
-func (s *DuckDBService) GetTopRegions(ctx context.Context) ([]models.RegionRevenue, error) {
-	query := `
+// GetTopRegions returns a page of regions ranked by sortBy, largest first
+func (s *DuckDBService) GetTopRegions(ctx context.Context, opts models.QueryOptions, sortBy string, limit, offset int) ([]models.RegionRevenue, error) {
+	ctx, done := s.begin(ctx, "top_regions")
+	defer done()
+
+	rank, err := rankColumn(regionRanks, sortBy)
+	if err != nil {
+		return nil, err
+	}
+	source, sourceArgs := sourceRelation(opts)
+	query := fmt.Sprintf(`
 		SELECT 
 			region,
-			CAST(SUM(total_price) AS DOUBLE) as total_revenue,
-			SUM(quantity) as items_sold
-		FROM transactions 
+			%s as total_revenue,
+			CAST(ROUND(SUM(quantity) * ?) AS BIGINT) as items_sold
+		FROM %s 
 		GROUP BY region
-		ORDER BY total_revenue DESC
-		LIMIT 30
-	`
+		ORDER BY %s DESC, region
+		LIMIT ? OFFSET ?
+	`, moneyCents("SUM(total_price)"), source, rank)
 
-	rows, err := s.db.QueryContext(ctx, query)
+	scale := opts.ScaleFactor()
+	args := append([]interface{}{scale, scale}, sourceArgs...)
+	args = append(args, limit, offset)
+	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query top regions: %w", err)
+		return nil, queryError("failed to query top regions", err)
 	}
 	defer rows.Close()
 
@@ -246,24 +656,117 @@ func (s *DuckDBService) GetTopRegions(ctx context.Context) ([]models.RegionReven
 		}
 		results = append(results, rr)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, queryError("failed to query top regions", err)
+	}
+	return results, nil
+}
+
+// GetTopRegionsCount returns the number of regions GetTopRegions ranks
+func (s *DuckDBService) GetTopRegionsCount(ctx context.Context, opts models.QueryOptions) (int, error) {
+	ctx, done := s.begin(ctx, "top_regions_count")
+	defer done()
+
+	source, sourceArgs := sourceRelation(opts)
+	var count int
+	err := s.db.QueryRowContext(ctx, fmt.Sprintf(`
+		SELECT COUNT(*) FROM (SELECT DISTINCT region FROM %s)
+	`, source), sourceArgs...).Scan(&count)
+	if err != nil {
+		return 0, queryError("failed to count top regions", err)
+	}
+	return count, nil
+}
 
+// GetRegionSales returns the revenue of every country and region pair,
+// largest first
+func (s *DuckDBService) GetRegionSales(ctx context.Context, opts models.QueryOptions) ([]models.RegionSales, error) {
+	ctx, done := s.begin(ctx, "region_sales")
+	defer done()
+
+	source, sourceArgs := sourceRelation(opts)
+	query := fmt.Sprintf(`
+		SELECT 
+			COALESCE(country, ''),
+			COALESCE(region, ''),
+			%s as revenue,
+			CAST(ROUND(COUNT(*) * ?) AS BIGINT),
+			CAST(ROUND(SUM(quantity) * ?) AS BIGINT)
+		FROM %s 
+		GROUP BY 1, 2
+		ORDER BY revenue DESC, 1, 2
+	`, moneyCents("SUM(total_price)"), source)
+
+	scale := opts.ScaleFactor()
+	args := append([]interface{}{scale, scale, scale}, sourceArgs...)
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, queryError("failed to query region sales", err)
+	}
+	defer rows.Close()
+
+	var results []models.RegionSales
+	for rows.Next() {
+		var rs models.RegionSales
+		if err := rows.Scan(&rs.Country, &rs.Region, &rs.Revenue, &rs.Transactions, &rs.ItemsSold); err != nil {
+			return nil, fmt.Errorf("failed to scan region sales: %w", err)
+		}
+		results = append(results, rs)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, queryError("failed to query region sales", err)
+	}
 	return results, nil
 }
 
 func (s *DuckDBService) GetTotalRecords(ctx context.Context) (int, error) {
+	ctx, done := s.begin(ctx, "total_records")
+	defer done()
+
 	var count int
 	err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM transactions").Scan(&count)
-	return count, err
+	if err != nil {
+		return 0, queryError("failed to count transactions", err)
+	}
+	return count, nil
 }
 
-func (s *DuckDBService) GetCountryRevenueCount(ctx context.Context) (int, error) {
+// GetCountryRevenueCount returns the number of country and product rows
+// GetCountryRevenue pages through
+func (s *DuckDBService) GetCountryRevenueCount(ctx context.Context, opts models.QueryOptions) (int, error) {
+	ctx, done := s.begin(ctx, "country_revenue_count")
+	defer done()
+
+	source, sourceArgs := sourceRelation(opts)
 	var count int
-	err := s.db.QueryRowContext(ctx, `
+	err := s.db.QueryRowContext(ctx, fmt.Sprintf(`
 		SELECT COUNT(*) 
 		FROM (
 			SELECT DISTINCT country, product_name 
-			FROM transactions
+			FROM %s
 		)
-	`).Scan(&count)
-	return count, err
+	`, source), sourceArgs...).Scan(&count)
+	if err != nil {
+		return 0, queryError("failed to count country revenue rows", err)
+	}
+	return count, nil
+}
+
+// GetDistinctCounts returns approximate unique customer and product counts.
+// approx_count_distinct keeps this cheap on datasets where exact counts are too slow.
+func (s *DuckDBService) GetDistinctCounts(ctx context.Context) (models.DistinctCounts, error) {
+	ctx, done := s.begin(ctx, "distinct_counts")
+	defer done()
+
+	var counts models.DistinctCounts
+	err := s.db.QueryRowContext(ctx, `
+		SELECT 
+			approx_count_distinct(user_id),
+			approx_count_distinct(product_id)
+		FROM transactions
+	`).Scan(&counts.UniqueCustomers, &counts.UniqueProducts)
+	if err != nil {
+		return counts, queryError("failed to query distinct counts", err)
+	}
+	return counts, nil
 }
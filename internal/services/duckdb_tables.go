@@ -0,0 +1,481 @@
+//go:build cgo
+
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"analytics-dashboard-api/internal/models"
+)
+
+// createTableSQL returns the CREATE TABLE statement for the spec
+func (t TableSpec) createTableSQL() string {
+	columns := make([]string, len(t.Columns))
+	for i, col := range t.Columns {
+		columns[i] = fmt.Sprintf("%s %s", col.Name, col.Type)
+	}
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (\n\t\t%s\n\t)", t.Name, strings.Join(columns, ",\n\t\t"))
+}
+
+// selectList returns the projection casting raw source columns to the table schema
+func (t TableSpec) selectList() string {
+	columns := make([]string, len(t.Columns))
+	for i, col := range t.Columns {
+		columns[i] = fmt.Sprintf("CAST(%s AS %s) as %s", col.Name, col.Type, col.Name)
+	}
+	return strings.Join(columns, ",\n\t\t\t")
+}
+
+// sourceSelectList is selectList for a source with the given column names,
+// reading mapped columns from the source column the mapping names
+func (t TableSpec) sourceSelectList(header []string) string {
+	index := t.sourceIndex(header)
+	columns := make([]string, len(t.Columns))
+	for i, col := range t.Columns {
+		source := col.Name
+		if index[i] >= 0 {
+			source = quoteIdent(header[index[i]])
+		}
+		columns[i] = fmt.Sprintf("CAST(%s AS %s) as %s", source, col.Type, col.Name)
+	}
+	return strings.Join(columns, ",\n\t\t\t")
+}
+
+// tableSource is the files a table is loaded from, in a models.DataFormat
+type tableSource struct {
+	paths  []string
+	format string
+}
+
+// loadTables replaces the contents of every table with its source files
+// inside a single transaction, then runs referential checks. Either all
+// tables are swapped or none are.
+func (s *DuckDBService) loadTables(ctx context.Context, sources map[string]tableSource) (*models.LoadResult, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin load: %w", err)
+	}
+	defer tx.Rollback()
+
+	result := &models.LoadResult{}
+	loaded := make(map[string]bool)
+
+	// Keep the data being replaced for Rollback, unless nothing was loaded yet
+	previousCoverage := s.DataCoverage()
+	snapshot := &models.DataSnapshot{Coverage: previousCoverage}
+	keepPrevious := !previousCoverage.LoadedAt.IsZero()
+
+	for _, spec := range tableRegistry {
+		source := sources[spec.Name]
+		paths := slices.DeleteFunc(slices.Clone(source.paths), func(path string) bool { return path == "" })
+		if len(paths) == 0 {
+			continue
+		}
+		if missing, err := firstMissing(paths); err != nil {
+			if spec.Required {
+				return nil, fmt.Errorf("source for %s: %w", spec.Name, sourceError(missing, err))
+			}
+			s.logger.Debug("Skipping optional table, source not found", "table", spec.Name, "file", missing)
+			continue
+		}
+
+		if keepPrevious {
+			if err := snapshotTable(ctx, tx, spec.Name); err != nil {
+				return nil, err
+			}
+			snapshot.Tables = append(snapshot.Tables, spec.Name)
+		}
+
+		if !s.optimize {
+			spec.ClusterBy = ""
+		}
+		records, ingest, err := loadTable(ctx, tx, spec, source.format, paths...)
+		if spec.Name == transactionsTable.Name {
+			s.setIngest(ingest)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		loaded[spec.Name] = true
+		result.Tables = append(result.Tables, models.TableLoadResult{
+			Name:    spec.Name,
+			Source:  strings.Join(paths, ","),
+			Records: records,
+		})
+	}
+
+	if loaded[transactionsTable.Name] {
+		// Flagged transactions and test orders go first, so they never skew
+		// outlier limits
+		if result.Excluded, err = s.excludeTransactions(ctx, tx); err != nil {
+			return nil, err
+		}
+		if result.TestOrders, err = s.excludeTestOrders(ctx, tx); err != nil {
+			return nil, err
+		}
+		if keepPrevious {
+			if err := snapshotTable(ctx, tx, outliersTable.Name); err != nil {
+				return nil, err
+			}
+			snapshot.Tables = append(snapshot.Tables, outliersTable.Name)
+		}
+		if result.Outliers, err = s.flagOutliers(ctx, tx); err != nil {
+			return nil, err
+		}
+		removed := result.Excluded + result.TestOrders
+		if result.Outliers != nil && result.Outliers.Mode == models.OutlierModeExclude {
+			removed += result.Outliers.Rows
+		}
+		for i := range result.Tables {
+			if result.Tables[i].Name == transactionsTable.Name {
+				result.Tables[i].Records -= removed
+			}
+		}
+	}
+
+	for _, spec := range tableRegistry {
+		if !loaded[spec.Name] {
+			continue
+		}
+		for _, ref := range spec.References {
+			if !loaded[ref.RefTable] {
+				continue
+			}
+			check, err := checkReference(ctx, tx, spec.Name, ref)
+			if err != nil {
+				return nil, err
+			}
+			if check.Orphans > 0 && s.strictReferences {
+				return nil, fmt.Errorf("%s.%s has %d values missing from %s.%s",
+					spec.Name, ref.Column, check.Orphans, ref.RefTable, ref.RefColumn)
+			}
+			result.ReferenceChecks = append(result.ReferenceChecks, check)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit load: %w", err)
+	}
+
+	if keepPrevious {
+		s.previousMu.Lock()
+		s.previous = snapshot
+		s.previousMu.Unlock()
+	}
+	return result, nil
+}
+
+// LoadTableFromFile replaces a single registered table with the contents of a
+// CSV file. The validate callback runs inside the load transaction, so a
+// rejected file leaves the previous contents in place.
+func (s *DuckDBService) LoadTableFromFile(ctx context.Context, table, path string, validate func(context.Context, *sql.Tx) error) (*models.TableLoadResult, error) {
+	spec, ok := lookupTable(table)
+	if !ok {
+		return nil, fmt.Errorf("unknown table %q", table)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin load: %w", err)
+	}
+	defer tx.Rollback()
+
+	records, _, err := loadTable(ctx, tx, spec, models.DataFormatCSV, path)
+	if err != nil {
+		return nil, err
+	}
+
+	if validate != nil {
+		if err := validate(ctx, tx); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit load: %w", err)
+	}
+
+	s.logger.Info("Table loaded", "table", table, "file", path, "records", records)
+	return &models.TableLoadResult{Name: table, Source: path, Records: records}, nil
+}
+
+// loadTable replaces the table contents with rows read from the files in a
+// single INSERT ... SELECT. DuckDB reads, parses and inserts in one pass, so
+// the stats time the statement as a whole, as the insert phase.
+func loadTable(ctx context.Context, tx *sql.Tx, spec TableSpec, format string, paths ...string) (int, models.IngestStats, error) {
+	var stats models.IngestStats
+	source, err := sourceReader(format, paths...)
+	if err != nil {
+		return 0, stats, fmt.Errorf("failed to load %s: %w", spec.Name, err)
+	}
+	if stats.Bytes, err = filesSize(paths); err != nil {
+		return 0, stats, fmt.Errorf("failed to load %s: %w", spec.Name, err)
+	}
+
+	selectList := spec.selectList()
+	if spec.mapping() != nil {
+		// Mapped columns are named by header or position, so the source's
+		// columns are needed to pick them
+		columns, err := describeSource(ctx, tx, source)
+		if err != nil {
+			return 0, stats, fmt.Errorf("failed to load %s: %w", spec.Name, err)
+		}
+		selectList = spec.sourceSelectList(sourceColumnNames(columns))
+	}
+
+	start := time.Now()
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s", spec.Name)); err != nil {
+		return 0, stats, fmt.Errorf("failed to clear %s: %w", spec.Name, err)
+	}
+	insertSQL := fmt.Sprintf(`
+		INSERT INTO %s (%s)
+		SELECT
+			%s
+		FROM %s
+	`, spec.Name, strings.Join(spec.columnNames(), ", "), selectList, source)
+	if spec.ClusterBy != "" {
+		insertSQL += " ORDER BY " + spec.ClusterBy
+	}
+	if _, err := tx.ExecContext(ctx, insertSQL); err != nil {
+		if ctx.Err() == nil {
+			stats.ParseErrors = 1
+		}
+		return 0, stats, fmt.Errorf("failed to load %s: %w", spec.Name, err)
+	}
+	stats.Insert = time.Since(start)
+
+	var count int
+	if err := tx.QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s", spec.Name)).Scan(&count); err != nil {
+		return 0, stats, fmt.Errorf("failed to get %s row count: %w", spec.Name, err)
+	}
+	stats.Rows = int64(count)
+	return count, stats, nil
+}
+
+// filesSize returns the combined size of the files
+func filesSize(paths []string) (int64, error) {
+	var total int64
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return total, sourceError(path, err)
+		}
+		total += info.Size()
+	}
+	return total, nil
+}
+
+// sourceReader returns the table function reading the files: read_parquet
+// for Parquet, read_json_auto for JSON Lines, read_csv_auto otherwise.
+// Several files are matched by column name, so parts may order their
+// columns differently.
+func sourceReader(format string, paths ...string) (string, error) {
+	format, err := resolveFormat(format, paths...)
+	if err != nil {
+		return "", err
+	}
+
+	files := make([]string, len(paths))
+	for i, path := range paths {
+		files[i] = "'" + escapeLiteral(path) + "'"
+	}
+	args := []string{files[0]}
+	if len(files) > 1 {
+		args = []string{"[" + strings.Join(files, ", ") + "]"}
+	}
+	function := "read_parquet"
+	switch format {
+	case models.DataFormatCSV:
+		function = "read_csv_auto"
+		args = append(args, "header=true")
+	case models.DataFormatJSONL:
+		function = "read_json_auto"
+		args = append(args, "format='newline_delimited'")
+	}
+	if len(files) > 1 {
+		args = append(args, "union_by_name=true")
+	}
+	return fmt.Sprintf("%s(%s)", function, strings.Join(args, ", ")), nil
+}
+
+// firstMissing returns the first path that cannot be stat'ed, with the error
+func firstMissing(paths []string) (string, error) {
+	for _, path := range paths {
+		if _, err := os.Stat(path); err != nil {
+			return path, err
+		}
+	}
+	return "", nil
+}
+
+// checkReference counts fact rows whose key has no matching dimension row
+func checkReference(ctx context.Context, tx *sql.Tx, table string, ref Reference) (models.ReferenceCheck, error) {
+	check := models.ReferenceCheck{
+		Table:     table,
+		Column:    ref.Column,
+		RefTable:  ref.RefTable,
+		RefColumn: ref.RefColumn,
+	}
+
+	query := fmt.Sprintf(`
+		SELECT COUNT(*)
+		FROM %s f
+		WHERE f.%s IS NOT NULL
+			AND NOT EXISTS (SELECT 1 FROM %s d WHERE d.%s = f.%s)
+	`, table, ref.Column, ref.RefTable, ref.RefColumn, ref.Column)
+
+	if err := tx.QueryRowContext(ctx, query).Scan(&check.Orphans); err != nil {
+		return check, fmt.Errorf("failed to check %s.%s reference: %w", table, ref.Column, err)
+	}
+	return check, nil
+}
+
+// InspectSource sniffs a transactions file in the given format and checks
+// that every row casts to the table schema, without writing to any table.
+// Problems with the file are reported in the inspection; only failures to
+// run the checks are returned as errors.
+func (s *DuckDBService) InspectSource(ctx context.Context, format, path string) (*models.SourceInspection, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, sourceError(path, err)
+	}
+	inspection := &models.SourceInspection{Source: path, SizeBytes: info.Size()}
+	source, err := sourceReader(format, path)
+	if err != nil {
+		return nil, err
+	}
+
+	// reject records a problem with the file, or fails the inspection if the
+	// query was cut short
+	reject := func(op string, err error) error {
+		if ctx.Err() != nil {
+			return queryError(op, err)
+		}
+		inspection.Problems = append(inspection.Problems, err.Error())
+		return nil
+	}
+
+	columns, err := describeSource(ctx, s.db, source)
+	if err != nil {
+		if err := reject("failed to sniff source", err); err != nil {
+			return nil, err
+		}
+		return inspection, nil
+	}
+	inspection.Columns = columns
+
+	header := sourceColumnNames(columns)
+	for i, pos := range transactionsTable.sourceIndex(header) {
+		if pos < 0 {
+			inspection.MissingColumns = append(inspection.MissingColumns, transactionsTable.Columns[i].Name)
+		}
+	}
+
+	countSQL := "SELECT COUNT(*) FROM " + source
+	if len(inspection.MissingColumns) > 0 {
+		inspection.Problems = append(inspection.Problems,
+			fmt.Sprintf("missing columns: %s", strings.Join(inspection.MissingColumns, ", ")))
+	} else {
+		// Filtering on a hash of every cast column makes DuckDB convert each
+		// value; a bare COUNT(*) would prune the casts away
+		names := make([]string, len(transactionsTable.Columns))
+		for i, col := range transactionsTable.Columns {
+			names[i] = col.Name
+		}
+		countSQL = fmt.Sprintf("SELECT COUNT(*) FROM (SELECT %s FROM %s) WHERE hash(%s) IS NOT NULL",
+			transactionsTable.sourceSelectList(header), source, strings.Join(names, ", "))
+	}
+	if err := s.db.QueryRowContext(ctx, countSQL).Scan(&inspection.Records); err != nil {
+		if err := reject("failed to count source rows", err); err != nil {
+			return nil, err
+		}
+	}
+
+	inspection.Valid = len(inspection.Problems) == 0
+	return inspection, nil
+}
+
+// sqlQuerier is a *sql.DB or a *sql.Tx
+type sqlQuerier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// describeSource returns the columns and types DuckDB detects in a source
+func describeSource(ctx context.Context, db sqlQuerier, source string) ([]models.SourceColumn, error) {
+	rows, err := db.QueryContext(ctx, "DESCRIBE SELECT * FROM "+source)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var columns []models.SourceColumn
+	for rows.Next() {
+		var col models.SourceColumn
+		var null, key, defaultValue, extra sql.NullString
+		if err := rows.Scan(&col.Name, &col.Type, &null, &key, &defaultValue, &extra); err != nil {
+			return nil, err
+		}
+		columns = append(columns, col)
+	}
+	return columns, rows.Err()
+}
+
+// sourceColumnNames returns the names of the described columns, in order
+func sourceColumnNames(columns []models.SourceColumn) []string {
+	names := make([]string, len(columns))
+	for i, col := range columns {
+		names[i] = col.Name
+	}
+	return names
+}
+
+// duckdbRowGroupSize is the number of rows DuckDB stores per row group
+const duckdbRowGroupSize = 122880
+
+// GetPartitions describes the transactions month by month, with the row
+// groups each month occupies. Loads insert in date order, so a month spans
+// few row groups and a date filter reads only those.
+func (s *DuckDBService) GetPartitions(ctx context.Context) ([]models.Partition, error) {
+	ctx, done := s.begin(ctx, "partitions")
+	defer done()
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT
+			STRFTIME('%Y-%m', transaction_date) AS month,
+			COUNT(*),
+			MIN(transaction_date),
+			MAX(transaction_date),
+			COUNT(DISTINCT rowid // ?)
+		FROM transactions
+		WHERE transaction_date IS NOT NULL
+		GROUP BY month
+		ORDER BY month
+	`, duckdbRowGroupSize)
+	if err != nil {
+		return nil, queryError("failed to query partitions", err)
+	}
+	defer rows.Close()
+
+	results := []models.Partition{}
+	for rows.Next() {
+		var p models.Partition
+		var from, to time.Time
+		if err := rows.Scan(&p.Month, &p.Rows, &from, &to, &p.RowGroups); err != nil {
+			return nil, fmt.Errorf("failed to scan partition: %w", err)
+		}
+		p.From = from.Format("2006-01-02")
+		p.To = to.Format("2006-01-02")
+		results = append(results, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, queryError("failed to query partitions", err)
+	}
+	return results, nil
+}
@@ -0,0 +1,117 @@
+//go:build cgo
+
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"analytics-dashboard-api/internal/models"
+)
+
+// LoadTargets replaces the revenue targets with the contents of a CSV file
+// with month (YYYY-MM), country and revenue_target columns
+func (s *DuckDBService) LoadTargets(ctx context.Context, path string) (*models.TableLoadResult, error) {
+	result, err := s.LoadTableFromFile(ctx, targetsTable.Name, path, validateTargets)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", models.ErrInvalidTargets, err)
+	}
+	return result, nil
+}
+
+// validateTargets rejects malformed months, missing countries and duplicate rows
+func validateTargets(ctx context.Context, tx *sql.Tx) error {
+	var invalid, duplicates int
+	err := tx.QueryRowContext(ctx, `
+		SELECT COUNT(*)
+		FROM targets
+		WHERE month IS NULL
+			OR NOT regexp_matches(month, '^\d{4}-(0[1-9]|1[0-2])$')
+			OR country IS NULL OR country = ''
+			OR revenue_target IS NULL
+	`).Scan(&invalid)
+	if err != nil {
+		return fmt.Errorf("failed to validate targets: %w", err)
+	}
+	if invalid > 0 {
+		return fmt.Errorf("%d rows have an invalid month, country or revenue_target", invalid)
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		SELECT COUNT(*)
+		FROM (
+			SELECT month, country
+			FROM targets
+			GROUP BY month, country
+			HAVING COUNT(*) > 1
+		)
+	`).Scan(&duplicates)
+	if err != nil {
+		return fmt.Errorf("failed to validate targets: %w", err)
+	}
+	if duplicates > 0 {
+		return fmt.Errorf("%d month/country pairs appear more than once", duplicates)
+	}
+
+	return nil
+}
+
+// GetTargetVariance returns actual revenue against target for every month and
+// country with a target, optionally restricted to one country, matched
+// regardless of case
+func (s *DuckDBService) GetTargetVariance(ctx context.Context, country string) ([]models.TargetVariance, error) {
+	ctx, done := s.begin(ctx, "target_variance")
+	defer done()
+
+	query := `
+		WITH actuals AS (
+			SELECT 
+				STRFTIME('%Y-%m', transaction_date) as month,
+				country,
+				SUM(total_price) as revenue
+			FROM transactions
+			GROUP BY 1, 2
+		)
+		SELECT 
+			tg.month,
+			tg.country,
+			CAST(tg.revenue_target * 100 AS BIGINT) as target,
+			CAST(COALESCE(a.revenue, 0) * 100 AS BIGINT) as actual
+		FROM targets tg
+		LEFT JOIN actuals a ON a.month = tg.month AND a.country = tg.country
+		WHERE ? = '' OR tg.country COLLATE NOCASE = ?
+		ORDER BY tg.month, tg.country
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, country, country)
+	if err != nil {
+		return nil, queryError("failed to query target variance", err)
+	}
+	defer rows.Close()
+
+	var results []models.TargetVariance
+	for rows.Next() {
+		var tv models.TargetVariance
+		err := rows.Scan(
+			&tv.Month,
+			&tv.Country,
+			&tv.Target,
+			&tv.Actual,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan target variance: %w", err)
+		}
+
+		tv.Variance = tv.Actual - tv.Target
+		if tv.Target != 0 {
+			pct := float64(tv.Variance) / float64(tv.Target) * 100
+			tv.VariancePct = &pct
+		}
+		results = append(results, tv)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, queryError("failed to query target variance", err)
+	}
+	return results, nil
+}
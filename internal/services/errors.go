@@ -0,0 +1,28 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"analytics-dashboard-api/internal/models"
+)
+
+// queryError wraps a failed query, classifying an expired deadline as
+// models.ErrQueryTimeout so callers can tell it from a broken query
+func queryError(op string, err error) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("%s: %w: %w", op, models.ErrQueryTimeout, err)
+	}
+	return fmt.Errorf("%s: %w", op, err)
+}
+
+// sourceError wraps a failure to open a source file, classifying a missing
+// file as models.ErrSourceMissing
+func sourceError(path string, err error) error {
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%w: %s", models.ErrSourceMissing, path)
+	}
+	return fmt.Errorf("failed to open %s: %w", path, err)
+}
@@ -0,0 +1,565 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"analytics-dashboard-api/internal/config"
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/pkg/logger"
+)
+
+// TransactionExporter writes the loaded transactions matching a filter to a
+// file, reporting rows written out of the total as it goes
+type TransactionExporter interface {
+	ExportTransactions(context.Context, models.QueryOptions, string, string, func(int64, int64)) (int64, error)
+}
+
+// ExportMailer emails finished exports
+type ExportMailer interface {
+	Configured() bool
+	Send(context.Context, Email) error
+}
+
+// exportFilePattern matches the files ExportManager writes, so only those
+// are swept from the export directory
+var exportFilePattern = regexp.MustCompile(`^[0-9a-f]{16}\.(csv|parquet)(\.tmp)?$`)
+
+// ExportManager runs exports as background jobs on the data job queue, so a
+// file never mixes rows from before and after a refresh. Jobs report their
+// progress, can be cancelled, and are retried with backoff after transient
+// failures. Jobs live in memory; finished ones and their files are discarded
+// after the configured TTL. A succeeded export can be emailed, attached or as
+// a download link signed with a key that, like the jobs, lasts until restart.
+type ExportManager struct {
+	cfg      config.ExportConfig
+	exporter TransactionExporter
+	loader   *DataLoader
+	mailer   ExportMailer
+	linkKey  []byte
+	logger   logger.Logger
+
+	mu      sync.Mutex
+	exports map[string]*exportEntry
+}
+
+// exportEntry is a job and the means to stop it
+type exportEntry struct {
+	job    models.ExportJob
+	email  *models.ExportEmail
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// snapshot copies the job for use outside mu. The caller holds mu.
+func (e *exportEntry) snapshot() models.ExportJob {
+	job := e.job
+	if job.Email != nil {
+		email := *job.Email
+		job.Email = &email
+	}
+	return job
+}
+
+func NewExportManager(cfg config.ExportConfig, exporter TransactionExporter, loader *DataLoader, mailer ExportMailer, logger logger.Logger) (*ExportManager, error) {
+	if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create export directory: %w", err)
+	}
+	linkKey := make([]byte, 32)
+	if _, err := rand.Read(linkKey); err != nil {
+		return nil, fmt.Errorf("failed to generate link signing key: %w", err)
+	}
+
+	m := &ExportManager{
+		cfg:      cfg,
+		exporter: exporter,
+		loader:   loader,
+		mailer:   mailer,
+		linkKey:  linkKey,
+		logger:   logger,
+		exports:  map[string]*exportEntry{},
+	}
+	// Jobs do not survive a restart, so neither do their files
+	m.removeOrphanedFiles()
+	return m, nil
+}
+
+// Start queues an export and returns at once; poll Get for its progress
+func (m *ExportManager) Start(req models.ExportRequest) (*models.ExportJob, error) {
+	if req.Format == "" {
+		req.Format = models.ExportFormatCSV
+	}
+	if req.Format != models.ExportFormatCSV && req.Format != models.ExportFormatParquet {
+		return nil, fmt.Errorf("%w: format must be %s or %s", models.ErrInvalidExport, models.ExportFormatCSV, models.ExportFormatParquet)
+	}
+	if err := m.validateEmail(req.Email); err != nil {
+		return nil, fmt.Errorf("%w: %w", models.ErrInvalidExport, err)
+	}
+
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	entry := &exportEntry{
+		job: models.ExportJob{
+			ID:        id,
+			Format:    req.Format,
+			Country:   req.Country,
+			Segment:   req.Segment,
+			Status:    models.ExportQueued,
+			CreatedAt: time.Now().UTC(),
+		},
+		email:  req.Email,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	if req.Email != nil {
+		entry.job.Email = &models.EmailDelivery{To: req.Email.To, Status: models.EmailPending}
+	}
+
+	m.mu.Lock()
+	m.sweep()
+	m.exports[id] = entry
+	job := entry.snapshot()
+	m.mu.Unlock()
+
+	m.logger.Info("Export queued", "export", id, "format", req.Format, "country", req.Country, "segment", req.Segment)
+	go m.run(ctx, entry)
+	return &job, nil
+}
+
+// Get returns an export job
+func (m *ExportManager) Get(id string) (*models.ExportJob, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.exports[id]
+	if !ok {
+		return nil, models.ErrExportNotFound
+	}
+	job := entry.snapshot()
+	return &job, nil
+}
+
+// List returns every export job, newest first
+func (m *ExportManager) List() []models.ExportJob {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sweep()
+	jobs := make([]models.ExportJob, 0, len(m.exports))
+	for _, entry := range m.exports {
+		jobs = append(jobs, entry.snapshot())
+	}
+	slices.SortFunc(jobs, func(a, b models.ExportJob) int {
+		return b.CreatedAt.Compare(a.CreatedAt)
+	})
+	return jobs
+}
+
+// Cancel stops a queued or running export and waits for it to wind down
+func (m *ExportManager) Cancel(id string) (*models.ExportJob, error) {
+	m.mu.Lock()
+	entry, ok := m.exports[id]
+	if ok && entry.job.Finished() {
+		m.mu.Unlock()
+		return nil, models.ErrExportFinished
+	}
+	m.mu.Unlock()
+	if !ok {
+		return nil, models.ErrExportNotFound
+	}
+
+	entry.cancel()
+	<-entry.done
+	return m.Get(id)
+}
+
+// Delete discards an export and its file, cancelling it first if needed
+func (m *ExportManager) Delete(id string) error {
+	m.mu.Lock()
+	entry, ok := m.exports[id]
+	m.mu.Unlock()
+	if !ok {
+		return models.ErrExportNotFound
+	}
+
+	entry.cancel()
+	<-entry.done
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.remove(id)
+	return nil
+}
+
+// File returns the path of a succeeded export's file
+func (m *ExportManager) File(id string) (string, *models.ExportJob, error) {
+	job, err := m.Get(id)
+	if err != nil {
+		return "", nil, err
+	}
+	if job.Status != models.ExportSucceeded {
+		return "", nil, fmt.Errorf("%w: status is %s", models.ErrExportNotReady, job.Status)
+	}
+	return m.path(job), job, nil
+}
+
+// run makes up to MaxAttempts attempts, backing off between them
+func (m *ExportManager) run(ctx context.Context, entry *exportEntry) {
+	defer close(entry.done)
+	defer entry.cancel()
+
+	backoff := m.cfg.RetryBackoff
+	for attempt := 1; ; attempt++ {
+		m.update(entry, func(job *models.ExportJob) {
+			job.Attempts = attempt
+			job.Status = models.ExportQueued
+		})
+
+		err := m.attempt(ctx, entry)
+		switch {
+		case err == nil:
+			m.finish(entry, models.ExportSucceeded, nil)
+			if entry.email != nil {
+				m.deliver(ctx, entry)
+			}
+			return
+		case ctx.Err() != nil:
+			m.finish(entry, models.ExportCancelled, nil)
+			return
+		case attempt >= m.cfg.MaxAttempts || !retryableExportError(err):
+			m.finish(entry, models.ExportFailed, err)
+			return
+		}
+
+		m.logger.Warn("Export attempt failed, retrying",
+			"export", entry.job.ID, "attempt", attempt, "retry_in", backoff, "error", err)
+		m.update(entry, func(job *models.ExportJob) {
+			job.Error = err.Error()
+		})
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			m.finish(entry, models.ExportCancelled, nil)
+			return
+		}
+		backoff *= 2
+	}
+}
+
+// attempt writes the file to a temporary path and moves it into place once
+// complete, so a failed attempt never leaves a partial file behind
+func (m *ExportManager) attempt(ctx context.Context, entry *exportEntry) error {
+	// Before queueing: the initial load runs on the same queue
+	if err := m.loader.EnsureInitialized(ctx); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	job := entry.snapshot()
+	m.mu.Unlock()
+	path := m.path(&job)
+	opts := models.QueryOptions{Country: job.Country, Segment: job.Segment}
+
+	return m.loader.RunJob(ctx, "export", func(context.Context) error {
+		// The queue runs jobs detached from callers; cancelling the export
+		// has to stop it even mid-file
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		started := time.Now().UTC()
+		m.update(entry, func(job *models.ExportJob) {
+			job.Status = models.ExportRunning
+			job.StartedAt = &started
+		})
+
+		tmp := path + ".tmp"
+		rows, err := m.exporter.ExportTransactions(ctx, opts, job.Format, tmp, func(written, total int64) {
+			m.update(entry, func(job *models.ExportJob) {
+				job.RowsWritten = written
+				job.TotalRows = total
+				job.Progress = 1
+				if total > 0 {
+					job.Progress = float64(written) / float64(total)
+				}
+			})
+		})
+		if err != nil {
+			os.Remove(tmp)
+			return err
+		}
+		if err := os.Rename(tmp, path); err != nil {
+			os.Remove(tmp)
+			return fmt.Errorf("failed to finish export file: %w", err)
+		}
+
+		var size int64
+		if info, err := os.Stat(path); err == nil {
+			size = info.Size()
+		}
+		m.update(entry, func(job *models.ExportJob) {
+			job.RowsWritten = rows
+			job.SizeBytes = size
+		})
+		return nil
+	})
+}
+
+// VerifyLink checks the expiry and signature of a download link sent by email
+func (m *ExportManager) VerifyLink(id, expires, signature string) error {
+	expiry, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > expiry {
+		return models.ErrExportLink
+	}
+	given, err := hex.DecodeString(signature)
+	if err != nil || !hmac.Equal(given, m.signature(id, expiry)) {
+		return models.ErrExportLink
+	}
+	return nil
+}
+
+func (m *ExportManager) validateEmail(email *models.ExportEmail) error {
+	if email == nil {
+		return nil
+	}
+	if m.mailer == nil || !m.mailer.Configured() {
+		return errors.New("email delivery needs SMTP_HOST to be configured")
+	}
+	if err := validEmailAddresses(email.To); err != nil {
+		return err
+	}
+	switch email.Delivery {
+	case "", models.EmailAttachment:
+	case models.EmailLink:
+		if m.cfg.PublicURL == "" {
+			return errors.New("link delivery needs EXPORT_PUBLIC_URL to be configured")
+		}
+	default:
+		return fmt.Errorf("delivery must be %s or %s", models.EmailAttachment, models.EmailLink)
+	}
+	return nil
+}
+
+// deliver emails a succeeded export, retrying transient failures with the
+// same attempts and backoff as the export itself. A failed delivery leaves
+// the export succeeded and downloadable.
+func (m *ExportManager) deliver(ctx context.Context, entry *exportEntry) {
+	m.mu.Lock()
+	job := entry.snapshot()
+	m.mu.Unlock()
+
+	email, via, err := m.composeEmail(&job, entry.email)
+	if err == nil {
+		err = m.send(ctx, entry, email)
+	}
+
+	sent := time.Now().UTC()
+	m.update(entry, func(job *models.ExportJob) {
+		job.Email.Via = via
+		if err != nil {
+			job.Email.Status = models.EmailFailed
+			job.Email.Error = err.Error()
+			return
+		}
+		job.Email.Status = models.EmailSent
+		job.Email.SentAt = &sent
+	})
+	if err != nil {
+		m.logger.Error("Export email failed", "export", job.ID, "error", err)
+		return
+	}
+	m.logger.Info("Export emailed", "export", job.ID, "recipients", len(email.To), "via", via)
+}
+
+// send makes up to MaxAttempts attempts at sending email
+func (m *ExportManager) send(ctx context.Context, entry *exportEntry, email Email) error {
+	backoff := m.cfg.RetryBackoff
+	for attempt := 1; ; attempt++ {
+		m.update(entry, func(job *models.ExportJob) {
+			job.Email.Attempts = attempt
+		})
+		err := m.mailer.Send(ctx, email)
+		if err == nil || attempt >= m.cfg.MaxAttempts || !retryableMailError(err) {
+			return err
+		}
+
+		m.logger.Warn("Export email failed, retrying", "export", entry.job.ID, "attempt", attempt, "retry_in", backoff, "error", err)
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+		backoff *= 2
+	}
+}
+
+// composeEmail attaches the file unless a link was asked for or the file is
+// over the attachment limit
+func (m *ExportManager) composeEmail(job *models.ExportJob, request *models.ExportEmail) (Email, string, error) {
+	email := Email{To: request.To, Subject: request.Subject}
+	if email.Subject == "" {
+		email.Subject = "Transactions export " + job.ID
+	}
+
+	filter := "all transactions"
+	var conditions []string
+	if job.Country != "" {
+		conditions = append(conditions, "country "+job.Country)
+	}
+	if job.Segment != "" {
+		conditions = append(conditions, "segment "+job.Segment)
+	}
+	if len(conditions) > 0 {
+		filter = strings.Join(conditions, ", ")
+	}
+	body := fmt.Sprintf("Transactions export %s\nFormat: %s\nFilter: %s\nRows: %d\n\n", job.ID, job.Format, filter, job.RowsWritten)
+
+	via := request.Delivery
+	if via == "" {
+		via = models.EmailAttachment
+	}
+	if via == models.EmailAttachment && job.SizeBytes > m.cfg.MaxAttachmentBytes {
+		if m.cfg.PublicURL == "" {
+			return Email{}, via, fmt.Errorf("file is %d bytes, over the %d byte attachment limit, and no EXPORT_PUBLIC_URL is set for a link", job.SizeBytes, m.cfg.MaxAttachmentBytes)
+		}
+		via = models.EmailLink
+	}
+
+	if via == models.EmailAttachment {
+		email.Attachment = m.path(job)
+		email.AttachmentName = job.Filename()
+		email.Body = body + "The file is attached.\n"
+		return email, via, nil
+	}
+	email.Body = body + fmt.Sprintf("Download the file before %s:\n%s\n", job.ExpiresAt.Format(time.RFC1123), m.signedLink(job))
+	return email, via, nil
+}
+
+// signedLink is a download URL valid until the export expires
+func (m *ExportManager) signedLink(job *models.ExportJob) string {
+	expiry := job.ExpiresAt.Unix()
+	query := url.Values{
+		"expires":   {strconv.FormatInt(expiry, 10)},
+		"signature": {hex.EncodeToString(m.signature(job.ID, expiry))},
+	}
+	return strings.TrimSuffix(m.cfg.PublicURL, "/") + "/api/v1/exports/" + job.ID + "/download?" + query.Encode()
+}
+
+func (m *ExportManager) signature(id string, expiry int64) []byte {
+	mac := hmac.New(sha256.New, m.linkKey)
+	fmt.Fprintf(mac, "%s.%d", id, expiry)
+	return mac.Sum(nil)
+}
+
+func (m *ExportManager) update(entry *exportEntry, fn func(*models.ExportJob)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	fn(&entry.job)
+}
+
+func (m *ExportManager) finish(entry *exportEntry, status string, err error) {
+	finished := time.Now().UTC()
+	expires := finished.Add(m.cfg.TTL)
+	m.update(entry, func(job *models.ExportJob) {
+		job.Status = status
+		job.FinishedAt = &finished
+		job.ExpiresAt = &expires
+		job.Error = ""
+		if err != nil {
+			job.Error = err.Error()
+		}
+	})
+
+	m.mu.Lock()
+	job := entry.snapshot()
+	m.mu.Unlock()
+	switch status {
+	case models.ExportSucceeded:
+		m.logger.Info("Export finished", "export", job.ID, "rows", job.RowsWritten, "size_bytes", job.SizeBytes, "attempts", job.Attempts)
+	case models.ExportFailed:
+		m.logger.Error("Export failed", "export", job.ID, "attempts", job.Attempts, "error", err)
+	default:
+		m.logger.Info("Export cancelled", "export", job.ID)
+	}
+}
+
+// sweep discards finished exports past their TTL. The caller holds mu.
+func (m *ExportManager) sweep() {
+	now := time.Now()
+	for id, entry := range m.exports {
+		if entry.job.ExpiresAt != nil && now.After(*entry.job.ExpiresAt) {
+			m.logger.Info("Removing expired export", "export", id)
+			m.remove(id)
+		}
+	}
+}
+
+// remove forgets an export and deletes its file. The caller holds mu.
+func (m *ExportManager) remove(id string) {
+	entry, ok := m.exports[id]
+	if !ok {
+		return
+	}
+	delete(m.exports, id)
+	if err := os.Remove(m.path(&entry.job)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		m.logger.Warn("Failed to remove export file", "export", id, "error", err)
+	}
+}
+
+func (m *ExportManager) removeOrphanedFiles() {
+	entries, err := os.ReadDir(m.cfg.Dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && exportFilePattern.MatchString(entry.Name()) {
+			os.Remove(filepath.Join(m.cfg.Dir, entry.Name()))
+		}
+	}
+}
+
+func (m *ExportManager) path(job *models.ExportJob) string {
+	return filepath.Join(m.cfg.Dir, job.ID+"."+job.Format)
+}
+
+// retryableExportError reports whether a failed attempt may succeed if
+// repeated: the data was still loading or failed to load, a query timed out,
+// or an I/O or database error occurred. Errors the caller has to fix are final.
+func retryableExportError(err error) bool {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return false
+	case errors.Is(err, models.ErrDataLoading),
+		errors.Is(err, models.ErrDataNotLoaded),
+		errors.Is(err, models.ErrQueryTimeout):
+		return true
+	case errors.Is(err, models.ErrValidation),
+		errors.Is(err, models.ErrNotFound),
+		errors.Is(err, models.ErrConflict),
+		errors.Is(err, models.ErrNotSupported),
+		errors.Is(err, models.ErrSourceMissing):
+		return false
+	}
+	return true
+}
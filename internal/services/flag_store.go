@@ -0,0 +1,178 @@
+package services
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/pkg/logger"
+)
+
+// maxFlagsPerRequest bounds the transactions flagged by one request
+const maxFlagsPerRequest = 10000
+
+// TransactionExcluder leaves transactions out of every later load
+type TransactionExcluder interface {
+	ExcludeTransactions([]string) error
+}
+
+// FlagStore keeps the flagged transactions in a JSON file in the state
+// directory and hands their IDs to the backend, which leaves them out of
+// every load. The source files are never edited.
+type FlagStore struct {
+	mu       sync.Mutex
+	path     string
+	flags    map[string]models.TransactionFlag
+	excluder TransactionExcluder
+	logger   logger.Logger
+}
+
+// NewFlagStore loads the stored flags and applies them to excluder. A
+// backend that cannot exclude transactions is logged, not fatal, so a
+// deployment can switch backends without losing its flags.
+func NewFlagStore(stateDir string, excluder TransactionExcluder, logger logger.Logger) (*FlagStore, error) {
+	store := &FlagStore{
+		path:     filepath.Join(stateDir, "flags.json"),
+		flags:    make(map[string]models.TransactionFlag),
+		excluder: excluder,
+		logger:   logger,
+	}
+
+	var flags []models.TransactionFlag
+	if err := loadJSONFile(store.path, &flags); err != nil {
+		return nil, err
+	}
+	for _, flag := range flags {
+		store.flags[flag.TransactionID] = flag
+	}
+
+	if len(store.flags) > 0 {
+		if err := excluder.ExcludeTransactions(store.ids()); err != nil {
+			logger.Warn("Flagged transactions are not excluded by this backend", "flags", len(store.flags), "error", err)
+		}
+	}
+	return store, nil
+}
+
+// List returns the flagged transactions, most recently flagged first
+func (s *FlagStore) List() []models.TransactionFlag {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sorted()
+}
+
+// Flag excludes the requested transactions from every later load. Flagging
+// a transaction again replaces its reason. It returns the new flags.
+func (s *FlagStore) Flag(request models.FlagRequest, flaggedBy string) ([]models.TransactionFlag, error) {
+	if err := validateFlagRequest(request); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous := s.flags
+	s.flags = make(map[string]models.TransactionFlag, len(previous)+len(request.TransactionIDs))
+	for id, flag := range previous {
+		s.flags[id] = flag
+	}
+	now := time.Now().UTC()
+	flagged := make([]models.TransactionFlag, 0, len(request.TransactionIDs))
+	for _, id := range request.TransactionIDs {
+		flag := models.TransactionFlag{
+			TransactionID: strings.TrimSpace(id),
+			Reason:        request.Reason,
+			FlaggedBy:     flaggedBy,
+			FlaggedAt:     now,
+		}
+		s.flags[flag.TransactionID] = flag
+		flagged = append(flagged, flag)
+	}
+
+	if err := s.apply(); err != nil {
+		s.revert(previous)
+		return nil, err
+	}
+	return flagged, nil
+}
+
+// Unflag lets a transaction back into later loads
+func (s *FlagStore) Unflag(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.flags[id]; !ok {
+		return models.ErrFlagNotFound
+	}
+	previous := s.flags
+	s.flags = make(map[string]models.TransactionFlag, len(previous))
+	for other, flag := range previous {
+		if other != id {
+			s.flags[other] = flag
+		}
+	}
+	if err := s.apply(); err != nil {
+		s.revert(previous)
+		return err
+	}
+	return nil
+}
+
+// apply hands the current flags to the backend and saves them
+func (s *FlagStore) apply() error {
+	if err := s.excluder.ExcludeTransactions(s.ids()); err != nil {
+		return fmt.Errorf("failed to exclude flagged transactions: %w", err)
+	}
+	return saveJSONFile(s.path, s.sorted())
+}
+
+// revert restores flags after apply failed, giving the backend the previous
+// IDs again so the backend and the file never disagree
+func (s *FlagStore) revert(flags map[string]models.TransactionFlag) {
+	s.flags = flags
+	if err := s.excluder.ExcludeTransactions(s.ids()); err != nil {
+		s.logger.Warn("Failed to restore excluded transactions", "error", err)
+	}
+}
+
+func (s *FlagStore) ids() []string {
+	ids := make([]string, 0, len(s.flags))
+	for id := range s.flags {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func (s *FlagStore) sorted() []models.TransactionFlag {
+	flags := make([]models.TransactionFlag, 0, len(s.flags))
+	for _, flag := range s.flags {
+		flags = append(flags, flag)
+	}
+	sort.Slice(flags, func(i, j int) bool {
+		if !flags[i].FlaggedAt.Equal(flags[j].FlaggedAt) {
+			return flags[i].FlaggedAt.After(flags[j].FlaggedAt)
+		}
+		return flags[i].TransactionID < flags[j].TransactionID
+	})
+	return flags
+}
+
+func validateFlagRequest(request models.FlagRequest) error {
+	if len(request.TransactionIDs) == 0 {
+		return fmt.Errorf("%w: transaction_ids must not be empty", models.ErrInvalidFlag)
+	}
+	if len(request.TransactionIDs) > maxFlagsPerRequest {
+		return fmt.Errorf("%w: at most %d transactions per request", models.ErrInvalidFlag, maxFlagsPerRequest)
+	}
+	for _, id := range request.TransactionIDs {
+		if strings.TrimSpace(id) == "" {
+			return fmt.Errorf("%w: transaction IDs must not be blank", models.ErrInvalidFlag)
+		}
+	}
+	return nil
+}
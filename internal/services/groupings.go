@@ -0,0 +1,18 @@
+package services
+
+// MetricGroupings maps aggregate group_by values to DuckDB SQL expressions.
+// Its keys are the groupings every backend accepts.
+var MetricGroupings = map[string]string{
+	"month":    "STRFTIME(transaction_date, '%Y-%m')",
+	"country":  "country",
+	"region":   "region",
+	"category": "category",
+	"product":  "product_name",
+}
+
+// dimensionColumns whitelists the transaction columns exposed as filter values
+var dimensionColumns = map[string]string{
+	"country":  "country",
+	"category": "category",
+	"region":   "region",
+}
@@ -0,0 +1,143 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+
+	"analytics-dashboard-api/internal/config"
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/pkg/logger"
+)
+
+// RegionQuerier reads revenue per country and region
+type RegionQuerier interface {
+	GetRegionSales(context.Context, models.QueryOptions) ([]models.RegionSales, error)
+}
+
+// Bounds of ?countries= and ?regions=
+const (
+	maxHeatmapCountries = 250
+	maxHeatmapRegions   = 100
+)
+
+// HeatmapService aggregates revenue into the country and region cells of
+// the map widget. Countries are ranked by revenue and regions by revenue
+// within their country; what falls outside the grid can be rolled into
+// "Other" cells so the map still adds up to the total.
+type HeatmapService struct {
+	grid    models.HeatmapGrid
+	queries RegionQuerier
+	logger  logger.Logger
+}
+
+func NewHeatmapService(cfg config.HeatmapConfig, queries RegionQuerier, logger logger.Logger) *HeatmapService {
+	return &HeatmapService{
+		grid:    models.HeatmapGrid{Countries: cfg.TopCountries, Regions: cfg.TopRegions},
+		queries: queries,
+		logger:  logger,
+	}
+}
+
+// Heatmap returns the cells of grid for the transactions under opts. Zero
+// grid sizes take the configured ones. Intensity is the revenue of a cell
+// over that of the largest named cell. "Other" cells have no place on the
+// map and would often dwarf every region, so they are left out of the scale
+// and capped at 1.
+func (s *HeatmapService) Heatmap(ctx context.Context, opts models.QueryOptions, grid models.HeatmapGrid) (*models.Heatmap, error) {
+	if grid.Countries == 0 {
+		grid.Countries = s.grid.Countries
+	}
+	if grid.Regions == 0 {
+		grid.Regions = s.grid.Regions
+	}
+	if grid.Countries < 1 || grid.Countries > maxHeatmapCountries {
+		return nil, fmt.Errorf("%w: countries must be between 1 and %d", models.ErrInvalidHeatmapGrid, maxHeatmapCountries)
+	}
+	if grid.Regions < 1 || grid.Regions > maxHeatmapRegions {
+		return nil, fmt.Errorf("%w: regions must be between 1 and %d", models.ErrInvalidHeatmapGrid, maxHeatmapRegions)
+	}
+
+	sales, err := s.queries.GetRegionSales(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// Regions arrive largest first, so each country keeps that order
+	type countrySales struct {
+		name    string
+		revenue models.Money
+		regions []models.RegionSales
+	}
+	var countries []*countrySales
+	byName := make(map[string]*countrySales)
+	heatmap := &models.Heatmap{Grid: grid}
+	for _, rs := range sales {
+		c := byName[rs.Country]
+		if c == nil {
+			c = &countrySales{name: rs.Country}
+			byName[rs.Country] = c
+			countries = append(countries, c)
+		}
+		c.revenue += rs.Revenue
+		c.regions = append(c.regions, rs)
+		heatmap.TotalRevenue += rs.Revenue
+	}
+	sort.SliceStable(countries, func(i, j int) bool {
+		if countries[i].revenue != countries[j].revenue {
+			return countries[i].revenue > countries[j].revenue
+		}
+		return countries[i].name < countries[j].name
+	})
+	heatmap.Countries = len(countries)
+
+	heatmap.Cells = []models.HeatmapCell{}
+	otherCountries := models.HeatmapCell{Country: models.OtherBucket, Region: models.OtherBucket, Other: true}
+	for i, c := range countries {
+		if i >= grid.Countries {
+			for _, rs := range c.regions {
+				addRegionSales(&otherCountries, rs)
+			}
+			continue
+		}
+		otherRegions := models.HeatmapCell{Country: c.name, Region: models.OtherBucket, Other: true}
+		for j, rs := range c.regions {
+			if j >= grid.Regions {
+				addRegionSales(&otherRegions, rs)
+				continue
+			}
+			cell := models.HeatmapCell{Country: rs.Country, Region: rs.Region}
+			addRegionSales(&cell, rs)
+			heatmap.Cells = append(heatmap.Cells, cell)
+		}
+		if grid.Other && len(c.regions) > grid.Regions {
+			heatmap.Cells = append(heatmap.Cells, otherRegions)
+		}
+	}
+	if grid.Other && len(countries) > grid.Countries {
+		heatmap.Cells = append(heatmap.Cells, otherCountries)
+	}
+
+	for _, cell := range heatmap.Cells {
+		if !cell.Other {
+			heatmap.MaxRevenue = max(heatmap.MaxRevenue, cell.Revenue)
+		}
+	}
+	if heatmap.MaxRevenue > 0 {
+		for i := range heatmap.Cells {
+			cell := &heatmap.Cells[i]
+			// Cells with net refunds show as 0 rather than below the scale
+			intensity := math.Round(float64(cell.Revenue)/float64(heatmap.MaxRevenue)*10000) / 10000
+			cell.Intensity = min(max(intensity, 0), 1)
+		}
+	}
+	return heatmap, nil
+}
+
+// addRegionSales adds the figures of rs to cell
+func addRegionSales(cell *models.HeatmapCell, rs models.RegionSales) {
+	cell.Revenue += rs.Revenue
+	cell.Transactions += rs.Transactions
+	cell.ItemsSold += rs.ItemsSold
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"analytics-dashboard-api/internal/config"
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/pkg/logger"
+)
+
+// StockQuerier reads the sales and closing stock of each product
+type StockQuerier interface {
+	GetStockLevels(context.Context, models.QueryOptions, time.Time) ([]models.StockLevel, error)
+	DataCoverage() models.DataCoverage
+}
+
+// maxSellThroughWindowDays bounds ?window_days=
+const maxSellThroughWindowDays = 3660
+
+// InventoryService computes inventory metrics from the quantity and
+// stock_quantity of the transactions. The stock of a product is the
+// stock_quantity of its latest transaction up to the end of the window,
+// so products that did not sell in the window still show their stock.
+type InventoryService struct {
+	windowDays int
+	queries    StockQuerier
+	logger     logger.Logger
+}
+
+func NewInventoryService(cfg config.InventoryConfig, queries StockQuerier, logger logger.Logger) *InventoryService {
+	return &InventoryService{
+		windowDays: cfg.SellThroughWindowDays,
+		queries:    queries,
+		logger:     logger,
+	}
+}
+
+// SellThrough returns sell-through per product or category (by) over the
+// windowDays ending on end, inclusive. Zero windowDays takes the configured
+// window, and a zero end the date of the latest transaction.
+func (s *InventoryService) SellThrough(ctx context.Context, opts models.QueryOptions, by string, windowDays int, end time.Time) (*models.SellThroughReport, error) {
+	switch by {
+	case "":
+		by = "product"
+	case "product", "category":
+	default:
+		return nil, fmt.Errorf("%w: cannot group by %q (want product or category)", models.ErrInvalidSellThrough, by)
+	}
+	if windowDays == 0 {
+		windowDays = s.windowDays
+	}
+	if windowDays < 1 || windowDays > maxSellThroughWindowDays {
+		return nil, fmt.Errorf("%w: window must be between 1 and %d days", models.ErrInvalidSellThrough, maxSellThroughWindowDays)
+	}
+	if end.IsZero() {
+		latest, err := time.Parse("2006-01-02", s.queries.DataCoverage().To)
+		if err != nil {
+			latest = time.Now().UTC().Truncate(24 * time.Hour)
+		}
+		end = latest
+	}
+
+	since := end.AddDate(0, 0, 1-windowDays)
+	opts.From, opts.To = time.Time{}, end.AddDate(0, 0, 1)
+	levels, err := s.queries.GetStockLevels(ctx, opts, since)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.SellThroughReport{
+		By: by,
+		Window: models.SellThroughWindow{
+			From: since.Format("2006-01-02"),
+			To:   end.Format("2006-01-02"),
+			Days: windowDays,
+		},
+		Rows: make([]models.SellThrough, 0, len(levels)),
+	}
+	if by == "product" {
+		for _, level := range levels {
+			report.Rows = append(report.Rows, withSellThrough(models.SellThrough{
+				Group:       level.ProductID,
+				ProductName: level.ProductName,
+				Category:    level.Category,
+				UnitsSold:   level.UnitsSold,
+				Stock:       level.Stock,
+			}))
+		}
+		return report, nil
+	}
+
+	categories := make(map[string]*models.SellThrough)
+	for _, level := range levels {
+		row := categories[level.Category]
+		if row == nil {
+			row = &models.SellThrough{Group: level.Category}
+			categories[level.Category] = row
+		}
+		row.Products++
+		row.UnitsSold += level.UnitsSold
+		row.Stock += level.Stock
+	}
+	for _, row := range categories {
+		report.Rows = append(report.Rows, withSellThrough(*row))
+	}
+	sort.Slice(report.Rows, func(i, j int) bool { return report.Rows[i].Group < report.Rows[j].Group })
+	return report, nil
+}
+
+func withSellThrough(row models.SellThrough) models.SellThrough {
+	if available := row.UnitsSold + row.Stock; available > 0 {
+		pct := float64(row.UnitsSold) / float64(available) * 100
+		row.SellThroughPct = &pct
+	}
+	return row
+}
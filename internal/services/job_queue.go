@@ -0,0 +1,322 @@
+package services
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+	"time"
+
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/pkg/logger"
+)
+
+// JobPriority orders queued jobs; higher priorities run first
+type JobPriority int
+
+const (
+	// PriorityScheduled is for background work nobody is waiting on
+	PriorityScheduled JobPriority = iota
+	// PriorityManual is for refreshes, uploads and rollbacks requested via the API
+	PriorityManual
+	// PriorityInitial is for the first load, which every request waits on
+	PriorityInitial
+)
+
+func (p JobPriority) String() string {
+	switch p {
+	case PriorityScheduled:
+		return "scheduled"
+	case PriorityManual:
+		return "manual"
+	case PriorityInitial:
+		return "initial"
+	}
+	return "unknown"
+}
+
+// finishedJobsKept is how many finished jobs Job can still look up
+const finishedJobsKept = 100
+
+// JobQueue runs jobs that change the loaded data (loads, uploads, rollbacks)
+// one at a time, by priority and then in submission order, on a single
+// worker goroutine. Callers either wait for their job with their own
+// context, so a disconnecting client stops waiting without aborting a job
+// that other requests may depend on, or submit it and look it up later.
+type JobQueue struct {
+	logger logger.Logger
+
+	mu        sync.Mutex
+	pending   jobHeap
+	running   *job
+	finished  []*job // oldest first, at most finishedJobsKept
+	nextID    uint64
+	completed int64
+	failed    int64
+}
+
+type job struct {
+	id         uint64
+	kind       string
+	priority   JobPriority
+	run        func(context.Context) error
+	detached   bool // submitted without a waiting caller
+	enqueued   time.Time
+	started    time.Time
+	finishedAt time.Time
+	done       chan struct{}
+	err        error // set before done is closed
+}
+
+// info describes the job; the queue's lock must be held
+func (j *job) info() models.JobInfo {
+	info := models.JobInfo{
+		ID:         j.id,
+		Kind:       j.kind,
+		Priority:   j.priority.String(),
+		Status:     models.JobQueued,
+		EnqueuedAt: j.enqueued,
+	}
+	if !j.started.IsZero() {
+		info.StartedAt = &j.started
+		info.Status = models.JobRunning
+	}
+	if !j.finishedAt.IsZero() {
+		info.FinishedAt = &j.finishedAt
+		info.Status = models.JobSucceeded
+		if j.err != nil {
+			info.Status = models.JobFailed
+			info.Error = j.err.Error()
+		}
+	}
+	return info
+}
+
+// jobHeap is a max-heap on priority, FIFO within a priority
+type jobHeap []*job
+
+func (h jobHeap) Len() int           { return len(h) }
+func (h jobHeap) Less(i, j int) bool { return h.less(h[i], h[j]) }
+
+// less reports whether a runs before b
+func (jobHeap) less(a, b *job) bool {
+	if a.priority != b.priority {
+		return a.priority > b.priority
+	}
+	return a.id < b.id
+}
+func (h jobHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *jobHeap) Push(x any)   { *h = append(*h, x.(*job)) }
+func (h *jobHeap) Pop() any {
+	old := *h
+	j := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return j
+}
+
+func NewJobQueue(logger logger.Logger) *JobQueue {
+	return &JobQueue{logger: logger}
+}
+
+// Run queues fn and waits for it to finish or for ctx to be done. The job
+// runs even if the caller stops waiting.
+func (q *JobQueue) Run(ctx context.Context, kind string, priority JobPriority, fn func(context.Context) error) error {
+	j := q.submit(kind, priority, fn)
+	select {
+	case <-j.done:
+		return j.err
+	case <-ctx.Done():
+		q.logger.Debug("Stopped waiting for job", "job", j.id, "kind", kind, "error", ctx.Err())
+		return ctx.Err()
+	}
+}
+
+// Submit queues fn and returns without waiting; the job's progress and
+// outcome are available from Job. A job of the same kind submitted earlier
+// and still queued already covers fn, so it is returned instead and fn is
+// dropped: repeated requests never pile up behind a long job.
+func (q *JobQueue) Submit(kind string, priority JobPriority, fn func(context.Context) error) models.JobInfo {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, queued := range q.pending {
+		if queued.detached && queued.kind == kind {
+			q.logger.Debug("Job already queued", "job", queued.id, "kind", kind)
+			return queued.info()
+		}
+	}
+	j := q.enqueue(kind, priority, fn)
+	j.detached = true
+	return j.info()
+}
+
+// Job looks up a queued, running or recently finished job
+func (q *JobQueue) Job(id uint64) (models.JobInfo, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	j := q.find(id)
+	if j == nil {
+		return models.JobInfo{}, models.ErrJobNotFound
+	}
+	return j.info(), nil
+}
+
+// Wait waits for a queued, running or recently finished job to finish, or
+// for ctx to be done, and returns the job with its error. Like Run, the job
+// runs on if the caller stops waiting, so any number of callers can follow
+// a job someone else submitted.
+func (q *JobQueue) Wait(ctx context.Context, id uint64) (models.JobInfo, error) {
+	q.mu.Lock()
+	j := q.find(id)
+	q.mu.Unlock()
+	if j == nil {
+		return models.JobInfo{}, models.ErrJobNotFound
+	}
+
+	var err error
+	select {
+	case <-j.done:
+		err = j.err
+	case <-ctx.Done():
+		q.logger.Debug("Stopped waiting for job", "job", j.id, "kind", j.kind, "error", ctx.Err())
+		err = ctx.Err()
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return j.info(), err
+}
+
+// Latest returns the running job of kind, the next queued one and the one
+// that finished last; each is nil when there is none
+func (q *JobQueue) Latest(kind string) (running, queued, last *models.JobInfo) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.running != nil && q.running.kind == kind {
+		info := q.running.info()
+		running = &info
+	}
+	var next *job
+	for _, j := range q.pending {
+		if j.kind == kind && (next == nil || q.pending.less(j, next)) {
+			next = j
+		}
+	}
+	if next != nil {
+		info := next.info()
+		queued = &info
+	}
+	for i := len(q.finished) - 1; i >= 0; i-- {
+		if q.finished[i].kind == kind {
+			info := q.finished[i].info()
+			last = &info
+			break
+		}
+	}
+	return running, queued, last
+}
+
+// find returns the job with id, or nil once it is no longer kept; the lock
+// must be held
+func (q *JobQueue) find(id uint64) *job {
+	if q.running != nil && q.running.id == id {
+		return q.running
+	}
+	for _, j := range q.pending {
+		if j.id == id {
+			return j
+		}
+	}
+	for _, j := range q.finished {
+		if j.id == id {
+			return j
+		}
+	}
+	return nil
+}
+
+func (q *JobQueue) submit(kind string, priority JobPriority, fn func(context.Context) error) *job {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.enqueue(kind, priority, fn)
+}
+
+// enqueue adds a job and starts the worker if it is idle; the lock must be
+// held
+func (q *JobQueue) enqueue(kind string, priority JobPriority, fn func(context.Context) error) *job {
+	q.nextID++
+	j := &job{
+		id:       q.nextID,
+		kind:     kind,
+		priority: priority,
+		run:      fn,
+		enqueued: time.Now().UTC(),
+		done:     make(chan struct{}),
+	}
+	heap.Push(&q.pending, j)
+	q.logger.Debug("Job queued", "job", j.id, "kind", kind, "priority", priority, "depth", q.pending.Len())
+
+	// A worker runs while there is work and exits once the queue drains
+	if q.running == nil && q.pending.Len() == 1 {
+		go q.work()
+	}
+	return j
+}
+
+func (q *JobQueue) work() {
+	for {
+		q.mu.Lock()
+		if q.pending.Len() == 0 {
+			q.running = nil
+			q.mu.Unlock()
+			return
+		}
+		j := heap.Pop(&q.pending).(*job)
+		j.started = time.Now().UTC()
+		q.running = j
+		q.mu.Unlock()
+
+		err := j.run(context.Background())
+
+		q.mu.Lock()
+		if err != nil {
+			q.failed++
+		} else {
+			q.completed++
+		}
+		j.err = err
+		j.finishedAt = time.Now().UTC()
+		q.finished = append(q.finished, j)
+		if len(q.finished) > finishedJobsKept {
+			q.finished = q.finished[1:]
+		}
+		q.mu.Unlock()
+
+		close(j.done)
+		q.logger.Debug("Job finished", "job", j.id, "kind", j.kind, "duration", time.Since(j.started), "error", err)
+	}
+}
+
+// Stats returns the queue depth, the running job and the pending jobs in the
+// order they will run
+func (q *JobQueue) Stats() models.JobQueueStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	ordered := make(jobHeap, len(q.pending))
+	copy(ordered, q.pending)
+	stats := models.JobQueueStats{
+		Depth:     ordered.Len(),
+		Completed: q.completed,
+		Failed:    q.failed,
+		Pending:   make([]models.JobInfo, 0, ordered.Len()),
+	}
+	for ordered.Len() > 0 {
+		stats.Pending = append(stats.Pending, heap.Pop(&ordered).(*job).info())
+	}
+	if q.running != nil {
+		running := q.running.info()
+		stats.Running = &running
+	}
+	return stats
+}
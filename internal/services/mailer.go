@@ -0,0 +1,241 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"analytics-dashboard-api/internal/config"
+)
+
+// Email is a plain text message with an optional file attached
+type Email struct {
+	To             []string
+	Subject        string
+	Body           string
+	Attachment     string // path of the file to attach, empty for none
+	AttachmentName string
+}
+
+// SMTPMailer sends email through the configured SMTP server, upgrading to
+// TLS with STARTTLS whenever the server offers it. Credentials are only sent
+// over TLS, or in the clear to localhost.
+type SMTPMailer struct {
+	cfg config.SMTPConfig
+}
+
+func NewSMTPMailer(cfg config.SMTPConfig) *SMTPMailer {
+	return &SMTPMailer{cfg: cfg}
+}
+
+// Configured reports whether a mail server is set
+func (m *SMTPMailer) Configured() bool {
+	return m.cfg.Host != ""
+}
+
+// Send delivers email to every recipient in one SMTP transaction
+func (m *SMTPMailer) Send(ctx context.Context, email Email) error {
+	if !m.Configured() {
+		return errors.New("no SMTP server configured")
+	}
+	ctx, cancel := context.WithTimeout(ctx, m.cfg.Timeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(m.cfg.Host, strconv.Itoa(m.cfg.Port)))
+	if err != nil {
+		return fmt.Errorf("failed to reach SMTP server: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, m.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("SMTP handshake failed: %w", err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: m.cfg.Host}); err != nil {
+			return fmt.Errorf("SMTP STARTTLS failed: %w", err)
+		}
+	}
+	if m.cfg.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", m.cfg.Username, m.cfg.Password, m.cfg.Host)); err != nil {
+			return fmt.Errorf("SMTP authentication failed: %w", err)
+		}
+	}
+
+	if err := client.Mail(m.cfg.From); err != nil {
+		return fmt.Errorf("SMTP server refused sender: %w", err)
+	}
+	for _, to := range email.To {
+		if err := client.Rcpt(to); err != nil {
+			return fmt.Errorf("SMTP server refused recipient %s: %w", to, err)
+		}
+	}
+
+	data, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("SMTP server refused message: %w", err)
+	}
+	if err := writeEmail(data, m.cfg.From, email); err != nil {
+		data.Close()
+		return err
+	}
+	if err := data.Close(); err != nil {
+		return fmt.Errorf("SMTP server refused message: %w", err)
+	}
+	return client.Quit()
+}
+
+// writeEmail writes email as a MIME message, streaming the attachment from
+// disk so large files are never held in memory
+func writeEmail(w io.Writer, from string, email Email) error {
+	parts := multipart.NewWriter(w)
+
+	var header bytes.Buffer
+	fmt.Fprintf(&header, "From: %s\r\n", from)
+	fmt.Fprintf(&header, "To: %s\r\n", strings.Join(email.To, ", "))
+	subject := strings.Map(func(r rune) rune {
+		if r == '\r' || r == '\n' {
+			return ' '
+		}
+		return r
+	}, email.Subject)
+	fmt.Fprintf(&header, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&header, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&header, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&header, "Content-Type: multipart/mixed; boundary=%q\r\n\r\n", parts.Boundary())
+	if _, err := w.Write(header.Bytes()); err != nil {
+		return fmt.Errorf("failed to write email: %w", err)
+	}
+
+	body, err := parts.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/plain; charset=utf-8"},
+		"Content-Transfer-Encoding": {"8bit"},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write email: %w", err)
+	}
+	if _, err := io.WriteString(body, strings.ReplaceAll(email.Body, "\n", "\r\n")); err != nil {
+		return fmt.Errorf("failed to write email: %w", err)
+	}
+
+	if email.Attachment != "" {
+		if err := writeAttachment(parts, email.Attachment, email.AttachmentName); err != nil {
+			return err
+		}
+	}
+
+	if err := parts.Close(); err != nil {
+		return fmt.Errorf("failed to write email: %w", err)
+	}
+	return nil
+}
+
+func writeAttachment(parts *multipart.Writer, path, name string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open attachment: %w", err)
+	}
+	defer file.Close()
+
+	if name == "" {
+		name = filepath.Base(path)
+	}
+	contentType := mime.TypeByExtension(filepath.Ext(name))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	part, err := parts.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {contentType},
+		"Content-Transfer-Encoding": {"base64"},
+		"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": name})},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write email: %w", err)
+	}
+
+	// Base64 lines may not exceed 76 characters
+	encoder := base64.NewEncoder(base64.StdEncoding, &lineWrapper{w: part, width: 76})
+	if _, err := io.Copy(encoder, file); err != nil {
+		return fmt.Errorf("failed to attach file: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return fmt.Errorf("failed to attach file: %w", err)
+	}
+	return nil
+}
+
+// lineWrapper breaks the stream written to w into CRLF-terminated lines
+type lineWrapper struct {
+	w      io.Writer
+	width  int
+	column int
+}
+
+func (l *lineWrapper) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		n := min(l.width-l.column, len(p))
+		if _, err := l.w.Write(p[:n]); err != nil {
+			return written, err
+		}
+		written += n
+		l.column += n
+		p = p[n:]
+		if l.column == l.width {
+			if _, err := l.w.Write([]byte("\r\n")); err != nil {
+				return written, err
+			}
+			l.column = 0
+		}
+	}
+	return written, nil
+}
+
+// validEmailAddresses checks that every address is a bare address, so no
+// header can be injected through a recipient
+func validEmailAddresses(addresses []string) error {
+	if len(addresses) == 0 {
+		return errors.New("at least one recipient is required")
+	}
+	for _, address := range addresses {
+		parsed, err := mail.ParseAddress(address)
+		if err != nil || parsed.Address != address {
+			return fmt.Errorf("invalid email address %q", address)
+		}
+	}
+	return nil
+}
+
+// retryableMailError reports whether a failed delivery may succeed if
+// repeated. SMTP replies in the 5xx range are permanent.
+func retryableMailError(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	var reply *textproto.Error
+	if errors.As(err, &reply) {
+		return reply.Code < 500
+	}
+	return true
+}
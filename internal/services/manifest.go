@@ -0,0 +1,136 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"analytics-dashboard-api/internal/models"
+)
+
+// manifestReadSize is the buffer used to hash and count manifest files
+const manifestReadSize = 1 << 20
+
+// isManifest reports whether a source path names a dataset manifest rather
+// than a CSV file
+func isManifest(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".json")
+}
+
+// readManifest decodes a manifest and resolves its file paths against the
+// manifest's directory
+func readManifest(path string) (*models.Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, sourceError(path, err)
+	}
+
+	var manifest models.Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("%w: %s: %w", models.ErrInvalidManifest, path, err)
+	}
+	if len(manifest.Files) == 0 {
+		return nil, fmt.Errorf("%w: %s lists no files", models.ErrInvalidManifest, path)
+	}
+
+	dir := filepath.Dir(path)
+	for i, file := range manifest.Files {
+		switch {
+		case file.Path == "":
+			return nil, fmt.Errorf("%w: file %d has no path", models.ErrInvalidManifest, i+1)
+		case len(file.SHA256) != sha256.Size*2:
+			return nil, fmt.Errorf("%w: %s needs a hex sha256 checksum", models.ErrInvalidManifest, file.Path)
+		case file.Rows < 0:
+			return nil, fmt.Errorf("%w: %s has a negative row count", models.ErrInvalidManifest, file.Path)
+		}
+		if !filepath.IsAbs(file.Path) {
+			manifest.Files[i].Path = filepath.Join(dir, file.Path)
+		}
+		manifest.Files[i].SHA256 = strings.ToLower(file.SHA256)
+	}
+	return &manifest, nil
+}
+
+// verifyManifest checks every file against the manifest. Existence and size
+// are checked for all files first, so a missing part fails before any
+// hashing; then each file is hashed and its rows counted, stopping at the
+// first mismatch. Rows are only counted in files read as CSV in format;
+// Parquet parts rely on their checksum.
+func verifyManifest(ctx context.Context, manifest *models.Manifest, format string) error {
+	for _, file := range manifest.Files {
+		info, err := os.Stat(file.Path)
+		if err != nil {
+			return sourceError(file.Path, err)
+		}
+		if file.Bytes > 0 && info.Size() != file.Bytes {
+			return fmt.Errorf("%w: %s is %d bytes, manifest says %d", models.ErrManifestMismatch, file.Path, info.Size(), file.Bytes)
+		}
+	}
+
+	for _, file := range manifest.Files {
+		checksum, rows, err := hashFile(ctx, file.Path)
+		if err != nil {
+			return err
+		}
+		if checksum != file.SHA256 {
+			return fmt.Errorf("%w: %s has sha256 %s, manifest says %s", models.ErrManifestMismatch, file.Path, checksum, file.SHA256)
+		}
+		switch fileFormat, _ := resolveFormat(format, file.Path); fileFormat {
+		case models.DataFormatParquet:
+			continue
+		case models.DataFormatCSV:
+			rows = max(rows-1, 0) // the header
+		}
+		if rows != file.Rows {
+			return fmt.Errorf("%w: %s has %d rows, manifest says %d", models.ErrManifestMismatch, file.Path, rows, file.Rows)
+		}
+	}
+	return nil
+}
+
+// hashFile returns the hex sha256 of a file and its number of lines. Lines
+// are counted by newline, so quoted fields must not span lines.
+func hashFile(ctx context.Context, path string) (string, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, sourceError(path, err)
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	buf := make([]byte, manifestReadSize)
+	var lines, size int64
+	var last byte
+	for {
+		if err := ctx.Err(); err != nil {
+			return "", 0, err
+		}
+		n, err := f.Read(buf)
+		if n > 0 {
+			hash.Write(buf[:n])
+			lines += int64(bytes.Count(buf[:n], []byte{'\n'}))
+			size += int64(n)
+			last = buf[n-1]
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", 0, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+	}
+
+	// A final line without a newline still counts
+	if size > 0 && last != '\n' {
+		lines++
+	}
+	return hex.EncodeToString(hash.Sum(nil)), lines, nil
+}
@@ -0,0 +1,469 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"analytics-dashboard-api/internal/models"
+)
+
+// memoryGroupings mirrors MetricGroupings as column selectors
+var memoryGroupings = map[string]func(*columnStore) *dimension{
+	"month":    func(c *columnStore) *dimension { return c.month },
+	"country":  func(c *columnStore) *dimension { return c.country },
+	"region":   func(c *columnStore) *dimension { return c.region },
+	"category": func(c *columnStore) *dimension { return c.category },
+	"product":  func(c *columnStore) *dimension { return c.productName },
+}
+
+// memoryDimensions mirrors dimensionColumns as column selectors
+var memoryDimensions = map[string]func(*columnStore) *dimension{
+	"country":  func(c *columnStore) *dimension { return c.country },
+	"category": func(c *columnStore) *dimension { return c.category },
+	"region":   func(c *columnStore) *dimension { return c.region },
+}
+
+// GetSegmentBreakdown returns revenue and retention per customer segment.
+// Customers missing from the customers dimension are grouped as "Unknown".
+func (s *MemoryService) GetSegmentBreakdown(ctx context.Context, opts models.QueryOptions) ([]models.SegmentRevenue, error) {
+	store := s.dataset().store
+	sel := store.filter(opts)
+	byUser := grouping{store.user}
+	perUser := store.aggregate(sel, byUser)
+	activeMonths := store.countDistinct(sel, byUser, store.month)
+
+	type segmentTotals struct {
+		revenue   models.Money
+		orders    int
+		customers int
+		retained  int
+	}
+	segments := map[string]*segmentTotals{}
+	for code, m := range perUser {
+		if m.rows == 0 {
+			continue
+		}
+		name := "Unknown"
+		if segment := store.userSegment[code]; segment != noSegment {
+			name = store.segment.values[segment]
+		}
+		t, ok := segments[name]
+		if !ok {
+			t = &segmentTotals{}
+			segments[name] = t
+		}
+		t.revenue += m.total
+		t.orders += m.rows
+		t.customers++
+		if activeMonths[code] > 1 {
+			t.retained++
+		}
+	}
+
+	scale := opts.ScaleFactor()
+	results := make([]models.SegmentRevenue, 0, len(segments))
+	for name, t := range segments {
+		results = append(results, models.SegmentRevenue{
+			Segment:          name,
+			TotalRevenue:     scaleMoney(t.revenue, scale),
+			TransactionCount: scaleCount(t.orders, scale),
+			CustomerCount:    t.customers,
+			AvgOrderValue:    models.Money(math.Round(float64(t.revenue) / float64(t.orders))),
+			RetentionRate:    float64(t.retained) / float64(t.customers),
+		})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].TotalRevenue != results[j].TotalRevenue {
+			return results[i].TotalRevenue > results[j].TotalRevenue
+		}
+		return results[i].Segment < results[j].Segment
+	})
+	return results, nil
+}
+
+// GetTopCustomers returns the limit customers who spent the most, with
+// their order count and average order value. Ties go to the lower user ID.
+func (s *MemoryService) GetTopCustomers(ctx context.Context, opts models.QueryOptions, limit int) ([]models.CustomerSpend, error) {
+	store := s.dataset().store
+	byUser := grouping{store.user}
+	perUser := store.aggregate(store.filter(opts), byUser)
+
+	var results []models.CustomerSpend
+	for code, m := range perUser {
+		if m.rows == 0 {
+			continue
+		}
+		results = append(results, models.CustomerSpend{
+			UserID:        byUser.label(uint32(code)),
+			TotalSpend:    m.total,
+			OrderCount:    m.rows,
+			AvgOrderValue: models.Money(math.Round(float64(m.total) / float64(m.rows))),
+		})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].TotalSpend != results[j].TotalSpend {
+			return results[i].TotalSpend > results[j].TotalSpend
+		}
+		return results[i].UserID < results[j].UserID
+	})
+	return results[:min(limit, len(results))], nil
+}
+
+// GetRepeatPurchase counts the customers with more than one order across
+// the whole dataset
+func (s *MemoryService) GetRepeatPurchase(ctx context.Context) (models.RepeatPurchase, error) {
+	store := s.dataset().store
+	var repeat models.RepeatPurchase
+	for _, m := range store.aggregate(store.filter(models.QueryOptions{}), grouping{store.user}) {
+		if m.rows == 0 {
+			continue
+		}
+		repeat.Customers++
+		if m.rows > 1 {
+			repeat.RepeatCustomers++
+		}
+	}
+	if repeat.Customers > 0 {
+		repeat.Rate = float64(repeat.RepeatCustomers) / float64(repeat.Customers)
+	}
+	return repeat, nil
+}
+
+// GetBaseMetrics computes every base metric, either for the whole (filtered)
+// dataset or per group when groupBy names one of MetricGroupings
+func (s *MemoryService) GetBaseMetrics(ctx context.Context, opts models.QueryOptions, groupBy string) ([]models.MetricRow, error) {
+	store := s.dataset().store
+	var g grouping
+	if groupBy != "" {
+		column, ok := memoryGroupings[groupBy]
+		if !ok {
+			return nil, fmt.Errorf("unsupported grouping %q", groupBy)
+		}
+		g = grouping{column(store)}
+	}
+
+	sel := store.filter(opts)
+	groups := store.aggregate(sel, g)
+	customers := store.countDistinct(sel, g, store.user)
+	products := store.countDistinct(sel, g, store.product)
+
+	scale := opts.ScaleFactor()
+	var results []models.MetricRow
+	for code, m := range groups {
+		// an ungrouped aggregate returns one row even over no data
+		if m.rows == 0 && g.dim != nil {
+			continue
+		}
+		results = append(results, models.MetricRow{
+			Group: g.label(uint32(code)),
+			Values: map[string]float64{
+				"revenue":      m.total.Float64() * scale,
+				"transactions": float64(m.rows) * scale,
+				"quantity":     float64(m.quantity) * scale,
+				"customers":    float64(customers[code]),
+				"products":     float64(products[code]),
+			},
+		})
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Group < results[j].Group })
+	return results, nil
+}
+
+// GetTimeSeries returns revenue, orders and units per day, week or month
+// with sales, in date order
+func (s *MemoryService) GetTimeSeries(ctx context.Context, opts models.QueryOptions, granularity string) ([]models.TimeBucket, error) {
+	if _, ok := periodStart(time.Time{}, granularity); !ok {
+		return nil, fmt.Errorf("%w: unsupported granularity %q", models.ErrInvalidTimeSeries, granularity)
+	}
+
+	store := s.dataset().store
+	byPeriod := make(map[int32]*measures)
+	store.filter(opts).each(store, func(i int) {
+		start, _ := periodStart(dayTime(store.days[i]), granularity)
+		day := int32(start.Unix() / secondsPerDay)
+		m := byPeriod[day]
+		if m == nil {
+			m = &measures{}
+			byPeriod[day] = m
+		}
+		m.add(store, i)
+	})
+
+	scale := opts.ScaleFactor()
+	results := make([]models.TimeBucket, 0, len(byPeriod))
+	for day, m := range byPeriod {
+		results = append(results, models.TimeBucket{
+			Start:   dayTime(day).Format("2006-01-02"),
+			Revenue: scaleMoney(m.total, scale),
+			Orders:  scaleCount(m.rows, scale),
+			Units:   scaleCount(m.quantity, scale),
+		})
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Start < results[j].Start })
+	return results, nil
+}
+
+// GetAlertMetrics returns the dataset metrics alert rules are evaluated
+// against, for the latest day and month present in the data
+func (s *MemoryService) GetAlertMetrics(ctx context.Context) (map[string]float64, error) {
+	store := s.dataset().store
+
+	var latestDay int32
+	for i, day := range store.days {
+		if i == 0 || day > latestDay {
+			latestDay = day
+		}
+	}
+	latestMonth, _ := store.month.dict.lookup(dayTime(latestDay).Format("2006-01"))
+
+	var total, daily, monthly models.Money
+	var dailyTransactions int
+	for i := 0; i < store.rows; i++ {
+		amount := models.Money(store.total[i])
+		total += amount
+		if store.month.codes[i] == latestMonth {
+			monthly += amount
+			if store.days[i] == latestDay {
+				daily += amount
+				dailyTransactions++
+			}
+		}
+	}
+
+	return map[string]float64{
+		"total_records":      float64(store.rows),
+		"total_revenue":      total.Float64(),
+		"daily_revenue":      daily.Float64(),
+		"daily_transactions": float64(dailyTransactions),
+		"monthly_revenue":    monthly.Float64(),
+	}, nil
+}
+
+// matchProducts returns the catalog entries whose id, name or brand contains
+// search case-insensitively, ordered by product_id
+func (d *memoryDataset) matchProducts(search string) []models.Product {
+	search = strings.ToLower(search)
+	var results []models.Product
+	for _, p := range d.products {
+		if search == "" ||
+			strings.Contains(strings.ToLower(p.ProductID), search) ||
+			strings.Contains(strings.ToLower(p.ProductName), search) ||
+			strings.Contains(strings.ToLower(p.Brand), search) {
+			results = append(results, p)
+		}
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].ProductID < results[j].ProductID })
+	return results
+}
+
+// ListProducts returns a page of the product catalog, optionally filtered by a
+// case-insensitive search over id, name and brand
+func (s *MemoryService) ListProducts(ctx context.Context, search string, limit, offset int) ([]models.Product, error) {
+	results := s.dataset().matchProducts(search)
+	start, end := page(len(results), limit, offset)
+	return results[start:end], nil
+}
+
+// CountProducts returns the number of catalog entries matching search
+func (s *MemoryService) CountProducts(ctx context.Context, search string) (int, error) {
+	return len(s.dataset().matchProducts(search)), nil
+}
+
+// GetProduct returns a catalog entry together with its sales summary
+func (s *MemoryService) GetProduct(ctx context.Context, productID string) (*models.Product, *models.ProductSales, error) {
+	data := s.dataset()
+	p, ok := data.products[productID]
+	if !ok {
+		return nil, nil, models.ErrProductNotFound
+	}
+
+	var sales models.ProductSales
+	store := data.store
+	if code, ok := store.product.dict.lookup(productID); ok {
+		for i := 0; i < store.rows; i++ {
+			if store.product.codes[i] == code {
+				sales.TotalRevenue += models.Money(store.total[i])
+				sales.UnitsSold += int(store.quantity[i])
+				sales.TransactionCount++
+			}
+		}
+	}
+	return &p, &sales, nil
+}
+
+// GetPriceHistory returns the monthly average selling price of a product in
+// month order. A product without any transactions is not found.
+func (s *MemoryService) GetPriceHistory(ctx context.Context, productID string, opts models.QueryOptions) ([]models.PricePoint, error) {
+	store := s.dataset().store
+	code, ok := store.product.dict.lookup(productID)
+	if !ok {
+		return nil, models.ErrProductNotFound
+	}
+
+	byMonth := make(map[uint32]*measures)
+	store.filter(opts).each(store, func(i int) {
+		if store.product.codes[i] != code {
+			return
+		}
+		m := byMonth[store.month.codes[i]]
+		if m == nil {
+			m = &measures{}
+			byMonth[store.month.codes[i]] = m
+		}
+		m.add(store, i)
+	})
+
+	scale := opts.ScaleFactor()
+	results := make([]models.PricePoint, 0, len(byMonth))
+	for month, m := range byMonth {
+		results = append(results, models.PricePoint{
+			Month:        store.month.dict.values[month],
+			Revenue:      scaleMoney(m.total, scale),
+			UnitsSold:    scaleCount(m.quantity, scale),
+			Transactions: scaleCount(m.rows, scale),
+		})
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Month < results[j].Month })
+	completePriceHistory(results)
+	return results, nil
+}
+
+// GetStockLevels returns every product with transactions under opts, with
+// the units sold since the given day and the stock_quantity of its latest
+// transaction
+func (s *MemoryService) GetStockLevels(ctx context.Context, opts models.QueryOptions, since time.Time) ([]models.StockLevel, error) {
+	store := s.dataset().store
+	sinceDay := int32(math.Floor(float64(since.Unix()) / secondsPerDay))
+
+	type productStock struct {
+		units     int
+		latestDay int32
+		latest    int // row of the latest transaction
+	}
+	byProduct := make(map[uint32]*productStock)
+	store.filter(opts).each(store, func(i int) {
+		code := store.product.codes[i]
+		p := byProduct[code]
+		if p == nil {
+			p = &productStock{latestDay: math.MinInt32}
+			byProduct[code] = p
+		}
+		if store.days[i] >= sinceDay {
+			p.units += int(store.quantity[i])
+		}
+		if store.days[i] >= p.latestDay {
+			p.latestDay, p.latest = store.days[i], i
+		}
+	})
+
+	scale := opts.ScaleFactor()
+	results := make([]models.StockLevel, 0, len(byProduct))
+	for code, p := range byProduct {
+		results = append(results, models.StockLevel{
+			ProductID:   store.product.dict.values[code],
+			ProductName: store.productName.dict.values[store.productName.codes[p.latest]],
+			Category:    store.category.dict.values[store.category.codes[p.latest]],
+			UnitsSold:   scaleCount(p.units, scale),
+			Stock:       int(store.stock[p.latest]),
+		})
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].ProductID < results[j].ProductID })
+	return results, nil
+}
+
+// dimensionCounts returns the non-empty values of a dimension matching search
+// with their transaction counts, most frequent first
+func (d *memoryDataset) dimensionCounts(dimension, search string) ([]models.DimensionValue, error) {
+	column, ok := memoryDimensions[dimension]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", models.ErrUnknownDimension, dimension)
+	}
+	dim := column(d.store)
+
+	search = strings.ToLower(search)
+	counts := map[string]int{}
+	for code, m := range d.store.aggregate(selection{all: true}, grouping{dim}) {
+		value := dim.dict.values[code]
+		if value == "" || (search != "" && !strings.Contains(strings.ToLower(value), search)) {
+			continue
+		}
+		counts[value] = m.rows
+	}
+
+	results := make([]models.DimensionValue, 0, len(counts))
+	for value, count := range counts {
+		results = append(results, models.DimensionValue{Value: value, Count: count})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Count != results[j].Count {
+			return results[i].Count > results[j].Count
+		}
+		return results[i].Value < results[j].Value
+	})
+	return results, nil
+}
+
+// ListDimensionValues returns a page of distinct values for a dimension with
+// transaction counts, most frequent first
+func (s *MemoryService) ListDimensionValues(ctx context.Context, dimension, search string, limit, offset int) ([]models.DimensionValue, error) {
+	results, err := s.dataset().dimensionCounts(dimension, search)
+	if err != nil {
+		return nil, err
+	}
+	start, end := page(len(results), limit, offset)
+	return results[start:end], nil
+}
+
+// CountDimensionValues returns the number of distinct values matching search
+func (s *MemoryService) CountDimensionValues(ctx context.Context, dimension, search string) (int, error) {
+	results, err := s.dataset().dimensionCounts(dimension, search)
+	return len(results), err
+}
+
+// GetDataProfile relies on DuckDB's SUMMARIZE; the column store also drops
+// the columns the dashboard never aggregates
+func (s *MemoryService) GetDataProfile(context.Context, int) (*models.DataProfile, error) {
+	return nil, models.ErrNotSupported
+}
+
+// GetPartitions describes the month partitions of the column store
+func (s *MemoryService) GetPartitions(ctx context.Context) ([]models.Partition, error) {
+	return s.dataset().store.partitionStats(), nil
+}
+
+// ListOutliers returns a page of the transactions the latest load flagged,
+// largest total first
+func (s *MemoryService) ListOutliers(ctx context.Context, limit, offset int) ([]models.Outlier, error) {
+	outliers := s.dataset().outliers
+	start, end := page(len(outliers), limit, offset)
+	return append([]models.Outlier{}, outliers[start:end]...), nil
+}
+
+// Targets and backups need the DuckDB tables and snapshot format; the memory
+// backend is rebuilt from the source files on every load
+
+func (s *MemoryService) LoadTargets(context.Context, string) (*models.TableLoadResult, error) {
+	return nil, models.ErrNotSupported
+}
+
+func (s *MemoryService) GetTargetVariance(context.Context, string) ([]models.TargetVariance, error) {
+	return nil, models.ErrNotSupported
+}
+
+func (s *MemoryService) Backup(context.Context, string) (*models.BackupInfo, error) {
+	return nil, models.ErrNotSupported
+}
+
+func (s *MemoryService) Restore(context.Context, string, string) (*models.BackupInfo, error) {
+	return nil, models.ErrNotSupported
+}
+
+// The column store keeps only the columns queries aggregate over, so full
+// rows cannot be exported
+func (s *MemoryService) ExportTransactions(context.Context, models.QueryOptions, string, string, func(int64, int64)) (int64, error) {
+	return 0, models.ErrNotSupported
+}
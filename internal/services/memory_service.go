@@ -0,0 +1,629 @@
+package services
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"analytics-dashboard-api/internal/config"
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/pkg/logger"
+)
+
+// MemoryService aggregates the CSV data in plain Go over a columnar store
+// (see columnStore). It needs no cgo, so it backs builds without DuckDB and
+// small deployments where an embedded database is not worth its footprint.
+// Every load builds a new dataset and swaps it in, so queries never observe
+// a partial load.
+type MemoryService struct {
+	processor        *CSVProcessor
+	dimensionSources map[string]string
+	strictRefs       bool
+	outliers         config.OutlierConfig
+	testOrders       testOrderRules
+	logger           logger.Logger
+
+	excludedMu sync.RWMutex
+	excluded   map[string]struct{} // flagged transaction IDs left out of every load
+
+	mu       sync.RWMutex
+	data     *memoryDataset
+	previous *memoryDataset     // replaced by the latest load, kept for Rollback
+	ingest   models.IngestStats // phases of the latest load
+}
+
+// memoryDataset is one immutable load of the source files
+type memoryDataset struct {
+	store    *columnStore
+	products map[string]models.Product
+	outliers []models.Outlier
+	coverage models.DataCoverage
+	tables   []string
+}
+
+func NewMemoryService(dimensions config.DimensionsConfig, outliers config.OutlierConfig, testOrders config.TestOrderConfig, logger logger.Logger) *MemoryService {
+	return &MemoryService{
+		processor: NewCSVProcessor(logger),
+		dimensionSources: map[string]string{
+			productsTable.Name:  dimensions.ProductsFilePath,
+			customersTable.Name: dimensions.CustomersFilePath,
+		},
+		strictRefs: dimensions.StrictReferences,
+		outliers:   outliers,
+		testOrders: newTestOrderRules(testOrders),
+		logger:     logger,
+		data:       &memoryDataset{store: newColumnStore(nil, nil), products: map[string]models.Product{}},
+	}
+}
+
+func (s *MemoryService) Close() error {
+	return nil
+}
+
+// Ping always succeeds: the data lives in this process
+func (s *MemoryService) Ping(context.Context) error {
+	return nil
+}
+
+// ExcludeTransactions sets the transaction IDs that every later load leaves
+// out
+func (s *MemoryService) ExcludeTransactions(ids []string) error {
+	excluded := make(map[string]struct{}, len(ids))
+	for _, id := range ids {
+		excluded[id] = struct{}{}
+	}
+
+	s.excludedMu.Lock()
+	defer s.excludedMu.Unlock()
+	s.excluded = excluded
+	return nil
+}
+
+// excludeTransactions drops the excluded transactions and returns the rest
+// with the number of rows dropped
+func (s *MemoryService) excludeTransactions(transactions []models.Transaction) ([]models.Transaction, int) {
+	s.excludedMu.RLock()
+	excluded := s.excluded
+	s.excludedMu.RUnlock()
+	if len(excluded) == 0 {
+		return transactions, 0
+	}
+
+	kept := transactions[:0]
+	for _, t := range transactions {
+		if _, ok := excluded[t.TransactionID]; !ok {
+			kept = append(kept, t)
+		}
+	}
+	return kept, len(transactions) - len(kept)
+}
+
+// excludeTestOrders drops the transactions matching the test-order rules and
+// returns the rest with the number of rows dropped
+func (s *MemoryService) excludeTestOrders(transactions []models.Transaction) ([]models.Transaction, int) {
+	if s.testOrders.empty() {
+		return transactions, 0
+	}
+
+	kept := transactions[:0]
+	for i := range transactions {
+		if !s.testOrders.match(&transactions[i]) {
+			kept = append(kept, transactions[i])
+		}
+	}
+	return kept, len(transactions) - len(kept)
+}
+
+// LoadFromFile reads the transactions files and any configured dimension
+// files and replaces the current dataset once all of them parsed. Only CSV
+// is read; Parquet needs the DuckDB backend. ctx is checked between files; a
+// load past its deadline leaves the current dataset in place.
+func (s *MemoryService) LoadFromFile(ctx context.Context, format string, paths ...string) error {
+	startTime := time.Now()
+	s.logger.Info("Loading CSV data into memory", "files", paths)
+
+	if err := csvOnly(format, paths...); err != nil {
+		return fmt.Errorf("failed to load CSV: %w", err)
+	}
+	var transactions []models.Transaction
+	var ingest models.IngestStats
+	defer func() { s.setIngest(ingest) }()
+	for _, path := range paths {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("failed to load CSV: %w", err)
+		}
+		part, stats, err := s.processor.ReadTransactions(path)
+		ingest.Rows += stats.Rows
+		ingest.Bytes += stats.Bytes
+		ingest.ParseErrors += stats.ParseErrors
+		ingest.Read += stats.Read
+		ingest.Parse += stats.Parse
+		if err != nil {
+			return fmt.Errorf("failed to load CSV: %w", err)
+		}
+		if transactions == nil {
+			transactions = part
+			continue
+		}
+		transactions = append(transactions, part...)
+		s.processor.Release(part)
+	}
+	// The column store copies the rows, so the batch can go back for the
+	// next load once this one is done
+	defer s.processor.Release(transactions)
+	insertStart := time.Now()
+	s.logger.Info("Table loaded", "table", transactionsTable.Name, "files", paths, "records", len(transactions))
+
+	data := &memoryDataset{products: map[string]models.Product{}, tables: []string{transactionsTable.Name}}
+
+	// Flagged transactions and test orders go first, so they never skew
+	// outlier limits
+	transactions, data.coverage.Excluded = s.excludeTransactions(transactions)
+	transactions, data.coverage.TestOrders = s.excludeTestOrders(transactions)
+	logTestOrders(s.logger, data.coverage.TestOrders)
+	transactions, data.outliers, data.coverage.Outliers = detectOutliers(s.outliers, transactions)
+	logOutliers(s.logger, data.coverage.Outliers)
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("failed to load CSV: %w", err)
+	}
+	products, err := s.readDimension(productsTable)
+	if err != nil {
+		return fmt.Errorf("failed to load CSV: %w", err)
+	}
+	if products != nil {
+		data.tables = append(data.tables, productsTable.Name)
+	}
+	for _, row := range products {
+		data.products[row[0]] = models.Product{
+			ProductID:   row[0],
+			ProductName: row[1],
+			Category:    row[2],
+			Brand:       row[3],
+			Supplier:    row[4],
+		}
+	}
+
+	customers, err := s.readDimension(customersTable)
+	if err != nil {
+		return fmt.Errorf("failed to load CSV: %w", err)
+	}
+	if customers != nil {
+		data.tables = append(data.tables, customersTable.Name)
+	}
+	segments := make(map[string]string, len(customers))
+	for _, row := range customers {
+		segments[row[0]] = row[2]
+	}
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("failed to load CSV: %w", err)
+	}
+	data.store = newColumnStore(transactions, segments)
+	if err := s.checkReferences(data, products != nil, segments, customers != nil); err != nil {
+		return fmt.Errorf("failed to load CSV: %w", err)
+	}
+
+	data.coverage.Records = data.store.rows
+	data.coverage.LoadedAt = time.Now().UTC()
+	if n := data.store.rows; n > 0 {
+		// The store is in date order
+		data.coverage.From = dayTime(data.store.days[0]).Format("2006-01-02")
+		data.coverage.To = dayTime(data.store.days[n-1]).Format("2006-01-02")
+	}
+
+	s.mu.Lock()
+	if !s.data.coverage.LoadedAt.IsZero() {
+		s.previous = s.data
+	}
+	s.data = data
+	s.mu.Unlock()
+	ingest.Insert = time.Since(insertStart)
+
+	s.logger.Info("CSV data loaded successfully",
+		"records", data.store.rows,
+		"duration", time.Since(startTime))
+
+	return nil
+}
+
+// InspectSource parses a transactions file the way LoadFromFile would,
+// without replacing the loaded dataset. Missing columns are reported but do
+// not invalidate the file, since they read as empty values.
+func (s *MemoryService) InspectSource(ctx context.Context, format, path string) (*models.SourceInspection, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, sourceError(path, err)
+	}
+	if err := csvOnly(format, path); err != nil {
+		return nil, err
+	}
+	inspection := &models.SourceInspection{Source: path, SizeBytes: info.Size()}
+
+	header, err := s.processor.ReadHeader(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range header {
+		inspection.Columns = append(inspection.Columns, models.SourceColumn{Name: name})
+	}
+	for i, pos := range transactionsTable.sourceIndex(header) {
+		col := transactionsTable.Columns[i]
+		if pos < 0 {
+			inspection.MissingColumns = append(inspection.MissingColumns, col.Name)
+			continue
+		}
+		inspection.Columns[pos].Type = col.Type
+	}
+
+	transactions, _, err := s.processor.ReadTransactions(path)
+	if err != nil {
+		inspection.Problems = append(inspection.Problems, err.Error())
+	}
+	inspection.Records = len(transactions)
+	s.processor.Release(transactions)
+	inspection.Valid = len(inspection.Problems) == 0
+	return inspection, nil
+}
+
+// readDimension reads an optional dimension file. A missing file yields nil.
+func (s *MemoryService) readDimension(spec TableSpec) ([][]string, error) {
+	path := s.dimensionSources[spec.Name]
+	if path == "" {
+		return nil, nil
+	}
+	if _, err := os.Stat(path); err != nil {
+		s.logger.Debug("Dimension file not found, skipping", "table", spec.Name, "file", path)
+		return nil, nil
+	}
+
+	if err := csvOnly(models.DataFormatAuto, path); err != nil {
+		return nil, fmt.Errorf("%s: %w", spec.Name, err)
+	}
+	rows, err := s.processor.ReadTable(path, spec)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Info("Table loaded", "table", spec.Name, "file", path, "records", len(rows))
+	return rows, nil
+}
+
+// csvOnly refuses files that would be read as Parquet or JSON Lines, which
+// the memory backend cannot parse
+func csvOnly(format string, paths ...string) error {
+	format, err := resolveFormat(format, paths...)
+	if err != nil {
+		return err
+	}
+	if format != models.DataFormatCSV {
+		return fmt.Errorf("reading %s files: %w", format, models.ErrNotSupported)
+	}
+	return nil
+}
+
+// checkReferences counts transactions whose product or customer is missing
+// from a loaded dimension, warning or failing like the DuckDB loader. Keys are
+// checked once per dictionary code rather than once per row.
+func (s *MemoryService) checkReferences(data *memoryDataset, hasProducts bool, segments map[string]string, hasCustomers bool) error {
+	store := data.store
+	var productOrphans, customerOrphans int
+	if hasProducts {
+		perProduct := store.aggregate(selection{all: true}, grouping{store.product})
+		for code, productID := range store.product.dict.values {
+			if _, ok := data.products[productID]; !ok {
+				productOrphans += perProduct[code].rows
+			}
+		}
+	}
+	if hasCustomers {
+		perUser := store.aggregate(selection{all: true}, grouping{store.user})
+		for code, userID := range store.user.dict.values {
+			if _, ok := segments[userID]; !ok {
+				customerOrphans += perUser[code].rows
+			}
+		}
+	}
+
+	checks := []models.ReferenceCheck{
+		{Table: "transactions", Column: "product_id", RefTable: "products", RefColumn: "product_id", Orphans: productOrphans},
+		{Table: "transactions", Column: "user_id", RefTable: "customers", RefColumn: "user_id", Orphans: customerOrphans},
+	}
+	for _, check := range checks {
+		if check.Orphans == 0 {
+			continue
+		}
+		if s.strictRefs {
+			return fmt.Errorf("%d rows in %s.%s reference missing %s.%s",
+				check.Orphans, check.Table, check.Column, check.RefTable, check.RefColumn)
+		}
+		s.logger.Warn("Referential check found orphaned rows",
+			"table", check.Table,
+			"column", check.Column,
+			"ref_table", check.RefTable,
+			"orphans", check.Orphans)
+	}
+	return nil
+}
+
+// Rollback swaps the dataset replaced by the latest load back in. The
+// swapped out dataset becomes the previous version.
+func (s *MemoryService) Rollback(ctx context.Context) (*models.DataSnapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.previous == nil {
+		return nil, models.ErrNoPreviousVersion
+	}
+	s.data, s.previous = s.previous, s.data
+
+	s.logger.Info("Rolled back to previous data version", "records", s.data.coverage.Records)
+	return &models.DataSnapshot{Tables: s.data.tables, Coverage: s.data.coverage}, nil
+}
+
+// DataCoverage returns the coverage recorded by the most recent load
+func (s *MemoryService) DataCoverage() models.DataCoverage {
+	return s.dataset().coverage
+}
+
+// LastIngest returns the phase timings of the most recent load
+func (s *MemoryService) LastIngest() models.IngestStats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.ingest
+}
+
+func (s *MemoryService) setIngest(stats models.IngestStats) {
+	s.mu.Lock()
+	s.ingest = stats
+	s.mu.Unlock()
+}
+
+func (s *MemoryService) dataset() *memoryDataset {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.data
+}
+
+// scaleCount extrapolates a sampled count to the full dataset
+func scaleCount(n int, scale float64) int {
+	return int(math.Round(float64(n) * scale))
+}
+
+// scaleMoney extrapolates a sampled amount to the full dataset
+func scaleMoney(m models.Money, scale float64) models.Money {
+	return models.Money(math.Round(float64(m) * scale))
+}
+
+// page returns the limit/offset window of n items as slice bounds
+func page(n, limit, offset int) (int, int) {
+	start := min(max(offset, 0), n)
+	return start, min(start+max(limit, 0), n)
+}
+
+// GetCountryRevenue returns a page of revenue per country and product,
+// largest first, with the totals over every pair
+func (s *MemoryService) GetCountryRevenue(ctx context.Context, opts models.QueryOptions, limit, offset int) ([]models.CountryRevenue, *models.Totals, error) {
+	store := s.dataset().store
+	groups := store.aggregatePairs(store.filter(opts), store.country, store.productName)
+
+	scale := opts.ScaleFactor()
+	var all measures
+	results := make([]models.CountryRevenue, 0, len(groups))
+	for _, g := range groups {
+		all.rows += g.rows
+		all.quantity += g.quantity
+		all.total += g.total
+		results = append(results, models.CountryRevenue{
+			Country:          store.country.dict.values[g.key.a],
+			ProductName:      store.productName.dict.values[g.key.b],
+			TotalRevenue:     scaleMoney(g.total, scale),
+			TransactionCount: scaleCount(g.rows, scale),
+		})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if a.TotalRevenue != b.TotalRevenue {
+			return a.TotalRevenue > b.TotalRevenue
+		}
+		if a.Country != b.Country {
+			return a.Country < b.Country
+		}
+		return a.ProductName < b.ProductName
+	})
+
+	totals := &models.Totals{
+		Revenue:      scaleMoney(all.total, scale),
+		Transactions: scaleCount(all.rows, scale),
+		Quantity:     scaleCount(all.quantity, scale),
+	}
+	start, end := page(len(results), limit, offset)
+	return results[start:end], totals, nil
+}
+
+// GetTopProducts returns a page of products ranked by sortBy, largest first
+func (s *MemoryService) GetTopProducts(ctx context.Context, opts models.QueryOptions, sortBy string, limit, offset int) ([]models.ProductFrequency, error) {
+	if _, err := rankColumn(productRanks, sortBy); err != nil {
+		return nil, err
+	}
+	data := s.dataset()
+	store := data.store
+	groups := store.aggregatePairs(store.filter(opts), store.product, store.productName)
+
+	scale := opts.ScaleFactor()
+	results := make([]models.ProductFrequency, 0, len(groups))
+	for _, g := range groups {
+		results = append(results, models.ProductFrequency{
+			ProductID:     store.product.dict.values[g.key.a],
+			ProductName:   store.productName.dict.values[g.key.b],
+			PurchaseCount: scaleCount(g.quantity, scale),
+			TotalRevenue:  scaleMoney(g.total, scale),
+			StockQuantity: g.stock,
+		})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		var c int
+		switch sortBy {
+		case models.RankByPurchaseCount:
+			c = cmp.Compare(a.PurchaseCount, b.PurchaseCount)
+		case models.RankByRevenue:
+			c = cmp.Compare(a.TotalRevenue, b.TotalRevenue)
+		case models.RankByStock:
+			c = cmp.Compare(a.StockQuantity, b.StockQuantity)
+		}
+		if c != 0 {
+			return c > 0
+		}
+		if a.ProductID != b.ProductID {
+			return a.ProductID < b.ProductID
+		}
+		return a.ProductName < b.ProductName
+	})
+
+	start, end := page(len(results), limit, offset)
+	results = results[start:end]
+	for i := range results {
+		if p, ok := data.products[results[i].ProductID]; ok {
+			results[i].Brand = p.Brand
+			results[i].Supplier = p.Supplier
+		}
+	}
+	return results, nil
+}
+
+// GetTopProductsCount returns the number of products GetTopProducts ranks
+func (s *MemoryService) GetTopProductsCount(ctx context.Context, opts models.QueryOptions) (int, error) {
+	store := s.dataset().store
+	return len(store.aggregatePairs(store.filter(opts), store.product, store.productName)), nil
+}
+
+func (s *MemoryService) GetMonthlySales(ctx context.Context, opts models.QueryOptions) ([]models.MonthlySales, error) {
+	store := s.dataset().store
+	sel := store.filter(opts)
+	byMonth := grouping{store.month}
+	groups := store.aggregate(sel, byMonth)
+	customers := store.countDistinct(sel, byMonth, store.user)
+	products := store.countDistinct(sel, byMonth, store.product)
+
+	scale := opts.ScaleFactor()
+	var results []models.MonthlySales
+	for code, m := range groups {
+		if m.rows == 0 {
+			continue
+		}
+		results = append(results, models.MonthlySales{
+			Month:           byMonth.label(uint32(code)),
+			SalesVolume:     scaleMoney(m.total, scale),
+			ItemCount:       scaleCount(m.quantity, scale),
+			UniqueCustomers: customers[code],
+			UniqueProducts:  products[code],
+		})
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Month < results[j].Month })
+	return results, nil
+}
+
+// GetTopRegions returns a page of regions ranked by sortBy, largest first
+func (s *MemoryService) GetTopRegions(ctx context.Context, opts models.QueryOptions, sortBy string, limit, offset int) ([]models.RegionRevenue, error) {
+	if _, err := rankColumn(regionRanks, sortBy); err != nil {
+		return nil, err
+	}
+	store := s.dataset().store
+	byRegion := grouping{store.region}
+	groups := store.aggregate(store.filter(opts), byRegion)
+
+	scale := opts.ScaleFactor()
+	var results []models.RegionRevenue
+	for code, m := range groups {
+		if m.rows == 0 {
+			continue
+		}
+		results = append(results, models.RegionRevenue{
+			Region:       byRegion.label(uint32(code)),
+			TotalRevenue: scaleMoney(m.total, scale),
+			ItemsSold:    scaleCount(m.quantity, scale),
+		})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		c := cmp.Compare(a.TotalRevenue, b.TotalRevenue)
+		if sortBy == models.RankByPurchaseCount {
+			c = cmp.Compare(a.ItemsSold, b.ItemsSold)
+		}
+		if c != 0 {
+			return c > 0
+		}
+		return a.Region < b.Region
+	})
+
+	start, end := page(len(results), limit, offset)
+	return results[start:end], nil
+}
+
+// GetTopRegionsCount returns the number of regions GetTopRegions ranks
+func (s *MemoryService) GetTopRegionsCount(ctx context.Context, opts models.QueryOptions) (int, error) {
+	store := s.dataset().store
+	count := 0
+	for _, m := range store.aggregate(store.filter(opts), grouping{store.region}) {
+		if m.rows > 0 {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// GetRegionSales returns the revenue of every country and region pair,
+// largest first
+func (s *MemoryService) GetRegionSales(ctx context.Context, opts models.QueryOptions) ([]models.RegionSales, error) {
+	store := s.dataset().store
+	groups := store.aggregatePairs(store.filter(opts), store.country, store.region)
+
+	scale := opts.ScaleFactor()
+	results := make([]models.RegionSales, 0, len(groups))
+	for _, g := range groups {
+		results = append(results, models.RegionSales{
+			Country:      store.country.dict.values[g.key.a],
+			Region:       store.region.dict.values[g.key.b],
+			Revenue:      scaleMoney(g.total, scale),
+			Transactions: scaleCount(g.rows, scale),
+			ItemsSold:    scaleCount(g.quantity, scale),
+		})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if a.Revenue != b.Revenue {
+			return a.Revenue > b.Revenue
+		}
+		if a.Country != b.Country {
+			return a.Country < b.Country
+		}
+		return a.Region < b.Region
+	})
+	return results, nil
+}
+
+func (s *MemoryService) GetTotalRecords(ctx context.Context) (int, error) {
+	return s.dataset().store.rows, nil
+}
+
+// GetCountryRevenueCount returns the number of country and product rows
+// GetCountryRevenue pages through
+func (s *MemoryService) GetCountryRevenueCount(ctx context.Context, opts models.QueryOptions) (int, error) {
+	store := s.dataset().store
+	return len(store.aggregatePairs(store.filter(opts), store.country, store.productName)), nil
+}
+
+// GetDistinctCounts returns exact unique customer and product counts, which
+// are the sizes of the column dictionaries
+func (s *MemoryService) GetDistinctCounts(ctx context.Context) (models.DistinctCounts, error) {
+	store := s.dataset().store
+	return models.DistinctCounts{
+		UniqueCustomers: store.user.dict.len(),
+		UniqueProducts:  store.product.dict.len(),
+	}, nil
+}
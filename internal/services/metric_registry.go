@@ -0,0 +1,228 @@
+package services
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"analytics-dashboard-api/internal/expr"
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/pkg/logger"
+)
+
+// BaseMetrics are computed in SQL for every aggregate query; derived metric
+// expressions may only reference these
+var BaseMetrics = []string{"revenue", "transactions", "quantity", "customers", "products"}
+
+var metricNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+
+// MetricRegistry holds derived metric definitions. Metrics from configuration
+// are read-only; metrics defined via the API are persisted to the state directory.
+type MetricRegistry struct {
+	mu         sync.RWMutex
+	path       string
+	configured []models.DerivedMetric
+	defined    []models.DerivedMetric
+	parsed     map[string]*expr.Expr
+	logger     logger.Logger
+}
+
+// NewMetricRegistry loads API-defined metrics and registers the configured
+// definitions, given as "name=expression" pairs separated by semicolons
+func NewMetricRegistry(stateDir, definitions string, logger logger.Logger) (*MetricRegistry, error) {
+	registry := &MetricRegistry{
+		path:   filepath.Join(stateDir, "metrics.json"),
+		parsed: make(map[string]*expr.Expr),
+		logger: logger,
+	}
+
+	for _, definition := range strings.Split(definitions, ";") {
+		if strings.TrimSpace(definition) == "" {
+			continue
+		}
+		name, expression, ok := strings.Cut(definition, "=")
+		if !ok {
+			return nil, fmt.Errorf("%w: %q is not name=expression", models.ErrInvalidMetric, definition)
+		}
+		metric := models.DerivedMetric{
+			Name:       strings.TrimSpace(name),
+			Expression: strings.TrimSpace(expression),
+			Source:     "config",
+		}
+		if err := registry.register(metric); err != nil {
+			return nil, err
+		}
+		registry.configured = append(registry.configured, metric)
+	}
+
+	var defined []models.DerivedMetric
+	if err := loadJSONFile(registry.path, &defined); err != nil {
+		return nil, err
+	}
+	for _, metric := range defined {
+		if err := registry.register(metric); err != nil {
+			// A configured metric may have taken the name since it was saved
+			logger.Warn("Skipping stored derived metric", "name", metric.Name, "error", err)
+			continue
+		}
+		registry.defined = append(registry.defined, metric)
+	}
+
+	return registry, nil
+}
+
+// register validates a definition and records its parsed expression
+func (m *MetricRegistry) register(metric models.DerivedMetric) error {
+	if !metricNamePattern.MatchString(metric.Name) {
+		return fmt.Errorf("%w: name must be lowercase letters, digits and underscores", models.ErrInvalidMetric)
+	}
+	if isBaseMetric(metric.Name) {
+		return fmt.Errorf("%w: %s is a base metric", models.ErrInvalidMetric, metric.Name)
+	}
+	if _, exists := m.parsed[metric.Name]; exists {
+		return fmt.Errorf("%w: %s is already defined", models.ErrInvalidMetric, metric.Name)
+	}
+
+	parsed, err := expr.Parse(metric.Expression)
+	if err != nil {
+		return fmt.Errorf("%w: %v", models.ErrInvalidMetric, err)
+	}
+	for _, name := range parsed.Variables() {
+		if !isBaseMetric(name) {
+			return fmt.Errorf("%w: unknown metric %q (expressions may use %s)",
+				models.ErrInvalidMetric, name, strings.Join(BaseMetrics, ", "))
+		}
+	}
+
+	m.parsed[metric.Name] = parsed
+	return nil
+}
+
+// List returns all derived metrics ordered by name
+func (m *MetricRegistry) List() []models.DerivedMetric {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	results := append(append([]models.DerivedMetric{}, m.configured...), m.defined...)
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Name < results[j].Name
+	})
+	return results
+}
+
+// Define validates and stores a new derived metric
+func (m *MetricRegistry) Define(metric models.DerivedMetric) (*models.DerivedMetric, error) {
+	metric.Name = strings.TrimSpace(metric.Name)
+	metric.Expression = strings.TrimSpace(metric.Expression)
+	metric.Source = "api"
+	createdAt := time.Now().UTC()
+	metric.CreatedAt = &createdAt
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.register(metric); err != nil {
+		return nil, err
+	}
+
+	m.defined = append(m.defined, metric)
+	if err := saveJSONFile(m.path, m.defined); err != nil {
+		m.defined = m.defined[:len(m.defined)-1]
+		delete(m.parsed, metric.Name)
+		return nil, err
+	}
+
+	return &metric, nil
+}
+
+// Delete removes an API-defined metric
+func (m *MetricRegistry) Delete(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, metric := range m.configured {
+		if metric.Name == name {
+			return models.ErrMetricReadOnly
+		}
+	}
+
+	for i, metric := range m.defined {
+		if metric.Name != name {
+			continue
+		}
+
+		remaining := append(append([]models.DerivedMetric{}, m.defined[:i]...), m.defined[i+1:]...)
+		if err := saveJSONFile(m.path, remaining); err != nil {
+			return err
+		}
+		m.defined = remaining
+		delete(m.parsed, name)
+		return nil
+	}
+
+	return models.ErrMetricNotFound
+}
+
+// Names returns every base and derived metric name
+func (m *MetricRegistry) Names() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	names := append([]string{}, BaseMetrics...)
+	derived := make([]string, 0, len(m.parsed))
+	for name := range m.parsed {
+		derived = append(derived, name)
+	}
+	sort.Strings(derived)
+	return append(names, derived...)
+}
+
+// Validate checks that every name is a base or derived metric
+func (m *MetricRegistry) Validate(names []string) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, name := range names {
+		if _, ok := m.parsed[name]; !ok && !isBaseMetric(name) {
+			return fmt.Errorf("%w: %s", models.ErrMetricNotFound, name)
+		}
+	}
+	return nil
+}
+
+// Evaluate computes the named metrics from base metric values. Unknown
+// metrics and expressions that fail (division by zero) evaluate to nil.
+func (m *MetricRegistry) Evaluate(names []string, values map[string]float64) map[string]*float64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	results := make(map[string]*float64, len(names))
+	for _, name := range names {
+		if value, ok := values[name]; ok && isBaseMetric(name) {
+			v := value
+			results[name] = &v
+			continue
+		}
+
+		parsed, ok := m.parsed[name]
+		if !ok {
+			results[name] = nil
+			continue
+		}
+		value, err := parsed.Eval(values)
+		if err != nil {
+			results[name] = nil
+			continue
+		}
+		results[name] = &value
+	}
+	return results
+}
+
+func isBaseMetric(name string) bool {
+	return containsString(BaseMetrics, name)
+}
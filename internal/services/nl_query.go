@@ -0,0 +1,462 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"analytics-dashboard-api/internal/config"
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/pkg/logger"
+)
+
+// NLQueryBackend answers the queries natural-language questions map to
+type NLQueryBackend interface {
+	GetBaseMetrics(context.Context, models.QueryOptions, string) ([]models.MetricRow, error)
+	ListDimensionValues(context.Context, string, string, int, int) ([]models.DimensionValue, error)
+	DataCoverage() models.DataCoverage
+}
+
+const (
+	maxNLQuestionLength = 200
+	maxNLCountries      = 1000 // country names matched in questions
+	defaultNLTopLimit   = 10
+	maxNLTopLimit       = 100
+)
+
+const nlExamples = `try "revenue by country last quarter", "top 5 products by quantity in Germany" or "monthly orders in 2023"`
+
+// nlMetricPhrases maps the words for base metrics to their names
+var nlMetricPhrases = []struct{ phrase, metric string }{
+	{"revenue", "revenue"}, {"sales", "revenue"}, {"turnover", "revenue"}, {"income", "revenue"},
+	{"transactions", "transactions"}, {"orders", "transactions"},
+	{"quantity", "quantity"}, {"units", "quantity"}, {"items sold", "quantity"},
+	{"customers", "customers"}, {"buyers", "customers"}, {"clients", "customers"},
+	{"number of products", "products"}, {"distinct products", "products"}, {"product count", "products"},
+}
+
+// nlGroupings maps the words for each grouping to its MetricGroupings key
+var nlGroupings = map[string]string{
+	"country": "country", "countries": "country",
+	"region": "region", "regions": "region",
+	"category": "category", "categories": "category",
+	"product": "product", "products": "product",
+	"month": "month", "months": "month",
+}
+
+var (
+	nlNonWord  = regexp.MustCompile(`[^a-z0-9]+`)
+	nlGroup    = regexp.MustCompile(`\b(?:by|per|for each|across|split by)\s+([a-z]+)\b`)
+	nlTop      = regexp.MustCompile(`\b(?:top|best|highest|biggest|largest)(?:\s+([0-9]+))?(?:\s+(?:selling|performing|grossing))?\s+([a-z]+)\b`)
+	nlTrend    = regexp.MustCompile(`\b(?:monthly|each month|month by month|over time|trends?)\b`)
+	nlRolling  = regexp.MustCompile(`\b(?:last|past|previous)\s+([0-9]+)\s+(days?|weeks?|months?|years?)\b`)
+	nlRelative = regexp.MustCompile(`\b(last|past|previous|this|current)\s+(month|quarter|year)\b`)
+	nlToDate   = regexp.MustCompile(`\b(?:ytd|year to date)\b`)
+	nlQuarter  = regexp.MustCompile(`\bq([1-4])\s+((?:19|20)[0-9]{2})\b`)
+	nlMonth    = regexp.MustCompile(`\b(` + nlMonthAlternation() + `)\s+((?:19|20)[0-9]{2})\b`)
+	nlYear     = regexp.MustCompile(`\b((?:19|20)[0-9]{2})\b`)
+)
+
+// nlMonths maps month names and their three-letter abbreviations to months
+var nlMonths = func() map[string]time.Month {
+	months := make(map[string]time.Month, 24)
+	for m := time.January; m <= time.December; m++ {
+		name := strings.ToLower(m.String())
+		months[name], months[name[:3]] = m, m
+	}
+	return months
+}()
+
+func nlMonthAlternation() string {
+	var names []string
+	for m := time.January; m <= time.December; m++ {
+		name := strings.ToLower(m.String())
+		names = append(names, name, name[:3])
+	}
+	return strings.Join(names, "|")
+}
+
+// NLQueryService answers constrained natural-language questions with a
+// rules-based matcher. A question is mapped to one of a few fixed aggregate
+// queries: it must name known metrics, groupings and countries, and may name
+// a period. Periods are relative to the latest transaction in the data.
+type NLQueryService struct {
+	enabled bool
+	backend NLQueryBackend
+	metrics *MetricRegistry
+	logger  logger.Logger
+}
+
+func NewNLQueryService(cfg config.NLQueryConfig, backend NLQueryBackend, metrics *MetricRegistry, logger logger.Logger) *NLQueryService {
+	return &NLQueryService{
+		enabled: cfg.Enabled,
+		backend: backend,
+		metrics: metrics,
+		logger:  logger,
+	}
+}
+
+// Answer interprets a question and runs the query it maps to
+func (s *NLQueryService) Answer(ctx context.Context, question string) (*models.NLAnswer, error) {
+	if !s.enabled {
+		return nil, models.ErrNLQueryDisabled
+	}
+
+	interpretation, opts, err := s.interpret(ctx, question)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.backend.GetBaseMetrics(ctx, opts, interpretation.GroupBy)
+	if err != nil {
+		return nil, err
+	}
+	data := make([]models.MetricResult, len(rows))
+	for i, row := range rows {
+		data[i] = models.MetricResult{
+			Group:   row.Group,
+			Metrics: s.metrics.Evaluate(interpretation.Metrics, row.Values),
+		}
+	}
+
+	if sortBy := interpretation.Sort; sortBy != "" {
+		sort.SliceStable(data, func(a, b int) bool {
+			return metricGreater(data[a].Metrics[sortBy], data[b].Metrics[sortBy])
+		})
+	}
+	if interpretation.Limit > 0 && len(data) > interpretation.Limit {
+		data = data[:interpretation.Limit]
+	}
+
+	s.logger.Info("Answered natural-language query", "question", question, "template", interpretation.Template,
+		"metrics", strings.Join(interpretation.Metrics, ","), "group_by", interpretation.GroupBy)
+	return &models.NLAnswer{Question: question, Interpretation: *interpretation, Data: data}, nil
+}
+
+// interpret maps a question to a query template. Each recognised phrase is
+// blanked out of the working text once matched, so later rules do not see
+// it again.
+func (s *NLQueryService) interpret(ctx context.Context, question string) (*models.NLInterpretation, models.QueryOptions, error) {
+	var opts models.QueryOptions
+	question = strings.TrimSpace(question)
+	if question == "" {
+		return nil, opts, fmt.Errorf("%w: question is required", models.ErrValidation)
+	}
+	if len(question) > maxNLQuestionLength {
+		return nil, opts, fmt.Errorf("%w: question is longer than %d characters", models.ErrValidation, maxNLQuestionLength)
+	}
+
+	text := &nlText{s: " " + strings.TrimSpace(nlNonWord.ReplaceAllString(strings.ToLower(question), " ")) + " "}
+	interpretation := &models.NLInterpretation{}
+
+	period, from, to, err := nlPeriod(text, s.anchor())
+	if err != nil {
+		return nil, opts, err
+	}
+	interpretation.Period = period
+	opts.From, opts.To = from, to
+
+	country, err := s.country(ctx, text)
+	if err != nil {
+		return nil, opts, err
+	}
+	interpretation.Country = country
+	opts.Country = country
+
+	// Derived metric names may contain grouping words ("revenue per
+	// customer"), so they are matched before groupings
+	var found []nlMatch
+	for _, name := range s.metrics.Names() {
+		if !isBaseMetric(name) {
+			found = append(found, text.matchAll(strings.ReplaceAll(name, "_", " "), name)...)
+		}
+	}
+
+	var topGroup string
+	if match := text.find(nlTop); match != nil {
+		if group, ok := nlGroupings[match.groups[2]]; ok {
+			topGroup = group
+			interpretation.Limit = defaultNLTopLimit
+			if match.groups[1] != "" {
+				limit, err := strconv.Atoi(match.groups[1])
+				if err != nil || limit < 1 || limit > maxNLTopLimit {
+					return nil, opts, fmt.Errorf("%w: top takes 1 to %d", models.ErrNLQueryUnmatched, maxNLTopLimit)
+				}
+				interpretation.Limit = limit
+			}
+			text.blank(match.start, match.end)
+		}
+	}
+
+	var groupBy string
+	for _, match := range text.findAll(nlGroup) {
+		group, ok := nlGroupings[match.groups[1]]
+		if !ok {
+			if nlIsMetricWord(match.groups[1]) {
+				continue // "top 5 products by revenue"
+			}
+			return nil, opts, fmt.Errorf("%w: cannot group by %q (use country, region, category, product or month)",
+				models.ErrNLQueryUnmatched, match.groups[1])
+		}
+		if groupBy != "" && groupBy != group {
+			return nil, opts, fmt.Errorf("%w: questions may group by one dimension", models.ErrNLQueryUnmatched)
+		}
+		groupBy = group
+		text.blank(match.start, match.end)
+	}
+
+	trend := false
+	if match := text.find(nlTrend); match != nil {
+		trend = true
+		text.blank(match.start, match.end)
+	}
+
+	for _, phrase := range nlMetricPhrases {
+		found = append(found, text.matchAll(phrase.phrase, phrase.metric)...)
+	}
+	sort.SliceStable(found, func(i, j int) bool {
+		return found[i].start < found[j].start
+	})
+	for _, match := range found {
+		if !containsString(interpretation.Metrics, match.metric) {
+			interpretation.Metrics = append(interpretation.Metrics, match.metric)
+		}
+	}
+
+	if len(interpretation.Metrics) == 0 {
+		if topGroup == "" && groupBy == "" && !trend {
+			return nil, opts, fmt.Errorf("%w: no metric found; %s", models.ErrNLQueryUnmatched, nlExamples)
+		}
+		interpretation.Metrics = []string{"revenue"}
+	}
+
+	switch {
+	case topGroup != "":
+		if (groupBy != "" && groupBy != topGroup) || trend {
+			return nil, opts, fmt.Errorf("%w: questions may group by one dimension", models.ErrNLQueryUnmatched)
+		}
+		interpretation.Template = models.NLTemplateTop
+		interpretation.GroupBy = topGroup
+		interpretation.Sort = interpretation.Metrics[0]
+	case trend || groupBy == "month":
+		if groupBy != "" && groupBy != "month" {
+			return nil, opts, fmt.Errorf("%w: a trend is always by month", models.ErrNLQueryUnmatched)
+		}
+		interpretation.Template = models.NLTemplateTrend
+		interpretation.GroupBy = "month"
+	case groupBy != "":
+		interpretation.Template = models.NLTemplateBreakdown
+		interpretation.GroupBy = groupBy
+		interpretation.Sort = interpretation.Metrics[0]
+	default:
+		interpretation.Template = models.NLTemplateTotal
+	}
+
+	return interpretation, opts, nil
+}
+
+// anchor is the date relative periods count back from: the latest
+// transaction in the data, or today before any data is loaded
+func (s *NLQueryService) anchor() time.Time {
+	if latest, err := time.Parse("2006-01-02", s.backend.DataCoverage().To); err == nil {
+		return latest
+	}
+	now := time.Now().UTC()
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// country finds a country of the loaded data named in the question,
+// preferring the longest name so "Papua New Guinea" is not read as "Guinea"
+func (s *NLQueryService) country(ctx context.Context, text *nlText) (string, error) {
+	values, err := s.backend.ListDimensionValues(ctx, "country", "", maxNLCountries, 0)
+	if err != nil {
+		return "", err
+	}
+	sort.SliceStable(values, func(i, j int) bool {
+		return len(values[i].Value) > len(values[j].Value)
+	})
+
+	country := ""
+	for _, value := range values {
+		phrase := strings.TrimSpace(nlNonWord.ReplaceAllString(strings.ToLower(value.Value), " "))
+		if phrase == "" {
+			continue
+		}
+		if len(text.matchAll(phrase, "")) == 0 {
+			continue
+		}
+		if country != "" {
+			return "", fmt.Errorf("%w: questions may name one country", models.ErrNLQueryUnmatched)
+		}
+		country = value.Value
+	}
+	return country, nil
+}
+
+// nlPeriod resolves the period named in the question, if any, to a date
+// range [from, to) relative to anchor
+func nlPeriod(text *nlText, anchor time.Time) (*models.NLPeriod, time.Time, time.Time, error) {
+	var from, to time.Time
+	day := anchor.AddDate(0, 0, 1)
+	startOfMonth := time.Date(anchor.Year(), anchor.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	// A quarter or month also contains a year, so the year is tried last
+	rolling, relative, toDate := text.find(nlRolling), text.find(nlRelative), text.find(nlToDate)
+	quarter, month, year := text.find(nlQuarter), text.find(nlMonth), text.find(nlYear)
+
+	var match *nlSubmatch
+	switch {
+	case rolling != nil:
+		match = rolling
+		n, err := strconv.Atoi(match.groups[1])
+		if err != nil || n < 1 || n > 3650 {
+			return nil, from, to, fmt.Errorf("%w: invalid period %q", models.ErrNLQueryUnmatched, match.groups[0])
+		}
+		to = day
+		switch strings.TrimSuffix(match.groups[2], "s") {
+		case "day":
+			from = to.AddDate(0, 0, -n)
+		case "week":
+			from = to.AddDate(0, 0, -7*n)
+		case "month":
+			from = to.AddDate(0, -n, 0)
+		case "year":
+			from = to.AddDate(-n, 0, 0)
+		}
+	case relative != nil:
+		match = relative
+		previous := match.groups[1] != "this" && match.groups[1] != "current"
+		switch match.groups[2] {
+		case "month":
+			from = startOfMonth
+			if previous {
+				from = from.AddDate(0, -1, 0)
+			}
+			to = from.AddDate(0, 1, 0)
+		case "quarter":
+			from = startOfMonth.AddDate(0, -(int(anchor.Month()-1) % 3), 0)
+			if previous {
+				from = from.AddDate(0, -3, 0)
+			}
+			to = from.AddDate(0, 3, 0)
+		case "year":
+			from = time.Date(anchor.Year(), time.January, 1, 0, 0, 0, 0, time.UTC)
+			if previous {
+				from = from.AddDate(-1, 0, 0)
+			}
+			to = from.AddDate(1, 0, 0)
+		}
+	case toDate != nil:
+		match = toDate
+		from = time.Date(anchor.Year(), time.January, 1, 0, 0, 0, 0, time.UTC)
+		to = day
+	case quarter != nil:
+		match = quarter
+		q, _ := strconv.Atoi(match.groups[1])
+		y, _ := strconv.Atoi(match.groups[2])
+		from = time.Date(y, time.Month(3*q-2), 1, 0, 0, 0, 0, time.UTC)
+		to = from.AddDate(0, 3, 0)
+	case month != nil:
+		match = month
+		y, _ := strconv.Atoi(match.groups[2])
+		from = time.Date(y, nlMonths[match.groups[1]], 1, 0, 0, 0, 0, time.UTC)
+		to = from.AddDate(0, 1, 0)
+	case year != nil:
+		match = year
+		y, _ := strconv.Atoi(match.groups[1])
+		from = time.Date(y, time.January, 1, 0, 0, 0, 0, time.UTC)
+		to = from.AddDate(1, 0, 0)
+	default:
+		return nil, from, to, nil
+	}
+
+	text.blank(match.start, match.end)
+	for _, pattern := range []*regexp.Regexp{nlRolling, nlRelative, nlToDate, nlYear} {
+		if text.find(pattern) != nil {
+			return nil, from, to, fmt.Errorf("%w: questions may name one period", models.ErrNLQueryUnmatched)
+		}
+	}
+	return &models.NLPeriod{
+		Label: strings.Join(strings.Fields(match.groups[0]), " "),
+		From:  from.Format("2006-01-02"),
+		To:    to.AddDate(0, 0, -1).Format("2006-01-02"),
+	}, from, to, nil
+}
+
+func nlIsMetricWord(word string) bool {
+	for _, phrase := range nlMetricPhrases {
+		if phrase.phrase == word {
+			return true
+		}
+	}
+	return false
+}
+
+// nlText is a normalised question: lower case words separated by spaces,
+// padded with a space at either end. Matched phrases are replaced by spaces
+// so the positions of the rest stay stable.
+type nlText struct {
+	s string
+}
+
+// nlSubmatch is a regexp match: the matched text and its groups, with the
+// span of the whole match
+type nlSubmatch struct {
+	groups     []string
+	start, end int
+}
+
+// nlMatch is a metric phrase found at start
+type nlMatch struct {
+	metric string
+	start  int
+}
+
+func (t *nlText) find(pattern *regexp.Regexp) *nlSubmatch {
+	loc := pattern.FindStringSubmatchIndex(t.s)
+	if loc == nil {
+		return nil
+	}
+	return t.submatch(loc)
+}
+
+func (t *nlText) findAll(pattern *regexp.Regexp) []*nlSubmatch {
+	var matches []*nlSubmatch
+	for _, loc := range pattern.FindAllStringSubmatchIndex(t.s, -1) {
+		matches = append(matches, t.submatch(loc))
+	}
+	return matches
+}
+
+func (t *nlText) submatch(loc []int) *nlSubmatch {
+	match := &nlSubmatch{start: loc[0], end: loc[1]}
+	for i := 0; i < len(loc); i += 2 {
+		group := ""
+		if loc[i] >= 0 {
+			group = t.s[loc[i]:loc[i+1]]
+		}
+		match.groups = append(match.groups, group)
+	}
+	return match
+}
+
+// matchAll finds every whole-word occurrence of phrase and blanks it out
+func (t *nlText) matchAll(phrase, metric string) []nlMatch {
+	var matches []nlMatch
+	for {
+		i := strings.Index(t.s, " "+phrase+" ")
+		if i < 0 {
+			return matches
+		}
+		matches = append(matches, nlMatch{metric: metric, start: i + 1})
+		t.blank(i+1, i+1+len(phrase))
+	}
+}
+
+func (t *nlText) blank(start, end int) {
+	t.s = t.s[:start] + strings.Repeat(" ", end-start) + t.s[end:]
+}
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"cmp"
+	"math"
+	"slices"
+
+	"analytics-dashboard-api/internal/config"
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/pkg/logger"
+)
+
+// outlierLimits are the price and quantity above which a transaction is an
+// outlier, +Inf for no limit
+type outlierLimits struct {
+	price    float64
+	quantity float64
+}
+
+// newOutlierLimits combines the percentile and absolute limits of cfg, given
+// the configured percentile of each column
+func newOutlierLimits(cfg config.OutlierConfig, pricePercentile, quantityPercentile float64) outlierLimits {
+	limits := outlierLimits{price: math.Inf(1), quantity: math.Inf(1)}
+	if cfg.Percentile > 0 {
+		limits.price = cfg.Factor * pricePercentile
+		limits.quantity = cfg.Factor * quantityPercentile
+	}
+	if cfg.MaxPrice > 0 {
+		limits.price = min(limits.price, cfg.MaxPrice)
+	}
+	if cfg.MaxQuantity > 0 {
+		limits.quantity = min(limits.quantity, float64(cfg.MaxQuantity))
+	}
+	return limits
+}
+
+// report returns an empty report for these limits
+func (l outlierLimits) report(mode string) *models.OutlierReport {
+	report := &models.OutlierReport{Mode: mode}
+	if !math.IsInf(l.price, 1) {
+		report.PriceLimit = &l.price
+	}
+	if !math.IsInf(l.quantity, 1) {
+		report.QuantityLimit = &l.quantity
+	}
+	return report
+}
+
+// outlierReasons lists the columns above their limit
+func (l outlierLimits) outlierReasons(price float64, quantity int) []string {
+	var reasons []string
+	if price > l.price {
+		reasons = append(reasons, "price")
+	}
+	if float64(quantity) > l.quantity {
+		reasons = append(reasons, "quantity")
+	}
+	return reasons
+}
+
+// detectOutliers flags transactions whose price or quantity exceeds the
+// limits of cfg. It returns the transactions to load, without the outliers in
+// exclude mode, the flagged transactions and a report; the report is nil when
+// detection is off.
+func detectOutliers(cfg config.OutlierConfig, transactions []models.Transaction) ([]models.Transaction, []models.Outlier, *models.OutlierReport) {
+	if cfg.Mode != models.OutlierModeTag && cfg.Mode != models.OutlierModeExclude {
+		return transactions, nil, nil
+	}
+
+	var pricePercentile, quantityPercentile float64
+	if cfg.Percentile > 0 && len(transactions) > 0 {
+		prices := make([]float64, len(transactions))
+		quantities := make([]float64, len(transactions))
+		for i, t := range transactions {
+			prices[i] = t.Price
+			quantities[i] = float64(t.Quantity)
+		}
+		pricePercentile = quantile(prices, cfg.Percentile)
+		quantityPercentile = quantile(quantities, cfg.Percentile)
+	}
+	limits := newOutlierLimits(cfg, pricePercentile, quantityPercentile)
+	report := limits.report(cfg.Mode)
+
+	var outliers []models.Outlier
+	kept := transactions
+	if cfg.Mode == models.OutlierModeExclude {
+		kept = make([]models.Transaction, 0, len(transactions))
+	}
+	for _, t := range transactions {
+		reasons := limits.outlierReasons(t.Price, t.Quantity)
+		if len(reasons) == 0 {
+			if cfg.Mode == models.OutlierModeExclude {
+				kept = append(kept, t)
+			}
+			continue
+		}
+		outliers = append(outliers, models.Outlier{Transaction: t, Reasons: reasons})
+		report.Rows++
+		if slices.Contains(reasons, "price") {
+			report.PriceOutliers++
+		}
+		if slices.Contains(reasons, "quantity") {
+			report.QuantityOutliers++
+		}
+	}
+	slices.SortStableFunc(outliers, func(a, b models.Outlier) int {
+		if a.TotalPrice != b.TotalPrice {
+			return cmp.Compare(b.TotalPrice, a.TotalPrice)
+		}
+		return cmp.Compare(a.TransactionID, b.TransactionID)
+	})
+	return kept, outliers, report
+}
+
+// logOutliers warns about the outliers a load flagged
+func logOutliers(log logger.Logger, report *models.OutlierReport) {
+	if report == nil || report.Rows == 0 {
+		return
+	}
+	log.Warn("Load flagged outlier transactions",
+		"mode", report.Mode,
+		"rows", report.Rows,
+		"price_outliers", report.PriceOutliers,
+		"quantity_outliers", report.QuantityOutliers)
+}
+
+// quantile returns the p quantile of values, interpolating between the
+// nearest ranks like DuckDB's quantile_cont. values is sorted in place.
+func quantile(values []float64, p float64) float64 {
+	slices.Sort(values)
+	rank := p * float64(len(values)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	return values[lower] + (values[upper]-values[lower])*(rank-float64(lower))
+}
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/pkg/logger"
+)
+
+// PreferenceFilters lists the query parameters that may carry stored defaults
+var PreferenceFilters = []string{"country", "segment"}
+
+var currencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// PreferenceStore keeps per-identity preferences in memory and persists them
+// to a JSON file in the state directory
+type PreferenceStore struct {
+	mu          sync.RWMutex
+	path        string
+	preferences map[string]models.Preferences
+	logger      logger.Logger
+}
+
+func NewPreferenceStore(stateDir string, logger logger.Logger) (*PreferenceStore, error) {
+	store := &PreferenceStore{
+		path:        filepath.Join(stateDir, "preferences.json"),
+		preferences: make(map[string]models.Preferences),
+		logger:      logger,
+	}
+
+	if err := loadJSONFile(store.path, &store.preferences); err != nil {
+		return nil, err
+	}
+
+	return store, nil
+}
+
+// Get returns the preferences stored for identity
+func (s *PreferenceStore) Get(identity string) (*models.Preferences, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	prefs, ok := s.preferences[identity]
+	if !ok {
+		return nil, false
+	}
+	return &prefs, true
+}
+
+// Save validates and replaces the preferences for identity
+func (s *PreferenceStore) Save(identity string, prefs models.Preferences) (*models.Preferences, error) {
+	if err := validatePreferences(&prefs); err != nil {
+		return nil, err
+	}
+	prefs.Identity = identity
+	prefs.UpdatedAt = time.Now().UTC()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous, existed := s.preferences[identity]
+	s.preferences[identity] = prefs
+	if err := saveJSONFile(s.path, s.preferences); err != nil {
+		if existed {
+			s.preferences[identity] = previous
+		} else {
+			delete(s.preferences, identity)
+		}
+		return nil, err
+	}
+
+	return &prefs, nil
+}
+
+// Delete removes the preferences stored for identity
+func (s *PreferenceStore) Delete(identity string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous, existed := s.preferences[identity]
+	if !existed {
+		return nil
+	}
+
+	delete(s.preferences, identity)
+	if err := saveJSONFile(s.path, s.preferences); err != nil {
+		s.preferences[identity] = previous
+		return err
+	}
+	return nil
+}
+
+// validatePreferences normalizes prefs in place and rejects unknown filters,
+// unknown timezones and malformed currency codes
+func validatePreferences(prefs *models.Preferences) error {
+	for key, value := range prefs.Filters {
+		if !isPreferenceFilter(key) {
+			return fmt.Errorf("%w: unsupported filter %q (supported: %s)",
+				models.ErrInvalidPreferences, key, strings.Join(PreferenceFilters, ", "))
+		}
+		prefs.Filters[key] = strings.TrimSpace(value)
+	}
+
+	if prefs.Timezone != "" {
+		if _, err := time.LoadLocation(prefs.Timezone); err != nil {
+			return fmt.Errorf("%w: unknown timezone %q", models.ErrInvalidPreferences, prefs.Timezone)
+		}
+	}
+
+	prefs.Currency = strings.ToUpper(strings.TrimSpace(prefs.Currency))
+	if prefs.Currency != "" && !currencyPattern.MatchString(prefs.Currency) {
+		return fmt.Errorf("%w: currency must be a 3-letter ISO code", models.ErrInvalidPreferences)
+	}
+
+	return nil
+}
+
+func isPreferenceFilter(key string) bool {
+	for _, filter := range PreferenceFilters {
+		if filter == key {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,20 @@
+package services
+
+import "analytics-dashboard-api/internal/models"
+
+// completePriceHistory fills in the average price of each month and its
+// change against the month before. points must be in month order. Months
+// are compared as listed, so a gap of unsold months spans one change.
+func completePriceHistory(points []models.PricePoint) {
+	for i := range points {
+		p := &points[i]
+		if p.UnitsSold > 0 {
+			p.AvgPrice = models.MoneyFromFloat(p.Revenue.Float64() / float64(p.UnitsSold))
+		}
+		if i > 0 && points[i-1].AvgPrice != 0 {
+			previous := points[i-1].AvgPrice
+			pct := float64(p.AvgPrice-previous) / float64(previous) * 100
+			p.ChangePct = &pct
+		}
+	}
+}
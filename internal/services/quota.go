@@ -0,0 +1,201 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"analytics-dashboard-api/internal/config"
+	"analytics-dashboard-api/internal/models"
+)
+
+// Quotas refuses loads larger than their dataset or tenant may hold, before
+// they replace any loaded data. Limits apply to each load, since a load
+// replaces the dataset it targets. Tenants are callers identified by
+// X-User-ID; anonymous loads only have dataset limits. A nil *Quotas
+// enforces nothing.
+type Quotas struct {
+	datasets map[string]config.QuotaLimit
+	tenant   config.QuotaLimit
+	tenants  map[string]config.QuotaLimit
+}
+
+// NewQuotas parses the per-tenant overrides in cfg.Tenants
+// ("acme-uk=rows:500000,bytes:200000000;..."). A tenant override replaces
+// only the measures it names.
+func NewQuotas(cfg config.QuotaConfig) (*Quotas, error) {
+	q := &Quotas{
+		datasets: map[string]config.QuotaLimit{
+			models.DatasetTransactions: cfg.Transactions,
+			models.DatasetTargets:      cfg.Targets,
+		},
+		tenant:  cfg.Tenant,
+		tenants: make(map[string]config.QuotaLimit),
+	}
+
+	for _, entry := range strings.Split(cfg.Tenants, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		tenant, measures, ok := strings.Cut(entry, "=")
+		tenant = strings.TrimSpace(tenant)
+		if !ok || tenant == "" {
+			return nil, fmt.Errorf("invalid tenant quota %q: want tenant=rows:N,bytes:N", entry)
+		}
+		limit := cfg.Tenant
+		for _, measure := range strings.Split(measures, ",") {
+			name, value, _ := strings.Cut(strings.TrimSpace(measure), ":")
+			n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("invalid tenant quota %q: %q is not a non-negative count", entry, value)
+			}
+			switch name {
+			case "rows":
+				limit.MaxRows = n
+			case "bytes":
+				limit.MaxBytes = n
+			default:
+				return nil, fmt.Errorf("invalid tenant quota %q: unknown measure %q (want rows or bytes)", entry, name)
+			}
+		}
+		q.tenants[tenant] = limit
+	}
+	return q, nil
+}
+
+// CheckSize refuses a file of size bytes announced by tenant before it is
+// received, so an upload that could never be loaded is not accepted
+func (q *Quotas) CheckSize(tenant string, size int64) error {
+	if q == nil || tenant == "" {
+		return nil
+	}
+	if limit := q.tenantLimit(tenant); limit.MaxBytes > 0 && size > limit.MaxBytes {
+		return &models.QuotaError{Tenant: tenant, Measure: "bytes", Value: size, Limit: limit.MaxBytes}
+	}
+	return nil
+}
+
+// Check refuses a load of dataset by tenant with the given rows and bytes
+func (q *Quotas) Check(dataset, tenant string, rows, size int64) error {
+	if q == nil {
+		return nil
+	}
+	datasetLimit, tenantLimit := q.limits(dataset, tenant)
+	if err := q.check(dataset, tenant, "bytes", size, datasetLimit.MaxBytes, tenantLimit.MaxBytes); err != nil {
+		return err
+	}
+	return q.check(dataset, tenant, "rows", rows, datasetLimit.MaxRows, tenantLimit.MaxRows)
+}
+
+// CheckFiles measures the CSV files a load of dataset would read and
+// refuses them if they exceed the dataset's quota or the tenant's. Rows are
+// only counted when a row limit applies; each line after the header counts
+// as one.
+func (q *Quotas) CheckFiles(ctx context.Context, dataset, tenant string, paths ...string) error {
+	if q == nil {
+		return nil
+	}
+	datasetLimit, tenantLimit := q.limits(dataset, tenant)
+
+	var size int64
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return fmt.Errorf("failed to measure %s: %w", path, err)
+		}
+		size += info.Size()
+	}
+	if err := q.check(dataset, tenant, "bytes", size, datasetLimit.MaxBytes, tenantLimit.MaxBytes); err != nil {
+		return err
+	}
+
+	if datasetLimit.MaxRows == 0 && tenantLimit.MaxRows == 0 {
+		return nil
+	}
+	var rows int64
+	for _, path := range paths {
+		format := detectFormat(path)
+		if format == models.DataFormatParquet {
+			// Parquet keeps its row count in binary metadata; its size is
+			// checked above
+			continue
+		}
+		n, err := countRows(ctx, path, format == models.DataFormatCSV)
+		if err != nil {
+			return err
+		}
+		rows += n
+	}
+	return q.check(dataset, tenant, "rows", rows, datasetLimit.MaxRows, tenantLimit.MaxRows)
+}
+
+// check compares value with the dataset limit, then the tenant limit
+func (q *Quotas) check(dataset, tenant, measure string, value, datasetLimit, tenantLimit int64) error {
+	if datasetLimit > 0 && value > datasetLimit {
+		return &models.QuotaError{Dataset: dataset, Measure: measure, Value: value, Limit: datasetLimit}
+	}
+	if tenantLimit > 0 && value > tenantLimit {
+		return &models.QuotaError{Dataset: dataset, Tenant: tenant, Measure: measure, Value: value, Limit: tenantLimit}
+	}
+	return nil
+}
+
+// limits returns the dataset's limit and the tenant's, which is unlimited
+// for anonymous loads
+func (q *Quotas) limits(dataset, tenant string) (config.QuotaLimit, config.QuotaLimit) {
+	if tenant == "" {
+		return q.datasets[dataset], config.QuotaLimit{}
+	}
+	return q.datasets[dataset], q.tenantLimit(tenant)
+}
+
+func (q *Quotas) tenantLimit(tenant string) config.QuotaLimit {
+	if limit, ok := q.tenants[tenant]; ok {
+		return limit
+	}
+	return q.tenant
+}
+
+// countRows counts the lines of a CSV or JSON Lines file, less the header
+// line of a CSV. Quoted fields spanning lines count once per line, so the
+// count errs on the high side.
+func countRows(ctx context.Context, path string, header bool) (int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count rows in %s: %w", path, err)
+	}
+	defer f.Close()
+
+	buf := make([]byte, 1<<20)
+	var lines int64
+	last := byte('\n')
+	for {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		n, err := f.Read(buf)
+		if n > 0 {
+			lines += int64(bytes.Count(buf[:n], []byte{'\n'}))
+			last = buf[n-1]
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to count rows in %s: %w", path, err)
+		}
+	}
+	if last != '\n' {
+		lines++ // final line without a newline
+	}
+	if header {
+		lines--
+	}
+	return max(lines, 0), nil
+}
@@ -0,0 +1,31 @@
+package services
+
+import (
+	"fmt"
+
+	"analytics-dashboard-api/internal/models"
+)
+
+// productRanks and regionRanks name the column of the top products and top
+// regions queries that each sort_by value orders by. The SQL backends alias
+// their measures to these names.
+var (
+	productRanks = map[string]string{
+		models.RankByPurchaseCount: "purchase_count",
+		models.RankByRevenue:       "total_revenue",
+		models.RankByStock:         "stock_quantity",
+	}
+	regionRanks = map[string]string{
+		models.RankByPurchaseCount: "items_sold",
+		models.RankByRevenue:       "total_revenue",
+	}
+)
+
+// rankColumn returns the column ranks maps sortBy to
+func rankColumn(ranks map[string]string, sortBy string) (string, error) {
+	column, ok := ranks[sortBy]
+	if !ok {
+		return "", fmt.Errorf("%w: cannot rank by %q", models.ErrInvalidParam, sortBy)
+	}
+	return column, nil
+}
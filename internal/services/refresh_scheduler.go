@@ -0,0 +1,190 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"analytics-dashboard-api/internal/config"
+	"analytics-dashboard-api/internal/cron"
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/pkg/logger"
+)
+
+// RefreshScheduler reloads the data on a cron schedule. The schedule starts
+// as configured and may be replaced at runtime; a replaced schedule lasts
+// until the next restart. Runs that come due while a refresh is still going
+// are skipped rather than queued up.
+type RefreshScheduler struct {
+	loader *DataLoader
+	after  func(context.Context) error // run after each successful refresh
+	logger logger.Logger
+
+	mu       sync.Mutex
+	schedule *cron.Schedule // nil when disabled
+	location *time.Location
+	source   string
+	next     time.Time
+	status   models.RefreshSchedule // run history
+	changed  chan struct{}
+}
+
+// NewRefreshScheduler schedules refreshes through loader as cfg says. after,
+// if not nil, runs after each successful refresh, to take the backups or
+// warm the caches that follow a manual refresh.
+func NewRefreshScheduler(cfg config.RefreshConfig, loader *DataLoader, after func(context.Context) error, logger logger.Logger) (*RefreshScheduler, error) {
+	s := &RefreshScheduler{
+		loader:  loader,
+		after:   after,
+		logger:  logger,
+		source:  models.ScheduleSourceConfig,
+		changed: make(chan struct{}, 1),
+	}
+	if err := s.set(cfg.Cron, cfg.Timezone); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Schedule returns the active schedule, when it next runs and how the
+// previous runs went
+func (s *RefreshScheduler) Schedule() models.RefreshSchedule {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	schedule := s.status
+	schedule.Enabled = s.schedule != nil
+	schedule.Timezone = s.location.String()
+	schedule.Source = s.source
+	if s.schedule != nil {
+		schedule.Cron = s.schedule.String()
+	}
+	if !s.next.IsZero() {
+		next := s.next
+		schedule.NextRun = &next
+	}
+	return schedule
+}
+
+// SetSchedule replaces the schedule. An invalid expression or timezone is a
+// models.ErrInvalidSchedule and leaves the schedule alone.
+func (s *RefreshScheduler) SetSchedule(request models.RefreshScheduleRequest) (models.RefreshSchedule, error) {
+	s.mu.Lock()
+	timezone := request.Timezone
+	if timezone == "" {
+		timezone = s.location.String()
+	}
+	err := s.set(request.Cron, timezone)
+	if err == nil {
+		s.source = models.ScheduleSourceAPI
+	}
+	s.mu.Unlock()
+	if err != nil {
+		return models.RefreshSchedule{}, err
+	}
+
+	// Wake the loop to wait for the new next run
+	select {
+	case s.changed <- struct{}{}:
+	default:
+	}
+	schedule := s.Schedule()
+	s.logger.Info("Refresh schedule changed", "cron", schedule.Cron, "timezone", schedule.Timezone, "next_run", schedule.NextRun)
+	return schedule, nil
+}
+
+// set parses and applies a schedule; the caller holds mu, except during
+// construction
+func (s *RefreshScheduler) set(expr, timezone string) error {
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return fmt.Errorf("%w: unknown timezone %q", models.ErrInvalidSchedule, timezone)
+	}
+	var schedule *cron.Schedule
+	if expr != "" {
+		if schedule, err = cron.Parse(expr); err != nil {
+			return fmt.Errorf("%w: %w", models.ErrInvalidSchedule, err)
+		}
+		if schedule.Next(time.Now().In(location)).IsZero() {
+			return fmt.Errorf("%w: %q never runs", models.ErrInvalidSchedule, expr)
+		}
+	}
+
+	s.schedule, s.location = schedule, location
+	s.plan(time.Now())
+	return nil
+}
+
+// plan sets the next run after now; the caller holds mu
+func (s *RefreshScheduler) plan(now time.Time) {
+	s.next = time.Time{}
+	if s.schedule != nil {
+		s.next = s.schedule.Next(now.In(s.location))
+	}
+}
+
+// Start runs scheduled refreshes until ctx is cancelled. It keeps waiting
+// while the schedule is disabled, so one set later through the API takes
+// effect without a restart.
+func (s *RefreshScheduler) Start(ctx context.Context) {
+	go func() {
+		for {
+			s.mu.Lock()
+			next := s.next
+			s.mu.Unlock()
+
+			var due <-chan time.Time
+			var timer *time.Timer
+			if !next.IsZero() {
+				timer = time.NewTimer(time.Until(next))
+				due = timer.C
+			}
+
+			select {
+			case <-ctx.Done():
+			case <-s.changed:
+			case <-due:
+				s.run(ctx)
+			}
+			if timer != nil {
+				timer.Stop()
+			}
+			if ctx.Err() != nil {
+				return
+			}
+		}
+	}()
+}
+
+// run refreshes the data once and plans the next run
+func (s *RefreshScheduler) run(ctx context.Context) {
+	start := time.Now()
+	s.logger.Info("Scheduled refresh started")
+	err := s.loader.ScheduledReload(ctx)
+	if err == nil && s.after != nil {
+		err = s.after(ctx)
+	}
+	if ctx.Err() != nil {
+		return
+	}
+
+	s.mu.Lock()
+	started := start.UTC()
+	s.status.LastRun = &started
+	s.status.LastDurationMs = time.Since(start).Milliseconds()
+	s.status.LastError = ""
+	s.status.Runs++
+	if err != nil {
+		s.status.LastError = err.Error()
+		s.status.Failures++
+	}
+	s.plan(time.Now())
+	s.mu.Unlock()
+
+	if err != nil {
+		s.logger.Error("Scheduled refresh failed", "error", err)
+		return
+	}
+	s.logger.Info("Scheduled refresh completed", "duration", time.Since(start))
+}
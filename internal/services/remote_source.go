@@ -0,0 +1,254 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"analytics-dashboard-api/internal/config"
+	"analytics-dashboard-api/internal/models"
+)
+
+// Object storage schemes a data path may use instead of a local file
+const (
+	schemeS3  = "s3://"
+	schemeGCS = "gs://"
+)
+
+// emptyPayloadHash is the hex sha256 of an empty request body, signed for
+// every GET
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// IsRemoteSource reports whether a data path names an object in S3 or
+// Cloud Storage rather than a local file
+func IsRemoteSource(path string) bool {
+	return strings.HasPrefix(path, schemeS3) || strings.HasPrefix(path, schemeGCS)
+}
+
+// remoteSource downloads objects from S3 and Cloud Storage so they load
+// like local files. Both are read through the S3 XML API with AWS
+// signature version 4, which Cloud Storage accepts with HMAC keys of its
+// interoperability API, so no SDK is needed. Requests without credentials
+// are sent unsigned.
+type remoteSource struct {
+	cfg    config.RemoteSourceConfig
+	client *http.Client
+}
+
+func newRemoteSource(cfg config.RemoteSourceConfig) *remoteSource {
+	return &remoteSource{cfg: cfg, client: &http.Client{}}
+}
+
+// Fetch downloads the object at uri into the cache directory and returns
+// the local path, which keeps the object's extension so the format can be
+// told from it, and its size. The file is written under a temporary name
+// and renamed when complete, so a failed download never replaces the last
+// good copy. The object's ETag is kept next to the copy and sent as
+// If-None-Match, so an object that has not changed is not downloaded
+// again; cached then reports that the copy was reused. A missing object or
+// bucket is reported as models.ErrSourceMissing.
+func (r *remoteSource) Fetch(ctx context.Context, uri string) (local string, size int64, cached bool, err error) {
+	if r.cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.cfg.Timeout)
+		defer cancel()
+	}
+
+	dir := r.cfg.CacheDir
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	sum := sha256.Sum256([]byte(uri))
+	local = filepath.Join(dir, hex.EncodeToString(sum[:8])+"-"+path.Base(uri))
+	etagPath := local + ".etag"
+
+	req, err := r.request(ctx, uri)
+	if err != nil {
+		return "", 0, false, err
+	}
+	// A cached copy that was removed is downloaded again, whatever its ETag
+	info, statErr := os.Stat(local)
+	if etag, err := os.ReadFile(etagPath); err == nil && statErr == nil {
+		req.Header.Set("If-None-Match", strings.TrimSpace(string(etag)))
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return "", 0, false, fmt.Errorf("failed to fetch %s: %w", uri, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotModified && statErr == nil {
+		return local, info.Size(), true, nil
+	}
+	if err := remoteStatusError(uri, resp); err != nil {
+		return "", 0, false, err
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", 0, false, fmt.Errorf("failed to create remote cache directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, ".download-*")
+	if err != nil {
+		return "", 0, false, fmt.Errorf("failed to create download file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // fails harmlessly once renamed
+	n, err := io.Copy(tmp, resp.Body)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", 0, false, fmt.Errorf("failed to download %s: %w", uri, err)
+	}
+	// Drop the old ETag first, so a failure below never pairs the new copy
+	// with the ETag of the object it replaced
+	os.Remove(etagPath)
+	if err := os.Rename(tmp.Name(), local); err != nil {
+		return "", 0, false, fmt.Errorf("failed to store download of %s: %w", uri, err)
+	}
+	if etag := resp.Header.Get("ETag"); etag != "" {
+		// Without the ETag the next fetch downloads the object again
+		os.WriteFile(etagPath, []byte(etag), 0o644)
+	}
+	return local, n, false, nil
+}
+
+// request builds the signed GET of the object at uri
+func (r *remoteSource) request(ctx context.Context, uri string) (*http.Request, error) {
+	endpoint, region, accessKey, secret, token := r.cfg.S3Endpoint, r.cfg.S3Region, r.cfg.S3AccessKeyID, r.cfg.S3SecretAccessKey, r.cfg.S3SessionToken
+	rest := strings.TrimPrefix(uri, schemeS3)
+	if strings.HasPrefix(uri, schemeGCS) {
+		endpoint, region, accessKey, secret, token = r.cfg.GCSEndpoint, "auto", r.cfg.GCSAccessKeyID, r.cfg.GCSSecret, ""
+		rest = strings.TrimPrefix(uri, schemeGCS)
+	}
+	bucket, key, _ := strings.Cut(rest, "/")
+	if bucket == "" || key == "" || strings.HasSuffix(key, "/") {
+		return nil, fmt.Errorf("%w: %s does not name an object", models.ErrInvalidDataset, uri)
+	}
+
+	// A custom endpoint takes the bucket in the path, which S3-compatible
+	// stores and Cloud Storage all accept; AWS itself gets the bucket as
+	// a virtual host
+	objectPath := "/" + bucket + "/" + key
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://%s.s3.%s.amazonaws.com", bucket, region)
+		objectPath = "/" + key
+	}
+	target, err := url.Parse(strings.TrimSuffix(endpoint, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid object storage endpoint %q: %w", endpoint, err)
+	}
+	target.Path += objectPath
+	target.RawPath = escapeObjectPath(target.Path)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if accessKey != "" {
+		signV4(req, time.Now().UTC(), region, accessKey, secret, token)
+	}
+	return req, nil
+}
+
+// signV4 signs req with AWS signature version 4 for the s3 service, over
+// the host, the payload hash, the date and the session token if any
+func signV4(req *http.Request, now time.Time, region, accessKey, secret, token string) {
+	amzDate := now.Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", emptyPayloadHash)
+	if token != "" {
+		req.Header.Set("X-Amz-Security-Token", token)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name := range req.Header {
+		if lower := strings.ToLower(name); strings.HasPrefix(lower, "x-amz-") {
+			headers[lower] = strings.TrimSpace(req.Header.Get(name))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonical := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		emptyPayloadHash,
+	}, "\n")
+	scope := date + "/" + region + "/s3/aws4_request"
+	canonicalHash := sha256.Sum256([]byte(canonical))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(canonicalHash[:])
+
+	key := []byte("AWS4" + secret)
+	for _, part := range []string{date, region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKey, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// escapeObjectPath percent-encodes every byte of p but the unreserved
+// characters and slashes, as signature version 4 expects of the path
+func escapeObjectPath(p string) string {
+	var b strings.Builder
+	for i := 0; i < len(p); i++ {
+		c := p[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', c == '/':
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// remoteStatusError turns a failed response into an error carrying the
+// store's error code, nil for a success
+func remoteStatusError(uri string, resp *http.Response) error {
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+	var body struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	xml.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&body)
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%w: %s", models.ErrSourceMissing, uri)
+	}
+	if body.Code != "" {
+		return fmt.Errorf("failed to fetch %s: %s: %s: %s", uri, resp.Status, body.Code, body.Message)
+	}
+	return fmt.Errorf("failed to fetch %s: %s", uri, resp.Status)
+}
@@ -0,0 +1,200 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"math"
+	"strings"
+
+	"analytics-dashboard-api/internal/format"
+	"analytics-dashboard-api/internal/models"
+)
+
+// Line chart drawing area in SVG user units, matching the viewBox below
+const (
+	chartWidth  = 640
+	chartHeight = 200
+	chartMargin = 8
+)
+
+// reportPage is the view model of a rendered report
+type reportPage struct {
+	*models.Report
+	Filters  string
+	Sections []reportSectionView
+}
+
+type reportSectionView struct {
+	models.ReportSection
+	Rows   []reportRowView
+	Bars   []reportBarView
+	Line   string // SVG polyline points
+	Labels [2]string
+}
+
+type reportRowView struct {
+	Group  string
+	Values []string
+}
+
+type reportBarView struct {
+	Label   string
+	Value   string
+	Percent float64
+}
+
+var reportHTML = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: Helvetica, Arial, sans-serif; color: #222; margin: 2em; }
+h1 { margin-bottom: 0.2em; }
+.meta { color: #666; font-size: 0.9em; }
+section { margin-top: 2em; page-break-inside: avoid; }
+table { border-collapse: collapse; width: 100%; font-size: 0.9em; }
+th, td { border-bottom: 1px solid #ddd; padding: 4px 8px; text-align: right; }
+th:first-child, td:first-child { text-align: left; }
+.bar { display: flex; align-items: center; margin: 2px 0; font-size: 0.9em; }
+.bar .label { width: 25%; overflow: hidden; white-space: nowrap; text-overflow: ellipsis; }
+.bar .fill { background: #4a7bd0; height: 1em; margin-right: 6px; }
+svg polyline { fill: none; stroke: #4a7bd0; stroke-width: 2; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{with .Description}}<p>{{.}}</p>{{end}}
+<p class="meta">Generated {{.GeneratedAt.Format "2006-01-02 15:04 MST"}}{{with .Filters}} &middot; {{.}}{{end}}</p>
+{{range .Sections}}<section>
+<h2>{{.Title}}</h2>
+{{with .Text}}<p>{{.}}</p>{{end}}
+{{if .Bars}}<div class="chart">{{range .Bars}}
+<div class="bar"><span class="label">{{.Label}}</span><span class="fill" style="width: {{printf "%.1f" .Percent}}%"></span>{{.Value}}</div>{{end}}
+</div>
+{{else if .Line}}<svg viewBox="0 0 640 200" width="100%" role="img" aria-label="{{.Title}}">
+<polyline points="{{.Line}}"/>
+</svg>
+<p class="meta">{{index .Labels 0}} &ndash; {{index .Labels 1}}</p>
+{{end}}{{if .Metrics}}<table>
+<thead><tr><th>{{.GroupBy}}</th>{{range .Metrics}}<th>{{.}}</th>{{end}}</tr></thead>
+<tbody>{{range .Rows}}
+<tr><td>{{.Group}}</td>{{range .Values}}<td>{{.}}</td>{{end}}</tr>{{end}}
+</tbody>
+</table>
+{{end}}</section>
+{{end}}</body>
+</html>
+`))
+
+// HTML renders a report as a standalone HTML page. Bar charts are drawn with
+// CSS and line charts with inline SVG, so the page needs no scripts and
+// converts to PDF as is.
+func (s *ReportService) HTML(report *models.Report) ([]byte, error) {
+	locale, _ := format.Lookup("en-US")
+	formatter := format.NewFormatter(locale, "")
+	formatValue := func(value *float64) string {
+		if value == nil {
+			return "–"
+		}
+		decimals := 2
+		if *value == math.Trunc(*value) {
+			decimals = 0
+		}
+		return formatter.Number(*value, decimals)
+	}
+
+	page := reportPage{Report: report}
+	var filters []string
+	if report.Filters.Country != "" {
+		filters = append(filters, "country: "+report.Filters.Country)
+	}
+	if report.Filters.Segment != "" {
+		filters = append(filters, "segment: "+report.Filters.Segment)
+	}
+	page.Filters = strings.Join(filters, ", ")
+
+	for _, section := range report.Sections {
+		view := reportSectionView{ReportSection: section.ReportSection}
+		for _, row := range section.Rows {
+			values := make([]string, len(section.Metrics))
+			for i, name := range section.Metrics {
+				values[i] = formatValue(row.Metrics[name])
+			}
+			view.Rows = append(view.Rows, reportRowView{Group: row.Group, Values: values})
+		}
+
+		if len(section.Metrics) > 0 && len(section.Rows) > 0 {
+			plotted := section.Metrics[0]
+			switch section.Chart {
+			case models.ChartBar:
+				view.Bars = reportBars(section.Rows, plotted, formatValue)
+			case models.ChartLine:
+				view.Line = reportLine(section.Rows, plotted)
+				view.Labels = [2]string{section.Rows[0].Group, section.Rows[len(section.Rows)-1].Group}
+			}
+		}
+		page.Sections = append(page.Sections, view)
+	}
+
+	var buf bytes.Buffer
+	if err := reportHTML.Execute(&buf, page); err != nil {
+		return nil, fmt.Errorf("failed to render report: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// reportBars sizes each row's bar relative to the largest value, which
+// takes 70% of the width to leave room for the label
+func reportBars(rows []models.MetricResult, metric string, formatValue func(*float64) string) []reportBarView {
+	var largest float64
+	for _, row := range rows {
+		if value := row.Metrics[metric]; value != nil && *value > largest {
+			largest = *value
+		}
+	}
+
+	bars := make([]reportBarView, len(rows))
+	for i, row := range rows {
+		bars[i] = reportBarView{Label: row.Group, Value: formatValue(row.Metrics[metric])}
+		if value := row.Metrics[metric]; value != nil && *value > 0 && largest > 0 {
+			bars[i].Percent = *value / largest * 70
+		}
+	}
+	return bars
+}
+
+// reportLine returns SVG polyline points plotting metric across the rows in
+// order, scaled between the smallest and largest value. Null values are skipped.
+func reportLine(rows []models.MetricResult, metric string) string {
+	low, high := math.Inf(1), math.Inf(-1)
+	for _, row := range rows {
+		if value := row.Metrics[metric]; value != nil {
+			low, high = math.Min(low, *value), math.Max(high, *value)
+		}
+	}
+	if math.IsInf(low, 1) {
+		return ""
+	}
+
+	step := 0.0
+	if len(rows) > 1 {
+		step = float64(chartWidth-2*chartMargin) / float64(len(rows)-1)
+	}
+	var points []string
+	for i, row := range rows {
+		value := row.Metrics[metric]
+		if value == nil {
+			continue
+		}
+		y := 0.5
+		if high > low {
+			y = (*value - low) / (high - low)
+		}
+		points = append(points, fmt.Sprintf("%.1f,%.1f",
+			chartMargin+float64(i)*step,
+			chartMargin+(1-y)*float64(chartHeight-2*chartMargin)))
+	}
+	return strings.Join(points, " ")
+}
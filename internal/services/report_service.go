@@ -0,0 +1,290 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"analytics-dashboard-api/internal/config"
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/pkg/logger"
+
+	"gopkg.in/yaml.v3"
+)
+
+// MetricQuerier computes base metrics, optionally grouped by one of MetricGroupings
+type MetricQuerier interface {
+	GetBaseMetrics(context.Context, models.QueryOptions, string) ([]models.MetricRow, error)
+}
+
+// reportNamePattern restricts template names, which are file names in the
+// template directory, so a request cannot reach outside it
+var reportNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// ReportService renders report templates, YAML files read from the template
+// directory on every request so reports change without a deploy. Each
+// section is an aggregate metrics query; reports render as JSON, HTML or,
+// through the configured converter, PDF.
+type ReportService struct {
+	cfg     config.ReportConfig
+	queries MetricQuerier
+	metrics *MetricRegistry
+	logger  logger.Logger
+}
+
+func NewReportService(cfg config.ReportConfig, queries MetricQuerier, metrics *MetricRegistry, logger logger.Logger) *ReportService {
+	return &ReportService{
+		cfg:     cfg,
+		queries: queries,
+		metrics: metrics,
+		logger:  logger,
+	}
+}
+
+// List returns every template in the directory ordered by name, including
+// templates that fail to load, listed with their error
+func (s *ReportService) List() ([]models.ReportTemplateInfo, error) {
+	entries, err := os.ReadDir(s.cfg.TemplateDir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return []models.ReportTemplateInfo{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read report templates: %w", err)
+	}
+
+	results := make([]models.ReportTemplateInfo, 0, len(entries))
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		name, ok := reportTemplateName(entry.Name())
+		if !ok || entry.IsDir() || seen[name] {
+			continue
+		}
+		seen[name] = true
+		info := models.ReportTemplateInfo{Name: name}
+		if template, err := s.Template(name); err != nil {
+			info.Error = err.Error()
+		} else {
+			info.Title = template.Title
+			info.Description = template.Description
+			info.Sections = len(template.Sections)
+		}
+		results = append(results, info)
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Name < results[j].Name
+	})
+	return results, nil
+}
+
+// Template loads and validates the named template
+func (s *ReportService) Template(name string) (*models.ReportTemplate, error) {
+	if !reportNamePattern.MatchString(name) {
+		return nil, models.ErrReportNotFound
+	}
+
+	var data []byte
+	var err error
+	for _, ext := range []string{".yaml", ".yml"} {
+		data, err = os.ReadFile(filepath.Join(s.cfg.TemplateDir, name+ext))
+		if !errors.Is(err, fs.ErrNotExist) {
+			break
+		}
+	}
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, models.ErrReportNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read report template: %w", err)
+	}
+
+	template, err := ParseReportTemplate(data)
+	if err != nil {
+		return nil, err
+	}
+	template.Name = name
+	if err := s.validate(template); err != nil {
+		return nil, err
+	}
+	return template, nil
+}
+
+// ParseReportTemplate decodes a YAML template. Unknown keys are rejected so
+// a misspelt option fails loudly instead of being ignored.
+func ParseReportTemplate(data []byte) (*models.ReportTemplate, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+
+	var template models.ReportTemplate
+	if err := decoder.Decode(&template); err != nil {
+		return nil, fmt.Errorf("%w: %v", models.ErrInvalidReport, err)
+	}
+	return &template, nil
+}
+
+// validate checks a template against the metrics and groupings available,
+// defaulting section charts to tables
+func (s *ReportService) validate(template *models.ReportTemplate) error {
+	if strings.TrimSpace(template.Title) == "" {
+		return fmt.Errorf("%w: title is required", models.ErrInvalidReport)
+	}
+	if len(template.Sections) == 0 {
+		return fmt.Errorf("%w: at least one section is required", models.ErrInvalidReport)
+	}
+
+	for i := range template.Sections {
+		section := &template.Sections[i]
+		invalid := func(format string, args ...interface{}) error {
+			return fmt.Errorf("%w: section %d: %s", models.ErrInvalidReport, i+1, fmt.Sprintf(format, args...))
+		}
+
+		if len(section.Metrics) == 0 {
+			if section.Text == "" {
+				return invalid("metrics or text is required")
+			}
+			if section.GroupBy != "" || section.Sort != "" || section.Limit != 0 || section.Chart != "" {
+				return invalid("text sections take no query options")
+			}
+			continue
+		}
+
+		if err := s.metrics.Validate(section.Metrics); err != nil {
+			return invalid("%v", err)
+		}
+		if _, ok := MetricGroupings[section.GroupBy]; section.GroupBy != "" && !ok {
+			return invalid("unknown group_by %q", section.GroupBy)
+		}
+		if section.Sort != "" && !containsString(section.Metrics, section.Sort) {
+			return invalid("sort metric %q is not one of the section's metrics", section.Sort)
+		}
+		if section.Limit < 0 {
+			return invalid("limit must not be negative")
+		}
+
+		switch section.Chart {
+		case "":
+			section.Chart = models.ChartTable
+		case models.ChartTable:
+		case models.ChartBar, models.ChartLine:
+			if section.GroupBy == "" {
+				return invalid("a %s chart needs group_by", section.Chart)
+			}
+		default:
+			return invalid("unknown chart %q (use table, bar or line)", section.Chart)
+		}
+	}
+	return nil
+}
+
+// Render evaluates the named template. Filters set in opts override the
+// template's own.
+func (s *ReportService) Render(ctx context.Context, name string, opts models.QueryOptions) (*models.Report, error) {
+	template, err := s.Template(name)
+	if err != nil {
+		return nil, err
+	}
+
+	filters := template.Filters
+	if opts.Country != "" {
+		filters.Country = opts.Country
+	}
+	if opts.Segment != "" {
+		filters.Segment = opts.Segment
+	}
+	opts.Country, opts.Segment = filters.Country, filters.Segment
+
+	report := &models.Report{
+		Name:        template.Name,
+		Title:       template.Title,
+		Description: template.Description,
+		Filters:     filters,
+		Sections:    make([]models.ReportResult, len(template.Sections)),
+		GeneratedAt: time.Now().UTC(),
+	}
+
+	for i, section := range template.Sections {
+		report.Sections[i].ReportSection = section
+		if len(section.Metrics) == 0 {
+			continue
+		}
+
+		rows, err := s.queries.GetBaseMetrics(ctx, opts, section.GroupBy)
+		if err != nil {
+			return nil, fmt.Errorf("report section %q: %w", section.Title, err)
+		}
+		results := make([]models.MetricResult, len(rows))
+		for j, row := range rows {
+			results[j] = models.MetricResult{
+				Group:   row.Group,
+				Metrics: s.metrics.Evaluate(section.Metrics, row.Values),
+			}
+		}
+
+		if section.Sort != "" {
+			sort.SliceStable(results, func(a, b int) bool {
+				return metricGreater(results[a].Metrics[section.Sort], results[b].Metrics[section.Sort])
+			})
+		}
+		if section.Limit > 0 && len(results) > section.Limit {
+			results = results[:section.Limit]
+		}
+		report.Sections[i].Rows = results
+	}
+
+	return report, nil
+}
+
+// PDF converts a rendered report to PDF by piping its HTML through the
+// configured command
+func (s *ReportService) PDF(ctx context.Context, report *models.Report) ([]byte, error) {
+	command := strings.Fields(s.cfg.PDFCommand)
+	if len(command) == 0 {
+		return nil, models.ErrReportNoPDF
+	}
+
+	html, err := s.HTML(report)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.PDFTimeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, command[0], command[1:]...)
+	cmd.Stdin = bytes.NewReader(html)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		s.logger.Error("PDF conversion failed", "report", report.Name, "error", err, "stderr", strings.TrimSpace(stderr.String()))
+		return nil, fmt.Errorf("failed to convert report to PDF: %w", err)
+	}
+	return stdout.Bytes(), nil
+}
+
+// reportTemplateName returns the template name of a file in the template directory
+func reportTemplateName(file string) (string, bool) {
+	for _, ext := range []string{".yaml", ".yml"} {
+		if name, ok := strings.CutSuffix(file, ext); ok && reportNamePattern.MatchString(name) {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// metricGreater orders metric values descending with nulls last
+func metricGreater(a, b *float64) bool {
+	if a == nil || b == nil {
+		return a != nil
+	}
+	return *a > *b
+}
@@ -0,0 +1,63 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"analytics-dashboard-api/internal/models"
+)
+
+// Repository is the analytics store behind the API. DuckDBService loads CSV
+// or Parquet files into an embedded database; ClickHouseService queries
+// tables that already live in ClickHouse; MemoryService aggregates CSV files
+// in plain Go for builds without cgo. Operations an implementation cannot serve
+// return models.ErrNotSupported.
+type Repository interface {
+	LoadFromFile(context.Context, string, ...string) error
+	InspectSource(context.Context, string, string) (*models.SourceInspection, error)
+	Rollback(context.Context) (*models.DataSnapshot, error)
+	ExcludeTransactions([]string) error
+	DataCoverage() models.DataCoverage
+	Ping(context.Context) error
+	Close() error
+
+	GetCountryRevenue(context.Context, models.QueryOptions, int, int) ([]models.CountryRevenue, *models.Totals, error)
+	GetTopProducts(context.Context, models.QueryOptions, string, int, int) ([]models.ProductFrequency, error)
+	GetTopProductsCount(context.Context, models.QueryOptions) (int, error)
+	GetMonthlySales(context.Context, models.QueryOptions) ([]models.MonthlySales, error)
+	GetTopRegions(context.Context, models.QueryOptions, string, int, int) ([]models.RegionRevenue, error)
+	GetTopRegionsCount(context.Context, models.QueryOptions) (int, error)
+	GetRegionSales(context.Context, models.QueryOptions) ([]models.RegionSales, error)
+	GetTotalRecords(context.Context) (int, error)
+	GetCountryRevenueCount(context.Context, models.QueryOptions) (int, error)
+	GetDistinctCounts(context.Context) (models.DistinctCounts, error)
+	GetSegmentBreakdown(context.Context, models.QueryOptions) ([]models.SegmentRevenue, error)
+	GetTopCustomers(context.Context, models.QueryOptions, int) ([]models.CustomerSpend, error)
+	GetRepeatPurchase(context.Context) (models.RepeatPurchase, error)
+	GetBaseMetrics(context.Context, models.QueryOptions, string) ([]models.MetricRow, error)
+	GetTimeSeries(context.Context, models.QueryOptions, string) ([]models.TimeBucket, error)
+	GetAlertMetrics(context.Context) (map[string]float64, error)
+
+	ListProducts(context.Context, string, int, int) ([]models.Product, error)
+	CountProducts(context.Context, string) (int, error)
+	GetProduct(context.Context, string) (*models.Product, *models.ProductSales, error)
+	GetPriceHistory(context.Context, string, models.QueryOptions) ([]models.PricePoint, error)
+	GetStockLevels(context.Context, models.QueryOptions, time.Time) ([]models.StockLevel, error)
+	ListDimensionValues(context.Context, string, string, int, int) ([]models.DimensionValue, error)
+	CountDimensionValues(context.Context, string, string) (int, error)
+	GetDataProfile(context.Context, int) (*models.DataProfile, error)
+	GetPartitions(context.Context) ([]models.Partition, error)
+	ListOutliers(context.Context, int, int) ([]models.Outlier, error)
+
+	LoadTargets(context.Context, string) (*models.TableLoadResult, error)
+	GetTargetVariance(context.Context, string) ([]models.TargetVariance, error)
+
+	Backup(context.Context, string) (*models.BackupInfo, error)
+	Restore(context.Context, string, string) (*models.BackupInfo, error)
+	ExportTransactions(context.Context, models.QueryOptions, string, string, func(int64, int64)) (int64, error)
+}
+
+var (
+	_ Repository = (*ClickHouseService)(nil)
+	_ Repository = (*MemoryService)(nil)
+)
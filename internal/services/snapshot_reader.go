@@ -0,0 +1,144 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"analytics-dashboard-api/internal/config"
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/pkg/logger"
+)
+
+// SnapshotReader answers queries as of a past time from the backups retained
+// in the backup directory. The backup in effect at that time is restored into
+// a backend of its own and kept open for later queries; beyond the configured
+// number of open snapshots the least recently used one is closed.
+type SnapshotReader struct {
+	dir    string
+	max    int
+	open   func() (Repository, error)
+	logger logger.Logger
+
+	mu        sync.Mutex
+	snapshots map[string]*openSnapshot
+	recent    []string // snapshot names, least recently used first
+}
+
+// openSnapshot is a restored backup and the requests using it
+type openSnapshot struct {
+	repo    Repository
+	info    *models.BackupInfo
+	refs    int
+	evicted bool // closed once the last request releases it
+}
+
+// NewSnapshotReader restores backups into backends built by open, which must
+// return an empty backend that supports Restore
+func NewSnapshotReader(cfg config.BackupConfig, open func() (Repository, error), logger logger.Logger) *SnapshotReader {
+	return &SnapshotReader{
+		dir:       cfg.Dir,
+		max:       cfg.OpenSnapshots,
+		open:      open,
+		logger:    logger,
+		snapshots: map[string]*openSnapshot{},
+	}
+}
+
+// AsOf returns a backend holding the newest backup taken at or before t.
+// The caller must call release once done querying it.
+func (r *SnapshotReader) AsOf(ctx context.Context, t time.Time) (Repository, *models.BackupInfo, func(), error) {
+	name, err := r.resolve(t)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	snapshot, ok := r.snapshots[name]
+	if !ok {
+		if snapshot, err = r.restore(ctx, name); err != nil {
+			return nil, nil, nil, err
+		}
+		r.snapshots[name] = snapshot
+	}
+	r.recent = append(slices.DeleteFunc(r.recent, func(n string) bool { return n == name }), name)
+	snapshot.refs++
+	r.evict()
+
+	return snapshot.repo, snapshot.info, func() { r.release(snapshot) }, nil
+}
+
+// Close closes every open snapshot
+func (r *SnapshotReader) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for name, snapshot := range r.snapshots {
+		snapshot.repo.Close()
+		delete(r.snapshots, name)
+	}
+	r.recent = nil
+	return nil
+}
+
+// resolve returns the name of the newest backup taken at or before t
+func (r *SnapshotReader) resolve(t time.Time) (string, error) {
+	names, err := listBackups(r.dir)
+	if err != nil {
+		return "", err
+	}
+	for i := len(names) - 1; i >= 0; i-- {
+		created, err := time.Parse(backupNameLayout, names[i])
+		if err == nil && !created.After(t) {
+			return names[i], nil
+		}
+	}
+	return "", fmt.Errorf("%w: none taken at or before %s", models.ErrBackupNotFound, t.UTC().Format(time.RFC3339))
+}
+
+// restore opens a backend and restores the named backup into it
+func (r *SnapshotReader) restore(ctx context.Context, name string) (*openSnapshot, error) {
+	repo, err := r.open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open snapshot backend: %w", err)
+	}
+	info, err := repo.Restore(ctx, r.dir, name)
+	if err != nil {
+		repo.Close()
+		return nil, err
+	}
+
+	r.logger.Info("Snapshot opened for as-of queries", "name", name, "records", info.Records)
+	return &openSnapshot{repo: repo, info: info}, nil
+}
+
+// evict closes the least recently used snapshots beyond the limit. One still
+// in use is closed when released.
+func (r *SnapshotReader) evict() {
+	for len(r.recent) > r.max {
+		name := r.recent[0]
+		r.recent = r.recent[1:]
+
+		snapshot := r.snapshots[name]
+		delete(r.snapshots, name)
+		snapshot.evicted = true
+		if snapshot.refs == 0 {
+			snapshot.repo.Close()
+		}
+		r.logger.Debug("Snapshot closed", "name", name)
+	}
+}
+
+func (r *SnapshotReader) release(snapshot *openSnapshot) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	snapshot.refs--
+	if snapshot.evicted && snapshot.refs == 0 {
+		snapshot.repo.Close()
+	}
+}
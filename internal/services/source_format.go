@@ -0,0 +1,47 @@
+package services
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"analytics-dashboard-api/internal/models"
+)
+
+// resolveFormat returns the format the files are read as. An explicit
+// format applies to every file; with auto each file's extension decides,
+// and since a load reads one format, a mix of formats is refused.
+func resolveFormat(format string, paths ...string) (string, error) {
+	switch format {
+	case models.DataFormatCSV, models.DataFormatParquet, models.DataFormatJSONL:
+		return format, nil
+	case models.DataFormatAuto, "":
+	default:
+		return "", fmt.Errorf("%w: unknown format %q", models.ErrInvalidDataset, format)
+	}
+
+	resolved := ""
+	for _, path := range paths {
+		detected := detectFormat(path)
+		if resolved != "" && detected != resolved {
+			return "", fmt.Errorf("%w: sources mix %s and %s files", models.ErrInvalidDataset, resolved, detected)
+		}
+		resolved = detected
+	}
+	if resolved == "" {
+		resolved = models.DataFormatCSV
+	}
+	return resolved, nil
+}
+
+// detectFormat returns the format auto detection reads path as
+func detectFormat(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".parquet":
+		return models.DataFormatParquet
+	case ".jsonl", ".ndjson":
+		return models.DataFormatJSONL
+	default:
+		return models.DataFormatCSV
+	}
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"analytics-dashboard-api/internal/config"
+	"analytics-dashboard-api/pkg/logger"
+)
+
+// SourceWatcher reloads the data when the configured data file changes, so
+// replacing the file on disk is enough to publish new data. For a manifest
+// the manifest itself is watched, as it is written last when a drop is
+// complete.
+//
+// The file is polled rather than watched through inotify, which keeps the
+// server free of extra dependencies and also sees changes on network and
+// container volumes that do not deliver file events. A change is loaded
+// once the file has stayed the same for the debounce, so a file still being
+// written or copied is not read half-way. Loads replace the data in one
+// step in every backend, so queries see either the old data or the new.
+type SourceWatcher struct {
+	loader   *DataLoader
+	path     string
+	interval time.Duration
+	debounce time.Duration
+	logger   logger.Logger
+}
+
+// NewSourceWatcher watches path with the interval and debounce of cfg
+func NewSourceWatcher(loader *DataLoader, path string, cfg config.DataConfig, logger logger.Logger) *SourceWatcher {
+	return &SourceWatcher{
+		loader:   loader,
+		path:     path,
+		interval: cfg.WatchInterval,
+		debounce: cfg.WatchDebounce,
+		logger:   logger,
+	}
+}
+
+// Start polls the file until ctx is cancelled. A zero interval disables it.
+func (w *SourceWatcher) Start(ctx context.Context) {
+	if w.interval <= 0 {
+		return
+	}
+	if IsRemoteSource(w.path) {
+		w.logger.Warn("Remote data files are not watched; use scheduled refreshes instead", "file", w.path)
+		return
+	}
+	w.logger.Info("Watching data file for changes", "file", w.path, "interval", w.interval, "debounce", w.debounce)
+
+	go func() {
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+
+		last, _ := os.Stat(w.path)
+		var changedAt time.Time // of an unloaded change, zero when there is none
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			current, err := os.Stat(w.path)
+			if !sameFile(last, current) {
+				last, changedAt = current, time.Now()
+				continue
+			}
+			// A missing file is being replaced; wait for the new one
+			if changedAt.IsZero() || err != nil || time.Since(changedAt) < w.debounce {
+				continue
+			}
+
+			changedAt = time.Time{}
+			w.logger.Info("Data file changed, reloading", "file", w.path)
+			if err := w.loader.SourceChanged(ctx); err != nil && ctx.Err() == nil {
+				w.logger.Error("Reload after data file change failed", "file", w.path, "error", err)
+			}
+		}
+	}()
+}
+
+// sameFile reports whether two stats, nil for a missing file, describe the
+// same unchanged file. A file replaced by a rename is a new file even with
+// the old size and modification time.
+func sameFile(a, b os.FileInfo) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return os.SameFile(a, b) && a.Size() == b.Size() && a.ModTime().Equal(b.ModTime())
+}
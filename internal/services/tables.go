@@ -0,0 +1,131 @@
+package services
+
+import "slices"
+
+// ColumnSpec describes a table column and the DuckDB type it is cast to on load
+type ColumnSpec struct {
+	Name string
+	Type string
+}
+
+// Reference is a foreign key from a fact column to a dimension table,
+// verified after every load
+type Reference struct {
+	Column    string
+	RefTable  string
+	RefColumn string
+}
+
+// TableSpec describes a table managed by the loader
+type TableSpec struct {
+	Name       string
+	Columns    []ColumnSpec
+	Required   bool // fail the load when the source file is missing
+	References []Reference
+	// ClusterBy is a column rows are inserted in the order of. DuckDB keeps
+	// the min and max of every column per row group, so a filter on a
+	// clustered column skips the row groups outside its range. Loads leave
+	// it out when DUCKDB_OPTIMIZE is off.
+	ClusterBy string
+}
+
+var transactionsTable = TableSpec{
+	Name: "transactions",
+	Columns: []ColumnSpec{
+		{"transaction_id", "VARCHAR"},
+		{"transaction_date", "DATE"},
+		{"user_id", "VARCHAR"},
+		{"country", "VARCHAR"},
+		{"region", "VARCHAR"},
+		{"product_id", "VARCHAR"},
+		{"product_name", "VARCHAR"},
+		{"category", "VARCHAR"},
+		{"price", "DECIMAL(10,2)"},
+		{"quantity", "INTEGER"},
+		{"total_price", "DECIMAL(10,2)"},
+		{"stock_quantity", "INTEGER"},
+		{"added_date", "DATE"},
+	},
+	Required: true,
+	References: []Reference{
+		{Column: "product_id", RefTable: "products", RefColumn: "product_id"},
+		{Column: "user_id", RefTable: "customers", RefColumn: "user_id"},
+	},
+	// Months become runs of row groups that date-range queries prune
+	ClusterBy: "transaction_date",
+}
+
+var productsTable = TableSpec{
+	Name: "products",
+	Columns: []ColumnSpec{
+		{"product_id", "VARCHAR"},
+		{"product_name", "VARCHAR"},
+		{"category", "VARCHAR"},
+		{"brand", "VARCHAR"},
+		{"supplier", "VARCHAR"},
+	},
+}
+
+var customersTable = TableSpec{
+	Name: "customers",
+	Columns: []ColumnSpec{
+		{"user_id", "VARCHAR"},
+		{"signup_date", "DATE"},
+		{"segment", "VARCHAR"},
+		{"country", "VARCHAR"},
+	},
+}
+
+// targetsTable holds monthly revenue targets per country, uploaded via the API
+var targetsTable = TableSpec{
+	Name: "targets",
+	Columns: []ColumnSpec{
+		{"month", "VARCHAR"}, // YYYY-MM
+		{"country", "VARCHAR"},
+		{"revenue_target", "DECIMAL(14,2)"},
+	},
+}
+
+// outliersTable lists the transactions the latest load flagged as outliers,
+// with a flag per checked column. The loader fills it, not a source file.
+var outliersTable = TableSpec{
+	Name: "transaction_outliers",
+	Columns: append(slices.Clone(transactionsTable.Columns),
+		ColumnSpec{"price_outlier", "BOOLEAN"},
+		ColumnSpec{"quantity_outlier", "BOOLEAN"},
+	),
+}
+
+// loadStateTable records, in a file-backed DuckDB database, which files the
+// data was loaded from and its coverage, so a restart can reuse the data
+// while those files are unchanged. It holds at most one row.
+var loadStateTable = TableSpec{
+	Name: "load_state",
+	Columns: []ColumnSpec{
+		{"sources", "VARCHAR"},  // JSON list of sourceFile
+		{"coverage", "VARCHAR"}, // JSON models.DataCoverage
+	},
+}
+
+// tableRegistry lists every table in load order: dimensions first so
+// referential checks on the fact table can run in the same transaction
+var tableRegistry = []TableSpec{productsTable, customersTable, targetsTable, transactionsTable}
+
+// lookupTable returns the registered spec for name
+func lookupTable(name string) (TableSpec, bool) {
+	for _, spec := range tableRegistry {
+		if spec.Name == name {
+			return spec, true
+		}
+	}
+	return TableSpec{}, false
+}
+
+// columnNames returns the names of the spec's columns, in order
+func (t TableSpec) columnNames() []string {
+	names := make([]string, len(t.Columns))
+	for i, col := range t.Columns {
+		names[i] = col.Name
+	}
+	return names
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"regexp"
+	"strings"
+
+	"analytics-dashboard-api/internal/config"
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/pkg/logger"
+)
+
+// testOrderRules recognise QA orders in the source files, by user_id
+// pattern or product_id prefix. Matching is case-sensitive.
+type testOrderRules struct {
+	// userPatterns are the user_id patterns as regular expressions, matched
+	// against the whole user_id (DuckDB's regexp_full_match)
+	userPatterns    []string
+	users           []*regexp.Regexp
+	productPrefixes []string
+}
+
+func newTestOrderRules(cfg config.TestOrderConfig) testOrderRules {
+	var rules testOrderRules
+	for _, pattern := range strings.Split(cfg.UserPatterns, ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			expr := globExpr(pattern)
+			rules.userPatterns = append(rules.userPatterns, expr)
+			rules.users = append(rules.users, regexp.MustCompile("^(?:"+expr+")$"))
+		}
+	}
+	for _, prefix := range strings.Split(cfg.ProductPrefixes, ",") {
+		if prefix = strings.TrimSpace(prefix); prefix != "" {
+			rules.productPrefixes = append(rules.productPrefixes, prefix)
+		}
+	}
+	return rules
+}
+
+// globExpr converts a pattern where * matches any run of characters and ?
+// a single character into a regular expression; everything else is literal
+func globExpr(pattern string) string {
+	expr := regexp.QuoteMeta(pattern)
+	expr = strings.ReplaceAll(expr, `\*`, `.*`)
+	return strings.ReplaceAll(expr, `\?`, `.`)
+}
+
+// empty reports whether no rule is configured
+func (r testOrderRules) empty() bool {
+	return len(r.users) == 0 && len(r.productPrefixes) == 0
+}
+
+// match reports whether t is a test order
+func (r testOrderRules) match(t *models.Transaction) bool {
+	for _, user := range r.users {
+		if user.MatchString(t.UserID) {
+			return true
+		}
+	}
+	for _, prefix := range r.productPrefixes {
+		if strings.HasPrefix(t.ProductID, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// logTestOrders reports the test orders a load left out
+func logTestOrders(log logger.Logger, removed int) {
+	if removed == 0 {
+		return
+	}
+	log.Info("Test orders excluded from load", "rows", removed)
+}
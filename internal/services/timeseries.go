@@ -0,0 +1,242 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/pkg/logger"
+)
+
+// TimeSeriesQuerier reads sales per period
+type TimeSeriesQuerier interface {
+	GetTimeSeries(context.Context, models.QueryOptions, string) ([]models.TimeBucket, error)
+	DataCoverage() models.DataCoverage
+}
+
+// maxTimeSeriesPoints bounds the periods of one response, about ten years
+// of days
+const maxTimeSeriesPoints = 3660
+
+// TimeSeriesService aligns revenue, orders and units on one list of
+// periods, so a chart can plot several metrics without matching dates
+// itself
+type TimeSeriesService struct {
+	queries TimeSeriesQuerier
+	logger  logger.Logger
+}
+
+func NewTimeSeriesService(queries TimeSeriesQuerier, logger logger.Logger) *TimeSeriesService {
+	return &TimeSeriesService{
+		queries: queries,
+		logger:  logger,
+	}
+}
+
+// Series returns every period of granularity between opts.From and opts.To,
+// or the data coverage where they are unset, with zero for periods
+// without sales. A smooth method (ma7, ma30 or ewma; empty for none) adds
+// the smoothed values of every metric.
+func (s *TimeSeriesService) Series(ctx context.Context, opts models.QueryOptions, granularity, smooth string) (*models.TimeSeries, error) {
+	if _, ok := periodStart(time.Time{}, granularity); !ok {
+		return nil, fmt.Errorf("%w: granularity must be day, week or month", models.ErrInvalidTimeSeries)
+	}
+	switch smooth {
+	case "", models.SmoothMA7, models.SmoothMA30, models.SmoothEWMA:
+	default:
+		return nil, fmt.Errorf("%w: smooth must be ma7, ma30 or ewma", models.ErrInvalidTimeSeries)
+	}
+
+	series := &models.TimeSeries{
+		Granularity: granularity,
+		Dates:       []string{},
+		Revenue:     []models.Money{},
+		Orders:      []int{},
+		Units:       []int{},
+	}
+	coverage := s.queries.DataCoverage()
+	from, to := opts.From, opts.To.AddDate(0, 0, -1) // opts.To is exclusive
+	if from.IsZero() {
+		from, _ = time.Parse("2006-01-02", coverage.From)
+	}
+	if opts.To.IsZero() {
+		to, _ = time.Parse("2006-01-02", coverage.To)
+	}
+	if from.IsZero() || to.IsZero() || to.Before(from) {
+		return series, nil
+	}
+
+	first, _ := periodStart(from, granularity)
+	var periods []string
+	for p := first; !p.After(to); p = nextPeriod(p, granularity) {
+		if len(periods) == maxTimeSeriesPoints {
+			return nil, fmt.Errorf("%w: more than %d periods, narrow the range or use a coarser granularity", models.ErrInvalidTimeSeries, maxTimeSeriesPoints)
+		}
+		periods = append(periods, p.Format("2006-01-02"))
+	}
+
+	buckets, err := s.queries.GetTimeSeries(ctx, opts, granularity)
+	if err != nil {
+		return nil, err
+	}
+	byStart := make(map[string]models.TimeBucket, len(buckets))
+	for _, bucket := range buckets {
+		byStart[bucket.Start] = bucket
+	}
+	for _, period := range periods {
+		bucket := byStart[period]
+		series.Dates = append(series.Dates, period)
+		series.Revenue = append(series.Revenue, bucket.Revenue)
+		series.Orders = append(series.Orders, bucket.Orders)
+		series.Units = append(series.Units, bucket.Units)
+	}
+	if smooth != "" {
+		revenue := make([]float64, len(series.Revenue))
+		orders := make([]float64, len(series.Orders))
+		units := make([]float64, len(series.Units))
+		for i := range series.Dates {
+			revenue[i] = series.Revenue[i].Float64()
+			orders[i] = float64(series.Orders[i])
+			units[i] = float64(series.Units[i])
+		}
+		series.Smooth = smooth
+		series.Smoothed = map[string][]float64{
+			models.SeriesRevenue: smoothValues(revenue, smooth),
+			models.SeriesOrders:  smoothValues(orders, smooth),
+			models.SeriesUnits:   smoothValues(units, smooth),
+		}
+	}
+	return series, nil
+}
+
+// smoothValues returns values smoothed by method, rounded to 2 decimal
+// places. The moving averages work like a SQL window of the period and the
+// ones before it, so the first periods average the fewer values there are;
+// the weighted average starts at the first value. Periods without sales
+// count as zero, since the series has every period.
+func smoothValues(values []float64, method string) []float64 {
+	smoothed := make([]float64, len(values))
+	switch method {
+	case models.SmoothEWMA:
+		const alpha = 2.0 / (7 + 1)
+		average := 0.0
+		for i, value := range values {
+			if i == 0 {
+				average = value
+			} else {
+				average = alpha*value + (1-alpha)*average
+			}
+			smoothed[i] = average
+		}
+	default:
+		window := 7
+		if method == models.SmoothMA30 {
+			window = 30
+		}
+		// Summed afresh for each period: a running sum would carry float
+		// drift into the periods after a spike
+		for i := range values {
+			start := max(i-window+1, 0)
+			sum := 0.0
+			for _, value := range values[start : i+1] {
+				sum += value
+			}
+			smoothed[i] = sum / float64(i+1-start)
+		}
+	}
+	for i := range smoothed {
+		smoothed[i] = math.Round(smoothed[i]*100) / 100
+	}
+	return smoothed
+}
+
+// Calendar returns metric (revenue, orders or units) for every day of year
+// under the filters in opts, whose date range is replaced by the year. Year
+// zero takes the year of the latest data. Intensity is each day's value over
+// the year's largest, so the busiest day is 1.
+func (s *TimeSeriesService) Calendar(ctx context.Context, opts models.QueryOptions, year int, metric string) (*models.Calendar, error) {
+	switch metric {
+	case models.SeriesRevenue, models.SeriesOrders, models.SeriesUnits:
+	default:
+		return nil, fmt.Errorf("%w: metric must be revenue, orders or units", models.ErrInvalidCalendar)
+	}
+	if year == 0 {
+		latest, err := time.Parse("2006-01-02", s.queries.DataCoverage().To)
+		if err != nil {
+			latest = time.Now().UTC()
+		}
+		year = latest.Year()
+	}
+	if year < 1 || year > 9999 {
+		return nil, fmt.Errorf("%w: year must be between 1 and 9999", models.ErrInvalidCalendar)
+	}
+
+	opts.From = time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	opts.To = opts.From.AddDate(1, 0, 0)
+	buckets, err := s.queries.GetTimeSeries(ctx, opts, models.GranularityDay)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]float64, len(buckets))
+	var totalRevenue models.Money
+	for _, bucket := range buckets {
+		switch metric {
+		case models.SeriesRevenue:
+			values[bucket.Start] = bucket.Revenue.Float64()
+			totalRevenue += bucket.Revenue
+		case models.SeriesOrders:
+			values[bucket.Start] = float64(bucket.Orders)
+		case models.SeriesUnits:
+			values[bucket.Start] = float64(bucket.Units)
+		}
+	}
+
+	calendar := &models.Calendar{Year: year, Metric: metric}
+	for day := opts.From; day.Before(opts.To); day = day.AddDate(0, 0, 1) {
+		date := day.Format("2006-01-02")
+		value := values[date]
+		calendar.Days = append(calendar.Days, models.CalendarDay{Date: date, Value: value})
+		calendar.Total += value
+		calendar.MaxValue = max(calendar.MaxValue, value)
+	}
+	if metric == models.SeriesRevenue {
+		// Summed in cents so the total carries no float drift
+		calendar.Total = totalRevenue.Float64()
+	}
+	if calendar.MaxValue > 0 {
+		for i := range calendar.Days {
+			// Days with net refunds show as 0 rather than below the scale
+			intensity := math.Round(calendar.Days[i].Value/calendar.MaxValue*10000) / 10000
+			calendar.Days[i].Intensity = max(intensity, 0)
+		}
+	}
+	return calendar, nil
+}
+
+// periodStart returns the first day of the day, Monday-based week or month
+// containing t, and false for an unknown granularity
+func periodStart(t time.Time, granularity string) (time.Time, bool) {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch granularity {
+	case models.GranularityDay:
+		return day, true
+	case models.GranularityWeek:
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7), true
+	case models.GranularityMonth:
+		return day.AddDate(0, 0, 1-day.Day()), true
+	}
+	return time.Time{}, false
+}
+
+// nextPeriod returns the start of the period after the one starting at p
+func nextPeriod(p time.Time, granularity string) time.Time {
+	switch granularity {
+	case models.GranularityWeek:
+		return p.AddDate(0, 0, 7)
+	case models.GranularityMonth:
+		return p.AddDate(0, 1, 0)
+	}
+	return p.AddDate(0, 0, 1)
+}
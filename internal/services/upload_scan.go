@@ -0,0 +1,172 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"analytics-dashboard-api/internal/config"
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/pkg/logger"
+)
+
+// UploadScanner checks an uploaded file before it is ingested. It returns a
+// *models.ScanRejection to refuse the file; any other error means the scan
+// itself could not complete.
+type UploadScanner interface {
+	Name() string
+	Scan(context.Context, models.UploadedFile) error
+}
+
+// UploadScan runs the configured scanners over every uploaded file before
+// ingestion and records each verdict in the audit log. A scan that cannot
+// complete rejects the file: uploads fail closed.
+type UploadScan struct {
+	scanners []UploadScanner
+	audit    *AuditLog
+	logger   logger.Logger
+}
+
+// NewUploadScan checks size and type, plus ClamAV when an address is set
+func NewUploadScan(cfg config.UploadConfig, audit *AuditLog, logger logger.Logger) *UploadScan {
+	scan := &UploadScan{audit: audit, logger: logger}
+	scan.Register(sizeScanner{max: cfg.MaxBytes})
+	scan.Register(typeScanner{
+		extensions: splitList(cfg.AllowedExtensions),
+		types:      splitList(cfg.AllowedTypes),
+	})
+	if cfg.ClamAVAddress != "" {
+		scan.Register(newClamAVScanner(cfg.ClamAVAddress, cfg.ClamAVTimeout))
+	}
+	return scan
+}
+
+// Register adds a scanner, run after those already registered
+func (s *UploadScan) Register(scanner UploadScanner) {
+	s.scanners = append(s.scanners, scanner)
+}
+
+// Scan runs every scanner until one refuses the file
+func (s *UploadScan) Scan(ctx context.Context, file models.UploadedFile) error {
+	event := models.AuditEvent{
+		Action:  "upload_scan",
+		Subject: file.Name,
+		Actor:   file.UploadedBy,
+		Outcome: models.AuditAccepted,
+		Details: map[string]interface{}{"size": file.Size},
+	}
+	if event.Subject == "" {
+		event.Subject = filepath.Base(file.Path)
+	}
+
+	var scanned []string
+	var err error
+	for _, scanner := range s.scanners {
+		if err = scanner.Scan(ctx, file); err != nil {
+			event.Details["scanner"] = scanner.Name()
+			break
+		}
+		scanned = append(scanned, scanner.Name())
+	}
+	event.Details["passed"] = scanned
+
+	var rejection *models.ScanRejection
+	switch {
+	case errors.As(err, &rejection):
+		event.Outcome = models.AuditRejected
+		event.Reason = rejection.Reason
+		s.logger.Warn("Upload rejected by scan", "file", event.Subject, "scanner", rejection.Scanner, "reason", rejection.Reason)
+	case err != nil:
+		event.Outcome = models.AuditError
+		event.Reason = err.Error()
+		err = fmt.Errorf("upload scan failed: %w", err)
+	}
+
+	if auditErr := s.audit.Record(event); auditErr != nil {
+		s.logger.Error("Failed to record upload scan", "file", event.Subject, "error", auditErr)
+		if err == nil {
+			// An unrecorded verdict counts as no verdict
+			err = fmt.Errorf("upload scan failed: %w", auditErr)
+		}
+	}
+	return err
+}
+
+// sizeScanner refuses empty files and files over the upload limit
+type sizeScanner struct {
+	max int64
+}
+
+func (sizeScanner) Name() string {
+	return "size"
+}
+
+func (s sizeScanner) Scan(_ context.Context, file models.UploadedFile) error {
+	if file.Size == 0 {
+		return &models.ScanRejection{Scanner: "size", Reason: "file is empty"}
+	}
+	if file.Size > s.max {
+		return &models.ScanRejection{Scanner: "size", Reason: fmt.Sprintf("file is %d bytes, the limit is %d", file.Size, s.max)}
+	}
+	return nil
+}
+
+// typeScanner refuses files whose name has an extension outside the allowed
+// list or whose content sniffs as a MIME type outside the allowed list. An
+// empty list disables its check.
+type typeScanner struct {
+	extensions []string
+	types      []string
+}
+
+func (typeScanner) Name() string {
+	return "type"
+}
+
+func (s typeScanner) Scan(_ context.Context, file models.UploadedFile) error {
+	if ext := strings.ToLower(filepath.Ext(file.Name)); file.Name != "" && len(s.extensions) > 0 && !slices.Contains(s.extensions, ext) {
+		return &models.ScanRejection{Scanner: "type", Reason: fmt.Sprintf("extension %q is not allowed", ext)}
+	}
+	if len(s.types) == 0 {
+		return nil
+	}
+
+	f, err := os.Open(file.Path)
+	if err != nil {
+		return fmt.Errorf("failed to open upload: %w", err)
+	}
+	defer f.Close()
+
+	// DetectContentType considers at most the first 512 bytes
+	head := make([]byte, 512)
+	n, err := io.ReadFull(f, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return fmt.Errorf("failed to read upload: %w", err)
+	}
+	detected, _, err := mime.ParseMediaType(http.DetectContentType(head[:n]))
+	if err != nil {
+		return fmt.Errorf("failed to detect upload type: %w", err)
+	}
+	if !slices.Contains(s.types, detected) {
+		return &models.ScanRejection{Scanner: "type", Reason: fmt.Sprintf("content type %s is not allowed", detected)}
+	}
+	return nil
+}
+
+// splitList parses a comma-separated setting into lowercase entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.ToLower(strings.TrimSpace(item)); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
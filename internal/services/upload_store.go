@@ -0,0 +1,253 @@
+package services
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"analytics-dashboard-api/internal/config"
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/pkg/logger"
+)
+
+// UploadStore keeps resumable upload sessions in a directory. Each session is
+// <id>.json with its size next to <id>.part holding the bytes received so far.
+// The offset is the size of the part file, so sessions survive restarts and a
+// chunk cut off mid-transfer keeps the bytes that arrived.
+type UploadStore struct {
+	mu     sync.Mutex
+	busy   map[string]bool // sessions receiving a chunk
+	cfg    config.UploadConfig
+	logger logger.Logger
+}
+
+// uploadMeta is the part of a session that does not change per chunk
+type uploadMeta struct {
+	ID        string    `json:"id"`
+	Filename  string    `json:"filename,omitempty"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func NewUploadStore(cfg config.UploadConfig, logger logger.Logger) (*UploadStore, error) {
+	if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create upload directory: %w", err)
+	}
+
+	store := &UploadStore{
+		busy:   map[string]bool{},
+		cfg:    cfg,
+		logger: logger,
+	}
+	store.sweep()
+	return store, nil
+}
+
+// Create starts a session for an upload of size bytes. filename is the
+// client's name for the file, if known.
+func (s *UploadStore) Create(size int64, filename string) (*models.Upload, error) {
+	if size <= 0 || size > s.cfg.MaxBytes {
+		return nil, fmt.Errorf("%w: size must be between 1 and %d bytes", models.ErrInvalidUpload, s.cfg.MaxBytes)
+	}
+	s.sweep()
+
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+	meta := uploadMeta{ID: id, Filename: filename, Size: size, CreatedAt: time.Now().UTC()}
+
+	part, err := os.OpenFile(s.partPath(id), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create upload: %w", err)
+	}
+	part.Close()
+
+	if err := saveJSONFile(s.metaPath(id), meta); err != nil {
+		os.Remove(s.partPath(id))
+		return nil, err
+	}
+
+	s.logger.Info("Upload started", "upload", id, "filename", filename, "size", size)
+	return s.Get(id)
+}
+
+// Get returns a session with the number of bytes received so far
+func (s *UploadStore) Get(id string) (*models.Upload, error) {
+	if !validUploadID(id) {
+		return nil, models.ErrUploadNotFound
+	}
+
+	var meta uploadMeta
+	if err := loadJSONFile(s.metaPath(id), &meta); err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(s.partPath(id))
+	if meta.ID == "" || errors.Is(err, os.ErrNotExist) {
+		return nil, models.ErrUploadNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read upload: %w", err)
+	}
+
+	expires := info.ModTime().Add(s.cfg.SessionTTL).UTC()
+	if time.Now().After(expires) {
+		return nil, models.ErrUploadNotFound
+	}
+
+	return &models.Upload{
+		ID:        meta.ID,
+		Filename:  meta.Filename,
+		Size:      meta.Size,
+		Offset:    info.Size(),
+		Complete:  info.Size() == meta.Size,
+		CreatedAt: meta.CreatedAt,
+		UpdatedAt: info.ModTime().UTC(),
+		ExpiresAt: expires,
+	}, nil
+}
+
+// Append writes a chunk starting at offset, which must be the number of
+// bytes already received. Bytes that arrive before r fails are kept, so the
+// client resumes from the returned offset.
+func (s *UploadStore) Append(id string, offset int64, r io.Reader) (*models.Upload, error) {
+	if err := s.acquire(id); err != nil {
+		return nil, err
+	}
+	defer s.release(id)
+
+	upload, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if offset != upload.Offset {
+		return nil, fmt.Errorf("%w: chunk starts at %d, upload is at %d", models.ErrUploadOffset, offset, upload.Offset)
+	}
+
+	part, err := os.OpenFile(s.partPath(id), os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open upload: %w", err)
+	}
+	defer part.Close()
+
+	// Read one byte past the declared size to detect an oversized chunk,
+	// which is dropped whole
+	remaining := upload.Size - upload.Offset
+	written, copyErr := io.Copy(part, io.LimitReader(r, remaining+1))
+	if written > remaining {
+		if err := part.Truncate(upload.Offset); err != nil {
+			return nil, fmt.Errorf("failed to trim upload: %w", err)
+		}
+		return nil, fmt.Errorf("%w: chunk runs past the declared size of %d bytes", models.ErrInvalidUpload, upload.Size)
+	}
+	if err := part.Close(); err != nil && copyErr == nil {
+		copyErr = err
+	}
+
+	upload, err = s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if copyErr != nil {
+		s.logger.Warn("Upload chunk interrupted", "upload", id, "offset", upload.Offset, "error", copyErr)
+		return nil, fmt.Errorf("chunk interrupted at offset %d: %w", upload.Offset, copyErr)
+	}
+	if upload.Complete {
+		s.logger.Info("Upload complete", "upload", id, "size", upload.Size)
+	}
+	return upload, nil
+}
+
+// File returns the file of a complete upload
+func (s *UploadStore) File(id string) (*models.UploadedFile, error) {
+	upload, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if !upload.Complete {
+		return nil, fmt.Errorf("%w: %d of %d bytes received", models.ErrUploadIncomplete, upload.Offset, upload.Size)
+	}
+	return &models.UploadedFile{
+		Path: s.partPath(id),
+		Name: upload.Filename,
+		Size: upload.Size,
+	}, nil
+}
+
+// Delete discards a session and its data
+func (s *UploadStore) Delete(id string) error {
+	if _, err := s.Get(id); err != nil {
+		return err
+	}
+	if err := s.acquire(id); err != nil {
+		return err
+	}
+	defer s.release(id)
+
+	s.remove(id)
+	return nil
+}
+
+func (s *UploadStore) acquire(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.busy[id] {
+		return models.ErrUploadBusy
+	}
+	s.busy[id] = true
+	return nil
+}
+
+func (s *UploadStore) release(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.busy, id)
+}
+
+// sweep removes sessions that have not received data within the TTL
+func (s *UploadStore) sweep() {
+	paths, err := filepath.Glob(filepath.Join(s.cfg.Dir, "*.json"))
+	if err != nil {
+		return
+	}
+	for _, path := range paths {
+		id := strings.TrimSuffix(filepath.Base(path), ".json")
+		if !validUploadID(id) {
+			continue
+		}
+		if _, err := s.Get(id); errors.Is(err, models.ErrUploadNotFound) {
+			s.logger.Info("Removing expired upload", "upload", id)
+			s.remove(id)
+		}
+	}
+}
+
+func (s *UploadStore) remove(id string) {
+	for _, path := range []string{s.partPath(id), s.metaPath(id)} {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			s.logger.Warn("Failed to remove upload file", "path", path, "error", err)
+		}
+	}
+}
+
+func (s *UploadStore) metaPath(id string) string {
+	return filepath.Join(s.cfg.Dir, id+".json")
+}
+
+func (s *UploadStore) partPath(id string) string {
+	return filepath.Join(s.cfg.Dir, id+".part")
+}
+
+// validUploadID accepts the IDs newID generates, so a caller-supplied ID can
+// never name a path outside the upload directory
+func validUploadID(id string) bool {
+	_, err := hex.DecodeString(id)
+	return len(id) == 16 && err == nil
+}
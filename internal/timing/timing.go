@@ -0,0 +1,175 @@
+// Package timing breaks the time a request takes down into phases: waiting
+// for the data, querying the backend and serializing the response. Timings
+// travel in the request context; a request without them records nothing.
+package timing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"analytics-dashboard-api/internal/models"
+)
+
+// Phases a request's time is recorded under
+const (
+	PhaseQueue     = "queue"     // waiting for the data to be loaded
+	PhaseDB        = "db"        // backend queries
+	PhaseSerialize = "serialize" // encoding and writing the response
+	// PhaseSection times a section of a response end to end, its queries
+	// included; sections run concurrently
+	PhaseSection = "section"
+)
+
+// PhaseOther holds the time of a request not spent in queue, db or serialize
+const PhaseOther = "other"
+
+// Span is one timed step of a request, such as a single query
+type Span = models.TimingSpan
+
+// Timings collects the spans of one request. Sections of a request may run
+// concurrently, so it is safe for concurrent use. A nil *Timings records
+// nothing.
+type Timings struct {
+	start time.Time
+
+	mu     sync.Mutex
+	spans  []Span
+	report bool
+}
+
+func New() *Timings {
+	return &Timings{start: time.Now()}
+}
+
+type contextKey struct{}
+
+// WithTimings returns a context carrying t
+func WithTimings(ctx context.Context, t *Timings) context.Context {
+	return context.WithValue(ctx, contextKey{}, t)
+}
+
+// FromContext returns the timings of the request ctx belongs to, nil if it
+// is not timed
+func FromContext(ctx context.Context) *Timings {
+	t, _ := ctx.Value(contextKey{}).(*Timings)
+	return t
+}
+
+// Carrier is a response writer holding the timings of its request, so code
+// that only sees the writer can record into them
+type Carrier interface {
+	Timings() *Timings
+}
+
+// FromWriter returns the timings held by w or a writer it wraps, nil if
+// there are none
+func FromWriter(w http.ResponseWriter) *Timings {
+	for {
+		if carrier, ok := w.(Carrier); ok {
+			return carrier.Timings()
+		}
+		unwrapper, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return nil
+		}
+		w = unwrapper.Unwrap()
+	}
+}
+
+// Since records a span of phase that started at start; meant to be
+// deferred as timing.FromContext(ctx).Since(timing.PhaseDB, name, time.Now())
+func (t *Timings) Since(phase, name string, start time.Time) {
+	if t == nil {
+		return
+	}
+	span := Span{Phase: phase, Name: name, DurationMs: milliseconds(time.Since(start))}
+	t.mu.Lock()
+	t.spans = append(t.spans, span)
+	t.mu.Unlock()
+}
+
+// Report asks for the breakdown to be added to the response
+func (t *Timings) Report() {
+	t.mu.Lock()
+	t.report = true
+	t.mu.Unlock()
+}
+
+// Reported tells whether the response should carry the breakdown
+func (t *Timings) Reported() bool {
+	if t == nil {
+		return false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.report
+}
+
+// Spans returns the spans recorded so far, in the order they ended
+func (t *Timings) Spans() []Span {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]Span(nil), t.spans...)
+}
+
+// Totals sums the spans by phase. Concurrent spans each count in full, so
+// the totals may add up to more than the elapsed time.
+func (t *Timings) Totals() map[string]float64 {
+	totals := map[string]float64{}
+	for _, span := range t.Spans() {
+		totals[span.Phase] += span.DurationMs
+	}
+	return totals
+}
+
+// Elapsed is the time since the timings were created, in milliseconds
+func (t *Timings) Elapsed() float64 {
+	if t == nil {
+		return 0
+	}
+	return milliseconds(time.Since(t.start))
+}
+
+// Breakdown returns the elapsed time, the totals per phase and the spans.
+// The queue, db and serialize totals are always present, and the rest of
+// the elapsed time is put under other.
+func (t *Timings) Breakdown() models.TimingBreakdown {
+	elapsed := t.Elapsed()
+	totals := t.Totals()
+	other := elapsed
+	for _, phase := range []string{PhaseQueue, PhaseDB, PhaseSerialize} {
+		if _, ok := totals[phase]; !ok {
+			totals[phase] = 0
+		}
+		other -= totals[phase]
+	}
+	totals[PhaseOther] = max(other, 0)
+	return models.TimingBreakdown{TotalMs: elapsed, Phases: totals, Spans: t.Spans()}
+}
+
+// ServerTiming formats the phase totals of b as a Server-Timing header value
+func ServerTiming(b models.TimingBreakdown) string {
+	phases := make([]string, 0, len(b.Phases))
+	for phase := range b.Phases {
+		phases = append(phases, phase)
+	}
+	sort.Strings(phases)
+	metrics := make([]string, 0, len(phases)+1)
+	for _, phase := range phases {
+		metrics = append(metrics, fmt.Sprintf("%s;dur=%.3f", phase, b.Phases[phase]))
+	}
+	metrics = append(metrics, fmt.Sprintf("total;dur=%.3f", b.TotalMs))
+	return strings.Join(metrics, ", ")
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
@@ -1,14 +1,96 @@
 package utils
 
 import (
+	"bytes"
 	"encoding/json"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
+
+	"analytics-dashboard-api/internal/timing"
 )
 
+// ErrorCode is a machine-readable error identifier clients can branch on.
+// Messages may be reworded; codes stay the same.
+type ErrorCode string
+
+const (
+	ErrCodeInvalidParam       ErrorCode = "ERR_INVALID_PARAM"  // a query parameter or header is malformed
+	ErrCodeInvalidBody        ErrorCode = "ERR_INVALID_BODY"   // the request body cannot be decoded
+	ErrCodeValidation         ErrorCode = "ERR_VALIDATION"     // the request is well-formed but not acceptable
+	ErrCodeBadRequest         ErrorCode = "ERR_BAD_REQUEST"    // any other client error
+	ErrCodeUnauthorized       ErrorCode = "ERR_UNAUTHORIZED"   // no or an unknown API key or caller identity
+	ErrCodeForbidden          ErrorCode = "ERR_FORBIDDEN"      // e.g. an expired download link
+	ErrCodeNotFound           ErrorCode = "ERR_NOT_FOUND"      // the addressed resource does not exist
+	ErrCodeSourceMissing      ErrorCode = "ERR_SOURCE_MISSING" // a data source file does not exist
+	ErrCodeMethodNotAllowed   ErrorCode = "ERR_METHOD_NOT_ALLOWED"
+	ErrCodeConflict           ErrorCode = "ERR_CONFLICT"
+	ErrCodeQuotaExceeded      ErrorCode = "ERR_QUOTA_EXCEEDED"
+	ErrCodeRateLimited        ErrorCode = "ERR_RATE_LIMITED"
+	ErrCodeDataLoading        ErrorCode = "ERR_DATA_LOADING"       // retry after Retry-After
+	ErrCodeDatasetNotLoaded   ErrorCode = "ERR_DATASET_NOT_LOADED" // the last load failed
+	ErrCodeQueryTimeout       ErrorCode = "ERR_QUERY_TIMEOUT"
+	ErrCodeTimeout            ErrorCode = "ERR_TIMEOUT" // the response timed out waiting for a job
+	ErrCodeNotSupported       ErrorCode = "ERR_NOT_SUPPORTED"
+	ErrCodeServiceUnavailable ErrorCode = "ERR_SERVICE_UNAVAILABLE"
+	ErrCodeInternal           ErrorCode = "ERR_INTERNAL"
+)
+
+// statusErrorCodes are the codes of errors written with a status alone
+var statusErrorCodes = map[int]ErrorCode{
+	http.StatusBadRequest:            ErrCodeInvalidParam,
+	http.StatusUnauthorized:          ErrCodeUnauthorized,
+	http.StatusForbidden:             ErrCodeForbidden,
+	http.StatusNotFound:              ErrCodeNotFound,
+	http.StatusMethodNotAllowed:      ErrCodeMethodNotAllowed,
+	http.StatusConflict:              ErrCodeConflict,
+	http.StatusRequestEntityTooLarge: ErrCodeQuotaExceeded,
+	http.StatusTooManyRequests:       ErrCodeRateLimited,
+	http.StatusNotImplemented:        ErrCodeNotSupported,
+	http.StatusServiceUnavailable:    ErrCodeServiceUnavailable,
+	http.StatusGatewayTimeout:        ErrCodeTimeout,
+}
+
+// StatusErrorCode returns the code an error response with the given status
+// carries when no more specific code is known. Most plain 400s reject a
+// query parameter.
+func StatusErrorCode(statusCode int) ErrorCode {
+	if code, ok := statusErrorCodes[statusCode]; ok {
+		return code
+	}
+	if statusCode < http.StatusInternalServerError {
+		return ErrCodeBadRequest
+	}
+	return ErrCodeInternal
+}
+
 type ErrorResponse struct {
-	Error   string `json:"error"`
-	Message string `json:"message"`
-	Code    int    `json:"code"`
+	Error     string    `json:"error"`
+	Message   string    `json:"message"`
+	Code      int       `json:"code"`
+	ErrorCode ErrorCode `json:"error_code"`
+}
+
+// InvalidParam describes a rejected query parameter
+type InvalidParam struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Reason string `json:"reason"`
+}
+
+// ValidationErrorResponse is an ErrorResponse listing the offending parameters
+type ValidationErrorResponse struct {
+	ErrorResponse
+	InvalidParams []InvalidParam `json:"invalid_params"`
+}
+
+// LoadingResponse is an ErrorResponse telling the caller the dataset is
+// still loading and when to retry
+type LoadingResponse struct {
+	ErrorResponse
+	Status     string `json:"status"`
+	RetryAfter int    `json:"retry_after"` // seconds
 }
 
 type SuccessResponse struct {
@@ -18,6 +100,13 @@ type SuccessResponse struct {
 
 // WriteJSONResponse writes a JSON response
 func WriteJSONResponse(w http.ResponseWriter, statusCode int, data interface{}) {
+	timings := timing.FromWriter(w)
+	if timings.Reported() {
+		writeTimedJSONResponse(w, statusCode, data, timings)
+		return
+	}
+	defer timings.Since(timing.PhaseSerialize, "response", time.Now())
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
 
@@ -26,17 +115,96 @@ func WriteJSONResponse(w http.ResponseWriter, statusCode int, data interface{})
 	}
 }
 
-// WriteErrorResponse writes an error JSON response
+// writeTimedJSONResponse encodes data before writing anything, so the
+// breakdown it adds includes the encoding. JSON objects get the breakdown as
+// debug_timing; the header carries it for any response. Writing the body to
+// the client is the only part not timed.
+func writeTimedJSONResponse(w http.ResponseWriter, statusCode int, data interface{}, timings *timing.Timings) {
+	start := time.Now()
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(data); err != nil {
+		http.Error(w, "Failed to encode JSON response", http.StatusInternalServerError)
+		return
+	}
+	timings.Since(timing.PhaseSerialize, "response", start)
+
+	breakdown := timings.Breakdown()
+	encoded := body.Bytes()
+	if object := bytes.TrimSpace(encoded); len(object) > 1 && object[0] == '{' {
+		if debug, err := json.Marshal(breakdown); err == nil {
+			var timed bytes.Buffer
+			timed.Write(object[:len(object)-1])
+			if len(bytes.TrimSpace(object[1:len(object)-1])) > 0 {
+				timed.WriteByte(',')
+			}
+			timed.WriteString(`"debug_timing":`)
+			timed.Write(debug)
+			timed.WriteString("}\n")
+			encoded = timed.Bytes()
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Server-Timing", timing.ServerTiming(breakdown))
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(statusCode)
+	w.Write(encoded)
+}
+
+// WriteErrorResponse writes an error JSON response with the status's
+// default error code
 func WriteErrorResponse(w http.ResponseWriter, statusCode int, message string) {
+	WriteErrorResponseWithCode(w, statusCode, StatusErrorCode(statusCode), message)
+}
+
+// WriteErrorResponseWithCode writes an error JSON response with a specific
+// error code
+func WriteErrorResponseWithCode(w http.ResponseWriter, statusCode int, code ErrorCode, message string) {
 	response := ErrorResponse{
-		Error:   http.StatusText(statusCode),
-		Message: message,
-		Code:    statusCode,
+		Error:     http.StatusText(statusCode),
+		Message:   message,
+		Code:      statusCode,
+		ErrorCode: code,
 	}
 
 	WriteJSONResponse(w, statusCode, response)
 }
 
+// WriteValidationErrorResponse writes a 400 response listing invalid parameters
+func WriteValidationErrorResponse(w http.ResponseWriter, params []InvalidParam) {
+	response := ValidationErrorResponse{
+		ErrorResponse: ErrorResponse{
+			Error:     http.StatusText(http.StatusBadRequest),
+			Message:   "Invalid query parameters",
+			Code:      http.StatusBadRequest,
+			ErrorCode: ErrCodeInvalidParam,
+		},
+		InvalidParams: params,
+	}
+
+	WriteJSONResponse(w, http.StatusBadRequest, response)
+}
+
+// WriteLoadingResponse writes a 503 with a Retry-After header while the
+// dataset is loading. The delay is rounded up to whole seconds.
+func WriteLoadingResponse(w http.ResponseWriter, retryAfter time.Duration) {
+	seconds := max(int(math.Ceil(retryAfter.Seconds())), 1)
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+
+	response := LoadingResponse{
+		ErrorResponse: ErrorResponse{
+			Error:     http.StatusText(http.StatusServiceUnavailable),
+			Message:   "Data is loading, retry later",
+			Code:      http.StatusServiceUnavailable,
+			ErrorCode: ErrCodeDataLoading,
+		},
+		Status:     "loading",
+		RetryAfter: seconds,
+	}
+
+	WriteJSONResponse(w, http.StatusServiceUnavailable, response)
+}
+
 // WriteSuccessResponse writes a success JSON response
 func WriteSuccessResponse(w http.ResponseWriter, data interface{}) {
 	response := SuccessResponse{
@@ -45,7 +45,7 @@ if [ $? -ne 0 ]; then
 fi
 
 print_status "Building backend..."
-go build -o bin/server cmd/server/main.go
+go build -o bin/server ./cmd/server
 if [ $? -ne 0 ]; then
     print_error "Failed to build backend"
     exit 1
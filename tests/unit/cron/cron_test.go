@@ -0,0 +1,65 @@
+package cron_test
+
+import (
+	"testing"
+	"time"
+
+	"analytics-dashboard-api/internal/cron"
+)
+
+func TestParseErrors(t *testing.T) {
+	for _, expr := range []string{
+		"", "* * * *", "* * * * * *", "60 * * * *", "* 24 * * *", "* * 0 * *",
+		"* * * 13 *", "* * * * 8", "5-1 * * * *", "*/0 * * * *", "a * * * *", "@often",
+	} {
+		if _, err := cron.Parse(expr); err == nil {
+			t.Errorf("Parse(%q) succeeded, want an error", expr)
+		}
+	}
+}
+
+func TestScheduleNext(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("no timezone data:", err)
+	}
+	at := func(loc *time.Location, year int, month time.Month, day, hour, minute int) time.Time {
+		return time.Date(year, month, day, hour, minute, 0, 0, loc)
+	}
+
+	cases := []struct {
+		expr string
+		from time.Time
+		want time.Time
+	}{
+		{"0 2 * * *", at(time.UTC, 2024, 5, 10, 1, 30), at(time.UTC, 2024, 5, 10, 2, 0)},
+		{"0 2 * * *", at(time.UTC, 2024, 5, 10, 2, 0), at(time.UTC, 2024, 5, 11, 2, 0)},
+		{"*/15 * * * *", at(time.UTC, 2024, 5, 10, 1, 7), at(time.UTC, 2024, 5, 10, 1, 15)},
+		{"30 9-17/4 * * mon-fri", at(time.UTC, 2024, 5, 10, 18, 0), at(time.UTC, 2024, 5, 13, 9, 30)},
+		{"0 0 1 jan,jul *", at(time.UTC, 2024, 2, 1, 0, 0), at(time.UTC, 2024, 7, 1, 0, 0)},
+		{"0 0 29 2 *", at(time.UTC, 2024, 3, 1, 0, 0), at(time.UTC, 2028, 2, 29, 0, 0)},
+		{"0 0 * * 7", at(time.UTC, 2024, 5, 10, 0, 0), at(time.UTC, 2024, 5, 12, 0, 0)},
+		// Day of month or day of week when both are restricted
+		{"0 0 15 * 1", at(time.UTC, 2024, 5, 10, 0, 0), at(time.UTC, 2024, 5, 13, 0, 0)},
+		{"0 0 */10 * *", at(time.UTC, 2024, 5, 10, 0, 0), at(time.UTC, 2024, 5, 11, 0, 0)},
+		{"@monthly", at(time.UTC, 2024, 5, 10, 0, 0), at(time.UTC, 2024, 6, 1, 0, 0)},
+		// 02:30 does not exist on the day clocks go forward
+		{"30 2 * * *", at(berlin, 2024, 3, 30, 12, 0), at(berlin, 2024, 4, 1, 2, 30)},
+		{"0 3 * * *", at(berlin, 2024, 3, 30, 12, 0), at(berlin, 2024, 3, 31, 3, 0)},
+	}
+	for _, tc := range cases {
+		schedule, err := cron.Parse(tc.expr)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tc.expr, err)
+			continue
+		}
+		if got := schedule.Next(tc.from); !got.Equal(tc.want) {
+			t.Errorf("%q after %v = %v, want %v", tc.expr, tc.from, got, tc.want)
+		}
+	}
+
+	never, _ := cron.Parse("0 0 30 2 *")
+	if got := never.Next(at(time.UTC, 2024, 1, 1, 0, 0)); !got.IsZero() {
+		t.Errorf("February 30th runs at %v, want never", got)
+	}
+}
@@ -0,0 +1,95 @@
+package expr_test
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"analytics-dashboard-api/internal/expr"
+)
+
+func TestParseAndEval(t *testing.T) {
+	vars := map[string]float64{
+		"revenue":      1000,
+		"transactions": 40,
+		"quantity":     120,
+	}
+
+	tests := []struct {
+		name       string
+		expression string
+		want       float64
+	}{
+		{"ratio", "revenue / transactions", 25},
+		{"constant factor", "revenue * 0.27", 270},
+		{"precedence", "revenue - quantity * 2", 760},
+		{"parentheses", "(revenue - quantity) * 2", 1760},
+		{"unary minus", "-revenue + 1", -999},
+		{"left associative", "revenue / 10 / 4", 25},
+		{"number only", "42", 42},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := expr.Parse(tt.expression)
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.expression, err)
+			}
+			got, err := e.Eval(vars)
+			if err != nil {
+				t.Fatalf("Eval() error = %v", err)
+			}
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("Eval() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []string{
+		"",
+		"revenue +",
+		"(revenue",
+		"revenue)",
+		"revenue % 2",
+		"os.Exit(1)",
+		"revenue transactions",
+		"1.2.3",
+		strings.Repeat("(", 40) + "1" + strings.Repeat(")", 40),
+	}
+
+	for _, expression := range tests {
+		if _, err := expr.Parse(expression); !errors.Is(err, expr.ErrSyntax) {
+			t.Errorf("Parse(%q) error = %v, want ErrSyntax", expression, err)
+		}
+	}
+}
+
+func TestEvalErrors(t *testing.T) {
+	e, err := expr.Parse("revenue / transactions")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	if _, err := e.Eval(map[string]float64{"revenue": 1, "transactions": 0}); !errors.Is(err, expr.ErrDivisionByZero) {
+		t.Errorf("Eval() error = %v, want ErrDivisionByZero", err)
+	}
+	if _, err := e.Eval(map[string]float64{"revenue": 1}); !errors.Is(err, expr.ErrUnknownVariable) {
+		t.Errorf("Eval() error = %v, want ErrUnknownVariable", err)
+	}
+}
+
+func TestVariables(t *testing.T) {
+	e, err := expr.Parse("(revenue - cost) / revenue")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	want := []string{"revenue", "cost"}
+	if got := e.Variables(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Variables() = %v, want %v", got, want)
+	}
+}
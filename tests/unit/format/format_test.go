@@ -0,0 +1,94 @@
+package format_test
+
+import (
+	"testing"
+
+	"analytics-dashboard-api/internal/format"
+	"analytics-dashboard-api/internal/models"
+)
+
+func mustLookup(t *testing.T, tag string) format.Locale {
+	t.Helper()
+	locale, ok := format.Lookup(tag)
+	if !ok {
+		t.Fatalf("Lookup(%q) not found", tag)
+	}
+	return locale
+}
+
+func TestFormatter_Money(t *testing.T) {
+	tests := []struct {
+		locale   string
+		currency string
+		amount   models.Money
+		want     string
+	}{
+		{"en-US", "USD", 123456789, "$1,234,567.89"},
+		{"en-US", "USD", -5, "-$0.05"},
+		{"de-DE", "EUR", 123450, "1.234,50\u00a0€"},
+		{"fr-FR", "EUR", 123450, "1\u202f234,50\u00a0€"},
+		{"en-IN", "INR", 123456789, "₹12,34,567.89"},
+		{"ja-JP", "JPY", 123450, "¥1,235"},
+		{"pt-BR", "BRL", 99, "R$\u00a00,99"},
+		{"en-US", "XYZ", 100, "XYZ\u00a01.00"},
+	}
+
+	for _, tt := range tests {
+		f := format.NewFormatter(mustLookup(t, tt.locale), tt.currency)
+		if got := f.Money(tt.amount); got != tt.want {
+			t.Errorf("%s %s Money(%d) = %q, want %q", tt.locale, tt.currency, int64(tt.amount), got, tt.want)
+		}
+	}
+}
+
+func TestFormatter_Number(t *testing.T) {
+	de := format.NewFormatter(mustLookup(t, "de-DE"), "EUR")
+	if got := de.Number(1234567.891, 1); got != "1.234.567,9" {
+		t.Errorf("Number() = %q, want %q", got, "1.234.567,9")
+	}
+	if got := de.Number(-0.001, 2); got != "0,00" {
+		t.Errorf("Number() = %q, want %q", got, "0,00")
+	}
+}
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want string
+		ok   bool
+	}{
+		{"de-DE", "de-DE", true},
+		{"DE_de", "de-DE", true},
+		{"de-AT", "de-DE", true},
+		{"en", "en-US", true},
+		{"xx-YY", "", false},
+		{"", "", false},
+	}
+
+	for _, tt := range tests {
+		locale, ok := format.Lookup(tt.tag)
+		if ok != tt.ok || locale.Tag != tt.want {
+			t.Errorf("Lookup(%q) = %q, %v; want %q, %v", tt.tag, locale.Tag, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+		ok     bool
+	}{
+		{"fr-CH, fr;q=0.9, en;q=0.8", "fr-FR", true},
+		{"xx, de;q=0.5, en;q=0.7", "en-US", true},
+		{"*", "", false},
+		{"", "", false},
+	}
+
+	for _, tt := range tests {
+		locale, ok := format.Negotiate(tt.header)
+		if ok != tt.ok || locale.Tag != tt.want {
+			t.Errorf("Negotiate(%q) = %q, %v; want %q, %v", tt.header, locale.Tag, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,920 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"analytics-dashboard-api/internal/config"
+	"analytics-dashboard-api/internal/handlers"
+	"analytics-dashboard-api/internal/models"
+
+	"github.com/gorilla/mux"
+)
+
+// fakeAnalytics is an in-memory AnalyticsService
+type fakeAnalytics struct {
+	countries    []models.CountryRevenue
+	products     []models.ProductFrequency
+	regions      []models.RegionRevenue
+	regionsErr   error
+	regionSort   string // sort_by of the last GetTopRegions
+	months       []models.MonthlySales
+	totals       map[string]float64 // base metrics without grouping
+	recordCounts int
+	customers    []models.CustomerSpend
+	customerOpts models.QueryOptions // options of the last GetTopCustomers
+}
+
+func (f *fakeAnalytics) GetCountryRevenue(_ context.Context, _ models.QueryOptions, limit, offset int) ([]models.CountryRevenue, *models.Totals, error) {
+	totals := &models.Totals{}
+	for _, c := range f.countries {
+		totals.Revenue += c.TotalRevenue
+		totals.Transactions += c.TransactionCount
+	}
+	if offset >= len(f.countries) {
+		return []models.CountryRevenue{}, totals, nil
+	}
+	end := offset + limit
+	if end > len(f.countries) {
+		end = len(f.countries)
+	}
+	return f.countries[offset:end], totals, nil
+}
+
+func (f *fakeAnalytics) GetTopProducts(context.Context, models.QueryOptions, string, int, int) ([]models.ProductFrequency, error) {
+	return slices.Clone(f.products), nil
+}
+
+func (f *fakeAnalytics) GetTopProductsCount(context.Context, models.QueryOptions) (int, error) {
+	return len(f.products), nil
+}
+
+func (f *fakeAnalytics) GetMonthlySales(context.Context, models.QueryOptions) ([]models.MonthlySales, error) {
+	return slices.Clone(f.months), nil
+}
+
+func (f *fakeAnalytics) GetTopRegions(_ context.Context, _ models.QueryOptions, sortBy string, limit, offset int) ([]models.RegionRevenue, error) {
+	f.regionSort = sortBy
+	start := min(offset, len(f.regions))
+	return f.regions[start:min(start+limit, len(f.regions))], f.regionsErr
+}
+
+func (f *fakeAnalytics) GetTopRegionsCount(context.Context, models.QueryOptions) (int, error) {
+	return len(f.regions), nil
+}
+
+func (f *fakeAnalytics) GetTotalRecords(context.Context) (int, error) {
+	f.recordCounts++
+	return len(f.countries), nil
+}
+
+func (f *fakeAnalytics) GetCountryRevenueCount(context.Context, models.QueryOptions) (int, error) {
+	return len(f.countries), nil
+}
+
+func (f *fakeAnalytics) GetDistinctCounts(context.Context) (models.DistinctCounts, error) {
+	return models.DistinctCounts{}, nil
+}
+
+func (f *fakeAnalytics) GetSegmentBreakdown(context.Context, models.QueryOptions) ([]models.SegmentRevenue, error) {
+	return nil, nil
+}
+
+func (f *fakeAnalytics) GetTopCustomers(_ context.Context, opts models.QueryOptions, limit int) ([]models.CustomerSpend, error) {
+	f.customerOpts = opts
+	return f.customers[:min(limit, len(f.customers))], nil
+}
+
+func (f *fakeAnalytics) GetRepeatPurchase(context.Context) (models.RepeatPurchase, error) {
+	repeat := models.RepeatPurchase{Customers: len(f.customers)}
+	for _, c := range f.customers {
+		if c.OrderCount > 1 {
+			repeat.RepeatCustomers++
+		}
+	}
+	if repeat.Customers > 0 {
+		repeat.Rate = float64(repeat.RepeatCustomers) / float64(repeat.Customers)
+	}
+	return repeat, nil
+}
+
+func (f *fakeAnalytics) GetBaseMetrics(_ context.Context, _ models.QueryOptions, groupBy string) ([]models.MetricRow, error) {
+	if f.totals == nil || groupBy != "" {
+		return nil, nil
+	}
+	return []models.MetricRow{{Values: f.totals}}, nil
+}
+
+func (f *fakeAnalytics) DataCoverage() models.DataCoverage {
+	return models.DataCoverage{Records: len(f.countries)}
+}
+
+// fakeBackups rejects every backup operation
+type fakeBackups struct{}
+
+func (fakeBackups) Backup(context.Context, string) (*models.BackupInfo, error) {
+	return nil, errors.New("not supported")
+}
+
+func (fakeBackups) Restore(context.Context, string, string) (*models.BackupInfo, error) {
+	return nil, models.ErrBackupNotFound
+}
+
+// fakeLoader counts loads and optionally fails them
+type fakeLoader struct {
+	err     error
+	loads   int
+	reloads int
+}
+
+func (f *fakeLoader) EnsureInitialized(context.Context) error {
+	f.loads++
+	return f.err
+}
+
+func (f *fakeLoader) StartReload(time.Duration, func(context.Context) error) models.JobInfo {
+	f.reloads++
+	return models.JobInfo{ID: 7, Kind: "refresh", Status: models.JobQueued}
+}
+
+func (f *fakeLoader) WaitJob(context.Context, uint64) (models.JobInfo, error) {
+	if f.err != nil {
+		return models.JobInfo{ID: 7, Kind: "refresh", Status: models.JobFailed, Error: f.err.Error()}, f.err
+	}
+	return models.JobInfo{ID: 7, Kind: "refresh", Status: models.JobSucceeded}, nil
+}
+
+func (f *fakeLoader) RefreshStatus() models.RefreshStatus {
+	return models.RefreshStatus{DataVersion: f.Version()}
+}
+
+func (f *fakeLoader) MarkLoaded() {}
+
+func (f *fakeLoader) Version() uint64 {
+	return uint64(f.reloads)
+}
+
+func (f *fakeLoader) RunJob(ctx context.Context, _ string, fn func(context.Context) error) error {
+	return fn(ctx)
+}
+
+func (f *fakeLoader) Rollback(context.Context) (*models.DataSnapshot, error) {
+	return nil, models.ErrNoPreviousVersion
+}
+
+func (f *fakeLoader) Inspect(context.Context, time.Duration) (*models.SourceInspection, error) {
+	return &models.SourceInspection{Source: "data.csv", Valid: f.err == nil}, f.err
+}
+
+// noMetrics has no derived metrics registered
+type noMetrics struct{}
+
+func (noMetrics) List() []models.DerivedMetric { return nil }
+
+func (noMetrics) Evaluate([]string, map[string]float64) map[string]*float64 { return nil }
+
+func newTestAnalyticsHandler(loader *fakeLoader) *handlers.AnalyticsHandler {
+	analytics := &fakeAnalytics{countries: []models.CountryRevenue{
+		{Country: "Germany", ProductName: "Widget", TotalRevenue: 150000, TransactionCount: 3},
+		{Country: "France", ProductName: "Gadget", TotalRevenue: 99950, TransactionCount: 2},
+		{Country: "Spain", ProductName: "Widget", TotalRevenue: 1000, TransactionCount: 1},
+	}}
+	return handlers.NewAnalyticsHandler(analytics, fakeBackups{}, loader, noMetrics{}, nil, nil, &mockLogger{}, config.BackupConfig{})
+}
+
+func TestAnalyticsHandler_GetCountryRevenue(t *testing.T) {
+	loader := &fakeLoader{}
+	handler := newTestAnalyticsHandler(loader)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/analytics/country-revenue?limit=2&offset=1", nil)
+	recorder := httptest.NewRecorder()
+	handler.GetCountryRevenue(recorder, req)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("GetCountryRevenue() status = %d, want %d", recorder.Code, http.StatusOK)
+	}
+	if loader.loads != 1 {
+		t.Errorf("EnsureInitialized called %d times, want 1", loader.loads)
+	}
+
+	var response struct {
+		Data    []models.CountryRevenue `json:"data"`
+		Total   int                     `json:"total"`
+		HasMore bool                    `json:"has_more"`
+	}
+	if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(response.Data) != 2 || response.Data[0].Country != "France" {
+		t.Errorf("GetCountryRevenue() data = %+v, want France and Spain", response.Data)
+	}
+	if response.Total != 3 || response.HasMore {
+		t.Errorf("GetCountryRevenue() total = %d, has_more = %v, want 3, false", response.Total, response.HasMore)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/analytics/country-revenue?limit=1&include_totals=true", nil)
+	recorder = httptest.NewRecorder()
+	handler.GetCountryRevenue(recorder, req)
+
+	var withTotals struct {
+		Totals *models.Totals `json:"totals"`
+	}
+	if err := json.NewDecoder(recorder.Body).Decode(&withTotals); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if withTotals.Totals == nil || withTotals.Totals.Revenue != 250950 || withTotals.Totals.Transactions != 6 {
+		t.Errorf("GetCountryRevenue() totals = %+v, want 2509.50 over 6 transactions", withTotals.Totals)
+	}
+}
+
+func TestAnalyticsHandler_TopRegionsIncludeOther(t *testing.T) {
+	analytics := &fakeAnalytics{
+		regions: []models.RegionRevenue{
+			{Region: "Bavaria", TotalRevenue: 150000, ItemsSold: 30},
+			{Region: "Texas", TotalRevenue: 99950, ItemsSold: 20},
+		},
+		totals: map[string]float64{"revenue": 3000.5, "transactions": 12, "quantity": 75},
+	}
+	handler := handlers.NewAnalyticsHandler(analytics, fakeBackups{}, &fakeLoader{}, noMetrics{}, nil, nil, &mockLogger{}, config.BackupConfig{})
+
+	for _, tt := range []struct {
+		query string
+		want  int
+	}{
+		{"", 2},
+		{"?include_other=false", 2},
+		{"?include_other=true", 3},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/analytics/top-regions"+tt.query, nil)
+		recorder := httptest.NewRecorder()
+		handler.GetTopRegions(recorder, req)
+
+		var response struct {
+			Data []models.RegionRevenue `json:"data"`
+		}
+		if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if len(response.Data) != tt.want {
+			t.Fatalf("GetTopRegions(%q) data = %+v, want %d rows", tt.query, response.Data, tt.want)
+		}
+		if tt.want == 3 {
+			other := response.Data[2]
+			if !other.Other || other.Region != "Other" || other.TotalRevenue != 50100 || other.ItemsSold != 25 {
+				t.Errorf("Other row = %+v, want the remaining 501.00 and 25 units", other)
+			}
+		}
+	}
+
+	// The totals cover every region, not just the page
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/analytics/top-regions?limit=1&include_totals=true", nil)
+	recorder := httptest.NewRecorder()
+	handler.GetTopRegions(recorder, req)
+	var withTotals struct {
+		Totals *models.Totals `json:"totals"`
+	}
+	if err := json.NewDecoder(recorder.Body).Decode(&withTotals); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if withTotals.Totals == nil || withTotals.Totals.Revenue != 300050 || withTotals.Totals.Transactions != 12 || withTotals.Totals.Quantity != 75 {
+		t.Errorf("GetTopRegions() totals = %+v, want 3000.50 over 12 transactions and 75 units", withTotals.Totals)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/analytics/top-regions?include_other=yes", nil)
+	recorder = httptest.NewRecorder()
+	handler.GetTopRegions(recorder, req)
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("GetTopRegions(include_other=yes) status = %d, want %d", recorder.Code, http.StatusBadRequest)
+	}
+}
+
+func TestAnalyticsHandler_TopProductsFormatted(t *testing.T) {
+	analytics := &fakeAnalytics{
+		products: []models.ProductFrequency{{ProductID: "P1", ProductName: "Widget", PurchaseCount: 3, TotalRevenue: 123456}},
+		totals:   map[string]float64{"revenue": 2000, "transactions": 4, "quantity": 5},
+	}
+	handler := handlers.NewAnalyticsHandler(analytics, fakeBackups{}, &fakeLoader{}, noMetrics{}, nil, nil, &mockLogger{}, config.BackupConfig{})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/analytics/top-products?locale=de-DE&include_other=true&include_totals=true", nil)
+	recorder := httptest.NewRecorder()
+	handler.GetTopProducts(recorder, req)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("GetTopProducts() status = %d, want %d", recorder.Code, http.StatusOK)
+	}
+	var response struct {
+		Data   []models.ProductFrequency `json:"data"`
+		Totals *models.Totals            `json:"totals"`
+		Locale string                    `json:"locale"`
+	}
+	if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(response.Data) != 2 || response.Locale != "de-DE" {
+		t.Fatalf("GetTopProducts() = %+v in %q, want the product and Other in de-DE", response.Data, response.Locale)
+	}
+	for _, p := range response.Data {
+		if p.Display["total_revenue"] == "" {
+			t.Errorf("product %s not formatted: %+v", p.ProductName, p)
+		}
+	}
+	if response.Totals == nil || response.Totals.Display["revenue"] == "" {
+		t.Errorf("GetTopProducts() totals = %+v, want display strings", response.Totals)
+	}
+
+	recorder = httptest.NewRecorder()
+	handler.GetTopProducts(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/analytics/top-products?locale=xx-bogus", nil))
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("GetTopProducts(locale=xx-bogus) status = %d, want %d", recorder.Code, http.StatusBadRequest)
+	}
+
+	// The dashboard summary formats its top products too
+	recorder = httptest.NewRecorder()
+	handler.GetAnalytics(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/analytics?include=top_products&locale=de-DE", nil))
+	var summary struct {
+		TopProducts []models.ProductFrequency `json:"top_products"`
+	}
+	if err := json.NewDecoder(recorder.Body).Decode(&summary); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(summary.TopProducts) != 1 || summary.TopProducts[0].Display["total_revenue"] == "" {
+		t.Errorf("GetAnalytics() top_products = %+v, want display strings", summary.TopProducts)
+	}
+}
+
+func TestAnalyticsHandler_TopRegionsPaging(t *testing.T) {
+	analytics := &fakeAnalytics{
+		regions: []models.RegionRevenue{
+			{Region: "Bavaria", TotalRevenue: 150000, ItemsSold: 30},
+			{Region: "Texas", TotalRevenue: 99950, ItemsSold: 20},
+			{Region: "Normandy", TotalRevenue: 5000, ItemsSold: 40},
+		},
+	}
+	handler := handlers.NewAnalyticsHandler(analytics, fakeBackups{}, &fakeLoader{}, noMetrics{}, nil, nil, &mockLogger{}, config.BackupConfig{})
+
+	for _, tt := range []struct {
+		query    string
+		sortBy   string
+		regions  []string
+		hasMore  bool
+		limit    int
+		wantCode int
+	}{
+		{"", "revenue", []string{"Bavaria", "Texas", "Normandy"}, false, 30, http.StatusOK},
+		{"?limit=2", "revenue", []string{"Bavaria", "Texas"}, true, 2, http.StatusOK},
+		{"?limit=2&offset=2&sort_by=purchase_count", "purchase_count", []string{"Normandy"}, false, 2, http.StatusOK},
+		{"?sort_by=stock", "", nil, false, 0, http.StatusBadRequest},
+	} {
+		analytics.regionSort = ""
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/analytics/top-regions"+tt.query, nil)
+		recorder := httptest.NewRecorder()
+		handler.GetTopRegions(recorder, req)
+		if recorder.Code != tt.wantCode {
+			t.Fatalf("GetTopRegions(%q) status = %d, want %d", tt.query, recorder.Code, tt.wantCode)
+		}
+		if tt.wantCode != http.StatusOK {
+			continue
+		}
+
+		var response struct {
+			Data    []models.RegionRevenue `json:"data"`
+			Total   int                    `json:"total"`
+			Limit   int                    `json:"limit"`
+			HasMore bool                   `json:"has_more"`
+			SortBy  string                 `json:"sort_by"`
+		}
+		if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		var regions []string
+		for _, rr := range response.Data {
+			regions = append(regions, rr.Region)
+		}
+		if !slices.Equal(regions, tt.regions) || response.Total != 3 || response.Limit != tt.limit || response.HasMore != tt.hasMore {
+			t.Errorf("GetTopRegions(%q) = %v, total %d, limit %d, has_more %v; want %v, 3, %d, %v",
+				tt.query, regions, response.Total, response.Limit, response.HasMore, tt.regions, tt.limit, tt.hasMore)
+		}
+		if response.SortBy != tt.sortBy || analytics.regionSort != tt.sortBy {
+			t.Errorf("GetTopRegions(%q) sorted by %q (service %q), want %q", tt.query, response.SortBy, analytics.regionSort, tt.sortBy)
+		}
+	}
+}
+
+func TestAnalyticsHandler_PartialResponse(t *testing.T) {
+	analytics := &fakeAnalytics{
+		countries:  []models.CountryRevenue{{Country: "Germany", ProductName: "Widget", TotalRevenue: 1000, TransactionCount: 1}},
+		regionsErr: fmt.Errorf("failed to query top regions: %w", models.ErrQueryTimeout),
+	}
+	handler := handlers.NewAnalyticsHandler(analytics, fakeBackups{}, &fakeLoader{}, noMetrics{}, nil, nil, &mockLogger{}, config.BackupConfig{})
+
+	recorder := httptest.NewRecorder()
+	handler.GetAnalytics(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/analytics", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("GetAnalytics() status = %d, want %d", recorder.Code, http.StatusOK)
+	}
+
+	var response struct {
+		CountryRevenue []models.CountryRevenue `json:"country_revenue"`
+		Partial        bool                    `json:"partial"`
+		Errors         []models.SectionError   `json:"errors"`
+	}
+	if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(response.CountryRevenue) != 1 || !response.Partial {
+		t.Errorf("GetAnalytics() = %+v, want country revenue and partial", response)
+	}
+	want := models.SectionError{Section: "top_regions", Status: http.StatusGatewayTimeout, ErrorCode: "ERR_QUERY_TIMEOUT", Message: "Failed to get analytics section: query timed out"}
+	if len(response.Errors) != 1 || response.Errors[0] != want {
+		t.Errorf("GetAnalytics() errors = %+v, want %+v", response.Errors, want)
+	}
+
+	recorder = httptest.NewRecorder()
+	handler.GetAnalytics(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/analytics?partial=false", nil))
+	if recorder.Code != http.StatusGatewayTimeout {
+		t.Errorf("GetAnalytics(partial=false) status = %d, want %d", recorder.Code, http.StatusGatewayTimeout)
+	}
+}
+
+func TestAnalyticsHandler_Include(t *testing.T) {
+	analytics := &fakeAnalytics{regionsErr: errors.New("top regions must not run")}
+	handler := handlers.NewAnalyticsHandler(analytics, fakeBackups{}, &fakeLoader{}, noMetrics{}, nil, nil, &mockLogger{}, config.BackupConfig{})
+
+	recorder := httptest.NewRecorder()
+	handler.GetAnalytics(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/analytics?include=summary,monthly_sales", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("GetAnalytics() status = %d, want %d", recorder.Code, http.StatusOK)
+	}
+	var response map[string]interface{}
+	if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	for _, key := range []string{"summary", "monthly_sales"} {
+		if _, ok := response[key]; !ok {
+			t.Errorf("GetAnalytics() missing included section %s", key)
+		}
+	}
+	for _, key := range []string{"country_revenue", "top_products", "top_regions", "errors"} {
+		if _, ok := response[key]; ok {
+			t.Errorf("GetAnalytics() returned excluded key %s", key)
+		}
+	}
+
+	recorder = httptest.NewRecorder()
+	handler.GetAnalytics(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/analytics?include=forecast", nil))
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("GetAnalytics(include=forecast) status = %d, want %d", recorder.Code, http.StatusBadRequest)
+	}
+}
+
+func TestAnalyticsHandler_Lite(t *testing.T) {
+	analytics := &fakeAnalytics{regionsErr: errors.New("top regions must not run")}
+	for month := 1; month <= 18; month++ {
+		analytics.months = append(analytics.months, models.MonthlySales{
+			Month:       time.Date(2023, time.Month(month), 1, 0, 0, 0, 0, time.UTC).Format("2006-01"),
+			SalesVolume: 100,
+		})
+	}
+	handler := handlers.NewAnalyticsHandler(analytics, fakeBackups{}, &fakeLoader{}, noMetrics{}, nil, nil, &mockLogger{}, config.BackupConfig{})
+
+	recorder := httptest.NewRecorder()
+	handler.GetAnalytics(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/analytics?lite=true", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("GetAnalytics(lite) status = %d, want %d", recorder.Code, http.StatusOK)
+	}
+	var response struct {
+		Summary        map[string]interface{} `json:"summary"`
+		MonthlySales   []models.MonthlySales  `json:"monthly_sales"`
+		CountryRevenue json.RawMessage        `json:"country_revenue"`
+	}
+	if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(response.MonthlySales) != 12 || response.MonthlySales[0].Month != "2023-07" || response.MonthlySales[11].Month != "2024-06" {
+		t.Errorf("monthly_sales = %+v, want the last 12 months", response.MonthlySales)
+	}
+	if response.Summary["total_revenue"] != 18.0 || response.Summary["monthly_sales_count"] != 18.0 {
+		t.Errorf("summary = %v, want totals over all 18 months", response.Summary)
+	}
+	if response.CountryRevenue != nil || analytics.recordCounts != 1 {
+		t.Errorf("lite ran %d record counts and returned country revenue %s, want the summary queries only", analytics.recordCounts, response.CountryRevenue)
+	}
+
+	// Any spelling of true that selects the lite sections also trims the months
+	recorder = httptest.NewRecorder()
+	handler.GetAnalytics(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/analytics?lite=1", nil))
+	response.MonthlySales = nil
+	if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(response.MonthlySales) != 12 {
+		t.Errorf("GetAnalytics(lite=1) returned %d months, want 12", len(response.MonthlySales))
+	}
+
+	recorder = httptest.NewRecorder()
+	handler.GetAnalytics(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/analytics?lite=true&include=summary", nil))
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("GetAnalytics(lite, include) status = %d, want %d", recorder.Code, http.StatusBadRequest)
+	}
+}
+
+func TestAnalyticsHandler_StatsCachedPerDataVersion(t *testing.T) {
+	analytics := &fakeAnalytics{countries: []models.CountryRevenue{{Country: "Germany", ProductName: "Widget"}}}
+	loader := &fakeLoader{}
+	handler := handlers.NewAnalyticsHandler(analytics, fakeBackups{}, loader, noMetrics{}, nil, nil, &mockLogger{}, config.BackupConfig{})
+
+	getStats := func() map[string]interface{} {
+		t.Helper()
+		recorder := httptest.NewRecorder()
+		handler.GetAnalyticsStats(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/analytics/stats", nil))
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("GetAnalyticsStats() status = %d, want %d", recorder.Code, http.StatusOK)
+		}
+		var stats map[string]interface{}
+		if err := json.NewDecoder(recorder.Body).Decode(&stats); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return stats
+	}
+
+	first := getStats()
+	second := getStats()
+	if first["cache_hit"] != false || second["cache_hit"] != true || analytics.recordCounts != 1 {
+		t.Errorf("cache_hit = %v then %v after %d count queries, want false then true after 1",
+			first["cache_hit"], second["cache_hit"], analytics.recordCounts)
+	}
+	if first["country_revenue_count"] != float64(1) || first["top_products_count"] != float64(0) {
+		t.Errorf("GetAnalyticsStats() counts = %v", first)
+	}
+
+	// A reload changes the data version and invalidates the cache
+	loader.reloads++
+	third := getStats()
+	if third["cache_hit"] != false || third["data_version"] != float64(1) || analytics.recordCounts != 2 {
+		t.Errorf("after reload: cache_hit = %v, data_version = %v, count queries = %d",
+			third["cache_hit"], third["data_version"], analytics.recordCounts)
+	}
+}
+
+func TestAnalyticsHandler_SummaryCachedPerDataVersion(t *testing.T) {
+	analytics := &fakeAnalytics{
+		countries: []models.CountryRevenue{{Country: "Germany", ProductName: "Widget", TotalRevenue: 1250}},
+		regions:   []models.RegionRevenue{{Region: "Europe", TotalRevenue: 1250}},
+	}
+	loader := &fakeLoader{}
+	handler := handlers.NewAnalyticsHandler(analytics, fakeBackups{}, loader, noMetrics{}, nil, nil, &mockLogger{}, config.BackupConfig{})
+
+	getSummary := func(query string) map[string]interface{} {
+		t.Helper()
+		recorder := httptest.NewRecorder()
+		handler.GetAnalytics(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/analytics"+query, nil))
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("GetAnalytics(%q) status = %d, want %d", query, recorder.Code, http.StatusOK)
+		}
+		var response map[string]interface{}
+		if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return response
+	}
+	cacheHit := func(response map[string]interface{}) interface{} {
+		return response["summary"].(map[string]interface{})["cache_hit"]
+	}
+
+	first := getSummary("")
+	formatted := getSummary("?locale=de-DE")
+	if cacheHit(first) != false || cacheHit(formatted) != true || analytics.recordCounts != 1 {
+		t.Errorf("cache_hit = %v then %v after %d count queries, want false then true after 1",
+			cacheHit(first), cacheHit(formatted), analytics.recordCounts)
+	}
+	// Formatting a cached summary leaves the cached sections as they were
+	plain := getSummary("")
+	if row := plain["country_revenue"].([]interface{})[0].(map[string]interface{}); row["display"] != nil {
+		t.Errorf("country revenue after a formatted hit = %v, want no display", row)
+	}
+
+	// Other filters and sections are cached apart
+	if response := getSummary("?country=Germany"); cacheHit(response) != false {
+		t.Error("filtered summary was a cache hit")
+	}
+	if response := getSummary("?include=summary"); cacheHit(response) != false {
+		t.Error("summary with other sections was a cache hit")
+	}
+
+	// A reload invalidates the cache, and partial results are not kept
+	loader.reloads++
+	analytics.regionsErr = errors.New("regions unavailable")
+	counts := analytics.recordCounts
+	getSummary("")
+	analytics.regionsErr = nil
+	if response := getSummary(""); cacheHit(response) != false || analytics.recordCounts != counts+2 {
+		t.Errorf("after reload and a partial result: cache_hit = %v, count queries = %d, want false and %d",
+			cacheHit(response), analytics.recordCounts, counts+2)
+	}
+
+	stats := handler.SummaryCacheStats()
+	if stats.Hits != 2 || stats.Misses != 5 || stats.Entries != 1 {
+		t.Errorf("SummaryCacheStats() = %+v, want 2 hits, 5 misses, 1 entry", stats)
+	}
+}
+
+func TestAnalyticsHandler_TopCustomers(t *testing.T) {
+	analytics := &fakeAnalytics{customers: []models.CustomerSpend{
+		{UserID: "U1", TotalSpend: 2520, OrderCount: 2, AvgOrderValue: 1260},
+		{UserID: "U2", TotalSpend: 1010, OrderCount: 1, AvgOrderValue: 1010},
+	}}
+	handler := handlers.NewAnalyticsHandler(analytics, fakeBackups{}, &fakeLoader{}, noMetrics{}, nil, nil, &mockLogger{}, config.BackupConfig{})
+
+	recorder := httptest.NewRecorder()
+	handler.GetTopCustomers(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/analytics/top-customers?limit=1&sample=0.1&from=2024-02-01", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("GetTopCustomers() status = %d, want %d", recorder.Code, http.StatusOK)
+	}
+	var response struct {
+		Data  []models.CustomerSpend `json:"data"`
+		Count int                    `json:"count"`
+	}
+	if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if response.Count != 1 || response.Data[0].UserID != "U1" || response.Data[0].TotalSpend != 2520 {
+		t.Errorf("GetTopCustomers() = %+v, want only U1", response)
+	}
+	if analytics.customerOpts.Sampled() || analytics.customerOpts.From.IsZero() {
+		t.Errorf("GetTopCustomers() options = %+v, want unsampled from 2024-02-01", analytics.customerOpts)
+	}
+
+	// A lone value keeps its single-value field; lists and exclusions become filters
+	recorder = httptest.NewRecorder()
+	handler.GetTopCustomers(recorder, httptest.NewRequest(http.MethodGet,
+		"/api/v1/analytics/top-customers?country=USA,%20Canada&country=usa&segment=VIP&exclude_category=Returns&exclude_category=returns,", nil))
+	wantFilters := []models.DimensionFilter{
+		{Dimension: models.FilterCountry, Include: []string{"USA", "Canada"}},
+		{Dimension: models.FilterCategory, Exclude: []string{"Returns"}},
+	}
+	if opts := analytics.customerOpts; opts.Segment != "VIP" || opts.Country != "" || !reflect.DeepEqual(opts.Filters, wantFilters) {
+		t.Errorf("GetTopCustomers() options = %+v, want segment VIP and filters %+v", opts, wantFilters)
+	}
+
+	for _, limit := range []string{"0", "1001", "many"} {
+		recorder := httptest.NewRecorder()
+		handler.GetTopCustomers(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/analytics/top-customers?limit="+limit, nil))
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("GetTopCustomers(limit=%s) status = %d, want %d", limit, recorder.Code, http.StatusBadRequest)
+		}
+	}
+
+	recorder = httptest.NewRecorder()
+	handler.GetAnalyticsStats(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/analytics/stats", nil))
+	var stats map[string]interface{}
+	if err := json.NewDecoder(recorder.Body).Decode(&stats); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if stats["repeat_customers"] != float64(1) || stats["repeat_purchase_rate"] != 0.5 {
+		t.Errorf("GetAnalyticsStats() repeat purchases = %v, %v, want 1 and 0.5", stats["repeat_customers"], stats["repeat_purchase_rate"])
+	}
+}
+
+func TestAnalyticsHandler_CountryDetail(t *testing.T) {
+	analytics := &fakeAnalytics{
+		regions: []models.RegionRevenue{{Region: "Bavaria", TotalRevenue: 2520, ItemsSold: 3}},
+		months:  []models.MonthlySales{{Month: "2024-01", SalesVolume: 2020, ItemCount: 2}},
+	}
+	handler := handlers.NewAnalyticsHandler(analytics, fakeBackups{}, &fakeLoader{}, noMetrics{}, nil, nil, &mockLogger{}, config.BackupConfig{})
+	get := func(country, query string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodGet, "/api/v1/analytics/countries/"+country+query, nil)
+		recorder := httptest.NewRecorder()
+		handler.GetCountryDetail(recorder, mux.SetURLVars(request, map[string]string{"country": country}))
+		return recorder
+	}
+
+	recorder := get("Germany", "?locale=en-US")
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("GetCountryDetail() status = %d, want %d", recorder.Code, http.StatusOK)
+	}
+	var response struct {
+		Data  models.CountryDetail `json:"data"`
+		Limit int                  `json:"limit"`
+	}
+	if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if response.Data.Country != "Germany" || response.Limit != 10 || len(response.Data.RevenueOverTime) != 1 || len(response.Data.TopRegions) != 1 {
+		t.Fatalf("GetCountryDetail() = %+v, want Germany with one month and one region", response)
+	}
+	if response.Data.TopRegions[0].Display["total_revenue"] == "" {
+		t.Errorf("GetCountryDetail() regions not formatted: %+v", response.Data.TopRegions)
+	}
+	if analytics.regionSort != models.RankByRevenue {
+		t.Errorf("top regions ranked by %q, want %q", analytics.regionSort, models.RankByRevenue)
+	}
+
+	for _, limit := range []string{"0", "101", "many"} {
+		if recorder := get("Germany", "?limit="+limit); recorder.Code != http.StatusBadRequest {
+			t.Errorf("GetCountryDetail(limit=%s) status = %d, want %d", limit, recorder.Code, http.StatusBadRequest)
+		}
+	}
+
+	analytics.regions = nil
+	if recorder := get("Atlantis", ""); recorder.Code != http.StatusNotFound {
+		t.Errorf("GetCountryDetail(unknown) status = %d, want %d", recorder.Code, http.StatusNotFound)
+	}
+}
+
+func TestAnalyticsHandler_LoadFailure(t *testing.T) {
+	loader := &fakeLoader{err: errors.New("csv missing")}
+	handler := newTestAnalyticsHandler(loader)
+
+	recorder := httptest.NewRecorder()
+	handler.GetCountryRevenue(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/analytics/country-revenue", nil))
+	if recorder.Code != http.StatusInternalServerError {
+		t.Errorf("GetCountryRevenue() status = %d, want %d", recorder.Code, http.StatusInternalServerError)
+	}
+
+	recorder = httptest.NewRecorder()
+	handler.RefreshCache(recorder, httptest.NewRequest(http.MethodPost, "/api/v1/analytics/refresh?wait=true", nil))
+	if recorder.Code != http.StatusInternalServerError {
+		t.Errorf("RefreshCache() status = %d, want %d", recorder.Code, http.StatusInternalServerError)
+	}
+	if loader.reloads != 1 {
+		t.Errorf("StartReload called %d times, want 1", loader.reloads)
+	}
+}
+
+func TestAnalyticsHandler_MissingSource(t *testing.T) {
+	loader := &fakeLoader{err: fmt.Errorf("failed to load data: %w: data.csv", models.ErrSourceMissing)}
+	handler := newTestAnalyticsHandler(loader)
+
+	recorder := httptest.NewRecorder()
+	handler.RefreshCache(recorder, httptest.NewRequest(http.MethodPost, "/api/v1/analytics/refresh?wait=true", nil))
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("RefreshCache() status = %d, want %d", recorder.Code, http.StatusNotFound)
+	}
+}
+
+func TestAnalyticsHandler_RefreshStartsJob(t *testing.T) {
+	loader := &fakeLoader{}
+	handler := newTestAnalyticsHandler(loader)
+
+	recorder := httptest.NewRecorder()
+	handler.RefreshCache(recorder, httptest.NewRequest(http.MethodPost, "/api/v1/analytics/refresh", nil))
+	if recorder.Code != http.StatusAccepted {
+		t.Fatalf("RefreshCache() status = %d, want %d", recorder.Code, http.StatusAccepted)
+	}
+	if location := recorder.Header().Get("Location"); location != "/api/v1/jobs/7" {
+		t.Errorf("Location = %q, want /api/v1/jobs/7", location)
+	}
+	var job models.JobInfo
+	if err := json.NewDecoder(recorder.Body).Decode(&job); err != nil || job.Status != models.JobQueued {
+		t.Errorf("RefreshCache() body = %+v (%v), want the queued job", job, err)
+	}
+	if loader.reloads != 1 {
+		t.Errorf("StartReload called %d times, want 1", loader.reloads)
+	}
+}
+
+func TestAnalyticsHandler_RestoreNotFound(t *testing.T) {
+	handler := newTestAnalyticsHandler(&fakeLoader{})
+
+	recorder := httptest.NewRecorder()
+	handler.RestoreData(recorder, httptest.NewRequest(http.MethodPost, "/api/v1/admin/restore", nil))
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("RestoreData() status = %d, want %d", recorder.Code, http.StatusNotFound)
+	}
+}
+
+// fakeSnapshots serves one snapshot taken at 2024-05-01 and counts releases
+type fakeSnapshots struct {
+	service  *fakeAnalytics
+	asOf     time.Time
+	released int
+}
+
+func (f *fakeSnapshots) AsOf(_ context.Context, t time.Time) (handlers.AnalyticsService, *models.BackupInfo, func(), error) {
+	f.asOf = t
+	created := time.Date(2024, 5, 1, 2, 0, 0, 0, time.UTC)
+	if t.Before(created) {
+		return nil, nil, nil, models.ErrBackupNotFound
+	}
+	info := &models.BackupInfo{Name: "20240501T020000Z", CreatedAt: created}
+	return f.service, info, func() { f.released++ }, nil
+}
+
+func TestAnalyticsHandler_AsOf(t *testing.T) {
+	loader := &fakeLoader{}
+	snapshots := &fakeSnapshots{service: &fakeAnalytics{countries: []models.CountryRevenue{{Country: "Italy", ProductName: "Widget"}}}}
+	current := &fakeAnalytics{countries: []models.CountryRevenue{{Country: "Germany", ProductName: "Widget"}}}
+	handler := handlers.NewAnalyticsHandler(current, fakeBackups{}, loader, noMetrics{}, snapshots, nil, &mockLogger{}, config.BackupConfig{})
+
+	get := func(query string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		handler.GetCountryRevenue(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/analytics/country-revenue"+query, nil))
+		return recorder
+	}
+
+	recorder := get("?as_of=2024-05-01")
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("as_of status = %d, want %d", recorder.Code, http.StatusOK)
+	}
+	var response struct {
+		Data     []models.CountryRevenue `json:"data"`
+		Snapshot *models.BackupInfo      `json:"snapshot"`
+	}
+	if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(response.Data) != 1 || response.Data[0].Country != "Italy" || response.Snapshot == nil || response.Snapshot.Name != "20240501T020000Z" {
+		t.Errorf("as_of response = %+v, want the snapshot's data and name", response)
+	}
+	if want := time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC).Add(-time.Nanosecond); !snapshots.asOf.Equal(want) {
+		t.Errorf("as_of date resolved to %v, want the end of the day %v", snapshots.asOf, want)
+	}
+	if snapshots.released != 1 || loader.loads != 0 {
+		t.Errorf("released = %d, loads = %d, want 1 release and no load of current data", snapshots.released, loader.loads)
+	}
+
+	for query, want := range map[string]int{
+		"?as_of=2024-04-30":           http.StatusNotFound,
+		"?as_of=yesterday":            http.StatusBadRequest,
+		"?as_of=2024-05-01T03:00:00Z": http.StatusOK,
+	} {
+		if recorder := get(query); recorder.Code != want {
+			t.Errorf("GetCountryRevenue(%s) status = %d, want %d", query, recorder.Code, want)
+		}
+	}
+
+	// Without snapshots as_of is not supported
+	handler = handlers.NewAnalyticsHandler(current, fakeBackups{}, loader, noMetrics{}, nil, nil, &mockLogger{}, config.BackupConfig{})
+	if recorder := get("?as_of=2024-05-01"); recorder.Code != http.StatusNotImplemented {
+		t.Errorf("as_of without snapshots status = %d, want %d", recorder.Code, http.StatusNotImplemented)
+	}
+}
+
+// fakeDatasets serves the named datasets in services, each loaded on first use
+type fakeDatasets map[string]*fakeAnalytics
+
+func (f fakeDatasets) Dataset(_ context.Context, id string) (handlers.AnalyticsService, error) {
+	service, ok := f[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", models.ErrDatasetNotFound, id)
+	}
+	return service, nil
+}
+
+func TestAnalyticsHandler_NamedDatasets(t *testing.T) {
+	loader := &fakeLoader{}
+	current := &fakeAnalytics{countries: []models.CountryRevenue{{Country: "Germany", ProductName: "Widget"}}}
+	datasets := fakeDatasets{"us": {countries: []models.CountryRevenue{{Country: "United States", ProductName: "Widget"}}}}
+	handler := handlers.NewAnalyticsHandler(current, fakeBackups{}, loader, noMetrics{}, nil, datasets, &mockLogger{}, config.BackupConfig{})
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/analytics/country-revenue", handler.GetCountryRevenue)
+	router.HandleFunc("/api/v1/datasets/{dataset}/analytics/country-revenue", handler.GetCountryRevenue)
+
+	cases := []struct {
+		path        string
+		wantStatus  int
+		wantCountry string
+		wantDataset interface{}
+	}{
+		{"/api/v1/analytics/country-revenue", http.StatusOK, "Germany", nil},
+		{"/api/v1/analytics/country-revenue?dataset=default", http.StatusOK, "Germany", nil},
+		{"/api/v1/analytics/country-revenue?dataset=us", http.StatusOK, "United States", "us"},
+		{"/api/v1/datasets/us/analytics/country-revenue", http.StatusOK, "United States", "us"},
+		{"/api/v1/datasets/default/analytics/country-revenue", http.StatusOK, "Germany", nil},
+		{"/api/v1/datasets/eu/analytics/country-revenue", http.StatusNotFound, "", nil},
+		{"/api/v1/analytics/country-revenue?dataset=us&as_of=2024-05-01", http.StatusBadRequest, "", nil},
+	}
+	for _, tc := range cases {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, tc.path, nil))
+		if recorder.Code != tc.wantStatus {
+			t.Errorf("%s status = %d, want %d", tc.path, recorder.Code, tc.wantStatus)
+			continue
+		}
+		if tc.wantStatus != http.StatusOK {
+			continue
+		}
+		var response struct {
+			Data    []models.CountryRevenue `json:"data"`
+			Dataset interface{}             `json:"dataset"`
+		}
+		if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if len(response.Data) != 1 || response.Data[0].Country != tc.wantCountry || response.Dataset != tc.wantDataset {
+			t.Errorf("%s = %+v, want %s from dataset %v", tc.path, response, tc.wantCountry, tc.wantDataset)
+		}
+	}
+	// Only the three requests for the default dataset initialize it
+	if loader.loads != 3 {
+		t.Errorf("default dataset initializations = %d, want 3", loader.loads)
+	}
+}
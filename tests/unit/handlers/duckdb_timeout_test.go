@@ -0,0 +1,58 @@
+//go:build cgo
+
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"analytics-dashboard-api/internal/config"
+	"analytics-dashboard-api/internal/handlers"
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/internal/services"
+	"analytics-dashboard-api/internal/utils"
+)
+
+const timeoutTransactionsCSV = `transaction_id,user_id,transaction_date,country,region,product_id,product_name,category,price,quantity,total_price,stock_quantity,added_date
+T1,U1,2024-01-05,Germany,Bavaria,P1,Widget,Tools,10.10,2,20.20,50,2023-06-01
+`
+
+// A query past QUERY_TIMEOUT fails with models.ErrQueryTimeout in DuckDB,
+// which the handlers answer with 504 and ERR_QUERY_TIMEOUT
+func TestDuckDBQueryTimeout(t *testing.T) {
+	service, err := services.NewDuckDBService(config.DuckDBConfig{QueryTimeout: time.Nanosecond},
+		config.DimensionsConfig{}, config.OutlierConfig{}, config.TestOrderConfig{}, nil, &mockLogger{})
+	if err != nil {
+		t.Fatalf("NewDuckDBService() error = %v", err)
+	}
+	defer service.Close()
+	path := filepath.Join(t.TempDir(), "transactions.csv")
+	if err := os.WriteFile(path, []byte(timeoutTransactionsCSV), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.LoadFromFile(context.Background(), models.DataFormatCSV, path); err != nil {
+		t.Fatalf("LoadFromFile() error = %v", err)
+	}
+
+	if _, _, err := service.GetCountryRevenue(context.Background(), models.QueryOptions{}, 10, 0); !errors.Is(err, models.ErrQueryTimeout) {
+		t.Errorf("GetCountryRevenue() error = %v, want %v", err, models.ErrQueryTimeout)
+	}
+
+	handler := handlers.NewAnalyticsHandler(service, fakeBackups{}, &fakeLoader{}, noMetrics{}, nil, nil, &mockLogger{}, config.BackupConfig{})
+	recorder := httptest.NewRecorder()
+	handler.GetCountryRevenue(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/analytics/country-revenue", nil))
+	var response utils.ErrorResponse
+	if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if recorder.Code != http.StatusGatewayTimeout || response.ErrorCode != utils.ErrCodeQueryTimeout {
+		t.Errorf("GetCountryRevenue() = %d %s, want %d %s", recorder.Code, response.ErrorCode, http.StatusGatewayTimeout, utils.ErrCodeQueryTimeout)
+	}
+}
@@ -0,0 +1,46 @@
+package handlers_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"analytics-dashboard-api/internal/handlers"
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/internal/utils"
+)
+
+func TestErrorStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		want     int
+		wantCode utils.ErrorCode
+	}{
+		{"validation sentinel", fmt.Errorf("rule: %w", models.ErrInvalidAlertRule), http.StatusBadRequest, utils.ErrCodeValidation},
+		{"unknown dimension", fmt.Errorf("%w: user_id", models.ErrUnknownDimension), http.StatusBadRequest, utils.ErrCodeInvalidParam},
+		{"bad granularity", fmt.Errorf("%w: granularity must be day, week or month", models.ErrInvalidTimeSeries), http.StatusBadRequest, utils.ErrCodeInvalidParam},
+		{"not found sentinel", models.ErrProductNotFound, http.StatusNotFound, utils.ErrCodeNotFound},
+		{"read-only metric", models.ErrMetricReadOnly, http.StatusConflict, utils.ErrCodeConflict},
+		{"missing source", fmt.Errorf("failed to load data: %w: data.csv", models.ErrSourceMissing), http.StatusNotFound, utils.ErrCodeSourceMissing},
+		{"initial load failed", fmt.Errorf("%w: %w", models.ErrDataNotLoaded, models.ErrSourceMissing), http.StatusServiceUnavailable, utils.ErrCodeDatasetNotLoaded},
+		{"timeout", fmt.Errorf("failed to query: %w", models.ErrQueryTimeout), http.StatusGatewayTimeout, utils.ErrCodeQueryTimeout},
+		{"request deadline", fmt.Errorf("failed to wait for job: %w", context.DeadlineExceeded), http.StatusGatewayTimeout, utils.ErrCodeQueryTimeout},
+		{"unsupported", models.ErrNotSupported, http.StatusNotImplemented, utils.ErrCodeNotSupported},
+		{"quota", fmt.Errorf("failed to load data: %w", &models.QuotaError{Dataset: models.DatasetTargets, Measure: "rows", Value: 2, Limit: 1}), http.StatusRequestEntityTooLarge, utils.ErrCodeQuotaExceeded},
+		{"unclassified", errors.New("disk on fire"), http.StatusInternalServerError, utils.ErrCodeInternal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, _ := handlers.ErrorStatus(tt.err); got != tt.want {
+				t.Errorf("ErrorStatus(%v) = %d, want %d", tt.err, got, tt.want)
+			}
+			if got := handlers.ErrorCode(tt.err); got != tt.wantCode {
+				t.Errorf("ErrorCode(%v) = %s, want %s", tt.err, got, tt.wantCode)
+			}
+		})
+	}
+}
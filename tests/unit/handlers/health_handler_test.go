@@ -1,13 +1,16 @@
 package handlers_test
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
 
 	"analytics-dashboard-api/internal/handlers"
+	"analytics-dashboard-api/internal/models"
 )
 
 // mockLogger is a simple mock implementation of logger.Logger
@@ -19,9 +22,32 @@ func (m *mockLogger) Warn(msg string, fields ...interface{})  {}
 func (m *mockLogger) Error(msg string, fields ...interface{}) {}
 func (m *mockLogger) Fatal(msg string, fields ...interface{}) {}
 
+// stubLoaderStats reports a loader that has completed one load
+type stubLoaderStats struct{}
+
+func (s *stubLoaderStats) Stats() models.LoaderStats {
+	return models.LoaderStats{Loaded: true, Loads: 1}
+}
+
+func (s *stubLoaderStats) StartInitialLoad() {}
+
+// probeLoader reports fixed stats and records whether a load was started
+type probeLoader struct {
+	stats   models.LoaderStats
+	started bool
+}
+
+func (p *probeLoader) Stats() models.LoaderStats { return p.stats }
+func (p *probeLoader) StartInitialLoad()         { p.started = true }
+
+// stubPinger answers pings with err
+type stubPinger struct{ err error }
+
+func (p stubPinger) Ping(ctx context.Context) error { return p.err }
+
 func TestHealthHandler_Health(t *testing.T) {
 	logger := &mockLogger{}
-	handler := handlers.NewHealthHandler(logger)
+	handler := handlers.NewHealthHandler(&stubLoaderStats{}, stubPinger{}, logger)
 
 	req := httptest.NewRequest(http.MethodGet, "/health", nil)
 	recorder := httptest.NewRecorder()
@@ -46,7 +72,7 @@ func TestHealthHandler_Health(t *testing.T) {
 	}
 
 	// Check required fields
-	requiredFields := []string{"status", "timestamp", "uptime", "version", "memory", "goroutines"}
+	requiredFields := []string{"status", "timestamp", "uptime", "version", "memory", "goroutines", "data"}
 	for _, field := range requiredFields {
 		if _, exists := response[field]; !exists {
 			t.Errorf("Health() missing required field: %s", field)
@@ -83,7 +109,7 @@ func TestHealthHandler_Health(t *testing.T) {
 
 func TestHealthHandler_Ready(t *testing.T) {
 	logger := &mockLogger{}
-	handler := handlers.NewHealthHandler(logger)
+	handler := handlers.NewHealthHandler(&stubLoaderStats{}, stubPinger{}, logger)
 
 	req := httptest.NewRequest(http.MethodGet, "/ready", nil)
 	recorder := httptest.NewRecorder()
@@ -132,7 +158,7 @@ func TestHealthHandler_Ready(t *testing.T) {
 
 func TestHealthHandler_HealthUptime(t *testing.T) {
 	logger := &mockLogger{}
-	handler := handlers.NewHealthHandler(logger)
+	handler := handlers.NewHealthHandler(&stubLoaderStats{}, stubPinger{}, logger)
 
 	// Wait a small amount to ensure uptime is positive
 	time.Sleep(1 * time.Millisecond)
@@ -156,3 +182,119 @@ func TestHealthHandler_HealthUptime(t *testing.T) {
 		t.Error("Health() uptime should be a string")
 	}
 }
+
+func TestHealthHandler_Probes(t *testing.T) {
+	started := time.Now().Add(-time.Minute)
+	loading := models.LoaderStats{Loading: true, LoadStartedAt: &started, EstimatedRemainingMs: 30000}
+	failed := models.LoaderStats{LastError: "failed to open data file"}
+	loaded := models.LoaderStats{Loaded: true, Loads: 1}
+
+	cases := []struct {
+		name   string
+		probe  func(*handlers.HealthHandler) http.HandlerFunc
+		stats  models.LoaderStats
+		dbErr  error
+		want   int
+		status string
+	}{
+		{"live while loading", func(h *handlers.HealthHandler) http.HandlerFunc { return h.Live }, loading, nil, http.StatusOK, "alive"},
+		{"live with database down", func(h *handlers.HealthHandler) http.HandlerFunc { return h.Live }, loaded, errors.New("down"), http.StatusOK, "alive"},
+		{"ready", func(h *handlers.HealthHandler) http.HandlerFunc { return h.Ready }, loaded, nil, http.StatusOK, "ready"},
+		{"ready while loading", func(h *handlers.HealthHandler) http.HandlerFunc { return h.Ready }, loading, nil, http.StatusServiceUnavailable, "not_ready"},
+		{"ready with database down", func(h *handlers.HealthHandler) http.HandlerFunc { return h.Ready }, loaded, errors.New("down"), http.StatusServiceUnavailable, "not_ready"},
+		{"startup while loading", func(h *handlers.HealthHandler) http.HandlerFunc { return h.Startup }, loading, nil, http.StatusServiceUnavailable, "loading"},
+		{"startup after failure", func(h *handlers.HealthHandler) http.HandlerFunc { return h.Startup }, failed, nil, http.StatusServiceUnavailable, "failed"},
+		{"startup loaded", func(h *handlers.HealthHandler) http.HandlerFunc { return h.Startup }, loaded, nil, http.StatusOK, "loaded"},
+	}
+	for _, tc := range cases {
+		loader := &probeLoader{stats: tc.stats}
+		handler := handlers.NewHealthHandler(loader, stubPinger{tc.dbErr}, &mockLogger{})
+		recorder := httptest.NewRecorder()
+		tc.probe(handler)(recorder, httptest.NewRequest(http.MethodGet, "/health/probe", nil))
+
+		var response map[string]interface{}
+		if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil {
+			t.Fatalf("%s: response parsing error: %v", tc.name, err)
+		}
+		if recorder.Code != tc.want || response["status"] != tc.status {
+			t.Errorf("%s: got %d %v, want %d %q", tc.name, recorder.Code, response["status"], tc.want, tc.status)
+		}
+		if tc.status == "loading" {
+			if _, ok := response["estimated_remaining_ms"]; !ok {
+				t.Errorf("%s: missing estimated_remaining_ms", tc.name)
+			}
+		}
+	}
+}
+
+func TestHealthHandler_ProbesStartInitialLoad(t *testing.T) {
+	loader := &probeLoader{}
+	handler := handlers.NewHealthHandler(loader, stubPinger{}, &mockLogger{})
+
+	handler.Live(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/health/live", nil))
+	if loader.started {
+		t.Error("Live() started the initial load, want it left alone")
+	}
+	handler.Startup(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/health/startup", nil))
+	if !loader.started {
+		t.Error("Startup() did not start the initial load")
+	}
+}
+
+func TestHealthHandler_HealthReportsFallback(t *testing.T) {
+	handler := handlers.NewHealthHandler(&stubLoaderStats{}, stubPinger{}, &mockLogger{})
+	handler.ReportFallback("memory", errors.New("duckdb backend unavailable: driver panicked"))
+
+	recorder := httptest.NewRecorder()
+	handler.Health(recorder, httptest.NewRequest(http.MethodGet, "/health", nil))
+	if recorder.Code != http.StatusOK {
+		t.Errorf("Health() status = %d, want %d", recorder.Code, http.StatusOK)
+	}
+	var response struct {
+		Status   string            `json:"status"`
+		Fallback map[string]string `json:"backend_fallback"`
+	}
+	if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil {
+		t.Fatalf("Health() response parsing error: %v", err)
+	}
+	if response.Status != "degraded" || response.Fallback["backend"] != "memory" || response.Fallback["reason"] != "duckdb backend unavailable: driver panicked" {
+		t.Errorf("Health() = %+v, want degraded on the memory fallback", response)
+	}
+}
+
+func TestDegradedHandler(t *testing.T) {
+	handler := handlers.NewDegradedHandler("duckdb", errors.New("libduckdb.so: cannot open shared object file"))
+
+	tests := []struct {
+		name   string
+		serve  http.HandlerFunc
+		status int
+	}{
+		{"health", handler.Health, http.StatusServiceUnavailable},
+		{"live", handler.Live, http.StatusOK},
+		{"ready", handler.Ready, http.StatusServiceUnavailable},
+		{"other routes", handler.Unavailable, http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			tt.serve(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+			if recorder.Code != tt.status {
+				t.Errorf("status = %d, want %d", recorder.Code, tt.status)
+			}
+		})
+	}
+
+	recorder := httptest.NewRecorder()
+	handler.Health(recorder, httptest.NewRequest(http.MethodGet, "/health", nil))
+	var response struct {
+		Status  string            `json:"status"`
+		Backend map[string]string `json:"backend"`
+	}
+	if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil {
+		t.Fatalf("Health() response parsing error: %v", err)
+	}
+	if response.Status != "degraded" || response.Backend["name"] != "duckdb" || response.Backend["error"] != "libduckdb.so: cannot open shared object file" {
+		t.Errorf("Health() = %+v, want the duckdb failure", response)
+	}
+}
@@ -0,0 +1,90 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"analytics-dashboard-api/internal/handlers"
+	"analytics-dashboard-api/internal/models"
+)
+
+// fakeMeta profiles a fixed one-column table and counts profile queries
+type fakeMeta struct {
+	profiles int
+	lastTop  int
+}
+
+func (f *fakeMeta) ListDimensionValues(context.Context, string, string, int, int) ([]models.DimensionValue, error) {
+	return nil, nil
+}
+
+func (f *fakeMeta) CountDimensionValues(context.Context, string, string) (int, error) {
+	return 0, nil
+}
+
+func (f *fakeMeta) ListOutliers(context.Context, int, int) ([]models.Outlier, error) {
+	return []models.Outlier{}, nil
+}
+
+func (f *fakeMeta) DataCoverage() models.DataCoverage {
+	return models.DataCoverage{}
+}
+
+func (f *fakeMeta) GetDataProfile(_ context.Context, top int) (*models.DataProfile, error) {
+	f.profiles++
+	f.lastTop = top
+	return &models.DataProfile{
+		Table: "transactions",
+		Rows:  2,
+		Columns: []models.ColumnProfile{{
+			Name: "country", Type: "VARCHAR", DistinctCount: 2,
+			TopValues: []models.DimensionValue{{Value: "Germany", Count: 1}},
+		}},
+	}, nil
+}
+
+func TestMetaHandler_DataProfile(t *testing.T) {
+	meta := &fakeMeta{}
+	loader := &fakeLoader{}
+	handler := handlers.NewMetaHandler(meta, loader, &mockLogger{})
+
+	getProfile := func(query string, wantStatus int) map[string]interface{} {
+		t.Helper()
+		recorder := httptest.NewRecorder()
+		handler.GetDataProfile(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/data/profile"+query, nil))
+		if recorder.Code != wantStatus {
+			t.Fatalf("GetDataProfile(%q) status = %d, want %d", query, recorder.Code, wantStatus)
+		}
+		var body map[string]interface{}
+		if err := json.NewDecoder(recorder.Body).Decode(&body); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return body
+	}
+
+	first := getProfile("", http.StatusOK)
+	if meta.lastTop != 5 || first["rows"] != float64(2) || len(first["columns"].([]interface{})) != 1 {
+		t.Errorf("GetDataProfile() = %v with top %d, want 1 column of 2 rows with top 5", first, meta.lastTop)
+	}
+	second := getProfile("", http.StatusOK)
+	if first["cache_hit"] != false || second["cache_hit"] != true || meta.profiles != 1 {
+		t.Errorf("cache_hit = %v then %v after %d profiles, want false then true after 1",
+			first["cache_hit"], second["cache_hit"], meta.profiles)
+	}
+
+	// A different top or a reload recomputes the profile
+	getProfile("?top=0", http.StatusOK)
+	loader.reloads++
+	third := getProfile("?top=0", http.StatusOK)
+	if third["cache_hit"] != false || third["data_version"] != float64(1) || meta.profiles != 3 {
+		t.Errorf("after top change and reload: cache_hit = %v, data_version = %v, profiles = %d",
+			third["cache_hit"], third["data_version"], meta.profiles)
+	}
+
+	for _, query := range []string{"?top=-1", "?top=51", "?top=many"} {
+		getProfile(query, http.StatusBadRequest)
+	}
+}
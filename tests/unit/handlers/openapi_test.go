@@ -0,0 +1,96 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"analytics-dashboard-api/internal/handlers"
+)
+
+// specFile is the checked-in spec clients are generated from
+const specFile = "../../../docs/openapi.json"
+
+// TestOpenAPISpec_Golden keeps docs/openapi.json in step with the code, so
+// a renamed JSON field or operation shows up in review as a change to it
+func TestOpenAPISpec_Golden(t *testing.T) {
+	var got bytes.Buffer
+	encoder := json.NewEncoder(&got)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(handlers.OpenAPISpec()); err != nil {
+		t.Fatalf("encoding the spec: %v", err)
+	}
+	want, err := os.ReadFile(specFile)
+	if err != nil {
+		t.Fatalf("reading %s: %v", specFile, err)
+	}
+	if !bytes.Equal(got.Bytes(), want) {
+		t.Errorf("docs/openapi.json is out of date; regenerate it with go run ./cmd/openapi > docs/openapi.json")
+	}
+}
+
+func TestRouteDocs(t *testing.T) {
+	ids := map[string]string{}
+	for route, doc := range handlers.RouteDocs {
+		if doc.ID == "" || doc.Summary == "" {
+			t.Errorf("%s: missing ID or summary", route)
+		}
+		if other, dup := ids[doc.ID]; dup {
+			t.Errorf("%s and %s share the operation ID %q", route, other, doc.ID)
+		}
+		ids[doc.ID] = route
+	}
+	// The spec takes each route's query parameters from QueryParamSpecs
+	for route := range handlers.QueryParamSpecs {
+		if _, ok := handlers.RouteDocs[route]; !ok {
+			t.Errorf("%s has query parameters but no RouteDoc", route)
+		}
+	}
+}
+
+// TestOpenAPISpec_MatchesResponses checks handler responses against their
+// documented schema: every field they send is documented and every
+// required field is sent
+func TestOpenAPISpec_MatchesResponses(t *testing.T) {
+	spec := handlers.OpenAPISpec()
+	handler := newTestAnalyticsHandler(&fakeLoader{})
+
+	tests := []struct {
+		path  string
+		url   string
+		serve http.HandlerFunc
+	}{
+		{"/api/v1/analytics", "/api/v1/analytics", handler.GetAnalytics},
+		{"/api/v1/analytics/country-revenue", "/api/v1/analytics/country-revenue?include_totals=true&sample=0.5", handler.GetCountryRevenue},
+		{"/api/v1/analytics/top-regions", "/api/v1/analytics/top-regions", handler.GetTopRegions},
+		{"/api/v1/analytics/stats", "/api/v1/analytics/stats", handler.GetAnalyticsStats},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			tt.serve(rec, httptest.NewRequest(http.MethodGet, tt.url, nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+			}
+			var body map[string]json.RawMessage
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("decoding %s: %v", rec.Body.String(), err)
+			}
+
+			schema := spec.Paths[tt.path]["get"].Responses["200"].Content["application/json"].Schema
+			for field := range body {
+				if _, ok := schema.Properties[field]; !ok {
+					t.Errorf("response field %q is not documented", field)
+				}
+			}
+			for _, field := range schema.Required {
+				if _, ok := body[field]; !ok {
+					t.Errorf("required field %q is missing from the response", field)
+				}
+			}
+		})
+	}
+}
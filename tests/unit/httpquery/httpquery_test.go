@@ -0,0 +1,184 @@
+package httpquery_test
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"analytics-dashboard-api/internal/httpquery"
+)
+
+func TestParsePage(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    httpquery.Page
+		wantErr bool
+	}{
+		{"", httpquery.Page{Limit: 100}, false},
+		{"limit=10&offset=20", httpquery.Page{Limit: 10, Offset: 20}, false},
+		{"limit=0", httpquery.Page{Limit: 0}, false},
+		{"limit=5000", httpquery.Page{Limit: 1000}, true},
+		{"limit=abc&offset=5", httpquery.Page{Limit: 100, Offset: 5}, true},
+		{"limit=-1", httpquery.Page{Limit: 100}, true},
+		{"offset=-3", httpquery.Page{Limit: 100}, true},
+	}
+
+	for _, tt := range tests {
+		query, _ := url.ParseQuery(tt.query)
+		got, err := httpquery.ParsePage(query, httpquery.DefaultPageOptions)
+		if got != tt.want {
+			t.Errorf("ParsePage(%q) = %+v, want %+v", tt.query, got, tt.want)
+		}
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParsePage(%q) error = %v, wantErr %v", tt.query, err, tt.wantErr)
+		}
+	}
+}
+
+func TestParsePage_MinLimit(t *testing.T) {
+	opts := httpquery.PageOptions{DefaultLimit: 100, MinLimit: 1, MaxLimit: 500}
+	query, _ := url.ParseQuery("limit=0")
+
+	_, err := httpquery.ParsePage(query, opts)
+	var paramErr *httpquery.Error
+	if !errors.As(err, &paramErr) || paramErr.Param != "limit" {
+		t.Errorf("ParsePage(limit=0) error = %v, want *httpquery.Error for limit", err)
+	}
+}
+
+func TestPage_HasMore(t *testing.T) {
+	page := httpquery.Page{Limit: 10, Offset: 90}
+	if !page.HasMore(101) {
+		t.Error("HasMore(101) = false, want true")
+	}
+	if page.HasMore(100) {
+		t.Error("HasMore(100) = true, want false")
+	}
+}
+
+func TestPage_Links(t *testing.T) {
+	u, _ := url.Parse("/api/v1/products?sort_by=revenue&limit=10&offset=20")
+	links := httpquery.Page{Limit: 10, Offset: 20}.Links(u, 45)
+
+	want := httpquery.Links{
+		First: "/api/v1/products?limit=10&offset=0&sort_by=revenue",
+		Prev:  "/api/v1/products?limit=10&offset=10&sort_by=revenue",
+		Next:  "/api/v1/products?limit=10&offset=30&sort_by=revenue",
+		Last:  "/api/v1/products?limit=10&offset=40&sort_by=revenue",
+	}
+	if links != want {
+		t.Errorf("Links = %+v, want %+v", links, want)
+	}
+
+	header := links.Header()
+	if !strings.HasPrefix(header, `</api/v1/products?limit=10&offset=0&sort_by=revenue>; rel="first", `) ||
+		!strings.Contains(header, `; rel="next", `) || !strings.HasSuffix(header, `; rel="last"`) {
+		t.Errorf("Header = %q", header)
+	}
+}
+
+func TestPage_LinksAtTheEnds(t *testing.T) {
+	u, _ := url.Parse("/api/v1/products")
+
+	first := httpquery.Page{Limit: 10}.Links(u, 10)
+	if first.Prev != "" || first.Next != "" {
+		t.Errorf("single page links = %+v, want no prev or next", first)
+	}
+	if first.Last != first.First {
+		t.Errorf("single page last = %q, want the first page", first.Last)
+	}
+	if strings.Contains(first.Header(), "prev") {
+		t.Errorf("Header = %q, want no prev link", first.Header())
+	}
+
+	empty := httpquery.Page{Limit: 10}.Links(u, 0)
+	if empty.Last != "/api/v1/products?limit=10&offset=0" {
+		t.Errorf("empty list last = %q, want the first page", empty.Last)
+	}
+
+	countOnly := httpquery.Page{Limit: 0}.Links(u, 50)
+	if countOnly.Next != "" || countOnly.Last != countOnly.First {
+		t.Errorf("limit=0 links = %+v, want only the first page", countOnly)
+	}
+}
+
+func TestParseSort(t *testing.T) {
+	allowed := []string{"revenue", "name"}
+	fallback := httpquery.Sort{Field: "revenue", Desc: true}
+
+	tests := []struct {
+		query   string
+		want    httpquery.Sort
+		wantErr bool
+	}{
+		{"", fallback, false},
+		{"sort=name", httpquery.Sort{Field: "name"}, false},
+		{"sort=-name", httpquery.Sort{Field: "name", Desc: true}, false},
+		{"sort=price", fallback, true},
+	}
+
+	for _, tt := range tests {
+		query, _ := url.ParseQuery(tt.query)
+		got, err := httpquery.ParseSort(query, allowed, fallback)
+		if got != tt.want {
+			t.Errorf("ParseSort(%q) = %+v, want %+v", tt.query, got, tt.want)
+		}
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseSort(%q) error = %v, wantErr %v", tt.query, err, tt.wantErr)
+		}
+	}
+}
+
+func TestParseDateRange(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    httpquery.DateRange
+		wantErr bool
+	}{
+		{"", httpquery.DateRange{}, false},
+		{"from=2024-01-01&to=2024-03-31", httpquery.DateRange{From: "2024-01-01", To: "2024-03-31"}, false},
+		{"from=2024-1-1&to=2024-03-31", httpquery.DateRange{To: "2024-03-31"}, true},
+		{"from=2024-04-01&to=2024-03-31", httpquery.DateRange{From: "2024-04-01", To: "2024-03-31"}, true},
+	}
+
+	for _, tt := range tests {
+		query, _ := url.ParseQuery(tt.query)
+		got, err := httpquery.ParseDateRange(query)
+		if got != tt.want {
+			t.Errorf("ParseDateRange(%q) = %+v, want %+v", tt.query, got, tt.want)
+		}
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseDateRange(%q) error = %v, wantErr %v", tt.query, err, tt.wantErr)
+		}
+	}
+}
+
+func TestParseValueFilter(t *testing.T) {
+	query := url.Values{
+		"country":          {"USA, Canada,,usa", "Mexico"},
+		"exclude_category": {"Returns"},
+	}
+	filter, err := httpquery.ParseValueFilter(query, "country")
+	if err != nil || strings.Join(filter.Include, "|") != "USA|Canada|Mexico" || filter.Exclude != nil {
+		t.Errorf("ParseValueFilter(country) = %+v, %v, want USA, Canada and Mexico", filter, err)
+	}
+	filter, _ = httpquery.ParseValueFilter(query, "category")
+	if filter.Include != nil || strings.Join(filter.Exclude, "|") != "Returns" {
+		t.Errorf("ParseValueFilter(category) = %+v, want Returns excluded", filter)
+	}
+	if filter, _ := httpquery.ParseValueFilter(query, "region"); !filter.Empty() {
+		t.Errorf("ParseValueFilter(region) = %+v, want an empty filter", filter)
+	}
+
+	many := make([]string, httpquery.MaxFilterValues+1)
+	for i := range many {
+		many[i] = strconv.Itoa(i)
+	}
+	filter, err = httpquery.ParseValueFilter(url.Values{"exclude_region": {strings.Join(many, ",")}}, "region")
+	var paramErr *httpquery.Error
+	if !errors.As(err, &paramErr) || paramErr.Param != "exclude_region" || len(filter.Exclude) != httpquery.MaxFilterValues {
+		t.Errorf("ParseValueFilter(too many) = %d values, %v, want the list cut short and reported", len(filter.Exclude), err)
+	}
+}
@@ -0,0 +1,110 @@
+package metrics_test
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"analytics-dashboard-api/internal/metrics"
+	"analytics-dashboard-api/internal/models"
+)
+
+func scrape(t *testing.T, m *metrics.Metrics) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type = %q, want the text exposition format", rec.Header().Get("Content-Type"))
+	}
+	return rec.Body.String()
+}
+
+func TestMetricsExposition(t *testing.T) {
+	m := metrics.New()
+	m.ObserveRequest("GET", "/api/v1/products/{id}", 200, 3*time.Millisecond)
+	m.ObserveRequest("GET", "/api/v1/products/{id}", 200, 40*time.Millisecond)
+	m.ObserveQuery("duckdb", "top_products", 20*time.Millisecond)
+	m.ObserveLoad("refresh", nil, 90*time.Second, 1000, 2048)
+	m.ObserveLoad("refresh", errors.New("bad file"), time.Second, 0, 0)
+	hits := 0.0
+	m.Registry().CounterFunc("response_cache_hits_total", "Cache hits.", func() float64 { return hits })
+	hits = 3
+
+	body := scrape(t, m)
+	for _, want := range []string{
+		"# TYPE http_requests_total counter\n",
+		`http_requests_total{method="GET",route="/api/v1/products/{id}",status="200"} 2` + "\n",
+		`http_request_duration_seconds_bucket{method="GET",route="/api/v1/products/{id}",le="0.005"} 1` + "\n",
+		`http_request_duration_seconds_bucket{method="GET",route="/api/v1/products/{id}",le="0.05"} 2` + "\n",
+		`http_request_duration_seconds_bucket{method="GET",route="/api/v1/products/{id}",le="+Inf"} 2` + "\n",
+		`http_request_duration_seconds_count{method="GET",route="/api/v1/products/{id}"} 2` + "\n",
+		`analytics_query_duration_seconds_count{backend="duckdb",query="top_products"} 1` + "\n",
+		`analytics_loads_total{kind="refresh",result="success"} 1` + "\n",
+		`analytics_loads_total{kind="refresh",result="failure"} 1` + "\n",
+		`analytics_load_duration_seconds_bucket{le="60"} 0` + "\n",
+		`analytics_load_duration_seconds_bucket{le="120"} 1` + "\n",
+		"analytics_loaded_rows 1000\n",
+		"analytics_loaded_source_bytes 2048\n",
+		"response_cache_hits_total 3\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("scrape is missing %q:\n%s", want, body)
+		}
+	}
+}
+
+func TestMetricsIngest(t *testing.T) {
+	m := metrics.New()
+	m.ObserveIngest(models.IngestStats{
+		Rows:   500_000,
+		Bytes:  100 << 20,
+		Read:   time.Second,
+		Parse:  2 * time.Second,
+		Insert: 500 * time.Millisecond,
+	})
+	m.ObserveIngest(models.IngestStats{Rows: 999, ParseErrors: 1, Read: time.Millisecond})
+
+	body := scrape(t, m)
+	for _, want := range []string{
+		"analytics_ingest_rows_total 500999\n",
+		"analytics_ingest_parse_errors_total 1\n",
+		`analytics_ingest_phase_duration_seconds_count{phase="read"} 2` + "\n",
+		`analytics_ingest_phase_duration_seconds_count{phase="insert"} 1` + "\n",
+		// 500k rows in 2s parsing: 250k rows/s, over the 100k bucket
+		`analytics_ingest_rows_per_second_bucket{phase="parse",le="100000"} 0` + "\n",
+		`analytics_ingest_rows_per_second_bucket{phase="parse",le="500000"} 1` + "\n",
+		// 100 MiB read in 1s
+		`analytics_ingest_bytes_per_second_bucket{phase="read",le="1.34217728e+08"} 1` + "\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("scrape is missing %q:\n%s", want, body)
+		}
+	}
+}
+
+func TestMetricsEscapesLabels(t *testing.T) {
+	r := metrics.NewRegistry()
+	r.Counter("errors_total", "Errors by reason.", "reason").Inc("bad \"quote\"\nline")
+
+	var b strings.Builder
+	r.WriteTo(&b)
+	if want := `errors_total{reason="bad \"quote\"\nline"} 1`; !strings.Contains(b.String(), want) {
+		t.Errorf("output = %q, want it to contain %q", b.String(), want)
+	}
+}
+
+func TestNilMetricsRecordNothing(t *testing.T) {
+	var m *metrics.Metrics
+	m.ObserveRequest("GET", "/", 200, time.Second)
+	m.ObserveQuery("duckdb", "top_products", time.Second)
+	m.ObserveLoad("refresh", nil, time.Second, 1, 1)
+	m.ObserveIngest(models.IngestStats{Rows: 1, Read: time.Second})
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if rec.Code != 404 {
+		t.Errorf("nil Metrics handler status = %d, want 404", rec.Code)
+	}
+}
@@ -0,0 +1,91 @@
+package middleware_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"analytics-dashboard-api/internal/middleware"
+	"analytics-dashboard-api/internal/utils"
+)
+
+func TestLoadAPIKeys(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "keys")
+	if err := os.WriteFile(file, []byte("# ops team\nops = k-ops\n\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := middleware.LoadAPIKeys("dashboard=k-dash; mobile=k-mob;", file)
+	if err != nil {
+		t.Fatalf("LoadAPIKeys: %v", err)
+	}
+	want := middleware.APIKeys{"k-dash": "dashboard", "k-mob": "mobile", "k-ops": "ops"}
+	if len(keys) != len(want) {
+		t.Fatalf("keys = %v, want %v", keys, want)
+	}
+	for key, id := range want {
+		if keys[key] != id {
+			t.Errorf("keys[%q] = %q, want %q", key, keys[key], id)
+		}
+	}
+
+	for _, spec := range []string{"dashboard", "=k", "a=k;b=k"} {
+		if _, err := middleware.LoadAPIKeys(spec, ""); err == nil {
+			t.Errorf("LoadAPIKeys(%q) succeeded, want an error", spec)
+		}
+	}
+}
+
+func TestAPIKeyAuth(t *testing.T) {
+	var gotID string
+	handler := middleware.APIKeyAuth(middleware.APIKeys{"k-dash": "dashboard"}, "/api/v1/")(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if id, ok := middleware.APIKeyIDFromContext(r.Context()); ok {
+				gotID = id
+			}
+		}))
+
+	cases := []struct {
+		path, key string
+		want      int
+	}{
+		{"/api/v1/analytics", "k-dash", http.StatusOK},
+		{"/api/v1/analytics", "", http.StatusUnauthorized},
+		{"/api/v1/analytics", "wrong", http.StatusUnauthorized},
+		{"/health", "", http.StatusOK},
+		{"/ready", "", http.StatusOK},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest("GET", tc.path, nil)
+		if tc.key != "" {
+			req.Header.Set(middleware.APIKeyHeader, tc.key)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tc.want {
+			t.Errorf("%s with key %q = %d, want %d", tc.path, tc.key, rec.Code, tc.want)
+			continue
+		}
+		if rec.Code == http.StatusUnauthorized {
+			var body utils.ErrorResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.Code != http.StatusUnauthorized {
+				t.Errorf("%s with key %q: body %q is not a 401 ErrorResponse", tc.path, tc.key, rec.Body.String())
+			}
+		}
+	}
+	if gotID != "dashboard" {
+		t.Errorf("key id in context = %q, want dashboard", gotID)
+	}
+}
+
+func TestAPIKeyAuthWithoutKeysIsOpen(t *testing.T) {
+	handler := middleware.APIKeyAuth(nil, "/api/v1/")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/analytics", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("status = %d, want 200", rec.Code)
+	}
+}
@@ -0,0 +1,90 @@
+package middleware_test
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"analytics-dashboard-api/internal/middleware"
+)
+
+func compressed(minBytes int, contentType, body string) http.Handler {
+	return middleware.Compression(minBytes)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		w.Write([]byte(body))
+	}))
+}
+
+func serveGzip(handler http.Handler, acceptEncoding string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", "/", nil)
+	if acceptEncoding != "" {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestCompressionGzipsLargeJSON(t *testing.T) {
+	body := `{"rows":"` + strings.Repeat("x", 2048) + `"}`
+	rec := serveGzip(compressed(1024, "application/json", body), "deflate, gzip;q=0.8")
+
+	if rec.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("Content-Encoding = %q, want gzip", rec.Header().Get("Content-Encoding"))
+	}
+	if rec.Header().Get("Vary") != "Accept-Encoding" {
+		t.Errorf("Vary = %q, want Accept-Encoding", rec.Header().Get("Vary"))
+	}
+	gz, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatalf("gzip.NewReader: %v", err)
+	}
+	plain, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatalf("reading gzip body: %v", err)
+	}
+	if string(plain) != body {
+		t.Errorf("decompressed body has %d bytes, want %d", len(plain), len(body))
+	}
+}
+
+func TestCompressionLeavesOtherResponsesAlone(t *testing.T) {
+	large := `{"rows":"` + strings.Repeat("x", 2048) + `"}`
+	cases := []struct {
+		name           string
+		minBytes       int
+		contentType    string
+		body           string
+		acceptEncoding string
+	}{
+		{"below threshold", 1024, "application/json", `{"ok":true}`, "gzip"},
+		{"not accepted", 1024, "application/json", large, ""},
+		{"refused by quality", 1024, "application/json", large, "gzip;q=0"},
+		{"not JSON", 1024, "text/csv", strings.Repeat("a,b\n", 1024), "gzip"},
+		{"disabled", -1, "application/json", large, "gzip"},
+	}
+	for _, tc := range cases {
+		rec := serveGzip(compressed(tc.minBytes, tc.contentType, tc.body), tc.acceptEncoding)
+		if rec.Header().Get("Content-Encoding") != "" {
+			t.Errorf("%s: Content-Encoding = %q, want none", tc.name, rec.Header().Get("Content-Encoding"))
+		}
+		if rec.Body.String() != tc.body {
+			t.Errorf("%s: body changed", tc.name)
+		}
+	}
+}
+
+func TestCompressionKeepsStatus(t *testing.T) {
+	handler := middleware.Compression(0)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"Bad Request"}`))
+	}))
+	rec := serveGzip(handler, "gzip")
+	if rec.Code != http.StatusBadRequest || rec.Header().Get("Content-Encoding") != "gzip" {
+		t.Errorf("got %d with Content-Encoding %q, want a gzipped 400", rec.Code, rec.Header().Get("Content-Encoding"))
+	}
+}
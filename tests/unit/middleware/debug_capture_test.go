@@ -0,0 +1,111 @@
+package middleware_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"analytics-dashboard-api/internal/middleware"
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/internal/timing"
+	"analytics-dashboard-api/internal/utils"
+
+	"github.com/gorilla/mux"
+)
+
+func newCapturedRouter(capture *middleware.DebugCapture) *mux.Router {
+	router := mux.NewRouter()
+	router.Use(capture.Middleware)
+	router.HandleFunc("/fail", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		timing.FromContext(r.Context()).Since(timing.PhaseDB, "top_products", time.Now().Add(-5*time.Millisecond))
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "failed after reading "+string(body))
+	}).Methods("POST")
+	router.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {
+		utils.WriteJSONResponse(w, http.StatusOK, map[string]bool{"ok": true})
+	}).Methods("GET")
+	router.HandleFunc("/panic", func(w http.ResponseWriter, r *http.Request) { panic("boom") }).Methods("GET")
+	return router
+}
+
+func TestDebugCapture(t *testing.T) {
+	capture := middleware.NewDebugCapture(2, 8, false, 1)
+	router := newCapturedRouter(capture)
+
+	fail := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/fail?x=1", strings.NewReader("0123456789"))
+		req.Header.Set(middleware.APIKeyHeader, "secret-key")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	fail()
+	if got := capture.List(); len(got) != 0 {
+		t.Fatalf("captured %d exchanges while disabled, want 0", len(got))
+	}
+
+	enabled := true
+	if _, err := capture.Configure(models.DebugCaptureRequest{Enabled: &enabled}); err != nil {
+		t.Fatalf("Configure() error = %v", err)
+	}
+	// The handler still reads the whole body, though only 8 bytes are kept
+	if rec := fail(); !strings.Contains(rec.Body.String(), "0123456789") {
+		t.Errorf("handler body = %s, want the full request body", rec.Body.String())
+	}
+	serve(router, http.MethodGet, "/ok")
+
+	captures := capture.List()
+	if len(captures) != 1 {
+		t.Fatalf("captured %d exchanges, want only the failed one", len(captures))
+	}
+	got := captures[0]
+	if got.Method != http.MethodPost || got.URL != "/fail?x=1" || got.Status != http.StatusInternalServerError {
+		t.Errorf("capture = %s %s %d, want POST /fail?x=1 500", got.Method, got.URL, got.Status)
+	}
+	if got.RequestBody != "01234567" || !got.RequestTruncated || len(got.ResponseBody) != 8 || !got.ResponseTruncated {
+		t.Errorf("capture bodies = %q (truncated %v), %q (truncated %v), want both cut at 8 bytes",
+			got.RequestBody, got.RequestTruncated, got.ResponseBody, got.ResponseTruncated)
+	}
+	if key := http.Header(got.RequestHeaders).Get(middleware.APIKeyHeader); key != "[REDACTED]" {
+		t.Errorf("captured API key = %v, want it redacted", key)
+	}
+	if got.Timings[timing.PhaseDB] < 5 || len(got.Spans) != 2 {
+		t.Errorf("capture timings = %v, spans = %v, want the 5ms query and the serialization", got.Timings, got.Spans)
+	}
+	for _, phase := range []string{timing.PhaseQueue, timing.PhaseSerialize, "other"} {
+		if _, ok := got.Timings[phase]; !ok {
+			t.Errorf("capture timings = %v, missing %s", got.Timings, phase)
+		}
+	}
+
+	// A panic is captured as a 500 and passed on
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("panic was not passed on")
+			}
+		}()
+		serve(router, http.MethodGet, "/panic")
+	}()
+	fail()
+	state := capture.State()
+	if state.Captured != 2 || state.Dropped != 1 {
+		t.Errorf("State() = %+v, want 2 captured and 1 dropped", state)
+	}
+	if captures := capture.List(); captures[1].Panic != "boom" || captures[0].ID != 3 {
+		t.Errorf("List() = %+v, want the last failure first and the panic second", captures)
+	}
+
+	rate := 1.5
+	if _, err := capture.Configure(models.DebugCaptureRequest{SampleRate: &rate}); err != models.ErrInvalidSampleRate {
+		t.Errorf("Configure(sample_rate=1.5) error = %v, want ErrInvalidSampleRate", err)
+	}
+	capture.Clear()
+	if state := capture.State(); state.Captured != 0 || len(capture.List()) != 0 {
+		t.Errorf("State() after Clear() = %+v, want empty", state)
+	}
+}
@@ -0,0 +1,91 @@
+package middleware_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"analytics-dashboard-api/internal/middleware"
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/internal/timing"
+	"analytics-dashboard-api/internal/utils"
+)
+
+func timedHandler(keys middleware.APIKeys) http.Handler {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		timing.FromContext(r.Context()).Since(timing.PhaseDB, "top_products", time.Now().Add(-5*time.Millisecond))
+		utils.WriteJSONResponse(w, http.StatusOK, map[string]bool{"ok": true})
+	})
+	return middleware.APIKeyAuth(keys, "/api/v1/")(middleware.DebugTiming(keys)(handler))
+}
+
+func TestDebugTiming(t *testing.T) {
+	handler := timedHandler(nil)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/analytics?debug_timing=true", nil))
+
+	var body struct {
+		OK          bool                   `json:"ok"`
+		DebugTiming models.TimingBreakdown `json:"debug_timing"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("body %s: %v", rec.Body.String(), err)
+	}
+	if !body.OK {
+		t.Errorf("body = %s, want the handler's fields kept", rec.Body.String())
+	}
+	if got := body.DebugTiming.Phases[timing.PhaseDB]; got < 5 {
+		t.Errorf("db phase = %vms, want at least 5", got)
+	}
+	for _, phase := range []string{timing.PhaseQueue, timing.PhaseSerialize, timing.PhaseOther} {
+		if _, ok := body.DebugTiming.Phases[phase]; !ok {
+			t.Errorf("phases = %v, want %s", body.DebugTiming.Phases, phase)
+		}
+	}
+	names := make([]string, 0, len(body.DebugTiming.Spans))
+	for _, span := range body.DebugTiming.Spans {
+		names = append(names, span.Phase+"/"+span.Name)
+	}
+	if got := strings.Join(names, ","); got != "db/top_products,serialize/response" {
+		t.Errorf("spans = %s, want db/top_products,serialize/response", got)
+	}
+	if header := rec.Header().Get("Server-Timing"); !strings.Contains(header, "db;dur=") || !strings.Contains(header, "total;dur=") {
+		t.Errorf("Server-Timing = %q, want db and total metrics", header)
+	}
+	if got := rec.Header().Get("Cache-Control"); got != "no-store" {
+		t.Errorf("Cache-Control = %q, want no-store", got)
+	}
+}
+
+func TestDebugTiming_NotRequested(t *testing.T) {
+	rec := httptest.NewRecorder()
+	timedHandler(nil).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/analytics", nil))
+
+	if strings.Contains(rec.Body.String(), "debug_timing") || rec.Header().Get("Server-Timing") != "" {
+		t.Errorf("response %s %v carries timings it did not ask for", rec.Body.String(), rec.Header())
+	}
+}
+
+func TestDebugTiming_RequiresAPIKey(t *testing.T) {
+	handler := timedHandler(middleware.APIKeys{"secret-key": "ops"})
+
+	// Outside the keyed prefix the request passes without a key, and the
+	// breakdown is withheld
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health?debug_timing=true", nil))
+	if strings.Contains(rec.Body.String(), "debug_timing") {
+		t.Errorf("unauthenticated response = %s, want no debug_timing", rec.Body.String())
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/analytics?debug_timing=true", nil)
+	req.Header.Set(middleware.APIKeyHeader, "secret-key")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if !strings.Contains(rec.Body.String(), `"debug_timing":`) {
+		t.Errorf("authenticated response = %s, want debug_timing", rec.Body.String())
+	}
+}
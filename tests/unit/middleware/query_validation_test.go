@@ -0,0 +1,131 @@
+package middleware_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"analytics-dashboard-api/internal/httpquery"
+	"analytics-dashboard-api/internal/middleware"
+	"analytics-dashboard-api/internal/utils"
+
+	"github.com/gorilla/mux"
+)
+
+// mockLogger is a simple mock implementation of logger.Logger
+type mockLogger struct{}
+
+func (m *mockLogger) Debug(msg string, fields ...interface{}) {}
+func (m *mockLogger) Info(msg string, fields ...interface{})  {}
+func (m *mockLogger) Warn(msg string, fields ...interface{})  {}
+func (m *mockLogger) Error(msg string, fields ...interface{}) {}
+
+var testSpecs = []middleware.ParamSpec{
+	{Name: "limit", Type: middleware.ParamInt, Min: 0, Max: 1000},
+	{Name: "sample", Type: middleware.ParamFloat, Min: 0, Max: 1},
+	{Name: "from", Type: middleware.ParamDate},
+	{Name: "order", Type: middleware.ParamEnum, Values: []string{"asc", "desc"}},
+	{Name: "search", Type: middleware.ParamString},
+	{Name: "country", Type: middleware.ParamList},
+}
+
+// distinctValues returns a comma-separated list of n different values
+func distinctValues(n int) string {
+	values := make([]string, n)
+	for i := range values {
+		values[i] = strconv.Itoa(i)
+	}
+	return strings.Join(values, ",")
+}
+
+func TestValidateQuery(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		invalid []string
+	}{
+		{"valid", "limit=10&sample=0.5&from=2024-01-31&order=asc&search=x", nil},
+		{"empty values ignored", "limit=&from=", nil},
+		{"malformed int", "limit=abc", []string{"limit"}},
+		{"out of range", "limit=5000&sample=2", []string{"limit", "sample"}},
+		{"bad date", "from=2024-13-01", []string{"from"}},
+		{"bad enum", "order=up", []string{"order"}},
+		{"unknown", "limt=10", []string{"limt"}},
+		{"repeated", "limit=1&limit=2", []string{"limit"}},
+		{"list repeated", "country=A,B&country=C", nil},
+		{"list too long", "country=" + distinctValues(httpquery.MaxFilterValues+1), []string{"country"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, _ := url.ParseQuery(tt.query)
+			got := middleware.ValidateQuery(query, testSpecs)
+			if len(got) != len(tt.invalid) {
+				t.Fatalf("ValidateQuery(%q) = %v, want params %v", tt.query, got, tt.invalid)
+			}
+			for i, name := range tt.invalid {
+				if got[i].Name != name || got[i].Reason == "" {
+					t.Errorf("ValidateQuery(%q)[%d] = %+v, want param %q with a reason", tt.query, i, got[i], name)
+				}
+			}
+		})
+	}
+}
+
+func newValidatedRouter(mode string) *mux.Router {
+	router := mux.NewRouter()
+	router.Use(middleware.QueryValidation(map[string][]middleware.ParamSpec{
+		"GET /items": testSpecs,
+	}, mode, &mockLogger{}))
+	router.HandleFunc("/items", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}).Methods("GET")
+	router.HandleFunc("/other", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}).Methods("GET")
+	return router
+}
+
+func TestQueryValidation_Strict(t *testing.T) {
+	router := newValidatedRouter(middleware.ValidationStrict)
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/items?limit=abc&foo=1", nil))
+
+	if recorder.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", recorder.Code, http.StatusBadRequest)
+	}
+
+	var response utils.ValidationErrorResponse
+	if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(response.InvalidParams) != 2 || response.InvalidParams[0].Name != "foo" || response.InvalidParams[1].Name != "limit" {
+		t.Errorf("invalid_params = %+v, want foo and limit", response.InvalidParams)
+	}
+
+	// Routes without a spec are not checked
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/other?limit=abc", nil))
+	if recorder.Code != http.StatusOK {
+		t.Errorf("unregistered route status = %d, want %d", recorder.Code, http.StatusOK)
+	}
+}
+
+func TestQueryValidation_Warn(t *testing.T) {
+	router := newValidatedRouter(middleware.ValidationWarn)
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/items?limit=abc", nil))
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", recorder.Code, http.StatusOK)
+	}
+	if recorder.Header().Get("Warning") == "" {
+		t.Error("expected a Warning header for ignored parameters")
+	}
+}
@@ -0,0 +1,113 @@
+package middleware_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"analytics-dashboard-api/internal/middleware"
+	"analytics-dashboard-api/internal/utils"
+
+	"github.com/gorilla/mux"
+)
+
+func TestRateLimit(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	handler := middleware.APIKeyAuth(middleware.APIKeys{"k-dash": "dashboard", "k-mob": "mobile"}, "/api/v1/")(
+		middleware.RateLimit(0.5, 2, "/api/v1/")(ok))
+
+	serve := func(path, key, addr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.RemoteAddr = addr
+		if key != "" {
+			req.Header.Set(middleware.APIKeyHeader, key)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// The burst is spent from one address, then the key's bucket is empty
+	// wherever it is used from
+	for i, addr := range []string{"10.0.0.1:4000", "10.0.0.1:4001"} {
+		if rec := serve("/api/v1/analytics", "k-dash", addr); rec.Code != http.StatusOK {
+			t.Fatalf("request %d = %d, want 200", i+1, rec.Code)
+		}
+	}
+	rec := serve("/api/v1/analytics", "k-dash", "10.0.0.2:4000")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("request over the burst = %d, want 429", rec.Code)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "2" {
+		t.Errorf("Retry-After = %q, want 2", got)
+	}
+	var body utils.ErrorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.Code != http.StatusTooManyRequests {
+		t.Errorf("body %q is not a 429 ErrorResponse", rec.Body.String())
+	}
+
+	// Other keys and the probes are unaffected
+	if rec := serve("/api/v1/analytics", "k-mob", "10.0.0.1:4000"); rec.Code != http.StatusOK {
+		t.Errorf("another key = %d, want 200", rec.Code)
+	}
+	for _, path := range []string{"/health", "/health/live", "/ready", "/metrics"} {
+		if rec := serve(path, "k-dash", "10.0.0.1:4000"); rec.Code != http.StatusOK {
+			t.Errorf("%s = %d, want 200", path, rec.Code)
+		}
+	}
+}
+
+func TestRateLimitByAddress(t *testing.T) {
+	handler := middleware.RateLimit(1, 1, "/api/v1/")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	cases := []struct {
+		addr string
+		want int
+	}{
+		{"192.0.2.1:5000", http.StatusOK},
+		{"192.0.2.1:5001", http.StatusTooManyRequests},
+		{"192.0.2.2:5000", http.StatusOK},
+		{"[2001:db8::1]:5000", http.StatusOK},
+		{"[2001:db8::1]:5001", http.StatusTooManyRequests},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest("GET", "/api/v1/analytics", nil)
+		req.RemoteAddr = tc.addr
+		req.Header.Set("X-Forwarded-For", "203.0.113.9")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tc.want {
+			t.Errorf("request from %s = %d, want %d", tc.addr, rec.Code, tc.want)
+		}
+	}
+
+	off := middleware.RateLimit(0, 1, "/api/v1/")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	for i := 0; i < 5; i++ {
+		rec := httptest.NewRecorder()
+		off.ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/analytics", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("request %d with limiting off = %d, want 200", i+1, rec.Code)
+		}
+	}
+}
+
+func TestRateLimitSharedAcrossRequestsThroughMux(t *testing.T) {
+	// mux applies middleware to the matched handler on every request, so the
+	// buckets must outlive each wrapping
+	router := mux.NewRouter()
+	router.Use(middleware.RateLimit(1, 1, "/api/v1/"))
+	router.HandleFunc("/api/v1/analytics", func(w http.ResponseWriter, r *http.Request) {})
+
+	codes := make([]int, 2)
+	for i := range codes {
+		req := httptest.NewRequest("GET", "/api/v1/analytics", nil)
+		req.RemoteAddr = "192.0.2.1:5000"
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		codes[i] = rec.Code
+	}
+	if codes[0] != http.StatusOK || codes[1] != http.StatusTooManyRequests {
+		t.Errorf("statuses = %v, want 200 then 429", codes)
+	}
+}
@@ -0,0 +1,90 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"analytics-dashboard-api/internal/middleware"
+
+	"github.com/gorilla/mux"
+)
+
+func newCachedRouter(cache *middleware.ResponseCache, calls *int) *mux.Router {
+	router := mux.NewRouter()
+	router.Use(cache.Middleware)
+	router.HandleFunc("/kpi", func(w http.ResponseWriter, r *http.Request) {
+		*calls++
+		if r.URL.Query().Get("partial") != "" {
+			w.Header().Set("Cache-Control", "no-store")
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Link", `</kpi?offset=10>; rel="next"`)
+		w.Write([]byte(`{"ok":true}`))
+	}).Methods("GET")
+	router.HandleFunc("/live", func(w http.ResponseWriter, r *http.Request) { *calls++ }).Methods("GET")
+	router.HandleFunc("/targets", func(w http.ResponseWriter, r *http.Request) {}).Methods("POST")
+	return router
+}
+
+func serve(router http.Handler, method, target string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(method, target, nil))
+	return rec
+}
+
+func TestResponseCache(t *testing.T) {
+	var version uint64 = 1
+	cache := middleware.NewResponseCache(map[string]time.Duration{"GET /kpi": time.Minute}, 10, func() uint64 { return version })
+	calls := 0
+	router := newCachedRouter(cache, &calls)
+
+	if rec := serve(router, "GET", "/kpi?b=2&a=1"); rec.Header().Get("X-Cache") != "MISS" {
+		t.Fatalf("first request X-Cache = %q, want MISS", rec.Header().Get("X-Cache"))
+	}
+	rec := serve(router, "GET", "/kpi?a=1&b=2")
+	if rec.Header().Get("X-Cache") != "HIT" || rec.Body.String() != `{"ok":true}` || rec.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("reordered query = %q %q, want a HIT with the stored body", rec.Header().Get("X-Cache"), rec.Body.String())
+	}
+	if rec.Header().Get("Link") != `</kpi?offset=10>; rel="next"` {
+		t.Errorf("HIT Link = %q, want the stored pagination links", rec.Header().Get("Link"))
+	}
+	if calls != 1 {
+		t.Errorf("handler calls = %d, want 1", calls)
+	}
+
+	version++
+	if rec := serve(router, "GET", "/kpi?a=1&b=2"); rec.Header().Get("X-Cache") != "MISS" {
+		t.Errorf("after a data version change X-Cache = %q, want MISS", rec.Header().Get("X-Cache"))
+	}
+
+	serve(router, "POST", "/targets")
+	if rec := serve(router, "GET", "/kpi?a=1&b=2"); rec.Header().Get("X-Cache") != "MISS" {
+		t.Errorf("after a POST X-Cache = %q, want MISS", rec.Header().Get("X-Cache"))
+	}
+
+	serve(router, "GET", "/kpi?partial=1")
+	serve(router, "GET", "/kpi?partial=1")
+	serve(router, "GET", "/live")
+	serve(router, "GET", "/live")
+	if calls != 7 {
+		t.Errorf("handler calls = %d, want no-store responses and routes without a TTL to bypass the cache", calls)
+	}
+
+	if stats := cache.Stats(); stats.Entries != 1 || stats.Hits != 1 {
+		t.Errorf("Stats() = %+v, want 1 entry and 1 hit", stats)
+	}
+}
+
+func TestResponseCache_Expiry(t *testing.T) {
+	cache := middleware.NewResponseCache(map[string]time.Duration{"GET /kpi": time.Millisecond}, 10, func() uint64 { return 1 })
+	calls := 0
+	router := newCachedRouter(cache, &calls)
+
+	serve(router, "GET", "/kpi")
+	time.Sleep(5 * time.Millisecond)
+	if rec := serve(router, "GET", "/kpi"); rec.Header().Get("X-Cache") != "MISS" || calls != 2 {
+		t.Errorf("expired entry X-Cache = %q after %d calls, want a MISS", rec.Header().Get("X-Cache"), calls)
+	}
+}
@@ -0,0 +1,73 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"analytics-dashboard-api/internal/config"
+	"analytics-dashboard-api/internal/handlers"
+	"analytics-dashboard-api/internal/middleware"
+
+	"github.com/gorilla/mux"
+)
+
+func TestResponseTimeouts(t *testing.T) {
+	resolve, err := handlers.ResponseTimeouts(config.ServerConfig{
+		DataTimeout:      30 * time.Second,
+		AdminTimeout:     30 * time.Minute,
+		TimeoutOverrides: "/analytics/refresh=0; /meta/values=5s",
+	})
+	if err != nil {
+		t.Fatalf("ResponseTimeouts: %v", err)
+	}
+
+	cases := []struct {
+		method, template string
+		want             time.Duration
+	}{
+		{"GET", "/api/v1/analytics", 30 * time.Second},
+		{"GET", "/api/v1/admin/stats", 30 * time.Minute},
+		{"POST", "/api/v1/datasets", 30 * time.Minute},
+		{"GET", "/api/v1/exports/{id}/download", 30 * time.Minute},
+		{"POST", "/api/v1/analytics/refresh", 0},
+		{"GET", "/api/v1/meta/values", 5 * time.Second},
+	}
+	for _, tc := range cases {
+		if got, ok := resolve(tc.method, tc.template); !ok || got != tc.want {
+			t.Errorf("%s %s = %s, %v; want %s", tc.method, tc.template, got, ok, tc.want)
+		}
+	}
+	if _, ok := resolve("GET", "/health"); ok {
+		t.Error("GET /health resolved, want it left to the server timeouts")
+	}
+
+	for _, overrides := range []string{"/meta/values", "meta/values=5s", "/meta/values=-1s"} {
+		if _, err := handlers.ResponseTimeouts(config.ServerConfig{TimeoutOverrides: overrides}); err == nil {
+			t.Errorf("ResponseTimeouts(%q) succeeded, want an error", overrides)
+		}
+	}
+}
+
+func TestResponseTimeoutSetsDeadline(t *testing.T) {
+	resolve := func(method, template string) (time.Duration, bool) {
+		return map[string]time.Duration{"/fast": time.Second, "/unbounded": 0}[template], template != "/other"
+	}
+	deadlines := map[string]bool{}
+	router := mux.NewRouter()
+	router.Use(middleware.ResponseTimeout(resolve, &mockLogger{}))
+	for _, path := range []string{"/fast", "/unbounded", "/other"} {
+		router.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			deadline, ok := r.Context().Deadline()
+			deadlines[r.URL.Path] = ok && time.Until(deadline) <= time.Second
+		})
+	}
+
+	for _, path := range []string{"/fast", "/unbounded", "/other"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+	if !deadlines["/fast"] || deadlines["/unbounded"] || deadlines["/other"] {
+		t.Errorf("context deadlines set = %v, want only /fast", deadlines)
+	}
+}
@@ -0,0 +1,106 @@
+package models_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"analytics-dashboard-api/internal/models"
+)
+
+func TestMoney_String(t *testing.T) {
+	tests := []struct {
+		money models.Money
+		want  string
+	}{
+		{0, "0.00"},
+		{5, "0.05"},
+		{123450, "1234.50"},
+		{-5, "-0.05"},
+		{-123456, "-1234.56"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.money.String(); got != tt.want {
+			t.Errorf("Money(%d).String() = %q, want %q", int64(tt.money), got, tt.want)
+		}
+	}
+}
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    models.Money
+		wantErr bool
+	}{
+		{input: "1234.5", want: 123450},
+		{input: "0.10", want: 10},
+		{input: "42", want: 4200},
+		{input: "-3.07", want: -307},
+		{input: "1.005", wantErr: true},
+		{input: "abc", wantErr: true},
+		{input: ".50", wantErr: true},
+		{input: "1.-5", wantErr: true},
+		{input: "--1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := models.ParseMoney(tt.input)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseMoney(%q) expected error, got %d", tt.input, int64(got))
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseMoney(%q) unexpected error = %v", tt.input, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseMoney(%q) = %d, want %d", tt.input, int64(got), int64(tt.want))
+		}
+	}
+}
+
+func TestMoney_SumHasNoFloatDrift(t *testing.T) {
+	var total models.Money
+	for i := 0; i < 1000; i++ {
+		total += models.MoneyFromFloat(0.10)
+	}
+	if total.String() != "100.00" {
+		t.Errorf("sum of 1000 x 0.10 = %s, want 100.00", total)
+	}
+}
+
+func TestMoney_MarshalJSONFormats(t *testing.T) {
+	defer models.SetMoneyFormat(models.MoneyFormatNumber)
+
+	value := struct {
+		Revenue models.Money `json:"revenue"`
+	}{Revenue: 123450}
+
+	tests := []struct {
+		format models.MoneyFormat
+		want   string
+	}{
+		{models.MoneyFormatNumber, `{"revenue":1234.50}`},
+		{models.MoneyFormatString, `{"revenue":"1234.50"}`},
+		{models.MoneyFormatCents, `{"revenue":123450}`},
+	}
+
+	for _, tt := range tests {
+		if err := models.SetMoneyFormat(tt.format); err != nil {
+			t.Fatalf("SetMoneyFormat(%q) error = %v", tt.format, err)
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			t.Fatalf("json.Marshal() error = %v", err)
+		}
+		if string(data) != tt.want {
+			t.Errorf("format %s: json = %s, want %s", tt.format, data, tt.want)
+		}
+	}
+
+	if err := models.SetMoneyFormat("float"); err == nil {
+		t.Error("SetMoneyFormat(\"float\") expected error")
+	}
+}
@@ -260,3 +260,66 @@ func TestTransaction_ParseCSVRow_AlternativeDateFormats(t *testing.T) {
 		})
 	}
 }
+
+func TestTransaction_ParseCSVRow_ISODateEdgeCases(t *testing.T) {
+	tests := []struct {
+		dateStr string
+		want    time.Time
+		wantErr bool
+	}{
+		{dateStr: "2024-02-29", want: time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{dateStr: "2023-12-31", want: time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC)},
+		{dateStr: "2023-02-29", wantErr: true},
+		{dateStr: "2023-13-01", wantErr: true},
+		{dateStr: "2023-00-10", wantErr: true},
+		{dateStr: "2023-04-31", wantErr: true},
+		{dateStr: "2023-1a-01", wantErr: true},
+		{dateStr: "2023/01/15", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		row := []string{"T1", tt.dateStr, "U1", "USA", "CA", "P1", "Product", "Category", "1", "1", "1", "1"}
+		var transaction models.Transaction
+		err := transaction.ParseCSVRow(row)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: parsed as %v, want an error", tt.dateStr, transaction.TransactionDate)
+			}
+			continue
+		}
+		if err != nil || !transaction.TransactionDate.Equal(tt.want) || transaction.TransactionDate.Location() != time.UTC {
+			t.Errorf("%s: got %v, %v; want %v", tt.dateStr, transaction.TransactionDate, err, tt.want)
+		}
+	}
+}
+
+func TestSetDateLayouts(t *testing.T) {
+	defer models.SetDateLayouts("")
+
+	if err := models.SetDateLayouts("02.01.2006; Jan 2, 2006"); err != nil {
+		t.Fatalf("SetDateLayouts() error = %v", err)
+	}
+	row := []string{"T1", "15.01.2023", "U1", "USA", "CA", "P1", "Product", "Category", "1", "1", "1", "1", "Dec 1, 2022"}
+	var transaction models.Transaction
+	if err := transaction.ParseCSVRow(row); err != nil {
+		t.Fatalf("ParseCSVRow() error = %v", err)
+	}
+	if want := time.Date(2023, 1, 15, 0, 0, 0, 0, time.UTC); !transaction.TransactionDate.Equal(want) {
+		t.Errorf("TransactionDate = %v, want %v", transaction.TransactionDate, want)
+	}
+	if want := time.Date(2022, 12, 1, 0, 0, 0, 0, time.UTC); !transaction.AddedDate.Equal(want) {
+		t.Errorf("AddedDate = %v, want %v", transaction.AddedDate, want)
+	}
+
+	// The built-in layouts no longer apply
+	row[1] = "2023-01-15"
+	if err := transaction.ParseCSVRow(row); err == nil {
+		t.Error("ParseCSVRow() accepted a date outside the configured layouts")
+	}
+
+	for _, spec := range []string{"01/02", "2006-01", "15:04", " ; "} {
+		if err := models.SetDateLayouts(spec); err == nil {
+			t.Errorf("SetDateLayouts(%q) succeeded, want an error", spec)
+		}
+	}
+}
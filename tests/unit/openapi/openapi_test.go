@@ -0,0 +1,87 @@
+package openapi_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"analytics-dashboard-api/internal/openapi"
+)
+
+type Amount int64
+
+func (a Amount) MarshalJSON() ([]byte, error) { return json.Marshal(float64(a) / 100) }
+
+type Node struct {
+	Name     string    `json:"name"`
+	Children []*Node   `json:"children,omitempty"`
+	Price    Amount    `json:"price"`
+	Seen     time.Time `json:"seen"`
+	Note     *string   `json:"note"`
+	Secret   string    `json:"-"`
+	internal int
+}
+
+type envelope struct {
+	paging
+	Data []Node `json:"data"`
+}
+
+type paging struct {
+	Total int64 `json:"total"`
+}
+
+func TestBuilder_Schema(t *testing.T) {
+	b := openapi.NewBuilder(openapi.Info{Title: "test", Version: "1"})
+	b.Override(Amount(0), openapi.Schema{Type: "number"})
+
+	got := b.Schema(envelope{})
+	// The unexported envelope is inlined with its embedded fields flattened
+	if got.Type != "object" || !reflect.DeepEqual(got.Required, []string{"total", "data"}) {
+		t.Fatalf("envelope = %+v, want an object requiring total and data", got)
+	}
+	if got.Properties["total"].Format != "int64" {
+		t.Errorf("total = %+v, want an int64", got.Properties["total"])
+	}
+	if ref := got.Properties["data"].Items.Ref; ref != "#/components/schemas/Node" {
+		t.Errorf("data items ref = %q, want the Node component", ref)
+	}
+
+	node := b.Document().Components.Schemas["Node"]
+	if node == nil {
+		t.Fatal("Node is not a component")
+	}
+	if !reflect.DeepEqual(node.Required, []string{"name", "price", "seen", "note"}) {
+		t.Errorf("Node required = %v, want every field without omitempty", node.Required)
+	}
+	if _, ok := node.Properties["Secret"]; ok {
+		t.Error(`a field tagged "-" is described`)
+	}
+	if _, ok := node.Properties["internal"]; ok {
+		t.Error("an unexported field is described")
+	}
+	if ref := node.Properties["children"].Items.Ref; ref != "#/components/schemas/Node" {
+		t.Errorf("children items ref = %q, want Node to refer to itself", ref)
+	}
+	if price := node.Properties["price"]; price.Type != "number" {
+		t.Errorf("price = %+v, want the override", price)
+	}
+	if seen := node.Properties["seen"]; seen.Format != "date-time" {
+		t.Errorf("seen = %+v, want a date-time", seen)
+	}
+	if note := node.Properties["note"]; note.Type != "string" || !note.Nullable {
+		t.Errorf("note = %+v, want a nullable string", note)
+	}
+}
+
+func TestBuilder_AddTwicePanics(t *testing.T) {
+	b := openapi.NewBuilder(openapi.Info{Title: "test", Version: "1"})
+	b.Add("GET", "/things", &openapi.Operation{OperationID: "listThings"})
+	defer func() {
+		if recover() == nil {
+			t.Error("adding GET /things twice did not panic")
+		}
+	}()
+	b.Add("get", "/things", &openapi.Operation{OperationID: "listThingsAgain"})
+}
@@ -0,0 +1,62 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"analytics-dashboard-api/internal/config"
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/internal/services"
+)
+
+func TestABCService_Classify(t *testing.T) {
+	queries := &fakeMetricQuerier{rows: map[string][]models.MetricRow{
+		"product": {
+			{Group: "Gadget", Values: map[string]float64{"revenue": 200}},
+			{Group: "Widget", Values: map[string]float64{"revenue": 700}},
+			{Group: "Gizmo", Values: map[string]float64{"revenue": 60}},
+			{Group: "Sprocket", Values: map[string]float64{"revenue": 40}},
+			{Group: "Returned", Values: map[string]float64{"revenue": 0}},
+		},
+	}}
+	abc := services.NewABCService(config.ABCConfig{ACutoff: 0.8, BCutoff: 0.95}, queries, &mockLogger{})
+
+	classification, err := abc.Classify(context.Background(), models.QueryOptions{Country: "Germany"}, models.ABCCutoffs{})
+	if err != nil {
+		t.Fatalf("Classify() error = %v", err)
+	}
+	if queries.opts[0].Country != "Germany" {
+		t.Errorf("query options = %+v, want the country filter passed on", queries.opts[0])
+	}
+
+	// Widget alone is 70%, so Gadget crosses the 80% cut-off and stays A;
+	// Gizmo starts at 90%, below 95%, and is B
+	want := map[string]string{"Widget": "A", "Gadget": "A", "Gizmo": "B", "Sprocket": "C", "Returned": "C"}
+	if len(classification.Products) != 5 || classification.Products[0].Product != "Widget" {
+		t.Fatalf("products = %+v, want Widget first", classification.Products)
+	}
+	for _, p := range classification.Products {
+		if p.Class != want[p.Product] {
+			t.Errorf("%s class = %s, want %s", p.Product, p.Class, want[p.Product])
+		}
+	}
+	if a := classification.Classes[0]; a.Products != 2 || a.Revenue != 900 || a.RevenueSharePct != 90 || a.ProductSharePct != 40 {
+		t.Errorf("class A = %+v, want 2 products with 90%% of revenue", a)
+	}
+	if p := classification.Products[1]; p.SharePct != 20 || p.CumulativePct != 90 {
+		t.Errorf("Gadget = %+v, want a 20%% share reaching 90%%", p)
+	}
+
+	classification, err = abc.Classify(context.Background(), models.QueryOptions{}, models.ABCCutoffs{A: 0.5})
+	if err != nil {
+		t.Fatalf("Classify(a=0.5) error = %v", err)
+	}
+	if classification.Products[1].Class != "B" || classification.Cutoffs.B != 0.95 {
+		t.Errorf("Classify(a=0.5) = %+v, want Gadget in B with the configured B cut-off", classification)
+	}
+
+	if _, err := abc.Classify(context.Background(), models.QueryOptions{}, models.ABCCutoffs{A: 0.99}); !errors.Is(err, models.ErrInvalidABCCutoffs) {
+		t.Errorf("Classify(a above b) error = %v, want ErrInvalidABCCutoffs", err)
+	}
+}
@@ -0,0 +1,195 @@
+package services_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/internal/services"
+)
+
+// staticMetrics is a MetricSource returning fixed values
+type staticMetrics struct {
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+func (m *staticMetrics) set(name string, value float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.values[name] = value
+}
+
+func (m *staticMetrics) GetAlertMetrics(ctx context.Context) (map[string]float64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	values := make(map[string]float64, len(m.values))
+	for k, v := range m.values {
+		values[k] = v
+	}
+	return values, nil
+}
+
+func TestAlertEngine_CreateRuleValidation(t *testing.T) {
+	engine, err := services.NewAlertEngine(t.TempDir(), &staticMetrics{}, time.Second, &mockLogger{})
+	if err != nil {
+		t.Fatalf("NewAlertEngine() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		rule    models.AlertRule
+		wantErr bool
+	}{
+		{
+			name: "valid rule",
+			rule: models.AlertRule{Name: "Low revenue", Metric: "daily_revenue", Operator: "<", Threshold: 100, WebhookURL: "https://example.com/hook"},
+		},
+		{
+			name:    "unknown metric",
+			rule:    models.AlertRule{Name: "Bad", Metric: "profit", Operator: "<", WebhookURL: "https://example.com/hook"},
+			wantErr: true,
+		},
+		{
+			name:    "unsupported operator",
+			rule:    models.AlertRule{Name: "Bad", Metric: "daily_revenue", Operator: "=~", WebhookURL: "https://example.com/hook"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid webhook",
+			rule:    models.AlertRule{Name: "Bad", Metric: "daily_revenue", Operator: "<", WebhookURL: "ftp://example.com"},
+			wantErr: true,
+		},
+		{
+			name:    "missing name",
+			rule:    models.AlertRule{Metric: "daily_revenue", Operator: "<", WebhookURL: "https://example.com/hook"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := engine.CreateRule(tt.rule)
+			if tt.wantErr {
+				if !errors.Is(err, models.ErrInvalidAlertRule) {
+					t.Errorf("CreateRule() error = %v, want ErrInvalidAlertRule", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("CreateRule() unexpected error = %v", err)
+			}
+		})
+	}
+}
+
+func TestAlertEngine_FiresOnceUntilRecovered(t *testing.T) {
+	var mu sync.Mutex
+	var received []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&payload)
+		mu.Lock()
+		received = append(received, payload)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	metrics := &staticMetrics{values: map[string]float64{"daily_revenue": 50}}
+	engine, err := services.NewAlertEngine(t.TempDir(), metrics, time.Second, &mockLogger{})
+	if err != nil {
+		t.Fatalf("NewAlertEngine() error = %v", err)
+	}
+
+	_, err = engine.CreateRule(models.AlertRule{
+		Name:       "Low revenue",
+		Metric:     "daily_revenue",
+		Operator:   "<",
+		Threshold:  100,
+		Channel:    "slack",
+		WebhookURL: server.URL,
+		Enabled:    true,
+	})
+	if err != nil {
+		t.Fatalf("CreateRule() error = %v", err)
+	}
+
+	ctx := context.Background()
+
+	// Dataset metrics are ignored until a refresh has loaded data
+	if fired, _ := engine.Evaluate(ctx, services.TriggerSchedule); len(fired) != 0 {
+		t.Fatalf("Evaluate() before load fired %d alerts, want 0", len(fired))
+	}
+
+	engine.HandleRefresh(ctx, nil)
+	if got := len(engine.History(0)); got != 1 {
+		t.Fatalf("History() after refresh = %d alerts, want 1", got)
+	}
+
+	// Still breaching: no repeat notification
+	if fired, _ := engine.Evaluate(ctx, services.TriggerSchedule); len(fired) != 0 {
+		t.Errorf("Evaluate() while firing returned %d alerts, want 0", len(fired))
+	}
+
+	// Recover, then breach again
+	metrics.set("daily_revenue", 500)
+	engine.Evaluate(ctx, services.TriggerSchedule)
+	metrics.set("daily_revenue", 10)
+	fired, err := engine.Evaluate(ctx, services.TriggerSchedule)
+	if err != nil {
+		t.Fatalf("Evaluate() error = %v", err)
+	}
+	if len(fired) != 1 || fired[0].Value != 10 || !fired[0].Delivered {
+		t.Errorf("Evaluate() after recovery = %+v, want one delivered alert with value 10", fired)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(received) != 2 {
+		t.Fatalf("webhook received %d payloads, want 2", len(received))
+	}
+	if _, ok := received[0]["text"]; !ok || len(received[0]) != 1 {
+		t.Errorf("slack payload = %v, want only a text field", received[0])
+	}
+}
+
+func TestAlertEngine_RefreshFailures(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	engine, err := services.NewAlertEngine(t.TempDir(), &staticMetrics{values: map[string]float64{}}, time.Second, &mockLogger{})
+	if err != nil {
+		t.Fatalf("NewAlertEngine() error = %v", err)
+	}
+
+	_, err = engine.CreateRule(models.AlertRule{
+		Name:       "Refresh failing",
+		Metric:     "refresh_failures",
+		Operator:   ">=",
+		Threshold:  2,
+		WebhookURL: server.URL,
+		Enabled:    true,
+	})
+	if err != nil {
+		t.Fatalf("CreateRule() error = %v", err)
+	}
+
+	ctx := context.Background()
+	engine.HandleRefresh(ctx, errors.New("load failed"))
+	if got := len(engine.History(0)); got != 0 {
+		t.Fatalf("History() after one failure = %d alerts, want 0", got)
+	}
+
+	engine.HandleRefresh(ctx, errors.New("load failed"))
+	history := engine.History(0)
+	if len(history) != 1 || history[0].Value != 2 || history[0].Trigger != services.TriggerRefresh {
+		t.Errorf("History() after two failures = %+v, want one refresh alert with value 2", history)
+	}
+}
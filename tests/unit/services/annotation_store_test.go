@@ -0,0 +1,130 @@
+package services_test
+
+import (
+	"errors"
+	"testing"
+
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/internal/services"
+)
+
+// mockLogger is a simple mock implementation of logger.Logger
+type mockLogger struct{}
+
+func (m *mockLogger) Debug(msg string, fields ...interface{}) {}
+func (m *mockLogger) Info(msg string, fields ...interface{})  {}
+func (m *mockLogger) Warn(msg string, fields ...interface{})  {}
+func (m *mockLogger) Error(msg string, fields ...interface{}) {}
+
+func TestAnnotationStore_CreateAndList(t *testing.T) {
+	dir := t.TempDir()
+	store, err := services.NewAnnotationStore(dir, &mockLogger{})
+	if err != nil {
+		t.Fatalf("NewAnnotationStore() error = %v", err)
+	}
+
+	annotations := []models.Annotation{
+		{Date: "2024-03-01", EndDate: "2024-03-15", Title: "Spring campaign"},
+		{Date: "2024-01-10", Title: "Price change"},
+		{Date: "2024-06-01", Title: "Summer sale"},
+	}
+	for _, a := range annotations {
+		created, err := store.Create(a)
+		if err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+		if created.ID == "" {
+			t.Errorf("Create() did not assign an ID")
+		}
+	}
+
+	tests := []struct {
+		name      string
+		from      string
+		to        string
+		wantTitle []string
+	}{
+		{
+			name:      "no bounds sorted by date",
+			wantTitle: []string{"Price change", "Spring campaign", "Summer sale"},
+		},
+		{
+			name:      "range overlapping a multi-day event",
+			from:      "2024-03-10",
+			to:        "2024-05-01",
+			wantTitle: []string{"Spring campaign"},
+		},
+		{
+			name:      "open upper bound",
+			from:      "2024-04-01",
+			wantTitle: []string{"Summer sale"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := store.List(tt.from, tt.to)
+			if len(got) != len(tt.wantTitle) {
+				t.Fatalf("List() returned %d annotations, want %d", len(got), len(tt.wantTitle))
+			}
+			for i, title := range tt.wantTitle {
+				if got[i].Title != title {
+					t.Errorf("List()[%d].Title = %s, want %s", i, got[i].Title, title)
+				}
+			}
+		})
+	}
+
+	// Annotations survive a reload from disk
+	reloaded, err := services.NewAnnotationStore(dir, &mockLogger{})
+	if err != nil {
+		t.Fatalf("NewAnnotationStore() reload error = %v", err)
+	}
+	if got := len(reloaded.List("", "")); got != len(annotations) {
+		t.Errorf("reloaded store has %d annotations, want %d", got, len(annotations))
+	}
+}
+
+func TestAnnotationStore_CreateInvalid(t *testing.T) {
+	store, err := services.NewAnnotationStore(t.TempDir(), &mockLogger{})
+	if err != nil {
+		t.Fatalf("NewAnnotationStore() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		annotation models.Annotation
+	}{
+		{"invalid date", models.Annotation{Date: "2024-13-01", Title: "x"}},
+		{"invalid end date", models.Annotation{Date: "2024-01-01", EndDate: "soon", Title: "x"}},
+		{"end before start", models.Annotation{Date: "2024-02-01", EndDate: "2024-01-01", Title: "x"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := store.Create(tt.annotation)
+			if !errors.Is(err, models.ErrInvalidAnnotation) {
+				t.Errorf("Create() error = %v, want ErrInvalidAnnotation", err)
+			}
+		})
+	}
+}
+
+func TestAnnotationStore_Delete(t *testing.T) {
+	store, err := services.NewAnnotationStore(t.TempDir(), &mockLogger{})
+	if err != nil {
+		t.Fatalf("NewAnnotationStore() error = %v", err)
+	}
+
+	created, err := store.Create(models.Annotation{Date: "2024-01-01", Title: "x"})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	if err := store.Delete(created.ID); err != nil {
+		t.Errorf("Delete() error = %v", err)
+	}
+	if err := store.Delete(created.ID); !errors.Is(err, models.ErrAnnotationNotFound) {
+		t.Errorf("Delete() second call error = %v, want ErrAnnotationNotFound", err)
+	}
+}
@@ -0,0 +1,79 @@
+package services_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"analytics-dashboard-api/internal/config"
+	"analytics-dashboard-api/internal/services"
+)
+
+func TestBackupRetention_Prune(t *testing.T) {
+	dir := t.TempDir()
+	names := []string{
+		"20240310T020000Z",
+		"20240420T020000Z",
+		"20240425T020000Z",
+		"20240502T020000Z",
+		"20240503T020000Z",
+		"20240504T020000Z",
+	}
+	for _, name := range names {
+		if err := os.Mkdir(filepath.Join(dir, name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name, "transactions.parquet"), []byte("data"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := config.BackupConfig{Dir: dir, KeepLast: 2, KeepMonthly: 2}
+	retention := services.NewBackupRetention(cfg, services.NewJobQueue(&mockLogger{}), &mockLogger{})
+
+	listed, err := retention.List()
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(listed) != len(names) || listed[0].Name != "20240504T020000Z" {
+		t.Fatalf("List() = %+v, want all backups newest first", listed)
+	}
+	if !slices.Equal(listed[0].KeptBy, []string{"last", "monthly"}) || listed[0].SizeBytes != 4 {
+		t.Errorf("List()[0] = %+v, want kept as last and monthly with its size", listed[0])
+	}
+
+	removed, err := retention.Prune(context.Background())
+	if err != nil {
+		t.Fatalf("Prune() error = %v", err)
+	}
+	// Kept: the two newest, plus the newest of April as the second month
+	want := []string{"20240310T020000Z", "20240420T020000Z", "20240502T020000Z"}
+	if !slices.Equal(removed, want) {
+		t.Errorf("Prune() removed %v, want %v", removed, want)
+	}
+	for _, name := range want {
+		if _, err := os.Stat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+			t.Errorf("backup %s still exists after pruning", name)
+		}
+	}
+}
+
+func TestBackupRetention_PruneDisabled(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "20240101T000000Z"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	retention := services.NewBackupRetention(config.BackupConfig{Dir: dir}, services.NewJobQueue(&mockLogger{}), &mockLogger{})
+	removed, err := retention.Prune(context.Background())
+	if err != nil || len(removed) != 0 {
+		t.Fatalf("Prune() = %v, %v; want nothing removed without a policy", removed, err)
+	}
+
+	listed, _ := retention.List()
+	if len(listed) != 1 || listed[0].Prune {
+		t.Errorf("List() = %+v, want the backup kept", listed)
+	}
+}
@@ -0,0 +1,114 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"analytics-dashboard-api/internal/config"
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/internal/services"
+)
+
+func newTestClickHouse(t *testing.T, handler http.HandlerFunc) *services.ClickHouseService {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	service, err := services.NewClickHouseService(config.ClickHouseConfig{
+		URL:               server.URL,
+		Database:          "analytics",
+		User:              "reader",
+		TransactionsTable: "sales.transactions",
+		ProductsTable:     "products",
+		CustomersTable:    "customers",
+		Timeout:           5 * time.Second,
+	}, &mockLogger{})
+	if err != nil {
+		t.Fatalf("NewClickHouseService() error = %v", err)
+	}
+	return service
+}
+
+func TestClickHouseService_GetCountryRevenue(t *testing.T) {
+	service := newTestClickHouse(t, func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		body, _ := io.ReadAll(r.Body)
+
+		if query.Get("database") != "analytics" || r.Header.Get("X-ClickHouse-User") != "reader" {
+			t.Errorf("unexpected database %q or user %q", query.Get("database"), r.Header.Get("X-ClickHouse-User"))
+		}
+		if query.Get("param_filter_0_in_0") != "Germany" || query.Get("param_limit") != "10" || query.Get("param_offset") != "5" {
+			t.Errorf("unexpected parameters: %v", query)
+		}
+		if !strings.Contains(string(body), "FROM (SELECT * FROM sales.transactions WHERE lowerUTF8(country) IN (lowerUTF8({filter_0_in_0:String})))") {
+			t.Errorf("query does not filter by country:\n%s", body)
+		}
+
+		if !strings.Contains(string(body), "WITH TOTALS") {
+			t.Errorf("query does not compute totals:\n%s", body)
+		}
+
+		w.Write([]byte(`{"meta":[],"data":[["Germany","Widget",123450,3,6],["Germany","Gadget",5,1,1]],"totals":["","",200000,9,14],"rows":2}`))
+	})
+
+	got, totals, err := service.GetCountryRevenue(context.Background(), models.QueryOptions{Country: "Germany"}, 10, 5)
+	if err != nil {
+		t.Fatalf("GetCountryRevenue() error = %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("GetCountryRevenue() returned %d rows, want 2", len(got))
+	}
+	if got[0].ProductName != "Widget" || got[0].TotalRevenue != 123450 || got[0].TransactionCount != 3 {
+		t.Errorf("GetCountryRevenue()[0] = %+v", got[0])
+	}
+	if got[1].TotalRevenue.String() != "0.05" {
+		t.Errorf("GetCountryRevenue()[1].TotalRevenue = %s, want 0.05", got[1].TotalRevenue)
+	}
+	if totals.Revenue != 200000 || totals.Transactions != 9 || totals.Quantity != 14 {
+		t.Errorf("GetCountryRevenue() totals = %+v, want the WITH TOTALS row", totals)
+	}
+}
+
+func TestClickHouseService_Errors(t *testing.T) {
+	service := newTestClickHouse(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("Code: 60. DB::Exception: Table sales.transactions does not exist."))
+	})
+
+	_, err := service.GetTotalRecords(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "does not exist") {
+		t.Errorf("GetTotalRecords() error = %v, want the ClickHouse exception", err)
+	}
+
+	if _, err := service.Backup(context.Background(), t.TempDir()); !errors.Is(err, models.ErrNotSupported) {
+		t.Errorf("Backup() error = %v, want ErrNotSupported", err)
+	}
+}
+
+func TestClickHouseService_GetProductNotFound(t *testing.T) {
+	service := newTestClickHouse(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"meta":[],"data":[],"rows":0}`))
+	})
+
+	if _, _, err := service.GetProduct(context.Background(), "P404"); !errors.Is(err, models.ErrProductNotFound) {
+		t.Errorf("GetProduct() error = %v, want ErrProductNotFound", err)
+	}
+}
+
+func TestNewClickHouseService_InvalidTable(t *testing.T) {
+	_, err := services.NewClickHouseService(config.ClickHouseConfig{
+		URL:               "http://localhost:8123",
+		TransactionsTable: "transactions; DROP TABLE x",
+		ProductsTable:     "products",
+		CustomersTable:    "customers",
+	}, &mockLogger{})
+	if err == nil {
+		t.Error("NewClickHouseService() accepted an invalid table name")
+	}
+}
@@ -0,0 +1,85 @@
+package services_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"analytics-dashboard-api/internal/config"
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/internal/services"
+)
+
+func TestLoadColumnMapping(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "columns.yaml")
+	if err := os.WriteFile(path, []byte("transaction_id: Order ID\nquantity: 3\ncountry: Country\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	mapping, err := services.LoadColumnMapping(" Country = Ship To ;total_price=7", path)
+	if err != nil {
+		t.Fatalf("LoadColumnMapping() error = %v", err)
+	}
+	// The pairs apply over the file
+	want := "country=Ship To;quantity=3;total_price=7;transaction_id=Order ID"
+	if got := mapping.String(); got != want {
+		t.Errorf("mapping = %q, want %q", got, want)
+	}
+	if source := mapping["quantity"]; !source.ByIndex || source.Index != 3 {
+		t.Errorf("quantity = %+v, want position 3", source)
+	}
+
+	if mapping, err := services.LoadColumnMapping("", ""); mapping != nil || err != nil {
+		t.Errorf("LoadColumnMapping(empty) = %v, %v, want nil", mapping, err)
+	}
+	for _, spec := range []string{"revenue=Total", "country", "country=", "quantity=99999999999999999999"} {
+		if _, err := services.LoadColumnMapping(spec, ""); err == nil {
+			t.Errorf("LoadColumnMapping(%q) succeeded, want an error", spec)
+		}
+	}
+}
+
+func TestMemoryService_LoadsMappedColumns(t *testing.T) {
+	// Another export's layout: renamed headers, quantity under a duplicate name
+	const exported = "Order ID,Customer,Date,Ship To,Region,SKU,Name,Category,Unit Price,qty,qty,Line Total,Stock\n" +
+		"T1,U1,2024-01-05,Germany,Bavaria,P1,Widget,Tools,10.10,x,2,20.20,50\n" +
+		"T2,U2,2024-02-10,France,Normandy,P1,Widget,Tools,10.10,x,1,10.10,49\n"
+	path := filepath.Join(t.TempDir(), "export.csv")
+	if err := os.WriteFile(path, []byte(exported), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	mapping, err := services.LoadColumnMapping("transaction_id=Order ID;user_id=customer;transaction_date=Date;"+
+		"country=Ship To;product_id=SKU;product_name=Name;price=Unit Price;quantity=10;total_price=Line Total;stock_quantity=Stock", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	services.SetColumnMapping(mapping)
+	t.Cleanup(func() { services.SetColumnMapping(nil) })
+
+	service := services.NewMemoryService(config.DimensionsConfig{}, config.OutlierConfig{}, config.TestOrderConfig{}, &mockLogger{})
+	ctx := context.Background()
+	inspection, err := service.InspectSource(ctx, models.DataFormatCSV, path)
+	if err != nil {
+		t.Fatalf("InspectSource() error = %v", err)
+	}
+	if strings.Join(inspection.MissingColumns, ",") != "added_date" {
+		t.Errorf("missing columns = %v, want only added_date", inspection.MissingColumns)
+	}
+	if col := inspection.Columns[10]; col.Type != "INTEGER" {
+		t.Errorf("column at position 10 = %+v, want it read as quantity", col)
+	}
+
+	if err := service.LoadFromFile(ctx, models.DataFormatCSV, path); err != nil {
+		t.Fatalf("LoadFromFile() error = %v", err)
+	}
+	revenue, _, err := service.GetCountryRevenue(ctx, models.QueryOptions{}, 10, 0)
+	if err != nil {
+		t.Fatalf("GetCountryRevenue() error = %v", err)
+	}
+	if len(revenue) != 2 || revenue[0].Country != "Germany" || revenue[0].TotalRevenue != models.MoneyFromFloat(20.20) {
+		t.Errorf("GetCountryRevenue() = %+v, want Germany first with 20.20", revenue)
+	}
+}
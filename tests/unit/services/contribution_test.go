@@ -0,0 +1,85 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/internal/services"
+)
+
+// periodMetricQuerier returns revenue per country for the period starting
+// at each date
+type periodMetricQuerier struct {
+	revenue map[string]map[string]float64
+	opts    []models.QueryOptions
+}
+
+func (q *periodMetricQuerier) GetBaseMetrics(_ context.Context, opts models.QueryOptions, _ string) ([]models.MetricRow, error) {
+	q.opts = append(q.opts, opts)
+	var rows []models.MetricRow
+	for country, revenue := range q.revenue[opts.From.Format("2006-01-02")] {
+		rows = append(rows, models.MetricRow{Group: country, Values: map[string]float64{"revenue": revenue}})
+	}
+	return rows, nil
+}
+
+func TestContributionService_Analyze(t *testing.T) {
+	queries := &periodMetricQuerier{revenue: map[string]map[string]float64{
+		"2024-01-02": {"Germany": 100, "France": 50, "Spain": 30},
+		"2024-02-01": {"Germany": 160, "France": 40, "Italy": 10, "Spain": 30.1},
+	}}
+	contributions := services.NewContributionService(queries, &mockLogger{})
+
+	analysis, err := contributions.Analyze(context.Background(), models.ContributionRequest{
+		From:       time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+		To:         time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		Dimensions: []string{"country"},
+		Limit:      2,
+		Filters:    models.QueryOptions{Segment: "Corporate"},
+	})
+	if err != nil {
+		t.Fatalf("Analyze() error = %v", err)
+	}
+
+	// The 30 days from February 1 are compared with the 30 days before
+	if analysis.PreviousPeriod != (models.ContributionPeriod{From: "2024-01-02", To: "2024-01-31"}) {
+		t.Errorf("previous period = %+v, want 2024-01-02 to 2024-01-31", analysis.PreviousPeriod)
+	}
+	if len(queries.opts) != 2 || queries.opts[1].To != time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC) || queries.opts[0].Segment != "Corporate" {
+		t.Errorf("queries ran with %+v, want both periods with the segment filter", queries.opts)
+	}
+	if total := analysis.Total; total.Previous != 180 || total.Current != 240.1 || total.Change != 60.1 {
+		t.Errorf("total = %+v, want 180 to 240.10", total)
+	}
+
+	breakdown := analysis.Breakdowns[0]
+	if len(breakdown.Contributions) != 2 || breakdown.Contributions[0].Group != "Germany" || breakdown.Contributions[1].Group != "France" {
+		t.Fatalf("contributions = %+v, want Germany and France by the size of their change", breakdown.Contributions)
+	}
+	if share := breakdown.Contributions[0].SharePct; share == nil || *share < 99.8 || *share > 99.9 {
+		t.Errorf("Germany share = %v, want 60 of the 60.10 change", share)
+	}
+	if other := breakdown.Other; other == nil || other.Previous != 30 || other.Current != 40.1 || other.Change != 10.1 || breakdown.Groups != 4 {
+		t.Errorf("other = %+v of %d groups, want Italy and Spain summed", other, breakdown.Groups)
+	}
+}
+
+func TestContributionService_InvalidRequests(t *testing.T) {
+	contributions := services.NewContributionService(&periodMetricQuerier{}, &mockLogger{})
+	day := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	for name, request := range map[string]models.ContributionRequest{
+		"no dates":          {},
+		"inverted":          {From: day, To: day.AddDate(0, 0, -1)},
+		"ratio metric":      {Metric: "customers", From: day, To: day},
+		"unknown dimension": {From: day, To: day, Dimensions: []string{"region"}},
+		"limit":             {From: day, To: day, Limit: 1000},
+	} {
+		if _, err := contributions.Analyze(context.Background(), request); !errors.Is(err, models.ErrInvalidContribution) {
+			t.Errorf("%s: Analyze() error = %v, want ErrInvalidContribution", name, err)
+		}
+	}
+}
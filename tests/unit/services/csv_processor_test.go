@@ -0,0 +1,142 @@
+package services_test
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"analytics-dashboard-api/internal/services"
+)
+
+// writeTransactionsFile writes rows generated transactions to a temp file
+func writeTransactionsFile(tb testing.TB, rows int) string {
+	tb.Helper()
+	var b strings.Builder
+	b.WriteString("transaction_id,transaction_date,user_id,country,region,product_id,product_name,category,price,quantity,total_price,stock_quantity,added_date\n")
+	countries := []string{"Germany", "France", "United States", "Japan"}
+	for i := 0; i < rows; i++ {
+		fmt.Fprintf(&b, "T%d,2024-%02d-%02d,U%d,%s,Region %d,P%d,Product %d,Category %d,%d.%02d,%d,%d.%02d,%d,2023-12-01\n",
+			i, i%12+1, i%28+1, i%5000, countries[i%len(countries)], i%40, i%900, i%900, i%15,
+			i%500, i%100, i%5+1, i%2500, i%100, i%1000)
+	}
+	path := filepath.Join(tb.TempDir(), "transactions.csv")
+	if err := os.WriteFile(path, []byte(b.String()), 0o644); err != nil {
+		tb.Fatal(err)
+	}
+	return path
+}
+
+func BenchmarkCSVProcessor_ReadTransactions(b *testing.B) {
+	path := writeTransactionsFile(b, 100_000)
+	processor := services.NewCSVProcessor(&mockLogger{})
+	info, _ := os.Stat(path)
+	b.SetBytes(info.Size())
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, _, err := processor.ReadTransactions(path); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestCSVProcessor_ReadTransactionsQuoting(t *testing.T) {
+	content := "\uFEFF Transaction_ID ,transaction_date,user_id,country,region,product_id,product_name,category,price,quantity,total_price,stock_quantity\r\n" +
+		"T1,2024-01-15,U1,Germany,Europe,P1,\"Chair, \"\"Deluxe\"\"\",Furniture,10.50,2,21.00,5\r\n" +
+		"\r\n" +
+		"T2, 01/20/2024,U2,France,Europe,P2,\"Lamp\nwith shade\",Lighting,3,1,3,0\n" +
+		"T3,2024-02-01 10:30:00,U3,Japan,Asia,P3,Desk,Furniture,7,1,7,1"
+	path := filepath.Join(t.TempDir(), "transactions.csv")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, stats, err := services.NewCSVProcessor(&mockLogger{}).ReadTransactions(path)
+	if err != nil {
+		t.Fatalf("ReadTransactions: %v", err)
+	}
+	if len(got) != 3 || stats.Rows != 3 || stats.Bytes != int64(len(content)) {
+		t.Fatalf("read %d transactions, stats %+v; want 3 rows of %d bytes", len(got), stats, len(content))
+	}
+	if got[0].ProductName != `Chair, "Deluxe"` || got[0].TotalPrice != 21 {
+		t.Errorf("quoted row = %q, %v", got[0].ProductName, got[0].TotalPrice)
+	}
+	if got[1].ProductName != "Lamp\nwith shade" || got[1].TransactionDate.Day() != 20 {
+		t.Errorf("multi-line row = %q on %v", got[1].ProductName, got[1].TransactionDate)
+	}
+	if got[2].TransactionID != "T3" || got[2].TransactionDate.Hour() != 10 {
+		t.Errorf("row without a final newline = %+v", got[2])
+	}
+
+	for name, row := range map[string]string{
+		"bare quote":       "T1,2024-01-15,U1,Germany,Europe,P1,Ch\"air,Furniture,1,1,1,1\n",
+		"unclosed quote":   "T1,2024-01-15,U1,Germany,Europe,P1,\"Chair,Furniture,1,1,1,1\n",
+		"text after quote": "T1,2024-01-15,U1,Germany,Europe,P1,\"Chair\"x,Furniture,1,1,1,1\n",
+	} {
+		header := content[:strings.Index(content, "\n")+1]
+		if err := os.WriteFile(path, []byte(header+row), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		_, stats, err := services.NewCSVProcessor(&mockLogger{}).ReadTransactions(path)
+		var parseErr *csv.ParseError
+		if !errors.As(err, &parseErr) || stats.ParseErrors != 1 {
+			t.Errorf("%s: err = %v, parse errors %d; want a *csv.ParseError", name, err, stats.ParseErrors)
+		}
+	}
+}
+
+// BenchmarkCSVProcessor_ReadTransactionsReleased hands every batch back, as
+// the memory backend does after each load, so reads reuse its memory
+func BenchmarkCSVProcessor_ReadTransactionsReleased(b *testing.B) {
+	path := writeTransactionsFile(b, 100_000)
+	processor := services.NewCSVProcessor(&mockLogger{})
+	info, _ := os.Stat(path)
+	b.SetBytes(info.Size())
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		transactions, _, err := processor.ReadTransactions(path)
+		if err != nil {
+			b.Fatal(err)
+		}
+		processor.Release(transactions)
+	}
+}
+
+func TestCSVProcessor_ReleasedBatchIsReused(t *testing.T) {
+	processor := services.NewCSVProcessor(&mockLogger{})
+	large, _, err := processor.ReadTransactions(writeTransactionsFile(t, 500))
+	if err != nil {
+		t.Fatalf("ReadTransactions: %v", err)
+	}
+	processor.Release(large)
+
+	// A smaller file without added dates must not see the rows read before
+	path := filepath.Join(t.TempDir(), "small.csv")
+	content := "transaction_id,transaction_date,user_id,country,region,product_id,product_name,category,price,quantity,total_price,stock_quantity\n" +
+		"S1,2024-03-01,U1,Germany,Europe,P1,Chair,Furniture,10,1,10,5\n" +
+		"S2,2024-03-02,U2,France,Europe,P2,Lamp,Lighting,3,2,6,0\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got, _, err := processor.ReadTransactions(path)
+	if err != nil {
+		t.Fatalf("ReadTransactions: %v", err)
+	}
+	if len(got) != 2 || got[0].TransactionID != "S1" || got[1].TransactionID != "S2" {
+		t.Fatalf("transactions = %+v, want S1 and S2", got)
+	}
+	for _, transaction := range got {
+		if !transaction.AddedDate.IsZero() {
+			t.Errorf("%s added date = %v, want none", transaction.TransactionID, transaction.AddedDate)
+		}
+	}
+	processor.Release(got)
+	processor.Release(nil)
+}
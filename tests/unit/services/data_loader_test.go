@@ -0,0 +1,222 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"analytics-dashboard-api/internal/config"
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/internal/services"
+)
+
+// blockingLoader holds every load until release is closed or its context ends
+type blockingLoader struct {
+	release chan struct{}
+	loads   atomic.Int32
+	paths   []string // of the latest load
+	err     error
+}
+
+func (l *blockingLoader) LoadFromFile(ctx context.Context, _ string, paths ...string) error {
+	l.loads.Add(1)
+	l.paths = paths
+	select {
+	case <-l.release:
+		return l.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (l *blockingLoader) InspectSource(context.Context, string, string) (*models.SourceInspection, error) {
+	return nil, models.ErrNotSupported
+}
+
+func (l *blockingLoader) Rollback(context.Context) (*models.DataSnapshot, error) {
+	return nil, models.ErrNotSupported
+}
+
+func TestDataLoader_ReportsLoadingWhileInitialLoadRuns(t *testing.T) {
+	backend := &blockingLoader{release: make(chan struct{})}
+	loader := services.NewDataLoader(backend, "data.csv", config.DataConfig{LoadWait: 10 * time.Millisecond, LoadTimeout: time.Minute}, services.NewJobQueue(&mockLogger{}), nil, nil, &mockLogger{})
+
+	err := loader.EnsureInitialized(context.Background())
+	var loading *models.LoadingError
+	if !errors.As(err, &loading) || !errors.Is(err, models.ErrDataLoading) {
+		t.Fatalf("EnsureInitialized() error = %v, want a LoadingError", err)
+	}
+	if loading.RetryAfter <= 0 {
+		t.Errorf("RetryAfter = %s, want a positive delay", loading.RetryAfter)
+	}
+
+	// A second request joins the running load instead of starting another
+	if err := loader.EnsureInitialized(context.Background()); !errors.Is(err, models.ErrDataLoading) {
+		t.Errorf("second EnsureInitialized() error = %v, want ErrDataLoading", err)
+	}
+
+	close(backend.release)
+	deadline := time.Now().Add(time.Second)
+	for loader.EnsureInitialized(context.Background()) != nil {
+		if time.Now().After(deadline) {
+			t.Fatal("load never completed")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := backend.loads.Load(); got != 1 {
+		t.Errorf("LoadFromFile called %d times, want 1", got)
+	}
+}
+
+func TestDataLoader_FailedLoad(t *testing.T) {
+	backend := &blockingLoader{release: make(chan struct{}), err: errors.New("broken csv")}
+	close(backend.release)
+	loader := services.NewDataLoader(backend, "data.csv", config.DataConfig{LoadWait: time.Second, LoadTimeout: time.Minute}, services.NewJobQueue(&mockLogger{}), nil, nil, &mockLogger{})
+
+	if err := loader.EnsureInitialized(context.Background()); !errors.Is(err, models.ErrDataNotLoaded) {
+		t.Errorf("EnsureInitialized() error = %v, want ErrDataNotLoaded", err)
+	}
+	// The failure is not cached: the next request tries again
+	loader.EnsureInitialized(context.Background())
+	if got := backend.loads.Load(); got != 2 {
+		t.Errorf("LoadFromFile called %d times, want 2", got)
+	}
+}
+
+func TestDataLoader_CoalescesConcurrentFirstRequests(t *testing.T) {
+	backend := &blockingLoader{release: make(chan struct{}), err: errors.New("broken csv")}
+	loader := services.NewDataLoader(backend, "data.csv", config.DataConfig{LoadWait: 5 * time.Second, LoadTimeout: time.Minute}, services.NewJobQueue(&mockLogger{}), nil, nil, &mockLogger{})
+
+	const requests = 8
+	errs := make([]error, requests)
+	var wg sync.WaitGroup
+	for i := range requests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = loader.EnsureInitialized(context.Background())
+		}()
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for loader.Stats().CoalescedWaits < requests-1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("CoalescedWaits = %d, want %d", loader.Stats().CoalescedWaits, requests-1)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(backend.release)
+	wg.Wait()
+
+	// Every waiter gets the outcome of the one shared load
+	for i, err := range errs {
+		if !errors.Is(err, models.ErrDataNotLoaded) || !errors.Is(err, backend.err) {
+			t.Errorf("request %d error = %v, want the shared load failure", i, err)
+		}
+	}
+	stats := loader.Stats()
+	if got := backend.loads.Load(); got != 1 || stats.Loads != 1 || stats.Failures != 1 {
+		t.Errorf("LoadFromFile called %d times, stats = %+v, want one failed load", got, stats)
+	}
+}
+
+func TestDataLoader_ReloadTimeout(t *testing.T) {
+	backend := &blockingLoader{release: make(chan struct{})}
+	loader := services.NewDataLoader(backend, "data.csv", config.DataConfig{LoadWait: time.Second, LoadTimeout: time.Minute}, services.NewJobQueue(&mockLogger{}), nil, nil, &mockLogger{})
+
+	// The override replaces the configured timeout
+	err := loader.Reload(context.Background(), 20*time.Millisecond)
+	if !errors.Is(err, models.ErrLoadTimeout) || !errors.Is(err, models.ErrQueryTimeout) {
+		t.Fatalf("Reload() error = %v, want ErrLoadTimeout", err)
+	}
+
+	// A caller that stops waiting leaves the load running
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := loader.Reload(ctx, 0); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Reload() with a cancelled context error = %v, want context.Canceled", err)
+	}
+	close(backend.release)
+	deadline := time.Now().Add(time.Second)
+	for !loader.Stats().Loaded {
+		if time.Now().After(deadline) {
+			t.Fatal("abandoned reload never completed")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestDataLoader_LoadUpload(t *testing.T) {
+	backend := &blockingLoader{release: make(chan struct{})}
+	close(backend.release)
+	loader := services.NewDataLoader(backend, "data.csv", config.DataConfig{LoadWait: time.Second, LoadTimeout: time.Minute}, services.NewJobQueue(&mockLogger{}), nil, nil, &mockLogger{})
+
+	released := false
+	var releaseErr error
+	err := loader.LoadUpload(context.Background(), models.DatasetUpload{
+		Paths:   []string{"upload.csv"},
+		Release: func(err error) { released, releaseErr = true, err },
+	})
+	if err != nil {
+		t.Fatalf("LoadUpload() error = %v", err)
+	}
+	if len(backend.paths) != 1 || backend.paths[0] != "upload.csv" {
+		t.Errorf("loaded %v, want the uploaded file instead of the configured source", backend.paths)
+	}
+	if !released || releaseErr != nil {
+		t.Errorf("Release called = %v with %v, want it called once the load succeeded", released, releaseErr)
+	}
+	if loader.Version() != 1 {
+		t.Errorf("Version() = %d, want 1 after the upload", loader.Version())
+	}
+
+	// The upload counts as loaded data, so a request does not replace it
+	if err := loader.EnsureInitialized(context.Background()); err != nil {
+		t.Fatalf("EnsureInitialized() error = %v", err)
+	}
+	if got := backend.loads.Load(); got != 1 {
+		t.Errorf("LoadFromFile called %d times, want only the upload", got)
+	}
+}
+
+// persistentLoader reports data kept from an earlier run
+type persistentLoader struct {
+	*blockingLoader
+	persisted *models.PersistedData
+}
+
+func (l *persistentLoader) PersistedData(context.Context, []string) (*models.PersistedData, error) {
+	return l.persisted, nil
+}
+
+func TestDataLoader_ReusesPersistedData(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		persisted *models.PersistedData
+		wantLoads int32
+	}{
+		{"fresh", &models.PersistedData{}, 0},
+		{"stale", &models.PersistedData{Stale: true, Reason: "data.csv was modified"}, 1},
+		{"none", nil, 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			backend := &persistentLoader{blockingLoader: &blockingLoader{release: make(chan struct{})}, persisted: tc.persisted}
+			close(backend.release)
+			loader := services.NewDataLoader(backend, "data.csv", config.DataConfig{LoadWait: time.Second, LoadTimeout: time.Minute}, services.NewJobQueue(&mockLogger{}), nil, nil, &mockLogger{})
+
+			if err := loader.EnsureInitialized(context.Background()); err != nil {
+				t.Fatalf("EnsureInitialized() error = %v", err)
+			}
+			if got := backend.loads.Load(); got != tc.wantLoads {
+				t.Errorf("LoadFromFile called %d times, want %d", got, tc.wantLoads)
+			}
+			stats := loader.Stats()
+			if !stats.Loaded || stats.DataVersion != 1 || stats.Persisted != tc.persisted {
+				t.Errorf("stats = %+v, want loaded data at version 1 with the persisted state", stats)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"analytics-dashboard-api/internal/config"
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/internal/services"
+)
+
+func TestParseDatasets(t *testing.T) {
+	specs, err := services.ParseDatasets(" eu = ./data/eu.csv; us=./data/us.parquet;")
+	if err != nil {
+		t.Fatalf("ParseDatasets: %v", err)
+	}
+	want := []models.DatasetSpec{{ID: "eu", Path: "./data/eu.csv"}, {ID: "us", Path: "./data/us.parquet"}}
+	if len(specs) != len(want) || specs[0] != want[0] || specs[1] != want[1] {
+		t.Errorf("ParseDatasets = %+v, want %+v", specs, want)
+	}
+
+	for _, spec := range []string{"eu", "eu=", "EU=a.csv", "../x=a.csv", "eu=a.csv;eu=b.csv", "default=a.csv"} {
+		if _, err := services.ParseDatasets(spec); err == nil {
+			t.Errorf("ParseDatasets(%q) succeeded, want an error", spec)
+		}
+	}
+}
+
+func TestDatasetRegistry(t *testing.T) {
+	dir := t.TempDir()
+	euPath := filepath.Join(dir, "eu.csv")
+	if err := os.WriteFile(euPath, []byte(memoryTransactionsCSV), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	newLoader := func(repo services.Repository, path string) *services.DataLoader {
+		return services.NewDataLoader(repo, path, config.DataConfig{LoadWait: 5 * time.Second, LoadTimeout: time.Minute},
+			services.NewJobQueue(&mockLogger{}), nil, nil, &mockLogger{})
+	}
+	primary := services.NewMemoryService(config.DimensionsConfig{}, config.OutlierConfig{}, config.TestOrderConfig{}, &mockLogger{})
+	eu := services.NewMemoryService(config.DimensionsConfig{}, config.OutlierConfig{}, config.TestOrderConfig{}, &mockLogger{})
+	registry := services.NewDatasetRegistry(primary, newLoader(primary, filepath.Join(dir, "main.csv")), filepath.Join(dir, "main.csv"))
+	if err := registry.Add(models.DatasetSpec{ID: "eu", Path: euPath}, eu, newLoader(eu, euPath)); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if err := registry.Add(models.DatasetSpec{ID: "eu", Path: euPath}, eu, newLoader(eu, euPath)); err == nil {
+		t.Error("Add of a registered ID succeeded, want an error")
+	}
+
+	infos := registry.List()
+	if len(infos) != 2 || infos[0].ID != models.DefaultDataset || infos[1].ID != "eu" || infos[1].Loaded {
+		t.Fatalf("List() before use = %+v, want default then eu, unloaded", infos)
+	}
+
+	// A named dataset is loaded on first use, independently of the default one
+	ctx := context.Background()
+	repo, err := registry.Dataset(ctx, "eu")
+	if err != nil {
+		t.Fatalf("Dataset(eu): %v", err)
+	}
+	if total, err := repo.GetTotalRecords(ctx); err != nil || total != 3 {
+		t.Errorf("eu GetTotalRecords() = %d, %v, want 3", total, err)
+	}
+	infos = registry.List()
+	if !infos[1].Loaded || infos[1].Coverage == nil || infos[1].Coverage.Records != 3 || infos[0].Loaded {
+		t.Errorf("List() after use = %+v, want only eu loaded with 3 records", infos)
+	}
+
+	if _, err := registry.Dataset(ctx, "us"); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("Dataset(us) error = %v, want ErrNotFound", err)
+	}
+	if _, err := registry.Dataset(ctx, models.DefaultDataset); !errors.Is(err, models.ErrDataNotLoaded) {
+		t.Errorf("Dataset(default) with a missing file error = %v, want ErrDataNotLoaded", err)
+	}
+}
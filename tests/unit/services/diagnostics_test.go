@@ -0,0 +1,89 @@
+package services_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"analytics-dashboard-api/internal/config"
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/internal/services"
+)
+
+func TestDiagnostics(t *testing.T) {
+	dir := t.TempDir()
+	dataPath := filepath.Join(dir, "transactions.csv")
+	if err := os.WriteFile(dataPath, []byte(memoryTransactionsCSV), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	notADir := filepath.Join(dir, "file")
+	if err := os.WriteFile(notADir, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	repo := services.NewMemoryService(config.DimensionsConfig{}, config.OutlierConfig{}, config.TestOrderConfig{}, &mockLogger{})
+	loader := services.NewDataLoader(repo, dataPath, config.DataConfig{LoadWait: 5 * time.Second, LoadTimeout: time.Minute},
+		services.NewJobQueue(&mockLogger{}), nil, nil, &mockLogger{})
+	paths := []services.DiagnosticPath{
+		{Name: "data", Dir: dir},
+		{Name: "exports", Dir: filepath.Join(dir, "exports", "daily"), Writable: true},
+		{Name: "broken", Dir: filepath.Join(notADir, "sub"), Writable: true},
+	}
+	diagnostics := services.NewDiagnostics(config.DiagnosticsConfig{}, "memory", paths, repo, loader, &mockLogger{})
+
+	checks := func(report models.DiagnosticsReport) map[string]models.DiagnosticCheck {
+		byName := map[string]models.DiagnosticCheck{}
+		for _, check := range report.Checks {
+			byName[check.Name] = check
+		}
+		return byName
+	}
+
+	report := diagnostics.Run(context.Background())
+	got := checks(report)
+	want := map[string]string{
+		"disk_space:data":      models.DiagnosticPass,
+		"disk_space:exports":   models.DiagnosticPass,
+		"write_access:exports": models.DiagnosticPass,
+		"disk_space:broken":    models.DiagnosticFail,
+		"write_access:broken":  models.DiagnosticFail,
+		"database_extensions":  models.DiagnosticSkip,
+		"sample_query":         models.DiagnosticWarn,
+		"clock":                models.DiagnosticPass,
+	}
+	if len(got) != len(want) {
+		t.Errorf("Run() checks = %+v, want %d checks", report.Checks, len(want))
+	}
+	for name, status := range want {
+		if got[name].Status != status {
+			t.Errorf("check %s = %+v, want %s", name, got[name], status)
+		}
+	}
+	if report.Status != models.DiagnosticFail || report.Backend != "memory" {
+		t.Errorf("Run() status %s on %s, want fail on memory", report.Status, report.Backend)
+	}
+	// The directory that does not exist yet is checked, not created
+	if !strings.Contains(got["write_access:exports"].Detail, "does not exist yet") {
+		t.Errorf("write_access:exports detail = %q", got["write_access:exports"].Detail)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "exports")); !os.IsNotExist(err) {
+		t.Errorf("exports directory was created (stat error %v)", err)
+	}
+
+	// Once data is loaded the sample query counts it; too little free space
+	// fails every path
+	if err := loader.EnsureInitialized(context.Background()); err != nil {
+		t.Fatalf("EnsureInitialized: %v", err)
+	}
+	diagnostics = services.NewDiagnostics(config.DiagnosticsConfig{MinFreeBytes: 1 << 62}, "memory", paths[:1], repo, loader, &mockLogger{})
+	got = checks(diagnostics.Run(context.Background()))
+	if check := got["sample_query"]; check.Status != models.DiagnosticPass || check.Detail != "counted 3 transactions" {
+		t.Errorf("sample_query = %+v, want 3 transactions counted", check)
+	}
+	if check := got["disk_space:data"]; check.Status != models.DiagnosticFail || !strings.Contains(check.Detail, "below the minimum") {
+		t.Errorf("disk_space:data = %+v, want a failure below the minimum", check)
+	}
+}
@@ -0,0 +1,93 @@
+//go:build cgo
+
+package services_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"analytics-dashboard-api/internal/config"
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/internal/services"
+)
+
+// duckdbTransactionsCSV is memoryTransactionsCSV with the added_date column
+// the DuckDB schema requires
+const duckdbTransactionsCSV = `transaction_id,user_id,transaction_date,country,region,product_id,product_name,category,price,quantity,total_price,stock_quantity,added_date
+T1,U1,2024-01-05,Germany,Bavaria,P1,Widget,Tools,10.10,2,20.20,50,2023-06-01
+T2,U1,2024-02-10,Germany,Bavaria,P2,Gadget,Toys,5.00,1,5.00,30,2023-06-01
+T3,U2,2024-02-10,France,Normandy,P1,Widget,Tools,10.10,1,10.10,49,2023-06-01
+`
+
+// newDuckDBService returns an in-memory DuckDB service with the transactions
+// in content loaded
+func newDuckDBService(t *testing.T, cfg config.DuckDBConfig, content string) *services.DuckDBService {
+	t.Helper()
+	service, err := services.NewDuckDBService(cfg, config.DimensionsConfig{}, config.OutlierConfig{}, config.TestOrderConfig{}, nil, &mockLogger{})
+	if err != nil {
+		t.Fatalf("NewDuckDBService() error = %v", err)
+	}
+	t.Cleanup(func() { service.Close() })
+	loadDuckDB(t, service, content)
+	return service
+}
+
+func loadDuckDB(t *testing.T, service *services.DuckDBService, content string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "transactions.csv")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.LoadFromFile(context.Background(), models.DataFormatCSV, path); err != nil {
+		t.Fatalf("LoadFromFile() error = %v", err)
+	}
+}
+
+func TestDuckDBService_BackupsBackToBack(t *testing.T) {
+	service := newDuckDBService(t, config.DuckDBConfig{}, duckdbTransactionsCSV)
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	first, err := service.Backup(ctx, dir)
+	if err != nil {
+		t.Fatalf("first Backup() error = %v", err)
+	}
+	second, err := service.Backup(ctx, dir)
+	if err != nil {
+		t.Fatalf("second Backup() error = %v", err)
+	}
+	if first.Name == second.Name || !second.CreatedAt.After(first.CreatedAt) {
+		t.Errorf("backups = %s and %s, want distinct names in order", first.Name, second.Name)
+	}
+
+	// Both are complete, and nothing of the work in progress is left
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("backup directory holds %d entries, want 2", len(entries))
+	}
+	for _, backup := range []*models.BackupInfo{first, second} {
+		if _, err := os.Stat(filepath.Join(backup.Path, "transactions.parquet")); err != nil {
+			t.Errorf("backup %s is missing its transactions: %v", backup.Name, err)
+		}
+	}
+
+	// A later load is undone by restoring the first backup by name
+	loadDuckDB(t, service, strings.SplitAfter(duckdbTransactionsCSV, "\n")[0]+"T9,U9,2024-03-01,Spain,Madrid,P1,Widget,Tools,10.10,1,10.10,48,2023-06-01\n")
+	restored, err := service.Restore(ctx, dir, first.Name)
+	if err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if restored.Name != first.Name || restored.Records != 3 {
+		t.Errorf("Restore() = %+v, want %s with 3 records", restored, first.Name)
+	}
+	revenue, _, err := service.GetCountryRevenue(ctx, models.QueryOptions{Country: "Spain"}, 10, 0)
+	if err != nil || len(revenue) != 0 {
+		t.Errorf("GetCountryRevenue(Spain) = %+v, %v, want no rows after the restore", revenue, err)
+	}
+}
@@ -0,0 +1,251 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"analytics-dashboard-api/internal/config"
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/internal/services"
+)
+
+// flakyExporter fails its first failures calls with err, then writes two rows.
+// With block set it instead waits for the export to be cancelled.
+type flakyExporter struct {
+	failures int32
+	err      error
+	block    bool
+	calls    atomic.Int32
+}
+
+func (e *flakyExporter) ExportTransactions(ctx context.Context, _ models.QueryOptions, _, path string, progress func(int64, int64)) (int64, error) {
+	if e.calls.Add(1) <= e.failures {
+		return 0, e.err
+	}
+	progress(0, 2)
+	if e.block {
+		<-ctx.Done()
+		return 0, ctx.Err()
+	}
+	if err := os.WriteFile(path, []byte("transaction_id\nT1\nT2\n"), 0o644); err != nil {
+		return 0, err
+	}
+	progress(2, 2)
+	return 2, nil
+}
+
+// recordingMailer records sent email, failing the first failures sends
+type recordingMailer struct {
+	failures int
+	err      error
+
+	mu   sync.Mutex
+	sent []services.Email
+}
+
+func (m *recordingMailer) Configured() bool {
+	return true
+}
+
+func (m *recordingMailer) Send(_ context.Context, email services.Email) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.failures > 0 {
+		m.failures--
+		return m.err
+	}
+	m.sent = append(m.sent, email)
+	return nil
+}
+
+func newTestExportManager(t *testing.T, exporter services.TransactionExporter, mailer services.ExportMailer) *services.ExportManager {
+	t.Helper()
+	backend := &blockingLoader{release: make(chan struct{})}
+	close(backend.release)
+	loader := services.NewDataLoader(backend, "data.csv", config.DataConfig{LoadWait: time.Second, LoadTimeout: time.Minute}, services.NewJobQueue(&mockLogger{}), nil, nil, &mockLogger{})
+
+	cfg := config.ExportConfig{
+		Dir: t.TempDir(), MaxAttempts: 3, RetryBackoff: time.Millisecond, TTL: time.Hour,
+		PublicURL: "https://dashboard.example.com", MaxAttachmentBytes: 1 << 20,
+	}
+	manager, err := services.NewExportManager(cfg, exporter, loader, mailer, &mockLogger{})
+	if err != nil {
+		t.Fatalf("NewExportManager() error = %v", err)
+	}
+	return manager
+}
+
+func waitForExport(t *testing.T, manager *services.ExportManager, id string) *models.ExportJob {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		job, err := manager.Get(id)
+		if err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		if job.Finished() {
+			return job
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("export still %s", job.Status)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestExportManager_RetriesTransientFailures(t *testing.T) {
+	exporter := &flakyExporter{failures: 1, err: errors.New("disk briefly unavailable")}
+	manager := newTestExportManager(t, exporter, &recordingMailer{})
+
+	job, err := manager.Start(models.ExportRequest{})
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if job.Format != models.ExportFormatCSV || job.Status != models.ExportQueued {
+		t.Errorf("Start() = %+v, want a queued CSV export", job)
+	}
+
+	job = waitForExport(t, manager, job.ID)
+	if job.Status != models.ExportSucceeded || job.Attempts != 2 {
+		t.Fatalf("export = %+v, want success on the second attempt", job)
+	}
+	if job.RowsWritten != 2 || job.Progress != 1 || job.SizeBytes == 0 {
+		t.Errorf("export = %+v, want 2 rows written and full progress", job)
+	}
+
+	path, _, err := manager.File(job.ID)
+	if err != nil {
+		t.Fatalf("File() error = %v", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("export file missing: %v", err)
+	}
+}
+
+func TestExportManager_PermanentFailureIsNotRetried(t *testing.T) {
+	exporter := &flakyExporter{failures: 3, err: models.ErrNotSupported}
+	manager := newTestExportManager(t, exporter, &recordingMailer{})
+
+	job, _ := manager.Start(models.ExportRequest{Format: models.ExportFormatParquet})
+	job = waitForExport(t, manager, job.ID)
+	if job.Status != models.ExportFailed || job.Attempts != 1 || job.Error == "" {
+		t.Errorf("export = %+v, want a single failed attempt", job)
+	}
+	if _, _, err := manager.File(job.ID); !errors.Is(err, models.ErrExportNotReady) {
+		t.Errorf("File() error = %v, want ErrExportNotReady", err)
+	}
+}
+
+func TestExportManager_Cancel(t *testing.T) {
+	manager := newTestExportManager(t, &flakyExporter{block: true}, &recordingMailer{})
+
+	job, _ := manager.Start(models.ExportRequest{})
+	deadline := time.Now().Add(2 * time.Second)
+	for job.Status != models.ExportRunning {
+		if time.Now().After(deadline) {
+			t.Fatal("export never started")
+		}
+		time.Sleep(5 * time.Millisecond)
+		job, _ = manager.Get(job.ID)
+	}
+
+	job, err := manager.Cancel(job.ID)
+	if err != nil {
+		t.Fatalf("Cancel() error = %v", err)
+	}
+	if job.Status != models.ExportCancelled {
+		t.Errorf("status after Cancel() = %s, want cancelled", job.Status)
+	}
+	if _, err := manager.Cancel(job.ID); !errors.Is(err, models.ErrExportFinished) {
+		t.Errorf("second Cancel() error = %v, want ErrExportFinished", err)
+	}
+
+	if err := manager.Delete(job.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := manager.Get(job.ID); !errors.Is(err, models.ErrExportNotFound) {
+		t.Errorf("Get() after Delete() error = %v, want ErrExportNotFound", err)
+	}
+}
+
+func TestExportManager_RejectsUnknownFormat(t *testing.T) {
+	manager := newTestExportManager(t, &flakyExporter{}, &recordingMailer{})
+
+	if _, err := manager.Start(models.ExportRequest{Format: "xlsx"}); !errors.Is(err, models.ErrValidation) {
+		t.Errorf("Start(xlsx) error = %v, want a validation error", err)
+	}
+}
+
+func TestExportManager_EmailsAttachment(t *testing.T) {
+	mailer := &recordingMailer{failures: 1, err: errors.New("connection reset")}
+	manager := newTestExportManager(t, &flakyExporter{}, mailer)
+
+	job, err := manager.Start(models.ExportRequest{Email: &models.ExportEmail{To: []string{"finance@example.com"}}})
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if job.Email == nil || job.Email.Status != models.EmailPending {
+		t.Fatalf("Start() email = %+v, want pending delivery", job.Email)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for job.Email.Status == models.EmailPending && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+		job, _ = manager.Get(job.ID)
+	}
+	if job.Email.Status != models.EmailSent || job.Email.Via != models.EmailAttachment || job.Email.Attempts != 2 {
+		t.Fatalf("email = %+v, want sent as an attachment on the second attempt", job.Email)
+	}
+	if len(mailer.sent) != 1 || mailer.sent[0].AttachmentName != job.Filename() {
+		t.Errorf("sent = %+v, want one email with the export attached", mailer.sent)
+	}
+}
+
+func TestExportManager_EmailsSignedLink(t *testing.T) {
+	mailer := &recordingMailer{}
+	manager := newTestExportManager(t, &flakyExporter{}, mailer)
+
+	job, _ := manager.Start(models.ExportRequest{Email: &models.ExportEmail{To: []string{"finance@example.com"}, Delivery: models.EmailLink}})
+	deadline := time.Now().Add(2 * time.Second)
+	for job.Email.Status == models.EmailPending && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+		job, _ = manager.Get(job.ID)
+	}
+	if job.Email.Status != models.EmailSent || mailer.sent[0].Attachment != "" {
+		t.Fatalf("email = %+v, want a link without attachment", job.Email)
+	}
+
+	body := mailer.sent[0].Body
+	start := strings.Index(body, "https://dashboard.example.com/api/v1/exports/"+job.ID+"/download?")
+	if start < 0 {
+		t.Fatalf("body = %q, want a download link", body)
+	}
+	link, err := url.Parse(strings.TrimSpace(body[start:]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := link.Query()
+	if err := manager.VerifyLink(job.ID, query.Get("expires"), query.Get("signature")); err != nil {
+		t.Errorf("VerifyLink() error = %v for the emailed link", err)
+	}
+	if err := manager.VerifyLink("0000000000000000", query.Get("expires"), query.Get("signature")); !errors.Is(err, models.ErrExportLink) {
+		t.Errorf("VerifyLink() for another export error = %v, want ErrExportLink", err)
+	}
+}
+
+func TestExportManager_RejectsInvalidRecipients(t *testing.T) {
+	manager := newTestExportManager(t, &flakyExporter{}, &recordingMailer{})
+
+	for _, to := range [][]string{nil, {"not an address"}, {"a@example.com\r\nBcc: b@example.com"}} {
+		if _, err := manager.Start(models.ExportRequest{Email: &models.ExportEmail{To: to}}); !errors.Is(err, models.ErrValidation) {
+			t.Errorf("Start(to %q) error = %v, want a validation error", to, err)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"analytics-dashboard-api/internal/config"
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/internal/services"
+)
+
+// recordingExcluder remembers the IDs it was last given
+type recordingExcluder struct {
+	ids []string
+	err error
+}
+
+func (e *recordingExcluder) ExcludeTransactions(ids []string) error {
+	if e.err != nil && len(ids) > 0 {
+		return e.err
+	}
+	e.ids = ids
+	return nil
+}
+
+func TestFlagStore(t *testing.T) {
+	dir := t.TempDir()
+	excluder := &recordingExcluder{}
+	store, err := services.NewFlagStore(dir, excluder, &mockLogger{})
+	if err != nil {
+		t.Fatalf("NewFlagStore() error = %v", err)
+	}
+
+	flagged, err := store.Flag(models.FlagRequest{TransactionIDs: []string{"T2", " T1 "}, Reason: "fraud"}, "ops")
+	if err != nil {
+		t.Fatalf("Flag() error = %v", err)
+	}
+	if len(flagged) != 2 || flagged[1].TransactionID != "T1" || flagged[0].FlaggedBy != "ops" {
+		t.Errorf("Flag() = %+v, want T2 and the trimmed T1 flagged by ops", flagged)
+	}
+	if len(excluder.ids) != 2 || excluder.ids[0] != "T1" || excluder.ids[1] != "T2" {
+		t.Errorf("excluded IDs = %v, want [T1 T2]", excluder.ids)
+	}
+
+	if _, err := store.Flag(models.FlagRequest{TransactionIDs: []string{" "}}, ""); !errors.Is(err, models.ErrInvalidFlag) {
+		t.Errorf("Flag(blank) error = %v, want ErrInvalidFlag", err)
+	}
+	if err := store.Unflag("T2"); err != nil {
+		t.Fatalf("Unflag() error = %v", err)
+	}
+	if err := store.Unflag("T2"); !errors.Is(err, models.ErrFlagNotFound) {
+		t.Errorf("second Unflag() error = %v, want ErrFlagNotFound", err)
+	}
+
+	// Flags survive a restart and are handed to the new backend
+	restarted := &recordingExcluder{}
+	reopened, err := services.NewFlagStore(dir, restarted, &mockLogger{})
+	if err != nil {
+		t.Fatalf("NewFlagStore() error = %v", err)
+	}
+	if flags := reopened.List(); len(flags) != 1 || flags[0].TransactionID != "T1" || flags[0].Reason != "fraud" {
+		t.Errorf("List() after restart = %+v, want T1", flags)
+	}
+	if len(restarted.ids) != 1 || restarted.ids[0] != "T1" {
+		t.Errorf("excluded IDs after restart = %v, want [T1]", restarted.ids)
+	}
+}
+
+func TestFlagStore_UnsupportedBackend(t *testing.T) {
+	store, err := services.NewFlagStore(t.TempDir(), &recordingExcluder{err: models.ErrNotSupported}, &mockLogger{})
+	if err != nil {
+		t.Fatalf("NewFlagStore() error = %v", err)
+	}
+	if _, err := store.Flag(models.FlagRequest{TransactionIDs: []string{"T1"}}, ""); !errors.Is(err, models.ErrNotSupported) {
+		t.Errorf("Flag() error = %v, want ErrNotSupported", err)
+	}
+	if flags := store.List(); len(flags) != 0 {
+		t.Errorf("List() = %+v, want no flags kept after the backend refused them", flags)
+	}
+}
+
+func TestMemoryService_ExcludesFlaggedTransactions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "transactions.csv")
+	if err := os.WriteFile(path, []byte(memoryTransactionsCSV), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	service := services.NewMemoryService(config.DimensionsConfig{}, config.OutlierConfig{}, config.TestOrderConfig{}, &mockLogger{})
+	if err := service.ExcludeTransactions([]string{"T2", "T9"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.LoadFromFile(context.Background(), models.DataFormatCSV, path); err != nil {
+		t.Fatalf("LoadFromFile() error = %v", err)
+	}
+
+	if coverage := service.DataCoverage(); coverage.Records != 2 || coverage.Excluded != 1 {
+		t.Errorf("coverage = %+v, want 2 records with 1 excluded", coverage)
+	}
+}
+
+func TestMemoryService_ExcludesTestOrders(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "transactions.csv")
+	csv := memoryTransactionsCSV +
+		"T4,qa-bot,2024-03-01,Germany,Bavaria,P1,Widget,Tools,10.10,1,10.10,48\n" +
+		"T5,U3,2024-03-02,France,Normandy,TEST-1,Probe,Tools,1.00,1,1.00,10\n" +
+		"T6,QA-U4,2024-03-03,France,Normandy,P1,Widget,Tools,10.10,1,10.10,47\n"
+	if err := os.WriteFile(path, []byte(csv), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	service := services.NewMemoryService(config.DimensionsConfig{}, config.OutlierConfig{},
+		config.TestOrderConfig{UserPatterns: "qa-*, U?x", ProductPrefixes: "TEST-"}, &mockLogger{})
+	if err := service.ExcludeTransactions([]string{"T4"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.LoadFromFile(context.Background(), models.DataFormatCSV, path); err != nil {
+		t.Fatalf("LoadFromFile() error = %v", err)
+	}
+
+	// T4 counts as flagged, and matching is case-sensitive so T6 stays
+	if coverage := service.DataCoverage(); coverage.Records != 4 || coverage.Excluded != 1 || coverage.TestOrders != 1 {
+		t.Errorf("coverage = %+v, want 4 records with 1 flagged and 1 test order excluded", coverage)
+	}
+}
@@ -0,0 +1,70 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"analytics-dashboard-api/internal/config"
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/internal/services"
+)
+
+// fakeRegionQuerier returns fixed region sales, largest first
+type fakeRegionQuerier []models.RegionSales
+
+func (q fakeRegionQuerier) GetRegionSales(context.Context, models.QueryOptions) ([]models.RegionSales, error) {
+	return q, nil
+}
+
+func TestHeatmapService_Heatmap(t *testing.T) {
+	queries := fakeRegionQuerier{
+		{Country: "Germany", Region: "Bavaria", Revenue: 50000, Transactions: 5, ItemsSold: 10},
+		{Country: "USA", Region: "Texas", Revenue: 40000, Transactions: 4, ItemsSold: 8},
+		{Country: "USA", Region: "Ohio", Revenue: 30000, Transactions: 3, ItemsSold: 6},
+		{Country: "USA", Region: "Iowa", Revenue: 20000, Transactions: 2, ItemsSold: 4},
+		{Country: "France", Region: "Brittany", Revenue: 10000, Transactions: 1, ItemsSold: 2},
+	}
+	heatmaps := services.NewHeatmapService(config.HeatmapConfig{TopCountries: 2, TopRegions: 2}, queries, &mockLogger{})
+
+	heatmap, err := heatmaps.Heatmap(context.Background(), models.QueryOptions{}, models.HeatmapGrid{Other: true})
+	if err != nil {
+		t.Fatalf("Heatmap() error = %v", err)
+	}
+	want := []struct {
+		country, region string
+		revenue         models.Money
+		intensity       float64
+	}{
+		{"USA", "Texas", 40000, 0.8},
+		{"USA", "Ohio", 30000, 0.6},
+		{"USA", "Other", 20000, 0.4},
+		{"Germany", "Bavaria", 50000, 1},
+		{"Other", "Other", 10000, 0.2},
+	}
+	if len(heatmap.Cells) != len(want) {
+		t.Fatalf("cells = %+v, want %d", heatmap.Cells, len(want))
+	}
+	for i, w := range want {
+		cell := heatmap.Cells[i]
+		if cell.Country != w.country || cell.Region != w.region || cell.Revenue != w.revenue || cell.Intensity != w.intensity {
+			t.Errorf("cell %d = %+v, want %s/%s with %d at %g", i, cell, w.country, w.region, w.revenue, w.intensity)
+		}
+	}
+	if heatmap.TotalRevenue != 150000 || heatmap.MaxRevenue != 50000 || heatmap.Countries != 3 {
+		t.Errorf("totals = %d, max %d over %d countries, want 150000, 50000 and 3", heatmap.TotalRevenue, heatmap.MaxRevenue, heatmap.Countries)
+	}
+
+	// Without "Other" the tail is dropped and the cells no longer add up
+	heatmap, err = heatmaps.Heatmap(context.Background(), models.QueryOptions{}, models.HeatmapGrid{Countries: 1, Regions: 1})
+	if err != nil {
+		t.Fatalf("Heatmap(1x1) error = %v", err)
+	}
+	if len(heatmap.Cells) != 1 || heatmap.Cells[0].Region != "Texas" || heatmap.Cells[0].Intensity != 1 {
+		t.Errorf("cells = %+v, want only Texas", heatmap.Cells)
+	}
+
+	if _, err := heatmaps.Heatmap(context.Background(), models.QueryOptions{}, models.HeatmapGrid{Regions: 1000}); !errors.Is(err, models.ErrInvalidHeatmapGrid) {
+		t.Errorf("Heatmap(1000 regions) error = %v, want ErrInvalidHeatmapGrid", err)
+	}
+}
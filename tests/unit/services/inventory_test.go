@@ -0,0 +1,65 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"analytics-dashboard-api/internal/config"
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/internal/services"
+)
+
+func TestInventoryService_SellThrough(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "transactions.csv")
+	if err := os.WriteFile(path, []byte(memoryTransactionsCSV), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	backend := services.NewMemoryService(config.DimensionsConfig{}, config.OutlierConfig{}, config.TestOrderConfig{}, &mockLogger{})
+	if err := backend.LoadFromFile(context.Background(), models.DataFormatCSV, path); err != nil {
+		t.Fatalf("LoadFromFile() error = %v", err)
+	}
+	inventory := services.NewInventoryService(config.InventoryConfig{SellThroughWindowDays: 30}, backend, &mockLogger{})
+	ctx := context.Background()
+
+	// The window ends on the latest transaction, 2024-02-10, so T1 is
+	// outside it while its product's stock comes from T3
+	report, err := inventory.SellThrough(ctx, models.QueryOptions{}, "", 0, time.Time{})
+	if err != nil {
+		t.Fatalf("SellThrough() error = %v", err)
+	}
+	if report.Window != (models.SellThroughWindow{From: "2024-01-12", To: "2024-02-10", Days: 30}) {
+		t.Errorf("window = %+v, want the 30 days up to 2024-02-10", report.Window)
+	}
+	if len(report.Rows) != 2 {
+		t.Fatalf("rows = %+v, want P1 and P2", report.Rows)
+	}
+	p1 := report.Rows[0]
+	if p1.Group != "P1" || p1.UnitsSold != 1 || p1.Stock != 49 || p1.SellThroughPct == nil || *p1.SellThroughPct != 2 {
+		t.Errorf("P1 = %+v, want 1 sold of 50 available", p1)
+	}
+
+	report, err = inventory.SellThrough(ctx, models.QueryOptions{}, "category", 60, time.Time{})
+	if err != nil {
+		t.Fatalf("SellThrough(category) error = %v", err)
+	}
+	if tools := report.Rows[0]; tools.Group != "Tools" || tools.Products != 1 || tools.UnitsSold != 3 || tools.Stock != 49 {
+		t.Errorf("Tools = %+v, want 3 sold with 49 left over 60 days", tools)
+	}
+
+	// Ending the window earlier takes the stock as of then
+	report, err = inventory.SellThrough(ctx, models.QueryOptions{}, "product", 0, time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("SellThrough(to) error = %v", err)
+	}
+	if len(report.Rows) != 1 || report.Rows[0].Stock != 50 || report.Rows[0].UnitsSold != 2 {
+		t.Errorf("rows to 2024-01-31 = %+v, want only P1 with 2 sold and 50 left", report.Rows)
+	}
+
+	if _, err := inventory.SellThrough(ctx, models.QueryOptions{}, "region", 0, time.Time{}); !errors.Is(err, models.ErrInvalidSellThrough) {
+		t.Errorf("SellThrough(region) error = %v, want ErrInvalidSellThrough", err)
+	}
+}
@@ -0,0 +1,229 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/internal/services"
+)
+
+func TestJobQueue_RunsByPriority(t *testing.T) {
+	queue := services.NewJobQueue(&mockLogger{})
+	release := make(chan struct{})
+	started := make(chan struct{})
+
+	var mu sync.Mutex
+	var order []string
+	record := func(kind string) func(context.Context) error {
+		return func(context.Context) error {
+			mu.Lock()
+			order = append(order, kind)
+			mu.Unlock()
+			return nil
+		}
+	}
+
+	var wg sync.WaitGroup
+	run := func(kind string, priority services.JobPriority, fn func(context.Context) error) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := queue.Run(context.Background(), kind, priority, fn); err != nil {
+				t.Errorf("Run(%s) error = %v", kind, err)
+			}
+		}()
+	}
+
+	// Hold the worker so the remaining jobs queue up behind it
+	run("upload", services.PriorityManual, func(context.Context) error {
+		close(started)
+		<-release
+		return nil
+	})
+	<-started
+
+	run("scheduled_refresh", services.PriorityScheduled, record("scheduled_refresh"))
+	waitForDepth(t, queue, 1)
+	run("refresh", services.PriorityManual, record("refresh"))
+	waitForDepth(t, queue, 2)
+	run("initial_load", services.PriorityInitial, record("initial_load"))
+	waitForDepth(t, queue, 3)
+
+	stats := queue.Stats()
+	if stats.Running == nil || stats.Running.Kind != "upload" || stats.Pending[0].Kind != "initial_load" {
+		t.Errorf("Stats() = %+v, want upload running and initial_load next", stats)
+	}
+
+	close(release)
+	wg.Wait()
+
+	want := []string{"initial_load", "refresh", "scheduled_refresh"}
+	for i := range want {
+		if i >= len(order) || order[i] != want[i] {
+			t.Fatalf("jobs ran in order %v, want %v", order, want)
+		}
+	}
+	if stats := queue.Stats(); stats.Depth != 0 || stats.Completed != 4 {
+		t.Errorf("Stats() after drain = %+v, want empty with 4 completed", stats)
+	}
+}
+
+func TestJobQueue_CallerCanStopWaiting(t *testing.T) {
+	queue := services.NewJobQueue(&mockLogger{})
+	release := make(chan struct{})
+	finished := make(chan struct{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := queue.Run(ctx, "refresh", services.PriorityManual, func(context.Context) error {
+		<-release
+		close(finished)
+		return nil
+	})
+	if err != context.DeadlineExceeded {
+		t.Fatalf("Run() error = %v, want DeadlineExceeded", err)
+	}
+
+	// The abandoned job still runs to completion
+	close(release)
+	select {
+	case <-finished:
+	case <-time.After(time.Second):
+		t.Fatal("abandoned job never finished")
+	}
+}
+
+func TestJobQueue_SubmitReturnsAtOnce(t *testing.T) {
+	queue := services.NewJobQueue(&mockLogger{})
+	release := make(chan struct{})
+	started := make(chan struct{})
+
+	first := queue.Submit("refresh", services.PriorityManual, func(context.Context) error {
+		close(started)
+		<-release
+		return nil
+	})
+	<-started
+	if job, err := queue.Job(first.ID); err != nil || job.Status != models.JobRunning {
+		t.Fatalf("Job(%d) = %+v, %v, want running", first.ID, job, err)
+	}
+
+	// A second refresh queues; a third is covered by the queued one
+	second := queue.Submit("refresh", services.PriorityManual, func(context.Context) error { return errors.New("bad file") })
+	third := queue.Submit("refresh", services.PriorityManual, func(context.Context) error { return nil })
+	if second.ID == first.ID || third.ID != second.ID || queue.Stats().Depth != 1 {
+		t.Errorf("job ids %d, %d, %d with depth %d, want the third to reuse the queued second",
+			first.ID, second.ID, third.ID, queue.Stats().Depth)
+	}
+
+	close(release)
+	deadline := time.Now().Add(time.Second)
+	for {
+		job, err := queue.Job(second.ID)
+		if err != nil {
+			t.Fatalf("Job(%d) error = %v", second.ID, err)
+		}
+		if job.FinishedAt != nil {
+			if job.Status != models.JobFailed || job.Error != "bad file" {
+				t.Errorf("Job(%d) = %+v, want failed with its error", second.ID, job)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job %d never finished", second.ID)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if job, _ := queue.Job(first.ID); job.Status != models.JobSucceeded {
+		t.Errorf("Job(%d) status = %s, want succeeded", first.ID, job.Status)
+	}
+	if _, err := queue.Job(99); !errors.Is(err, models.ErrJobNotFound) {
+		t.Errorf("Job(99) error = %v, want ErrJobNotFound", err)
+	}
+}
+
+func TestJobQueue_WaitFollowsSharedJob(t *testing.T) {
+	queue := services.NewJobQueue(&mockLogger{})
+	release := make(chan struct{})
+	started := make(chan struct{})
+
+	queue.Submit("upload", services.PriorityManual, func(context.Context) error {
+		close(started)
+		<-release
+		return nil
+	})
+	<-started
+
+	// Two refreshes requested behind the upload share one job, which both
+	// callers follow to its outcome
+	var loads int
+	refresh := func(context.Context) error {
+		loads++
+		return errors.New("bad file")
+	}
+	first := queue.Submit("refresh", services.PriorityManual, refresh)
+	second := queue.Submit("refresh", services.PriorityManual, refresh)
+	if second.ID != first.ID {
+		t.Fatalf("job ids %d and %d, want one shared job", first.ID, second.ID)
+	}
+	if running, queued, last := queue.Latest("refresh"); running != nil || queued == nil || queued.ID != first.ID || last != nil {
+		t.Errorf("Latest(refresh) = %+v, %+v, %+v, want only job %d queued", running, queued, last, first.ID)
+	}
+
+	errs := make(chan error, 2)
+	for range 2 {
+		go func() {
+			job, err := queue.Wait(context.Background(), first.ID)
+			if job.Status != models.JobFailed {
+				t.Errorf("Wait() job = %+v, want failed", job)
+			}
+			errs <- err
+		}()
+	}
+	close(release)
+	for range 2 {
+		if err := <-errs; err == nil || err.Error() != "bad file" {
+			t.Errorf("Wait() error = %v, want the job's error", err)
+		}
+	}
+	if loads != 1 {
+		t.Errorf("refresh ran %d times, want 1", loads)
+	}
+	if _, _, last := queue.Latest("refresh"); last == nil || last.ID != first.ID {
+		t.Errorf("Latest(refresh) last = %+v, want job %d", last, first.ID)
+	}
+
+	// A finished job can still be waited on, and ctx bounds the wait
+	if _, err := queue.Wait(context.Background(), first.ID); err == nil {
+		t.Error("Wait() on the finished job returned no error, want its error")
+	}
+	if _, err := queue.Wait(context.Background(), 99); !errors.Is(err, models.ErrJobNotFound) {
+		t.Errorf("Wait(99) error = %v, want ErrJobNotFound", err)
+	}
+	hold := make(chan struct{})
+	defer close(hold)
+	held := queue.Submit("refresh", services.PriorityManual, func(context.Context) error {
+		<-hold
+		return nil
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := queue.Wait(ctx, held.ID); err != context.DeadlineExceeded {
+		t.Errorf("Wait() error = %v, want DeadlineExceeded", err)
+	}
+}
+
+func waitForDepth(t *testing.T, queue *services.JobQueue, depth int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for queue.Stats().Depth < depth {
+		if time.Now().After(deadline) {
+			t.Fatalf("queue depth = %d, want %d", queue.Stats().Depth, depth)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,91 @@
+package services_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"analytics-dashboard-api/internal/config"
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/internal/services"
+)
+
+func writeManifest(t *testing.T, dir string, files []models.ManifestFile) string {
+	t.Helper()
+	data, err := json.Marshal(models.Manifest{Files: files})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "manifest.json")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestDataLoader_LoadsManifestParts(t *testing.T) {
+	dir := t.TempDir()
+	lines := strings.SplitAfter(strings.TrimSuffix(memoryTransactionsCSV, "\n"), "\n")
+	header := lines[0]
+	parts := map[string]string{
+		"part-1.csv": header + lines[1] + lines[2],
+		"part-2.csv": header + lines[3], // no trailing newline
+	}
+	var files []models.ManifestFile
+	for _, name := range []string{"part-1.csv", "part-2.csv"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(parts[name]), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		sum := sha256.Sum256([]byte(parts[name]))
+		files = append(files, models.ManifestFile{Path: name, SHA256: hex.EncodeToString(sum[:]), Rows: int64(strings.Count(parts[name], "\n")) - 1})
+	}
+	// The last line of part 2 has no newline but still counts
+	files[1].Rows = 1
+
+	backend := services.NewMemoryService(config.DimensionsConfig{}, config.OutlierConfig{}, config.TestOrderConfig{}, &mockLogger{})
+	cfg := config.DataConfig{LoadWait: 5 * time.Second, LoadTimeout: time.Minute}
+	loader := services.NewDataLoader(backend, writeManifest(t, dir, files), cfg, services.NewJobQueue(&mockLogger{}), nil, nil, &mockLogger{})
+	ctx := context.Background()
+
+	if err := loader.EnsureInitialized(ctx); err != nil {
+		t.Fatalf("EnsureInitialized() error = %v", err)
+	}
+	if got := backend.DataCoverage().Records; got != 3 {
+		t.Errorf("loaded %d records from the manifest, want 3", got)
+	}
+
+	// A wrong row count fails the load before any part is read
+	files[0].Rows = 5
+	writeManifest(t, dir, files)
+	if err := loader.Reload(ctx, 0); !errors.Is(err, models.ErrManifestMismatch) {
+		t.Errorf("Reload() error = %v, want ErrManifestMismatch", err)
+	}
+
+	// So does a part that changed after the manifest was written
+	files[0].Rows = 2
+	writeManifest(t, dir, files)
+	if err := os.WriteFile(filepath.Join(dir, "part-2.csv"), []byte(parts["part-1.csv"]), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := loader.Reload(ctx, 0); !errors.Is(err, models.ErrManifestMismatch) || !strings.Contains(err.Error(), "sha256") {
+		t.Errorf("Reload() error = %v, want a checksum mismatch", err)
+	}
+	if got := backend.DataCoverage().Records; got != 3 {
+		t.Errorf("failed reloads replaced the dataset: %d records", got)
+	}
+
+	inspection, err := loader.Inspect(ctx, 0)
+	if err != nil {
+		t.Fatalf("Inspect() error = %v", err)
+	}
+	if inspection.Valid || len(inspection.Problems) == 0 {
+		t.Errorf("Inspect() = %+v, want an invalid source", inspection)
+	}
+}
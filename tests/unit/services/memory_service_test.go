@@ -0,0 +1,551 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"analytics-dashboard-api/internal/config"
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/internal/services"
+)
+
+// Columns are deliberately out of the canonical order to exercise header matching
+const memoryTransactionsCSV = `transaction_id,user_id,transaction_date,country,region,product_id,product_name,category,price,quantity,total_price,stock_quantity
+T1,U1,2024-01-05,Germany,Bavaria,P1,Widget,Tools,10.10,2,20.20,50
+T2,U1,2024-02-10,Germany,Bavaria,P2,Gadget,Toys,5.00,1,5.00,30
+T3,U2,2024-02-10,France,Normandy,P1,Widget,Tools,10.10,1,10.10,49
+`
+
+const memoryProductsCSV = `product_id,product_name,category,brand,supplier
+P1,Widget,Tools,Acme,Supply Co
+P2,Gadget,Toys,Globex,Toy Co
+`
+
+const memoryCustomersCSV = `user_id,signup_date,segment,country
+U1,2023-12-01,VIP,Germany
+`
+
+func newTestMemoryService(t *testing.T) *services.MemoryService {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"transactions.csv": memoryTransactionsCSV,
+		"products.csv":     memoryProductsCSV,
+		"customers.csv":    memoryCustomersCSV,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	service := services.NewMemoryService(config.DimensionsConfig{
+		ProductsFilePath:  filepath.Join(dir, "products.csv"),
+		CustomersFilePath: filepath.Join(dir, "customers.csv"),
+	}, config.OutlierConfig{}, config.TestOrderConfig{}, &mockLogger{})
+	if err := service.LoadFromFile(context.Background(), models.DataFormatCSV, filepath.Join(dir, "transactions.csv")); err != nil {
+		t.Fatalf("LoadFromFile() error = %v", err)
+	}
+	return service
+}
+
+func TestMemoryService_Analytics(t *testing.T) {
+	service := newTestMemoryService(t)
+	ctx := context.Background()
+
+	coverage := service.DataCoverage()
+	if coverage.From != "2024-01-05" || coverage.To != "2024-02-10" || coverage.Records != 3 {
+		t.Errorf("DataCoverage() = %+v", coverage)
+	}
+
+	revenue, totals, err := service.GetCountryRevenue(ctx, models.QueryOptions{}, 10, 0)
+	if err != nil {
+		t.Fatalf("GetCountryRevenue() error = %v", err)
+	}
+	if len(revenue) != 3 || revenue[0].Country != "Germany" || revenue[0].TotalRevenue.String() != "20.20" {
+		t.Errorf("GetCountryRevenue() = %+v", revenue)
+	}
+
+	// Totals cover every pair, not just the page
+	page, pageTotals, err := service.GetCountryRevenue(ctx, models.QueryOptions{}, 1, 5)
+	if err != nil {
+		t.Fatalf("GetCountryRevenue(offset 5) error = %v", err)
+	}
+	if len(page) != 0 || pageTotals.Revenue != totals.Revenue || totals.Revenue.String() != "35.30" || totals.Transactions != 3 || totals.Quantity != 4 {
+		t.Errorf("GetCountryRevenue() totals = %+v and %+v past the end, want 35.30 over 3 transactions and 4 units", totals, pageTotals)
+	}
+
+	top, err := service.GetTopProducts(ctx, models.QueryOptions{}, models.RankByPurchaseCount, 20, 0)
+	if err != nil {
+		t.Fatalf("GetTopProducts() error = %v", err)
+	}
+	if top[0].ProductID != "P1" || top[0].PurchaseCount != 3 || top[0].StockQuantity != 50 || top[0].Brand != "Acme" {
+		t.Errorf("GetTopProducts()[0] = %+v", top[0])
+	}
+
+	ranked, err := service.GetTopProducts(ctx, models.QueryOptions{}, models.RankByRevenue, 1, 1)
+	if err != nil {
+		t.Fatalf("GetTopProducts(revenue, offset 1) error = %v", err)
+	}
+	if count, _ := service.GetTopProductsCount(ctx, models.QueryOptions{}); len(ranked) != 1 || ranked[0].ProductID != "P2" || ranked[0].TotalRevenue.String() != "5.00" || count != 2 {
+		t.Errorf("GetTopProducts(revenue, offset 1) = %+v of %d, want P2 with 5.00 of 2", ranked, count)
+	}
+	if _, err := service.GetTopRegions(ctx, models.QueryOptions{}, models.RankByStock, 30, 0); !errors.Is(err, models.ErrValidation) {
+		t.Errorf("GetTopRegions(stock) error = %v, want a validation error", err)
+	}
+
+	monthly, err := service.GetMonthlySales(ctx, models.QueryOptions{Country: "Germany"})
+	if err != nil {
+		t.Fatalf("GetMonthlySales() error = %v", err)
+	}
+	if len(monthly) != 2 || monthly[0].Month != "2024-01" || monthly[1].SalesVolume.String() != "5.00" {
+		t.Errorf("GetMonthlySales() = %+v", monthly)
+	}
+}
+
+func TestMemoryService_SegmentsAndMetrics(t *testing.T) {
+	service := newTestMemoryService(t)
+	ctx := context.Background()
+
+	segments, err := service.GetSegmentBreakdown(ctx, models.QueryOptions{})
+	if err != nil {
+		t.Fatalf("GetSegmentBreakdown() error = %v", err)
+	}
+	if len(segments) != 2 {
+		t.Fatalf("GetSegmentBreakdown() returned %d segments, want 2", len(segments))
+	}
+	vip := segments[0]
+	if vip.Segment != "VIP" || vip.TotalRevenue.String() != "25.20" || vip.AvgOrderValue.String() != "12.60" || vip.RetentionRate != 1 {
+		t.Errorf("VIP segment = %+v", vip)
+	}
+	if segments[1].Segment != "Unknown" {
+		t.Errorf("unmatched customers grouped as %q, want Unknown", segments[1].Segment)
+	}
+
+	rows, err := service.GetBaseMetrics(ctx, models.QueryOptions{Segment: "VIP"}, "category")
+	if err != nil {
+		t.Fatalf("GetBaseMetrics() error = %v", err)
+	}
+	if len(rows) != 2 || rows[0].Group != "Tools" || rows[0].Values["revenue"] != 20.2 || rows[0].Values["quantity"] != 2 {
+		t.Errorf("GetBaseMetrics() = %+v", rows)
+	}
+
+	for _, opts := range []models.QueryOptions{{Segment: "Churned"}, {Country: "Atlantis"}} {
+		rows, err := service.GetBaseMetrics(ctx, opts, "")
+		if err != nil {
+			t.Fatalf("GetBaseMetrics(%+v) error = %v", opts, err)
+		}
+		if len(rows) != 1 || rows[0].Values["transactions"] != 0 {
+			t.Errorf("GetBaseMetrics(%+v) = %+v, want one empty row", opts, rows)
+		}
+	}
+
+	// Several values, exclusions and dimensions combine
+	filtered := []struct {
+		filters      []models.DimensionFilter
+		transactions float64
+	}{
+		{[]models.DimensionFilter{{Dimension: models.FilterCountry, Include: []string{"France", "germany", "Atlantis"}}}, 3},
+		{[]models.DimensionFilter{{Dimension: models.FilterCategory, Exclude: []string{"toys"}}}, 2},
+		{[]models.DimensionFilter{{Dimension: models.FilterRegion, Include: []string{"Bavaria"}, Exclude: []string{"Bavaria"}}}, 0},
+		{[]models.DimensionFilter{{Dimension: models.FilterSegment, Exclude: []string{"VIP"}}}, 1},
+		{[]models.DimensionFilter{
+			{Dimension: models.FilterCountry, Exclude: []string{"France"}},
+			{Dimension: models.FilterCategory, Include: []string{"Tools", "Toys"}, Exclude: []string{"Atlantis"}},
+		}, 2},
+		{[]models.DimensionFilter{{Dimension: models.FilterCountry, Include: []string{"Atlantis"}}}, 0},
+	}
+	for _, tt := range filtered {
+		rows, err := service.GetBaseMetrics(ctx, models.QueryOptions{Filters: tt.filters}, "")
+		if err != nil || rows[0].Values["transactions"] != tt.transactions {
+			t.Errorf("GetBaseMetrics(%+v) = %+v, %v, want %g transactions", tt.filters, rows, err, tt.transactions)
+		}
+	}
+
+	// Filters ignore case
+	for _, opts := range []models.QueryOptions{{Segment: "vip"}, {Country: "GERMANY"}} {
+		rows, err := service.GetBaseMetrics(ctx, opts, "")
+		if err != nil || rows[0].Values["transactions"] != 2 {
+			t.Errorf("GetBaseMetrics(%+v) = %+v, %v, want 2 transactions", opts, rows, err)
+		}
+	}
+
+	february := models.QueryOptions{
+		From: time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC),
+		To:   time.Date(2024, time.February, 10, 0, 0, 0, 0, time.UTC),
+	}
+	if rows, err := service.GetBaseMetrics(ctx, february, ""); err != nil || rows[0].Values["transactions"] != 0 {
+		t.Errorf("GetBaseMetrics(before 2024-02-10) = %+v, %v, want no transactions", rows, err)
+	}
+	february.To = february.To.AddDate(0, 0, 1)
+	if rows, err := service.GetBaseMetrics(ctx, february, ""); err != nil || rows[0].Values["transactions"] != 2 {
+		t.Errorf("GetBaseMetrics(to 2024-02-10 inclusive) = %+v, %v, want 2 transactions", rows, err)
+	}
+
+	alerts, err := service.GetAlertMetrics(ctx)
+	if err != nil {
+		t.Fatalf("GetAlertMetrics() error = %v", err)
+	}
+	if alerts["daily_transactions"] != 2 || alerts["monthly_revenue"] != 15.1 {
+		t.Errorf("GetAlertMetrics() = %v", alerts)
+	}
+}
+
+func TestMemoryService_FiltersFoldUnicodeCase(t *testing.T) {
+	const accented = "transaction_id,user_id,transaction_date,country,region,product_id,product_name,category,price,quantity,total_price,stock_quantity\n" +
+		"T1,U1,2024-01-05,Österreich,Wien,P1,Widget,Tools,10.00,1,10.00,5\n" +
+		"T2,U2,2024-01-06,österreich,Tirol,P1,Widget,Tools,10.00,1,10.00,4\n" +
+		"T3,U3,2024-01-07,Ostereich,Tirol,P1,Widget,Tools,10.00,1,10.00,3\n"
+	path := filepath.Join(t.TempDir(), "transactions.csv")
+	if err := os.WriteFile(path, []byte(accented), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	service := services.NewMemoryService(config.DimensionsConfig{}, config.OutlierConfig{}, config.TestOrderConfig{}, &mockLogger{})
+	ctx := context.Background()
+	if err := service.LoadFromFile(ctx, models.DataFormatCSV, path); err != nil {
+		t.Fatalf("LoadFromFile() error = %v", err)
+	}
+
+	// Both spellings of the accented name match; the unaccented one does not
+	rows, err := service.GetBaseMetrics(ctx, models.QueryOptions{Country: "ÖSTERREICH"}, "")
+	if err != nil || rows[0].Values["transactions"] != 2 {
+		t.Errorf("GetBaseMetrics(ÖSTERREICH) = %+v, %v, want 2 transactions", rows, err)
+	}
+}
+
+func TestMemoryService_Customers(t *testing.T) {
+	service := newTestMemoryService(t)
+	ctx := context.Background()
+
+	customers, err := service.GetTopCustomers(ctx, models.QueryOptions{}, 10)
+	if err != nil {
+		t.Fatalf("GetTopCustomers() error = %v", err)
+	}
+	if len(customers) != 2 {
+		t.Fatalf("GetTopCustomers() returned %d customers, want 2", len(customers))
+	}
+	top := customers[0]
+	if top.UserID != "U1" || top.TotalSpend.String() != "25.20" || top.OrderCount != 2 || top.AvgOrderValue.String() != "12.60" {
+		t.Errorf("top customer = %+v", top)
+	}
+	if customers, _ := service.GetTopCustomers(ctx, models.QueryOptions{Country: "France"}, 10); len(customers) != 1 || customers[0].UserID != "U2" {
+		t.Errorf("GetTopCustomers(France) = %+v, want only U2", customers)
+	}
+	if customers, _ := service.GetTopCustomers(ctx, models.QueryOptions{}, 1); len(customers) != 1 {
+		t.Errorf("GetTopCustomers(limit 1) returned %d customers", len(customers))
+	}
+
+	repeat, err := service.GetRepeatPurchase(ctx)
+	if err != nil {
+		t.Fatalf("GetRepeatPurchase() error = %v", err)
+	}
+	if repeat.Customers != 2 || repeat.RepeatCustomers != 1 || repeat.Rate != 0.5 {
+		t.Errorf("GetRepeatPurchase() = %+v, want 1 of 2 customers", repeat)
+	}
+}
+
+func TestMemoryService_CatalogAndDimensions(t *testing.T) {
+	service := newTestMemoryService(t)
+	ctx := context.Background()
+
+	products, err := service.ListProducts(ctx, "glob", 10, 0)
+	if err != nil {
+		t.Fatalf("ListProducts() error = %v", err)
+	}
+	if len(products) != 1 || products[0].ProductID != "P2" {
+		t.Errorf("ListProducts(glob) = %+v", products)
+	}
+
+	_, sales, err := service.GetProduct(ctx, "P1")
+	if err != nil {
+		t.Fatalf("GetProduct() error = %v", err)
+	}
+	if sales.UnitsSold != 3 || sales.TransactionCount != 2 || sales.TotalRevenue.String() != "30.30" {
+		t.Errorf("GetProduct() sales = %+v", sales)
+	}
+	if _, _, err := service.GetProduct(ctx, "P404"); !errors.Is(err, models.ErrProductNotFound) {
+		t.Errorf("GetProduct(P404) error = %v, want ErrProductNotFound", err)
+	}
+
+	values, err := service.ListDimensionValues(ctx, "country", "", 1, 0)
+	if err != nil {
+		t.Fatalf("ListDimensionValues() error = %v", err)
+	}
+	if len(values) != 1 || values[0].Value != "Germany" || values[0].Count != 2 {
+		t.Errorf("ListDimensionValues() = %+v", values)
+	}
+	if _, err := service.CountDimensionValues(ctx, "user_id", ""); !errors.Is(err, models.ErrUnknownDimension) {
+		t.Errorf("CountDimensionValues(user_id) error = %v, want ErrUnknownDimension", err)
+	}
+
+	if _, err := service.Backup(ctx, t.TempDir()); !errors.Is(err, models.ErrNotSupported) {
+		t.Errorf("Backup() error = %v, want ErrNotSupported", err)
+	}
+}
+
+func TestMemoryService_LoadRejectsBadRow(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "transactions.csv")
+	content := memoryTransactionsCSV + "T4,U3,2024-03-01,Spain,Madrid,P1,Widget,Tools,10.10,-1,10.10,48\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	service := services.NewMemoryService(config.DimensionsConfig{}, config.OutlierConfig{}, config.TestOrderConfig{}, &mockLogger{})
+	inspection, err := service.InspectSource(context.Background(), models.DataFormatCSV, path)
+	if err != nil {
+		t.Fatalf("InspectSource() error = %v", err)
+	}
+	if inspection.Valid || len(inspection.Problems) != 1 || len(inspection.Columns) != 12 {
+		t.Errorf("InspectSource() = %+v, want an invalid file with 12 columns and one problem", inspection)
+	}
+	if len(inspection.MissingColumns) != 1 || inspection.MissingColumns[0] != "added_date" {
+		t.Errorf("InspectSource() missing columns = %v, want [added_date]", inspection.MissingColumns)
+	}
+
+	err = service.LoadFromFile(context.Background(), models.DataFormatCSV, path)
+	if err == nil {
+		t.Fatal("LoadFromFile() accepted a negative quantity")
+	}
+	if got := service.DataCoverage().Records; got != 0 {
+		t.Errorf("failed load replaced the dataset: %d records", got)
+	}
+	if ingest := service.LastIngest(); ingest.Rows != 3 || ingest.ParseErrors != 1 {
+		t.Errorf("LastIngest() = %+v, want 3 rows parsed before 1 parse error", ingest)
+	}
+}
+
+func TestMemoryService_LastIngest(t *testing.T) {
+	service := newTestMemoryService(t)
+
+	ingest := service.LastIngest()
+	if ingest.Rows != 3 || ingest.Bytes != int64(len(memoryTransactionsCSV)) || ingest.ParseErrors != 0 {
+		t.Errorf("LastIngest() = %+v, want 3 rows, %d bytes and no parse errors", ingest, len(memoryTransactionsCSV))
+	}
+	if ingest.Read <= 0 || ingest.Parse <= 0 || ingest.Insert <= 0 {
+		t.Errorf("LastIngest() = %+v, want every phase timed", ingest)
+	}
+}
+
+func TestMemoryService_LoadFormats(t *testing.T) {
+	dir := t.TempDir()
+	csvPath := filepath.Join(dir, "transactions.csv")
+	parquetPath := filepath.Join(dir, "transactions.PARQUET")
+	for _, path := range []string{csvPath, parquetPath} {
+		if err := os.WriteFile(path, []byte(memoryTransactionsCSV), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	service := services.NewMemoryService(config.DimensionsConfig{}, config.OutlierConfig{}, config.TestOrderConfig{}, &mockLogger{})
+	ctx := context.Background()
+
+	// Auto detection goes by extension, and an explicit format overrides it
+	if err := service.LoadFromFile(ctx, models.DataFormatAuto, parquetPath); !errors.Is(err, models.ErrNotSupported) {
+		t.Errorf("LoadFromFile(auto, .parquet) error = %v, want ErrNotSupported", err)
+	}
+	if err := service.LoadFromFile(ctx, models.DataFormatAuto, filepath.Join(dir, "transactions.ndjson")); !errors.Is(err, models.ErrNotSupported) {
+		t.Errorf("LoadFromFile(auto, .ndjson) error = %v, want ErrNotSupported", err)
+	}
+	if err := service.LoadFromFile(ctx, models.DataFormatCSV, parquetPath); err != nil {
+		t.Errorf("LoadFromFile(csv, .parquet) error = %v, want the file read as CSV", err)
+	}
+	if err := service.LoadFromFile(ctx, models.DataFormatAuto, csvPath, parquetPath); !errors.Is(err, models.ErrValidation) {
+		t.Errorf("LoadFromFile(auto, mixed) error = %v, want a validation error", err)
+	}
+	if _, err := service.InspectSource(ctx, models.DataFormatParquet, csvPath); !errors.Is(err, models.ErrNotSupported) {
+		t.Errorf("InspectSource(parquet) error = %v, want ErrNotSupported", err)
+	}
+}
+
+func TestMemoryService_Rollback(t *testing.T) {
+	service := newTestMemoryService(t)
+	ctx := context.Background()
+
+	if _, err := service.Rollback(ctx); !errors.Is(err, models.ErrNoPreviousVersion) {
+		t.Fatalf("Rollback() after the first load error = %v, want ErrNoPreviousVersion", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "transactions.csv")
+	truncated := "transaction_id,user_id,transaction_date,country,region,product_id,product_name,category,price,quantity,total_price,stock_quantity\n"
+	if err := os.WriteFile(path, []byte(truncated), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.LoadFromFile(context.Background(), models.DataFormatCSV, path); err != nil {
+		t.Fatalf("LoadFromFile() error = %v", err)
+	}
+
+	restored, err := service.Rollback(ctx)
+	if err != nil {
+		t.Fatalf("Rollback() error = %v", err)
+	}
+	if restored.Coverage.Records != 3 || service.DataCoverage().Records != 3 {
+		t.Errorf("Rollback() restored %d records, coverage %d, want 3", restored.Coverage.Records, service.DataCoverage().Records)
+	}
+
+	// Rolling back again returns to the replaced version
+	if _, err := service.Rollback(ctx); err != nil || service.DataCoverage().Records != 0 {
+		t.Errorf("second Rollback() error = %v, records = %d, want the empty load back", err, service.DataCoverage().Records)
+	}
+}
+
+func TestMemoryService_GetPriceHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "transactions.csv")
+	csv := memoryTransactionsCSV +
+		"T4,U2,2024-03-15,France,Normandy,P1,Widget,Tools,12.00,2,22.00,45\n" +
+		"T5,U3,2024-03-20,Spain,Madrid,P1,Widget,Tools,12.00,2,24.00,43\n"
+	if err := os.WriteFile(path, []byte(csv), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	service := services.NewMemoryService(config.DimensionsConfig{}, config.OutlierConfig{}, config.TestOrderConfig{}, &mockLogger{})
+	if err := service.LoadFromFile(context.Background(), models.DataFormatCSV, path); err != nil {
+		t.Fatalf("LoadFromFile() error = %v", err)
+	}
+	ctx := context.Background()
+
+	history, err := service.GetPriceHistory(ctx, "P1", models.QueryOptions{})
+	if err != nil {
+		t.Fatalf("GetPriceHistory() error = %v", err)
+	}
+	if len(history) != 3 || history[0].Month != "2024-01" || history[2].Month != "2024-03" {
+		t.Fatalf("GetPriceHistory() = %+v, want January to March", history)
+	}
+	if history[0].ChangePct != nil {
+		t.Errorf("first month change = %v, want nil", *history[0].ChangePct)
+	}
+	// March sold 4 units for 46.00, a discounted 11.50 average
+	march := history[2]
+	if march.AvgPrice != 1150 || march.UnitsSold != 4 || march.Transactions != 2 {
+		t.Errorf("March = %+v, want 4 units at 11.50", march)
+	}
+	if march.ChangePct == nil || math.Abs(*march.ChangePct-13.861) > 0.001 {
+		t.Errorf("March change = %v, want about 13.86%%", march.ChangePct)
+	}
+
+	history, err = service.GetPriceHistory(ctx, "P1", models.QueryOptions{Country: "France"})
+	if err != nil {
+		t.Fatalf("GetPriceHistory(France) error = %v", err)
+	}
+	if len(history) != 2 || history[0].Month != "2024-02" {
+		t.Errorf("GetPriceHistory(France) = %+v, want February and March", history)
+	}
+
+	if _, err := service.GetPriceHistory(ctx, "P404", models.QueryOptions{}); !errors.Is(err, models.ErrProductNotFound) {
+		t.Errorf("GetPriceHistory(P404) error = %v, want ErrProductNotFound", err)
+	}
+}
+
+func TestMemoryService_Partitions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "transactions.csv")
+	content := `transaction_id,user_id,transaction_date,country,region,product_id,product_name,category,price,quantity,total_price,stock_quantity
+T1,U1,2024-03-02,Germany,Bavaria,P1,Widget,Tools,10.00,1,10.00,50
+T2,U1,2024-01-31,Germany,Bavaria,P1,Widget,Tools,10.00,2,20.00,50
+T3,U2,2024-03-15,France,Normandy,P2,Gadget,Toys,5.00,1,5.00,30
+T4,U2,2024-01-01,France,Normandy,P2,Gadget,Toys,5.00,3,15.00,30
+T5,U1,2024-05-20,Germany,Bavaria,P1,Widget,Tools,10.00,1,10.00,50
+`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	service := services.NewMemoryService(config.DimensionsConfig{}, config.OutlierConfig{}, config.TestOrderConfig{}, &mockLogger{})
+	ctx := context.Background()
+	if err := service.LoadFromFile(ctx, models.DataFormatCSV, path); err != nil {
+		t.Fatalf("LoadFromFile() error = %v", err)
+	}
+
+	partitions, err := service.GetPartitions(ctx)
+	if err != nil {
+		t.Fatalf("GetPartitions() error = %v", err)
+	}
+	want := []models.Partition{
+		{Month: "2024-01", Rows: 2, From: "2024-01-01", To: "2024-01-31"},
+		{Month: "2024-03", Rows: 2, From: "2024-03-02", To: "2024-03-15"},
+		{Month: "2024-05", Rows: 1, From: "2024-05-20", To: "2024-05-20"},
+	}
+	if len(partitions) != len(want) {
+		t.Fatalf("GetPartitions() = %+v, want %+v", partitions, want)
+	}
+	for i := range want {
+		if partitions[i] != want[i] {
+			t.Errorf("GetPartitions()[%d] = %+v, want %+v", i, partitions[i], want[i])
+		}
+	}
+
+	day := func(s string) time.Time {
+		d, _ := time.Parse("2006-01-02", s)
+		return d
+	}
+	cases := []struct {
+		from, to string
+		want     string // revenue
+	}{
+		{"2024-01-31", "2024-03-03", "30.00"}, // ends of two partitions
+		{"2024-03-01", "2024-06-01", "25.00"},
+		{"2024-02-01", "2024-03-01", "0.00"}, // between partitions
+		{"2023-01-01", "2024-01-01", "0.00"}, // before the first
+		{"2024-06-01", "2025-01-01", "0.00"}, // after the last
+	}
+	for _, tc := range cases {
+		_, totals, err := service.GetCountryRevenue(ctx, models.QueryOptions{From: day(tc.from), To: day(tc.to)}, 10, 0)
+		if err != nil {
+			t.Fatalf("GetCountryRevenue(%s..%s) error = %v", tc.from, tc.to, err)
+		}
+		if got := totals.Revenue.String(); got != tc.want {
+			t.Errorf("GetCountryRevenue(%s..%s) revenue = %s, want %s", tc.from, tc.to, got, tc.want)
+		}
+	}
+}
+
+func TestMemoryService_ManyPairs(t *testing.T) {
+	// 300 countries by 300 product names is too many combinations to sum in
+	// an array, so the pairs are grouped through a map
+	const n = 300
+	var content strings.Builder
+	content.WriteString("transaction_id,user_id,transaction_date,country,region,product_id,product_name,category,price,quantity,total_price,stock_quantity\n")
+	for i := range n {
+		fmt.Fprintf(&content, "T%d,U1,2024-01-%02d,C%d,R1,P%d,Name%d,Tools,1.00,1,%d.00,10\n", i, i%28+1, i, i, i, i+1)
+	}
+	for i := range n {
+		fmt.Fprintf(&content, "X%d,U1,2024-02-01,C%d,R1,P%d,Name%d,Tools,1.00,1,1.00,10\n", i, i, i, i)
+	}
+	path := filepath.Join(t.TempDir(), "transactions.csv")
+	if err := os.WriteFile(path, []byte(content.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	service := services.NewMemoryService(config.DimensionsConfig{}, config.OutlierConfig{}, config.TestOrderConfig{}, &mockLogger{})
+	ctx := context.Background()
+	if err := service.LoadFromFile(ctx, models.DataFormatCSV, path); err != nil {
+		t.Fatalf("LoadFromFile() error = %v", err)
+	}
+
+	if count, err := service.GetCountryRevenueCount(ctx, models.QueryOptions{}); err != nil || count != n {
+		t.Errorf("GetCountryRevenueCount() = %d, %v, want %d", count, err, n)
+	}
+	// The count pages the same filtered rows as GetCountryRevenue
+	if count, err := service.GetCountryRevenueCount(ctx, models.QueryOptions{Country: "C7"}); err != nil || count != 1 {
+		t.Errorf("GetCountryRevenueCount(C7) = %d, %v, want 1", count, err)
+	}
+	revenue, totals, err := service.GetCountryRevenue(ctx, models.QueryOptions{}, 1, 0)
+	if err != nil {
+		t.Fatalf("GetCountryRevenue() error = %v", err)
+	}
+	if len(revenue) != 1 || revenue[0].Country != "C299" || revenue[0].TotalRevenue.String() != "301.00" || revenue[0].TransactionCount != 2 {
+		t.Errorf("GetCountryRevenue() = %+v, want C299 with 301.00 over 2 transactions", revenue)
+	}
+	if totals.Transactions != 2*n || totals.Revenue.String() != "45450.00" {
+		t.Errorf("GetCountryRevenue() totals = %+v, want 45450.00 over %d transactions", totals, 2*n)
+	}
+
+	filtered, _, err := service.GetCountryRevenue(ctx, models.QueryOptions{Country: "C7"}, 10, 0)
+	if err != nil {
+		t.Fatalf("GetCountryRevenue(C7) error = %v", err)
+	}
+	if len(filtered) != 1 || filtered[0].ProductName != "Name7" || filtered[0].TotalRevenue.String() != "9.00" {
+		t.Errorf("GetCountryRevenue(C7) = %+v, want Name7 with 9.00", filtered)
+	}
+}
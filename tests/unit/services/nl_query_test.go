@@ -0,0 +1,132 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"analytics-dashboard-api/internal/config"
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/internal/services"
+)
+
+// fakeNLBackend answers every grouping with the same rows; data ends on 2024-02-10
+type fakeNLBackend struct {
+	fakeMetricQuerier
+}
+
+func (b *fakeNLBackend) ListDimensionValues(context.Context, string, string, int, int) ([]models.DimensionValue, error) {
+	return []models.DimensionValue{{Value: "Germany"}, {Value: "New Zealand"}, {Value: "Zealand"}}, nil
+}
+
+func (b *fakeNLBackend) DataCoverage() models.DataCoverage {
+	return models.DataCoverage{From: "2023-01-01", To: "2024-02-10"}
+}
+
+func newTestNLQueryService(t *testing.T, enabled bool) (*services.NLQueryService, *fakeNLBackend) {
+	t.Helper()
+	registry, err := services.NewMetricRegistry(t.TempDir(), "aov=revenue/transactions", &mockLogger{})
+	if err != nil {
+		t.Fatalf("NewMetricRegistry() error = %v", err)
+	}
+	rows := []models.MetricRow{
+		{Group: "A", Values: map[string]float64{"revenue": 100, "transactions": 4}},
+		{Group: "B", Values: map[string]float64{"revenue": 300, "transactions": 2}},
+		{Group: "C", Values: map[string]float64{"revenue": 200, "transactions": 1}},
+	}
+	backend := &fakeNLBackend{fakeMetricQuerier{rows: map[string][]models.MetricRow{
+		"": rows[:1], "country": rows, "product": rows, "month": rows,
+	}}}
+	return services.NewNLQueryService(config.NLQueryConfig{Enabled: enabled}, backend, registry, &mockLogger{}), backend
+}
+
+func TestNLQueryService_Interpretations(t *testing.T) {
+	tests := []struct {
+		question string
+		want     models.NLInterpretation
+		from, to string
+	}{
+		{
+			question: "Revenue by country last quarter?",
+			want: models.NLInterpretation{Template: models.NLTemplateBreakdown, Metrics: []string{"revenue"}, GroupBy: "country", Sort: "revenue",
+				Period: &models.NLPeriod{Label: "last quarter", From: "2023-10-01", To: "2023-12-31"}},
+			from: "2023-10-01", to: "2024-01-01",
+		},
+		{
+			question: "top 2 products by orders in New Zealand",
+			want: models.NLInterpretation{Template: models.NLTemplateTop, Metrics: []string{"transactions"}, GroupBy: "product",
+				Country: "New Zealand", Sort: "transactions", Limit: 2},
+		},
+		{
+			question: "monthly sales and AOV in March 2023",
+			want: models.NLInterpretation{Template: models.NLTemplateTrend, Metrics: []string{"revenue", "aov"}, GroupBy: "month",
+				Period: &models.NLPeriod{Label: "march 2023", From: "2023-03-01", To: "2023-03-31"}},
+			from: "2023-03-01", to: "2023-04-01",
+		},
+		{
+			question: "customers in Germany over the last 7 days",
+			want: models.NLInterpretation{Template: models.NLTemplateTotal, Metrics: []string{"customers"}, Country: "Germany",
+				Period: &models.NLPeriod{Label: "last 7 days", From: "2024-02-04", To: "2024-02-10"}},
+			from: "2024-02-04", to: "2024-02-11",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.question, func(t *testing.T) {
+			service, backend := newTestNLQueryService(t, true)
+			answer, err := service.Answer(context.Background(), tt.question)
+			if err != nil {
+				t.Fatalf("Answer() error = %v", err)
+			}
+
+			got := answer.Interpretation
+			if got.Template != tt.want.Template || got.GroupBy != tt.want.GroupBy || got.Country != tt.want.Country ||
+				got.Sort != tt.want.Sort || got.Limit != tt.want.Limit || !slices.Equal(got.Metrics, tt.want.Metrics) {
+				t.Errorf("interpretation = %+v, want %+v", got, tt.want)
+			}
+			if (got.Period == nil) != (tt.want.Period == nil) || (got.Period != nil && *got.Period != *tt.want.Period) {
+				t.Errorf("period = %+v, want %+v", got.Period, tt.want.Period)
+			}
+
+			opts := backend.opts[0]
+			if opts.Country != tt.want.Country || formatDay(opts.From) != tt.from || formatDay(opts.To) != tt.to {
+				t.Errorf("query options = %+v, want country %q from %s to %s", opts, tt.want.Country, tt.from, tt.to)
+			}
+			if tt.want.Limit > 0 && (len(answer.Data) != tt.want.Limit || answer.Data[0].Group != "A") {
+				t.Errorf("data = %+v, want the %d groups with most orders", answer.Data, tt.want.Limit)
+			}
+		})
+	}
+}
+
+func TestNLQueryService_RejectsUnsupportedQuestions(t *testing.T) {
+	service, _ := newTestNLQueryService(t, true)
+
+	for _, question := range []string{
+		"",
+		"what is the meaning of life",
+		"revenue by customer",
+		"revenue by country and by region",
+		"revenue in 2022 and 2023",
+		"revenue in Germany and New Zealand",
+		"top 500 products",
+	} {
+		if _, err := service.Answer(context.Background(), question); !errors.Is(err, models.ErrValidation) {
+			t.Errorf("Answer(%q) error = %v, want a validation error", question, err)
+		}
+	}
+
+	disabled, _ := newTestNLQueryService(t, false)
+	if _, err := disabled.Answer(context.Background(), "revenue"); !errors.Is(err, models.ErrNLQueryDisabled) {
+		t.Errorf("Answer() while disabled error = %v, want ErrNLQueryDisabled", err)
+	}
+}
+
+func formatDay(day time.Time) string {
+	if day.IsZero() {
+		return ""
+	}
+	return day.Format("2006-01-02")
+}
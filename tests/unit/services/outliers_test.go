@@ -0,0 +1,81 @@
+package services_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"analytics-dashboard-api/internal/config"
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/internal/services"
+)
+
+// outlierCSV has a fat-fingered quantity in T4 and price in T5
+const outlierCSV = memoryTransactionsCSV + `T4,U2,2024-02-11,France,Normandy,P1,Widget,Tools,10.10,100000,1010000.00,49
+T5,U1,2024-02-12,Germany,Bavaria,P2,Gadget,Toys,5000.00,1,5000.00,30
+`
+
+func loadOutliers(t *testing.T, cfg config.OutlierConfig) *services.MemoryService {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "transactions.csv")
+	if err := os.WriteFile(path, []byte(outlierCSV), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	service := services.NewMemoryService(config.DimensionsConfig{}, cfg, config.TestOrderConfig{}, &mockLogger{})
+	if err := service.LoadFromFile(context.Background(), models.DataFormatCSV, path); err != nil {
+		t.Fatalf("LoadFromFile() error = %v", err)
+	}
+	return service
+}
+
+func TestMemoryService_TagsOutliers(t *testing.T) {
+	service := loadOutliers(t, config.OutlierConfig{Mode: models.OutlierModeTag, MaxPrice: 1000, MaxQuantity: 100})
+
+	report := service.DataCoverage().Outliers
+	if report == nil || report.Rows != 2 || report.PriceOutliers != 1 || report.QuantityOutliers != 1 {
+		t.Fatalf("Outliers = %+v, want 2 rows, 1 price and 1 quantity outlier", report)
+	}
+	if *report.PriceLimit != 1000 || *report.QuantityLimit != 100 {
+		t.Errorf("limits = %v, %v, want 1000, 100", *report.PriceLimit, *report.QuantityLimit)
+	}
+
+	// Tagged rows stay in the aggregates
+	if records, _ := service.GetTotalRecords(context.Background()); records != 5 {
+		t.Errorf("GetTotalRecords() = %d, want 5", records)
+	}
+
+	outliers, err := service.ListOutliers(context.Background(), 10, 0)
+	if err != nil {
+		t.Fatalf("ListOutliers() error = %v", err)
+	}
+	if len(outliers) != 2 || outliers[0].TransactionID != "T4" || outliers[0].Reasons[0] != "quantity" ||
+		outliers[1].TransactionID != "T5" || outliers[1].Reasons[0] != "price" {
+		t.Errorf("ListOutliers() = %+v, want T4 (quantity) then T5 (price)", outliers)
+	}
+}
+
+func TestMemoryService_ExcludesPercentileOutliers(t *testing.T) {
+	// With five rows the 0.5 quantile is the median: price 10.10, quantity 1
+	service := loadOutliers(t, config.OutlierConfig{Mode: models.OutlierModeExclude, Percentile: 0.5, Factor: 10})
+
+	report := service.DataCoverage().Outliers
+	if report == nil || report.Rows != 2 || report.QuantityLimit == nil || *report.QuantityLimit != 10 {
+		t.Fatalf("Outliers = %+v, want 2 rows with a quantity limit of 10", report)
+	}
+	if records, _ := service.GetTotalRecords(context.Background()); records != 3 {
+		t.Errorf("GetTotalRecords() = %d, want 3 after excluding outliers", records)
+	}
+}
+
+func TestMemoryService_OutliersOff(t *testing.T) {
+	service := loadOutliers(t, config.OutlierConfig{Mode: "off", MaxQuantity: 1})
+
+	if report := service.DataCoverage().Outliers; report != nil {
+		t.Errorf("Outliers = %+v, want nil", report)
+	}
+	outliers, _ := service.ListOutliers(context.Background(), 10, 0)
+	if len(outliers) != 0 {
+		t.Errorf("ListOutliers() = %v, want none", outliers)
+	}
+}
@@ -0,0 +1,103 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"analytics-dashboard-api/internal/config"
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/internal/services"
+)
+
+func writeCSV(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "data.csv")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestQuotas_CheckFiles(t *testing.T) {
+	quotas, err := services.NewQuotas(config.QuotaConfig{
+		Targets: config.QuotaLimit{MaxRows: 3},
+		Tenant:  config.QuotaLimit{MaxRows: 2, MaxBytes: 1000},
+		Tenants: "big=rows:0; tiny=bytes:10",
+	})
+	if err != nil {
+		t.Fatalf("NewQuotas() error = %v", err)
+	}
+	ctx := context.Background()
+	path := writeCSV(t, "month,country,revenue_target\n2024-01,DE,1\n2024-01,FR,2\n2024-01,ES,3") // 3 rows, no final newline
+
+	if err := quotas.CheckFiles(ctx, models.DatasetTargets, "", path); err != nil {
+		t.Errorf("anonymous load at the dataset limit: error = %v, want nil", err)
+	}
+
+	var quota *models.QuotaError
+	err = quotas.CheckFiles(ctx, models.DatasetTargets, "acme", path)
+	if !errors.As(err, &quota) || quota.Tenant != "acme" || quota.Measure != "rows" || quota.Value != 3 || quota.Limit != 2 {
+		t.Errorf("default tenant limit: error = %v, want 3 rows over acme's limit of 2", err)
+	}
+	if err := quotas.CheckFiles(ctx, models.DatasetTargets, "big", path); err != nil {
+		t.Errorf("override lifting the row limit: error = %v, want nil", err)
+	}
+	err = quotas.CheckFiles(ctx, models.DatasetTargets, "tiny", path)
+	if !errors.As(err, &quota) || quota.Measure != "bytes" {
+		t.Errorf("override lowering the byte limit: error = %v, want a bytes quota error", err)
+	}
+
+	if err := quotas.CheckFiles(ctx, models.DatasetTargets, "", path, path); !errors.Is(err, models.ErrQuotaExceeded) {
+		t.Errorf("two files: error = %v, want 6 rows over the dataset limit of 3", err)
+	}
+	// JSON Lines has no header, so every line is a row
+	jsonl := filepath.Join(t.TempDir(), "data.jsonl")
+	if err := os.WriteFile(jsonl, []byte("{\"month\":\"2024-01\"}\n{\"month\":\"2024-02\"}\n{\"month\":\"2024-03\"}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	err = quotas.CheckFiles(ctx, models.DatasetTargets, "acme", jsonl)
+	if !errors.As(err, &quota) || quota.Measure != "rows" || quota.Value != 3 {
+		t.Errorf("JSON Lines file: error = %v, want 3 rows over acme's limit of 2", err)
+	}
+	if err := quotas.CheckSize("tiny", 11); !errors.Is(err, models.ErrQuotaExceeded) {
+		t.Errorf("CheckSize() error = %v, want ErrQuotaExceeded", err)
+	}
+
+	for _, tenants := range []string{"acme", "acme=rows:-1", "acme=lines:5"} {
+		if _, err := services.NewQuotas(config.QuotaConfig{Tenants: tenants}); err == nil {
+			t.Errorf("NewQuotas(%q) succeeded, want an error", tenants)
+		}
+	}
+}
+
+func TestDataLoader_QuotaKeepsLoadedData(t *testing.T) {
+	backend := &blockingLoader{release: make(chan struct{})}
+	close(backend.release)
+	path := writeCSV(t, "transaction_id\n1\n2\n")
+	quotas, err := services.NewQuotas(config.QuotaConfig{Transactions: config.QuotaLimit{MaxBytes: 1 << 20}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	loader := services.NewDataLoader(backend, path, config.DataConfig{LoadWait: time.Second, LoadTimeout: time.Minute}, services.NewJobQueue(&mockLogger{}), quotas, nil, &mockLogger{})
+	if err := loader.EnsureInitialized(context.Background()); err != nil {
+		t.Fatalf("EnsureInitialized() error = %v", err)
+	}
+
+	// The file grows past the quota before the next refresh
+	if err := os.WriteFile(path, make([]byte, 2<<20), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := loader.Reload(context.Background(), 0); !errors.Is(err, models.ErrQuotaExceeded) {
+		t.Fatalf("Reload() error = %v, want ErrQuotaExceeded", err)
+	}
+	if got := backend.loads.Load(); got != 1 {
+		t.Errorf("LoadFromFile called %d times, want the refused refresh never to reach the backend", got)
+	}
+	if stats := loader.Stats(); !stats.Loaded || stats.DataVersion != 1 {
+		t.Errorf("Stats() = %+v, want the first load still in place", stats)
+	}
+}
@@ -0,0 +1,53 @@
+package services_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"analytics-dashboard-api/internal/config"
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/internal/services"
+)
+
+func TestRefreshScheduler_Schedule(t *testing.T) {
+	loader := services.NewDataLoader(&blockingLoader{}, "data.csv", config.DataConfig{LoadTimeout: time.Minute}, services.NewJobQueue(&mockLogger{}), nil, nil, &mockLogger{})
+	scheduler, err := services.NewRefreshScheduler(config.RefreshConfig{Cron: "0 2 * * *", Timezone: "UTC"}, loader, nil, &mockLogger{})
+	if err != nil {
+		t.Fatalf("NewRefreshScheduler: %v", err)
+	}
+
+	schedule := scheduler.Schedule()
+	if !schedule.Enabled || schedule.Cron != "0 2 * * *" || schedule.Source != models.ScheduleSourceConfig || schedule.NextRun == nil {
+		t.Fatalf("Schedule() = %+v, want the configured schedule with a next run", schedule)
+	}
+	if next := schedule.NextRun.UTC(); next.Hour() != 2 || next.Minute() != 0 || !next.After(time.Now()) {
+		t.Errorf("next run = %v, want the next 02:00", next)
+	}
+
+	schedule, err = scheduler.SetSchedule(models.RefreshScheduleRequest{Cron: "@hourly", Timezone: "Asia/Kolkata"})
+	if err != nil {
+		t.Fatalf("SetSchedule: %v", err)
+	}
+	if schedule.Source != models.ScheduleSourceAPI || schedule.Timezone != "Asia/Kolkata" || schedule.NextRun.Minute() != 0 {
+		t.Errorf("Schedule() after change = %+v", schedule)
+	}
+
+	for _, request := range []models.RefreshScheduleRequest{
+		{Cron: "0 25 * * *"},
+		{Cron: "0 0 30 2 *"},
+		{Cron: "@daily", Timezone: "Mars/Olympus"},
+	} {
+		if _, err := scheduler.SetSchedule(request); !errors.Is(err, models.ErrValidation) {
+			t.Errorf("SetSchedule(%+v) error = %v, want a validation error", request, err)
+		}
+	}
+	if schedule := scheduler.Schedule(); schedule.Cron != "@hourly" {
+		t.Errorf("rejected changes replaced the schedule: %+v", schedule)
+	}
+
+	schedule, err = scheduler.SetSchedule(models.RefreshScheduleRequest{})
+	if err != nil || schedule.Enabled || schedule.NextRun != nil || schedule.Timezone != "Asia/Kolkata" {
+		t.Errorf("disabled schedule = %+v, %v", schedule, err)
+	}
+}
@@ -0,0 +1,123 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"analytics-dashboard-api/internal/config"
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/internal/services"
+)
+
+func TestDataLoader_LoadsRemoteSources(t *testing.T) {
+	var mu sync.Mutex
+	var requests []*http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, r)
+		mu.Unlock()
+		switch r.URL.EscapedPath() {
+		case "/exports/daily%20drop/transactions.csv", "/public/transactions.csv":
+			w.Write([]byte(memoryTransactionsCSV))
+		case "/private/transactions.csv":
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`<Error><Code>AccessDenied</Code><Message>Access Denied</Message></Error>`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	cfg := config.DataConfig{LoadWait: 5 * time.Second, LoadTimeout: time.Minute, Remote: config.RemoteSourceConfig{
+		CacheDir:          t.TempDir(),
+		S3Region:          "eu-west-1",
+		S3Endpoint:        server.URL,
+		S3AccessKeyID:     "AKIDEXAMPLE",
+		S3SecretAccessKey: "secret",
+		S3SessionToken:    "token",
+		GCSEndpoint:       server.URL,
+	}}
+	load := func(path string) (*services.MemoryService, error) {
+		repo := services.NewMemoryService(config.DimensionsConfig{}, config.OutlierConfig{}, config.TestOrderConfig{}, &mockLogger{})
+		loader := services.NewDataLoader(repo, path, cfg, services.NewJobQueue(&mockLogger{}), nil, nil, &mockLogger{})
+		return repo, loader.EnsureInitialized(context.Background())
+	}
+
+	repo, err := load("s3://exports/daily drop/transactions.csv")
+	if err != nil {
+		t.Fatalf("loading from S3: %v", err)
+	}
+	if records, _ := repo.GetTotalRecords(context.Background()); records != 3 {
+		t.Errorf("loaded %d records from S3, want 3", records)
+	}
+	signed := requests[0]
+	auth := signed.Header.Get("Authorization")
+	today := time.Now().UTC().Format("20060102")
+	if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/"+today+"/eu-west-1/s3/aws4_request, SignedHeaders=host;x-amz-content-sha256;x-amz-date;x-amz-security-token, Signature=") {
+		t.Errorf("Authorization = %q", auth)
+	}
+	if signed.Header.Get("X-Amz-Security-Token") != "token" {
+		t.Errorf("session token not sent: %v", signed.Header)
+	}
+
+	// Cloud Storage without HMAC keys is read anonymously
+	if _, err := load("gs://public/transactions.csv"); err != nil {
+		t.Fatalf("loading from GCS: %v", err)
+	}
+	if auth := requests[1].Header.Get("Authorization"); auth != "" {
+		t.Errorf("anonymous request signed: %q", auth)
+	}
+
+	if _, err := load("s3://exports/missing.csv"); !errors.Is(err, models.ErrSourceMissing) {
+		t.Errorf("missing object error = %v, want ErrSourceMissing", err)
+	}
+	if _, err := load("s3://private/transactions.csv"); err == nil || !strings.Contains(err.Error(), "AccessDenied") {
+		t.Errorf("forbidden object error = %v, want AccessDenied", err)
+	}
+	if _, err := load("s3://exports/manifest.json"); !errors.Is(err, models.ErrInvalidManifest) {
+		t.Errorf("remote manifest error = %v, want ErrInvalidManifest", err)
+	}
+}
+
+func TestDataLoader_RevalidatesRemoteSources(t *testing.T) {
+	var downloads, revalidations int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			revalidations++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		downloads++
+		w.Write([]byte(memoryTransactionsCSV))
+	}))
+	defer server.Close()
+
+	cfg := config.DataConfig{LoadWait: 5 * time.Second, LoadTimeout: time.Minute, Remote: config.RemoteSourceConfig{
+		CacheDir:   t.TempDir(),
+		S3Endpoint: server.URL,
+	}}
+	repo := services.NewMemoryService(config.DimensionsConfig{}, config.OutlierConfig{}, config.TestOrderConfig{}, &mockLogger{})
+	loader := services.NewDataLoader(repo, "s3://exports/transactions.csv", cfg, services.NewJobQueue(&mockLogger{}), nil, nil, &mockLogger{})
+	ctx := context.Background()
+	if err := loader.EnsureInitialized(ctx); err != nil {
+		t.Fatalf("initial load: %v", err)
+	}
+
+	// An unchanged object answers 304 and the cached copy is loaded again
+	if err := loader.Reload(ctx, 0); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if downloads != 1 || revalidations != 1 {
+		t.Errorf("downloads = %d, revalidations = %d, want 1 and 1", downloads, revalidations)
+	}
+	if records, _ := repo.GetTotalRecords(ctx); records != 3 {
+		t.Errorf("loaded %d records from the cached copy, want 3", records)
+	}
+}
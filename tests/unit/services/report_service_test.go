@@ -0,0 +1,151 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"analytics-dashboard-api/internal/config"
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/internal/services"
+)
+
+// fakeMetricQuerier returns fixed rows per grouping and records the filters
+type fakeMetricQuerier struct {
+	rows map[string][]models.MetricRow
+	opts []models.QueryOptions
+}
+
+func (q *fakeMetricQuerier) GetBaseMetrics(_ context.Context, opts models.QueryOptions, groupBy string) ([]models.MetricRow, error) {
+	q.opts = append(q.opts, opts)
+	return q.rows[groupBy], nil
+}
+
+func newTestReportService(t *testing.T, templates map[string]string) (*services.ReportService, *fakeMetricQuerier) {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range templates {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	registry, err := services.NewMetricRegistry(t.TempDir(), "aov=revenue/transactions", &mockLogger{})
+	if err != nil {
+		t.Fatalf("NewMetricRegistry() error = %v", err)
+	}
+	queries := &fakeMetricQuerier{rows: map[string][]models.MetricRow{
+		"": {{Values: map[string]float64{"revenue": 600, "transactions": 6}}},
+		"country": {
+			{Group: "France", Values: map[string]float64{"revenue": 100, "transactions": 1}},
+			{Group: "Germany", Values: map[string]float64{"revenue": 300, "transactions": 3}},
+			{Group: "Spain", Values: map[string]float64{"revenue": 200, "transactions": 0}},
+		},
+	}}
+	cfg := config.ReportConfig{TemplateDir: dir}
+	return services.NewReportService(cfg, queries, registry, &mockLogger{}), queries
+}
+
+const testReportTemplate = `
+title: Country review
+filters: {country: Germany}
+sections:
+  - title: Total
+    metrics: [revenue]
+  - title: Top countries
+    metrics: [revenue, aov]
+    group_by: country
+    sort: revenue
+    limit: 2
+    chart: bar
+  - title: Notes
+    text: Gross of refunds.
+`
+
+func TestReportService_Render(t *testing.T) {
+	reports, queries := newTestReportService(t, map[string]string{"review.yaml": testReportTemplate})
+
+	report, err := reports.Render(context.Background(), "review", models.QueryOptions{Segment: "Corporate"})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if report.Filters != (models.ReportFilters{Country: "Germany", Segment: "Corporate"}) {
+		t.Errorf("filters = %+v, want the template country with the requested segment", report.Filters)
+	}
+	if len(queries.opts) != 2 || queries.opts[0].Country != "Germany" || queries.opts[0].Segment != "Corporate" {
+		t.Errorf("queries ran with %+v, want the merged filters for each query section", queries.opts)
+	}
+
+	if chart := report.Sections[0].Chart; chart != models.ChartTable {
+		t.Errorf("default chart = %q, want table", chart)
+	}
+	top := report.Sections[1].Rows
+	if len(top) != 2 || top[0].Group != "Germany" || top[1].Group != "Spain" {
+		t.Fatalf("top countries = %+v, want Germany and Spain by revenue", top)
+	}
+	if aov := top[0].Metrics["aov"]; aov == nil || *aov != 100 {
+		t.Errorf("Germany aov = %v, want 100", aov)
+	}
+	if aov := top[1].Metrics["aov"]; aov != nil {
+		t.Errorf("Spain aov = %v, want null after division by zero", *aov)
+	}
+
+	html, err := reports.HTML(report)
+	if err != nil {
+		t.Fatalf("HTML() error = %v", err)
+	}
+	for _, want := range []string{"<h1>Country review</h1>", "Germany", "Gross of refunds.", `class="fill"`} {
+		if !strings.Contains(string(html), want) {
+			t.Errorf("HTML lacks %q", want)
+		}
+	}
+}
+
+func TestReportService_InvalidTemplates(t *testing.T) {
+	reports, _ := newTestReportService(t, map[string]string{
+		"review.yaml":  testReportTemplate,
+		"typo.yaml":    "title: Typo\nsections:\n  - title: A\n    metrics: [revenue]\n    groupby: month\n",
+		"metric.yml":   "title: Metric\nsections:\n  - title: A\n    metrics: [profit]\n",
+		"chart.yaml":   "title: Chart\nsections:\n  - title: A\n    metrics: [revenue]\n    chart: line\n",
+		"ignored.json": "{}",
+	})
+
+	list, err := reports.List()
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(list) != 4 {
+		t.Fatalf("List() = %+v, want the four YAML templates", list)
+	}
+	for _, info := range list {
+		if (info.Error == "") != (info.Name == "review") {
+			t.Errorf("template %s error = %q, want an error only for the invalid templates", info.Name, info.Error)
+		}
+	}
+
+	for _, name := range []string{"typo", "metric", "chart"} {
+		if _, err := reports.Render(context.Background(), name, models.QueryOptions{}); !errors.Is(err, models.ErrValidation) {
+			t.Errorf("Render(%s) error = %v, want a validation error", name, err)
+		}
+	}
+	for _, name := range []string{"missing", "../review", "ignored"} {
+		if _, err := reports.Render(context.Background(), name, models.QueryOptions{}); !errors.Is(err, models.ErrReportNotFound) {
+			t.Errorf("Render(%q) error = %v, want ErrReportNotFound", name, err)
+		}
+	}
+}
+
+func TestReportService_PDFNeedsCommand(t *testing.T) {
+	reports, _ := newTestReportService(t, map[string]string{"review.yaml": testReportTemplate})
+
+	report, err := reports.Render(context.Background(), "review", models.QueryOptions{})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if _, err := reports.PDF(context.Background(), report); !errors.Is(err, models.ErrReportNoPDF) {
+		t.Errorf("PDF() error = %v, want ErrReportNoPDF without REPORT_PDF_COMMAND", err)
+	}
+}
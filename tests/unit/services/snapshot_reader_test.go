@@ -0,0 +1,82 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"analytics-dashboard-api/internal/config"
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/internal/services"
+)
+
+// snapshotRepo restores any backup by name and records when it is closed
+type snapshotRepo struct {
+	services.Repository
+	name   string
+	closed bool
+}
+
+func (r *snapshotRepo) Restore(_ context.Context, _, name string) (*models.BackupInfo, error) {
+	r.name = name
+	return &models.BackupInfo{Name: name}, nil
+}
+
+func (r *snapshotRepo) Close() error {
+	r.closed = true
+	return nil
+}
+
+func TestSnapshotReader_AsOf(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"20240401T020000Z", "20240501T020000Z", "20240601T020000Z", "not-a-backup"} {
+		if err := os.Mkdir(filepath.Join(dir, name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var opened []*snapshotRepo
+	reader := services.NewSnapshotReader(config.BackupConfig{Dir: dir, OpenSnapshots: 1}, func() (services.Repository, error) {
+		repo := &snapshotRepo{}
+		opened = append(opened, repo)
+		return repo, nil
+	}, &mockLogger{})
+	defer reader.Close()
+
+	ctx := context.Background()
+	_, info, release, err := reader.AsOf(ctx, time.Date(2024, 5, 20, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("AsOf() error = %v", err)
+	}
+	if info.Name != "20240501T020000Z" {
+		t.Errorf("AsOf(2024-05-20) = %s, want the newest backup before it", info.Name)
+	}
+
+	// The same snapshot is reused while open
+	_, _, releaseAgain, _ := reader.AsOf(ctx, time.Date(2024, 5, 1, 2, 0, 0, 0, time.UTC))
+	if len(opened) != 1 {
+		t.Errorf("opened %d backends, want the snapshot reused", len(opened))
+	}
+
+	// Opening another evicts it, but it stays open until released
+	_, info, releaseOther, err := reader.AsOf(ctx, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil || info.Name != "20240601T020000Z" {
+		t.Fatalf("AsOf(2025-01-01) = %v, %v, want the latest backup", info, err)
+	}
+	if opened[0].closed {
+		t.Error("evicted snapshot closed while in use")
+	}
+	release()
+	releaseAgain()
+	if !opened[0].closed {
+		t.Error("evicted snapshot not closed after its last release")
+	}
+	releaseOther()
+
+	if _, _, _, err := reader.AsOf(ctx, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)); !errors.Is(err, models.ErrBackupNotFound) {
+		t.Errorf("AsOf() before the first backup error = %v, want ErrBackupNotFound", err)
+	}
+}
@@ -0,0 +1,83 @@
+package services_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"analytics-dashboard-api/internal/config"
+	"analytics-dashboard-api/internal/services"
+)
+
+func TestSourceWatcher_ReloadsOnceAfterDebounce(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "transactions.csv")
+	if err := os.WriteFile(path, []byte("v1"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	backend := &blockingLoader{release: make(chan struct{})}
+	close(backend.release)
+	cfg := config.DataConfig{LoadWait: time.Second, LoadTimeout: time.Minute, WatchInterval: 5 * time.Millisecond, WatchDebounce: 50 * time.Millisecond}
+	loader := services.NewDataLoader(backend, path, cfg, services.NewJobQueue(&mockLogger{}), nil, nil, &mockLogger{})
+	if err := loader.EnsureInitialized(context.Background()); err != nil {
+		t.Fatalf("EnsureInitialized: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	services.NewSourceWatcher(loader, path, cfg, &mockLogger{}).Start(ctx)
+	time.Sleep(20 * time.Millisecond)
+
+	// A file written in several steps is loaded once, after the last one
+	for _, content := range []string{"v2", "v2 and more", "v2 and more rows"} {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(15 * time.Millisecond)
+	}
+	if got := backend.loads.Load(); got != 1 {
+		t.Fatalf("loads while the file kept changing = %d, want only the initial load", got)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for backend.loads.Load() < 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("changed file was never reloaded")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if got := backend.loads.Load(); got != 2 {
+		t.Errorf("loads = %d, want 2", got)
+	}
+	if stats := loader.Stats(); stats.Loads != 2 || stats.Failures != 0 {
+		t.Errorf("loader stats = %+v, want 2 successful loads", stats)
+	}
+}
+
+func TestSourceWatcher_WaitsForFirstLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "transactions.csv")
+	if err := os.WriteFile(path, []byte("v1"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	backend := &blockingLoader{release: make(chan struct{})}
+	close(backend.release)
+	cfg := config.DataConfig{LoadTimeout: time.Minute, WatchInterval: 5 * time.Millisecond}
+	loader := services.NewDataLoader(backend, path, cfg, services.NewJobQueue(&mockLogger{}), nil, nil, &mockLogger{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	services.NewSourceWatcher(loader, path, cfg, &mockLogger{}).Start(ctx)
+	time.Sleep(20 * time.Millisecond)
+	if err := os.WriteFile(path, []byte("v2"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	if got := backend.loads.Load(); got != 0 {
+		t.Errorf("loads = %d, want none before the first request", got)
+	}
+}
@@ -0,0 +1,151 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"analytics-dashboard-api/internal/config"
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/internal/services"
+)
+
+func newTestTimeSeriesService(t *testing.T) *services.TimeSeriesService {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "transactions.csv")
+	if err := os.WriteFile(path, []byte(memoryTransactionsCSV), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	backend := services.NewMemoryService(config.DimensionsConfig{}, config.OutlierConfig{}, config.TestOrderConfig{}, &mockLogger{})
+	if err := backend.LoadFromFile(context.Background(), models.DataFormatCSV, path); err != nil {
+		t.Fatalf("LoadFromFile() error = %v", err)
+	}
+	return services.NewTimeSeriesService(backend, &mockLogger{})
+}
+
+func TestTimeSeriesService_Series(t *testing.T) {
+	series := newTestTimeSeriesService(t)
+	ctx := context.Background()
+
+	// 2024-01-05 is a Friday and 2024-02-10 a Saturday; the weeks between
+	// them have no sales but still get a zero
+	weekly, err := series.Series(ctx, models.QueryOptions{}, models.GranularityWeek, "")
+	if err != nil {
+		t.Fatalf("Series(week) error = %v", err)
+	}
+	wantDates := []string{"2024-01-01", "2024-01-08", "2024-01-15", "2024-01-22", "2024-01-29", "2024-02-05"}
+	if !slices.Equal(weekly.Dates, wantDates) {
+		t.Fatalf("dates = %v, want %v", weekly.Dates, wantDates)
+	}
+	if !slices.Equal(weekly.Revenue, []models.Money{2020, 0, 0, 0, 0, 1510}) ||
+		!slices.Equal(weekly.Orders, []int{1, 0, 0, 0, 0, 2}) ||
+		!slices.Equal(weekly.Units, []int{2, 0, 0, 0, 0, 2}) {
+		t.Errorf("series = %v, %v, %v, want the sales of the first and last week", weekly.Revenue, weekly.Orders, weekly.Units)
+	}
+
+	// An explicit range is covered in full, To exclusive
+	opts := models.QueryOptions{
+		From: time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
+		To:   time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC),
+	}
+	monthly, err := series.Series(ctx, opts, models.GranularityMonth, "")
+	if err != nil {
+		t.Fatalf("Series(month) error = %v", err)
+	}
+	if !slices.Equal(monthly.Dates, []string{"2024-01-01", "2024-02-01", "2024-03-01"}) || !slices.Equal(monthly.Revenue, []models.Money{0, 1510, 0}) {
+		t.Errorf("monthly = %v %v, want January to March with only February's sales", monthly.Dates, monthly.Revenue)
+	}
+
+	if _, err := series.Series(ctx, models.QueryOptions{}, "year", ""); !errors.Is(err, models.ErrInvalidTimeSeries) {
+		t.Errorf("Series(year) error = %v, want ErrInvalidTimeSeries", err)
+	}
+	if weekly.Smoothed != nil {
+		t.Errorf("smoothed = %v without smoothing, want nil", weekly.Smoothed)
+	}
+}
+
+func TestTimeSeriesService_Smooth(t *testing.T) {
+	series := newTestTimeSeriesService(t)
+	ctx := context.Background()
+
+	// Weekly revenue is 20.20, 0, 0, 0, 0, 15.10
+	tests := []struct {
+		smooth string
+		want   []float64
+	}{
+		// Windows shorter than the series average the periods there are
+		{models.SmoothMA7, []float64{20.2, 10.1, 6.73, 5.05, 4.04, 5.88}},
+		{models.SmoothMA30, []float64{20.2, 10.1, 6.73, 5.05, 4.04, 5.88}},
+		{models.SmoothEWMA, []float64{20.2, 15.15, 11.36, 8.52, 6.39, 8.57}},
+	}
+	for _, tt := range tests {
+		weekly, err := series.Series(ctx, models.QueryOptions{}, models.GranularityWeek, tt.smooth)
+		if err != nil {
+			t.Fatalf("Series(%s) error = %v", tt.smooth, err)
+		}
+		if weekly.Smooth != tt.smooth || !slices.Equal(weekly.Smoothed[models.SeriesRevenue], tt.want) {
+			t.Errorf("Series(%s) smoothed revenue = %v, want %v", tt.smooth, weekly.Smoothed[models.SeriesRevenue], tt.want)
+		}
+		if !slices.Equal(weekly.Revenue, []models.Money{2020, 0, 0, 0, 0, 1510}) {
+			t.Errorf("Series(%s) revenue = %v, want the raw values kept", tt.smooth, weekly.Revenue)
+		}
+	}
+
+	// The daily series starts with the sale of 2024-01-05, which leaves the
+	// 7-day window a week later
+	daily, err := series.Series(ctx, models.QueryOptions{}, models.GranularityDay, models.SmoothMA7)
+	if err != nil {
+		t.Fatalf("Series(day) error = %v", err)
+	}
+	orders := daily.Smoothed[models.SeriesOrders]
+	if orders[0] != 1 || orders[6] != 0.14 || orders[7] != 0 {
+		t.Errorf("daily orders ma7 = %v, want the first sale to leave the window after 7 days", orders[:8])
+	}
+
+	if _, err := series.Series(ctx, models.QueryOptions{}, models.GranularityWeek, "ma14"); !errors.Is(err, models.ErrInvalidTimeSeries) {
+		t.Errorf("Series(ma14) error = %v, want ErrInvalidTimeSeries", err)
+	}
+}
+
+func TestTimeSeriesService_Calendar(t *testing.T) {
+	series := newTestTimeSeriesService(t)
+	ctx := context.Background()
+
+	// The default year is that of the latest data, 2024, a leap year
+	calendar, err := series.Calendar(ctx, models.QueryOptions{}, 0, models.SeriesRevenue)
+	if err != nil {
+		t.Fatalf("Calendar() error = %v", err)
+	}
+	if calendar.Year != 2024 || len(calendar.Days) != 366 || calendar.Days[0].Date != "2024-01-01" {
+		t.Fatalf("calendar = year %d with %d days, want every day of 2024", calendar.Year, len(calendar.Days))
+	}
+	if calendar.Total != 35.3 || calendar.MaxValue != 20.2 {
+		t.Errorf("total, max = %g, %g, want 35.3, 20.2", calendar.Total, calendar.MaxValue)
+	}
+	for _, want := range []models.CalendarDay{
+		{Date: "2024-01-05", Value: 20.2, Intensity: 1},
+		{Date: "2024-02-10", Value: 15.1, Intensity: 0.7475},
+		{Date: "2024-02-11", Value: 0, Intensity: 0},
+	} {
+		day, _ := time.Parse("2006-01-02", want.Date)
+		if got := calendar.Days[day.YearDay()-1]; got != want {
+			t.Errorf("day = %+v, want %+v", got, want)
+		}
+	}
+
+	units, err := series.Calendar(ctx, models.QueryOptions{}, 2023, models.SeriesUnits)
+	if err != nil {
+		t.Fatalf("Calendar(2023) error = %v", err)
+	}
+	if len(units.Days) != 365 || units.Total != 0 || units.MaxValue != 0 {
+		t.Errorf("2023 = %d days, total %g, want 365 empty days", len(units.Days), units.Total)
+	}
+
+	if _, err := series.Calendar(ctx, models.QueryOptions{}, 2024, "profit"); !errors.Is(err, models.ErrInvalidCalendar) {
+		t.Errorf("Calendar(profit) error = %v, want ErrInvalidCalendar", err)
+	}
+}
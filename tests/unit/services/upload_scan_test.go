@@ -0,0 +1,137 @@
+package services_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"analytics-dashboard-api/internal/config"
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/internal/services"
+)
+
+// fakeClamd answers one INSTREAM request per connection, reporting a virus
+// when the stream contains "EICAR"
+func fakeClamd(t *testing.T) string {
+	t.Helper()
+	socket := filepath.Join(t.TempDir(), "clamd.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			reader := bufio.NewReader(conn)
+			if command, _ := reader.ReadString(0); command != "zINSTREAM\x00" {
+				conn.Write([]byte("UNKNOWN COMMAND\x00"))
+				conn.Close()
+				continue
+			}
+			var stream strings.Builder
+			for {
+				var size uint32
+				if binary.Read(reader, binary.BigEndian, &size) != nil || size == 0 {
+					break
+				}
+				io.CopyN(&stream, reader, int64(size))
+			}
+			if strings.Contains(stream.String(), "EICAR") {
+				conn.Write([]byte("stream: Eicar-Test-Signature FOUND\x00"))
+			} else {
+				conn.Write([]byte("stream: OK\x00"))
+			}
+			conn.Close()
+		}
+	}()
+	return "unix:" + socket
+}
+
+func writeUpload(t *testing.T, content string) models.UploadedFile {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "upload.part")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return models.UploadedFile{Path: path, Name: "targets.csv", Size: int64(len(content)), UploadedBy: "analyst"}
+}
+
+func TestUploadScan_RecordsVerdicts(t *testing.T) {
+	stateDir := t.TempDir()
+	audit, err := services.NewAuditLog(stateDir, &mockLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	scan := services.NewUploadScan(config.UploadConfig{
+		MaxBytes:          100,
+		AllowedExtensions: ".csv",
+		AllowedTypes:      "text/plain",
+		ClamAVAddress:     fakeClamd(t),
+		ClamAVTimeout:     time.Second,
+	}, audit, &mockLogger{})
+	ctx := context.Background()
+
+	if err := scan.Scan(ctx, writeUpload(t, "month,country,revenue_target\n")); err != nil {
+		t.Fatalf("Scan() of a clean CSV error = %v", err)
+	}
+
+	rejected := map[string]models.UploadedFile{
+		"size":   writeUpload(t, strings.Repeat("a", 101)),
+		"type":   writeUpload(t, "\x89PNG\r\n\x1a\n"),
+		"clamav": writeUpload(t, "month,EICAR\n"),
+	}
+	exe := writeUpload(t, "month\n")
+	exe.Name = "targets.exe"
+	rejected["extension"] = exe
+
+	for name, file := range rejected {
+		err := scan.Scan(ctx, file)
+		var rejection *models.ScanRejection
+		if !errors.As(err, &rejection) || !errors.Is(err, models.ErrValidation) {
+			t.Errorf("Scan(%s) error = %v, want a validation ScanRejection", name, err)
+		}
+	}
+
+	events, err := audit.List(0)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(events) != 5 {
+		t.Fatalf("audit log has %d events, want 5", len(events))
+	}
+	accepted := events[len(events)-1]
+	if accepted.Outcome != models.AuditAccepted || accepted.Actor != "analyst" || accepted.Subject != "targets.csv" {
+		t.Errorf("oldest audit event = %+v, want the accepted upload", accepted)
+	}
+	for _, event := range events[:4] {
+		if event.Outcome != models.AuditRejected || event.Reason == "" {
+			t.Errorf("audit event = %+v, want a rejection with a reason", event)
+		}
+	}
+
+	// An unreachable clamd fails the scan closed
+	broken := services.NewUploadScan(config.UploadConfig{
+		MaxBytes:      100,
+		ClamAVAddress: "unix:" + filepath.Join(t.TempDir(), "missing.sock"),
+		ClamAVTimeout: time.Second,
+	}, audit, &mockLogger{})
+	if err := broken.Scan(ctx, writeUpload(t, "month\n")); err == nil || errors.Is(err, models.ErrUploadRejected) {
+		t.Errorf("Scan() without clamd error = %v, want a scan failure", err)
+	}
+	if events, _ := audit.List(1); events[0].Outcome != models.AuditError {
+		t.Errorf("latest audit event = %+v, want an error outcome", events[0])
+	}
+}
@@ -0,0 +1,90 @@
+package services_test
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"analytics-dashboard-api/internal/config"
+	"analytics-dashboard-api/internal/models"
+	"analytics-dashboard-api/internal/services"
+)
+
+// failingReader delivers data and then fails like a dropped connection
+type failingReader struct {
+	data io.Reader
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	n, err := r.data.Read(p)
+	if errors.Is(err, io.EOF) {
+		return n, errors.New("connection reset")
+	}
+	return n, err
+}
+
+func TestUploadStore_ResumesAfterInterruptedChunk(t *testing.T) {
+	cfg := config.UploadConfig{Dir: t.TempDir(), MaxBytes: 1 << 20, SessionTTL: time.Hour}
+	store, err := services.NewUploadStore(cfg, &mockLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const content = "month,country,revenue_target\n2024-01,Germany,100\n"
+	upload, err := store.Create(int64(len(content)), "targets.csv")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	// The first chunk is cut off after 10 bytes; those bytes are kept
+	if _, err := store.Append(upload.ID, 0, &failingReader{strings.NewReader(content[:10])}); err == nil {
+		t.Fatal("Append() of an interrupted chunk succeeded")
+	}
+	if _, err := store.File(upload.ID); !errors.Is(err, models.ErrUploadIncomplete) {
+		t.Errorf("File() of a partial upload error = %v, want ErrUploadIncomplete", err)
+	}
+
+	// A restarted server picks the session up where it stopped
+	store, err = services.NewUploadStore(cfg, &mockLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	upload, err = store.Get(upload.ID)
+	if err != nil || upload.Offset != 10 {
+		t.Fatalf("Get() = %+v, %v, want offset 10", upload, err)
+	}
+	if _, err := store.Append(upload.ID, 0, strings.NewReader(content)); !errors.Is(err, models.ErrUploadOffset) {
+		t.Errorf("Append() at a stale offset error = %v, want ErrUploadOffset", err)
+	}
+	if _, err := store.Append(upload.ID, 10, strings.NewReader(content[10:]+"extra")); !errors.Is(err, models.ErrInvalidUpload) {
+		t.Errorf("Append() past the declared size error = %v, want ErrInvalidUpload", err)
+	}
+
+	upload, err = store.Append(upload.ID, 10, strings.NewReader(content[10:]))
+	if err != nil || !upload.Complete {
+		t.Fatalf("Append() = %+v, %v, want a complete upload", upload, err)
+	}
+	file, err := store.File(upload.ID)
+	if err != nil {
+		t.Fatalf("File() error = %v", err)
+	}
+	if file.Name != "targets.csv" || file.Size != int64(len(content)) {
+		t.Errorf("File() = %+v", file)
+	}
+	if data, _ := os.ReadFile(file.Path); string(data) != content {
+		t.Errorf("uploaded file = %q, want %q", data, content)
+	}
+
+	if err := store.Delete(upload.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := store.Get(upload.ID); !errors.Is(err, models.ErrUploadNotFound) {
+		t.Errorf("Get() after Delete() error = %v, want ErrUploadNotFound", err)
+	}
+	if _, err := store.Get("../../etc/passwd"); !errors.Is(err, models.ErrUploadNotFound) {
+		t.Errorf("Get() of a path error = %v, want ErrUploadNotFound", err)
+	}
+}
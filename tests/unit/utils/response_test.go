@@ -73,26 +73,37 @@ func TestWriteErrorResponse(t *testing.T) {
 		name       string
 		statusCode int
 		message    string
+		errorCode  utils.ErrorCode
 	}{
 		{
 			name:       "bad request",
 			statusCode: http.StatusBadRequest,
 			message:    "Invalid input",
+			errorCode:  utils.ErrCodeInvalidParam,
 		},
 		{
 			name:       "internal server error",
 			statusCode: http.StatusInternalServerError,
 			message:    "Something went wrong",
+			errorCode:  utils.ErrCodeInternal,
 		},
 		{
 			name:       "not found",
 			statusCode: http.StatusNotFound,
 			message:    "Resource not found",
+			errorCode:  utils.ErrCodeNotFound,
 		},
 		{
 			name:       "unauthorized",
 			statusCode: http.StatusUnauthorized,
 			message:    "Access denied",
+			errorCode:  utils.ErrCodeUnauthorized,
+		},
+		{
+			name:       "unmapped client error",
+			statusCode: http.StatusTeapot,
+			message:    "No coffee",
+			errorCode:  utils.ErrCodeBadRequest,
 		},
 	}
 
@@ -128,6 +139,10 @@ func TestWriteErrorResponse(t *testing.T) {
 				t.Errorf("WriteErrorResponse() code = %d, want %d", errorResp.Code, tt.statusCode)
 			}
 
+			if errorResp.ErrorCode != tt.errorCode {
+				t.Errorf("WriteErrorResponse() error_code = %s, want %s", errorResp.ErrorCode, tt.errorCode)
+			}
+
 			expectedError := http.StatusText(tt.statusCode)
 			if errorResp.Error != expectedError {
 				t.Errorf("WriteErrorResponse() error = %s, want %s", errorResp.Error, expectedError)
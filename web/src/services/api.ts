@@ -62,6 +62,13 @@ interface CountryRevenuePaginatedResponse {
   has_more: boolean;
 }
 
+interface JobInfo {
+  id: number;
+  kind: string;
+  status: "queued" | "running" | "succeeded" | "failed";
+  error?: string;
+}
+
 interface DataResponse<T> {
   data: T[];
   count: number;
@@ -125,10 +132,19 @@ export async function getTopRegions(): Promise<DataResponse<RegionRevenue>> {
   return fetchApi<DataResponse<RegionRevenue>>("/api/v1/analytics/top-regions");
 }
 
-export async function refreshCache(): Promise<{ message: string }> {
-  return fetchApi<{ message: string }>("/api/v1/analytics/refresh", {
+// refreshCache starts a refresh job and polls it until it finishes
+export async function refreshCache(): Promise<JobInfo> {
+  let job = await fetchApi<JobInfo>("/api/v1/analytics/refresh", {
     method: "POST",
   });
+  while (job.status === "queued" || job.status === "running") {
+    await new Promise((resolve) => setTimeout(resolve, 1000));
+    job = await fetchApi<JobInfo>(`/api/v1/jobs/${job.id}`);
+  }
+  if (job.status === "failed") {
+    throw new Error(job.error ?? "Refresh failed");
+  }
+  return job;
 }
 
 export async function healthCheck(): Promise<{ status: string }> {
@@ -144,4 +160,5 @@ export type {
   StatsResponse,
   CountryRevenuePaginatedResponse,
   DataResponse,
+  JobInfo,
 };